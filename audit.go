@@ -0,0 +1,126 @@
+// audit.go - transcripts of public handshake values for interop reports
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// An audit transcript lists the public values of a handshake in the
+// notation of the test vectors of RFC 5054 (Appendix B), so that it can be
+// attached to a bug report about interop with another implementation:
+//
+//	H = SHA-256 (5)
+//	N = EEAF0AB9 ADB38DD6 9C33F80A FA8FC5E8 60726187 75FF3C0B 9EA2314C
+//	    9C256576 D674DF74 96EA81D3 383B4813 D692C6E0 E0D5D8E2 50B98BE4
+//	...
+//
+// It never contains secrets: the password, x, v, a, b, S, K and the proofs
+// are left out. The identity is the hashed I sent on the wire.
+
+// WithAuditLog makes clients and servers in this environment write the
+// audit transcript of each handshake to 'w' (see Client.Audit()). Each
+// transcript is written with a single call to w.Write(); errors are
+// ignored.
+func WithAuditLog(w io.Writer) Option {
+	return func(s *SRP) error {
+		if w == nil {
+			return fmt.Errorf("srp: nil audit log")
+		}
+		s.audit = w
+		return nil
+	}
+}
+
+// Audit returns the transcript of the public values of the handshake in
+// the notation of RFC 5054; it must be called after Respond() or
+// Generate(). It contains no secrets.
+func (c *Client) Audit() (string, error) {
+	if c.xT == nil {
+		return "", fmt.Errorf("srp: no handshake to audit")
+	}
+	return c.s.auditTranscript(c.xT), nil
+}
+
+// Audit returns the transcript of the public values of the handshake in
+// the notation of RFC 5054. It contains no secrets.
+func (s *Server) Audit() string {
+	return s.s.auditTranscript(s.s.transcript(nil, s.xA, s.xB, s.i, s.salt))
+}
+
+// write the audit transcript of 't' to the audit log, if any
+func (s *SRP) logAudit(t *Transcript) {
+	if s.audit != nil {
+		s.audit.Write([]byte(s.auditTranscript(t)))
+	}
+}
+
+// return the audit transcript of 't'
+func (s *SRP) auditTranscript(t *Transcript) string {
+	var b strings.Builder
+
+	name := "hash"
+	if _, ok := hashPackages[s.h]; ok {
+		name = hashName(s.h)
+	}
+
+	grp := fmt.Sprintf("%d-bit Group", s.FieldSize())
+	if s.pf.id != "" {
+		grp += ", " + s.pf.id
+	}
+
+	fmt.Fprintf(&b, "H = %s (%d)\n", name, s.h)
+	auditField(&b, "N", t.N.Bytes())
+	fmt.Fprintf(&b, "g = %s (%s)\n", strings.ToUpper(t.G.Text(16)), grp)
+	auditField(&b, "k", s.multiplier().Bytes())
+	auditField(&b, "I", t.I)
+	auditField(&b, "s", t.Salt)
+	if t.A != nil {
+		auditField(&b, "A", t.A.Bytes())
+	}
+	auditField(&b, "B", t.B.Bytes())
+	if t.A != nil {
+		auditField(&b, "u", s.ComputeU(t.A, t.B).Bytes())
+	}
+	fmt.Fprintf(&b, "proof = %s\n", s.scheme().Name())
+	// only the application's context; a step-up binding is secret
+	if len(s.tctx) > 0 {
+		auditField(&b, "H(ctx)", t.H(s.tctx))
+	}
+	return b.String()
+}
+
+// write 'v' as "name = " followed by 'v' in upper case hex, in groups of 8
+// digits and 7 groups per line
+func auditField(b *strings.Builder, name string, v []byte) {
+	const perLine = 7
+
+	x := strings.ToUpper(hex.EncodeToString(v))
+
+	// left pad to whole groups
+	if r := len(x) % 8; r != 0 {
+		x = strings.Repeat("0", 8-r) + x
+	}
+
+	prefix := name + " = "
+	indent := strings.Repeat(" ", len(prefix))
+	b.WriteString(prefix)
+	for i := 0; i < len(x); i += 8 {
+		switch {
+		case i == 0:
+		case (i/8)%perLine == 0:
+			b.WriteString("\n" + indent)
+		default:
+			b.WriteByte(' ')
+		}
+		b.WriteString(x[i : i+8])
+	}
+	b.WriteByte('\n')
+}
@@ -0,0 +1,69 @@
+// self test for audit transcripts
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestAudit(t *testing.T) {
+	assert := newAsserter(t)
+
+	user := []byte("user")
+	pass := []byte("pass")
+
+	var clog, slog bytes.Buffer
+
+	cs, err := NewWithHash(crypto.SHA256, 2048, WithAuditLog(&clog))
+	assert(err == nil, "New: %s", err)
+
+	ss, err := NewWithHash(crypto.SHA256, 2048, WithAuditLog(&slog))
+	assert(err == nil, "New: %s", err)
+
+	v, err := ss.Verifier(user, pass, nil)
+	assert(err == nil, "Verifier: %s", err)
+
+	c, err := cs.NewClient(user, pass)
+	assert(err == nil, "NewClient: %s", err)
+
+	_, err = c.Audit()
+	assert(err != nil, "audited before the handshake")
+
+	srv, err := ss.NewServer(v, big.NewInt(0).SetBytes(c.Hello().A))
+	assert(err == nil, "NewServer: %s", err)
+
+	_, err = c.Respond(srv.Challenge())
+	assert(err == nil, "Respond: %s", err)
+
+	ca, err := c.Audit()
+	assert(err == nil, "Audit: %s", err)
+	assert(ca == srv.Audit(), "client and server transcripts differ:\n%s\n%s", ca, srv.Audit())
+	assert(clog.String() == ca, "client log mismatch:\n%s", clog.String())
+	assert(slog.String() == ca, "server log mismatch:\n%s", slog.String())
+
+	assert(strings.HasPrefix(ca, "H = SHA-256 (5)\nN = "), "wrong header:\n%s", ca)
+	assert(strings.Contains(ca, "g = 2 (2048-bit Group, rfc5054-2048)\n"), "wrong generator:\n%s", ca)
+	assert(strings.Contains(ca, "proof = legacy\n"), "wrong proof scheme:\n%s", ca)
+
+	// N wraps after 7 groups of 8 digits
+	lines := strings.Split(ca, "\n")
+	N := strings.ToUpper(hex.EncodeToString(ss.pf.N.Bytes()))
+	assert(strings.HasPrefix(lines[1], "N = "+N[:8]+" "+N[8:16]), "wrong N %q", lines[1])
+	assert(len(lines[1]) == len("N = ")+7*9-1, "wrong line width %q", lines[1])
+	assert(strings.HasPrefix(lines[2], "    ") && lines[2][4] != ' ', "wrong continuation %q", lines[2])
+
+	// no secrets
+	flat := strings.Replace(strings.Replace(ca, " ", "", -1), "\n", "", -1)
+	for _, x := range [][]byte{c.xK, v.v, c.xM} {
+		assert(!strings.Contains(flat, strings.ToUpper(hex.EncodeToString(x))), "secret in transcript")
+	}
+}
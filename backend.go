@@ -0,0 +1,109 @@
+// backend.go - pluggable modular arithmetic
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// Backend performs the modular arithmetic of an SRP environment: computing
+// verifiers, public keys and shared secrets. An alternative backend (e.g.,
+// GMP via cgo, a constant-time implementation or a hardware accelerator)
+// is attached with WithBackend(). Values are passed as *big.Int so that
+// backends plug in without changing the rest of the API; a backend converts
+// them to its own representation as needed.
+//
+// Arguments are non-negative and must not be modified. Every arithmetic
+// method returns a new value in [0, m). Cmp and Bytes handle the secret
+// values (ephemerals and the shared secret S), so constant-time backends
+// implement them too.
+type Backend interface {
+	// Exp returns x^y mod m
+	Exp(x, y, m *big.Int) *big.Int
+
+	// Mul returns x*y mod m
+	Mul(x, y, m *big.Int) *big.Int
+
+	// Add returns x+y mod m
+	Add(x, y, m *big.Int) *big.Int
+
+	// Sub returns x-y mod m
+	Sub(x, y, m *big.Int) *big.Int
+
+	// Mod returns x mod m
+	Mod(x, m *big.Int) *big.Int
+
+	// Cmp returns -1, 0 or +1 as x is less than, equal to or greater
+	// than y
+	Cmp(x, y *big.Int) int
+
+	// Bytes returns x as a big-endian number left-padded with zeros to
+	// 'n' bytes; it is longer if x doesn't fit and has no leading zeros
+	// if n is 0
+	Bytes(x *big.Int, n int) []byte
+}
+
+// MathBig is the default Backend; it uses math/big.
+var MathBig Backend = mathBig{}
+
+// WithBackend makes the environment do its modular arithmetic with 'b'
+// instead of MathBig.
+func WithBackend(b Backend) Option {
+	return func(s *SRP) error {
+		if b == nil {
+			return fmt.Errorf("srp: nil arithmetic backend")
+		}
+		s.be = b
+		return nil
+	}
+}
+
+// return the arithmetic backend of this environment
+func (s *SRP) arith() Backend {
+	be := s.be
+	if be == nil {
+		be = MathBig
+	}
+	if s.blindExp {
+		return blindingBackend{be, s.random()}
+	}
+	return be
+}
+
+type mathBig struct{}
+
+func (mathBig) Exp(x, y, m *big.Int) *big.Int {
+	return big.NewInt(0).Exp(x, y, m)
+}
+
+func (mathBig) Mul(x, y, m *big.Int) *big.Int {
+	z := big.NewInt(0).Mul(x, y)
+	return z.Mod(z, m)
+}
+
+func (mathBig) Add(x, y, m *big.Int) *big.Int {
+	z := big.NewInt(0).Add(x, y)
+	return z.Mod(z, m)
+}
+
+func (mathBig) Sub(x, y, m *big.Int) *big.Int {
+	z := big.NewInt(0).Sub(x, y)
+	return z.Mod(z, m)
+}
+
+func (mathBig) Mod(x, m *big.Int) *big.Int {
+	return big.NewInt(0).Mod(x, m)
+}
+
+func (mathBig) Cmp(x, y *big.Int) int {
+	return x.Cmp(y)
+}
+
+func (mathBig) Bytes(x *big.Int, n int) []byte {
+	return pad(x, n)
+}
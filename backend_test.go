@@ -0,0 +1,91 @@
+// self test for arithmetic backends
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"bytes"
+	"math/big"
+	"sync/atomic"
+	"testing"
+)
+
+// counts the exponentiations done by MathBig
+type countingBackend struct {
+	Backend
+	exps int64
+}
+
+func (b *countingBackend) Exp(x, y, m *big.Int) *big.Int {
+	atomic.AddInt64(&b.exps, 1)
+	return b.Backend.Exp(x, y, m)
+}
+
+func TestBackend(t *testing.T) {
+	assert := newAsserter(t)
+
+	user := []byte("user")
+	pass := []byte("pass")
+
+	_, err := New(2048, WithBackend(nil))
+	assert(err != nil, "accepted nil backend")
+
+	be := &countingBackend{Backend: MathBig}
+	s, err := New(2048, WithBackend(be))
+	assert(err == nil, "New: %s", err)
+
+	v, err := s.Verifier(user, pass, nil)
+	assert(err == nil, "Verifier: %s", err)
+
+	c, err := s.NewClient(user, pass)
+	assert(err == nil, "NewClient: %s", err)
+
+	_, A, err := ServerBegin(c.Credentials())
+	assert(err == nil, "ServerBegin: %s", err)
+
+	srv, err := s.NewServer(v, A)
+	assert(err == nil, "NewServer: %s", err)
+
+	m, err := c.Generate(srv.Credentials())
+	assert(err == nil, "Generate: %s", err)
+
+	proof, ok := srv.ClientOk(m)
+	assert(ok, "server: bad client proof")
+	assert(c.ServerOk(proof), "client: bad server proof")
+
+	// v, A, B, kg^x and the two shared secrets (2 for the server)
+	assert(be.exps == 7, "exp 7 exponentiations, saw %d", be.exps)
+}
+
+func TestMathBig(t *testing.T) {
+	assert := newAsserter(t)
+
+	m := big.NewInt(7)
+	tests := []struct {
+		z   *big.Int
+		exp int64
+	}{
+		{MathBig.Exp(big.NewInt(3), big.NewInt(4), m), 4},
+		{MathBig.Mul(big.NewInt(3), big.NewInt(5), m), 1},
+		{MathBig.Add(big.NewInt(3), big.NewInt(5), m), 1},
+		{MathBig.Sub(big.NewInt(3), big.NewInt(5), m), 5},
+		{MathBig.Mod(big.NewInt(15), m), 1},
+	}
+	for i, x := range tests {
+		assert(x.z.Int64() == x.exp, "%d: exp %d, saw %s", i, x.exp, x.z)
+	}
+
+	assert(MathBig.Cmp(big.NewInt(3), m) < 0, "3 >= 7")
+	assert(MathBig.Cmp(m, big.NewInt(7)) == 0, "7 != 7")
+	assert(MathBig.Cmp(m, big.NewInt(3)) > 0, "7 <= 3")
+
+	b := MathBig.Bytes(big.NewInt(0x102), 4)
+	assert(bytes.Equal(b, []byte{0, 0, 1, 2}), "padded: saw %x", b)
+	b = MathBig.Bytes(big.NewInt(0x102), 0)
+	assert(bytes.Equal(b, []byte{1, 2}), "unpadded: saw %x", b)
+	b = MathBig.Bytes(big.NewInt(0x10203), 2)
+	assert(bytes.Equal(b, []byte{1, 2, 3}), "wide: saw %x", b)
+}
@@ -0,0 +1,70 @@
+// blind.go - blinding of the identities stored in verifiers
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+)
+
+// name of the blinding function recorded in encoded verifiers
+const identityBlinding = "hmac-sha256"
+
+// minimum size of an identity blinding key in bytes
+const minIdentityKeyLen = 16
+
+var errBlinded = fmt.Errorf("srp: verifier has a blinded identity; use NewServerFor()")
+
+// WithIdentityKey blinds the identities stored in verifiers with the server
+// secret 'key': a verifier records HMAC-SHA256(key, I) instead of the hashed
+// identity I sent by clients. Someone who obtains the verifier database but
+// not the key can then no longer confirm guesses of user names offline.
+//
+// Servers look verifiers up with BlindIdentity(), must give the same option
+// to MakeSRPVerifier() and start handshakes with NewServerFor(). The key
+// isn't recorded in the verifier; keep it out of the database.
+func WithIdentityKey(key []byte) Option {
+	return func(s *SRP) error {
+		if len(key) < minIdentityKeyLen {
+			return fmt.Errorf("srp: identity key must be at least %d bytes", minIdentityKeyLen)
+		}
+		s.idk = append([]byte{}, key...)
+		return nil
+	}
+}
+
+// BlindIdentity returns the form in which the hashed identity 'ih' (as
+// returned by ServerBegin()) is stored by verifiers created with
+// WithIdentityKey(key). Servers use it as the key to lookup verifiers.
+func BlindIdentity(key []byte, ih string) (string, error) {
+	i, err := hex.DecodeString(ih)
+	if err != nil {
+		return "", fmt.Errorf("srp: invalid identity")
+	}
+	return hex.EncodeToString(blindIdentity(key, i)), nil
+}
+
+// NewServerFor constructs a Server for the client with hashed identity 'ih'
+// (as sent by the client) and public key 'A'. Unlike NewServer(), it works
+// with verifiers whose identity is blinded (see WithIdentityKey()). It
+// returns an error if 'v' doesn't belong to 'ih'.
+func (s *SRP) NewServerFor(ih []byte, v *Verifier, A *big.Int) (*Server, error) {
+	if !v.MatchesIdentity(ih) {
+		return nil, fmt.Errorf("srp: verifier doesn't match identity")
+	}
+	return s.newServer(v, ih, big.NewInt(0).SetBytes(v.v), A)
+}
+
+// blind the hashed identity 'ih' with 'key'
+func blindIdentity(key, ih []byte) []byte {
+	m := hmac.New(sha256.New, key)
+	m.Write(ih)
+	return m.Sum(nil)
+}
@@ -0,0 +1,72 @@
+// self test for identity blinding
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestIdentityKey(t *testing.T) {
+	assert := newAsserter(t)
+
+	user := []byte("user")
+	pass := []byte("pass")
+	key := []byte("0123456789abcdef")
+
+	_, err := New(2048, WithIdentityKey(key[:8]))
+	assert(err != nil, "accepted short identity key")
+
+	s, err := New(2048, WithIdentityKey(key))
+	assert(err == nil, "New: %s", err)
+
+	v, err := s.Verifier(user, pass, nil)
+	assert(err == nil, "Verifier: %s", err)
+	dbkey, vh := v.Encode()
+
+	c, err := s.NewClient(user, pass)
+	assert(err == nil, "NewClient: %s", err)
+
+	ih, A, err := ServerBegin(c.Credentials())
+	assert(err == nil, "ServerBegin: %s", err)
+	assert(ih != dbkey, "identity isn't blinded")
+
+	bk, err := BlindIdentity(key, ih)
+	assert(err == nil, "BlindIdentity: %s", err)
+	assert(bk == dbkey, "lookup key mismatch: exp %s, saw %s", dbkey, bk)
+
+	// the key is needed to use the verifier
+	_, _, err = MakeSRPVerifier(vh)
+	assert(err != nil, "decoded blinded verifier without a key")
+
+	ss, sv, err := MakeSRPVerifier(vh, WithIdentityKey(key))
+	assert(err == nil, "MakeSRPVerifier: %s", err)
+
+	_, err = ss.NewServer(sv, A)
+	assert(err != nil, "NewServer accepted blinded verifier")
+
+	i, _ := hex.DecodeString(ih)
+	assert(sv.MatchesIdentity(i), "MatchesIdentity failed")
+
+	_, err = ss.NewServerFor(s.hashbyte([]byte("other")), sv, A)
+	assert(err != nil, "NewServerFor accepted wrong identity")
+
+	srv, err := ss.NewServerFor(i, sv, A)
+	assert(err == nil, "NewServerFor: %s", err)
+
+	m, err := c.Generate(srv.Credentials())
+	assert(err == nil, "Generate: %s", err)
+
+	proof, ok := srv.ClientOk(m)
+	assert(ok, "ClientOk failed")
+	assert(c.ServerOk(proof), "ServerOk failed")
+
+	// the CBOR encoding keeps the blinding
+	_, cv, err := DecodeVerifierCBOR(v.EncodeCBOR(), WithIdentityKey(key))
+	assert(err == nil, "DecodeVerifierCBOR: %s", err)
+	assert(cv.MatchesIdentity(i), "CBOR: MatchesIdentity failed")
+}
@@ -0,0 +1,90 @@
+// blinding.go - randomized exponentiations against timing analysis
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"io"
+	"math/big"
+)
+
+// math/big isn't constant time: the time of an exponentiation depends on
+// the exponent and the base. The secrets of a handshake (a, b, x and the
+// base B - kg^x, which depends on x) are exponents or bases of every
+// handshake, so an attacker who can time many handshakes of a user
+// learns about them. WithExponentBlinding() randomizes each exponentiation
+// x^y mod N of the environment so that its inputs are fresh random values
+// unrelated to the secrets:
+//
+//	exponent blinding: y' = y + r(N-1)      for a random 64 bit r
+//	base blinding:     x^y = (xz)^y' (z^-1)^y' mod N   for a random z
+//
+// Both hold because x^(N-1) = 1 mod N for prime N and x != 0 (Fermat), so
+// the result is unchanged; the two exponentiations run in random order.
+// Blinding is a software countermeasure, not a constant-time
+// implementation: it roughly doubles the cost of an exponentiation and
+// needs the prime fields of the environment to be prime (as all built-in
+// groups are; see AnalyzeGroup() for custom ones). It applies on top of
+// the backend set with WithBackend().
+
+// size of the random multiplier of the group order in bits
+const blindingBits = 64
+
+// WithExponentBlinding makes clients and servers in this environment blind
+// each modular exponentiation with fresh randomness (see above).
+func WithExponentBlinding() Option {
+	return func(s *SRP) error {
+		s.blindExp = true
+		return nil
+	}
+}
+
+// blindingBackend blinds the exponentiations of another Backend with
+// randomness read from 'rand'
+type blindingBackend struct {
+	Backend
+	rand io.Reader
+}
+
+// Exp returns x^y mod m computed on blinded inputs; 'm' must be prime
+func (b blindingBackend) Exp(x, y, m *big.Int) *big.Int {
+	x = b.Backend.Mod(x, m)
+	if y.Sign() == 0 || x.Sign() == 0 || m.BitLen() < 2 {
+		return b.Backend.Exp(x, y, m)
+	}
+
+	// y' = y + r(m-1)
+	ord := big.NewInt(0).Sub(m, one)
+	yb := big.NewInt(0).Mul(randBigInt(b.rand, blindingBits), ord)
+	yb.Add(yb, y)
+
+	// a random z in [1, m) and its inverse
+	z := b.nonzero(m)
+	zi := big.NewInt(0).ModInverse(z, m)
+
+	xz := b.Backend.Mul(x, z, m)
+	var t0, t1 *big.Int
+	if readRand(b.rand, 1)[0]&1 == 0 {
+		t0 = b.Backend.Exp(xz, yb, m)
+		t1 = b.Backend.Exp(zi, yb, m)
+	} else {
+		t1 = b.Backend.Exp(zi, yb, m)
+		t0 = b.Backend.Exp(xz, yb, m)
+	}
+	return b.Backend.Mul(t0, t1, m)
+}
+
+// return a random number in [1, m)
+func (b blindingBackend) nonzero(m *big.Int) *big.Int {
+	for i := 0; i < maxEphemeralDraws; i++ {
+		// 64 extra bits make the bias of the reduction negligible
+		z := b.Backend.Mod(randBigInt(b.rand, m.BitLen()+64), m)
+		if z.Sign() != 0 {
+			return z
+		}
+	}
+	panic("Random source is broken!")
+}
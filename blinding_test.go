@@ -0,0 +1,90 @@
+// self test for blinded exponentiations
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	CR "crypto/rand"
+	"math/big"
+	"testing"
+)
+
+// records the exponents it is called with
+type exponentRecorder struct {
+	Backend
+	ys []*big.Int
+}
+
+func (b *exponentRecorder) Exp(x, y, m *big.Int) *big.Int {
+	b.ys = append(b.ys, y)
+	return b.Backend.Exp(x, y, m)
+}
+
+func TestBlindingBackend(t *testing.T) {
+	assert := newAsserter(t)
+
+	N := pflist[2048].N
+	be := blindingBackend{MathBig, CR.Reader}
+
+	Nm1 := big.NewInt(0).Sub(N, one)
+	xs := []*big.Int{
+		big.NewInt(0), big.NewInt(1), big.NewInt(2), Nm1,
+		big.NewInt(0).Add(N, big.NewInt(5)),
+		randBigInt(CR.Reader, 2048),
+	}
+	ys := []*big.Int{
+		big.NewInt(0), big.NewInt(1), big.NewInt(3), Nm1,
+		randBigInt(CR.Reader, 256), randBigInt(CR.Reader, 2048),
+	}
+	for _, x := range xs {
+		for _, y := range ys {
+			exp := MathBig.Exp(x, y, N)
+			z := be.Exp(x, y, N)
+			assert(z.Cmp(exp) == 0, "%x^%x: wrong result", x, y)
+		}
+	}
+
+	// the underlying backend never sees the secret exponent
+	rec := &exponentRecorder{Backend: MathBig}
+	be = blindingBackend{rec, CR.Reader}
+	y := randBigInt(CR.Reader, 256)
+	be.Exp(big.NewInt(2), y, N)
+	be.Exp(big.NewInt(2), y, N)
+	assert(len(rec.ys) == 4, "exp 4 exponentiations, saw %d", len(rec.ys))
+	for _, yb := range rec.ys {
+		assert(yb.Cmp(y) != 0, "unblinded exponent")
+	}
+	assert(rec.ys[0].Cmp(rec.ys[2]) != 0, "blinding isn't fresh")
+}
+
+func TestExponentBlinding(t *testing.T) {
+	assert := newAsserter(t)
+
+	be := &countingBackend{Backend: MathBig}
+	s, err := New(2048, WithExponentBlinding(), WithBackend(be))
+	assert(err == nil, "New: %s", err)
+
+	v, err := s.Verifier([]byte("user"), []byte("pass"), nil)
+	assert(err == nil, "Verifier: %s", err)
+
+	// the verifier matches one computed without blinding
+	p, err := New(2048)
+	assert(err == nil, "New: %s", err)
+	w, err := p.Verifier([]byte("user"), []byte("pass"), v.s)
+	assert(err == nil, "Verifier: %s", err)
+	assert(string(v.v) == string(w.v), "blinded verifier differs")
+
+	for _, pw := range []string{"pass", "wrong"} {
+		c, err := s.NewClient([]byte("user"), []byte(pw))
+		assert(err == nil, "NewClient: %s", err)
+		srv, err := s.NewServer(v, c.xA)
+		assert(err == nil, "NewServer: %s", err)
+		assert(authenticate(c, srv) == (pw == "pass"), "%s: wrong outcome", pw)
+	}
+
+	// each exponentiation runs blinded on the backend, i.e., twice
+	assert(be.exps%2 == 0 && be.exps > 0, "exponentiations not blinded: %d", be.exps)
+}
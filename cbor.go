@@ -0,0 +1,482 @@
+// cbor.go - CBOR (RFC 8949) encoding of SRP messages and verifiers
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"crypto"
+	"fmt"
+	"math/big"
+)
+
+// Each message is a CBOR map keyed by small unsigned integers; proofs are
+// bare byte strings. Only the subset of CBOR needed for these messages is
+// supported: definite length maps, byte strings, text strings and
+// unsigned integers.
+//
+//   ClientCredentials: {1: I, 2: A}
+//   ServerCredentials: {1: s, 2: B, 3: kdf, 4: grp, 5: sig, 6: pow, 7: x}
+//   Verifier:          {1: bytes(N), 2: N, 3: g, 4: hash, 5: I, 6: s, 7: v, 8: kdf, 9: idk, 10: grp, 11: x}
+//
+// The kdf is the text form of KDF.String() and is omitted if there is none.
+// The idk names the function that blinded I and is omitted if I isn't
+// blinded (see WithIdentityKey()). The grp is the id of a built-in group
+// (see SupportedGroups()) and is omitted for custom groups; servers only
+// send it if the size of B doesn't identify the group. The sig is only sent
+// by servers that sign their challenges (see WithChallengeSigningKey()) and
+// the pow, the text form of Puzzle.String(), by servers that require
+// clients to solve a puzzle (see WithClientPuzzle()). The x names how
+// clients derive the private key of a verifier made by another
+// implementation and is omitted for the verifiers of this package (see
+// ImportVerifier()).
+
+// CBOR major types
+const (
+	cborUint  byte = 0
+	cborBytes byte = 2
+	cborText  byte = 3
+	cborMap   byte = 5
+)
+
+// EncodeCBOR returns the CBOR encoding of the client credentials
+func (cc *ClientCredentials) EncodeCBOR() []byte {
+	var w cborWriter
+
+	w.head(cborMap, 2)
+	w.uint(1)
+	w.bytes(cc.IdentityHash)
+	w.uint(2)
+	w.bytes(cc.A)
+	return w.b
+}
+
+// DecodeClientCredentialsCBOR decodes the output of ClientCredentials.EncodeCBOR()
+func DecodeClientCredentialsCBOR(b []byte) (ClientCredentials, error) {
+	var cc ClientCredentials
+
+	err := decodeCBORMap(b, func(k uint64, r *cborReader) (err error) {
+		switch k {
+		case 1:
+			cc.IdentityHash, err = r.bytes()
+		case 2:
+			cc.A, err = r.bytes()
+		default:
+			err = fmt.Errorf("unknown key %d", k)
+		}
+		return err
+	})
+	if err != nil {
+		return cc, fmt.Errorf("srp: invalid client credentials: %s", err)
+	}
+	if cc.IdentityHash == nil || cc.A == nil {
+		return cc, fmt.Errorf("srp: invalid client credentials: missing fields")
+	}
+	return cc, nil
+}
+
+// EncodeCBOR returns the CBOR encoding of the server credentials
+func (sc *ServerCredentials) EncodeCBOR() []byte {
+	var w cborWriter
+
+	n := 2
+	if sc.KDF != nil {
+		n++
+	}
+	if sc.Group != "" {
+		n++
+	}
+	if sc.Signature != nil {
+		n++
+	}
+	if sc.Puzzle != nil {
+		n++
+	}
+	if sc.X != "" {
+		n++
+	}
+
+	w.head(cborMap, uint64(n))
+	w.uint(1)
+	w.bytes(sc.Salt)
+	w.uint(2)
+	w.bytes(sc.B)
+	if sc.KDF != nil {
+		w.uint(3)
+		w.text(sc.KDF.String())
+	}
+	if sc.Group != "" {
+		w.uint(4)
+		w.text(sc.Group)
+	}
+	if sc.Signature != nil {
+		w.uint(5)
+		w.bytes(sc.Signature)
+	}
+	if sc.Puzzle != nil {
+		w.uint(6)
+		w.text(sc.Puzzle.String())
+	}
+	if sc.X != "" {
+		w.uint(7)
+		w.text(sc.X)
+	}
+	return w.b
+}
+
+// DecodeServerCredentialsCBOR decodes the output of ServerCredentials.EncodeCBOR()
+// NB: We don't leak any information in error messages.
+func DecodeServerCredentialsCBOR(b []byte) (ServerCredentials, error) {
+	var sc ServerCredentials
+
+	err := decodeCBORMap(b, func(k uint64, r *cborReader) (err error) {
+		switch k {
+		case 1:
+			sc.Salt, err = r.bytes()
+		case 2:
+			sc.B, err = r.bytes()
+		case 3:
+			var s string
+			if s, err = r.text(); err == nil {
+				sc.KDF, err = parseKDF(s)
+			}
+		case 4:
+			sc.Group, err = r.text()
+		case 5:
+			sc.Signature, err = r.bytes()
+		case 6:
+			var s string
+			if s, err = r.text(); err == nil {
+				sc.Puzzle, err = parsePuzzle(s)
+			}
+		case 7:
+			if sc.X, err = r.text(); err == nil {
+				err = checkXDerivation(sc.X)
+			}
+		default:
+			err = fmt.Errorf("unknown key %d", k)
+		}
+		return err
+	})
+	if err != nil || sc.Salt == nil || sc.B == nil {
+		return sc, ErrBadServerKey
+	}
+	return sc, nil
+}
+
+// EncodeProofCBOR returns the CBOR encoding of a client or server proof
+// (see Client.Respond() and Server.CheckProof()).
+func EncodeProofCBOR(proof []byte) []byte {
+	var w cborWriter
+
+	w.bytes(proof)
+	return w.b
+}
+
+// DecodeProofCBOR decodes the output of EncodeProofCBOR()
+func DecodeProofCBOR(b []byte) ([]byte, error) {
+	r := &cborReader{b: b}
+	p, err := r.bytes()
+	if err == nil && len(r.b) > 0 {
+		err = fmt.Errorf("trailing data")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("srp: invalid proof: %s", err)
+	}
+	return p, nil
+}
+
+// EncodeCBOR returns the CBOR encoding of the verifier; it carries the
+// same information as the string returned by Encode().
+func (v *Verifier) EncodeCBOR() []byte {
+	return v.encodeCBOR(false)
+}
+
+// return the CBOR encoding of the verifier; a 'compact' encoding omits N and
+// g of built-in groups.
+func (v *Verifier) encodeCBOR(compact bool) []byte {
+	var w cborWriter
+
+	omit := compact && v.pf.id != ""
+
+	n := 7
+	if omit {
+		n -= 2
+	}
+	if v.kdf != nil {
+		n++
+	}
+	if v.idk != nil {
+		n++
+	}
+	if v.pf.id != "" {
+		n++
+	}
+	if v.xd != "" {
+		n++
+	}
+
+	w.head(cborMap, uint64(n))
+	w.uint(1)
+	w.uint(uint64(v.pf.n))
+	if !omit {
+		w.uint(2)
+		w.bytes(v.pf.N.Bytes())
+		w.uint(3)
+		w.bytes(v.pf.g.Bytes())
+	}
+	w.uint(4)
+	w.uint(uint64(v.h))
+	w.uint(5)
+	w.bytes(v.i)
+	w.uint(6)
+	w.bytes(v.s)
+	w.uint(7)
+	w.bytes(v.v)
+	if v.kdf != nil {
+		w.uint(8)
+		w.text(v.kdf.String())
+	}
+	if v.idk != nil {
+		w.uint(9)
+		w.text(identityBlinding)
+	}
+	if v.pf.id != "" {
+		w.uint(10)
+		w.text(v.pf.id)
+	}
+	if v.xd != "" {
+		w.uint(11)
+		w.text(v.xd)
+	}
+	return w.b
+}
+
+// DecodeVerifierCBOR decodes the output of Verifier.EncodeCBOR() into an SRP
+// environment and Verifier; it is the CBOR counterpart of MakeSRPVerifier().
+// N and g may be omitted if the verifier names a built-in group.
+func DecodeVerifierCBOR(b []byte, opts ...Option) (*SRP, *Verifier, error) {
+	var sz, h uint64
+	var N, g, i, s, v []byte
+	var kdf *KDF
+	var blind bool
+	var grp, xd string
+
+	err := decodeCBORMap(b, func(k uint64, r *cborReader) (err error) {
+		switch k {
+		case 1:
+			sz, err = r.uint()
+		case 2:
+			N, err = r.bytes()
+		case 3:
+			g, err = r.bytes()
+		case 4:
+			h, err = r.uint()
+		case 5:
+			i, err = r.bytes()
+		case 6:
+			s, err = r.bytes()
+		case 7:
+			v, err = r.bytes()
+		case 8:
+			var ks string
+			if ks, err = r.text(); err == nil {
+				kdf, err = parseKDF(ks)
+			}
+		case 9:
+			var ks string
+			if ks, err = r.text(); err == nil && ks != identityBlinding {
+				err = fmt.Errorf("unknown identity blinding %q", ks)
+			}
+			blind = true
+		case 10:
+			grp, err = r.text()
+		case 11:
+			if xd, err = r.text(); err == nil {
+				err = checkXDerivation(xd)
+			}
+		default:
+			err = fmt.Errorf("unknown key %d", k)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("verifier: %s", err)
+	}
+
+	// a compact encoding omits N and g of built-in groups
+	named := N == nil && g == nil && grp != ""
+
+	switch {
+	case sz == 0 || sz > 1<<16:
+		return nil, nil, fmt.Errorf("verifier: malformed field size %d", sz)
+	case !named && (len(N) == 0 || len(g) == 0):
+		return nil, nil, fmt.Errorf("verifier: missing prime field")
+	case h == 0 || h > 0xffff:
+		return nil, nil, fmt.Errorf("verifier: malformed hash type %d", h)
+	case i == nil || s == nil || v == nil:
+		return nil, nil, fmt.Errorf("verifier: missing fields")
+	}
+
+	var pf *primeField
+	if named {
+		if pf = groupByID(grp); pf == nil || pf.n != int(sz) {
+			return nil, nil, fmt.Errorf("verifier: unknown group %s", grp)
+		}
+	} else {
+		pf, err = resolveGroup(grp, &primeField{
+			n: int(sz),
+			N: big.NewInt(0).SetBytes(N),
+			g: big.NewInt(0).SetBytes(g),
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return makeSRPVerifier(pf, crypto.Hash(h), i, s, v, kdf, xd, blind, opts)
+}
+
+// cborWriter accumulates CBOR encoded items
+type cborWriter struct {
+	b []byte
+}
+
+// write the head of an item of major type 'm' with argument 'n'
+func (w *cborWriter) head(m byte, n uint64) {
+	m <<= 5
+	switch {
+	case n < 24:
+		w.b = append(w.b, m|byte(n))
+	case n <= 0xff:
+		w.b = append(w.b, m|24, byte(n))
+	case n <= 0xffff:
+		w.b = append(w.b, m|25, byte(n>>8), byte(n))
+	case n <= 0xffffffff:
+		w.b = append(w.b, m|26, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	default:
+		w.b = append(w.b, m|27, byte(n>>56), byte(n>>48), byte(n>>40), byte(n>>32),
+			byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	}
+}
+
+func (w *cborWriter) uint(n uint64) {
+	w.head(cborUint, n)
+}
+
+func (w *cborWriter) bytes(b []byte) {
+	w.head(cborBytes, uint64(len(b)))
+	w.b = append(w.b, b...)
+}
+
+func (w *cborWriter) text(s string) {
+	w.head(cborText, uint64(len(s)))
+	w.b = append(w.b, s...)
+}
+
+// cborReader consumes CBOR encoded items from a buffer
+type cborReader struct {
+	b []byte
+}
+
+// read the head of the next item; it must be of major type 'm'
+func (r *cborReader) head(m byte) (uint64, error) {
+	if len(r.b) == 0 {
+		return 0, fmt.Errorf("short buffer")
+	}
+
+	ib := r.b[0]
+	if ib>>5 != m {
+		return 0, fmt.Errorf("exp major type %d, saw %d", m, ib>>5)
+	}
+
+	var n uint64
+	var w int
+	switch ai := ib & 0x1f; {
+	case ai < 24:
+		n = uint64(ai)
+	case ai == 24:
+		w = 1
+	case ai == 25:
+		w = 2
+	case ai == 26:
+		w = 4
+	case ai == 27:
+		w = 8
+	default:
+		return 0, fmt.Errorf("unsupported additional info %d", ai)
+	}
+
+	if len(r.b) < 1+w {
+		return 0, fmt.Errorf("short buffer")
+	}
+	for _, c := range r.b[1 : 1+w] {
+		n = n<<8 | uint64(c)
+	}
+	r.b = r.b[1+w:]
+	return n, nil
+}
+
+func (r *cborReader) uint() (uint64, error) {
+	return r.head(cborUint)
+}
+
+func (r *cborReader) bytes() ([]byte, error) {
+	return r.str(cborBytes)
+}
+
+func (r *cborReader) text() (string, error) {
+	b, err := r.str(cborText)
+	return string(b), err
+}
+
+// read a byte or text string
+func (r *cborReader) str(m byte) ([]byte, error) {
+	n, err := r.head(m)
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(len(r.b)) {
+		return nil, fmt.Errorf("short buffer")
+	}
+
+	b := make([]byte, n)
+	copy(b, r.b)
+	r.b = r.b[n:]
+	return b, nil
+}
+
+// decode the map in 'b' calling 'fp' for each key; keys must be unique
+// unsigned integers and there must be no trailing data.
+func decodeCBORMap(b []byte, fp func(k uint64, r *cborReader) error) error {
+	r := &cborReader{b: b}
+	n, err := r.head(cborMap)
+	if err != nil {
+		return err
+	}
+	if n > uint64(len(r.b)) {
+		return fmt.Errorf("short buffer")
+	}
+
+	seen := make(map[uint64]bool)
+	for ; n > 0; n-- {
+		k, err := r.uint()
+		if err != nil {
+			return err
+		}
+		if seen[k] {
+			return fmt.Errorf("duplicate key %d", k)
+		}
+		seen[k] = true
+
+		if err = fp(k, r); err != nil {
+			return err
+		}
+	}
+
+	if len(r.b) > 0 {
+		return fmt.Errorf("trailing data")
+	}
+	return nil
+}
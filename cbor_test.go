@@ -0,0 +1,93 @@
+// self test for CBOR encoding
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math/big"
+	"testing"
+)
+
+func TestCBOR(t *testing.T) {
+	skipFIPS(t)
+
+	assert := newAsserter(t)
+
+	user := []byte("user")
+	pass := []byte("pass")
+
+	s, err := New(2048, WithKDF(testKDFs[0]))
+	assert(err == nil, "New: %s", err)
+
+	v, err := s.Verifier(user, pass, nil)
+	assert(err == nil, "Verifier: %s", err)
+
+	_, vs := v.Encode()
+	ss, sv, err := DecodeVerifierCBOR(v.EncodeCBOR())
+	assert(err == nil, "DecodeVerifierCBOR: %s", err)
+	_, vs2 := sv.Encode()
+	assert(vs == vs2, "verifier mismatch:\n%s\n%s", vs, vs2)
+
+	c, err := s.NewClient(user, pass)
+	assert(err == nil, "NewClient: %s", err)
+
+	cc := c.Hello()
+	cc2, err := DecodeClientCredentialsCBOR(cc.EncodeCBOR())
+	assert(err == nil, "DecodeClientCredentialsCBOR: %s", err)
+	assert(bytes.Equal(cc.IdentityHash, cc2.IdentityHash) && bytes.Equal(cc.A, cc2.A), "client creds mismatch")
+
+	srv, err := ss.NewServer(sv, big.NewInt(0).SetBytes(cc2.A))
+	assert(err == nil, "NewServer: %s", err)
+
+	sc := srv.Challenge()
+	sc2, err := DecodeServerCredentialsCBOR(sc.EncodeCBOR())
+	assert(err == nil, "DecodeServerCredentialsCBOR: %s", err)
+	assert(sc2.encode() == sc.encode(), "server creds mismatch")
+
+	m, err := c.Respond(sc2)
+	assert(err == nil, "Respond: %s", err)
+
+	m2, err := DecodeProofCBOR(EncodeProofCBOR(m))
+	assert(err == nil, "DecodeProofCBOR: %s", err)
+
+	proof, ok := srv.CheckProof(m2)
+	assert(ok, "server: bad client proof")
+	assert(c.CheckProof(proof), "client: bad server proof")
+}
+
+func TestCBORMalformed(t *testing.T) {
+	assert := newAsserter(t)
+
+	bad := []string{
+		"",
+		"a0",             // empty map
+		"a1014100",       // missing A
+		"a201410001410",  // odd
+		"a2014100014100", // duplicate key
+		"a20141000241",   // short
+		"a2014100024100ff",
+		"a2014100034100", // unknown key
+		"a1f7",
+		"bf",
+	}
+
+	for _, s := range bad {
+		b, _ := hex.DecodeString(s)
+		_, err := DecodeClientCredentialsCBOR(b)
+		assert(err != nil, "decoded bad message %q", s)
+	}
+
+	// known answer: {1: h'01', 2: h'0203'}
+	b, _ := hex.DecodeString("a2014101024202" + "03")
+	cc, err := DecodeClientCredentialsCBOR(b)
+	assert(err == nil, "decode: %s", err)
+	assert(bytes.Equal(cc.EncodeCBOR(), b), "re-encoding mismatch")
+
+	_, err = DecodeProofCBOR([]byte{0x41, 0x01, 0x00})
+	assert(err != nil, "decoded proof with trailing data")
+}
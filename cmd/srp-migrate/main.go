@@ -0,0 +1,133 @@
+// main.go - re-encode stored SRP verifiers
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+// srp-migrate re-encodes the verifiers of a credential store dump into
+// another format, e.g., from the text format of Verifier.Encode() to the
+// compact binary format. It reads one verifier per line from stdin and
+// writes one per line to stdout:
+//
+//	[identity <TAB>] verifier
+//
+// The identity column is optional on input and always written on output.
+// Text verifiers are written as is; binary ones are base64 encoded. Each
+// verifier is decoded again after encoding and the tool stops at the first
+// one that doesn't survive the round trip.
+//
+// Only the encoding changes: new salt sizes or hash functions need the
+// users' passwords. The number of verifiers that should be replaced at the
+// next login (see Verifier.NeedsUpgrade()) is reported on stderr.
+//
+// Usage:
+//
+//	srp-migrate [-from text] [-to compact-deflate] < dump > migrated
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/tomsons/go-srp"
+)
+
+func main() {
+	from := flag.String("from", "text", "format of the input: text, cbor, compact or compact-deflate")
+	to := flag.String("to", "compact-deflate", "format of the output: text, cbor, compact or compact-deflate")
+	idk := flag.String("identity-key", "", "hex `key` that blinded the identities (see srp.WithIdentityKey)")
+	flag.Parse()
+
+	ff, err := srp.ParseVerifierFormat(*from)
+	if err != nil {
+		die("%s", err)
+	}
+	tf, err := srp.ParseVerifierFormat(*to)
+	if err != nil {
+		die("%s", err)
+	}
+
+	var opts []srp.Option
+	if *idk != "" {
+		k, err := hex.DecodeString(*idk)
+		if err != nil {
+			die("invalid identity key: %s", err)
+		}
+		opts = append(opts, srp.WithIdentityKey(k))
+	}
+
+	n, weak, err := migrate(os.Stdin, os.Stdout, ff, tf, opts)
+	if err != nil {
+		die("%s", err)
+	}
+	fmt.Fprintf(os.Stderr, "srp-migrate: %d verifiers migrated; %d need upgrading at the next login\n", n, weak)
+}
+
+// migrate the verifiers read from 'r' and write them to 'w'; return the
+// number of verifiers and how many of them need upgrading
+func migrate(r io.Reader, w io.Writer, from, to srp.VerifierFormat, opts []srp.Option) (int, int, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1<<20)
+
+	bw := bufio.NewWriter(w)
+	n, weak := 0, 0
+	for line := 1; sc.Scan(); line++ {
+		s := strings.TrimSpace(sc.Text())
+		if s == "" || s[0] == '#' {
+			continue
+		}
+
+		if i := strings.LastIndexByte(s, '\t'); i >= 0 {
+			s = s[i+1:]
+		}
+
+		b, err := decode(s, from)
+		if err != nil {
+			return n, weak, fmt.Errorf("line %d: %s", line, err)
+		}
+
+		out, v, err := srp.MigrateVerifier(b, from, to, opts...)
+		if err != nil {
+			return n, weak, fmt.Errorf("line %d: %s", line, err)
+		}
+
+		ih, _ := v.Encode()
+		fmt.Fprintf(bw, "%s\t%s\n", ih, encode(out, to))
+
+		n++
+		if v.NeedsUpgrade() {
+			weak++
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return n, weak, err
+	}
+	return n, weak, bw.Flush()
+}
+
+// decode a verifier in format 'f' from its form in a line
+func decode(s string, f srp.VerifierFormat) ([]byte, error) {
+	if f == srp.VerifierText {
+		return []byte(s), nil
+	}
+	return base64.StdEncoding.DecodeString(s)
+}
+
+// return the form of a verifier in format 'f' in a line
+func encode(b []byte, f srp.VerifierFormat) string {
+	if f == srp.VerifierText {
+		return string(b)
+	}
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+func die(f string, v ...interface{}) {
+	fmt.Fprintf(os.Stderr, "srp-migrate: "+f+"\n", v...)
+	os.Exit(1)
+}
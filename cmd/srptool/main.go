@@ -0,0 +1,283 @@
+// main.go - SRP administration tool
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+// srptool collects administrative tasks for SRP deployments as
+// subcommands:
+//
+//	srptool check-group [-g 2] [-rounds 64] N
+//	srptool check-group -id rfc5054-3072
+//
+// check-group checks that N (in hex; "-" reads it from stdin, white space
+// is ignored) is a safe prime and reports the order of g, so that a custom
+// group can be validated before it is registered with srp.NewWithGroup()
+// or pinned with srp.AllowGroups(). Groups equal to a published group are
+// named. The exit status is 1 if the group is unfit.
+//
+//	srptool vectors [-seed s] [-group id] [-hash name] [-scheme name] > vectors.json
+//	srptool check-vectors vectors.json
+//
+// vectors prints test vectors of complete handshakes (see
+// srp.GenerateVectors()) as JSON for every built-in group, hash function
+// and proof scheme, or those selected with the flags (comma separated);
+// the same seed always yields the same vectors. check-vectors replays a
+// file of vectors ("-" reads stdin) and reports the first difference of
+// each vector; the exit status is 1 if one differs.
+package main
+
+import (
+	"crypto"
+	_ "crypto/md5"
+	_ "crypto/sha1"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/tomsons/go-srp"
+)
+
+// a subcommand; run returns the exit status
+type command struct {
+	name  string
+	usage string
+	run   func(args []string) int
+}
+
+var commands = []command{
+	{"check-group", "check that a prime field is a safe prime and a generator", checkGroup},
+	{"vectors", "print test vectors of handshakes as JSON", vectors},
+	{"check-vectors", "replay test vectors printed by vectors", checkVectors},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	for _, c := range commands {
+		if c.name == os.Args[1] {
+			os.Exit(c.run(os.Args[2:]))
+		}
+	}
+	usage()
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: srptool command [options]\n\nCommands:\n")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-12s %s\n", c.name, c.usage)
+	}
+	os.Exit(2)
+}
+
+func checkGroup(args []string) int {
+	fs := flag.NewFlagSet("check-group", flag.ExitOnError)
+	g := fs.Int64("g", 2, "the generator")
+	id := fs.String("id", "", "check the built-in group `id` instead of N")
+	rounds := fs.Int("rounds", srp.DefaultPrimeRounds, "Miller-Rabin rounds")
+	fs.Parse(args)
+
+	var N, G *big.Int
+	switch {
+	case *id != "":
+		for _, gi := range srp.SupportedGroups() {
+			if gi.ID == *id {
+				N, G = gi.N, gi.G
+			}
+		}
+		if N == nil {
+			die("unknown group %s", *id)
+		}
+
+	case fs.NArg() == 1:
+		N = readPrime(fs.Arg(0))
+		G = big.NewInt(*g)
+
+	default:
+		fs.Usage()
+		return 2
+	}
+
+	r := srp.AnalyzeGroup(N, G, *rounds)
+
+	fmt.Printf("bits:           %d\n", r.Bits)
+	fmt.Printf("fingerprint:    %s\n", hex.EncodeToString(r.Fingerprint))
+	if r.ID != "" {
+		fmt.Printf("published as:   %s\n", r.ID)
+	} else {
+		fmt.Printf("published as:   - (custom; error probability <= 4^-%d)\n", r.Rounds)
+	}
+	fmt.Printf("N prime:        %s\n", yes(r.Prime))
+	fmt.Printf("N safe prime:   %s\n", yes(r.SafePrime))
+	if r.Order != "" {
+		fmt.Printf("order of g:     %s\n", r.Order)
+		fmt.Printf("g is a square:  %s\n", yes(r.QuadraticResidue))
+	}
+	fmt.Printf("strong:         %s (minimum %d bits)\n", yes(r.Strong), srp.MinimumBits)
+
+	if err := r.Err(); err != nil {
+		fmt.Printf("verdict:        unfit: %s\n", strings.TrimPrefix(err.Error(), "srp: "))
+		return 1
+	}
+	fmt.Printf("verdict:        ok\n")
+	return 0
+}
+
+func vectors(args []string) int {
+	fs := flag.NewFlagSet("vectors", flag.ExitOnError)
+	seed := fs.String("seed", "go-srp test vectors", "the `seed` of the random values")
+	group := fs.String("group", "", "only the groups `ids`")
+	hash := fs.String("hash", "", "only the hash functions `names`, e.g., SHA-256")
+	scheme := fs.String("scheme", "", "only the proof schemes `names`, e.g., legacy")
+	fs.Parse(args)
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return 2
+	}
+
+	cfg := srp.VectorConfig{
+		Seed:   []byte(*seed),
+		Groups: list(*group),
+	}
+	for _, name := range list(*hash) {
+		h := hashByName(name)
+		if h == 0 {
+			die("unknown hash %s", name)
+		}
+		cfg.Hashes = append(cfg.Hashes, h)
+	}
+	for _, name := range list(*scheme) {
+		p := schemeByName(name)
+		if p == nil {
+			die("unknown proof scheme %s", name)
+		}
+		cfg.Schemes = append(cfg.Schemes, p)
+	}
+
+	tv, err := srp.GenerateVectors(cfg)
+	if err != nil {
+		die("%s", err)
+	}
+
+	b, err := json.MarshalIndent(tv, "", "  ")
+	if err != nil {
+		die("%s", err)
+	}
+	fmt.Printf("%s\n", b)
+	return 0
+}
+
+func checkVectors(args []string) int {
+	fs := flag.NewFlagSet("check-vectors", flag.ExitOnError)
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	var b []byte
+	var err error
+	if fn := fs.Arg(0); fn == "-" {
+		b, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		b, err = ioutil.ReadFile(fn)
+	}
+	if err != nil {
+		die("%s", err)
+	}
+
+	var tv []srp.TestVector
+	if err := json.Unmarshal(b, &tv); err != nil {
+		die("%s", err)
+	}
+
+	bad := 0
+	for i := range tv {
+		if err := tv[i].Check(); err != nil {
+			fmt.Printf("%s\n", strings.TrimPrefix(err.Error(), "srp: "))
+			bad++
+		}
+	}
+	fmt.Printf("%d vectors, %d failed\n", len(tv), bad)
+	if bad > 0 {
+		return 1
+	}
+	return 0
+}
+
+// return the hash function of this program named 'name' or 0
+func hashByName(name string) crypto.Hash {
+	for _, h := range srp.AvailableHashes() {
+		if strings.EqualFold(hashName(h), name) {
+			return h
+		}
+	}
+	return 0
+}
+
+// return the name of 'h' as in the vectors
+func hashName(h crypto.Hash) string {
+	if h.Available() {
+		return h.String()
+	}
+	return fmt.Sprintf("%d", h)
+}
+
+// return the proof scheme named 'name' or nil
+func schemeByName(name string) srp.ProofScheme {
+	for _, p := range []srp.ProofScheme{srp.ProofLegacy, srp.ProofLegacyPadded, srp.ProofRFC5054, srp.ProofRFC5054Padded} {
+		if p.Name() == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// split the comma separated list 's'; nil if empty
+func list(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+// parse the hex prime 's' or read it from stdin if it is "-"
+func readPrime(s string) *big.Int {
+	if s == "-" {
+		b, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			die("%s", err)
+		}
+		s = string(b)
+	}
+
+	s = strings.Join(strings.Fields(s), "")
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
+
+	N, ok := big.NewInt(0).SetString(s, 16)
+	if !ok {
+		die("N is not a hex number")
+	}
+	return N
+}
+
+func yes(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+func die(f string, v ...interface{}) {
+	fmt.Fprintf(os.Stderr, "srptool: "+f+"\n", v...)
+	os.Exit(1)
+}
@@ -0,0 +1,296 @@
+// main.go - manage a flat-file store of SRP verifiers
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+// srpverify maintains the users of a flat-file verifier store (see package
+// srpfile) the way htpasswd maintains a password file:
+//
+//	srpverify add    -f users.srp [-bits 2048] [-key-len 0] user
+//	srpverify passwd -f users.srp [-bits 2048] [-key-len 0] user
+//	srpverify delete -f users.srp [-key-len 0] user
+//	srpverify verify -f users.srp [-bits 2048] [-key-len 0] user
+//	srpverify list   -f users.srp
+//
+// add creates a user and fails if it exists; passwd rotates the verifier
+// of an existing user; verify runs a handshake against the stored verifier
+// to check a password. Passwords are read from the terminal without echo
+// (and asked twice when setting them) or, if stdin isn't a terminal, as a
+// line from stdin for scripted use. Identities aren't stored in the clear:
+// list prints storage keys. -key-len must be the same for all commands on
+// a store (see srp.WithStorageKeyLen()).
+//
+// The tool is pure Go and cross-compiles, e.g.:
+//
+//	GOOS=windows GOARCH=amd64 go build ./cmd/srpverify
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/tomsons/go-srp"
+	"github.com/tomsons/go-srp/srpfile"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// a subcommand; run returns the exit status
+type command struct {
+	name  string
+	usage string
+	run   func(args []string) int
+}
+
+var commands = []command{
+	{"add", "add a user", add},
+	{"passwd", "change the password of a user", passwd},
+	{"delete", "delete a user", remove},
+	{"verify", "check the password of a user", verify},
+	{"list", "list the storage keys of the users", list},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	for _, c := range commands {
+		if c.name == os.Args[1] {
+			os.Exit(c.run(os.Args[2:]))
+		}
+	}
+	usage()
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: srpverify command -f file [options] [user]\n\nCommands:\n")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-8s %s\n", c.name, c.usage)
+	}
+	os.Exit(2)
+}
+
+// common flags of the subcommands
+type flags struct {
+	fs     *flag.FlagSet
+	file   *string
+	bits   *int
+	keyLen *int
+}
+
+func newFlags(name string) *flags {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	return &flags{
+		fs:     fs,
+		file:   fs.String("f", "", "the verifier store `file`"),
+		bits:   fs.Int("bits", 2048, "the size of the prime field"),
+		keyLen: fs.Int("key-len", 0, "shorten storage keys to `n` bytes"),
+	}
+}
+
+// parse 'args' and return the store and the user (if 'user' is true)
+func (f *flags) parse(args []string, user bool) (*srpfile.Store, string) {
+	f.fs.Parse(args)
+
+	n := 0
+	if user {
+		n = 1
+	}
+	if *f.file == "" || f.fs.NArg() != n {
+		f.fs.Usage()
+		os.Exit(2)
+	}
+	return srpfile.Open(*f.file), f.fs.Arg(0)
+}
+
+// return the options of the environment
+func (f *flags) opts() []srp.Option {
+	var opts []srp.Option
+	if *f.keyLen > 0 {
+		opts = append(opts, srp.WithStorageKeyLen(*f.keyLen))
+	}
+	return opts
+}
+
+// return the environment of new verifiers and clients
+func (f *flags) env() *srp.SRP {
+	s, err := srp.New(*f.bits, f.opts()...)
+	if err != nil {
+		die("%s", err)
+	}
+	return s
+}
+
+func add(args []string) int {
+	return setPassword("add", args, false)
+}
+
+func passwd(args []string) int {
+	return setPassword("passwd", args, true)
+}
+
+// make a new verifier for a user; 'exists' says whether the user must
+// already exist (passwd) or must not (add)
+func setPassword(name string, args []string, exists bool) int {
+	f := newFlags(name)
+	st, user := f.parse(args, true)
+	s := f.env()
+
+	p := readPassword("Password: ", true)
+	defer wipe(p)
+
+	v, err := s.Verifier([]byte(user), p, nil)
+	if err != nil {
+		die("%s", err)
+	}
+	_, vh := v.Encode()
+	key := v.StorageKey()
+
+	err = st.Update(func(m map[string]string) error {
+		_, ok := m[key]
+		switch {
+		case ok && !exists:
+			return fmt.Errorf("user %s exists", user)
+		case !ok && exists:
+			return fmt.Errorf("no user %s", user)
+		}
+		m[key] = vh
+		return nil
+	})
+	if err != nil {
+		die("%s", err)
+	}
+	return 0
+}
+
+func remove(args []string) int {
+	f := newFlags("delete")
+	st, user := f.parse(args, true)
+
+	if err := st.Delete(storageKey(f, user)); err != nil {
+		if err == srp.ErrNoVerifier {
+			die("no user %s", user)
+		}
+		die("%s", err)
+	}
+	return 0
+}
+
+func verify(args []string) int {
+	f := newFlags("verify")
+	st, user := f.parse(args, true)
+	s := f.env()
+
+	lookup, err := srp.StoreLookup(st, f.opts()...)
+	if err != nil {
+		die("%s", err)
+	}
+
+	p := readPassword("Password: ", false)
+	defer wipe(p)
+
+	c, err := s.NewClient([]byte(user), p)
+	if err != nil {
+		die("%s", err)
+	}
+	hello := c.Hello()
+
+	vs, v, err := lookup(hello.IdentityHash)
+	if err != nil {
+		if err == srp.ErrNoVerifier {
+			die("no user %s", user)
+		}
+		die("%s", err)
+	}
+	A, err := vs.ParsePublicKey(hello.A)
+	if err != nil {
+		die("%s", err)
+	}
+	srv, err := vs.NewServerFor(hello.IdentityHash, v, A)
+	if err != nil {
+		die("%s", err)
+	}
+
+	if m, err := c.Respond(srv.Challenge()); err == nil {
+		if proof, ok := srv.CheckProof(m); ok && c.CheckProof(proof) {
+			fmt.Printf("%s: password ok\n", user)
+			return 0
+		}
+	}
+	fmt.Printf("%s: wrong password\n", user)
+	return 1
+}
+
+func list(args []string) int {
+	f := newFlags("list")
+	st, _ := f.parse(args, false)
+
+	keys, err := st.Keys()
+	if err != nil {
+		die("%s", err)
+	}
+	for _, k := range keys {
+		fmt.Println(k)
+	}
+	return 0
+}
+
+// return the storage key of 'user' without a password
+func storageKey(f *flags, user string) string {
+	s := f.env()
+	c, err := s.NewClient([]byte(user), []byte{})
+	if err != nil {
+		die("%s", err)
+	}
+	return s.StorageKey(c.Hello().IdentityHash)
+}
+
+// read a password from the terminal without echo, twice if 'confirm' is
+// true; read a line from stdin if it isn't a terminal
+func readPassword(prompt string, confirm bool) []byte {
+	fd := int(os.Stdin.Fd())
+	if !terminal.IsTerminal(fd) {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			die("can't read password: %s", err)
+		}
+		return []byte(strings.TrimRight(line, "\r\n"))
+	}
+
+	p := ask(fd, prompt)
+	if confirm {
+		q := ask(fd, "Retype password: ")
+		defer wipe(q)
+		if !bytes.Equal(p, q) {
+			wipe(p)
+			die("passwords don't match")
+		}
+	}
+	return p
+}
+
+func ask(fd int, prompt string) []byte {
+	fmt.Fprint(os.Stderr, prompt)
+	p, err := terminal.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		die("can't read password: %s", err)
+	}
+	return p
+}
+
+func wipe(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+func die(f string, v ...interface{}) {
+	fmt.Fprintf(os.Stderr, "srpverify: "+f+"\n", v...)
+	os.Exit(1)
+}
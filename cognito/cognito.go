@@ -0,0 +1,234 @@
+// cognito.go - SRP client for Amazon Cognito user pools
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+// Package cognito authenticates users against Amazon Cognito user pools
+// with the USER_SRP_AUTH flow. It computes the parameters of the
+// InitiateAuth and RespondToAuthChallenge calls; making the calls is left
+// to the application's AWS client:
+//
+//	c, err := cognito.NewClient(cfg, username, password)
+//	out, err := idp.InitiateAuth(... AuthFlow: "USER_SRP_AUTH",
+//		AuthParameters: c.AuthParameters() ...)
+//	resp, err := c.PasswordVerifier(out.ChallengeParameters)
+//	tokens, err := idp.RespondToAuthChallenge(... ChallengeName: "PASSWORD_VERIFIER",
+//		ChallengeResponses: resp ...)
+//
+// Cognito's SRP differs from RFC 5054 and from package srp: the group is
+// the 3072-bit prime of RFC 3526 with g = 2, numbers are hashed in their
+// minimal two's complement form (a leading zero byte if the high bit is
+// set), the user pool name is part of the password hash, and instead of a
+// proof M the client signs the server's secret block and a timestamp with
+// a key derived from S:
+//
+//	k   = SHA256(N | g)
+//	u   = SHA256(A | B)
+//	x   = SHA256(s | SHA256(pool | user ":" password))
+//	S   = (B - k g^x) ^ (a + u x)  mod N
+//	key = HKDF-SHA256(S, salt = u, "Caldera Derived Key")[:16]
+//	sig = HMAC-SHA256(key, pool | user | secret block | timestamp)
+package cognito
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/tomsons/go-srp"
+	"golang.org/x/crypto/hkdf"
+)
+
+// the timestamp format of Cognito: the day isn't padded
+const timestampFormat = "Mon Jan 2 15:04:05 UTC 2006"
+
+// size of the secret ephemeral a in bytes
+const ephemeralLen = 128
+
+var hkdfInfo = []byte("Caldera Derived Key")
+
+// Config describes the user pool and app client
+type Config struct {
+	PoolID       string // e.g., "us-east-1_AbCdEf123"
+	ClientID     string // the app client id
+	ClientSecret string // the app client secret, if the client has one
+
+	Now  func() time.Time // nil => time.Now
+	Rand io.Reader        // nil => crypto/rand
+}
+
+// Client runs the client side of USER_SRP_AUTH for one login
+type Client struct {
+	cfg      Config
+	pool     string // the pool name: the part of the pool id after '_'
+	username string
+	password string
+
+	a, A *big.Int
+}
+
+// the group of Cognito: RFC 3526 3072-bit prime with g = 2
+var (
+	bigN *big.Int
+	bigG = big.NewInt(2)
+	bigK *big.Int
+)
+
+func init() {
+	for _, gi := range srp.SupportedGroups() {
+		if gi.ID == "rfc5054-3072" {
+			bigN = gi.N
+		}
+	}
+	bigK = hashInt(padded(bigN), padded(bigG))
+}
+
+// NewClient returns a client that logs in 'username' with 'password'
+func NewClient(cfg Config, username, password string) (*Client, error) {
+	i := strings.IndexByte(cfg.PoolID, '_')
+	if i < 0 || i == len(cfg.PoolID)-1 {
+		return nil, fmt.Errorf("cognito: invalid pool id %q", cfg.PoolID)
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	if cfg.Rand == nil {
+		cfg.Rand = rand.Reader
+	}
+
+	c := &Client{
+		cfg:      cfg,
+		pool:     cfg.PoolID[i+1:],
+		username: username,
+		password: password,
+	}
+
+	b := make([]byte, ephemeralLen)
+	for {
+		if _, err := io.ReadFull(cfg.Rand, b); err != nil {
+			return nil, fmt.Errorf("cognito: random: %s", err)
+		}
+		c.a = big.NewInt(0).SetBytes(b)
+		c.A = big.NewInt(0).Exp(bigG, c.a, bigN)
+		if c.A.Sign() != 0 {
+			break
+		}
+	}
+	return c, nil
+}
+
+// AuthParameters returns the AuthParameters of InitiateAuth
+func (c *Client) AuthParameters() map[string]string {
+	p := map[string]string{
+		"USERNAME": c.username,
+		"SRP_A":    c.A.Text(16),
+	}
+	if c.cfg.ClientSecret != "" {
+		p["SECRET_HASH"] = c.secretHash(c.username)
+	}
+	return p
+}
+
+// PasswordVerifier returns the ChallengeResponses of the PASSWORD_VERIFIER
+// challenge with ChallengeParameters 'params'
+func (c *Client) PasswordVerifier(params map[string]string) (map[string]string, error) {
+	user := params["USER_ID_FOR_SRP"]
+	block := params["SECRET_BLOCK"]
+	if user == "" || block == "" {
+		return nil, fmt.Errorf("cognito: incomplete challenge parameters")
+	}
+
+	B, ok := big.NewInt(0).SetString(params["SRP_B"], 16)
+	if !ok || big.NewInt(0).Mod(B, bigN).Sign() == 0 {
+		return nil, fmt.Errorf("cognito: invalid SRP_B")
+	}
+	salt, ok := big.NewInt(0).SetString(params["SALT"], 16)
+	if !ok {
+		return nil, fmt.Errorf("cognito: invalid SALT")
+	}
+	sb, err := base64.StdEncoding.DecodeString(block)
+	if err != nil {
+		return nil, fmt.Errorf("cognito: invalid SECRET_BLOCK")
+	}
+
+	key, err := c.passwordKey(user, B, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	ts := c.cfg.Now().UTC().Format(timestampFormat)
+	m := hmac.New(sha256.New, key)
+	m.Write([]byte(c.pool))
+	m.Write([]byte(user))
+	m.Write(sb)
+	m.Write([]byte(ts))
+
+	r := map[string]string{
+		"USERNAME":                    user,
+		"PASSWORD_CLAIM_SECRET_BLOCK": block,
+		"PASSWORD_CLAIM_SIGNATURE":    base64.StdEncoding.EncodeToString(m.Sum(nil)),
+		"TIMESTAMP":                   ts,
+	}
+	if c.cfg.ClientSecret != "" {
+		r["SECRET_HASH"] = c.secretHash(user)
+	}
+	return r, nil
+}
+
+// return the key that signs the secret block
+func (c *Client) passwordKey(user string, B, salt *big.Int) ([]byte, error) {
+	u := hashInt(padded(c.A), padded(B))
+	if u.Sign() == 0 {
+		return nil, fmt.Errorf("cognito: invalid SRP_B")
+	}
+
+	h := sha256.Sum256([]byte(c.pool + user + ":" + c.password))
+	x := hashInt(padded(salt), h[:])
+
+	// S = (B - k g^x) ^ (a + u x) mod N
+	t := big.NewInt(0).Exp(bigG, x, bigN)
+	t.Mul(t, bigK)
+	t.Sub(B, t)
+	t.Mod(t, bigN)
+	e := big.NewInt(0).Mul(u, x)
+	e.Add(e, c.a)
+	S := t.Exp(t, e, bigN)
+
+	key := make([]byte, 16)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, padded(S), padded(u), hkdfInfo), key); err != nil {
+		return nil, fmt.Errorf("cognito: hkdf: %s", err)
+	}
+	return key, nil
+}
+
+// return the SECRET_HASH of 'user' for app clients with a secret
+func (c *Client) secretHash(user string) string {
+	m := hmac.New(sha256.New, []byte(c.cfg.ClientSecret))
+	m.Write([]byte(user + c.cfg.ClientID))
+	return base64.StdEncoding.EncodeToString(m.Sum(nil))
+}
+
+// return 'x' in its minimal two's complement form, as hashed by Cognito
+func padded(x *big.Int) []byte {
+	b := x.Bytes()
+	if len(b) == 0 || b[0]&0x80 != 0 {
+		b = append([]byte{0}, b...)
+	}
+	return b
+}
+
+// return SHA256(a...) as a number
+func hashInt(a ...[]byte) *big.Int {
+	h := sha256.New()
+	for _, b := range a {
+		h.Write(b)
+	}
+	return big.NewInt(0).SetBytes(h.Sum(nil))
+}
@@ -0,0 +1,181 @@
+// self test for the Cognito client
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package cognito
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// a user pool as Cognito runs it
+type pool struct {
+	name string
+	salt *big.Int
+	v    *big.Int
+}
+
+func newPool(t *testing.T, name, user, password string) *pool {
+	salt := randInt(t, 16)
+	h := sha256.Sum256([]byte(name + user + ":" + password))
+	x := hashInt(padded(salt), h[:])
+	return &pool{name: name, salt: salt, v: big.NewInt(0).Exp(bigG, x, bigN)}
+}
+
+// run the server side of a login and return true if the signature is valid
+func (p *pool) login(t *testing.T, c *Client, user string) bool {
+	A, ok := big.NewInt(0).SetString(c.AuthParameters()["SRP_A"], 16)
+	if !ok {
+		t.Fatalf("invalid SRP_A")
+	}
+
+	b := randInt(t, 128)
+	B := big.NewInt(0).Exp(bigG, b, bigN)
+	B.Add(B, big.NewInt(0).Mul(bigK, p.v))
+	B.Mod(B, bigN)
+
+	block := make([]byte, 64)
+	rand.Read(block)
+	sb := base64.StdEncoding.EncodeToString(block)
+
+	resp, err := c.PasswordVerifier(map[string]string{
+		"USER_ID_FOR_SRP": user,
+		"SRP_B":           B.Text(16),
+		"SALT":            p.salt.Text(16),
+		"SECRET_BLOCK":    sb,
+	})
+	if err != nil {
+		t.Fatalf("PasswordVerifier: %s", err)
+	}
+
+	// S = (A v^u) ^ b
+	u := hashInt(padded(A), padded(B))
+	S := big.NewInt(0).Exp(p.v, u, bigN)
+	S.Mul(S, A)
+	S.Exp(S, b, bigN)
+
+	key := make([]byte, 16)
+	io.ReadFull(hkdf.New(sha256.New, padded(S), padded(u), hkdfInfo), key)
+
+	m := hmac.New(sha256.New, key)
+	m.Write([]byte(p.name + user))
+	m.Write(block)
+	m.Write([]byte(resp["TIMESTAMP"]))
+
+	sig, _ := base64.StdEncoding.DecodeString(resp["PASSWORD_CLAIM_SIGNATURE"])
+	return resp["PASSWORD_CLAIM_SECRET_BLOCK"] == sb && resp["USERNAME"] == user && hmac.Equal(sig, m.Sum(nil))
+}
+
+func randInt(t *testing.T, n int) *big.Int {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatalf("rand: %s", err)
+	}
+	return big.NewInt(0).SetBytes(b)
+}
+
+func TestLogin(t *testing.T) {
+	cfg := Config{PoolID: "us-east-1_AbCdEf123", ClientID: "client"}
+	p := newPool(t, "AbCdEf123", "0b1c-uuid", "hunter2")
+
+	c, err := NewClient(cfg, "alice@example.com", "hunter2")
+	if err != nil {
+		t.Fatalf("NewClient: %s", err)
+	}
+	if !p.login(t, c, "0b1c-uuid") {
+		t.Fatalf("login failed")
+	}
+
+	c, _ = NewClient(cfg, "alice@example.com", "wrong")
+	if p.login(t, c, "0b1c-uuid") {
+		t.Fatalf("wrong password accepted")
+	}
+
+	// the pool name is part of the password hash
+	cfg.PoolID = "us-east-1_Other"
+	c, _ = NewClient(cfg, "alice@example.com", "hunter2")
+	if p.login(t, c, "0b1c-uuid") {
+		t.Fatalf("login to another pool accepted")
+	}
+}
+
+func TestParameters(t *testing.T) {
+	now := time.Date(2026, 3, 5, 7, 8, 9, 0, time.UTC)
+	cfg := Config{
+		PoolID:       "eu-west-1_Pool",
+		ClientID:     "cid",
+		ClientSecret: "secret",
+		Now:          func() time.Time { return now },
+	}
+	c, err := NewClient(cfg, "bob", "pw")
+	if err != nil {
+		t.Fatalf("NewClient: %s", err)
+	}
+
+	ap := c.AuthParameters()
+	m := hmac.New(sha256.New, []byte("secret"))
+	m.Write([]byte("bobcid"))
+	if ap["USERNAME"] != "bob" || ap["SECRET_HASH"] != base64.StdEncoding.EncodeToString(m.Sum(nil)) {
+		t.Fatalf("wrong auth parameters %v", ap)
+	}
+
+	r, err := c.PasswordVerifier(map[string]string{
+		"USER_ID_FOR_SRP": "bob",
+		"SRP_B":           "1234",
+		"SALT":            "ab",
+		"SECRET_BLOCK":    "AAAA",
+	})
+	if err != nil {
+		t.Fatalf("PasswordVerifier: %s", err)
+	}
+	if r["TIMESTAMP"] != "Thu Mar 5 07:08:09 UTC 2026" {
+		t.Fatalf("wrong timestamp %q", r["TIMESTAMP"])
+	}
+	if r["SECRET_HASH"] != ap["SECRET_HASH"] {
+		t.Fatalf("wrong secret hash")
+	}
+
+	bad := []map[string]string{
+		{"USER_ID_FOR_SRP": "bob", "SRP_B": "0", "SALT": "ab", "SECRET_BLOCK": "AAAA"},
+		{"USER_ID_FOR_SRP": "bob", "SRP_B": bigN.Text(16), "SALT": "ab", "SECRET_BLOCK": "AAAA"},
+		{"USER_ID_FOR_SRP": "bob", "SRP_B": "12", "SALT": "xy", "SECRET_BLOCK": "AAAA"},
+		{"USER_ID_FOR_SRP": "bob", "SRP_B": "12", "SALT": "ab", "SECRET_BLOCK": "!"},
+		{"SRP_B": "12", "SALT": "ab", "SECRET_BLOCK": "AAAA"},
+	}
+	for i, p := range bad {
+		if _, err := c.PasswordVerifier(p); err == nil {
+			t.Fatalf("%d: bad challenge accepted", i)
+		}
+	}
+
+	if _, err := NewClient(Config{PoolID: "nopool"}, "bob", "pw"); err == nil {
+		t.Fatalf("invalid pool id accepted")
+	}
+}
+
+func TestGroup(t *testing.T) {
+	// the prime of RFC 3526 group 15
+	n := bigN.Text(16)
+	if bigN.BitLen() != 3072 || !strings.HasPrefix(n, "ffffffffffffffffc90fdaa22168c234") || !strings.HasSuffix(n, "a93ad2caffffffffffffffff") {
+		t.Fatalf("wrong prime %s", n)
+	}
+
+	if b := padded(big.NewInt(0x80)); len(b) != 2 || b[0] != 0 {
+		t.Fatalf("0x80 not padded: %x", b)
+	}
+	if b := padded(big.NewInt(0x7f)); len(b) != 1 {
+		t.Fatalf("0x7f padded: %x", b)
+	}
+}
@@ -0,0 +1,129 @@
+// compact.go - compact storage encoding of verifiers
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"bytes"
+	"compress/flate"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// In the large groups the encoded verifier is dominated by N, the salt and
+// v: a 8192-bit verifier made by Encode() is over 4 KiB. The compact
+// encoding is the binary encoding of EncodeCBOR() without N and g for
+// built-in groups (the group id names them), preceded by a version byte
+// and a flags byte. Salts can be made smaller with WithSaltSize().
+//
+// The encoding may be compressed with DEFLATE. Salts and verifiers are
+// random and don't compress well, so compression is only kept if it makes
+// the encoding smaller; it mostly helps verifiers of custom groups that
+// carry N.
+
+// version of the compact encoding
+const compactVersion = 1
+
+// flags of the compact encoding
+const compactDeflate = 1 << 0
+
+// largest decompressed verifier that is accepted
+const maxCompactLen = 1 << 16
+
+// EncodeCompact returns the compact storage encoding of the verifier; it
+// carries the same information as EncodeCBOR(). If 'compress' is true, the
+// encoding is compressed with DEFLATE when that makes it smaller.
+func (v *Verifier) EncodeCompact(compress bool) []byte {
+	b := v.encodeCBOR(true)
+	if compress {
+		if z := deflate(b); len(z) < len(b) {
+			return append([]byte{compactVersion, compactDeflate}, z...)
+		}
+	}
+	return append([]byte{compactVersion, 0}, b...)
+}
+
+// DecodeVerifierCompact decodes the output of Verifier.EncodeCompact() into
+// an SRP environment and Verifier.
+func DecodeVerifierCompact(b []byte, opts ...Option) (*SRP, *Verifier, error) {
+	if len(b) < 2 {
+		return nil, nil, fmt.Errorf("verifier: compact encoding too short")
+	}
+	if b[0] != compactVersion {
+		return nil, nil, fmt.Errorf("verifier: unknown compact encoding %d", b[0])
+	}
+
+	switch b[1] {
+	case 0:
+		b = b[2:]
+	case compactDeflate:
+		var err error
+		if b, err = inflate(b[2:]); err != nil {
+			return nil, nil, fmt.Errorf("verifier: %s", err)
+		}
+	default:
+		return nil, nil, fmt.Errorf("verifier: unknown compact flags %#x", b[1])
+	}
+	return DecodeVerifierCBOR(b, opts...)
+}
+
+// VerifierSize is the size in bytes of a verifier in each encoding
+type VerifierSize struct {
+	Text    int // the identity and verifier strings of Encode()
+	CBOR    int // EncodeCBOR()
+	Compact int // EncodeCompact() without compression
+}
+
+// EstimateVerifierSize returns the largest size of the verifiers made in
+// this environment in each encoding, for capacity planning. Verifiers with
+// longer salts (see VerifierWithSalt()) are larger.
+func (s *SRP) EstimateVerifierSize() VerifierSize {
+	v := &Verifier{
+		i:   make([]byte, newHash(s.h).Size()),
+		s:   make([]byte, s.saltSize()),
+		v:   make([]byte, s.pf.n),
+		h:   s.h,
+		pf:  s.pf,
+		kdf: s.kdf,
+		idk: s.idk,
+	}
+	for i := range v.v {
+		v.v[i] = 0xff
+	}
+
+	ih, vh := v.Encode()
+	return VerifierSize{
+		Text:    len(ih) + len(vh),
+		CBOR:    len(v.EncodeCBOR()),
+		Compact: len(v.EncodeCompact(false)),
+	}
+}
+
+// compress 'b' with DEFLATE
+func deflate(b []byte) []byte {
+	var z bytes.Buffer
+
+	w, _ := flate.NewWriter(&z, flate.BestCompression)
+	w.Write(b)
+	w.Close()
+	return z.Bytes()
+}
+
+// decompress 'b'; the output is at most maxCompactLen bytes
+func inflate(b []byte) ([]byte, error) {
+	r := flate.NewReader(bytes.NewReader(b))
+	defer r.Close()
+
+	out, err := ioutil.ReadAll(io.LimitReader(r, maxCompactLen+1))
+	if err != nil {
+		return nil, fmt.Errorf("malformed compressed data")
+	}
+	if len(out) > maxCompactLen {
+		return nil, fmt.Errorf("compressed data too large")
+	}
+	return out, nil
+}
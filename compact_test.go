@@ -0,0 +1,75 @@
+// self test for the compact verifier encoding
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"crypto"
+	"testing"
+)
+
+func TestCompactVerifier(t *testing.T) {
+	assert := newAsserter(t)
+
+	user := []byte("user")
+	pass := []byte("pass")
+
+	_, err := New(2048, WithSaltSize(8))
+	assert(err != nil, "accepted short salt")
+
+	s, err := New(8192, WithSaltSize(32))
+	assert(err == nil, "New: %s", err)
+
+	pf := pflist[2048]
+	cs, err := NewWithGroup(crypto.SHA256, pf.N, pf.g)
+	assert(err == nil, "NewWithGroup: %s", err)
+
+	for _, env := range []*SRP{s, cs} {
+		v, err := env.Verifier(user, pass, nil)
+		assert(err == nil, "Verifier: %s", err)
+		_, vh := v.Encode()
+
+		// the compact form of a built-in group omits N and g
+		est := env.EstimateVerifierSize()
+		assert(est.CBOR < est.Text, "bad estimate %+v", est)
+		if env.GroupID() != "" {
+			assert(est.Compact < est.CBOR-env.FieldSize()/8, "bad estimate %+v", est)
+		} else {
+			assert(est.Compact == est.CBOR+2, "bad estimate %+v", est)
+		}
+
+		for _, z := range []bool{false, true} {
+			b := v.EncodeCompact(z)
+			assert(len(b) <= est.Compact, "%d bytes exceed estimate %d", len(b), est.Compact)
+
+			_, v2, err := DecodeVerifierCompact(b)
+			assert(err == nil, "DecodeVerifierCompact: %s", err)
+			_, vh2 := v2.Encode()
+			assert(vh == vh2, "verifier mismatch:\n%s\n%s", vh, vh2)
+		}
+	}
+
+	v, err := s.Verifier(user, pass, nil)
+	assert(err == nil, "Verifier: %s", err)
+	b := v.EncodeCompact(false)
+
+	bad := [][]byte{
+		nil,
+		{compactVersion},
+		append([]byte{2}, b[1:]...),
+		append([]byte{compactVersion, 0x80}, b[2:]...),
+		append([]byte{compactVersion, compactDeflate}, b[2:]...),
+		b[:len(b)-1],
+	}
+	for i, x := range bad {
+		_, _, err = DecodeVerifierCompact(x)
+		assert(err != nil, "%d: accepted bad encoding", i)
+	}
+
+	// an oversized payload is rejected
+	_, err = inflate(deflate(make([]byte, maxCompactLen+1)))
+	assert(err != nil, "accepted oversized payload")
+}
@@ -0,0 +1,144 @@
+// confirm.go - key confirmation with AEAD sealed messages
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"crypto/cipher"
+	"fmt"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// Key confirmation is an alternative to exchanging the proofs M and M'.
+// After deriving K, each side sends a confirmation message sealed with
+// ChaCha20-Poly1305 under a key derived from K for its direction:
+//
+//	Kc = HKDF(K, "srp confirm client")
+//	Ks = HKDF(K, "srp confirm server")
+//
+// The plaintext is a version byte followed by a hash of the handshake
+// transcript, T = H(N, g, I, s, A, B [, H(ctx)]). Opening a message proves
+// that the sender knows K and agrees on the transcript, and nothing about
+// the transcript is revealed to an observer.
+//
+// Since the keys are unique to a session and direction and each key seals
+// exactly one message, the nonce is fixed at zero.
+
+// version of the confirmation message format
+const confirmVersion = 1
+
+// size of the largest confirmation message: the version, the transcript
+// hash and the Poly1305 tag
+const maxConfirmLen = 1 + maxHashSize + 16
+
+// Labels for the direction-specific confirmation keys
+var (
+	confirmClientLabel = []byte("srp confirm client")
+	confirmServerLabel = []byte("srp confirm server")
+)
+
+// Confirm returns the client's key confirmation message; it is sent to the
+// server instead of the proof returned by Respond(). It must be called
+// after Respond().
+func (c *Client) Confirm() ([]byte, error) {
+	if c.xT == nil {
+		return nil, fmt.Errorf("srp: no session key")
+	}
+
+	msg, err := c.xT.seal(confirmClientLabel)
+	if err != nil {
+		return nil, err
+	}
+	return append(msg, c.sol...), nil
+}
+
+// CheckConfirm opens the server's key confirmation message 'msg' and returns
+// true if the server derived the same session key and transcript.
+func (c *Client) CheckConfirm(msg []byte) bool {
+	if c.xT == nil {
+		return false
+	}
+	c.authed = c.xT.open(confirmServerLabel, msg)
+	return c.authed
+}
+
+// CheckConfirm opens the client's key confirmation message 'msg' (see
+// Client.Confirm()) and, if it is valid, returns the server's key
+// confirmation message. It is the counterpart of CheckProof() for the
+// key confirmation flow.
+func (s *Server) CheckConfirm(msg []byte) (reply []byte, ok bool) {
+	if len(msg) > maxConfirmLen+s.s.solutionLen() {
+		return nil, false
+	}
+	if s.s.replayCheck("C", msg) != nil {
+		return nil, false
+	}
+
+	msg, err := s.checkPuzzle(msg)
+	if err != nil {
+		return nil, false
+	}
+
+	t := s.transcript()
+	if t.A == nil || !t.open(confirmClientLabel, msg) {
+		return nil, false
+	}
+
+	reply, err = t.seal(confirmServerLabel)
+	if err != nil {
+		return nil, false
+	}
+	s.authed = true
+	return reply, true
+}
+
+// seal the confirmation message under the key for direction 'label'
+func (t *Transcript) seal(label []byte) ([]byte, error) {
+	aead, err := t.confirmAEAD(label)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	pt := append([]byte{confirmVersion}, t.digest()...)
+	return aead.Seal(nil, nonce, pt, label), nil
+}
+
+// open the confirmation message 'msg' sealed for direction 'label' and
+// return true if it matches this transcript
+func (t *Transcript) open(label, msg []byte) bool {
+	aead, err := t.confirmAEAD(label)
+	if err != nil {
+		return false
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	pt, err := aead.Open(nil, nonce, msg, label)
+	if err != nil || len(pt) == 0 || pt[0] != confirmVersion {
+		return false
+	}
+	return ctEqual(pt[1:], t.digest())
+}
+
+// return the AEAD keyed for direction 'label'
+func (t *Transcript) confirmAEAD(label []byte) (cipher.AEAD, error) {
+	return chacha20poly1305.New(t.s.expandKey(t.K, label, chacha20poly1305.KeySize))
+}
+
+// return T = H(N, g, I, s, A, B [, H(ctx)]); I is empty with
+// WithIdentityFreeProofs()
+func (t *Transcript) digest() []byte {
+	I := t.I
+	if t.s.noid {
+		I = nil
+	}
+	v := [][]byte{t.N.Bytes(), t.G.Bytes(), I, t.Salt, t.A.Bytes(), t.B.Bytes()}
+	if len(t.Context) > 0 {
+		v = append(v, t.H(t.Context))
+	}
+	return t.H(v...)
+}
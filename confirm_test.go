@@ -0,0 +1,57 @@
+// self test for key confirmation
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestKeyConfirmation(t *testing.T) {
+	assert := newAsserter(t)
+
+	user := []byte("user")
+	pass := []byte("pass")
+
+	s, err := New(2048)
+	assert(err == nil, "New: %s", err)
+
+	for _, pw := range []string{"pass", "wrong"} {
+		v, err := s.Verifier(user, pass, nil)
+		assert(err == nil, "Verifier: %s", err)
+
+		c, err := s.NewClient(user, []byte(pw))
+		assert(err == nil, "NewClient: %s", err)
+
+		_, err = c.Confirm()
+		assert(err != nil, "confirmed without a session key")
+
+		srv, err := s.NewServer(v, new(big.Int).SetBytes(c.Hello().A))
+		assert(err == nil, "NewServer: %s", err)
+
+		_, err = c.Respond(srv.Challenge())
+		assert(err == nil, "Respond: %s", err)
+
+		cm, err := c.Confirm()
+		assert(err == nil, "Confirm: %s", err)
+
+		reply, ok := srv.CheckConfirm(cm)
+		if pw != string(pass) {
+			assert(!ok, "server accepted bad password")
+			continue
+		}
+		assert(ok, "server: bad confirmation")
+		assert(c.CheckConfirm(reply), "client: bad confirmation")
+
+		// a message isn't valid in the other direction
+		assert(!c.CheckConfirm(cm), "client accepted its own message")
+
+		cm[len(cm)-1] ^= 1
+		_, ok = srv.CheckConfirm(cm)
+		assert(!ok, "server accepted tampered message")
+	}
+}
@@ -0,0 +1,280 @@
+// conn.go - complete handshakes over a net.Conn with timeouts
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// HandshakeConfig bounds the time a handshake over a net.Conn may take so
+// that a stalled peer can't pin the goroutine running it. Zero fields mean
+// no limit.
+type HandshakeConfig struct {
+	// Timeout bounds the whole handshake
+	Timeout time.Duration
+
+	// LegTimeout bounds each message sent or received
+	LegTimeout time.Duration
+
+	// OnFailure, if set, is called by ServerHandshake() with the reason
+	// and error of a failed handshake, including unknown users (a lookup
+	// error matching ErrNoVerifier)
+	OnFailure FailureHook
+
+	// Tarpit, if set, is consulted by ServerHandshake() before the lookup
+	// of the verifier, both with the host of the peer's address and with
+	// the empty source of the identity as a whole; failed lookups and
+	// proofs count as failed attempts of both.
+	Tarpit Tarpit
+}
+
+// VerifierLookup returns the SRP environment and verifier of the user with
+// hashed identity 'ih'; it is called by ServerHandshake().
+type VerifierLookup func(ih []byte) (*SRP, *Verifier, error)
+
+// Handshake runs the client side of a handshake on 'conn' using the stream
+// helpers (see stream.go). It returns an error if the server isn't
+// authenticated, 'ctx' is cancelled or a timeout in 'cfg' expires; 'cfg'
+// may be nil. The deadlines of 'conn' are cleared on return.
+func (c *Client) Handshake(ctx context.Context, conn net.Conn, cfg *HandshakeConfig) error {
+	return c.handshake(ctx, conn, cfg)
+}
+
+// do the work of Handshake() on 'rw'; deadlines only apply if it is a
+// net.Conn
+func (c *Client) handshake(ctx context.Context, rw io.ReadWriter, cfg *HandshakeConfig) error {
+	h := newHandshake(ctx, rw, cfg)
+	defer h.done()
+
+	h.leg()
+	if err := c.WriteHello(rw); err != nil {
+		return h.err(err)
+	}
+
+	h.leg()
+	sc, err := ReadChallenge(rw)
+	if err != nil {
+		return h.err(err)
+	}
+
+	m, err := c.Respond(sc)
+	if err != nil {
+		return err
+	}
+
+	h.leg()
+	if err := WriteProof(rw, m); err != nil {
+		return h.err(err)
+	}
+
+	h.leg()
+	proof, err := ReadProof(rw)
+	if err != nil {
+		return h.err(err)
+	}
+
+	if !c.CheckProof(proof) {
+		return fmt.Errorf("srp: server authentication failed")
+	}
+	return nil
+}
+
+// ServerHandshake runs the server side of a handshake on 'conn'; 'lookup'
+// finds the verifier of the client. It returns the Server of an
+// authenticated client or an error if the client isn't authenticated, 'ctx'
+// is cancelled or a timeout in 'cfg' expires; 'cfg' may be nil. The
+// deadlines of 'conn' are cleared on return.
+func ServerHandshake(ctx context.Context, conn net.Conn, lookup VerifierLookup, cfg *HandshakeConfig) (*Server, error) {
+	return serverHandshakeOn(ctx, conn, lookup, cfg)
+}
+
+// run ServerHandshake() on 'rw' and report its failure
+func serverHandshakeOn(ctx context.Context, rw io.ReadWriter, lookup VerifierLookup, cfg *HandshakeConfig) (*Server, error) {
+	srv, err := serverHandshake(ctx, rw, lookup, cfg)
+	if err != nil && cfg != nil && cfg.OnFailure != nil {
+		cfg.OnFailure(ReasonOf(err), err)
+	}
+	return srv, err
+}
+
+// do the work of ServerHandshake()
+func serverHandshake(ctx context.Context, rw io.ReadWriter, lookup VerifierLookup, cfg *HandshakeConfig) (*Server, error) {
+	h := newHandshake(ctx, rw, cfg)
+	defer h.done()
+
+	h.leg()
+	cc, err := ReadHello(rw)
+	if err != nil {
+		return nil, h.err(err)
+	}
+
+	tp, srcs := h.cfg.Tarpit, tarpitSources(peerHost(rw))
+	if tp != nil {
+		for _, src := range srcs {
+			if err := CheckTarpit(tp, cc.IdentityHash, src); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	s, v, err := lookup(cc.IdentityHash)
+	if err != nil {
+		if tp != nil {
+			for _, src := range srcs {
+				tp.Failure(cc.IdentityHash, src)
+			}
+		}
+		return nil, err
+	}
+
+	A, err := s.ParsePublicKey(cc.A)
+	if err != nil {
+		return nil, err
+	}
+
+	srv, err := s.NewServerFor(cc.IdentityHash, v, A)
+	if err != nil {
+		return nil, err
+	}
+
+	h.leg()
+	if err := srv.WriteChallenge(rw); err != nil {
+		return nil, h.err(err)
+	}
+
+	h.leg()
+	m, err := ReadProof(rw)
+	if err != nil {
+		return nil, h.err(err)
+	}
+
+	proof, err := srv.verifyProof(m)
+	if tp != nil {
+		for _, src := range srcs {
+			if err != nil {
+				tp.Failure(cc.IdentityHash, src)
+			} else {
+				tp.Success(cc.IdentityHash, src)
+			}
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("srp: client authentication failed: %w", err)
+	}
+
+	h.leg()
+	if err := WriteProof(rw, proof); err != nil {
+		return nil, h.err(err)
+	}
+	return srv, nil
+}
+
+// return the tarpit sources of an attempt from the peer 'host': the host,
+// if known, and the identity as a whole
+func tarpitSources(host string) []string {
+	if host == "" {
+		return []string{""}
+	}
+	return []string{host, ""}
+}
+
+// return the host of the remote address of 'rw' if it is a net.Conn
+func peerHost(rw io.ReadWriter) string {
+	conn, ok := rw.(net.Conn)
+	if !ok {
+		return ""
+	}
+	a := conn.RemoteAddr()
+	if a == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(a.String())
+	if err != nil {
+		return a.String()
+	}
+	return host
+}
+
+// handshake tracks the deadlines of a handshake on a net.Conn
+type handshake struct {
+	ctx  context.Context
+	conn net.Conn // nil if the stream has no deadlines
+	cfg  HandshakeConfig
+	end  time.Time // zero if there is no overall deadline
+	stop chan struct{}
+	wait chan struct{} // closed when the watcher of ctx exits
+}
+
+// start tracking a handshake on 'rw'; if it is a net.Conn, cancelling
+// 'ctx' interrupts any pending read or write.
+func newHandshake(ctx context.Context, rw io.ReadWriter, cfg *HandshakeConfig) *handshake {
+	conn, _ := rw.(net.Conn)
+	h := &handshake{
+		ctx:  ctx,
+		conn: conn,
+		stop: make(chan struct{}),
+		wait: make(chan struct{}),
+	}
+	if cfg != nil {
+		h.cfg = *cfg
+	}
+	if h.cfg.Timeout > 0 {
+		h.end = time.Now().Add(h.cfg.Timeout)
+	}
+
+	if conn == nil {
+		close(h.wait)
+		return h
+	}
+
+	go func() {
+		defer close(h.wait)
+		select {
+		case <-ctx.Done():
+			// a deadline in the past unblocks reads and writes
+			conn.SetDeadline(time.Unix(1, 0))
+		case <-h.stop:
+		}
+	}()
+	return h
+}
+
+// set the deadline for the next message
+func (h *handshake) leg() {
+	if h.conn == nil || h.ctx.Err() != nil {
+		return
+	}
+
+	d := h.end
+	if h.cfg.LegTimeout > 0 {
+		if t := time.Now().Add(h.cfg.LegTimeout); d.IsZero() || t.Before(d) {
+			d = t
+		}
+	}
+	h.conn.SetDeadline(d)
+}
+
+// return the error for the failed read or write 'err'
+func (h *handshake) err(err error) error {
+	if cerr := h.ctx.Err(); cerr != nil {
+		return fmt.Errorf("srp: handshake: %w", cerr)
+	}
+	return err
+}
+
+// stop tracking the handshake and clear the deadlines
+func (h *handshake) done() {
+	close(h.stop)
+	<-h.wait
+	if h.conn != nil {
+		h.conn.SetDeadline(time.Time{})
+	}
+}
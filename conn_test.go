@@ -0,0 +1,109 @@
+// self test for handshakes over a net.Conn
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestHandshake(t *testing.T) {
+	assert := newAsserter(t)
+
+	user := []byte("user")
+	pass := []byte("pass")
+
+	s, err := New(2048)
+	assert(err == nil, "New: %s", err)
+
+	v, err := s.Verifier(user, pass, nil)
+	assert(err == nil, "Verifier: %s", err)
+
+	lookup := func(ih []byte) (*SRP, *Verifier, error) {
+		if !v.MatchesIdentity(ih) {
+			return nil, nil, fmt.Errorf("unknown user")
+		}
+		return s, v, nil
+	}
+
+	cfg := &HandshakeConfig{
+		Timeout:    10 * time.Second,
+		LegTimeout: 5 * time.Second,
+	}
+
+	for _, pw := range []string{"pass", "wrong"} {
+		cc, sc := net.Pipe()
+
+		type result struct {
+			srv *Server
+			err error
+		}
+		done := make(chan result, 1)
+		go func() {
+			srv, err := ServerHandshake(context.Background(), sc, lookup, cfg)
+			sc.Close()
+			done <- result{srv, err}
+		}()
+
+		c, err := s.NewClient(user, []byte(pw))
+		assert(err == nil, "NewClient: %s", err)
+
+		err = c.Handshake(context.Background(), cc, cfg)
+		cc.Close()
+		r := <-done
+
+		if pw != string(pass) {
+			assert(err != nil, "client: accepted bad password")
+			assert(r.err != nil, "server: accepted bad password")
+			continue
+		}
+		assert(err == nil, "client: %s", err)
+		assert(r.err == nil, "server: %s", r.err)
+		assert(ctEqual(c.RawKey(), r.srv.RawKey()), "key mismatch")
+	}
+}
+
+func TestHandshakeTimeout(t *testing.T) {
+	assert := newAsserter(t)
+
+	s, err := New(2048)
+	assert(err == nil, "New: %s", err)
+
+	lookup := func(ih []byte) (*SRP, *Verifier, error) {
+		return nil, nil, fmt.Errorf("unreachable")
+	}
+
+	// a client that never speaks
+	cc, sc := net.Pipe()
+	defer cc.Close()
+	defer sc.Close()
+
+	t0 := time.Now()
+	_, err = ServerHandshake(context.Background(), sc, lookup, &HandshakeConfig{LegTimeout: 50 * time.Millisecond})
+	assert(err != nil, "handshake didn't time out")
+	assert(time.Since(t0) < 5*time.Second, "timeout took too long")
+
+	// a server that never replies; the context is cancelled
+	c, err := s.NewClient([]byte("user"), []byte("pass"))
+	assert(err == nil, "NewClient: %s", err)
+
+	go func() {
+		ReadHello(sc)
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+
+	err = c.Handshake(ctx, cc, nil)
+	assert(err != nil, "handshake wasn't cancelled")
+}
@@ -0,0 +1,112 @@
+// constwork.go - handshakes whose work doesn't depend on the user's group
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"fmt"
+	"sort"
+)
+
+// A server whose verifiers span several generations of parameters (e.g.,
+// legacy 2048 bit users next to 4096 bit ones) does work proportional to
+// the user's group in each handshake: a handshake with a 4096 bit verifier
+// takes several times as long as one with a 2048 bit verifier, so response
+// times tell an attacker which users still have legacy parameters, and
+// that an identity is unknown if its handshake takes another time than the
+// others. WithConstantWork(bits...) lists the group sizes in use; each
+// server handshake (and DummyHandshake()) then also runs a dummy handshake
+// in each listed size other than its own, so that every handshake does the
+// work of one handshake in every listed size. Decoding a verifier whose
+// group isn't listed fails, since its handshakes would stand out.
+//
+// The storage should not give the generation away either:
+//
+//   - keep all verifiers in one table under the same kind of key (see
+//     StorageKey()) rather than a table per generation;
+//   - store verifiers of built-in groups with their group name (the "grp="
+//     field of Encode()) so that decoding a legacy verifier doesn't parse
+//     a different prime;
+//   - answer unknown identities with DummyHandshake() (or with a fake
+//     verifier) in an environment with the same WithConstantWork().
+//
+// The dummy handshakes use the hash function and options of the user's
+// environment; generations that also differ in hash function differ a
+// little in time. Combine with EqualizeLatency() to hide the rest.
+
+// WithConstantWork makes servers in this environment do the work of a
+// handshake in each of the built-in groups of sizes 'bits' in every
+// handshake (see above); verifiers decoded with it must be in a group of
+// one of these sizes.
+func WithConstantWork(bits ...int) Option {
+	return func(s *SRP) error {
+		if len(bits) == 0 {
+			return fmt.Errorf("srp: no group sizes for constant work")
+		}
+
+		cw := make([]*primeField, 0, len(bits))
+		for _, b := range bits {
+			pf, err := findPrimeField(b)
+			if err != nil {
+				return err
+			}
+			if !hasFieldSize(cw, pf.n) {
+				cw = append(cw, pf)
+			}
+		}
+		sort.Slice(cw, func(i, j int) bool { return cw[i].n < cw[j].n })
+		s.cw = cw
+		return nil
+	}
+}
+
+// return an error if the group of this environment isn't in its set of
+// constant-work groups
+func (s *SRP) checkConstantWork() error {
+	if s.cw == nil || hasFieldSize(s.cw, s.pf.n) {
+		return nil
+	}
+	return fmt.Errorf("verifier: %d bit group outside the constant-work sizes", s.FieldSize())
+}
+
+// do the work of a dummy handshake in each constant-work group whose size
+// isn't that of this environment's group
+func (s *SRP) balanceWork() {
+	for _, pf := range s.cw {
+		if pf.n != s.pf.n {
+			s.inGroup(pf).dummyHandshake()
+		}
+	}
+}
+
+// return a copy of the parts of this environment that affect the work of a
+// handshake with the group 'pf' instead of its own
+func (s *SRP) inGroup(pf *primeField) *SRP {
+	return &SRP{
+		h:        s.h,
+		pf:       pf,
+		saltLen:  s.saltLen,
+		ephBits:  s.ephBits,
+		tctx:     s.tctx,
+		ps:       s.ps,
+		noid:     s.noid,
+		keyBits:  s.keyBits,
+		ph:       s.ph,
+		rand:     s.rand,
+		be:       s.be,
+		blindExp: s.blindExp,
+	}
+}
+
+// return true if one of 'pfs' is 'n' bytes wide
+func hasFieldSize(pfs []*primeField, n int) bool {
+	for _, pf := range pfs {
+		if pf.n == n {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+// self test for constant-work handshakes
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+// counts exponentiations by the size of the modulus
+type sizeCountingBackend struct {
+	Backend
+	n map[int]int
+}
+
+func (b *sizeCountingBackend) Exp(x, y, m *big.Int) *big.Int {
+	b.n[m.BitLen()]++
+	return b.Backend.Exp(x, y, m)
+}
+
+func TestConstantWork(t *testing.T) {
+	assert := newAsserter(t)
+
+	for _, bits := range [][]int{nil, {1000}} {
+		_, err := New(2048, WithConstantWork(bits...))
+		assert(err != nil, "WithConstantWork(%v) accepted", bits)
+	}
+
+	// verifiers of two generations
+	vh := map[int]string{}
+	for _, bits := range []int{2048, 3072} {
+		s, err := New(bits)
+		assert(err == nil, "New: %s", err)
+		v, err := s.Verifier([]byte("user"), []byte("pass"), nil)
+		assert(err == nil, "Verifier: %s", err)
+		_, vh[bits] = v.Encode()
+	}
+
+	// a server handshake does the same work in either generation
+	var work []map[int]int
+	for _, bits := range []int{2048, 3072} {
+		be := &sizeCountingBackend{Backend: MathBig, n: map[int]int{}}
+		s, v, err := MakeSRPVerifier(vh[bits], WithConstantWork(2048, 3072), WithBackend(be))
+		assert(err == nil, "MakeSRPVerifier: %s", err)
+
+		c, err := s.NewClient([]byte("user"), []byte("pass"))
+		assert(err == nil, "NewClient: %s", err)
+		be.n = map[int]int{}
+		srv, err := s.NewServer(v, c.xA)
+		assert(err == nil, "NewServer: %s", err)
+		work = append(work, be.n)
+
+		be.n = map[int]int{}
+		s.DummyHandshake()
+		assert(reflect.DeepEqual(be.n, work[0]), "dummy work %v, exp %v", be.n, work[0])
+
+		assert(authenticate(c, srv), "%d bit login failed", bits)
+	}
+	assert(reflect.DeepEqual(work[0], work[1]), "work differs: %v vs %v", work[0], work[1])
+	assert(work[0][2048] > 0 && work[0][3072] > 0, "work in one group only: %v", work[0])
+
+	// verifiers of unlisted sizes are refused
+	_, _, err := MakeSRPVerifier(vh[3072], WithConstantWork(2048, 4096))
+	assert(err != nil, "unlisted group accepted")
+}
@@ -0,0 +1,544 @@
+// datagram.go - SRP handshakes over lossy datagram transports
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+// Package datagram runs SRP handshakes over datagram transports such as
+// UDP, where messages may be lost, duplicated or reordered and the source
+// address of a datagram can be spoofed.
+//
+// Each handshake is identified by a random session id chosen by the
+// client. The client retransmits its last message with exponential backoff
+// until the server replies; the server answers retransmissions with the
+// reply it already sent instead of repeating any SRP work. Completed
+// sessions are remembered for Config.SessionTTL so that a retransmitted or
+// replayed proof can't start another authentication.
+//
+// Before it spends any SRP work (or memory) on a client, the server sends
+// a stateless cookie bound to the client's address; only a client that
+// receives it, and thus owns the address, can continue. This stops floods
+// of spoofed hellos and keeps the server from amplifying traffic towards a
+// victim.
+//
+// Every datagram starts with a header:
+//
+//	version(1) | type(1) | session id(8)
+//
+// followed by the body of the message type:
+//
+//	Hello        cookie length(1) | cookie | CBOR ClientCredentials
+//	Cookie       cookie
+//	Challenge    CBOR ServerCredentials
+//	Proof        CBOR client proof M
+//	ServerProof  CBOR server proof M'
+//	Failed       (empty)
+//
+// Messages of the 8192-bit group exceed a typical MTU; such deployments
+// should use short salts (see srp.WithSaltSize()) or rely on IP
+// fragmentation.
+package datagram
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/tomsons/go-srp"
+)
+
+// version of the datagram format
+const version = 1
+
+// message types
+const (
+	msgHello = iota + 1
+	msgCookie
+	msgChallenge
+	msgProof
+	msgServerProof
+	msgFailed
+)
+
+const (
+	headerLen = 2 + sidLen
+	sidLen    = 8
+	cookieLen = 16
+
+	// largest datagram that is read
+	maxDatagram = 8192
+
+	// cookies are valid for one to two epochs
+	cookieEpoch = 30 * time.Second
+)
+
+// Defaults of Config
+const (
+	DefaultRTO         = 250 * time.Millisecond
+	DefaultRetries     = 6
+	DefaultSessionTTL  = 30 * time.Second
+	DefaultMaxSessions = 1024
+)
+
+// Config tunes clients and servers; zero fields take the defaults
+type Config struct {
+	// RTO is the client's initial retransmission timeout; it doubles
+	// with every retransmission.
+	RTO time.Duration
+
+	// Retries is the number of times a client retransmits a message
+	// before giving up.
+	Retries int
+
+	// SessionTTL is how long a server keeps a session, whether complete
+	// or not.
+	SessionTTL time.Duration
+
+	// MaxSessions bounds the number of sessions a server keeps; new
+	// clients are ignored while it is full.
+	MaxSessions int
+
+	// SkipCookies makes the server start handshakes without validating
+	// the client's address first. Only use it on trusted networks.
+	SkipCookies bool
+}
+
+// return a copy of 'cfg' with the defaults filled in
+func (cfg *Config) withDefaults() Config {
+	var c Config
+	if cfg != nil {
+		c = *cfg
+	}
+	if c.RTO <= 0 {
+		c.RTO = DefaultRTO
+	}
+	if c.Retries <= 0 {
+		c.Retries = DefaultRetries
+	}
+	if c.SessionTTL <= 0 {
+		c.SessionTTL = DefaultSessionTTL
+	}
+	if c.MaxSessions <= 0 {
+		c.MaxSessions = DefaultMaxSessions
+	}
+	return c
+}
+
+// Handshake authenticates the server at 'addr' with the client 'c' over
+// 'pc'; 'cfg' may be nil. It returns nil once the server's proof is
+// verified; the session key is then available from 'c'. Datagrams from
+// other addresses or sessions are ignored. Cancelling 'ctx' is noticed at
+// the next retransmission timeout.
+func Handshake(ctx context.Context, pc net.PacketConn, addr net.Addr, c *srp.Client, cfg *Config) error {
+	conf := cfg.withDefaults()
+	defer pc.SetReadDeadline(time.Time{})
+
+	sid := make([]byte, sidLen)
+	if _, err := rand.Read(sid); err != nil {
+		return err
+	}
+
+	cc := c.Hello()
+	hello := func(cookie []byte) []byte {
+		b := header(msgHello, sid)
+		b = append(b, byte(len(cookie)))
+		b = append(b, cookie...)
+		return append(b, cc.EncodeCBOR()...)
+	}
+
+	out := hello(nil)
+	rto := conf.RTO
+	tries := 0
+	responded := false
+	buf := make([]byte, maxDatagram)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if _, err := pc.WriteTo(out, addr); err != nil {
+			return err
+		}
+
+		deadline := time.Now().Add(rto)
+		if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+			deadline = d
+		}
+		pc.SetReadDeadline(deadline)
+
+		typ, body, err := readFrom(pc, buf, addr, sid)
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				if tries++; tries > conf.Retries {
+					return fmt.Errorf("datagram: server didn't respond")
+				}
+				rto *= 2
+				continue
+			}
+			return err
+		}
+
+		switch typ {
+		case msgCookie:
+			if len(body) != cookieLen {
+				continue
+			}
+			out = hello(body)
+
+		case msgChallenge:
+			sc, err := srp.DecodeServerCredentialsCBOR(body)
+			if err != nil {
+				continue
+			}
+			if responded {
+				// a duplicate; the server may have lost the proof
+				continue
+			}
+
+			m, err := c.Respond(sc)
+			if err != nil {
+				return err
+			}
+			out = append(header(msgProof, sid), srp.EncodeProofCBOR(m)...)
+			responded = true
+			tries = 0
+			rto = conf.RTO
+
+		case msgServerProof:
+			proof, err := srp.DecodeProofCBOR(body)
+			if err != nil {
+				continue
+			}
+			if !c.CheckProof(proof) {
+				return fmt.Errorf("datagram: server authentication failed")
+			}
+			return nil
+
+		case msgFailed:
+			return fmt.Errorf("datagram: authentication failed")
+		}
+	}
+}
+
+// read the next datagram of session 'sid' from 'addr'; others are skipped
+func readFrom(pc net.PacketConn, buf []byte, addr net.Addr, sid []byte) (typ byte, body []byte, err error) {
+	for {
+		n, from, err := pc.ReadFrom(buf)
+		if err != nil {
+			return 0, nil, err
+		}
+		if from.String() != addr.String() {
+			continue
+		}
+
+		typ, id, body, ok := parse(buf[:n])
+		if ok && bytes.Equal(id, sid) {
+			return typ, body, nil
+		}
+	}
+}
+
+// Session is a completed handshake
+type Session struct {
+	ID   []byte   // the session id chosen by the client
+	Addr net.Addr // the client's address
+
+	// Server is the authenticated SRP server; its key seals the
+	// application's traffic.
+	Server *srp.Server
+}
+
+// Server runs the server side of datagram handshakes. It is safe for
+// concurrent use.
+type Server struct {
+	lookup srp.VerifierLookup
+	conf   Config
+	secret []byte // cookie key
+
+	mu       sync.Mutex
+	sessions map[string]*session
+	pruned   time.Time
+}
+
+// state of a handshake
+type session struct {
+	mu sync.Mutex
+
+	expires time.Time
+	hello   []byte // the client's hello body, to spot retransmissions
+	reply   []byte // the challenge sent
+	proof   []byte // the client proof that completed the session
+	final   []byte // the reply to 'proof'
+	srv     *srp.Server
+}
+
+// NewServer returns a Server that finds verifiers with 'lookup'; 'cfg' may
+// be nil.
+func NewServer(lookup srp.VerifierLookup, cfg *Config) (*Server, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+
+	return &Server{
+		lookup:   lookup,
+		conf:     cfg.withDefaults(),
+		secret:   secret,
+		sessions: make(map[string]*session),
+	}, nil
+}
+
+// Handle processes the datagram 'b' received from 'from' and returns the
+// reply to send back, if any. When the datagram completes a handshake, the
+// new Session is returned as well; it is returned only once per session.
+func (s *Server) Handle(b []byte, from net.Addr) (reply []byte, sess *Session) {
+	typ, sid, body, ok := parse(b)
+	if !ok {
+		return nil, nil
+	}
+
+	switch typ {
+	case msgHello:
+		return s.hello(sid, body, from), nil
+	case msgProof:
+		return s.proof(sid, body, from)
+	}
+	return nil, nil
+}
+
+// Serve reads datagrams from 'pc' and answers them until 'ctx' is
+// cancelled or reading fails; 'fp' is called with each completed Session.
+// Handshakes are processed one at a time; servers with many concurrent
+// clients should call Handle() from several goroutines instead.
+func (s *Server) Serve(ctx context.Context, pc net.PacketConn, fp func(*Session)) error {
+	go func() {
+		<-ctx.Done()
+		pc.SetReadDeadline(time.Unix(1, 0))
+	}()
+
+	buf := make([]byte, maxDatagram)
+	for {
+		n, from, err := pc.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+
+		reply, sess := s.Handle(buf[:n], from)
+		if reply != nil {
+			pc.WriteTo(reply, from)
+		}
+		if sess != nil {
+			fp(sess)
+		}
+	}
+}
+
+// Len returns the number of sessions the server keeps
+func (s *Server) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.prune(time.Now())
+	return len(s.sessions)
+}
+
+// process a Hello
+func (s *Server) hello(sid, body []byte, from net.Addr) []byte {
+	if len(body) < 1 || len(body) < 1+int(body[0]) {
+		return nil
+	}
+	cookie := body[1 : 1+body[0]]
+	hello := body[1+body[0]:]
+
+	if !s.conf.SkipCookies && !s.validCookie(cookie, sid, from) {
+		return append(header(msgCookie, sid), s.cookie(sid, from, time.Now())...)
+	}
+
+	x, fresh := s.session(sid, from)
+	if x == nil {
+		return nil
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if !fresh {
+		// answer a retransmission with the same challenge
+		if x.reply != nil && bytes.Equal(x.hello, hello) && x.final == nil {
+			return append(header(msgChallenge, sid), x.reply...)
+		}
+		return nil
+	}
+
+	srv, err := s.start(hello)
+	if err != nil {
+		s.drop(sid, from)
+		return header(msgFailed, sid)
+	}
+
+	sc := srv.Challenge()
+	x.hello = append([]byte{}, hello...)
+	x.reply = sc.EncodeCBOR()
+	x.srv = srv
+	return append(header(msgChallenge, sid), x.reply...)
+}
+
+// start an SRP server for the client credentials 'hello'
+func (s *Server) start(hello []byte) (*srp.Server, error) {
+	cc, err := srp.DecodeClientCredentialsCBOR(hello)
+	if err != nil {
+		return nil, err
+	}
+
+	env, v, err := s.lookup(cc.IdentityHash)
+	if err != nil {
+		return nil, err
+	}
+
+	A, err := env.ParsePublicKey(cc.A)
+	if err != nil {
+		return nil, err
+	}
+	return env.NewServerFor(cc.IdentityHash, v, A)
+}
+
+// process a Proof
+func (s *Server) proof(sid, body []byte, from net.Addr) ([]byte, *Session) {
+	s.mu.Lock()
+	x := s.sessions[key(sid, from)]
+	s.mu.Unlock()
+	if x == nil {
+		return nil, nil
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if x.srv == nil {
+		return nil, nil
+	}
+	if x.final != nil {
+		// answer a retransmission with the same reply
+		if bytes.Equal(x.proof, body) {
+			return x.final, nil
+		}
+		return nil, nil
+	}
+
+	m, err := srp.DecodeProofCBOR(body)
+	if err != nil {
+		return nil, nil
+	}
+
+	x.proof = append([]byte{}, body...)
+	proof, ok := x.srv.CheckProof(m)
+	if !ok {
+		x.final = header(msgFailed, sid)
+		return x.final, nil
+	}
+
+	x.final = append(header(msgServerProof, sid), srp.EncodeProofCBOR(proof)...)
+	sess := &Session{
+		ID:     append([]byte{}, sid...),
+		Addr:   from,
+		Server: x.srv,
+	}
+	return x.final, sess
+}
+
+// return the session 'sid' of 'from' and true if it was just created; nil
+// if the server is full.
+func (s *Server) session(sid []byte, from net.Addr) (*session, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	k := key(sid, from)
+	if x, ok := s.sessions[k]; ok {
+		return x, false
+	}
+
+	if len(s.sessions) >= s.conf.MaxSessions || now.Sub(s.pruned) > s.conf.SessionTTL/2 {
+		s.prune(now)
+	}
+	if len(s.sessions) >= s.conf.MaxSessions {
+		return nil, false
+	}
+
+	x := &session{
+		expires: now.Add(s.conf.SessionTTL),
+	}
+	s.sessions[k] = x
+	return x, true
+}
+
+// forget the session 'sid' of 'from'
+func (s *Server) drop(sid []byte, from net.Addr) {
+	s.mu.Lock()
+	delete(s.sessions, key(sid, from))
+	s.mu.Unlock()
+}
+
+// forget expired sessions; the caller holds s.mu
+func (s *Server) prune(now time.Time) {
+	for k, x := range s.sessions {
+		if now.After(x.expires) {
+			delete(s.sessions, k)
+		}
+	}
+	s.pruned = now
+}
+
+// return the cookie for session 'sid' of 'from' in the epoch of 'now'
+func (s *Server) cookie(sid []byte, from net.Addr, now time.Time) []byte {
+	var e [8]byte
+
+	binary.BigEndian.PutUint64(e[:], uint64(now.UnixNano()/int64(cookieEpoch)))
+
+	h := hmac.New(sha256.New, s.secret)
+	h.Write(e[:])
+	h.Write(sid)
+	h.Write([]byte(from.String()))
+	return h.Sum(nil)[:cookieLen]
+}
+
+// return true if 'c' is a cookie of this or the previous epoch
+func (s *Server) validCookie(c, sid []byte, from net.Addr) bool {
+	if len(c) != cookieLen {
+		return false
+	}
+
+	now := time.Now()
+	return hmac.Equal(c, s.cookie(sid, from, now)) ||
+		hmac.Equal(c, s.cookie(sid, from, now.Add(-cookieEpoch)))
+}
+
+// return the key of session 'sid' of 'from'
+func key(sid []byte, from net.Addr) string {
+	return string(sid) + from.String()
+}
+
+// return the header of a message
+func header(typ byte, sid []byte) []byte {
+	b := make([]byte, 0, 256)
+	b = append(b, version, typ)
+	return append(b, sid...)
+}
+
+// split a datagram into its type, session id and body
+func parse(b []byte) (typ byte, sid, body []byte, ok bool) {
+	if len(b) < headerLen || b[0] != version {
+		return 0, nil, nil, false
+	}
+	return b[1], b[2:headerLen], b[headerLen:], true
+}
@@ -0,0 +1,234 @@
+// self test for datagram handshakes
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package datagram
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tomsons/go-srp"
+)
+
+// a PacketConn that drops the writes for which 'drop' returns true
+type lossyConn struct {
+	net.PacketConn
+
+	mu   sync.Mutex
+	n    int
+	drop func(n int) bool
+}
+
+func (c *lossyConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	c.mu.Lock()
+	c.n++
+	drop := c.drop != nil && c.drop(c.n)
+	c.mu.Unlock()
+
+	if drop {
+		return len(b), nil
+	}
+	return c.PacketConn.WriteTo(b, addr)
+}
+
+func listen(t *testing.T) net.PacketConn {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("can't listen on UDP: %s", err)
+	}
+	return pc
+}
+
+type env struct {
+	s *srp.SRP
+	v *srp.Verifier
+}
+
+func newEnv(t *testing.T) *env {
+	s, err := srp.New(2048)
+	if err != nil {
+		t.Fatalf("New: %s", err)
+	}
+
+	v, err := s.Verifier([]byte("user"), []byte("pass"), nil)
+	if err != nil {
+		t.Fatalf("Verifier: %s", err)
+	}
+	return &env{s, v}
+}
+
+func (e *env) lookup(ih []byte) (*srp.SRP, *srp.Verifier, error) {
+	if !e.v.MatchesIdentity(ih) {
+		return nil, nil, fmt.Errorf("unknown user")
+	}
+	return e.s, e.v, nil
+}
+
+func TestHandshake(t *testing.T) {
+	e := newEnv(t)
+	cfg := &Config{RTO: 20 * time.Millisecond}
+
+	srv, err := NewServer(e.lookup, cfg)
+	if err != nil {
+		t.Fatalf("NewServer: %s", err)
+	}
+
+	// the server loses its first cookie, challenge and proof
+	spc := &lossyConn{PacketConn: listen(t), drop: func(n int) bool { return n%2 == 1 }}
+	defer spc.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sessions := make(chan *Session, 4)
+	go srv.Serve(ctx, spc, func(s *Session) { sessions <- s })
+
+	for _, pw := range []string{"pass", "wrong"} {
+		cpc := listen(t)
+		defer cpc.Close()
+
+		c, err := e.s.NewClient([]byte("user"), []byte(pw))
+		if err != nil {
+			t.Fatalf("NewClient: %s", err)
+		}
+
+		err = Handshake(ctx, cpc, spc.LocalAddr(), c, cfg)
+		if pw == "wrong" {
+			if err == nil {
+				t.Fatalf("wrong password accepted")
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Handshake: %s", err)
+		}
+
+		s := <-sessions
+		if !bytes.Equal(s.Server.RawKey(), c.RawKey()) {
+			t.Fatalf("key mismatch")
+		}
+		if s.Addr.String() != cpc.LocalAddr().String() {
+			t.Fatalf("wrong client address %s", s.Addr)
+		}
+	}
+
+	select {
+	case <-sessions:
+		t.Fatalf("session for the wrong password")
+	default:
+	}
+}
+
+func TestServer(t *testing.T) {
+	e := newEnv(t)
+
+	srv, err := NewServer(e.lookup, nil)
+	if err != nil {
+		t.Fatalf("NewServer: %s", err)
+	}
+
+	c, err := e.s.NewClient([]byte("user"), []byte("pass"))
+	if err != nil {
+		t.Fatalf("NewClient: %s", err)
+	}
+
+	sid := []byte("12345678")
+	from := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1000}
+	cc := c.Hello()
+	hello := func(cookie []byte) []byte {
+		b := append(header(msgHello, sid), byte(len(cookie)))
+		return append(append(b, cookie...), cc.EncodeCBOR()...)
+	}
+
+	// hellos without a valid cookie only get a cookie
+	for _, cookie := range [][]byte{nil, make([]byte, cookieLen)} {
+		r, _ := srv.Handle(hello(cookie), from)
+		typ, _, body, ok := parse(r)
+		if !ok || typ != msgCookie || len(body) != cookieLen {
+			t.Fatalf("exp cookie, saw %x", r)
+		}
+		if srv.Len() != 0 {
+			t.Fatalf("state kept for unvalidated client")
+		}
+	}
+
+	r, _ := srv.Handle(hello(nil), from)
+	_, _, cookie, _ := parse(r)
+
+	// the cookie is bound to the address
+	other := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 2), Port: 1000}
+	if r, _ := srv.Handle(hello(cookie), other); r[1] != msgCookie {
+		t.Fatalf("cookie accepted from another address")
+	}
+
+	ch, _ := srv.Handle(hello(cookie), from)
+	if ch[1] != msgChallenge || srv.Len() != 1 {
+		t.Fatalf("exp challenge, saw %x", ch)
+	}
+
+	// retransmissions get the same challenge
+	if r, _ := srv.Handle(hello(cookie), from); !bytes.Equal(r, ch) {
+		t.Fatalf("different challenge for a retransmitted hello")
+	}
+
+	sc, err := srp.DecodeServerCredentialsCBOR(ch[headerLen:])
+	if err != nil {
+		t.Fatalf("challenge: %s", err)
+	}
+	m, err := c.Respond(sc)
+	if err != nil {
+		t.Fatalf("Respond: %s", err)
+	}
+
+	proof := append(header(msgProof, sid), srp.EncodeProofCBOR(m)...)
+	r1, sess := srv.Handle(proof, from)
+	if sess == nil || r1[1] != msgServerProof {
+		t.Fatalf("exp server proof, saw %x", r1)
+	}
+
+	// a retransmitted proof gets the same reply but no new session
+	r2, sess := srv.Handle(proof, from)
+	if sess != nil || !bytes.Equal(r1, r2) {
+		t.Fatalf("retransmitted proof started another session")
+	}
+
+	p, err := srp.DecodeProofCBOR(r1[headerLen:])
+	if err != nil || !c.CheckProof(p) {
+		t.Fatalf("bad server proof")
+	}
+
+	// garbage is ignored
+	for _, b := range [][]byte{nil, {version}, {2, msgHello, 1, 2, 3, 4, 5, 6, 7, 8}} {
+		if r, s := srv.Handle(b, from); r != nil || s != nil {
+			t.Fatalf("reply to garbage %x", b)
+		}
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	e := newEnv(t)
+
+	// a server that never answers
+	spc := listen(t)
+	defer spc.Close()
+	cpc := listen(t)
+	defer cpc.Close()
+
+	c, err := e.s.NewClient([]byte("user"), []byte("pass"))
+	if err != nil {
+		t.Fatalf("NewClient: %s", err)
+	}
+
+	cfg := &Config{RTO: 5 * time.Millisecond, Retries: 2}
+	if err := Handshake(context.Background(), cpc, spc.LocalAddr(), c, cfg); err == nil {
+		t.Fatalf("handshake without a server succeeded")
+	}
+}
@@ -0,0 +1,90 @@
+// decoded.go - pre-parsed verifiers for in-memory user caches
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+)
+
+// DecodedVerifier is an encoded verifier that was parsed once along with its
+// SRP environment. It can be kept in an in-memory user cache so that logins
+// by frequent users don't decode and validate the verifier every time;
+// Precompute() also saves part of their handshakes' work. A
+// DecodedVerifier is safe for concurrent use.
+type DecodedVerifier struct {
+	SRP      *SRP
+	Verifier *Verifier
+
+	ih string   // hex form of the hashed identity
+	v  *big.Int // numeric value of the verifier
+
+	pre precomputed // see Precompute()
+}
+
+// NewDecodedVerifier wraps an SRP environment and Verifier returned by
+// MakeSRPVerifier() or SRP.Verifier().
+func NewDecodedVerifier(s *SRP, v *Verifier) *DecodedVerifier {
+	return &DecodedVerifier{
+		SRP:      s,
+		Verifier: v,
+		ih:       hex.EncodeToString(v.i),
+		v:        big.NewInt(0).SetBytes(v.v),
+	}
+}
+
+// MakeSRPVerifiers decodes a batch of encoded verifiers (see
+// MakeSRPVerifier()). Verifiers that use the same prime field share a
+// single copy of it. The options 'opts' are applied to every environment.
+func MakeSRPVerifiers(vs []string, opts ...Option) ([]*DecodedVerifier, error) {
+	dv := make([]*DecodedVerifier, 0, len(vs))
+	fields := make(map[string]*primeField)
+
+	for i, b := range vs {
+		s, v, err := MakeSRPVerifier(b, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("verifier %d: %s", i, err)
+		}
+
+		key := fmt.Sprintf("%d:%x:%x", v.pf.n, v.pf.N, v.pf.g)
+		if pf, ok := fields[key]; ok {
+			s.pf = pf
+			v.pf = pf
+		} else {
+			fields[key] = v.pf
+		}
+
+		dv = append(dv, NewDecodedVerifier(s, v))
+	}
+	return dv, nil
+}
+
+// Identity returns the hashed identity of the verifier in the same form
+// as returned by Verifier.Encode(); for verifiers without a blinded
+// identity, it is also the form returned by ServerBegin().
+func (d *DecodedVerifier) Identity() string {
+	return d.ih
+}
+
+// NewServer starts a new handshake with a client whose public key is 'A'.
+// Verifiers with a blinded identity need NewServerFor().
+func (d *DecodedVerifier) NewServer(A *big.Int) (*Server, error) {
+	if d.Verifier.idk != nil {
+		return nil, errBlinded
+	}
+	return d.SRP.initServer(new(Server), d.Verifier, d.Verifier.i, d.v, d.table(), A)
+}
+
+// NewServerFor starts a new handshake with the client whose hashed identity
+// is 'ih' and public key is 'A' (see SRP.NewServerFor()).
+func (d *DecodedVerifier) NewServerFor(ih []byte, A *big.Int) (*Server, error) {
+	if !d.Verifier.MatchesIdentity(ih) {
+		return nil, fmt.Errorf("srp: verifier doesn't match identity")
+	}
+	return d.SRP.initServer(new(Server), d.Verifier, ih, d.v, d.table(), A)
+}
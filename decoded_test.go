@@ -0,0 +1,69 @@
+// self test for decoded verifiers
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestMakeSRPVerifiers(t *testing.T) {
+	assert := newAsserter(t)
+
+	s, err := New(2048)
+	assert(err == nil, "New: %s", err)
+
+	var vs []string
+	for i := 0; i < 4; i++ {
+		v, err := s.Verifier([]byte(fmt.Sprintf("user%d", i)), []byte("pass"), nil)
+		assert(err == nil, "Verifier: %s", err)
+
+		_, vh := v.Encode()
+		vs = append(vs, vh)
+	}
+
+	dv, err := MakeSRPVerifiers(vs)
+	assert(err == nil, "MakeSRPVerifiers: %s", err)
+	assert(len(dv) == len(vs), "exp %d verifiers, saw %d", len(vs), len(dv))
+
+	cache := make(map[string]*DecodedVerifier)
+	for i, d := range dv {
+		assert(d.SRP.pf == dv[0].SRP.pf, "%d: prime field not shared", i)
+		cache[d.Identity()] = d
+	}
+
+	for i := 0; i < 4; i++ {
+		c, err := s.NewClient([]byte(fmt.Sprintf("user%d", i)), []byte("pass"))
+		assert(err == nil, "NewClient: %s", err)
+
+		ih, _, err := ServerBegin(c.Credentials())
+		assert(err == nil, "ServerBegin: %s", err)
+
+		d, ok := cache[ih]
+		assert(ok, "%d: can't find user", i)
+
+		// a cached verifier is used for several logins
+		for j := 0; j < 2; j++ {
+			c.Reset()
+			_, A, err := ServerBegin(c.Credentials())
+			assert(err == nil, "ServerBegin: %s", err)
+
+			srv, err := d.NewServer(A)
+			assert(err == nil, "NewServer: %s", err)
+
+			m, err := c.Generate(srv.Credentials())
+			assert(err == nil, "Generate: %s", err)
+
+			proof, ok := srv.ClientOk(m)
+			assert(ok, "%d: bad client proof", i)
+			assert(c.ServerOk(proof), "%d: bad server proof", i)
+		}
+	}
+
+	_, err = MakeSRPVerifiers(append(vs, "junk"))
+	assert(err != nil, "decoded junk verifier")
+}
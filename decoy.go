@@ -0,0 +1,77 @@
+// decoy.go - fake verifiers for unknown identities
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"math/big"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// A server that rejects an unknown identity right after the client's hello
+// tells an attacker which identities exist. It should instead run the
+// handshake with a decoy verifier and fail at the client's proof, as it
+// would for a wrong password. The challenge of a decoy must look like a
+// real one: it has the group, hash and password hardening of the
+// environment, and the same identity always gets the same salt, since a
+// real user's salt doesn't change between logins either. Both salt and
+// verifier are derived from a secret key of the server and the hashed
+// identity:
+//
+//	salt = HKDF-SHA256(key, "srp decoy salt" || I)
+//	v    = HKDF-SHA256(key, "srp decoy verifier" || I) mod N
+//
+// No password matches a decoy. Servers with several instances should share
+// the key, and keep it across restarts, so that they all send the same
+// salt. Lookups of unknown identities may still be faster than those of
+// known ones; see EqualizeLatency().
+
+// minimum size of the key of DecoyVerifier() in bytes
+const minDecoyKeyLen = 16
+
+// DecoyVerifier returns a verifier for the hashed identity 'ih' of an
+// unknown user that no password matches (see above); 'key' is a secret of
+// the server of at least 16 bytes.
+func (s *SRP) DecoyVerifier(key, ih []byte) (*Verifier, error) {
+	if len(key) < minDecoyKeyLen {
+		return nil, fmt.Errorf("srp: decoy key must be at least %d bytes", minDecoyKeyLen)
+	}
+	if len(ih) == 0 {
+		return nil, fmt.Errorf("srp: empty identity")
+	}
+
+	pf := s.pf
+	salt := decoyBytes(key, "srp decoy salt", ih, s.saltSize())
+
+	// 8 more bytes than N make the bias of the reduction negligible
+	v := big.NewInt(0).SetBytes(decoyBytes(key, "srp decoy verifier", ih, pf.n+8))
+	v.Mod(v, pf.N)
+
+	return &Verifier{
+		i:   ih,
+		s:   salt,
+		v:   v.Bytes(),
+		h:   s.h,
+		pf:  pf,
+		kdf: s.kdf,
+		xd:  s.xd,
+		env: s,
+	}, nil
+}
+
+// return 'n' bytes derived from 'key' for the identity 'ih' with 'label'
+func decoyBytes(key []byte, label string, ih []byte, n int) []byte {
+	info := append([]byte(label), ih...)
+	b := make([]byte, n)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, key, nil, info), b); err != nil {
+		panic(fmt.Sprintf("srp: hkdf: %s", err))
+	}
+	return b
+}
@@ -0,0 +1,65 @@
+// self test for decoy verifiers
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDecoyVerifier(t *testing.T) {
+	assert := newAsserter(t)
+
+	user := []byte("nobody")
+	pass := []byte("pass")
+	key := []byte("0123456789abcdef")
+	kdf := KDF{Alg: KDFArgon2id, Time: 1, Memory: 64, Threads: 1}
+
+	// a "fips" build rejects the KDF
+	var opts []Option
+	if !FIPSBuild {
+		opts = append(opts, WithKDF(kdf))
+	}
+	s, err := New(2048, opts...)
+	assert(err == nil, "New: %s", err)
+
+	_, err = s.DecoyVerifier(key[:15], []byte("x"))
+	assert(err != nil, "accepted short key")
+
+	c, err := s.NewClient(user, pass)
+	assert(err == nil, "NewClient: %s", err)
+	cc := c.Hello()
+
+	d1, err := s.DecoyVerifier(key, cc.IdentityHash)
+	assert(err == nil, "DecoyVerifier: %s", err)
+	d2, err := s.DecoyVerifier(key, cc.IdentityHash)
+	assert(err == nil, "DecoyVerifier: %s", err)
+	assert(d1.Equal(d2), "decoys of an identity differ")
+	assert(len(d1.Salt()) == s.FieldSize()/8, "salt of %d bytes", len(d1.Salt()))
+
+	d3, err := s.DecoyVerifier(key, []byte("other"))
+	assert(err == nil, "DecoyVerifier: %s", err)
+	assert(!bytes.Equal(d1.Salt(), d3.Salt()), "identities share a salt")
+
+	d4, err := s.DecoyVerifier([]byte("fedcba9876543210"), cc.IdentityHash)
+	assert(err == nil, "DecoyVerifier: %s", err)
+	assert(!bytes.Equal(d1.Salt(), d4.Salt()), "keys share a salt")
+
+	// the challenge looks like a real one and the proof fails
+	A, err := s.ParsePublicKey(cc.A)
+	assert(err == nil, "ParsePublicKey: %s", err)
+	srv, err := s.NewServerFor(cc.IdentityHash, d1, A)
+	assert(err == nil, "NewServerFor: %s", err)
+
+	sc := srv.Challenge()
+	assert(FIPSBuild || sc.KDF != nil && *sc.KDF == kdf, "challenge without the KDF")
+
+	m, err := c.Respond(sc)
+	assert(err == nil, "Respond: %s", err)
+	_, ok := srv.CheckProof(m)
+	assert(!ok, "decoy accepted a password")
+}
@@ -0,0 +1,109 @@
+// device.go - remembered devices that skip the full SRP handshake
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+)
+
+// After a successful handshake, the server can remember the client's
+// device: it picks a random device id and both sides derive a device key
+// from the session key K:
+//
+//	Kd = HKDF(K, "srp device" || id)
+//
+// The server stores <id, Kd> for the user and sends only the id to the
+// client, which stores its own copy of <id, Kd>. Later logins from that
+// device use a cheap challenge-response instead of SRP:
+//
+//	server -> client: nonce (see SRP.NewDeviceChallenge())
+//	client -> server: HMAC-SHA256(Kd, "srp device" || id || I || nonce)
+//
+// The server decides when to demand a full handshake instead, e.g., when
+// the credential is too old, after a password change or for sensitive
+// operations; it simply doesn't offer a challenge then. Unlike the
+// verifier, the device keys stored by the server are secrets.
+
+// Sizes of device ids, keys and challenges in bytes
+const (
+	deviceIDLen        = 16
+	deviceKeyLen       = 32
+	deviceChallengeLen = 32
+)
+
+var deviceLabel = []byte("srp device")
+
+// DeviceCredential is the long-term secret of a remembered device
+type DeviceCredential struct {
+	ID  []byte
+	Key []byte
+}
+
+// RememberDevice returns a new credential for the client's device; it must
+// be called after the client's proof was verified. The server stores the
+// credential against the user and sends its ID (but not the Key) to the
+// client (see Client.RememberDevice()).
+func (s *Server) RememberDevice() (DeviceCredential, error) {
+	if !s.authed {
+		return DeviceCredential{}, fmt.Errorf("srp: client isn't authenticated")
+	}
+
+	id := s.s.randbytes(deviceIDLen)
+	return DeviceCredential{
+		ID:  id,
+		Key: s.s.deviceKey(s.xK, id),
+	}, nil
+}
+
+// RememberDevice returns the credential for the device 'id' chosen by the
+// server; it must be called after the server's proof was verified. The
+// client keeps the credential in its secure storage.
+func (c *Client) RememberDevice(id []byte) (DeviceCredential, error) {
+	if !c.authed {
+		return DeviceCredential{}, fmt.Errorf("srp: server isn't authenticated")
+	}
+	if len(id) != deviceIDLen {
+		return DeviceCredential{}, fmt.Errorf("srp: invalid device id")
+	}
+
+	return DeviceCredential{
+		ID:  append([]byte{}, id...),
+		Key: c.s.deviceKey(c.xK, id),
+	}, nil
+}
+
+// NewDeviceChallenge returns a random challenge for a remembered device
+// from the random source of this environment (see WithRand()). The server
+// must use each challenge only once.
+func (s *SRP) NewDeviceChallenge() []byte {
+	return s.randbytes(deviceChallengeLen)
+}
+
+// Respond returns the device's response to the server 'challenge' for the
+// user with hashed identity 'ih' (see Client.Hello()).
+func (d *DeviceCredential) Respond(ih, challenge []byte) []byte {
+	m := hmac.New(sha256.New, d.Key)
+	m.Write(deviceLabel)
+	m.Write(d.ID)
+	m.Write(ih)
+	m.Write(challenge)
+	return m.Sum(nil)
+}
+
+// Verify returns true if 'resp' is the valid response of this device to
+// 'challenge' for the user with hashed identity 'ih'.
+func (d *DeviceCredential) Verify(ih, challenge, resp []byte) bool {
+	return ctEqual(d.Respond(ih, challenge), resp)
+}
+
+// derive the key of device 'id' from the session key 'K'
+func (s *SRP) deviceKey(K, id []byte) []byte {
+	info := append(append([]byte{}, deviceLabel...), id...)
+	return s.expandKey(K, info, deviceKeyLen)
+}
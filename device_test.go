@@ -0,0 +1,67 @@
+// self test for remembered devices
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestRememberDevice(t *testing.T) {
+	assert := newAsserter(t)
+
+	user := []byte("user")
+	pass := []byte("pass")
+
+	s, err := New(2048)
+	assert(err == nil, "New: %s", err)
+
+	v, err := s.Verifier(user, pass, nil)
+	assert(err == nil, "Verifier: %s", err)
+
+	c, err := s.NewClient(user, pass)
+	assert(err == nil, "NewClient: %s", err)
+
+	cc := c.Hello()
+	srv, err := s.NewServer(v, big.NewInt(0).SetBytes(cc.A))
+	assert(err == nil, "NewServer: %s", err)
+
+	_, err = srv.RememberDevice()
+	assert(err != nil, "remembered device before authentication")
+
+	m, err := c.Respond(srv.Challenge())
+	assert(err == nil, "Respond: %s", err)
+
+	proof, ok := srv.CheckProof(m)
+	assert(ok, "CheckProof failed")
+
+	sd, err := srv.RememberDevice()
+	assert(err == nil, "Server.RememberDevice: %s", err)
+
+	_, err = c.RememberDevice(sd.ID)
+	assert(err != nil, "remembered device before server was authenticated")
+
+	assert(c.CheckProof(proof), "client: bad server proof")
+
+	cd, err := c.RememberDevice(sd.ID)
+	assert(err == nil, "Client.RememberDevice: %s", err)
+	assert(ctEqual(cd.Key, sd.Key), "device key mismatch")
+
+	// a later login from the device
+	ch := s.NewDeviceChallenge()
+	resp := cd.Respond(cc.IdentityHash, ch)
+	assert(sd.Verify(cc.IdentityHash, ch, resp), "device response rejected")
+	assert(!sd.Verify(cc.IdentityHash, s.NewDeviceChallenge(), resp), "accepted response to another challenge")
+	assert(!sd.Verify(s.hashbyte([]byte("other")), ch, resp), "accepted response for another user")
+
+	// challenges come from the environment's random source
+	r1, err := New(2048, WithRand(&counterReader{}))
+	assert(err == nil, "New: %s", err)
+	r2, err := New(2048, WithRand(&counterReader{}))
+	assert(err == nil, "New: %s", err)
+	assert(ctEqual(r1.NewDeviceChallenge(), r2.NewDeviceChallenge()), "WithRand ignored")
+}
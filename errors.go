@@ -0,0 +1,107 @@
+// errors.go - error categories for operators
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// Reasons a client proof is rejected by Server.VerifyClientProof(). They
+// are meant for internal logs and metrics; a server must not tell the
+// client which one applied.
+var (
+	// ErrMalformedProof means the proof isn't a hex string of the size of
+	// the hash
+	ErrMalformedProof = fmt.Errorf("srp: malformed client proof")
+
+	// ErrProofMismatch means the client derived a different session key
+	// (e.g., it has the wrong password) or uses another proof scheme
+	ErrProofMismatch = fmt.Errorf("srp: client proof mismatch")
+
+	// ErrReplayed means the replay cache has seen the value before (see
+	// WithReplayCache())
+	ErrReplayed = fmt.Errorf("srp: replayed handshake")
+)
+
+// Errors of malformed or out of range public keys and credentials
+var (
+	// ErrBadClientKey means the client's credentials or public key A are
+	// malformed or too large
+	ErrBadClientKey = fmt.Errorf("srp: invalid client public key")
+
+	// ErrBadServerKey means the server's credentials or public key B are
+	// malformed or too large
+	ErrBadServerKey = fmt.Errorf("srp: invalid server public key")
+)
+
+// ErrDowngrade is matched (with errors.Is()) by the errors of clients
+// whose server asked for weaker password hardening (or another derivation
+// of x) than the environment requires
+var ErrDowngrade = fmt.Errorf("srp: parameter downgrade")
+
+// ErrSecurityAbort is matched (with errors.Is()) by the errors of handshakes
+// that were aborted because a peer sent a value that would make the session
+// key predictable. Such values aren't sent by honest peers and are a sign
+// of an attack; errors.As() with a *SecurityAbortError yields the reason.
+var ErrSecurityAbort = fmt.Errorf("srp: handshake aborted")
+
+// AbortReason identifies the check that aborted a handshake
+type AbortReason int
+
+// Checks that abort a handshake
+const (
+	AbortZeroA          AbortReason = iota + 1 // A = 0 mod N
+	AbortZeroB                                 // B = 0 mod N
+	AbortZeroU                                 // u = 0
+	AbortDegenerateBase                        // Av^u or B - kg^x is 0, 1 or N-1 mod N
+	AbortDegenerateS                           // S is 0 or 1
+)
+
+var abortReasons = map[AbortReason]string{
+	AbortZeroA:          "A is zero",
+	AbortZeroB:          "B is zero",
+	AbortZeroU:          "u is zero",
+	AbortDegenerateBase: "degenerate base",
+	AbortDegenerateS:    "degenerate shared secret",
+}
+
+// String describes the reason
+func (r AbortReason) String() string {
+	if s, ok := abortReasons[r]; ok {
+		return s
+	}
+	return fmt.Sprintf("reason %d", int(r))
+}
+
+// SecurityAbortError is the error of an aborted handshake
+type SecurityAbortError struct {
+	Reason AbortReason
+}
+
+// Error implements error
+func (e *SecurityAbortError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrSecurityAbort, e.Reason)
+}
+
+// Is returns true if 'target' is ErrSecurityAbort
+func (e *SecurityAbortError) Is(target error) bool {
+	return target == ErrSecurityAbort
+}
+
+// return the error of a handshake aborted for reason 'r'
+func abort(r AbortReason) error {
+	return &SecurityAbortError{Reason: r}
+}
+
+// return true if 'x' is 0, 1 or N-1 mod N, i.e., it has order at most 2
+func degenerate(x, N *big.Int) bool {
+	if x.Sign() == 0 || x.Cmp(one) == 0 {
+		return true
+	}
+	return big.NewInt(0).Add(x, one).Cmp(N) == 0
+}
@@ -0,0 +1,94 @@
+// self test for error categories
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestSecurityAbort(t *testing.T) {
+	assert := newAsserter(t)
+
+	user := []byte("user")
+	pass := []byte("pass")
+
+	s, err := New(2048)
+	assert(err == nil, "New: %s", err)
+	pf := s.pf
+
+	v, err := s.Verifier(user, pass, nil)
+	assert(err == nil, "Verifier: %s", err)
+
+	// kg^x of the user; a server that knows it can force S
+	kgx := s.ComputeVerifier(s.ComputeX(user, pass, v.s))
+	kgx.Mul(kgx, s.ComputeK())
+	kgx.Mod(kgx, pf.N)
+
+	add := func(x *big.Int, d int64) *big.Int {
+		z := big.NewInt(0).Add(x, big.NewInt(d))
+		return z.Mod(z, pf.N)
+	}
+
+	// malicious servers
+	servers := []struct {
+		B      *big.Int
+		reason AbortReason
+	}{
+		{big.NewInt(0), AbortZeroB},
+		{pf.N, AbortZeroB},
+		{kgx, AbortDegenerateBase},
+		{add(kgx, 1), AbortDegenerateBase},
+		{add(kgx, -1), AbortDegenerateBase},
+	}
+
+	for i, x := range servers {
+		c, err := s.NewClient(user, pass)
+		assert(err == nil, "NewClient: %s", err)
+
+		_, err = c.Respond(ServerCredentials{Salt: v.s, B: x.B.Bytes()})
+		assert(errors.Is(err, ErrSecurityAbort), "%d: exp abort, saw %v", i, err)
+
+		var sa *SecurityAbortError
+		assert(errors.As(err, &sa) && sa.Reason == x.reason, "%d: exp %s, saw %v", i, x.reason, err)
+	}
+
+	// malicious clients (and a corrupt verifier)
+	corrupt := &Verifier{i: v.i, s: v.s, v: []byte{1}, h: v.h, pf: v.pf}
+	nA := big.NewInt(0).Sub(pf.N, one)
+	clients := []struct {
+		v      *Verifier
+		A      *big.Int
+		reason AbortReason
+	}{
+		{v, big.NewInt(0), AbortZeroA},
+		{v, pf.N, AbortZeroA},
+		{corrupt, one, AbortDegenerateBase},
+		{corrupt, nA, AbortDegenerateBase},
+	}
+
+	for i, x := range clients {
+		_, err = s.NewServer(x.v, x.A)
+		var sa *SecurityAbortError
+		assert(errors.As(err, &sa) && sa.Reason == x.reason, "%d: exp %s, saw %v", i, x.reason, err)
+	}
+
+	// degenerate values
+	for _, x := range []*big.Int{big.NewInt(0), one, nA} {
+		_, err = checkS(big.NewInt(2), x, pf.N)
+		assert(errors.Is(err, ErrSecurityAbort), "%s: not degenerate", x)
+	}
+	for _, x := range []*big.Int{big.NewInt(0), one} {
+		_, err = checkS(x, big.NewInt(2), pf.N)
+		assert(errors.Is(err, ErrSecurityAbort), "S=%s: not degenerate", x)
+	}
+	_, err = checkS(big.NewInt(3), big.NewInt(2), pf.N)
+	assert(err == nil, "valid S rejected: %v", err)
+
+	assert(AbortZeroU.String() == "u is zero", "wrong reason %s", AbortZeroU)
+}
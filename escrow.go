@@ -0,0 +1,116 @@
+// escrow.go - opt-in delivery of session keys to an escrow
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Some regulated deployments must retain the session keys of completed
+// handshakes for a lawful audit. WithKeyEscrow() makes clients or servers
+// deliver the session key K of each handshake, once the peer's proof is
+// verified, to a function of the application, e.g., one that stores it
+// encrypted to an auditor's key. Escrow defeats the forward secrecy of
+// SRP for these sessions; it is never on by default and an environment
+// with it can't hide it:
+//
+//   - every delivery writes a line starting with EscrowMarker to a log
+//     that must be configured, before the key is delivered;
+//   - the handshake fails (the server rejects the client's proof, the
+//     client rejects the server's) if the log or the delivery fails, so
+//     that no session runs on a key that wasn't escrowed.
+//
+// EscrowConfig.Wrap lets the application deliver a wrapped form of K, so
+// that the delivery function never sees the key itself.
+
+// EscrowMarker starts each line written to the escrow log
+const EscrowMarker = "SRP-KEY-ESCROW"
+
+// ErrEscrow is matched (with errors.Is()) by the errors of handshakes that
+// failed because their session key couldn't be escrowed
+var ErrEscrow = fmt.Errorf("srp: session key escrow failed")
+
+// EscrowRecord is the session key of a completed handshake
+type EscrowRecord struct {
+	Marker   string    // EscrowMarker
+	Role     string    // "client" or "server"
+	Time     time.Time // of the delivery
+	Identity []byte    // the hashed identity I
+	Session  []byte    // H(pad(B)); identifies the session to both sides
+	Key      []byte    // K, or Wrap(K) if Wrapped
+	Wrapped  bool
+}
+
+// EscrowConfig configures WithKeyEscrow()
+type EscrowConfig struct {
+	// Deliver receives the record of each completed handshake; it must
+	// be safe for concurrent use. An error fails the handshake.
+	Deliver func(r *EscrowRecord) error
+
+	// Wrap, if not nil, returns the form of K to deliver instead of K
+	Wrap func(K []byte) ([]byte, error)
+
+	// Log receives a marker line for each delivery with a single call to
+	// Write(); an error fails the handshake.
+	Log io.Writer
+}
+
+// WithKeyEscrow makes clients and servers in this environment deliver the
+// session key of each completed handshake as configured in 'cfg' (see
+// above). Deliver and Log are mandatory.
+func WithKeyEscrow(cfg EscrowConfig) Option {
+	return func(s *SRP) error {
+		if cfg.Deliver == nil {
+			return fmt.Errorf("srp: key escrow needs a delivery function")
+		}
+		if cfg.Log == nil {
+			return fmt.Errorf("srp: key escrow needs a log")
+		}
+		s.escrow = &cfg
+		return nil
+	}
+}
+
+// deliver the session key 'K' of the handshake of 'role' for the hashed
+// identity 'ih' and server public key 'B' to the escrow, if any
+func (s *SRP) escrowKey(role string, ih []byte, B, K []byte) error {
+	e := s.escrow
+	if e == nil {
+		return nil
+	}
+
+	r := &EscrowRecord{
+		Marker:   EscrowMarker,
+		Role:     role,
+		Time:     time.Now(),
+		Identity: ih,
+		Session:  s.hashbyte(B),
+		Key:      append([]byte{}, K...),
+	}
+	if e.Wrap != nil {
+		w, err := e.Wrap(K)
+		if err != nil {
+			return fmt.Errorf("%w: wrap: %s", ErrEscrow, err)
+		}
+		r.Key = w
+		r.Wrapped = true
+	}
+
+	line := fmt.Sprintf("%s role=%s time=%s identity=%s session=%s wrapped=%t\n",
+		EscrowMarker, role, r.Time.UTC().Format(time.RFC3339Nano),
+		hex.EncodeToString(ih), hex.EncodeToString(r.Session), r.Wrapped)
+	if _, err := e.Log.Write([]byte(line)); err != nil {
+		return fmt.Errorf("%w: log: %s", ErrEscrow, err)
+	}
+	if err := e.Deliver(r); err != nil {
+		return fmt.Errorf("%w: %s", ErrEscrow, err)
+	}
+	return nil
+}
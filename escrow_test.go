@@ -0,0 +1,120 @@
+// self test for WithKeyEscrow()
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// escrowRecorder collects escrowed records
+type escrowRecorder struct {
+	sync.Mutex
+	recs []*EscrowRecord
+	err  error
+}
+
+func (e *escrowRecorder) deliver(r *EscrowRecord) error {
+	e.Lock()
+	defer e.Unlock()
+	if e.err != nil {
+		return e.err
+	}
+	e.recs = append(e.recs, r)
+	return nil
+}
+
+func TestKeyEscrow(t *testing.T) {
+	assert := newAsserter(t)
+
+	var log bytes.Buffer
+	er := &escrowRecorder{}
+	s, err := New(2048, WithKeyEscrow(EscrowConfig{Deliver: er.deliver, Log: &log}))
+	assert(err == nil, "New: %s", err)
+
+	v, err := s.Verifier([]byte("user"), []byte("pass"), nil)
+	assert(err == nil, "Verifier: %s", err)
+
+	c, err := s.NewClient([]byte("user"), []byte("pass"))
+	assert(err == nil, "NewClient: %s", err)
+	srv, err := s.NewServer(v, c.xA)
+	assert(err == nil, "NewServer: %s", err)
+	assert(authenticate(c, srv), "handshake failed")
+
+	assert(len(er.recs) == 2, "exp 2 records, saw %d", len(er.recs))
+	rs, rc := er.recs[0], er.recs[1]
+	assert(rs.Role == "server" && rc.Role == "client", "roles %s %s", rs.Role, rc.Role)
+	for _, r := range er.recs {
+		assert(r.Marker == EscrowMarker, "marker %q", r.Marker)
+		assert(bytes.Equal(r.Key, c.RawKey()) && !r.Wrapped, "%s: wrong key", r.Role)
+		assert(bytes.Equal(r.Identity, c.i), "%s: wrong identity", r.Role)
+	}
+	assert(bytes.Equal(rs.Session, rc.Session), "sessions differ")
+
+	lines := strings.Split(strings.TrimSpace(log.String()), "\n")
+	assert(len(lines) == 2, "exp 2 log lines, saw %d", len(lines))
+	for _, l := range lines {
+		assert(strings.HasPrefix(l, EscrowMarker+" role="), "log line %q", l)
+	}
+
+	// a failed handshake isn't escrowed
+	c, err = s.NewClient([]byte("user"), []byte("wrong"))
+	assert(err == nil, "NewClient: %s", err)
+	srv, err = s.NewServer(v, c.xA)
+	assert(err == nil, "NewServer: %s", err)
+	assert(!authenticate(c, srv), "wrong password accepted")
+	assert(len(er.recs) == 2, "failed handshake escrowed")
+
+	// a failed delivery fails the handshake
+	er.err = errors.New("escrow down")
+	c, err = s.NewClient([]byte("user"), []byte("pass"))
+	assert(err == nil, "NewClient: %s", err)
+	srv, err = s.NewServer(v, c.xA)
+	assert(err == nil, "NewServer: %s", err)
+	m, err := c.Respond(srv.Challenge())
+	assert(err == nil, "Respond: %s", err)
+	_, err = srv.verifyProof(m)
+	assert(errors.Is(err, ErrEscrow), "exp ErrEscrow, saw %v", err)
+	assert(srv.authed == false, "server authenticated without escrow")
+}
+
+func TestKeyEscrowWrap(t *testing.T) {
+	assert := newAsserter(t)
+
+	var log bytes.Buffer
+	er := &escrowRecorder{}
+	wrap := func(K []byte) ([]byte, error) {
+		return append([]byte("wrapped:"), K...), nil
+	}
+
+	// only the server escrows
+	s, err := New(2048, WithKeyEscrow(EscrowConfig{Deliver: er.deliver, Wrap: wrap, Log: &log}))
+	assert(err == nil, "New: %s", err)
+	cs, err := New(2048)
+	assert(err == nil, "New: %s", err)
+
+	v, err := s.Verifier([]byte("user"), []byte("pass"), nil)
+	assert(err == nil, "Verifier: %s", err)
+	c, err := cs.NewClient([]byte("user"), []byte("pass"))
+	assert(err == nil, "NewClient: %s", err)
+	srv, err := s.NewServer(v, c.xA)
+	assert(err == nil, "NewServer: %s", err)
+	assert(authenticate(c, srv), "handshake failed")
+
+	assert(len(er.recs) == 1, "exp 1 record, saw %d", len(er.recs))
+	r := er.recs[0]
+	assert(r.Wrapped && bytes.Equal(r.Key, append([]byte("wrapped:"), c.RawKey()...)), "key not wrapped")
+	assert(strings.Contains(log.String(), "wrapped=true"), "log: %s", log.String())
+
+	_, err = New(2048, WithKeyEscrow(EscrowConfig{Deliver: er.deliver}))
+	assert(err != nil, "escrow without a log")
+	_, err = New(2048, WithKeyEscrow(EscrowConfig{Log: &log}))
+	assert(err != nil, "escrow without a delivery")
+}
@@ -0,0 +1,283 @@
+// events.go - notifying security pipelines of attacks on handshakes
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Some failed handshakes are a sign of an attack rather than of a user
+// who mistyped a password. A Notifier watches the failures of the clients
+// and servers of the environments it is given to (see
+// WithSecurityNotifier()) and reports:
+//
+//   - EventBadProofs: an identity had a number of bad client proofs
+//     within a window, i.e., someone is guessing its password;
+//   - EventInvalidKeys: a number of public keys (A or B) were malformed
+//     or aborted handshakes within a window, across all identities;
+//   - EventDowngrade: a peer asked for another group, hash or weaker
+//     password hardening than the environment requires (each attempt).
+//
+// Each event is a JSON document (see SecurityEvent) delivered by a
+// function of the application, e.g., a POST to a webhook of the SOC (see
+// package srphttp). Delivery runs in the background so that handshakes
+// never wait for it; failed deliveries are retried with exponential
+// backoff, and events are dropped while the queue is full. Events carry
+// the hashed identity but never a secret.
+
+// Kinds of security events
+const (
+	EventBadProofs   = "bad_proofs"
+	EventInvalidKeys = "invalid_public_keys"
+	EventDowngrade   = "downgrade"
+)
+
+// SecurityEvent is the JSON payload of a security event
+type SecurityEvent struct {
+	Kind     string    `json:"kind"`               // one of the Event* kinds
+	Time     time.Time `json:"time"`               // of the failure that raised it
+	Role     string    `json:"role"`               // "client" or "server"
+	Identity string    `json:"identity,omitempty"` // hex hashed identity, if known
+	Count    int       `json:"count"`              // failures within the window
+	Window   int       `json:"window_seconds"`     // the window of Count
+	Reason   string    `json:"reason"`             // FailureReason of the last failure
+	Error    string    `json:"error"`              // its error
+	Params   string    `json:"params"`             // hex Fingerprint() of the environment
+}
+
+// NotifierConfig configures a Notifier; zero fields take the defaults
+type NotifierConfig struct {
+	// Deliver sends the JSON payload of an event; it is required and is
+	// only called from the delivery goroutine. An error retries.
+	Deliver func(payload []byte) error
+
+	// OnError, if set, is called with the error of an event that
+	// couldn't be delivered or was dropped
+	OnError func(err error)
+
+	BadProofs   int           // bad proofs of an identity that raise an event; default 5
+	InvalidKeys int           // invalid public keys that raise an event; default 20
+	Window      time.Duration // of the counts above; default 10m
+	Retries     int           // deliveries after the first one; default 3, < 0 for none
+	Backoff     time.Duration // before the first retry, doubled for each; default 1s
+	Queue       int           // events waiting for delivery; default 256
+	Keys        int           // identities counted; default 100000
+}
+
+// Notifier raises security events (see above). It is safe for concurrent
+// use and may be shared by many environments.
+type Notifier struct {
+	cfg NotifierConfig
+
+	mu sync.Mutex
+	m  map[string]*eventCount
+
+	q    chan []byte
+	wg   sync.WaitGroup
+	once sync.Once
+
+	now   func() time.Time    // for tests
+	sleep func(time.Duration) // for tests
+}
+
+// the failures of one identity (or of all, for invalid keys)
+type eventCount struct {
+	start time.Time // of the window
+	n     int
+}
+
+// NewNotifier creates a Notifier with the configuration 'cfg' and starts
+// its delivery; Close() stops it.
+func NewNotifier(cfg NotifierConfig) (*Notifier, error) {
+	if cfg.Deliver == nil {
+		return nil, fmt.Errorf("srp: notifier without a delivery function")
+	}
+	if cfg.BadProofs <= 0 {
+		cfg.BadProofs = 5
+	}
+	if cfg.InvalidKeys <= 0 {
+		cfg.InvalidKeys = 20
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = 10 * time.Minute
+	}
+	if cfg.Retries < 0 {
+		cfg.Retries = 0
+	} else if cfg.Retries == 0 {
+		cfg.Retries = 3
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = time.Second
+	}
+	if cfg.Queue <= 0 {
+		cfg.Queue = 256
+	}
+	if cfg.Keys <= 0 {
+		cfg.Keys = 100000
+	}
+
+	n := &Notifier{
+		cfg:   cfg,
+		m:     make(map[string]*eventCount),
+		q:     make(chan []byte, cfg.Queue),
+		now:   time.Now,
+		sleep: time.Sleep,
+	}
+	n.wg.Add(1)
+	go n.run(n.q)
+	return n, nil
+}
+
+// WithSecurityNotifier makes clients and servers in this environment
+// report their failures to 'n'
+func WithSecurityNotifier(n *Notifier) Option {
+	return func(s *SRP) error {
+		if n == nil {
+			return fmt.Errorf("srp: nil security notifier")
+		}
+		s.sn = n
+		return nil
+	}
+}
+
+// Close delivers the queued events and stops the delivery; events raised
+// afterwards are dropped.
+func (n *Notifier) Close() {
+	n.once.Do(func() {
+		n.mu.Lock()
+		close(n.q)
+		n.q = nil
+		n.mu.Unlock()
+	})
+	n.wg.Wait()
+}
+
+// report the failure 'err' of the 'role' side of a handshake of the
+// identity 'ih' in the environment 's'
+func (n *Notifier) observe(s *SRP, role string, ih []byte, err error) {
+	r := ReasonOf(err)
+	now := n.now()
+
+	var kind string
+	var count int
+	switch r {
+	case FailureBadProof:
+		kind, count = EventBadProofs, n.count("p:"+string(ih), now, n.cfg.BadProofs)
+	case FailureBadPublicKey:
+		kind, count, ih = EventInvalidKeys, n.count("k:", now, n.cfg.InvalidKeys), nil
+	case FailureParamMismatch:
+		kind, count = EventDowngrade, 1
+	}
+	if count == 0 {
+		return
+	}
+
+	ev := &SecurityEvent{
+		Kind:   kind,
+		Time:   now.UTC(),
+		Role:   role,
+		Count:  count,
+		Window: int(n.cfg.Window / time.Second),
+		Reason: r.String(),
+		Error:  err.Error(),
+		Params: hex.EncodeToString(s.Fingerprint()),
+	}
+	if ih != nil {
+		ev.Identity = hex.EncodeToString(ih)
+	}
+	n.raise(ev)
+}
+
+// count a failure under 'key' and return the count if it reached 'limit'
+// (and starts over), else 0
+func (n *Notifier) count(key string, now time.Time, limit int) int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	e, ok := n.m[key]
+	if !ok || now.Sub(e.start) >= n.cfg.Window {
+		if !ok && len(n.m) >= n.cfg.Keys {
+			n.expire(now)
+			if len(n.m) >= n.cfg.Keys {
+				return 0
+			}
+		}
+		e = &eventCount{start: now}
+		n.m[key] = e
+	}
+
+	e.n++
+	if e.n < limit {
+		return 0
+	}
+	delete(n.m, key)
+	return limit
+}
+
+// forget the counts whose window has passed; the caller holds n.mu
+func (n *Notifier) expire(now time.Time) {
+	for k, e := range n.m {
+		if now.Sub(e.start) >= n.cfg.Window {
+			delete(n.m, k)
+		}
+	}
+}
+
+// queue 'ev' for delivery
+func (n *Notifier) raise(ev *SecurityEvent) {
+	b, err := json.Marshal(ev)
+	if err != nil {
+		n.fail(err)
+		return
+	}
+
+	var why string
+	n.mu.Lock()
+	if n.q == nil {
+		why = "notifier closed"
+	} else {
+		select {
+		case n.q <- b:
+		default:
+			why = "notifier queue full"
+		}
+	}
+	n.mu.Unlock()
+
+	if why != "" {
+		n.fail(fmt.Errorf("srp: %s; dropped %s event", why, ev.Kind))
+	}
+}
+
+// deliver the events queued in 'q' until Close()
+func (n *Notifier) run(q chan []byte) {
+	defer n.wg.Done()
+
+	for b := range q {
+		d := n.cfg.Backoff
+		err := n.cfg.Deliver(b)
+		for i := 0; err != nil && i < n.cfg.Retries; i++ {
+			n.sleep(d)
+			d *= 2
+			err = n.cfg.Deliver(b)
+		}
+		if err != nil {
+			n.fail(fmt.Errorf("srp: security event not delivered: %w", err))
+		}
+	}
+}
+
+// report 'err' to OnError, if set
+func (n *Notifier) fail(err error) {
+	if n.cfg.OnError != nil {
+		n.cfg.OnError(err)
+	}
+}
@@ -0,0 +1,129 @@
+// self test for security events
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+)
+
+// eventRecorder collects the delivered events
+type eventRecorder struct {
+	mu   sync.Mutex
+	evs  []SecurityEvent
+	fail int // deliveries to fail
+	n    int // deliveries tried
+}
+
+func (r *eventRecorder) deliver(b []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.n++
+	if r.fail > 0 {
+		r.fail--
+		return fmt.Errorf("unavailable")
+	}
+	var ev SecurityEvent
+	if err := json.Unmarshal(b, &ev); err != nil {
+		return err
+	}
+	r.evs = append(r.evs, ev)
+	return nil
+}
+
+func TestSecurityEvents(t *testing.T) {
+	assert := newAsserter(t)
+
+	rec := &eventRecorder{fail: 2}
+	n, err := NewNotifier(NotifierConfig{Deliver: rec.deliver, BadProofs: 3, InvalidKeys: 2})
+	assert(err == nil, "NewNotifier: %s", err)
+	n.sleep = func(time.Duration) {}
+
+	s, err := New(2048, WithSecurityNotifier(n))
+	assert(err == nil, "New: %s", err)
+	v, err := s.Verifier([]byte("user"), []byte("pass"), nil)
+	assert(err == nil, "Verifier: %s", err)
+
+	// three wrong passwords
+	for i := 0; i < 3; i++ {
+		c, err := s.NewClient([]byte("user"), []byte("wrong"))
+		assert(err == nil, "NewClient: %s", err)
+		srv, err := s.NewServer(v, c.xA)
+		assert(err == nil, "NewServer: %s", err)
+		assert(!authenticate(c, srv), "wrong password accepted")
+	}
+
+	// two invalid A
+	for i := 0; i < 2; i++ {
+		_, err := s.NewServer(v, big.NewInt(0))
+		assert(err != nil, "A = 0 accepted")
+	}
+
+	// a server that drops the HMAC derivation of x
+	cs, err := New(2048, WithHMACPrivateKey(), WithSecurityNotifier(n))
+	assert(err == nil, "New: %s", err)
+	c, err := cs.NewClient([]byte("user"), []byte("pass"))
+	assert(err == nil, "NewClient: %s", err)
+	srv, err := s.NewServer(v, c.xA)
+	assert(err == nil, "NewServer: %s", err)
+	_, err = c.Respond(srv.Challenge())
+	assert(err != nil && ReasonOf(err) == FailureParamMismatch, "downgrade accepted: %v", err)
+
+	n.Close()
+	assert(rec.n == 5, "%d deliveries, expected 5 (with 2 retries)", rec.n)
+	assert(len(rec.evs) == 3, "%d events, expected 3", len(rec.evs))
+
+	ev := rec.evs[0]
+	assert(ev.Kind == EventBadProofs && ev.Role == "server" && ev.Count == 3, "wrong event %+v", ev)
+	assert(ev.Identity == hex.EncodeToString(v.i), "wrong identity %q", ev.Identity)
+	assert(ev.Params == hex.EncodeToString(s.Fingerprint()), "wrong params %q", ev.Params)
+
+	ev = rec.evs[1]
+	assert(ev.Kind == EventInvalidKeys && ev.Count == 2 && ev.Identity == "", "wrong event %+v", ev)
+
+	ev = rec.evs[2]
+	assert(ev.Kind == EventDowngrade && ev.Role == "client", "wrong event %+v", ev)
+
+	// dropped after Close()
+	var dropped error
+	n.cfg.OnError = func(err error) { dropped = err }
+	s.NewServer(v, big.NewInt(0))
+	s.NewServer(v, big.NewInt(0))
+	assert(dropped != nil, "event raised after Close()")
+}
+
+func TestNotifierWindow(t *testing.T) {
+	assert := newAsserter(t)
+
+	rec := &eventRecorder{}
+	n, err := NewNotifier(NotifierConfig{Deliver: rec.deliver, BadProofs: 2, Window: time.Minute})
+	assert(err == nil, "NewNotifier: %s", err)
+	now := time.Now()
+	n.now = func() time.Time { return now }
+
+	s, err := New(2048)
+	assert(err == nil, "New: %s", err)
+
+	// failures in different windows don't add up
+	n.observe(s, "server", []byte("ih"), ErrProofMismatch)
+	now = now.Add(2 * time.Minute)
+	n.observe(s, "server", []byte("ih"), ErrProofMismatch)
+	n.observe(s, "server", []byte("other"), ErrProofMismatch)
+	n.observe(s, "server", []byte("ih"), ErrMalformedProof)
+	n.observe(s, "server", []byte("ih"), ErrProofMismatch)
+	n.Close()
+
+	assert(len(rec.evs) == 1 && rec.evs[0].Identity == hex.EncodeToString([]byte("ih")), "wrong events %+v", rec.evs)
+
+	_, err = NewNotifier(NotifierConfig{})
+	assert(err != nil, "notifier without delivery")
+}
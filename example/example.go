@@ -11,7 +11,7 @@ import (
 )
 
 func main() {
-	bits := 1024
+	bits := 2048
 	pass := []byte("password string that's too long")
 	i := []byte("foouser")
 
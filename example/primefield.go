@@ -1,3 +1,6 @@
+//go:build ignore
+// +build ignore
+
 // compute a safe prime and its field generator
 //
 // Usage: ./primefield bits [bits ..]
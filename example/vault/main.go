@@ -0,0 +1,101 @@
+package main
+
+// Example of SRP with a password-wrapped master key: the server
+// authenticates the user and returns their wrapped key, which only the
+// client can open. See package vault.
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/tomsons/go-srp"
+	"github.com/tomsons/go-srp/vault"
+)
+
+func main() {
+	user := []byte("foouser")
+	pass := []byte("correct horse battery staple")
+
+	// harden x as well as the KEK
+	s, err := srp.New(2048, srp.WithKDF(vault.DefaultKDF))
+	if err != nil {
+		panic(err)
+	}
+
+	// Registration (client): create the record and send it to the server.
+	// The client keeps the master key to encrypt the user's data.
+	rec, mk, err := vault.Register(s, user, pass, vault.DefaultKDF)
+	if err != nil {
+		panic(err)
+	}
+
+	// The server stores the record; it can't open the wrapped key.
+	fmt.Printf("Record Store:\n   %s => %s\n   wrapped key: %s\n", rec.Identity, rec.Verifier, rec.Key.String())
+
+	// Login (client)
+	ss, _, err := srp.MakeSRPVerifier(rec.Verifier)
+	if err != nil {
+		panic(err)
+	}
+	c, err := ss.NewClient(user, pass)
+	if err != nil {
+		panic(err)
+	}
+
+	creds := c.Credentials()
+
+	// Login (server): look up the record of the user
+	_, A, err := srp.ServerBegin(creds)
+	if err != nil {
+		panic(err)
+	}
+	ss, v, err := srp.MakeSRPVerifier(rec.Verifier)
+	if err != nil {
+		panic(err)
+	}
+	srv, err := ss.NewServer(v, A)
+	if err != nil {
+		panic(err)
+	}
+
+	cauth, err := c.Generate(srv.Credentials())
+	if err != nil {
+		panic(err)
+	}
+
+	proof, ok := srv.ClientOk(cauth)
+	if !ok {
+		panic("client auth failed")
+	}
+	if !c.ServerOk(proof) {
+		panic("server auth failed")
+	}
+
+	// Only now does the server release the wrapped key, sealed under the
+	// session key.
+	ct, err := srv.Seal([]byte(rec.Key.String()), nil)
+	if err != nil {
+		panic(err)
+	}
+
+	// The client opens it and unwraps the master key with the password.
+	b, err := c.Open(ct, nil)
+	if err != nil {
+		panic(err)
+	}
+	wk, err := vault.ParseWrappedKey(string(b))
+	if err != nil {
+		panic(err)
+	}
+	k, err := wk.Unwrap(pass)
+	if err != nil {
+		panic(err)
+	}
+
+	if !bytes.Equal(k, mk) {
+		panic("master keys are different!")
+	}
+	fmt.Printf("Master Key: %x\n", k)
+}
+
+// EOF
@@ -0,0 +1,82 @@
+//go:build js && wasm
+// +build js,wasm
+
+// SRP client for web browsers
+//
+// Build with:
+//
+//	GOOS=js GOARCH=wasm go build -o srp.wasm
+//	cp "$(go env GOROOT)/lib/wasm/wasm_exec.js" .
+//
+// and serve this directory together with index.html. On Go releases before
+// 1.24, wasm_exec.js is in misc/wasm instead of lib/wasm.
+//
+// The module exports srpClient(user, password, bits); the returned object
+// has the methods credentials(), generate(serverCreds), serverOk(proof) and
+// key(). Errors are returned as JavaScript Error objects rather than thrown,
+// since a panic would stop the Go program. Randomness comes from crypto/rand, which reads the browser's Web
+// Crypto API (crypto.getRandomValues()).
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package main
+
+import (
+	"encoding/hex"
+	"syscall/js"
+
+	"github.com/tomsons/go-srp"
+)
+
+func main() {
+	js.Global().Set("srpClient", js.FuncOf(newClient))
+
+	// keep the exported functions alive
+	select {}
+}
+
+// srpClient(user, password, bits) returns a client object or an Error
+func newClient(this js.Value, args []js.Value) interface{} {
+	if len(args) != 3 {
+		return jsError("srpClient: expected user, password and bits")
+	}
+
+	s, err := srp.New(args[2].Int())
+	if err != nil {
+		return jsError(err.Error())
+	}
+
+	c, err := s.NewClient([]byte(args[0].String()), []byte(args[1].String()))
+	if err != nil {
+		return jsError(err.Error())
+	}
+
+	o := js.Global().Get("Object").New()
+	o.Set("credentials", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		return c.Credentials()
+	}))
+	o.Set("generate", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		if len(args) != 1 {
+			return jsError("generate: expected server credentials")
+		}
+		m, err := c.Generate(args[0].String())
+		if err != nil {
+			return jsError(err.Error())
+		}
+		return m
+	}))
+	o.Set("serverOk", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		return len(args) == 1 && c.ServerOk(args[0].String())
+	}))
+	o.Set("key", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		return hex.EncodeToString(c.RawKey())
+	}))
+	return o
+}
+
+// return a JavaScript Error with the message 'msg'
+func jsError(msg string) js.Value {
+	return js.Global().Get("Error").New(msg)
+}
@@ -0,0 +1,146 @@
+// failure.go - a stable taxonomy of handshake failures for metrics
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"context"
+	"errors"
+	"net"
+)
+
+// FailureReason classifies the error of a failed handshake step so that
+// servers of different deployments count failures under the same names.
+// The names returned by String() are stable across releases and valid
+// OpenMetrics label values, e.g.
+//
+//	srp_handshake_failures_total{reason="bad_proof"} 12
+//
+// New reasons may be added; existing ones are never renamed or reused.
+// A server must not tell the client which reason applied.
+type FailureReason int
+
+// Reasons of failed handshakes
+const (
+	FailureOther          FailureReason = iota // none of the below
+	FailureBadPublicKey                        // A or B is malformed or aborts the handshake (ErrBadClientKey, ErrSecurityAbort, ...)
+	FailureMalformedProof                      // ErrMalformedProof
+	FailureBadProof                            // wrong password or proof scheme (ErrProofMismatch, ErrBadChallengeSignature)
+	FailureReplayed                            // ErrReplayed
+	FailureStaleState                          // the pending handshake expired or was taken (ErrNoHandshake)
+	FailureUnknownUser                         // the store has no verifier (ErrNoVerifier)
+	FailureParamMismatch                       // hash, group or pinned parameters differ (ErrGroupMismatch, ErrParamsMismatch, ErrDowngrade, ...)
+	FailureRateLimited                         // ErrTooManyHandshakes, ErrTarpit
+	FailurePuzzle                              // ErrPuzzleUnsolved
+	FailureTimeout                             // a deadline of the handshake passed
+)
+
+var failureReasons = []string{
+	FailureOther:          "other",
+	FailureBadPublicKey:   "bad_public_key",
+	FailureMalformedProof: "malformed_proof",
+	FailureBadProof:       "bad_proof",
+	FailureReplayed:       "replayed",
+	FailureStaleState:     "stale_state",
+	FailureUnknownUser:    "unknown_user",
+	FailureParamMismatch:  "param_mismatch",
+	FailureRateLimited:    "rate_limited",
+	FailurePuzzle:         "puzzle_unsolved",
+	FailureTimeout:        "timeout",
+}
+
+// the sentinel errors of each reason
+var failureErrors = []struct {
+	err error
+	r   FailureReason
+}{
+	{ErrBadClientKey, FailureBadPublicKey},
+	{ErrBadServerKey, FailureBadPublicKey},
+	{ErrSecurityAbort, FailureBadPublicKey},
+	{ErrMalformedProof, FailureMalformedProof},
+	{ErrProofMismatch, FailureBadProof},
+	{ErrBadChallengeSignature, FailureBadProof},
+	{ErrReplayed, FailureReplayed},
+	{ErrNoHandshake, FailureStaleState},
+	{ErrNoVerifier, FailureUnknownUser},
+	{ErrGroupMismatch, FailureParamMismatch},
+	{ErrParamsMismatch, FailureParamMismatch},
+	{ErrDowngrade, FailureParamMismatch},
+	{ErrHashUnavailable, FailureParamMismatch},
+	{ErrTooManyHandshakes, FailureRateLimited},
+	{ErrTarpit, FailureRateLimited},
+	{ErrPuzzleUnsolved, FailurePuzzle},
+	{context.DeadlineExceeded, FailureTimeout},
+}
+
+// String returns the stable name of the reason
+func (r FailureReason) String() string {
+	if r < 0 || int(r) >= len(failureReasons) {
+		return failureReasons[FailureOther]
+	}
+	return failureReasons[r]
+}
+
+// FailureReasons returns all reasons; a server can use it to export every
+// counter, with a value of zero, before the first failure.
+func FailureReasons() []FailureReason {
+	rs := make([]FailureReason, len(failureReasons))
+	for i := range rs {
+		rs[i] = FailureReason(i)
+	}
+	return rs
+}
+
+// ReasonOf returns the reason of the handshake error 'err' (which may wrap
+// the errors of this package); it is FailureOther for nil and unknown
+// errors.
+func ReasonOf(err error) FailureReason {
+	if err == nil {
+		return FailureOther
+	}
+	for _, fe := range failureErrors {
+		if errors.Is(err, fe.err) {
+			return fe.r
+		}
+	}
+
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return FailureTimeout
+	}
+	return FailureOther
+}
+
+// FailureHook is called with the reason and error of each failed
+// handshake step; it typically increments a counter labelled with the
+// reason. It must be safe for concurrent use and must not block.
+type FailureHook func(r FailureReason, err error)
+
+// WithFailureHook makes servers in this environment call 'fn' when
+// NewServer() (or NewServerFor()) or the check of a client proof fails.
+// Failures before there is an environment, such as unknown users, are
+// reported by the caller (see HandshakeConfig.OnFailure).
+func WithFailureHook(fn FailureHook) Option {
+	return func(s *SRP) error {
+		s.fh = fn
+		return nil
+	}
+}
+
+// report the error 'err' of a server step for the identity 'ih' to the
+// failure hook and the security notifier and return it
+func (s *SRP) failed(ih []byte, err error) error {
+	if err == nil {
+		return nil
+	}
+	if s.fh != nil {
+		s.fh(ReasonOf(err), err)
+	}
+	if s.sn != nil {
+		s.sn.observe(s, "server", ih, err)
+	}
+	return err
+}
@@ -0,0 +1,102 @@
+// self test for the failure taxonomy
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"net"
+	"testing"
+)
+
+func TestFailureReasons(t *testing.T) {
+	assert := newAsserter(t)
+
+	// the names are part of the API; never change them
+	names := []string{
+		"other", "bad_public_key", "malformed_proof", "bad_proof",
+		"replayed", "stale_state", "unknown_user", "param_mismatch",
+		"rate_limited", "puzzle_unsolved", "timeout",
+	}
+	rs := FailureReasons()
+	assert(len(rs) == len(names), "exp %d reasons, saw %d", len(names), len(rs))
+	for i, r := range rs {
+		assert(r.String() == names[i], "reason %d: exp %s, saw %s", i, names[i], r)
+	}
+	assert(FailureReason(-1).String() == "other", "out of range reason")
+
+	tests := []struct {
+		err error
+		r   FailureReason
+	}{
+		{nil, FailureOther},
+		{fmt.Errorf("boom"), FailureOther},
+		{abort(AbortZeroA), FailureBadPublicKey},
+		{fmt.Errorf("%w (A)", ErrReplayed), FailureReplayed},
+		{fmt.Errorf("lookup: %w", ErrNoVerifier), FailureUnknownUser},
+		{&GroupMismatchError{Source: "verifier"}, FailureParamMismatch},
+		{&HashUnavailableError{}, FailureParamMismatch},
+		{ErrNoHandshake, FailureStaleState},
+		{fmt.Errorf("srp: handshake: %w", context.DeadlineExceeded), FailureTimeout},
+	}
+	for _, tc := range tests {
+		r := ReasonOf(tc.err)
+		assert(r == tc.r, "%v: exp %s, saw %s", tc.err, tc.r, r)
+	}
+}
+
+func TestFailureHook(t *testing.T) {
+	assert := newAsserter(t)
+
+	var seen []FailureReason
+	hook := func(r FailureReason, err error) {
+		assert(err != nil, "hook called without an error")
+		seen = append(seen, r)
+	}
+
+	s, err := New(2048, WithFailureHook(hook))
+	assert(err == nil, "New: %s", err)
+	v, err := s.Verifier([]byte("user"), []byte("pass"), nil)
+	assert(err == nil, "Verifier: %s", err)
+
+	_, err = s.NewServer(v, big.NewInt(0))
+	assert(err != nil, "accepted A = 0")
+
+	c, err := s.NewClient([]byte("user"), []byte("wrong"))
+	assert(err == nil, "NewClient: %s", err)
+	srv, err := s.NewServer(v, c.xA)
+	assert(err == nil, "NewServer: %s", err)
+	assert(!authenticate(c, srv), "accepted a bad password")
+
+	_, err = srv.VerifyClientProof("xyz")
+	assert(err == ErrMalformedProof, "malformed proof: %v", err)
+
+	exp := []FailureReason{FailureBadPublicKey, FailureBadProof, FailureMalformedProof}
+	assert(fmt.Sprint(seen) == fmt.Sprint(exp), "exp %v, saw %v", exp, seen)
+
+	// unknown users are reported by ServerHandshake()
+	var r FailureReason
+	cfg := &HandshakeConfig{
+		OnFailure: func(fr FailureReason, err error) { r = fr },
+	}
+	lookup := func(ih []byte) (*SRP, *Verifier, error) {
+		return nil, nil, ErrNoVerifier
+	}
+
+	cc, sc := net.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		_, err := ServerHandshake(context.Background(), sc, lookup, cfg)
+		sc.Close()
+		done <- err
+	}()
+	c.Handshake(context.Background(), cc, nil)
+	cc.Close()
+	<-done
+	assert(r == FailureUnknownUser, "exp unknown_user, saw %s", r)
+}
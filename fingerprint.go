@@ -0,0 +1,73 @@
+// fingerprint.go - comparing verifiers without decoding them
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"hash"
+)
+
+// domain separation of verifier fingerprints
+const fingerprintContext = "srp verifier fingerprint v1"
+
+// Fingerprint returns a SHA-256 digest of all fields of the verifier: the
+// hashed identity, salt, verifier, hash function, group, KDF, identity
+// blinding and derivation of x. Two verifiers have the same fingerprint
+// if and only if they encode the same credential, whatever the encoding
+// they were decoded from; replicas of a credential store can compare
+// fingerprints to detect drift. The fingerprint doesn't reveal the
+// verifier, but it identifies it: treat it like the hashed identity.
+func (v *Verifier) Fingerprint() []byte {
+	h := sha256.New()
+	h.Write([]byte(fingerprintContext))
+
+	var kdf string
+	if v.kdf != nil {
+		kdf = v.kdf.String()
+	}
+	var idk byte
+	if v.idk != nil {
+		idk = 1
+	}
+
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], uint32(v.h))
+	for _, f := range [][]byte{
+		v.i,
+		v.s,
+		v.v,
+		b[:],
+		v.pf.N.Bytes(),
+		v.pf.g.Bytes(),
+		[]byte(v.pf.id),
+		[]byte(kdf),
+		{idk},
+		[]byte(v.xd),
+	} {
+		writeField(h, f)
+	}
+	return h.Sum(nil)
+}
+
+// Equal returns true if 'v' and 'w' are the same credential (see
+// Fingerprint()). The comparison takes constant time; two nil verifiers
+// are equal.
+func (v *Verifier) Equal(w *Verifier) bool {
+	if v == nil || w == nil {
+		return v == w
+	}
+	return ctEqual(v.Fingerprint(), w.Fingerprint())
+}
+
+// write 'b' to 'h' prefixed with its length
+func writeField(h hash.Hash, b []byte) {
+	var n [4]byte
+	binary.BigEndian.PutUint32(n[:], uint32(len(b)))
+	h.Write(n[:])
+	h.Write(b)
+}
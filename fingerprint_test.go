@@ -0,0 +1,75 @@
+// self test for Verifier.Fingerprint() and Verifier.Equal()
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestVerifierFingerprint(t *testing.T) {
+	assert := newAsserter(t)
+
+	s, err := New(2048)
+	assert(err == nil, "New: %s", err)
+
+	v, err := s.Verifier([]byte("user"), []byte("pass"), nil)
+	assert(err == nil, "Verifier: %s", err)
+
+	fp := v.Fingerprint()
+	assert(len(fp) == 32, "fingerprint is %d bytes", len(fp))
+	assert(bytes.Equal(fp, v.Fingerprint()), "fingerprint isn't stable")
+	assert(v.Equal(v), "verifier differs from itself")
+
+	// the same in every encoding
+	for _, f := range []VerifierFormat{VerifierText, VerifierCBOR, VerifierCompact} {
+		b, err := v.EncodeAs(f)
+		assert(err == nil, "EncodeAs %s: %s", f, err)
+		_, w, err := DecodeVerifier(b, f)
+		assert(err == nil, "decode %s: %s", f, err)
+		assert(bytes.Equal(w.Fingerprint(), fp), "%s: fingerprint changed", f)
+		assert(w.Equal(v) && v.Equal(w), "%s: verifiers differ", f)
+	}
+
+	// any other credential differs
+	salt := append([]byte{}, v.s...)
+	others := []func() (*Verifier, error){
+		func() (*Verifier, error) { return s.Verifier([]byte("user"), []byte("pass"), nil) },
+		func() (*Verifier, error) { return s.Verifier([]byte("user"), []byte("Pass"), salt) },
+		func() (*Verifier, error) { return s.Verifier([]byte("User"), []byte("pass"), salt) },
+		func() (*Verifier, error) {
+			s, err := New(3072)
+			if err != nil {
+				return nil, err
+			}
+			return s.Verifier([]byte("user"), []byte("pass"), salt)
+		},
+	}
+	if !FIPSBuild {
+		others = append(others, func() (*Verifier, error) {
+			s, err := New(2048, WithKDF(KDF{Alg: KDFArgon2id, Time: 1, Memory: 64, Threads: 1}))
+			if err != nil {
+				return nil, err
+			}
+			return s.Verifier([]byte("user"), []byte("pass"), salt)
+		})
+	}
+	for i, fn := range others {
+		w, err := fn()
+		assert(err == nil, "verifier %d: %s", i, err)
+		assert(!bytes.Equal(w.Fingerprint(), fp), "verifier %d: same fingerprint", i)
+		assert(!w.Equal(v), "verifier %d: equal", i)
+	}
+
+	same, err := s.Verifier([]byte("user"), []byte("pass"), salt)
+	assert(err == nil, "Verifier: %s", err)
+	assert(same.Equal(v), "recomputed verifier differs")
+
+	var nv *Verifier
+	assert(nv.Equal(nil), "nil verifiers differ")
+	assert(!v.Equal(nil) && !nv.Equal(v), "nil verifier equal")
+}
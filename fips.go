@@ -0,0 +1,102 @@
+// fips.go - restricting environments to FIPS approved parameters
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"crypto"
+	"fmt"
+)
+
+// Inside a FIPS 140 validated stack, only approved hash functions may be
+// used: of those this package supports, SHA-256, SHA-384 and SHA-512, and
+// prime fields of at least 2048 bits. Neither Argon2id nor scrypt is
+// approved, so password hardening (WithKDF()) is rejected as well.
+// WithFIPSMode(), or building with the "fips" tag (which applies it to
+// every environment), makes constructors and decoders reject other
+// parameters with an error matching ErrFIPS:
+//
+//	go build -tags fips ./...
+//
+// In FIPS mode New() and NewDefault() use SHA-256 instead of BLAKE2b-256;
+// verifiers stored with BLAKE2b-256 must be replaced by ones made with an
+// approved hash. The mode only checks the parameters of SRP; the
+// application must still use a validated module for the primitives around
+// it (e.g., Seal() or TLS).
+
+// FIPSBuild is true if the package was built with the "fips" tag
+const FIPSBuild = fipsBuild
+
+// ErrFIPS is matched (with errors.Is()) by the errors of parameters that
+// FIPS mode rejects
+var ErrFIPS = fmt.Errorf("srp: not approved in FIPS mode")
+
+// hash functions approved in FIPS mode
+var fipsHashes = map[crypto.Hash]bool{
+	crypto.SHA256: true,
+	crypto.SHA384: true,
+	crypto.SHA512: true,
+}
+
+// smallest prime field in FIPS mode in bits
+const fipsMinBits = 2048
+
+// WithFIPSMode restricts this environment to FIPS approved hash functions
+// and group sizes (see above).
+func WithFIPSMode() Option {
+	return func(s *SRP) error {
+		s.fips = true
+		return nil
+	}
+}
+
+// FIPS returns true if this environment is restricted to FIPS approved
+// parameters
+func (s *SRP) FIPS() bool {
+	return s.fips || FIPSBuild
+}
+
+// return an error matching ErrFIPS if this environment is in FIPS mode
+// and has parameters that aren't approved
+func (s *SRP) checkFIPS() error {
+	if !s.FIPS() {
+		return nil
+	}
+
+	hs := append([]crypto.Hash{s.h}, s.aph...)
+	if s.ph != 0 {
+		hs = append(hs, s.ph)
+	}
+	if err := fipsApproved(s.FieldSize(), hs...); err != nil {
+		return err
+	}
+	if s.kdf != nil {
+		return fmt.Errorf("%w: KDF %s", ErrFIPS, s.kdf.Alg)
+	}
+	return nil
+}
+
+// return the default hash of New() and NewDefault()
+func (s *SRP) defaultHash() crypto.Hash {
+	if s.FIPS() {
+		return crypto.SHA256
+	}
+	return crypto.BLAKE2b_256
+}
+
+// return an error matching ErrFIPS unless a 'bits' sized prime field and
+// the hash functions 'hs' are approved
+func fipsApproved(bits int, hs ...crypto.Hash) error {
+	for _, h := range hs {
+		if !fipsHashes[h] {
+			return fmt.Errorf("%w: hash %s; use SHA-256, SHA-384 or SHA-512", ErrFIPS, hashName(h))
+		}
+	}
+	if bits < fipsMinBits {
+		return fmt.Errorf("%w: %d bit prime-field; need at least %d bits", ErrFIPS, bits, fipsMinBits)
+	}
+	return nil
+}
@@ -0,0 +1,12 @@
+//go:build fips
+// +build fips
+
+// fips_on.go - FIPS mode for all environments
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+const fipsBuild = true
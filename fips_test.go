@@ -0,0 +1,85 @@
+// self test for FIPS mode
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"crypto"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestFIPSMode(t *testing.T) {
+	assert := newAsserter(t)
+
+	for _, h := range []crypto.Hash{crypto.BLAKE2b_256, crypto.SHA1, crypto.SHA3_256} {
+		_, err := NewWithHash(h, 2048, WithFIPSMode())
+		assert(errors.Is(err, ErrFIPS), "%s: exp ErrFIPS, saw %v", hashName(h), err)
+		assert(strings.Contains(err.Error(), hashName(h)), "%s: error doesn't name the hash: %s", hashName(h), err)
+	}
+
+	s, err := New(2048, WithFIPSMode())
+	assert(err == nil, "New: %s", err)
+	assert(s.h == crypto.SHA256, "default hash %s in FIPS mode", hashName(s.h))
+	s, err = NewDefault(WithFIPSMode())
+	assert(err == nil, "NewDefault: %s", err)
+	assert(s.h == crypto.SHA256, "default hash %s in FIPS mode", hashName(s.h))
+
+	kdf := KDF{Alg: KDFArgon2id, Time: 1, Memory: 64, Threads: 1}
+	_, err = NewWithHash(crypto.SHA256, 2048, WithFIPSMode(), WithKDF(kdf))
+	assert(errors.Is(err, ErrFIPS), "Argon2id accepted")
+
+	_, err = NewWithHash(crypto.SHA256, 1024, WithFIPSMode(), WithInsecureGroups())
+	assert(errors.Is(err, ErrFIPS), "1024 bit group accepted")
+
+	_, err = NewWithGroupID(crypto.SHA256, "rfc2409-1024", WithFIPSMode(), WithInsecureGroups())
+	assert(errors.Is(err, ErrFIPS), "1024 bit group id accepted")
+
+	_, err = NewWithHash(crypto.SHA256, 2048, WithFIPSMode(), WithProofHash(crypto.SHA3_256))
+	assert(errors.Is(err, ErrFIPS), "SHA3 proof hash accepted")
+
+	s, err = NewWithHash(crypto.SHA256, 2048, WithFIPSMode())
+	assert(err == nil, "SHA-256: %s", err)
+	assert(s.FIPS(), "FIPS mode not reported")
+
+	// verifiers and servers outside FIPS mode don't decode in it; a "fips"
+	// build has none
+	if !FIPSBuild {
+		ns, err := New(2048)
+		assert(err == nil, "New: %s", err)
+		assert(ns.h == crypto.BLAKE2b_256, "default hash %s", hashName(ns.h))
+		assert(!ns.FIPS(), "FIPS mode without the option")
+
+		v, err := ns.Verifier([]byte("user"), []byte("pass"), nil)
+		assert(err == nil, "Verifier: %s", err)
+		_, vh := v.Encode()
+		_, _, err = MakeSRPVerifier(vh, WithFIPSMode())
+		assert(errors.Is(err, ErrFIPS), "BLAKE2b verifier decoded")
+
+		c, err := ns.NewClient([]byte("user"), []byte("pass"))
+		assert(err == nil, "NewClient: %s", err)
+		srv, err := ns.NewServer(v, c.xA)
+		assert(err == nil, "NewServer: %s", err)
+		_, err = UnmarshalServer(srv.Marshal(), WithFIPSMode())
+		assert(errors.Is(err, ErrFIPS), "BLAKE2b server unmarshaled")
+	}
+
+	// a FIPS verifier works as usual
+	v, err := s.Verifier([]byte("user"), []byte("pass"), nil)
+	assert(err == nil, "Verifier: %s", err)
+	_, vh := v.Encode()
+	fs, fv, err := MakeSRPVerifier(vh, WithFIPSMode())
+	assert(err == nil, "MakeSRPVerifier: %s", err)
+	c, err := s.NewClient([]byte("user"), []byte("pass"))
+	assert(err == nil, "NewClient: %s", err)
+	srv, err := fs.NewServer(fv, c.xA)
+	assert(err == nil, "NewServer: %s", err)
+	assert(authenticate(c, srv), "FIPS handshake failed")
+
+	assert(errors.Is(MinimumAcceptable(2048, crypto.BLAKE2b_256, WithFIPSMode()), ErrFIPS), "MinimumAcceptable ignores FIPS mode")
+	assert(MinimumAcceptable(3072, crypto.SHA256, WithFIPSMode()) == nil, "MinimumAcceptable rejects SHA-256")
+}
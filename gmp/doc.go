@@ -0,0 +1,26 @@
+// doc.go - package documentation
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+// Package gmp provides an SRP arithmetic backend (see srp.Backend) that
+// does modular exponentiation with GMP, whose assembly Montgomery
+// multiplication is faster than math/big for the 2048 to 4096-bit groups.
+// Exponentiation dominates the cost of a handshake, so high-volume login
+// servers gain throughput; see the benchmarks in this package:
+//
+//	go test -tags gmp -bench . github.com/tomsons/go-srp/gmp
+//
+// GMP is only used when building with cgo and the "gmp" build tag, and
+// libgmp (e.g., the libgmp-dev package) is installed. Otherwise Backend is
+// srp.MathBig, so applications can use this package unconditionally:
+//
+//	s, err := srp.New(3072, srp.WithBackend(gmp.Backend))
+//
+// Unlike math/big, the backend exponentiates with mpz_powm_sec, whose
+// running time and memory accesses don't depend on the (secret) exponent;
+// it takes the place of mpz_powm for every odd modulus, i.e., for every
+// group. It is slower than mpz_powm, so handshakes gain about 10% over
+// math/big rather than the speed of plain GMP.
+package gmp
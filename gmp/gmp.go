@@ -0,0 +1,82 @@
+//go:build cgo && gmp
+// +build cgo,gmp
+
+// gmp.go - modular exponentiation with GMP
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package gmp
+
+/*
+#cgo LDFLAGS: -lgmp
+#include <gmp.h>
+
+// out = x^y mod m; 'out' has room for 'ml' bytes. Numbers are big-endian.
+// Returns the size of the result in bytes. The exponents of SRP are
+// secret, so odd moduli (all SRP groups) use the side-channel silent
+// mpz_powm_sec; it requires y > 0, and x^0 is 1 mod m anyway.
+static size_t powm(unsigned char *out,
+		   const unsigned char *x, size_t xl,
+		   const unsigned char *y, size_t yl,
+		   const unsigned char *m, size_t ml)
+{
+	mpz_t X, Y, M, Z;
+	size_t n = 0;
+
+	mpz_inits(X, Y, M, Z, NULL);
+	mpz_import(X, xl, 1, 1, 1, 0, x);
+	mpz_import(Y, yl, 1, 1, 1, 0, y);
+	mpz_import(M, ml, 1, 1, 1, 0, m);
+
+	if (mpz_odd_p(M) && mpz_sgn(Y) > 0)
+		mpz_powm_sec(Z, X, Y, M);
+	else
+		mpz_powm(Z, X, Y, M);
+	mpz_export(out, &n, 1, 1, 1, 0, Z);
+
+	mpz_clears(X, Y, M, Z, NULL);
+	return n;
+}
+*/
+import "C"
+
+import (
+	"math/big"
+	"unsafe"
+
+	"github.com/tomsons/go-srp"
+)
+
+// Available is true if Backend uses GMP
+const Available = true
+
+// Backend is an srp.Backend that exponentiates with GMP
+var Backend srp.Backend = backend{srp.MathBig}
+
+type backend struct {
+	srp.Backend
+}
+
+func (backend) Exp(x, y, m *big.Int) *big.Int {
+	xb, yb, mb := x.Bytes(), y.Bytes(), m.Bytes()
+	if len(mb) == 0 {
+		return big.NewInt(0)
+	}
+
+	out := make([]byte, len(mb))
+	n := C.powm((*C.uchar)(unsafe.Pointer(&out[0])),
+		ptr(xb), C.size_t(len(xb)),
+		ptr(yb), C.size_t(len(yb)),
+		ptr(mb), C.size_t(len(mb)))
+	return big.NewInt(0).SetBytes(out[:n])
+}
+
+// return a C pointer to 'b' or nil if it is empty
+func ptr(b []byte) *C.uchar {
+	if len(b) == 0 {
+		return nil
+	}
+	return (*C.uchar)(unsafe.Pointer(&b[0]))
+}
@@ -0,0 +1,121 @@
+// self test and benchmarks for the GMP backend
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package gmp
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/tomsons/go-srp"
+)
+
+func TestExp(t *testing.T) {
+	t.Logf("GMP available: %v", Available)
+
+	for _, g := range srp.SupportedGroups() {
+		N := g.N
+		for i := 0; i < 3; i++ {
+			x, _ := rand.Int(rand.Reader, N)
+			y, _ := rand.Int(rand.Reader, N)
+			if i == 0 {
+				x.SetInt64(0)
+			}
+			if i == 1 {
+				y.SetInt64(0)
+			}
+
+			want := srp.MathBig.Exp(x, y, N)
+			if got := Backend.Exp(x, y, N); got.Cmp(want) != 0 {
+				t.Fatalf("%s: %x^%x: exp %x, saw %x", g.ID, x, y, want, got)
+			}
+		}
+	}
+
+	if z := Backend.Exp(big.NewInt(3), big.NewInt(4), big.NewInt(1)); z.Sign() != 0 {
+		t.Fatalf("exp 0 mod 1, saw %s", z)
+	}
+}
+
+func TestHandshake(t *testing.T) {
+	s, err := srp.New(2048, srp.WithBackend(Backend))
+	if err != nil {
+		t.Fatalf("New: %s", err)
+	}
+	if err := handshake(s); err != nil {
+		t.Fatalf("%s", err)
+	}
+}
+
+func BenchmarkHandshake(b *testing.B) {
+	backends := []struct {
+		name string
+		be   srp.Backend
+	}{
+		{"math-big", srp.MathBig},
+		{"gmp", Backend},
+	}
+
+	for _, bits := range []int{2048, 3072, 4096} {
+		for _, x := range backends {
+			if x.name == "gmp" && !Available {
+				continue
+			}
+
+			b.Run(fmt.Sprintf("%d/%s", bits, x.name), func(b *testing.B) {
+				s, err := srp.New(bits, srp.WithBackend(x.be))
+				if err != nil {
+					b.Fatalf("New: %s", err)
+				}
+
+				for i := 0; i < b.N; i++ {
+					if err := handshake(s); err != nil {
+						b.Fatalf("%s", err)
+					}
+				}
+			})
+		}
+	}
+}
+
+// run a complete handshake in the environment 's'
+func handshake(s *srp.SRP) error {
+	user := []byte("user")
+	pass := []byte("pass")
+
+	v, err := s.Verifier(user, pass, nil)
+	if err != nil {
+		return err
+	}
+
+	c, err := s.NewClient(user, pass)
+	if err != nil {
+		return err
+	}
+
+	_, A, err := srp.ServerBegin(c.Credentials())
+	if err != nil {
+		return err
+	}
+
+	srv, err := s.NewServer(v, A)
+	if err != nil {
+		return err
+	}
+
+	m, err := c.Generate(srv.Credentials())
+	if err != nil {
+		return err
+	}
+
+	proof, ok := srv.ClientOk(m)
+	if !ok || !c.ServerOk(proof) {
+		return fmt.Errorf("handshake failed")
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+//go:build !cgo || !gmp
+// +build !cgo !gmp
+
+// nogmp.go - fallback without GMP
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package gmp
+
+import (
+	"github.com/tomsons/go-srp"
+)
+
+// Available is true if Backend uses GMP
+const Available = false
+
+// Backend is srp.MathBig when GMP isn't available
+var Backend = srp.MathBig
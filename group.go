@@ -0,0 +1,264 @@
+// group.go - custom prime fields and policies restricting their use
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+)
+
+// GroupInfo describes a built-in group or the group of an environment
+type GroupInfo struct {
+	ID     string   // standard identifier, e.g., "rfc5054-3072"
+	Bits   int      // size of N in bits
+	N, G   *big.Int // the safe prime and generator
+	Strong bool     // false if it needs WithInsecureGroups()
+}
+
+// SupportedGroups returns the built-in groups in increasing size. Groups
+// of the same size are listed with the default group (used by New()) first;
+// the others must be selected with NewWithGroupID(). The returned values are
+// copies and may be modified by the caller.
+func SupportedGroups() []GroupInfo {
+	gi := make([]GroupInfo, 0, len(groups))
+	for _, pf := range groups {
+		gi = append(gi, pf.info())
+	}
+	return gi
+}
+
+// GroupID returns the standard identifier of the group of this environment
+// (see SupportedGroups()) or "" if it is a custom group.
+func (s *SRP) GroupID() string {
+	return s.pf.id
+}
+
+// Group describes the group of this environment; the ID of a custom group
+// is "". The returned values are copies.
+func (s *SRP) Group() GroupInfo {
+	return s.pf.info()
+}
+
+// return the description of the group
+func (pf *primeField) info() GroupInfo {
+	bits := pf.n * 8
+	return GroupInfo{
+		ID:     pf.id,
+		Bits:   bits,
+		N:      big.NewInt(0).Set(pf.N),
+		G:      big.NewInt(0).Set(pf.g),
+		Strong: bits >= MinimumBits,
+	}
+}
+
+// ErrGroupMismatch is matched (with errors.Is()) by the errors of
+// handshakes whose verifier or peer uses another group than the
+// environment; errors.As() with a *GroupMismatchError yields both groups.
+// A verifier v = g^x mod N is only valid in its own group: moving a user
+// to a larger group needs a new verifier made from the password (see
+// UpgradeVerifier()).
+var ErrGroupMismatch = fmt.Errorf("srp: group mismatch")
+
+// GroupMismatchError is the error of a handshake whose verifier or peer
+// uses another group than the environment
+type GroupMismatchError struct {
+	Source string    // "verifier" or "server"
+	Env    GroupInfo // the group of the environment
+	Other  GroupInfo // the group of the source; fields it didn't tell are zero
+}
+
+// Error implements error
+func (e *GroupMismatchError) Error() string {
+	return fmt.Sprintf("%s: %s uses %s, environment uses %s", ErrGroupMismatch, e.Source, e.Other, e.Env)
+}
+
+// Is returns true if 'target' is ErrGroupMismatch
+func (e *GroupMismatchError) Is(target error) bool {
+	return target == ErrGroupMismatch
+}
+
+// String describes the group as "<bits>-bit group <id>"
+func (gi GroupInfo) String() string {
+	id := gi.ID
+	if id == "" {
+		id = "(custom)"
+	}
+	if gi.Bits == 0 {
+		return "group " + id
+	}
+	return fmt.Sprintf("%d-bit group %s", gi.Bits, id)
+}
+
+// NewWithGroupID creates a new SRP environment using the hash function 'h'
+// (0 for the default hash, as in New()) and the built-in group named 'id'
+// (see SupportedGroups()). It is needed for groups that have the same size
+// as a default group, e.g., the RFC 3526 MODP groups used by some other
+// implementations; servers in such groups send the group id with their
+// credentials and clients reject servers that use another group of the
+// same size.
+func NewWithGroupID(h crypto.Hash, id string, opts ...Option) (*SRP, error) {
+	pf := groupByID(id)
+	if pf == nil {
+		return nil, fmt.Errorf("srp: unknown group %s", id)
+	}
+	return newEnv(h, pf, opts)
+}
+
+// LookupGroup returns the built-in group named 'id' (see
+// SupportedGroups()); the returned values are copies.
+func LookupGroup(id string) (GroupInfo, bool) {
+	pf := groupByID(id)
+	if pf == nil {
+		return GroupInfo{}, false
+	}
+	return pf.info(), true
+}
+
+// return the built-in group named 'id' or nil
+func groupByID(id string) *primeField {
+	for _, pf := range groups {
+		if pf.id == id {
+			return pf
+		}
+	}
+	return nil
+}
+
+// return the built-in group named 'id' if it is given; it must be the same
+// group as the decoded 'pf'. Otherwise 'pf' is returned.
+func resolveGroup(id string, pf *primeField) (*primeField, error) {
+	if id == "" {
+		return pf, nil
+	}
+
+	g := groupByID(id)
+	if g == nil {
+		return nil, fmt.Errorf("verifier: unknown group %s", id)
+	}
+	if !g.is(pf) {
+		return nil, fmt.Errorf("verifier: prime field doesn't match group %s", id)
+	}
+	return g, nil
+}
+
+// return true if 'pf' and 'o' are the same group; 'o' may be nil
+func (pf *primeField) is(o *primeField) bool {
+	return o != nil && pf.n == o.n && pf.N.Cmp(o.N) == 0 && pf.g.Cmp(o.g) == 0
+}
+
+// GroupPolicy decides whether the prime field (N, g) may be used. A policy
+// attached to an environment via WithGroupPolicy() is consulted at the start
+// of every handshake; it lets clients and servers pin the groups they accept
+// instead of trusting whatever was configured or decoded from storage.
+type GroupPolicy interface {
+	CheckGroup(N, g *big.Int) error
+}
+
+// GroupPolicyFunc is an adapter to use ordinary functions as a GroupPolicy
+type GroupPolicyFunc func(N, g *big.Int) error
+
+// CheckGroup calls f(N, g)
+func (f GroupPolicyFunc) CheckGroup(N, g *big.Int) error {
+	return f(N, g)
+}
+
+// WithGroupPolicy makes every handshake in the environment consult 'p'
+func WithGroupPolicy(p GroupPolicy) Option {
+	return func(s *SRP) error {
+		if p == nil {
+			return fmt.Errorf("srp: nil group policy")
+		}
+		s.gp = p
+		return nil
+	}
+}
+
+// AllowGroups returns a GroupPolicy that only accepts the groups whose
+// fingerprints (see GroupFingerprint()) are in 'fps'.
+func AllowGroups(fps ...[]byte) GroupPolicy {
+	m := make(map[string]bool, len(fps))
+	for _, fp := range fps {
+		m[string(fp)] = true
+	}
+
+	return GroupPolicyFunc(func(N, g *big.Int) error {
+		if !m[string(GroupFingerprint(N, g))] {
+			return fmt.Errorf("srp: prime field is not in the allowed set")
+		}
+		return nil
+	})
+}
+
+// GroupFingerprint returns a SHA-256 fingerprint of the prime field (N, g);
+// it is suitable for pinning groups via AllowGroups().
+func GroupFingerprint(N, g *big.Int) []byte {
+	n := (N.BitLen() + 7) / 8
+	h := sha256.New()
+	h.Write(pad(N, n))
+	h.Write(pad(g, n))
+	return h.Sum(nil)
+}
+
+// NewWithGroup creates a new SRP environment using the hash function 'h' (0
+// for the default hash, as in New()) and a custom prime field: N must be a safe prime and g must generate the
+// multiplicative group mod N or its subgroup of order (N-1)/2, like g = 2
+// in the RFC 3526 groups. Checking N is expensive; callers should create
+// the environment once and reuse it.
+func NewWithGroup(h crypto.Hash, N, g *big.Int, opts ...Option) (*SRP, error) {
+	if err := checkGroup(N, g); err != nil {
+		return nil, err
+	}
+
+	pf := &primeField{
+		N: big.NewInt(0).Set(N),
+		g: big.NewInt(0).Set(g),
+		n: (N.BitLen() + 7) / 8,
+	}
+	return newEnv(h, pf, opts)
+}
+
+// return an error if N isn't a safe prime or g generates neither the
+// group mod N nor its subgroup of order q = (N-1)/2
+func checkGroup(N, g *big.Int) error {
+	if N.Sign() <= 0 || N.Bit(0) == 0 {
+		return fmt.Errorf("srp: N is not an odd prime")
+	}
+
+	if g.Cmp(one) <= 0 || g.Cmp(N) >= 0 {
+		return fmt.Errorf("srp: g is out of range")
+	}
+
+	if !N.ProbablyPrime(20) {
+		return fmt.Errorf("srp: N is not prime")
+	}
+
+	q := big.NewInt(0).Rsh(N, 1)
+	if !q.ProbablyPrime(20) {
+		return fmt.Errorf("srp: N is not a safe prime")
+	}
+
+	// with N = 2q+1, every g in (1, N-1) has order q or 2q; N-1 has
+	// order 2
+	if g.Cmp(big.NewInt(0).Sub(N, one)) == 0 {
+		return fmt.Errorf("srp: g is not a generator mod N")
+	}
+	return nil
+}
+
+// consult the pinned parameters and the group policy of the environment,
+// if any
+func (s *SRP) checkPolicy() error {
+	if err := s.checkPin(); err != nil {
+		return err
+	}
+	if s.gp == nil {
+		return nil
+	}
+	return s.gp.CheckGroup(big.NewInt(0).Set(s.pf.N), big.NewInt(0).Set(s.pf.g))
+}
@@ -0,0 +1,418 @@
+// self test for custom groups and group policies
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"crypto"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestNewWithGroup(t *testing.T) {
+	assert := newAsserter(t)
+
+	pf := pflist[2048]
+	s, err := NewWithGroup(crypto.SHA256, pf.N, pf.g)
+	assert(err == nil, "NewWithGroup: %s", err)
+	assert(s.FieldSize() == 2048, "exp 2048 bit field, saw %d", s.FieldSize())
+
+	db := newUserDBFrom(t, s, []byte("user"), []byte("pass"))
+	db.verify(t, []byte("user"), []byte("pass"), true)
+
+	// 1024-bit fields need an explicit opt-in
+	weak := pflist[1024]
+	_, err = NewWithGroup(crypto.SHA256, weak.N, weak.g)
+	assert(err != nil, "accepted weak group")
+	_, err = NewWithGroup(crypto.SHA256, weak.N, weak.g, WithInsecureGroups())
+	if FIPSBuild {
+		assert(errors.Is(err, ErrFIPS), "FIPS build accepted weak group")
+	} else {
+		assert(err == nil, "WithInsecureGroups: %s", err)
+	}
+
+	// 2^127-1 is prime, but not a safe prime
+	p := big.NewInt(0).Sub(big.NewInt(0).Lsh(one, 127), one)
+	bad := []struct {
+		N, g *big.Int
+	}{
+		{big.NewInt(0).Add(pf.N, one), pf.g},
+		{big.NewInt(0).Add(pf.N, big.NewInt(2)), pf.g},
+		{pf.N, big.NewInt(1)},
+		{pf.N, pf.N},
+		{pf.N, big.NewInt(0).Sub(pf.N, one)},
+		{p, big.NewInt(3)},
+	}
+	for i, x := range bad {
+		_, err = NewWithGroup(crypto.SHA256, x.N, x.g, WithInsecureGroups())
+		assert(err != nil, "%d: accepted bad group", i)
+	}
+
+	// g may generate the subgroup of order (N-1)/2
+	_, err = NewWithGroup(crypto.SHA256, pf.N, big.NewInt(4))
+	assert(err == nil, "g=4: %s", err)
+
+	// a zero hash takes the default of New() in all constructors
+	d, err := New(2048)
+	assert(err == nil, "New: %s", err)
+	s, err = NewWithGroup(0, pf.N, pf.g)
+	assert(err == nil && s.h == d.h, "NewWithGroup: no default hash: %v", err)
+	s, err = NewWithGroupID(0, d.GroupID())
+	assert(err == nil && s.h == d.h, "NewWithGroupID: no default hash: %v", err)
+}
+
+func TestGroupPolicy(t *testing.T) {
+	assert := newAsserter(t)
+
+	user := []byte("user")
+	pass := []byte("pass")
+
+	pf := pflist[2048]
+	pin := WithGroupPolicy(AllowGroups(GroupFingerprint(pf.N, pf.g)))
+
+	s, err := New(2048, pin)
+	assert(err == nil, "New: %s", err)
+	_, err = s.NewClient(user, pass)
+	assert(err == nil, "NewClient: %s", err)
+
+	s3, err := New(3072, pin)
+	assert(err == nil, "New: %s", err)
+	_, err = s3.NewClient(user, pass)
+	assert(err != nil, "client accepted unpinned group")
+
+	// a server pins the groups it accepts from stored verifiers
+	v, err := s3.Verifier(user, pass, nil)
+	assert(err == nil, "Verifier: %s", err)
+	_, vh := v.Encode()
+
+	ss, sv, err := MakeSRPVerifier(vh, pin)
+	assert(err == nil, "MakeSRPVerifier: %s", err)
+
+	c, err := New(3072)
+	assert(err == nil, "New: %s", err)
+	cl, err := c.NewClient(user, pass)
+	assert(err == nil, "NewClient: %s", err)
+
+	_, A, err := ServerBegin(cl.Credentials())
+	assert(err == nil, "ServerBegin: %s", err)
+	_, err = ss.NewServer(sv, A)
+	assert(err != nil, "server accepted unpinned group")
+}
+
+func TestSupportedGroups(t *testing.T) {
+	assert := newAsserter(t)
+
+	gs := SupportedGroups()
+	assert(len(gs) == len(pflist)+3, "exp %d groups, saw %d", len(pflist)+3, len(gs))
+
+	ids := make(map[string]bool)
+	for i, g := range gs {
+		assert(g.ID != "" && !ids[g.ID], "%d: bad or duplicate id %q", i, g.ID)
+		ids[g.ID] = true
+
+		assert(g.N.BitLen() == g.Bits, "%s: exp %d bits, saw %d", g.ID, g.Bits, g.N.BitLen())
+		assert(g.Strong == (g.Bits >= MinimumBits), "%s: wrong strength", g.ID)
+		if i > 0 {
+			assert(gs[i-1].Bits <= g.Bits, "%s: groups not sorted", g.ID)
+		}
+
+		if FIPSBuild && g.Bits < fipsMinBits {
+			continue
+		}
+		s, err := NewWithGroupID(crypto.SHA256, g.ID, WithInsecureGroups())
+		assert(err == nil, "%s: NewWithGroupID: %s", g.ID, err)
+		assert(s.GroupID() == g.ID, "%s: wrong group %s", g.ID, s.GroupID())
+	}
+
+	// callers get copies
+	gs[0].N.SetInt64(7)
+	assert(SupportedGroups()[0].N.Cmp(big.NewInt(7)) != 0, "SupportedGroups returned shared values")
+}
+
+func TestGroupIDs(t *testing.T) {
+	assert := newAsserter(t)
+
+	user := []byte("user")
+	pass := []byte("pass")
+
+	s, err := New(2048)
+	assert(err == nil, "New: %s", err)
+	assert(s.GroupID() == "rfc5054-2048", "wrong group id %q", s.GroupID())
+
+	pf := pflist[2048]
+	cs, err := NewWithGroup(crypto.SHA256, pf.N, pf.g)
+	assert(err == nil, "NewWithGroup: %s", err)
+	assert(cs.GroupID() == "", "custom group has id %q", cs.GroupID())
+
+	v, err := s.Verifier(user, pass, nil)
+	assert(err == nil, "Verifier: %s", err)
+
+	_, vh := v.Encode()
+	assert(strings.HasSuffix(vh, ":grp=rfc5054-2048"), "group id missing in verifier")
+
+	ss, _, err := MakeSRPVerifier(vh)
+	assert(err == nil, "MakeSRPVerifier: %s", err)
+	assert(ss.pf == pf, "verifier doesn't use the built-in group")
+
+	_, _, err = MakeSRPVerifier(strings.Replace(vh, "grp=rfc5054-2048", "grp=rfc5054-3072", 1))
+	assert(err != nil, "accepted verifier with mismatched group id")
+	_, _, err = MakeSRPVerifier(strings.Replace(vh, "grp=rfc5054-2048", "grp=nope", 1))
+	assert(err != nil, "accepted verifier with unknown group id")
+
+	_, _, err = DecodeVerifierCBOR(v.EncodeCBOR())
+	assert(err == nil, "DecodeVerifierCBOR: %s", err)
+
+	// a client refuses a server that names another group
+	c, err := s.NewClient(user, pass)
+	assert(err == nil, "NewClient: %s", err)
+
+	srv, err := s.NewServer(v, big.NewInt(0).SetBytes(c.Hello().A))
+	assert(err == nil, "NewServer: %s", err)
+
+	sc := srv.Challenge()
+	sc.Group = "rfc5054-3072"
+	_, err = c.Respond(sc)
+	assert(err != nil, "accepted server with another group")
+
+	sc2, err := parseServerCredentials(sc.encode())
+	assert(err == nil, "parseServerCredentials: %s", err)
+	assert(sc2.Group == sc.Group, "group id lost in encoding")
+
+	sc2, err = DecodeServerCredentialsCBOR(sc.EncodeCBOR())
+	assert(err == nil, "DecodeServerCredentialsCBOR: %s", err)
+	assert(sc2.Group == sc.Group, "group id lost in CBOR encoding")
+
+	sc.Group = "rfc5054-2048"
+	_, err = c.Respond(sc)
+	assert(err == nil, "Respond: %s", err)
+
+	m := srv.Marshal()
+	assert(strings.HasSuffix(m, ":grp=rfc5054-2048"), "group id missing in marshaled server")
+
+	u, err := UnmarshalServer(m)
+	assert(err == nil, "UnmarshalServer: %s", err)
+	assert(u.s.pf == pf, "unmarshaled server doesn't use the built-in group")
+}
+
+func TestAltGroups(t *testing.T) {
+	assert := newAsserter(t)
+
+	user := []byte("user")
+	pass := []byte("pass")
+
+	_, err := NewWithGroupID(crypto.SHA256, "rfc2409-1024")
+	assert(err != nil, "weak group accepted")
+	_, err = NewWithGroupID(crypto.SHA256, "rfc3526-1024")
+	assert(err != nil, "unknown group accepted")
+
+	s, err := NewWithGroupID(crypto.SHA256, "rfc3526-2048")
+	assert(err == nil, "NewWithGroupID: %s", err)
+	assert(s.FieldSize() == 2048, "wrong size %d", s.FieldSize())
+
+	g := s.Group()
+	assert(g.ID == "rfc3526-2048" && g.Bits == 2048 && g.Strong, "wrong group info %+v", g)
+	assert(g.N.Cmp(s.pf.N) == 0 && g.N != s.pf.N, "N isn't a copy")
+
+	d, err := NewWithHash(crypto.SHA256, 2048)
+	assert(err == nil, "NewWithHash: %s", err)
+	assert(!d.pf.is(s.pf), "default group is the RFC 3526 group")
+
+	v, err := s.Verifier(user, pass, nil)
+	assert(err == nil, "Verifier: %s", err)
+	_, vh := v.Encode()
+
+	ss, sv, err := MakeSRPVerifier(vh)
+	assert(err == nil, "MakeSRPVerifier: %s", err)
+	assert(ss.GroupID() == "rfc3526-2048", "wrong group %s", ss.GroupID())
+
+	// handshake in the RFC 3526 group
+	c, err := s.NewClient(user, pass)
+	assert(err == nil, "NewClient: %s", err)
+
+	_, A, err := ServerBegin(c.Credentials())
+	assert(err == nil, "ServerBegin: %s", err)
+
+	srv, err := ss.NewServer(sv, A)
+	assert(err == nil, "NewServer: %s", err)
+
+	creds := srv.Credentials()
+	assert(strings.HasSuffix(creds, ":grp=rfc3526-2048"), "group id missing in %s", creds)
+
+	srv, err = UnmarshalServer(srv.Marshal())
+	assert(err == nil, "UnmarshalServer: %s", err)
+	assert(srv.s.GroupID() == "rfc3526-2048", "marshaled server lost its group")
+
+	m, err := c.Generate(creds)
+	assert(err == nil, "Generate: %s", err)
+
+	proof, ok := srv.ClientOk(m)
+	assert(ok, "server rejected client")
+	assert(c.ServerOk(proof), "client rejected server")
+
+	// clients of the default group reject the server and vice versa
+	dc, err := d.NewClient(user, pass)
+	assert(err == nil, "NewClient: %s", err)
+	_, err = dc.Generate(creds)
+	assert(err != nil, "default client accepted RFC 3526 server")
+
+	dv, err := d.Verifier(user, pass, nil)
+	assert(err == nil, "Verifier: %s", err)
+	_, dvh := dv.Encode()
+	ds, dsv, err := MakeSRPVerifier(dvh)
+	assert(err == nil, "MakeSRPVerifier: %s", err)
+
+	c, err = s.NewClient(user, pass)
+	assert(err == nil, "NewClient: %s", err)
+	_, A, err = ServerBegin(c.Credentials())
+	assert(err == nil, "ServerBegin: %s", err)
+	dsrv, err := ds.NewServer(dsv, A)
+	assert(err == nil, "NewServer: %s", err)
+	_, err = c.Generate(dsrv.Credentials())
+	assert(err != nil, "RFC 3526 client accepted default server")
+}
+
+func TestAnalyzeGroup(t *testing.T) {
+	assert := newAsserter(t)
+
+	for _, gi := range SupportedGroups() {
+		if gi.Bits > 3072 {
+			continue
+		}
+		r := AnalyzeGroup(gi.N, gi.G, 4)
+		assert(r.ID == gi.ID, "%s: identified as %q", gi.ID, r.ID)
+		assert(r.Prime && r.SafePrime, "%s: not a safe prime", gi.ID)
+		assert(r.Order == "2q" || r.Order == "q", "%s: order %s", gi.ID, r.Order)
+		if strings.HasPrefix(gi.ID, "rfc5054") && gi.Strong {
+			assert(r.Err() == nil, "%s: %s", gi.ID, r.Err())
+		}
+	}
+
+	s, err := New(2048)
+	assert(err == nil, "New: %s", err)
+	N := s.pf.N
+
+	// a square generates only the subgroup of order q, which is sound
+	r := AnalyzeGroup(N, big.NewInt(4), 4)
+	assert(r.ID == "" && r.Order == "q" && r.QuadraticResidue, "g=4: %+v", r)
+	assert(r.Err() == nil, "g=4: %s", r.Err())
+
+	// as are the built-in groups whose g generates that subgroup
+	for _, id := range []string{"rfc3526-1536", "rfc3526-2048"} {
+		if FIPSBuild && id == "rfc3526-1536" {
+			continue
+		}
+		s, err := NewWithGroupID(crypto.SHA256, id, WithInsecureGroups())
+		assert(err == nil, "%s: NewWithGroupID: %s", id, err)
+		r = AnalyzeGroup(s.pf.N, s.pf.g, 4)
+		if s.pf.n*8 >= MinimumBits {
+			assert(r.Err() == nil, "%s: %s", id, r.Err())
+		}
+		assert(r.Order == "q", "%s: order %s", id, r.Order)
+	}
+
+	r = AnalyzeGroup(big.NewInt(0).Sub(N, one), big.NewInt(2), 4)
+	assert(!r.Prime && r.Err() != nil, "N-1 is prime")
+
+	// 23 = 2*11 + 1 is a safe prime; 5 generates Z23*, 22 = -1 has order 2
+	r = AnalyzeGroup(big.NewInt(23), big.NewInt(5), 0)
+	assert(r.SafePrime && r.Order == "2q" && r.Rounds == DefaultPrimeRounds, "23: %+v", r)
+	assert(r.Err() != nil, "tiny group accepted")
+	r = AnalyzeGroup(big.NewInt(23), big.NewInt(22), 0)
+	assert(r.Order == "2", "23: order of 22 is %s", r.Order)
+
+	// 29 is prime but 14 isn't
+	r = AnalyzeGroup(big.NewInt(29), big.NewInt(2), 0)
+	assert(r.Prime && !r.SafePrime, "29: %+v", r)
+}
+
+func TestGroupMismatch(t *testing.T) {
+	assert := newAsserter(t)
+
+	s2, err := New(2048)
+	assert(err == nil, "New: %s", err)
+	s3, err := New(3072)
+	assert(err == nil, "New: %s", err)
+
+	v, err := s2.Verifier([]byte("user"), []byte("pass"), nil)
+	assert(err == nil, "Verifier: %s", err)
+
+	// a 2048-bit verifier can't be used in a 3072-bit handshake
+	c, err := s3.NewClient([]byte("user"), []byte("pass"))
+	assert(err == nil, "NewClient: %s", err)
+	_, err = s3.NewServer(v, c.xA)
+	assert(errors.Is(err, ErrGroupMismatch), "exp ErrGroupMismatch, saw %v", err)
+
+	var ge *GroupMismatchError
+	assert(errors.As(err, &ge), "exp *GroupMismatchError, saw %T", err)
+	assert(ge.Source == "verifier" && ge.Env.Bits == 3072 && ge.Other.Bits == 2048, "wrong details %+v", ge)
+	assert(strings.Contains(err.Error(), "2048-bit group rfc5054-2048"), "no details in %q", err)
+
+	// a client in the 2048-bit MODP group rejects the default group
+	alt, err := NewWithGroupID(crypto.SHA256, "rfc3526-2048")
+	assert(err == nil, "NewWithGroupID: %s", err)
+	ca, err := alt.NewClient([]byte("user"), []byte("pass"))
+	assert(err == nil, "NewClient: %s", err)
+
+	c2, _ := s2.NewClient([]byte("user"), []byte("pass"))
+	srv, err := s2.NewServer(v, c2.xA)
+	assert(err == nil, "NewServer: %s", err)
+	_, err = ca.Respond(srv.Challenge())
+	assert(errors.As(err, &ge) && ge.Source == "server", "exp server mismatch, saw %v", err)
+}
+
+// mutator is a ProofScheme that scribbles over the group it is given
+type mutator struct {
+	ProofScheme
+}
+
+func (m mutator) ClientProof(t *Transcript) []byte {
+	t.N.SetInt64(7)
+	t.G.SetInt64(3)
+	return m.ProofScheme.ClientProof(t)
+}
+
+func TestGroupsImmutable(t *testing.T) {
+	assert := newAsserter(t)
+
+	want := make(map[string][]byte)
+	for _, gi := range SupportedGroups() {
+		want[gi.ID] = GroupFingerprint(gi.N, gi.G)
+	}
+
+	// callers get copies
+	gi, ok := LookupGroup("rfc5054-2048")
+	assert(ok, "LookupGroup failed")
+	gi.N.SetInt64(5)
+	_, ok = LookupGroup("no-such-group")
+	assert(!ok, "found unknown group")
+
+	s, err := New(2048)
+	assert(err == nil, "New: %s", err)
+	s.Group().G.SetInt64(4)
+
+	// so do proof schemes and group policies
+	policy := GroupPolicyFunc(func(N, g *big.Int) error {
+		N.SetInt64(11)
+		return nil
+	})
+	s, err = New(2048, WithProofScheme(mutator{ProofLegacy}), WithGroupPolicy(policy))
+	assert(err == nil, "New: %s", err)
+	v, err := s.Verifier([]byte("user"), []byte("pass"), nil)
+	assert(err == nil, "Verifier: %s", err)
+	c, err := s.NewClient([]byte("user"), []byte("pass"))
+	assert(err == nil, "NewClient: %s", err)
+	srv, err := s.NewServer(v, c.xA)
+	assert(err == nil, "NewServer: %s", err)
+	authenticate(c, srv)
+
+	for _, gi := range SupportedGroups() {
+		assert(ctEqual(GroupFingerprint(gi.N, gi.G), want[gi.ID]), "group %s modified", gi.ID)
+	}
+	gi, _ = LookupGroup("rfc5054-2048")
+	assert(gi.N.Cmp(pflist[2048].N) == 0 && gi.Bits == 2048, "LookupGroup: wrong group")
+}
@@ -0,0 +1,116 @@
+// groupcheck.go - analysis of custom prime fields
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// DefaultPrimeRounds is the number of Miller-Rabin rounds AnalyzeGroup()
+// runs on custom groups when asked for 0.
+const DefaultPrimeRounds = 64
+
+// GroupReport holds the facts about a prime field (N, g) found by
+// AnalyzeGroup(); security teams can use it to vet a custom group before
+// registering it with NewWithGroup() or AllowGroups().
+type GroupReport struct {
+	Bits        int    // size of N in bits
+	Fingerprint []byte // see GroupFingerprint()
+
+	// ID is the identifier of the built-in group (N, g) is equal to, or
+	// "" for a custom group. The built-in groups are published in RFC 5054
+	// and RFC 3526 with proofs of primality, so their verdicts are known in
+	// advance; custom groups are only probably prime.
+	ID string
+
+	Rounds    int  // Miller-Rabin rounds run on N and q = (N-1)/2
+	Prime     bool // N is (probably) prime
+	SafePrime bool // q is (probably) prime as well
+
+	// Order of g in the multiplicative group mod N, as a multiple of q:
+	// "2q" for a generator of the whole group, "q" for a generator of the
+	// subgroup of quadratic residues, "2" for N-1 or "" if unknown (N isn't
+	// a safe prime or g is out of range). The subgroup of order q is sound
+	// for SRP as well and is what g = 2 generates in the RFC 3526 and
+	// RFC 2409 groups, so both orders are accepted.
+	Order            string
+	QuadraticResidue bool // g is a square mod N
+	Strong           bool // N is at least MinimumBits wide
+}
+
+// AnalyzeGroup checks whether N is a safe prime and g generates the
+// multiplicative group mod N or its subgroup of order q, and reports the
+// facts it found. Custom
+// groups are tested with 'rounds' rounds of Miller-Rabin (and the
+// Baillie-PSW test of math/big); the chance that a composite passes is at
+// most 4^-rounds. Checking a large group takes seconds.
+func AnalyzeGroup(N, g *big.Int, rounds int) GroupReport {
+	if rounds <= 0 {
+		rounds = DefaultPrimeRounds
+	}
+
+	r := GroupReport{
+		Bits:   N.BitLen(),
+		Rounds: rounds,
+		Strong: N.BitLen() >= MinimumBits,
+	}
+	if N.Sign() <= 0 {
+		return r
+	}
+	r.Fingerprint = GroupFingerprint(N, g)
+
+	for _, pf := range groups {
+		if pf.N.Cmp(N) == 0 && pf.g.Cmp(g) == 0 {
+			r.ID = pf.id
+			break
+		}
+	}
+
+	r.Prime = N.Bit(0) == 1 && N.ProbablyPrime(rounds)
+	if !r.Prime {
+		return r
+	}
+
+	q := big.NewInt(0).Rsh(N, 1)
+	r.SafePrime = q.ProbablyPrime(rounds)
+
+	N1 := big.NewInt(0).Sub(N, one)
+	if !r.SafePrime || g.Cmp(one) <= 0 || g.Cmp(N1) > 0 {
+		return r
+	}
+
+	// with N = 2q+1, the order of g is 1, 2, q or 2q; g^q is the Legendre
+	// symbol of g
+	r.QuadraticResidue = big.NewInt(0).Exp(g, q, N).Cmp(one) == 0
+	switch {
+	case g.Cmp(N1) == 0:
+		r.Order = "2"
+	case r.QuadraticResidue:
+		r.Order = "q"
+	default:
+		r.Order = "2q"
+	}
+	return r
+}
+
+// Err returns the reason the group is unfit for NewWithGroup(), or nil
+func (r *GroupReport) Err() error {
+	switch {
+	case !r.Prime:
+		return fmt.Errorf("srp: N is not prime")
+	case !r.SafePrime:
+		return fmt.Errorf("srp: N is not a safe prime")
+	case r.Order == "":
+		return fmt.Errorf("srp: g is out of range")
+	case r.Order != "2q" && r.Order != "q":
+		return fmt.Errorf("srp: g is not a generator mod N (order %s)", r.Order)
+	case !r.Strong:
+		return fmt.Errorf("srp: %d bit prime-field is insecure", r.Bits)
+	}
+	return nil
+}
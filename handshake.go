@@ -0,0 +1,124 @@
+// handshake.go - a complete handshake in a single call
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// Handshake() runs either side of a complete handshake, including the
+// check of the peer's proof, over any stream: a net.Conn of a TCP service,
+// a pipe to a child process or a serial line. Both sides exchange the
+// frames of stream.go, so a peer may also use Client.Handshake() or
+// ServerHandshake(). Handshake() returns the session key K and the hashed
+// identity of the user only if the peer was authenticated.
+//
+// The role can be given or left to Handshake() (RoleAuto): a Config with a
+// Lookup is a server, one with a password a client. The timeouts of
+// Config.HandshakeConfig and the cancellation of Config.Context apply to
+// a net.Conn only; other streams must be closed to interrupt a stalled
+// handshake.
+
+// Role is the side of a handshake run by Handshake()
+type Role int
+
+// Roles of Handshake()
+const (
+	RoleAuto   Role = iota // RoleServer if Config.Lookup is set, else RoleClient
+	RoleClient             // authenticate to the peer with Config.Identity and Config.Password
+	RoleServer             // authenticate the peer with Config.Lookup
+)
+
+// String returns the name of the role
+func (r Role) String() string {
+	switch r {
+	case RoleAuto:
+		return "auto"
+	case RoleClient:
+		return "client"
+	case RoleServer:
+		return "server"
+	}
+	return fmt.Sprintf("Role(%d)", int(r))
+}
+
+// Config configures Handshake()
+type Config struct {
+	// HandshakeConfig has the timeouts of the handshake and, for
+	// servers, the failure hook and tarpit
+	HandshakeConfig
+
+	// Context cancels the handshake; nil means context.Background()
+	Context context.Context
+
+	// SRP is the environment of a client
+	SRP *SRP
+
+	// Identity and Password are the credentials of a client
+	Identity []byte
+	Password []byte
+
+	// Lookup finds the verifiers of a server
+	Lookup VerifierLookup
+}
+
+// Handshake runs the side 'role' of a handshake over 'rw' (see above) and
+// returns the session key K and the hashed identity of the user once the
+// peer is authenticated.
+func Handshake(rw io.ReadWriter, role Role, cfg Config) (key, identity []byte, err error) {
+	ctx := cfg.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if role == RoleAuto {
+		role, err = cfg.role()
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	switch role {
+	case RoleClient:
+		if cfg.SRP == nil {
+			return nil, nil, fmt.Errorf("srp: handshake: client without an environment")
+		}
+		c, err := cfg.SRP.NewClient(cfg.Identity, cfg.Password)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := c.handshake(ctx, rw, &cfg.HandshakeConfig); err != nil {
+			return nil, nil, err
+		}
+		return c.RawKey(), append([]byte{}, c.i...), nil
+
+	case RoleServer:
+		if cfg.Lookup == nil {
+			return nil, nil, fmt.Errorf("srp: handshake: server without a verifier lookup")
+		}
+		srv, err := serverHandshakeOn(ctx, rw, cfg.Lookup, &cfg.HandshakeConfig)
+		if err != nil {
+			return nil, nil, err
+		}
+		return srv.RawKey(), append([]byte{}, srv.i...), nil
+	}
+	return nil, nil, fmt.Errorf("srp: handshake: unknown role %s", role)
+}
+
+// return the role of a handshake with this configuration
+func (cfg *Config) role() (Role, error) {
+	client := cfg.SRP != nil || cfg.Password != nil
+	switch {
+	case cfg.Lookup != nil && !client:
+		return RoleServer, nil
+	case cfg.Lookup == nil && client:
+		return RoleClient, nil
+	}
+	return RoleAuto, fmt.Errorf("srp: handshake: can't tell the role from the configuration")
+}
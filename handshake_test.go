@@ -0,0 +1,98 @@
+// self test for single call handshakes
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+)
+
+// one end of a pair of pipes
+type pipeEnd struct {
+	io.Reader
+	io.Writer
+}
+
+// return the ends of a bidirectional stream that isn't a net.Conn
+func streamPair() (a, b *pipeEnd, closer func()) {
+	r1, w1 := io.Pipe()
+	r2, w2 := io.Pipe()
+	return &pipeEnd{r1, w2}, &pipeEnd{r2, w1}, func() {
+		w1.Close()
+		w2.Close()
+	}
+}
+
+func TestHandshakeRoles(t *testing.T) {
+	assert := newAsserter(t)
+
+	s, err := New(2048)
+	assert(err == nil, "New: %s", err)
+	v, err := s.Verifier([]byte("user"), []byte("pass"), nil)
+	assert(err == nil, "Verifier: %s", err)
+
+	lookup := func(ih []byte) (*SRP, *Verifier, error) {
+		if !v.MatchesIdentity(ih) {
+			return nil, nil, fmt.Errorf("unknown user")
+		}
+		return s, v, nil
+	}
+
+	type result struct {
+		K, ih []byte
+		err   error
+	}
+
+	for _, conn := range []bool{false, true} {
+		for _, pw := range []string{"pass", "wrong"} {
+			var cs, ss io.ReadWriter
+			var closer func()
+			if conn {
+				c1, c2 := net.Pipe()
+				cs, ss, closer = c1, c2, func() { c1.Close(); c2.Close() }
+			} else {
+				cs, ss, closer = streamPair()
+			}
+
+			done := make(chan result, 1)
+			go func() {
+				K, ih, err := Handshake(ss, RoleAuto, Config{Lookup: lookup})
+				closer()
+				done <- result{K, ih, err}
+			}()
+
+			cfg := Config{SRP: s, Identity: []byte("user"), Password: []byte(pw)}
+			K, ih, err := Handshake(cs, RoleAuto, cfg)
+			closer()
+			r := <-done
+
+			if pw != "pass" {
+				assert(err != nil && r.err != nil, "conn %v: wrong password accepted", conn)
+				continue
+			}
+			assert(err == nil, "conn %v: client: %s", conn, err)
+			assert(r.err == nil, "conn %v: server: %s", conn, r.err)
+			assert(bytes.Equal(K, r.K) && len(K) > 0, "conn %v: keys differ", conn)
+			assert(v.MatchesIdentity(ih) && bytes.Equal(ih, r.ih), "conn %v: wrong identity", conn)
+		}
+	}
+
+	// the role must follow from the configuration
+	a, _, closer := streamPair()
+	defer closer()
+	_, _, err = Handshake(a, RoleAuto, Config{})
+	assert(err != nil, "empty config accepted")
+	_, _, err = Handshake(a, RoleAuto, Config{SRP: s, Lookup: lookup})
+	assert(err != nil, "ambiguous config accepted")
+	_, _, err = Handshake(a, RoleServer, Config{SRP: s})
+	assert(err != nil, "server without a lookup accepted")
+	_, _, err = Handshake(a, Role(7), Config{SRP: s})
+	assert(err != nil, "unknown role accepted")
+}
@@ -0,0 +1,186 @@
+// hash.go - hash functions usable by SRP environments
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"crypto"
+	"fmt"
+	"hash"
+	"sort"
+	"sync"
+
+	// register SHA3 against the stdlib enums so it is always available
+	_ "golang.org/x/crypto/sha3"
+)
+
+// CustomHashMin is the smallest id that can be given to WithHasher() or
+// RegisterHash(). Smaller ids are the values of crypto.Hash.
+const CustomHashMin = 256
+
+// registry of hash functions that are not enumerated in "crypto"
+var hashes = struct {
+	sync.RWMutex
+	m map[uint16]func() hash.Hash
+}{
+	m: make(map[uint16]func() hash.Hash),
+}
+
+// RegisterHash makes the hash function 'fn' available under 'id'. This lets
+// hash functions without a crypto.Hash enum (e.g., BLAKE3) be used for SRP
+// and be named in encoded verifiers. A server that only decodes verifiers
+// must register the same functions (under the same ids) that were used to
+// create them. Registering a different function under an existing id
+// replaces it.
+func RegisterHash(id uint16, fn func() hash.Hash) error {
+	if id < CustomHashMin {
+		return fmt.Errorf("srp: hash id %d is reserved; use at least %d", id, CustomHashMin)
+	}
+	if fn == nil {
+		return fmt.Errorf("srp: nil hash function for id %d", id)
+	}
+
+	hashes.Lock()
+	hashes.m[id] = fn
+	hashes.Unlock()
+	return nil
+}
+
+// WithHasher uses the hash function 'fn' for the SRP environment. The
+// function is registered under 'id' (see RegisterHash()) and the id is
+// recorded in encoded verifiers.
+func WithHasher(fn func() hash.Hash, id uint16) Option {
+	return func(s *SRP) error {
+		if err := RegisterHash(id, fn); err != nil {
+			return err
+		}
+		s.h = crypto.Hash(id)
+		return nil
+	}
+}
+
+// return the hash constructor for 'h' or nil if it is unavailable.
+// Ids at or above CustomHashMin are looked up in the registry.
+func hashFunc(h crypto.Hash) func() hash.Hash {
+	if h < CustomHashMin {
+		if h == 0 || !h.Available() {
+			return nil
+		}
+		return h.New
+	}
+
+	if h > 0xffff {
+		return nil
+	}
+
+	hashes.RLock()
+	fn := hashes.m[uint16(h)]
+	hashes.RUnlock()
+	return fn
+}
+
+// return true if the hash function 'h' can be used
+func hashAvailable(h crypto.Hash) bool {
+	return hashFunc(h) != nil
+}
+
+// ErrHashUnavailable is matched (with errors.Is()) by the errors of
+// environments whose hash function isn't linked into the program;
+// errors.As() with a *HashUnavailableError tells which package to import.
+var ErrHashUnavailable = fmt.Errorf("srp: hash algorithm unavailable")
+
+// HashUnavailableError is the error of an environment whose hash function
+// isn't linked into the program
+type HashUnavailableError struct {
+	Hash crypto.Hash
+
+	// Import is the package that registers the hash function, e.g.,
+	// "crypto/sha512", or "" for ids of RegisterHash().
+	Import string
+}
+
+// Error implements error
+func (e *HashUnavailableError) Error() string {
+	if e.Import == "" {
+		return fmt.Sprintf("srp: hash algorithm %d unavailable; register it with RegisterHash()", e.Hash)
+	}
+	return fmt.Sprintf("srp: hash algorithm %s unavailable; add import _ %q", hashName(e.Hash), e.Import)
+}
+
+// Is returns true if 'target' is ErrHashUnavailable
+func (e *HashUnavailableError) Is(target error) bool {
+	return target == ErrHashUnavailable
+}
+
+// names and packages of the hash functions enumerated in "crypto"
+var hashPackages = map[crypto.Hash][2]string{
+	crypto.MD4:         {"MD4", "golang.org/x/crypto/md4"},
+	crypto.MD5:         {"MD5", "crypto/md5"},
+	crypto.SHA1:        {"SHA-1", "crypto/sha1"},
+	crypto.SHA224:      {"SHA-224", "crypto/sha256"},
+	crypto.SHA256:      {"SHA-256", "crypto/sha256"},
+	crypto.SHA384:      {"SHA-384", "crypto/sha512"},
+	crypto.SHA512:      {"SHA-512", "crypto/sha512"},
+	crypto.RIPEMD160:   {"RIPEMD-160", "golang.org/x/crypto/ripemd160"},
+	crypto.SHA3_224:    {"SHA3-224", "golang.org/x/crypto/sha3"},
+	crypto.SHA3_256:    {"SHA3-256", "golang.org/x/crypto/sha3"},
+	crypto.SHA3_384:    {"SHA3-384", "golang.org/x/crypto/sha3"},
+	crypto.SHA3_512:    {"SHA3-512", "golang.org/x/crypto/sha3"},
+	crypto.SHA512_224:  {"SHA-512/224", "crypto/sha512"},
+	crypto.SHA512_256:  {"SHA-512/256", "crypto/sha512"},
+	crypto.BLAKE2s_256: {"BLAKE2s-256", "golang.org/x/crypto/blake2s"},
+	crypto.BLAKE2b_256: {"BLAKE2b-256", "golang.org/x/crypto/blake2b"},
+	crypto.BLAKE2b_384: {"BLAKE2b-384", "golang.org/x/crypto/blake2b"},
+	crypto.BLAKE2b_512: {"BLAKE2b-512", "golang.org/x/crypto/blake2b"},
+}
+
+// return the name of hash function 'h'
+func hashName(h crypto.Hash) string {
+	if p, ok := hashPackages[h]; ok {
+		return p[0]
+	}
+	return fmt.Sprintf("%d", h)
+}
+
+// return a *HashUnavailableError if the hash function 'h' can't be used
+func checkHash(h crypto.Hash) error {
+	if hashAvailable(h) {
+		return nil
+	}
+	return &HashUnavailableError{Hash: h, Import: hashPackages[h][1]}
+}
+
+// AvailableHashes returns the hash functions that can be used by SRP
+// environments in this program, in increasing order: those of "crypto"
+// whose packages are linked in and the ids registered with RegisterHash().
+// Hash functions that MinimumAcceptable() rejects are included; UIs that
+// offer a choice should filter with it.
+func AvailableHashes() []crypto.Hash {
+	var v []crypto.Hash
+	for h := range hashPackages {
+		if h.Available() {
+			v = append(v, h)
+		}
+	}
+
+	hashes.RLock()
+	for id := range hashes.m {
+		v = append(v, crypto.Hash(id))
+	}
+	hashes.RUnlock()
+
+	sort.Slice(v, func(i, j int) bool { return v[i] < v[j] })
+	return v
+}
+
+// return a new instance of hash function 'h'
+func newHash(h crypto.Hash) hash.Hash {
+	fn := hashFunc(h)
+	if fn == nil {
+		panic(fmt.Sprintf("srp: hash algorithm %d unavailable", h))
+	}
+	return fn()
+}
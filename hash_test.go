@@ -0,0 +1,107 @@
+// self test for registered hash functions
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"errors"
+	"hash"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestHashSHA3(t *testing.T) {
+	skipFIPS(t)
+
+	assert := newAsserter(t)
+
+	for _, h := range []crypto.Hash{crypto.SHA3_256, crypto.SHA3_512} {
+		s, err := NewWithHash(h, 2048)
+		assert(err == nil, "NewWithHash %d: %s", h, err)
+
+		db := newUserDBFrom(t, s, []byte("user"), []byte("pass"))
+		db.verify(t, []byte("user"), []byte("pass"), true)
+		db.verify(t, []byte("user"), []byte("bad"), false)
+	}
+}
+
+func TestWithHasher(t *testing.T) {
+	skipFIPS(t)
+
+	assert := newAsserter(t)
+
+	// a stand-in for a hash function that isn't part of "crypto"
+	fn := func() hash.Hash { return sha256.New() }
+
+	_, err := New(2048, WithHasher(fn, 7))
+	assert(err != nil, "accepted reserved hash id")
+
+	_, err = New(2048, WithHasher(nil, CustomHashMin))
+	assert(err != nil, "accepted nil hash function")
+
+	s, err := New(2048, WithHasher(fn, CustomHashMin+1))
+	assert(err == nil, "WithHasher: %s", err)
+	assert(s.h == crypto.Hash(CustomHashMin+1), "wrong hash id %d", s.h)
+
+	db := newUserDBFrom(t, s, []byte("user"), []byte("pass"))
+	db.verify(t, []byte("user"), []byte("pass"), true)
+	db.verify(t, []byte("user"), []byte("bad"), false)
+
+	_, _, err = MakeSRPVerifier("2048:2:2:999:00:00:00")
+	assert(err != nil, "decoded verifier with unregistered hash")
+}
+
+func TestHashUnavailable(t *testing.T) {
+	assert := newAsserter(t)
+
+	avail := AvailableHashes()
+	seen := make(map[crypto.Hash]bool)
+	for i, h := range avail {
+		assert(hashAvailable(h), "%d listed but unavailable", h)
+		assert(i == 0 || avail[i-1] < h, "not sorted: %v", avail)
+		seen[h] = true
+	}
+	assert(seen[crypto.BLAKE2b_256] && seen[crypto.SHA256], "default hashes missing: %v", avail)
+
+	var h crypto.Hash
+	for x := range hashPackages {
+		if !x.Available() {
+			h = x
+			break
+		}
+	}
+	if h == 0 {
+		t.Skip("all hash functions are linked in")
+	}
+
+	_, err := NewWithHash(h, 2048)
+	assert(errors.Is(err, ErrHashUnavailable), "exp ErrHashUnavailable, saw %v", err)
+
+	var he *HashUnavailableError
+	assert(errors.As(err, &he), "exp *HashUnavailableError, saw %T", err)
+	assert(he.Hash == h && he.Import != "", "wrong error %+v", he)
+	assert(strings.Contains(err.Error(), he.Import), "import path missing in %q", err)
+
+	_, err = NewWithGroupID(h, "rfc5054-2048")
+	assert(errors.Is(err, ErrHashUnavailable), "NewWithGroupID: %v", err)
+
+	_, err = NewWithHash(crypto.Hash(CustomHashMin+4242), 2048)
+	assert(errors.As(err, &he) && he.Import == "", "custom id: %v", err)
+
+	// so do verifiers made with it
+	s, err := New(2048)
+	assert(err == nil, "New: %s", err)
+	v, err := s.Verifier([]byte("user"), []byte("pass"), nil)
+	assert(err == nil, "Verifier: %s", err)
+	_, vs := v.Encode()
+	f := strings.Split(vs, ":")
+	f[3] = strconv.Itoa(int(h))
+	_, _, err = MakeSRPVerifier(strings.Join(f, ":"))
+	assert(errors.As(err, &he) && he.Hash == h, "MakeSRPVerifier: %v", err)
+}
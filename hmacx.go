@@ -0,0 +1,47 @@
+// hmacx.go - deriving the private key x with HMAC
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"crypto/hmac"
+	"math/big"
+)
+
+// By default x = H(H(I), H(p), s), a plain hash of the salt and the hashed
+// credentials. Some crypto policies require secrets to be derived with a
+// keyed function, and HMAC is easier to reason about in a formal review
+// than an ad-hoc concatenation. WithHMACPrivateKey() selects
+//
+//	x = HMAC(s, H(I) || H(p))
+//
+// with the hash function of the environment and the salt as the key. With
+// a KDF, H(p) is hardened first as in the default derivation. Verifiers
+// record the derivation (the "x=hmac" field of Encode()), servers send it
+// to clients along with the salt, and clients of this package derive x the
+// same way; other SRP implementations need the same change to interoperate.
+
+// DeriveHMAC is the derivation of x of WithHMACPrivateKey() as recorded in
+// verifiers and sent to clients
+const DeriveHMAC = "hmac"
+
+// WithHMACPrivateKey makes new verifiers in this environment derive x with
+// HMAC (see above). A client in such an environment refuses servers whose
+// verifier derives x otherwise.
+func WithHMACPrivateKey() Option {
+	return func(s *SRP) error {
+		s.xd = DeriveHMAC
+		return nil
+	}
+}
+
+// return x = HMAC(salt, ih || ph)
+func (s *SRP) hmacX(ih, ph, salt []byte) *big.Int {
+	m := hmac.New(hashFunc(s.h), salt)
+	m.Write(ih)
+	m.Write(ph)
+	return big.NewInt(0).SetBytes(m.Sum(nil))
+}
@@ -0,0 +1,98 @@
+// self test for WithHMACPrivateKey()
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/sha256"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestHMACPrivateKey(t *testing.T) {
+	assert := newAsserter(t)
+
+	kdf := KDF{Alg: KDFArgon2id, Time: 1, Memory: 64, Threads: 1}
+	envs := [][]Option{{WithHMACPrivateKey()}}
+	if !FIPSBuild {
+		envs = append(envs, []Option{WithHMACPrivateKey(), WithKDF(kdf)})
+	}
+	for _, opts := range envs {
+		s, err := NewWithHash(crypto.SHA256, 2048, opts...)
+		assert(err == nil, "New: %s", err)
+
+		salt := []byte("0123456789abcdef")
+		v, err := s.VerifierWithSalt([]byte("user"), []byte("pass"), salt)
+		assert(err == nil, "Verifier: %s", err)
+
+		// x = HMAC(s, H(I) || H(p))
+		if s.kdf == nil {
+			ih := sha256.Sum256([]byte("user"))
+			ph := sha256.Sum256([]byte("pass"))
+			m := hmac.New(sha256.New, salt)
+			m.Write(ih[:])
+			m.Write(ph[:])
+			x := big.NewInt(0).SetBytes(m.Sum(nil))
+			assert(s.ComputeX([]byte("user"), []byte("pass"), salt).Cmp(x) == 0, "x differs")
+			assert(big.NewInt(0).Exp(s.pf.g, x, s.pf.N).Cmp(big.NewInt(0).SetBytes(v.v)) == 0, "v differs")
+		}
+
+		// the derivation is recorded
+		_, vh := v.Encode()
+		assert(strings.Contains(vh, ":x=hmac"), "encoding lacks the derivation: %s", vh)
+		ss, v2, err := MakeSRPVerifier(vh)
+		assert(err == nil, "MakeSRPVerifier: %s", err)
+		assert(v2.Equal(v), "text encoding lost the derivation")
+		_, v3, err := DecodeVerifierCompact(v.EncodeCompact(false))
+		assert(err == nil, "DecodeVerifierCompact: %s", err)
+		assert(v3.Equal(v), "compact encoding lost the derivation")
+
+		// verifiers of the decoded environment keep it
+		w, err := ss.Verifier([]byte("user"), []byte("pass"), salt)
+		assert(err == nil, "Verifier: %s", err)
+		assert(w.Equal(v), "decoded environment changed the derivation")
+
+		// a client learns the derivation from the server
+		cs, err := NewWithHash(crypto.SHA256, 2048)
+		assert(err == nil, "New: %s", err)
+		for _, pass := range []string{"pass", "Pass"} {
+			c, err := cs.NewClient([]byte("user"), []byte(pass))
+			assert(err == nil, "NewClient: %s", err)
+			srv, err := ss.NewServer(v2, c.xA)
+			assert(err == nil, "NewServer: %s", err)
+
+			sc, err := ParseServerCredentials(srv.Credentials())
+			assert(err == nil, "ParseServerCredentials: %s", err)
+			assert(sc.X == DeriveHMAC, "server sent derivation %q", sc.X)
+			assert(authenticate(c, srv) == (pass == "pass"), "%s: wrong outcome", pass)
+		}
+	}
+
+	// a client that requires the derivation refuses servers without it
+	s, err := NewWithHash(crypto.SHA256, 2048)
+	assert(err == nil, "New: %s", err)
+	v, err := s.Verifier([]byte("user"), []byte("pass"), nil)
+	assert(err == nil, "Verifier: %s", err)
+
+	cs, err := NewWithHash(crypto.SHA256, 2048, WithHMACPrivateKey())
+	assert(err == nil, "New: %s", err)
+	c, err := cs.NewClient([]byte("user"), []byte("pass"))
+	assert(err == nil, "NewClient: %s", err)
+	srv, err := s.NewServer(v, c.xA)
+	assert(err == nil, "NewServer: %s", err)
+	_, err = c.Respond(srv.Challenge())
+	assert(err != nil, "client accepted the default derivation")
+
+	// the default derivation still works alongside
+	c, err = s.NewClient([]byte("user"), []byte("pass"))
+	assert(err == nil, "NewClient: %s", err)
+	srv, err = s.NewServer(v, c.xA)
+	assert(err == nil, "NewServer: %s", err)
+	assert(authenticate(c, srv), "default derivation failed")
+}
@@ -0,0 +1,54 @@
+// identity.go - normalization of identities before they are hashed
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"bytes"
+)
+
+// Identities are hashed exactly as given, so "Alice@example.com" and
+// "alice@example.com " are different users. The options below normalize
+// identities before they are hashed when verifiers are created and by
+// clients (and in ComputeX()). They aren't recorded in verifiers: clients
+// and the environment that creates verifiers must use the same ones, and
+// existing verifiers of identities that change under them must be created
+// again.
+
+// WithIdentityCaseFolding lowercases identities (per Unicode) before they
+// are hashed; it suits email-style user names.
+func WithIdentityCaseFolding() Option {
+	return func(s *SRP) error {
+		s.foldID = true
+		return nil
+	}
+}
+
+// WithIdentityTrimming removes leading and trailing white space (per
+// Unicode) from identities before they are hashed.
+func WithIdentityTrimming() Option {
+	return func(s *SRP) error {
+		s.trimID = true
+		return nil
+	}
+}
+
+// NormalizeIdentity returns the identity 'I' as it is hashed in this
+// environment.
+func (s *SRP) NormalizeIdentity(I []byte) []byte {
+	return s.identity(I)
+}
+
+// return the identity 'I' normalized per the options of this environment
+func (s *SRP) identity(I []byte) []byte {
+	if s.trimID {
+		I = bytes.TrimSpace(I)
+	}
+	if s.foldID {
+		I = bytes.ToLower(I)
+	}
+	return I
+}
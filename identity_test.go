@@ -0,0 +1,71 @@
+// self test for identity normalization
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"encoding/hex"
+	"math/big"
+	"testing"
+)
+
+func TestIdentityNormalization(t *testing.T) {
+	assert := newAsserter(t)
+
+	pass := []byte("pass")
+
+	tests := []struct {
+		opts   []Option
+		stored string
+		typed  string
+		ok     bool
+	}{
+		{nil, "alice@example.com", "alice@example.com", true},
+		{nil, "alice@example.com", "Alice@Example.com", false},
+		{[]Option{WithIdentityCaseFolding()}, "alice@example.com", "Alice@Example.COM", true},
+		{[]Option{WithIdentityCaseFolding()}, "alice@example.com", " alice@example.com", false},
+		{[]Option{WithIdentityTrimming()}, "alice@example.com", " alice@example.com\n", true},
+		{[]Option{WithIdentityTrimming()}, "alice@example.com", "ALICE@example.com", false},
+		{[]Option{WithIdentityCaseFolding(), WithIdentityTrimming()}, " Alice@Example.com", "alice@example.com\t", true},
+	}
+
+	for i, x := range tests {
+		s, err := New(2048, x.opts...)
+		assert(err == nil, "New: %s", err)
+
+		v, err := s.Verifier([]byte(x.stored), pass, nil)
+		assert(err == nil, "Verifier: %s", err)
+
+		c, err := s.NewClient([]byte(x.typed), pass)
+		assert(err == nil, "NewClient: %s", err)
+
+		ih, A, err := ServerBegin(c.Credentials())
+		assert(err == nil, "ServerBegin: %s", err)
+
+		i2, err := hex.DecodeString(ih)
+		assert(err == nil, "hex: %s", err)
+
+		_, err = s.NewServerFor(i2, v, A)
+		if !x.ok {
+			assert(err != nil, "%d: identities %q and %q matched", i, x.stored, x.typed)
+			continue
+		}
+		assert(err == nil, "%d: NewServerFor: %s", i, err)
+
+		srv, err := s.NewServer(v, A)
+		assert(err == nil, "NewServer: %s", err)
+
+		m, err := c.Generate(srv.Credentials())
+		assert(err == nil, "Generate: %s", err)
+
+		_, ok := srv.ClientOk(m)
+		assert(ok, "%d: ClientOk failed", i)
+
+		// ComputeX agrees with the verifier
+		vx := s.ComputeVerifier(s.ComputeX([]byte(x.typed), pass, v.s))
+		assert(vx.Cmp(big.NewInt(0).SetBytes(v.v)) == 0, "%d: ComputeX mismatch", i)
+	}
+}
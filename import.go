@@ -0,0 +1,153 @@
+// import.go - verifiers exported by other SRP implementations
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Other SRP implementations derive the private key x differently from
+// this package (x = H(H(I), H(p), s)), so their verifiers can't simply be
+// re-encoded. An imported verifier records how x is derived; servers send
+// it to clients along with the salt and clients of this package derive x
+// the same way. Both are computed with SHA-256:
+//
+//	thinbus:  x = H(upper(s || trim(hex(H(I | ":" | p)))))
+//	srptools: x = H(s | H(I | ":" | p))
+//
+// For thinbus-srp, the salt is the hex string as stored by the library and
+// is hashed as text, trim() removes leading zeros and the hash of the
+// upper-cased concatenation is read as a hex number. For srptools, the
+// salt is hashed as a number, i.e., without leading zero bytes.
+//
+// Only the verifier is imported: the identity is hashed as usual and
+// proofs are those of this package, so users move to clients of this
+// package (or of its wire protocol) while keeping their passwords.
+
+// Verifier export formats understood by ImportVerifier()
+const (
+	ImportThinbus  = "thinbus"
+	ImportSRPTools = "srptools"
+)
+
+// ImportVerifier converts a verifier exported by another SRP library into
+// an SRP environment and Verifier. 'format' is ImportThinbus or
+// ImportSRPTools and 'data' is a record
+//
+//	identity,salt,verifier[,bits]
+//
+// where salt and verifier are hex strings as stored by the library and
+// bits is the size of the RFC 5054 group it used (2048 if omitted). Groups
+// smaller than MinimumBits need WithInsecureGroups() in 'opts'; the other
+// options are applied to the returned SRP instance as by MakeSRPVerifier().
+func ImportVerifier(format string, data []byte, opts ...Option) (*SRP, *Verifier, error) {
+	switch format {
+	case ImportThinbus, ImportSRPTools:
+	default:
+		return nil, nil, fmt.Errorf("srp: unknown verifier format %q", format)
+	}
+
+	f := strings.Split(strings.TrimSpace(string(data)), ",")
+	if len(f) < 3 || len(f) > 4 {
+		return nil, nil, fmt.Errorf("srp: malformed %s verifier", format)
+	}
+
+	bits := 2048
+	if len(f) == 4 {
+		var err error
+		if bits, err = strconv.Atoi(f[3]); err != nil {
+			return nil, nil, fmt.Errorf("srp: malformed %s group size %q", format, f[3])
+		}
+	}
+
+	s, err := NewWithHash(crypto.SHA256, bits, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	I := []byte(f[0])
+	if len(I) == 0 {
+		return nil, nil, fmt.Errorf("srp: empty %s identity", format)
+	}
+
+	salt, err := importSalt(format, f[1])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	v, ok := big.NewInt(0).SetString(f[2], 16)
+	if !ok || v.Sign() <= 0 || v.Cmp(s.pf.N) >= 0 {
+		return nil, nil, fmt.Errorf("srp: invalid %s verifier", format)
+	}
+
+	vf := &Verifier{
+		i:   s.hashbyte(s.identity(I)),
+		s:   salt,
+		v:   v.Bytes(),
+		h:   s.h,
+		pf:  s.pf,
+		xd:  format,
+		env: s,
+	}
+	if s.idk != nil {
+		vf.i = blindIdentity(s.idk, vf.i)
+		vf.idk = s.idk
+	}
+	return s, vf, nil
+}
+
+// return the salt of a verifier in 'format' as it is stored in the
+// Verifier
+func importSalt(format, s string) ([]byte, error) {
+	n, ok := big.NewInt(0).SetString(s, 16)
+	if !ok || s == "" {
+		return nil, fmt.Errorf("srp: invalid %s salt", format)
+	}
+
+	if format == ImportThinbus {
+		// hashed as text
+		return []byte(s), nil
+	}
+	return n.Bytes(), nil
+}
+
+// return an error if 'xd' isn't a known derivation of x ("" is the one of
+// this package)
+func checkXDerivation(xd string) error {
+	switch xd {
+	case "", DeriveHMAC, ImportThinbus, ImportSRPTools:
+		return nil
+	}
+	return fmt.Errorf("unknown derivation of x %q", xd)
+}
+
+// derive x for the salt 's' of a verifier imported from 'xd'
+func (c *Client) importedX(s []byte, xd string) (*big.Int, error) {
+	if c.s.h != crypto.SHA256 {
+		return nil, fmt.Errorf("srp: %s verifiers need SHA-256", xd)
+	}
+	if c.ip == nil {
+		return nil, fmt.Errorf("srp: %s verifiers need the password, not its hash", xd)
+	}
+
+	switch xd {
+	case ImportThinbus:
+		h := strings.TrimLeft(hex.EncodeToString(c.ip), "0")
+		t := bytes.ToUpper(append(append([]byte{}, s...), h...))
+		return c.s.hashint(t), nil
+
+	case ImportSRPTools:
+		return c.s.hashint(s, c.ip), nil
+	}
+	return nil, fmt.Errorf("srp: %s", checkXDerivation(xd))
+}
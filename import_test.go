@@ -0,0 +1,115 @@
+// self test for imported verifiers
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+// return the record of a verifier exported by 'format' for 'I', 'p'
+// computed as the other library does
+func exportVerifier(format, I, p, salt string, bits int) string {
+	s, err := NewWithHash(crypto.SHA256, bits)
+	if err != nil {
+		panic(err)
+	}
+
+	ip := sha256.Sum256([]byte(I + ":" + p))
+
+	var x *big.Int
+	switch format {
+	case ImportThinbus:
+		h := strings.TrimLeft(hex.EncodeToString(ip[:]), "0")
+		d := sha256.Sum256([]byte(strings.ToUpper(salt + h)))
+		x = big.NewInt(0).SetBytes(d[:])
+	case ImportSRPTools:
+		sb, _ := big.NewInt(0).SetString(salt, 16)
+		d := sha256.Sum256(append(sb.Bytes(), ip[:]...))
+		x = big.NewInt(0).SetBytes(d[:])
+	}
+
+	v := big.NewInt(0).Exp(s.pf.g, x, s.pf.N)
+	return fmt.Sprintf("%s,%s,%x,%d", I, salt, v, bits)
+}
+
+func TestImportVerifier(t *testing.T) {
+	assert := newAsserter(t)
+
+	salt := "0aa9c1f0beefcafe0123456789abcdef0aa9c1f0beefcafe0123456789abcdef"
+
+	for _, format := range []string{ImportThinbus, ImportSRPTools} {
+		rec := exportVerifier(format, "alice", "password123", salt, 2048)
+
+		_, v, err := ImportVerifier(format, []byte(rec))
+		assert(err == nil, "%s: ImportVerifier: %s", format, err)
+
+		// the derivation survives every encoding and marshaled servers
+		_, vh := v.Encode()
+		_, v2, err := MakeSRPVerifier(vh)
+		assert(err == nil, "%s: MakeSRPVerifier: %s", format, err)
+		assert(v2.Equal(v), "%s: text encoding lost the derivation", format)
+
+		_, v3, err := DecodeVerifierCompact(v.EncodeCompact(true))
+		assert(err == nil, "%s: DecodeVerifierCompact: %s", format, err)
+		assert(v3.Equal(v), "%s: compact encoding lost the derivation", format)
+
+		for _, pass := range []string{"password123", "password124"} {
+			cs, err := NewWithHash(crypto.SHA256, 2048)
+			assert(err == nil, "New: %s", err)
+
+			c, err := cs.NewClient([]byte("alice"), []byte(pass))
+			assert(err == nil, "NewClient: %s", err)
+
+			srv, err := v2.NewSession(big.NewInt(0).SetBytes(c.Hello().A))
+			assert(err == nil, "%s: NewSession: %s", format, err)
+
+			srv, err = UnmarshalServer(srv.Marshal())
+			assert(err == nil, "%s: UnmarshalServer: %s", format, err)
+
+			sc, err := ParseServerCredentials(srv.Credentials())
+			assert(err == nil, "ParseServerCredentials: %s", err)
+			assert(sc.X == format, "%s: wrong derivation %q", format, sc.X)
+
+			m, err := c.Generate(srv.Credentials())
+			assert(err == nil, "%s: Generate: %s", format, err)
+
+			proof, ok := srv.ClientOk(m)
+			assert(ok == (pass == "password123"), "%s: %s: exp %v", format, pass, !ok)
+			if ok {
+				assert(c.ServerOk(proof), "%s: ServerOk failed", format)
+			}
+		}
+	}
+
+	bad := []string{
+		"alice,00",
+		"alice,zz,1234",
+		",00,1234",
+		"alice,00,0",
+		"alice,00,1234,99",
+		"alice,00,1234,1,2",
+	}
+	for _, b := range bad {
+		_, _, err := ImportVerifier(ImportThinbus, []byte(b))
+		assert(err != nil, "imported %q", b)
+	}
+
+	for _, format := range []string{"sha1-crypt", "", DeriveHMAC} {
+		_, _, err := ImportVerifier(format, []byte("alice,00,1234"))
+		assert(err != nil, "format %q accepted", format)
+	}
+
+	// clients refuse unknown derivations
+	_, err := ParseServerCredentials("00:1234:x=bcrypt")
+	assert(err != nil, "unknown derivation accepted")
+}
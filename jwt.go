@@ -0,0 +1,183 @@
+// jwt.go - JSON Web Tokens issued after a successful handshake
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// After a successful handshake, a REST backend can hand the client a JSON
+// Web Token (RFC 7519) signed with HMAC-SHA256 ("HS256"). The signing key
+// is either a long-term key of the server (so that any of its backends can
+// verify the token) or derived from the session key K:
+//
+//	Kt = HKDF(K, "srp jwt")
+//
+// in which case only the two parties of the handshake can verify it.
+
+// size of the signing key derived from K in bytes
+const tokenKeyLen = 32
+
+var tokenLabel = []byte("srp jwt")
+
+// encoded header of every token issued by this package
+var tokenHeader = b64(mustJSON(map[string]string{"alg": "HS256", "typ": "JWT"}))
+
+// TokenClaims are the claims of a token issued by Server.IssueToken()
+type TokenClaims struct {
+	Subject  string `json:"sub"` // hex encoded hashed identity
+	Issuer   string `json:"iss,omitempty"`
+	IssuedAt int64  `json:"iat"`
+	Expires  int64  `json:"exp,omitempty"`
+
+	// parameters of the SRP environment
+	Hash  uint   `json:"srp_hash"`          // crypto.Hash or registered id
+	Bits  int    `json:"srp_bits"`          // size of the prime field
+	Group string `json:"srp_grp,omitempty"` // see SRP.GroupID()
+}
+
+// TokenConfig describes a token issued by Server.IssueToken()
+type TokenConfig struct {
+	// Key signs the token; if nil, it is derived from the session key
+	// (see Server.TokenKey()).
+	Key []byte
+
+	// Issuer is recorded in the token if it is set
+	Issuer string
+
+	// TTL is the lifetime of the token; zero issues a token that doesn't
+	// expire.
+	TTL time.Duration
+
+	// Now returns the current time; if nil, time.Now is used
+	Now func() time.Time
+}
+
+// IssueToken returns a JWT for the authenticated client, signed as
+// described by 'cfg'. It must be called after the client's proof was
+// verified.
+func (s *Server) IssueToken(cfg TokenConfig) (string, error) {
+	if !s.authed {
+		return "", fmt.Errorf("srp: client isn't authenticated")
+	}
+
+	key := cfg.Key
+	if key == nil {
+		key = s.s.expandKey(s.xK, tokenLabel, tokenKeyLen)
+	}
+	if len(key) == 0 {
+		return "", fmt.Errorf("srp: empty token signing key")
+	}
+
+	now := time.Now
+	if cfg.Now != nil {
+		now = cfg.Now
+	}
+
+	t := now()
+	c := TokenClaims{
+		Subject:  hex.EncodeToString(s.i),
+		Issuer:   cfg.Issuer,
+		IssuedAt: t.Unix(),
+		Hash:     uint(s.s.h),
+		Bits:     s.s.FieldSize(),
+		Group:    s.s.GroupID(),
+	}
+	if cfg.TTL > 0 {
+		c.Expires = t.Add(cfg.TTL).Unix()
+	}
+
+	b, err := json.Marshal(&c)
+	if err != nil {
+		return "", err
+	}
+
+	m := tokenHeader + "." + b64(b)
+	return m + "." + b64(tokenMAC(key, m)), nil
+}
+
+// TokenKey returns the token signing key derived from the session key. The
+// client must have been authenticated.
+func (s *Server) TokenKey() ([]byte, error) {
+	if !s.authed {
+		return nil, fmt.Errorf("srp: client isn't authenticated")
+	}
+	return s.s.expandKey(s.xK, tokenLabel, tokenKeyLen), nil
+}
+
+// TokenKey returns the signing key of tokens issued by the server without
+// a key of its own (see TokenConfig.Key); the client can verify such tokens
+// with VerifyToken(). The server must have been authenticated.
+func (c *Client) TokenKey() ([]byte, error) {
+	if !c.authed {
+		return nil, fmt.Errorf("srp: server isn't authenticated")
+	}
+	return c.s.expandKey(c.xK, tokenLabel, tokenKeyLen), nil
+}
+
+// VerifyToken checks the signature of 'token' with 'key' and returns its
+// claims. Tokens that expired before 'now' are rejected.
+func VerifyToken(token string, key []byte, now time.Time) (*TokenClaims, error) {
+	v := strings.Split(token, ".")
+	if len(v) != 3 {
+		return nil, fmt.Errorf("srp: malformed token")
+	}
+
+	if v[0] != tokenHeader {
+		return nil, fmt.Errorf("srp: unsupported token header")
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(v[2])
+	if err != nil {
+		return nil, fmt.Errorf("srp: malformed token signature")
+	}
+	if !hmac.Equal(sig, tokenMAC(key, v[0]+"."+v[1])) {
+		return nil, fmt.Errorf("srp: invalid token signature")
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(v[1])
+	if err != nil {
+		return nil, fmt.Errorf("srp: malformed token claims")
+	}
+
+	var c TokenClaims
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("srp: malformed token claims: %w", err)
+	}
+
+	if c.Expires != 0 && now.Unix() >= c.Expires {
+		return nil, fmt.Errorf("srp: token expired")
+	}
+	return &c, nil
+}
+
+// return HMAC-SHA256(key, m)
+func tokenMAC(key []byte, m string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(m))
+	return h.Sum(nil)
+}
+
+// return 'b' in unpadded base64url
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func mustJSON(v interface{}) []byte {
+	b, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
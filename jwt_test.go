@@ -0,0 +1,85 @@
+// self test for JSON Web Tokens
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIssueToken(t *testing.T) {
+	assert := newAsserter(t)
+
+	user := []byte("user")
+	pass := []byte("pass")
+
+	s, err := New(2048)
+	assert(err == nil, "New: %s", err)
+
+	v, err := s.Verifier(user, pass, nil)
+	assert(err == nil, "Verifier: %s", err)
+
+	c, err := s.NewClient(user, pass)
+	assert(err == nil, "NewClient: %s", err)
+
+	srv, err := s.NewServer(v, big.NewInt(0).SetBytes(c.Hello().A))
+	assert(err == nil, "NewServer: %s", err)
+
+	m, err := c.Respond(srv.Challenge())
+	assert(err == nil, "Respond: %s", err)
+
+	_, err = srv.IssueToken(TokenConfig{})
+	assert(err != nil, "issued a token before the client was authenticated")
+
+	proof, ok := srv.CheckProof(m)
+	assert(ok, "CheckProof failed")
+	assert(c.CheckProof(proof), "CheckProof failed")
+
+	now := time.Unix(1500000000, 0)
+	cfg := TokenConfig{
+		Issuer: "test",
+		TTL:    time.Hour,
+		Now:    func() time.Time { return now },
+	}
+
+	// signed with the session key; the client can verify it
+	tok, err := srv.IssueToken(cfg)
+	assert(err == nil, "IssueToken: %s", err)
+
+	key, err := c.TokenKey()
+	assert(err == nil, "TokenKey: %s", err)
+
+	cl, err := VerifyToken(tok, key, now)
+	assert(err == nil, "VerifyToken: %s", err)
+	assert(cl.Subject == hex.EncodeToString(c.i), "wrong subject %s", cl.Subject)
+	assert(cl.Issuer == "test", "wrong issuer %s", cl.Issuer)
+	assert(cl.Expires == now.Add(time.Hour).Unix(), "wrong expiry %d", cl.Expires)
+	assert(cl.Bits == 2048 && cl.Hash == uint(s.h), "wrong parameters %d %d", cl.Bits, cl.Hash)
+
+	_, err = VerifyToken(tok, key, now.Add(time.Hour))
+	assert(err != nil, "expired token accepted")
+
+	// signed with a server key
+	cfg.Key = []byte("server signing key")
+	tok, err = srv.IssueToken(cfg)
+	assert(err == nil, "IssueToken: %s", err)
+
+	_, err = VerifyToken(tok, key, now)
+	assert(err != nil, "token verified with the wrong key")
+
+	_, err = VerifyToken(tok, cfg.Key, now)
+	assert(err == nil, "VerifyToken: %s", err)
+
+	// tampered claims
+	v2 := strings.Split(tok, ".")
+	v2[1] = b64([]byte(`{"sub":"admin","iat":0}`))
+	_, err = VerifyToken(strings.Join(v2, "."), cfg.Key, now)
+	assert(err != nil, "tampered token accepted")
+}
@@ -0,0 +1,213 @@
+// kdf.go - password hardening for SRP verifiers
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Names of the supported password hardening functions
+const (
+	KDFArgon2id = "argon2id"
+	KDFScrypt   = "scrypt"
+)
+
+// Upper bounds on the KDF cost a client accepts from a server; they bound
+// the work a malicious server can make a client do.
+const (
+	maxKDFMemory      = 4 * 1024 * 1024 // KiB
+	maxKDFParallelism = 64
+	maxArgonTime      = 64
+	maxScryptLogN     = 24
+	maxScryptR        = 64
+)
+
+// KDF describes a memory-hard function and its cost parameters. When an
+// SRP environment has a KDF (see WithKDF()), the hashed password is
+// hardened with it before deriving the private key x:
+//
+//	x = H(I, KDF(H(p), s), s)
+//
+// The KDF and its parameters are recorded per-user in the encoded verifier
+// and sent to the client along with the salt; a server can raise the cost
+// over time by re-creating verifiers.
+type KDF struct {
+	Alg string // KDFArgon2id or KDFScrypt
+
+	Time   uint32 // argon2id: number of passes
+	Memory uint32 // argon2id: memory in KiB
+
+	LogN uint8  // scrypt: log2 of the CPU/memory cost N
+	R    uint32 // scrypt: block size
+
+	Threads uint8 // parallelism for both argon2id and scrypt
+}
+
+// WithKDF hardens passwords with the memory-hard function 'k' when creating
+// verifiers. A client in such an environment refuses servers that offer a
+// different KDF or a lower cost.
+func WithKDF(k KDF) Option {
+	return func(s *SRP) error {
+		if err := k.validate(); err != nil {
+			return err
+		}
+		s.kdf = &k
+		return nil
+	}
+}
+
+// String returns the portable encoding of the KDF parameters
+func (k *KDF) String() string {
+	switch k.Alg {
+	case KDFArgon2id:
+		return fmt.Sprintf("%s,t=%d,m=%d,p=%d", k.Alg, k.Time, k.Memory, k.Threads)
+	case KDFScrypt:
+		return fmt.Sprintf("%s,ln=%d,r=%d,p=%d", k.Alg, k.LogN, k.R, k.Threads)
+	}
+	return k.Alg
+}
+
+// MarshalText implements encoding.TextMarshaler with the encoding of
+// String(); it lets KDF parameters be stored or sent as JSON.
+func (k *KDF) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler; the parameters are
+// validated as in WithKDF().
+func (k *KDF) UnmarshalText(b []byte) error {
+	v, err := parseKDF(string(b))
+	if err != nil {
+		return err
+	}
+	*k = *v
+	return nil
+}
+
+// Derive returns 'n' bytes derived from the secret 'p' and 'salt' with the
+// KDF; it lets applications harden other password-based keys with the
+// same parameters. The parameters are validated as in WithKDF().
+func (k *KDF) Derive(p, salt []byte, n int) ([]byte, error) {
+	if err := k.validate(); err != nil {
+		return nil, err
+	}
+	if n <= 0 {
+		return nil, fmt.Errorf("srp: kdf %s: invalid key size %d", k.Alg, n)
+	}
+	return k.key(p, salt, n), nil
+}
+
+// derive the hardened form of the hashed password 'ph'; the output is
+// 'n' bytes long.
+func (k *KDF) key(ph, salt []byte, n int) []byte {
+	switch k.Alg {
+	case KDFArgon2id:
+		return argon2.IDKey(ph, salt, k.Time, k.Memory, k.Threads, uint32(n))
+
+	case KDFScrypt:
+		b, err := scrypt.Key(ph, salt, 1<<k.LogN, int(k.R), int(k.Threads), n)
+		if err != nil {
+			panic(fmt.Sprintf("srp: scrypt: %s", err))
+		}
+		return b
+	}
+	panic(fmt.Sprintf("srp: unknown kdf %s", k.Alg))
+}
+
+// return an error if the parameters are unusable or exceed our bounds
+func (k *KDF) validate() error {
+	if k.Threads == 0 || k.Threads > maxKDFParallelism {
+		return fmt.Errorf("srp: kdf %s: invalid parallelism %d", k.Alg, k.Threads)
+	}
+
+	switch k.Alg {
+	case KDFArgon2id:
+		if k.Time == 0 || k.Time > maxArgonTime {
+			return fmt.Errorf("srp: kdf %s: invalid time %d", k.Alg, k.Time)
+		}
+		if k.Memory < 8*uint32(k.Threads) || k.Memory > maxKDFMemory {
+			return fmt.Errorf("srp: kdf %s: invalid memory %d", k.Alg, k.Memory)
+		}
+
+	case KDFScrypt:
+		if k.LogN == 0 || k.LogN > maxScryptLogN {
+			return fmt.Errorf("srp: kdf %s: invalid cost %d", k.Alg, k.LogN)
+		}
+		if k.R == 0 || k.R > maxScryptR {
+			return fmt.Errorf("srp: kdf %s: invalid block size %d", k.Alg, k.R)
+		}
+
+		// scrypt needs 128 * r * N bytes
+		if mem := (uint64(k.R) << k.LogN) / 8; mem > maxKDFMemory {
+			return fmt.Errorf("srp: kdf %s: needs too much memory (%d KiB)", k.Alg, mem)
+		}
+
+	default:
+		return fmt.Errorf("srp: unknown kdf %q", k.Alg)
+	}
+	return nil
+}
+
+// return true if 'k' is the same function as 'min' and costs at least as much
+func (k *KDF) atLeast(min *KDF) bool {
+	if k.Alg != min.Alg || k.Threads < min.Threads {
+		return false
+	}
+
+	switch k.Alg {
+	case KDFArgon2id:
+		return k.Time >= min.Time && k.Memory >= min.Memory
+	case KDFScrypt:
+		return k.LogN >= min.LogN && k.R >= min.R
+	}
+	return false
+}
+
+// parse the portable encoding of KDF parameters made by String()
+func parseKDF(s string) (*KDF, error) {
+	v := strings.Split(s, ",")
+	k := &KDF{
+		Alg: v[0],
+	}
+
+	for _, kv := range v[1:] {
+		i := strings.IndexByte(kv, '=')
+		if i <= 0 {
+			return nil, fmt.Errorf("srp: malformed kdf parameter %q", kv)
+		}
+
+		n, err := strconv.ParseUint(kv[i+1:], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("srp: malformed kdf parameter %q", kv)
+		}
+
+		switch key := kv[:i]; {
+		case key == "p" && n <= 0xff:
+			k.Threads = uint8(n)
+		case key == "t" && k.Alg == KDFArgon2id:
+			k.Time = uint32(n)
+		case key == "m" && k.Alg == KDFArgon2id:
+			k.Memory = uint32(n)
+		case key == "ln" && k.Alg == KDFScrypt && n <= 0xff:
+			k.LogN = uint8(n)
+		case key == "r" && k.Alg == KDFScrypt:
+			k.R = uint32(n)
+		default:
+			return nil, fmt.Errorf("srp: invalid kdf parameter %q", kv)
+		}
+	}
+
+	if err := k.validate(); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
@@ -0,0 +1,183 @@
+// self test for password hardening
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+)
+
+// cheap parameters to keep the tests fast
+var testKDFs = []KDF{
+	{Alg: KDFArgon2id, Time: 1, Memory: 64, Threads: 1},
+	{Alg: KDFScrypt, LogN: 4, R: 8, Threads: 1},
+}
+
+func TestKDF(t *testing.T) {
+	skipFIPS(t)
+
+	assert := newAsserter(t)
+
+	user := []byte("user")
+	pass := []byte("pass")
+
+	for _, k := range testKDFs {
+		s, err := New(2048, WithKDF(k))
+		assert(err == nil, "%s: New: %s", k.Alg, err)
+
+		v, err := s.Verifier(user, pass, nil)
+		assert(err == nil, "%s: Verifier: %s", k.Alg, err)
+
+		_, vh := v.Encode()
+		assert(strings.Contains(vh, ":kdf="+k.String()), "%s: kdf missing in %s", k.Alg, vh)
+
+		_, v2, err := MakeSRPVerifier(vh)
+		assert(err == nil, "%s: MakeSRPVerifier: %s", k.Alg, err)
+		assert(*v2.kdf == k, "%s: kdf mismatch: %s", k.Alg, v2.kdf)
+
+		db := newUserDBFrom(t, s, user, pass)
+		db.verify(t, user, pass, true)
+		db.verify(t, user, []byte("bad"), false)
+
+		// a client without a KDF learns the parameters from the server
+		s0, err := New(2048)
+		assert(err == nil, "New: %s", err)
+		db.s = s0
+		db.verify(t, user, pass, true)
+	}
+}
+
+func TestKDFDowngrade(t *testing.T) {
+	skipFIPS(t)
+
+	assert := newAsserter(t)
+
+	user := []byte("user")
+	pass := []byte("pass")
+
+	// the server has no KDF
+	s, err := New(2048)
+	assert(err == nil, "New: %s", err)
+
+	v, err := s.Verifier(user, pass, nil)
+	assert(err == nil, "Verifier: %s", err)
+
+	// .. but the client expects one
+	strong := testKDFs[0]
+	strong.Time++
+	cs, err := New(2048, WithKDF(strong))
+	assert(err == nil, "New: %s", err)
+
+	for _, k := range []*KDF{nil, &testKDFs[0], &testKDFs[1]} {
+		v.kdf = k
+
+		c, err := cs.NewClient(user, pass)
+		assert(err == nil, "NewClient: %s", err)
+
+		_, A, err := ServerBegin(c.Credentials())
+		assert(err == nil, "ServerBegin: %s", err)
+
+		srv, err := s.NewServer(v, A)
+		assert(err == nil, "NewServer: %s", err)
+
+		_, err = c.Generate(srv.Credentials())
+		assert(err != nil, "client accepted weaker kdf %s", k)
+	}
+}
+
+func TestKDFParse(t *testing.T) {
+	assert := newAsserter(t)
+
+	bad := []string{
+		"",
+		"bcrypt,p=1",
+		"argon2id,t=1,m=64",
+		"argon2id,t=0,m=64,p=1",
+		"argon2id,t=1,m=64,p=1,ln=3",
+		"argon2id,t=1,m=99999999,p=1",
+		"scrypt,ln=30,r=8,p=1",
+		"scrypt,ln=24,r=64,p=1",
+		"scrypt,ln=x,r=8,p=1",
+	}
+
+	for _, s := range bad {
+		_, err := parseKDF(s)
+		assert(err != nil, "parsed bad kdf %q", s)
+	}
+
+	for _, k := range testKDFs {
+		k2, err := parseKDF(k.String())
+		assert(err == nil, "parse %s: %s", k.String(), err)
+		assert(*k2 == k, "parse mismatch: %s vs %s", k2, k.String())
+	}
+}
+
+func TestKDFDerive(t *testing.T) {
+	assert := newAsserter(t)
+
+	for _, k := range testKDFs {
+		a, err := k.Derive([]byte("pass"), []byte("salt"), 24)
+		assert(err == nil, "%s: Derive: %s", k.String(), err)
+		assert(len(a) == 24, "%s: exp 24 bytes, saw %d", k.String(), len(a))
+
+		b, _ := k.Derive([]byte("pass"), []byte("tlas"), 24)
+		assert(!ctEqual(a, b), "%s: salt ignored", k.String())
+	}
+
+	k := KDF{Alg: KDFArgon2id, Time: 0, Memory: 64, Threads: 1}
+	_, err := k.Derive([]byte("pass"), []byte("salt"), 24)
+	assert(err != nil, "invalid kdf accepted")
+}
+
+func TestClientReuse(t *testing.T) {
+	skipFIPS(t)
+
+	assert := newAsserter(t)
+
+	user := []byte("user")
+	pass := []byte("pass")
+
+	s, err := New(2048, WithKDF(testKDFs[0]))
+	assert(err == nil, "New: %s", err)
+
+	v, err := s.Verifier(user, pass, nil)
+	assert(err == nil, "Verifier: %s", err)
+
+	c, err := s.NewClient(user, pass)
+	assert(err == nil, "NewClient: %s", err)
+	c.Reuse(v.s)
+
+	var x *big.Int
+	for i := 0; i < 3; i++ {
+		A := c.xA
+		srv, err := s.NewServer(v, A)
+		assert(err == nil, "%d: NewServer: %s", i, err)
+
+		m, err := c.Respond(srv.Challenge())
+		assert(err == nil, "%d: Respond: %s", i, err)
+
+		proof, ok := srv.CheckProof(m)
+		assert(ok, "%d: server: bad client proof", i)
+		assert(c.CheckProof(proof), "%d: client: bad server proof", i)
+
+		assert(c.xc.x != nil, "%d: x not cached", i)
+		if x != nil {
+			assert(x == c.xc.x, "%d: x recomputed", i)
+		}
+		x = c.xc.x
+
+		c.Reset()
+		assert(c.xA.Cmp(A) != 0, "%d: Reset didn't change A", i)
+		assert(c.RawKey() == nil, "%d: Reset kept the key", i)
+	}
+
+	// a different salt must not use the cached value
+	x2, err := c.privateKey([]byte("other salt"), v.kdf, "")
+	assert(err == nil, "privateKey: %s", err)
+	assert(x2.Cmp(x) != 0, "cached x used for different salt")
+}
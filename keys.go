@@ -0,0 +1,129 @@
+// keys.go - key schedule for application channels
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Applications that build their own secure channel on the session key
+// need a key and an IV for each direction; using K itself both ways lets
+// an attacker reflect messages and reuses nonces. Like the key block of
+// TLS 1.2, they are derived in one expansion of K and split in order:
+//
+//	HKDF(K, "srp key block") = client key | server key | client IV | server IV
+
+// Sizes of the parts of a KeyBlock: keys for AES-256 or ChaCha20 and IVs
+// for the 96 bit nonces of AEADs.
+const (
+	KeyBlockKeyLen = 32
+	KeyBlockIVLen  = 12
+)
+
+var keyBlockLabel = []byte("srp key block")
+
+// Protocols that need a session key of a given size (e.g., 128 bits) can
+// set it with WithSessionKeyBits(n). K = H(S) is then replaced by an
+// expansion of it, whether n is smaller or larger than the hash:
+//
+//	K = HKDF(H(S), "srp session key" || n)
+//
+// where n is encoded as 16 bit big-endian. The same label and size are
+// appended to the transcript context, so peers that disagree on the size
+// fail the proofs instead of deriving keys of different lengths.
+
+// Bounds of the session key size in bits
+const (
+	minSessionKeyBits = 128
+	maxSessionKeyBits = 4096
+)
+
+var sessionKeyLabel = []byte("srp session key")
+
+// WithSessionKeyBits makes clients and servers in this environment derive
+// a session key K of 'n' bits instead of the size of the hash. 'n' must be
+// a multiple of 8 from 128 to 4096. Both sides must use the same size.
+func WithSessionKeyBits(n int) Option {
+	return func(s *SRP) error {
+		if n%8 != 0 || n < minSessionKeyBits || n > maxSessionKeyBits {
+			return fmt.Errorf("srp: invalid session key size %d bits", n)
+		}
+		s.keyBits = n
+		return nil
+	}
+}
+
+// return the label that records the session key size, or nil if K is
+// the plain hash
+func (s *SRP) keySizeInfo() []byte {
+	if s.keyBits == 0 {
+		return nil
+	}
+	var n [2]byte
+	binary.BigEndian.PutUint16(n[:], uint16(s.keyBits))
+	return append(append([]byte{}, sessionKeyLabel...), n[:]...)
+}
+
+// return the session key of the configured size derived from 'K'
+func (s *SRP) sizeKey(K []byte) []byte {
+	if s.keyBits == 0 {
+		return K
+	}
+	return s.expandKey(K, s.keySizeInfo(), s.keyBits/8)
+}
+
+// return the transcript context of this environment: the application
+// context and the session key size, if any
+func (s *SRP) context() []byte {
+	info := s.keySizeInfo()
+	if info == nil {
+		return s.tctx
+	}
+	return append(append([]byte{}, s.tctx...), info...)
+}
+
+// KeyBlock holds the directional keys and IVs derived from a session key.
+// Each side encrypts with its own key and decrypts with its peer's.
+type KeyBlock struct {
+	ClientKey []byte // client -> server
+	ServerKey []byte // server -> client
+	ClientIV  []byte // client -> server
+	ServerIV  []byte // server -> client
+}
+
+// SessionKeys returns the key block of the session; the server must have
+// been authenticated.
+func (c *Client) SessionKeys() (KeyBlock, error) {
+	c.s.misuse(!c.authed, "Client.SessionKeys() before the server's proof was verified")
+	if !c.authed {
+		return KeyBlock{}, fmt.Errorf("srp: server isn't authenticated")
+	}
+	return c.s.keyBlock(c.xK), nil
+}
+
+// SessionKeys returns the key block of the session; the client must have
+// been authenticated.
+func (s *Server) SessionKeys() (KeyBlock, error) {
+	s.s.misuse(!s.authed, "Server.SessionKeys() before the client's proof was verified")
+	if !s.authed {
+		return KeyBlock{}, fmt.Errorf("srp: client isn't authenticated")
+	}
+	return s.s.keyBlock(s.xK), nil
+}
+
+// derive the key block of session key 'K'
+func (s *SRP) keyBlock(K []byte) KeyBlock {
+	b := s.expandKey(K, keyBlockLabel, 2*KeyBlockKeyLen+2*KeyBlockIVLen)
+
+	var kb KeyBlock
+	kb.ClientKey, b = b[:KeyBlockKeyLen:KeyBlockKeyLen], b[KeyBlockKeyLen:]
+	kb.ServerKey, b = b[:KeyBlockKeyLen:KeyBlockKeyLen], b[KeyBlockKeyLen:]
+	kb.ClientIV, b = b[:KeyBlockIVLen:KeyBlockIVLen], b[KeyBlockIVLen:]
+	kb.ServerIV = b
+	return kb
+}
@@ -0,0 +1,103 @@
+// self test for the key schedule
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"testing"
+)
+
+func TestSessionKeys(t *testing.T) {
+	assert := newAsserter(t)
+
+	s, err := New(2048)
+	assert(err == nil, "New: %s", err)
+	v, err := s.Verifier([]byte("user"), []byte("pass"), nil)
+	assert(err == nil, "Verifier: %s", err)
+
+	c, err := s.NewClient([]byte("user"), []byte("pass"))
+	assert(err == nil, "NewClient: %s", err)
+	srv, err := s.NewServer(v, c.xA)
+	assert(err == nil, "NewServer: %s", err)
+
+	_, err = srv.SessionKeys()
+	assert(err != nil, "keys before authentication")
+
+	assert(authenticate(c, srv), "handshake failed")
+
+	ck, err := c.SessionKeys()
+	assert(err == nil, "Client.SessionKeys: %s", err)
+	sk, err := srv.SessionKeys()
+	assert(err == nil, "Server.SessionKeys: %s", err)
+
+	pairs := [][2][]byte{
+		{ck.ClientKey, sk.ClientKey},
+		{ck.ServerKey, sk.ServerKey},
+		{ck.ClientIV, sk.ClientIV},
+		{ck.ServerIV, sk.ServerIV},
+	}
+	for i, p := range pairs {
+		assert(ctEqual(p[0], p[1]), "%d: sides disagree", i)
+	}
+
+	assert(len(ck.ClientKey) == KeyBlockKeyLen && len(ck.ServerKey) == KeyBlockKeyLen, "wrong key size")
+	assert(len(ck.ClientIV) == KeyBlockIVLen && len(ck.ServerIV) == KeyBlockIVLen, "wrong IV size")
+	assert(!ctEqual(ck.ClientKey, ck.ServerKey), "same key both ways")
+	assert(!ctEqual(ck.ClientIV, ck.ServerIV), "same IV both ways")
+	assert(!ctEqual(ck.ClientKey, c.RawKey()), "K used as a key")
+
+	// the parts don't share storage
+	ck.ClientKey = append(ck.ClientKey, 0)
+	assert(ctEqual(ck.ServerKey, sk.ServerKey), "appending to a key clobbered another")
+}
+
+func TestSessionKeyBits(t *testing.T) {
+	assert := newAsserter(t)
+
+	for _, n := range []int{0, 100, 127, 4104} {
+		_, err := New(2048, WithSessionKeyBits(n))
+		assert(err != nil, "accepted %d bits", n)
+	}
+
+	login := func(cbits, sbits int) (*Client, *Server, bool) {
+		var copts, sopts []Option
+		if cbits > 0 {
+			copts = append(copts, WithSessionKeyBits(cbits))
+		}
+		if sbits > 0 {
+			sopts = append(sopts, WithSessionKeyBits(sbits))
+		}
+		cs, err := New(2048, copts...)
+		assert(err == nil, "New: %s", err)
+		ss, err := New(2048, sopts...)
+		assert(err == nil, "New: %s", err)
+
+		v, err := ss.Verifier([]byte("user"), []byte("pass"), nil)
+		assert(err == nil, "Verifier: %s", err)
+		c, err := cs.NewClient([]byte("user"), []byte("pass"))
+		assert(err == nil, "NewClient: %s", err)
+		srv, err := ss.NewServer(v, c.xA)
+		assert(err == nil, "NewServer: %s", err)
+		return c, srv, authenticate(c, srv)
+	}
+
+	for _, n := range []int{128, 256, 512} {
+		c, srv, ok := login(n, n)
+		assert(ok, "%d bits: handshake failed", n)
+		assert(len(c.RawKey()) == n/8, "%d bits: key is %d bytes", n, len(c.RawKey()))
+		assert(ctEqual(c.RawKey(), srv.RawKey()), "%d bits: sides disagree", n)
+	}
+
+	// without the option, K is the plain hash
+	c, _, ok := login(0, 0)
+	assert(ok, "handshake failed")
+	assert(len(c.RawKey()) == 32, "plain key is %d bytes", len(c.RawKey()))
+
+	_, _, ok = login(128, 256)
+	assert(!ok, "peers with different key sizes agreed")
+	_, _, ok = login(256, 0)
+	assert(!ok, "resized key matched the plain one")
+}
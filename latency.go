@@ -0,0 +1,97 @@
+// latency.go - padding authentication responses to a constant duration
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Default latency targets by prime-field size (see SRP.LatencyTarget()).
+// They are well above the time a handshake step takes on current servers
+// so that the padding hides it; servers on slow hardware should set their
+// own with WithLatencyTarget().
+var defaultLatencyTargets = []struct {
+	bits   int
+	target time.Duration
+}{
+	{2048, 50 * time.Millisecond},
+	{3072, 100 * time.Millisecond},
+	{4096, 200 * time.Millisecond},
+	{6144, 500 * time.Millisecond},
+	{8192, time.Second},
+}
+
+// WithLatencyTarget sets the duration to which SRP.EqualizeLatency() pads
+// the authentication steps of this environment.
+func WithLatencyTarget(d time.Duration) Option {
+	return func(s *SRP) error {
+		if d <= 0 {
+			return fmt.Errorf("srp: invalid latency target %s", d)
+		}
+		s.lat = d
+		return nil
+	}
+}
+
+// LatencyTarget returns the duration to which EqualizeLatency() pads the
+// authentication steps of this environment: the one set with
+// WithLatencyTarget() or a default for the size of the prime field.
+func (s *SRP) LatencyTarget() time.Duration {
+	if s.lat > 0 {
+		return s.lat
+	}
+
+	bits := s.FieldSize()
+	for _, t := range defaultLatencyTargets {
+		if bits <= t.bits {
+			return t.target
+		}
+	}
+	return defaultLatencyTargets[len(defaultLatencyTargets)-1].target
+}
+
+// EqualizeLatency is EqualizeLatency() with the target of this environment
+func (s *SRP) EqualizeLatency(ctx context.Context, fn func() error) error {
+	return EqualizeLatency(ctx, s.LatencyTarget(), fn)
+}
+
+// EqualizeLatency runs 'fn' and returns its error once 'target' has passed
+// since the call. A server wraps each authentication step in it (e.g., the
+// lookup of the verifier and NewServer(), or ClientOk()) so that a success,
+// an unknown user and a bad proof all take the same time, which stops
+// attackers from enumerating users by timing responses. The time is
+// measured with the monotonic clock, so changes of the wall clock don't
+// affect it.
+//
+// If 'fn' takes longer than 'target', its error is returned right away;
+// 'target' should be well above the normal duration of 'fn'. If 'ctx' is
+// done while waiting, EqualizeLatency returns at once with the error of
+// 'fn' or, if it succeeded, the error of 'ctx'.
+func EqualizeLatency(ctx context.Context, target time.Duration, fn func() error) error {
+	start := time.Now()
+	err := fn()
+
+	d := target - time.Since(start)
+	if d <= 0 {
+		return err
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return err
+	case <-ctx.Done():
+		if err != nil {
+			return err
+		}
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,69 @@
+// self test for latency equalization
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestEqualizeLatency(t *testing.T) {
+	assert := newAsserter(t)
+
+	ctx := context.Background()
+	target := 20 * time.Millisecond
+	bad := fmt.Errorf("bad proof")
+
+	for _, e := range []error{nil, bad} {
+		start := time.Now()
+		err := EqualizeLatency(ctx, target, func() error { return e })
+		assert(err == e, "exp error %v, saw %v", e, err)
+		assert(time.Since(start) >= target, "returned after %s", time.Since(start))
+	}
+
+	// a slow fn isn't delayed further
+	start := time.Now()
+	err := EqualizeLatency(ctx, time.Millisecond, func() error {
+		time.Sleep(5 * time.Millisecond)
+		return nil
+	})
+	assert(err == nil, "slow: %s", err)
+	assert(time.Since(start) < 5*time.Millisecond+target, "slow fn delayed")
+
+	// cancellation ends the wait
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	start = time.Now()
+	err = EqualizeLatency(cctx, time.Hour, func() error { return nil })
+	assert(err == context.Canceled, "cancel: exp Canceled, saw %v", err)
+	assert(time.Since(start) < time.Second, "cancel didn't end the wait")
+
+	err = EqualizeLatency(cctx, time.Hour, func() error { return bad })
+	assert(err == bad, "cancel: exp fn error, saw %v", err)
+}
+
+func TestLatencyTarget(t *testing.T) {
+	assert := newAsserter(t)
+
+	prev := time.Duration(0)
+	for _, bits := range []int{2048, 3072, 4096, 8192} {
+		s, err := New(bits)
+		assert(err == nil, "New %d: %s", bits, err)
+		d := s.LatencyTarget()
+		assert(d > prev, "%d: target %s not above %s", bits, d, prev)
+		prev = d
+	}
+
+	s, err := New(2048, WithLatencyTarget(time.Second))
+	assert(err == nil, "New: %s", err)
+	assert(s.LatencyTarget() == time.Second, "option ignored: %s", s.LatencyTarget())
+
+	_, err = New(2048, WithLatencyTarget(0))
+	assert(err != nil, "zero target accepted")
+}
@@ -0,0 +1,192 @@
+// legacy.go - moving users of password files to SRP at their next login
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// A verifier can only be made from the password, so users of an existing
+// password file (htpasswd or /etc/shadow) move to SRP when they next log
+// in. LegacyMigration imports the password hashes into a VerifierStore as
+// placeholder records under the users' storage keys:
+//
+//	"legacy:" format ":" hash
+//
+// and manages the life of each user's record:
+//
+//  1. An SRP login of an imported user fails with ErrLegacyLogin (see
+//     StoreLookup()); the client falls back to the legacy login, sending
+//     the password over a protected channel (e.g., TLS).
+//  2. The application checks the password against the hash returned by
+//     Legacy(); this package doesn't implement the legacy hash schemes.
+//  3. On success the client makes a verifier (e.g., with
+//     UpgradeVerifier()) and sends it over the same session; Complete()
+//     replaces the placeholder with it.
+//  4. From then on Legacy() returns nil and the application must refuse
+//     legacy logins of the user: the old hash is gone from the store.
+//
+// Verifiers are looked up by hashed identity, so the legacy login and the
+// clients must hash identities in the environment given to
+// NewLegacyMigration(); it must not blind identities (WithIdentityKey()),
+// which clients can't do.
+
+// Password file formats understood by LegacyMigration.Import()
+const (
+	LegacyHtpasswd = "htpasswd" // user:hash
+	LegacyShadow   = "shadow"   // user:hash:lastchg:... as in /etc/shadow
+)
+
+const legacyRecordPrefix = "legacy:"
+
+// ErrLegacyLogin means the user has a password hash imported by
+// LegacyMigration instead of a verifier and must log in the legacy way
+// once.
+var ErrLegacyLogin = fmt.Errorf("srp: user must log in with the legacy password first")
+
+// LegacyHash is an imported password hash
+type LegacyHash struct {
+	Format string // LegacyHtpasswd or LegacyShadow
+	Hash   string // as in the password file, e.g., "$2y$10$..."
+}
+
+// LegacyMigration moves the users of a password file to SRP verifiers
+// kept in a VerifierStore (see above).
+type LegacyMigration struct {
+	s  *SRP
+	st VerifierStore
+}
+
+// NewLegacyMigration returns a migration of users into 'st' whose
+// identities and storage keys are those of the environment 's'.
+func NewLegacyMigration(st VerifierStore, s *SRP) (*LegacyMigration, error) {
+	if s.idk != nil {
+		return nil, fmt.Errorf("srp: legacy migration can't blind identities")
+	}
+	return &LegacyMigration{s: s, st: st}, nil
+}
+
+// Import reads a password file in 'format' from 'r' and stores a
+// placeholder for each user that has a password hash and no record in the
+// store yet; it returns the number of users imported. Comments, blank
+// lines and locked accounts (hashes that are empty or start with '*' or
+// '!') are skipped.
+func (m *LegacyMigration) Import(r io.Reader, format string) (int, error) {
+	var min, max int
+	switch format {
+	case LegacyHtpasswd:
+		min, max = 2, 2
+	case LegacyShadow:
+		min, max = 2, 9
+	default:
+		return 0, fmt.Errorf("srp: unknown password file format %q", format)
+	}
+
+	n := 0
+	sc := bufio.NewScanner(r)
+	for line := 1; sc.Scan(); line++ {
+		s := strings.TrimSpace(sc.Text())
+		if len(s) == 0 || s[0] == '#' {
+			continue
+		}
+
+		f := strings.Split(s, ":")
+		if len(f) < min || len(f) > max || len(f[0]) == 0 {
+			return n, fmt.Errorf("srp: %s line %d: malformed entry", format, line)
+		}
+		user, hash := f[0], f[1]
+		if len(hash) == 0 || hash[0] == '*' || hash[0] == '!' {
+			continue
+		}
+
+		key := m.key([]byte(user))
+		_, err := m.st.Get(key)
+		switch {
+		case err == nil:
+			continue
+		case err != ErrNoVerifier:
+			return n, err
+		}
+
+		if err := m.st.Put(key, legacyRecordPrefix+format+":"+hash); err != nil {
+			return n, err
+		}
+		n++
+	}
+	if err := sc.Err(); err != nil {
+		return n, err
+	}
+	return n, nil
+}
+
+// Legacy returns the imported password hash of user 'I', nil if the user
+// already has a verifier, or ErrNoVerifier if the user is unknown.
+func (m *LegacyMigration) Legacy(I []byte) (*LegacyHash, error) {
+	rec, err := m.st.Get(m.key(I))
+	if err != nil {
+		return nil, err
+	}
+	return parseLegacyRecord(rec)
+}
+
+// Complete replaces the imported password hash of user 'I' with the
+// encoded verifier 'vh' (the second value returned by Verifier.Encode())
+// made by the client after a successful legacy login. The verifier must
+// be for 'I' and must not need upgrading.
+func (m *LegacyMigration) Complete(I []byte, vh string) error {
+	lh, err := m.Legacy(I)
+	if err != nil {
+		return err
+	}
+	if lh == nil {
+		return fmt.Errorf("srp: user has migrated already")
+	}
+
+	_, v, err := MakeSRPVerifier(vh)
+	if err != nil {
+		return err
+	}
+	if !v.MatchesIdentity(m.ih(I)) {
+		return fmt.Errorf("srp: verifier doesn't match identity")
+	}
+	if v.NeedsUpgrade() {
+		return fmt.Errorf("srp: verifier parameters are too weak")
+	}
+	return m.st.Put(m.key(I), vh)
+}
+
+// return the hashed identity of user 'I' as sent by clients
+func (m *LegacyMigration) ih(I []byte) []byte {
+	return m.s.hashbyte(m.s.identity(I))
+}
+
+// return the storage key of user 'I'
+func (m *LegacyMigration) key(I []byte) string {
+	return m.s.StorageKey(m.ih(I))
+}
+
+// return true if the stored record 'rec' is a placeholder of
+// LegacyMigration
+func isLegacyRecord(rec string) bool {
+	return strings.HasPrefix(rec, legacyRecordPrefix)
+}
+
+// parse the placeholder 'rec'; return nil if it is a verifier
+func parseLegacyRecord(rec string) (*LegacyHash, error) {
+	if !isLegacyRecord(rec) {
+		return nil, nil
+	}
+
+	f := strings.SplitN(rec[len(legacyRecordPrefix):], ":", 2)
+	if len(f) != 2 {
+		return nil, fmt.Errorf("srp: malformed legacy record")
+	}
+	return &LegacyHash{Format: f[0], Hash: f[1]}, nil
+}
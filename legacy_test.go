@@ -0,0 +1,80 @@
+// self test for migrating password files
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLegacyMigration(t *testing.T) {
+	assert := newAsserter(t)
+
+	s, err := NewDefault()
+	assert(err == nil, "NewDefault: %s", err)
+
+	st := &mapStore{m: map[string]string{}}
+	m, err := NewLegacyMigration(st, s)
+	assert(err == nil, "NewLegacyMigration: %s", err)
+
+	htpasswd := "# web users\nalice:$apr1$salt$hash\n\nbob:{SHA}abcd\n"
+	n, err := m.Import(strings.NewReader(htpasswd), LegacyHtpasswd)
+	assert(err == nil && n == 2, "htpasswd: %d, %v", n, err)
+
+	shadow := "root:*:18000:0:99999:7:::\ncarol:$6$salt$hash:18000:0:99999:7:::\n" +
+		"dave:!$6$x$y:18000::::::\nalice:$6$other:18000:0:99999:7:::\n"
+	n, err = m.Import(strings.NewReader(shadow), LegacyShadow)
+	assert(err == nil && n == 1, "shadow: %d, %v", n, err)
+
+	_, err = m.Import(strings.NewReader("alice\n"), LegacyHtpasswd)
+	assert(err != nil, "malformed file accepted")
+	_, err = m.Import(strings.NewReader(""), "passwd")
+	assert(err != nil, "unknown format accepted")
+
+	// existing users are not clobbered
+	lh, err := m.Legacy([]byte("alice"))
+	assert(err == nil && lh != nil, "Legacy: %v", err)
+	assert(lh.Format == LegacyHtpasswd && lh.Hash == "$apr1$salt$hash", "alice: %+v", lh)
+	lh, err = m.Legacy([]byte("carol"))
+	assert(err == nil && lh != nil && lh.Format == LegacyShadow, "carol: %+v %v", lh, err)
+	_, err = m.Legacy([]byte("dave"))
+	assert(err == ErrNoVerifier, "locked account imported: %v", err)
+
+	// SRP logins need a legacy login first
+	lookup, err := StoreLookup(st)
+	assert(err == nil, "StoreLookup: %s", err)
+	c, err := s.NewClient([]byte("alice"), []byte("pass"))
+	assert(err == nil, "NewClient: %s", err)
+	_, _, err = lookup(c.Hello().IdentityHash)
+	assert(err == ErrLegacyLogin, "exp ErrLegacyLogin, saw %v", err)
+
+	// after the (application's) legacy login the client sends a verifier
+	v, err := UpgradeVerifier([]byte("bob"), []byte("pass"))
+	assert(err == nil, "UpgradeVerifier: %s", err)
+	_, vh := v.Encode()
+	assert(m.Complete([]byte("alice"), vh) != nil, "verifier of another user accepted")
+
+	v, err = UpgradeVerifier([]byte("alice"), []byte("pass"))
+	assert(err == nil, "UpgradeVerifier: %s", err)
+	_, vh = v.Encode()
+	assert(m.Complete([]byte("alice"), vh) == nil, "Complete failed")
+	assert(m.Complete([]byte("alice"), vh) != nil, "Complete twice")
+
+	lh, err = m.Legacy([]byte("alice"))
+	assert(err == nil && lh == nil, "legacy hash after migration: %+v %v", lh, err)
+
+	vs, v, err := lookup(c.Hello().IdentityHash)
+	assert(err == nil, "lookup: %s", err)
+	srv, err := vs.NewServerFor(c.Hello().IdentityHash, v, c.xA)
+	assert(err == nil, "NewServerFor: %s", err)
+	assert(authenticate(c, srv), "login after migration failed")
+
+	bs, err := New(2048, WithIdentityKey(make([]byte, 32)))
+	assert(err == nil, "New: %s", err)
+	_, err = NewLegacyMigration(st, bs)
+	assert(err != nil, "blinding environment accepted")
+}
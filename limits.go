@@ -0,0 +1,115 @@
+// limits.go - bounds on the size of values parsed from the wire
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"fmt"
+	"math/big"
+)
+
+const (
+	// the largest digest of the hash functions in "crypto"
+	maxHashSize = 64
+
+	// the largest built-in prime field in bits
+	maxFieldBits = 8192
+
+	// room for the optional "key=value" fields of a message
+	maxExtLen = 512
+)
+
+// Limits bounds the size (in bytes) of the values a client or server parses
+// from its peer. Oversized messages are rejected before any hex decoding or
+// big number conversion so that a peer can't make us allocate or compute
+// on huge crafted inputs. A zero field means the default for the prime field
+// (see DefaultLimits()).
+type Limits struct {
+	Identity  int // hashed identity I
+	Salt      int // salt s
+	PublicKey int // public keys A and B
+	Proof     int // proofs M and M'
+}
+
+// DefaultLimits returns the limits for a 'bits' sized prime field: public
+// keys can't be larger than the field, salts can't be larger than the field
+// or a hash digest (whichever is larger) and identities and proofs can't be
+// larger than a hash digest.
+func DefaultLimits(bits int) Limits {
+	n := (bits + 7) / 8
+	salt := n
+	if salt < maxHashSize {
+		salt = maxHashSize
+	}
+
+	return Limits{
+		Identity:  maxHashSize,
+		Salt:      salt,
+		PublicKey: n,
+		Proof:     maxHashSize,
+	}
+}
+
+// WithLimits overrides the default size limits of the environment; zero
+// fields in 'l' keep their defaults.
+func WithLimits(l Limits) Option {
+	return func(s *SRP) error {
+		if l.Identity < 0 || l.Salt < 0 || l.PublicKey < 0 || l.Proof < 0 {
+			return fmt.Errorf("srp: invalid limits %+v", l)
+		}
+		s.lim = l
+		return nil
+	}
+}
+
+// Limits returns the size limits in effect for this environment
+func (s *SRP) Limits() Limits {
+	return s.lim.merge(DefaultLimits(s.FieldSize()))
+}
+
+// ServerBeginWithLimits is like ServerBegin() but rejects credentials that
+// exceed the limits 'l'. ServerBegin() uses the defaults for the largest
+// built-in prime field; servers that only use smaller fields can be stricter.
+func ServerBeginWithLimits(creds string, l Limits) (string, *big.Int, error) {
+	l = l.merge(DefaultLimits(maxFieldBits))
+	if len(creds) > 2*(l.Identity+l.PublicKey)+1 {
+		return "", nil, fmt.Errorf("srp: client credentials too large")
+	}
+	return serverBegin(creds, l)
+}
+
+// return a copy of 'l' with its zero fields taken from 'd'
+func (l Limits) merge(d Limits) Limits {
+	if l.Identity == 0 {
+		l.Identity = d.Identity
+	}
+	if l.Salt == 0 {
+		l.Salt = d.Salt
+	}
+	if l.PublicKey == 0 {
+		l.PublicKey = d.PublicKey
+	}
+	if l.Proof == 0 {
+		l.Proof = d.Proof
+	}
+	return l
+}
+
+// return an error if a proof 'm' in encoding 'e' is too large
+func (l *Limits) checkProof(m string, e ProofEncoding) error {
+	if len(m) > e.encodedLen(l.Proof) {
+		return fmt.Errorf("srp: proof too large")
+	}
+	return nil
+}
+
+// return an error if the encoded server credentials 'srv' are too large
+func (l *Limits) checkServer(srv string) error {
+	if len(srv) > 2*(l.Salt+l.PublicKey)+1+maxExtLen {
+		return ErrBadServerKey
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+// self test for wire size limits
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestLimits(t *testing.T) {
+	assert := newAsserter(t)
+
+	user := []byte("user")
+	pass := []byte("pass")
+
+	s, err := New(2048)
+	assert(err == nil, "New: %s", err)
+
+	l := s.Limits()
+	assert(l.PublicKey == 256, "exp 256 byte public keys, saw %d", l.PublicKey)
+	assert(l.Identity == maxHashSize && l.Proof == maxHashSize, "bad limits %+v", l)
+
+	huge := strings.Repeat("ab", 4096)
+
+	_, _, err = ServerBegin("00:" + huge)
+	assert(err != nil, "accepted huge A")
+	_, _, err = ServerBegin(huge + ":00")
+	assert(err != nil, "accepted huge identity")
+
+	c, err := s.NewClient(user, pass)
+	assert(err == nil, "NewClient: %s", err)
+
+	creds := c.Credentials()
+	_, _, err = ServerBeginWithLimits(creds, s.Limits())
+	assert(err == nil, "ServerBeginWithLimits: %s", err)
+	_, _, err = ServerBeginWithLimits(creds, Limits{PublicKey: 16})
+	assert(err != nil, "accepted A larger than the limit")
+
+	_, err = c.Generate("00:" + huge)
+	assert(err != nil, "accepted huge B")
+	_, err = c.Generate(huge + ":02")
+	assert(err != nil, "accepted huge salt")
+	_, err = c.Respond(ServerCredentials{Salt: []byte{1}, B: make([]byte, 300)})
+	assert(err != nil, "accepted huge B")
+
+	assert(!c.ServerOk(huge), "accepted huge proof")
+
+	v, err := s.Verifier(user, pass, nil)
+	assert(err == nil, "Verifier: %s", err)
+
+	A := big.NewInt(0).Lsh(one, 4096)
+	_, err = s.NewServer(v, A)
+	assert(err != nil, "server accepted huge A")
+
+	_, A, err = ServerBegin(creds)
+	assert(err == nil, "ServerBegin: %s", err)
+	srv, err := s.NewServer(v, A)
+	assert(err == nil, "NewServer: %s", err)
+
+	_, ok := srv.ClientOk(huge)
+	assert(!ok, "server accepted huge proof")
+
+	_, err = New(2048, WithLimits(Limits{Salt: -1}))
+	assert(err != nil, "accepted negative limit")
+}
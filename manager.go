@@ -0,0 +1,179 @@
+// manager.go - pending handshakes of many clients
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// size of the nonces that name pending handshakes
+const handshakeNonceLen = 16
+
+// Defaults of NewHandshakeManager()
+const (
+	DefaultHandshakeTTL   = 30 * time.Second
+	DefaultMaxPerIdentity = 4
+	DefaultMaxHandshakes  = 10000
+)
+
+// Errors of HandshakeManager
+var (
+	// ErrTooManyHandshakes means the identity has as many pending
+	// handshakes as the manager allows
+	ErrTooManyHandshakes = fmt.Errorf("srp: too many pending handshakes")
+
+	// ErrHandshakesFull means the manager holds as many pending
+	// handshakes, of all identities, as it allows
+	ErrHandshakesFull = fmt.Errorf("srp: handshake manager full")
+
+	// ErrNoHandshake means the handshake is unknown, was taken or expired
+	ErrNoHandshake = fmt.Errorf("srp: no such handshake")
+)
+
+// HandshakeManager keeps the Servers of handshakes between the challenge
+// and the client's proof. Integrations that keep one pending Server per
+// user let a second login of the user (e.g., from another device) clobber
+// the first; the manager names each handshake by the identity and a
+// random nonce instead, which the server sends to the client with the
+// challenge and gets back with the proof.
+//
+// Handshakes are forgotten after a fixed time-to-live, and each identity
+// may have a bounded number pending at once, as may all identities
+// together. An attacker who knows an identity can use up its share until
+// the handshakes expire, and one with many identities the whole manager,
+// but neither can displace handshakes already in progress. The manager is
+// safe for concurrent use.
+type HandshakeManager struct {
+	mu sync.Mutex
+
+	ttl   time.Duration
+	max   int // per identity
+	total int // of all identities
+
+	pending map[string]*pendingHandshake
+	count   map[string]int // pending handshakes per identity
+
+	// keys in insertion order; with a fixed ttl this is also expiry order
+	fifo []string
+}
+
+type pendingHandshake struct {
+	srv *Server
+	ih  string
+	exp time.Time
+}
+
+// NewHandshakeManager creates a manager that keeps handshakes for 'ttl',
+// at most 'maxPerIdentity' of them for each identity and 'maxTotal' in
+// all; values <= 0 take DefaultHandshakeTTL, DefaultMaxPerIdentity and
+// DefaultMaxHandshakes.
+func NewHandshakeManager(ttl time.Duration, maxPerIdentity, maxTotal int) *HandshakeManager {
+	if ttl <= 0 {
+		ttl = DefaultHandshakeTTL
+	}
+	if maxPerIdentity <= 0 {
+		maxPerIdentity = DefaultMaxPerIdentity
+	}
+	if maxTotal <= 0 {
+		maxTotal = DefaultMaxHandshakes
+	}
+	return &HandshakeManager{
+		ttl:     ttl,
+		max:     maxPerIdentity,
+		total:   maxTotal,
+		pending: make(map[string]*pendingHandshake),
+		count:   make(map[string]int),
+	}
+}
+
+// Add records the pending handshake 'srv' and returns the nonce that
+// names it along with the client's identity. It fails with
+// ErrTooManyHandshakes if the identity has its share pending and with
+// ErrHandshakesFull if the manager holds its total.
+func (m *HandshakeManager) Add(srv *Server) ([]byte, error) {
+	nonce := srv.s.randbytes(handshakeNonceLen)
+	ih := string(srv.i)
+	k := ih + string(nonce)
+	now := time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.expire(now)
+	if m.count[ih] >= m.max {
+		return nil, ErrTooManyHandshakes
+	}
+	if len(m.pending) >= m.total {
+		return nil, ErrHandshakesFull
+	}
+
+	m.pending[k] = &pendingHandshake{srv: srv, ih: ih, exp: now.Add(m.ttl)}
+	m.count[ih]++
+	m.fifo = append(m.fifo, k)
+	return nonce, nil
+}
+
+// Take removes and returns the pending handshake of the client with hashed
+// identity 'ih' named by 'nonce'. A handshake can be taken once, so a
+// client gets one attempt at the proof.
+func (m *HandshakeManager) Take(ih, nonce []byte) (*Server, error) {
+	k := string(ih) + string(nonce)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.expire(time.Now())
+	p, ok := m.pending[k]
+	if !ok || len(nonce) != handshakeNonceLen {
+		return nil, ErrNoHandshake
+	}
+	m.remove(k, p)
+	return p.srv, nil
+}
+
+// Pending returns the number of pending handshakes of hashed identity 'ih'
+func (m *HandshakeManager) Pending(ih []byte) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.expire(time.Now())
+	return m.count[string(ih)]
+}
+
+// Len returns the number of pending handshakes
+func (m *HandshakeManager) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.expire(time.Now())
+	return len(m.pending)
+}
+
+// forget the handshake 'p' under key 'k'
+func (m *HandshakeManager) remove(k string, p *pendingHandshake) {
+	delete(m.pending, k)
+	if m.count[p.ih]--; m.count[p.ih] <= 0 {
+		delete(m.count, p.ih)
+	}
+}
+
+// forget handshakes that expired before 'now'; keys of handshakes already
+// taken are dropped on the way
+func (m *HandshakeManager) expire(now time.Time) {
+	for len(m.fifo) > 0 {
+		k := m.fifo[0]
+		if p, ok := m.pending[k]; ok {
+			if now.Before(p.exp) {
+				break
+			}
+			m.remove(k, p)
+		}
+		m.fifo = m.fifo[1:]
+	}
+}
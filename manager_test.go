@@ -0,0 +1,107 @@
+// self test for the handshake manager
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHandshakeManager(t *testing.T) {
+	assert := newAsserter(t)
+
+	s, err := New(2048)
+	assert(err == nil, "New: %s", err)
+	v, err := s.Verifier([]byte("user"), []byte("pass"), nil)
+	assert(err == nil, "Verifier: %s", err)
+
+	m := NewHandshakeManager(time.Minute, 2, 0)
+
+	// two devices of the same user log in at once
+	var cs []*Client
+	var nonces [][]byte
+	for i := 0; i < 2; i++ {
+		c, err := s.NewClient([]byte("user"), []byte("pass"))
+		assert(err == nil, "NewClient: %s", err)
+		srv, err := s.NewServer(v, c.xA)
+		assert(err == nil, "NewServer: %s", err)
+
+		_, err = c.Respond(srv.Challenge())
+		assert(err == nil, "Respond: %s", err)
+
+		n, err := m.Add(srv)
+		assert(err == nil, "Add: %s", err)
+		cs = append(cs, c)
+		nonces = append(nonces, n)
+	}
+	assert(m.Pending(cs[0].i) == 2 && m.Len() == 2, "exp 2 pending, saw %d", m.Len())
+
+	c, _ := s.NewClient([]byte("user"), []byte("pass"))
+	srv, _ := s.NewServer(v, c.xA)
+	_, err = m.Add(srv)
+	assert(errors.Is(err, ErrTooManyHandshakes), "cap not enforced: %v", err)
+
+	// the second device finishes first
+	for _, i := range []int{1, 0} {
+		srv, err := m.Take(cs[i].i, nonces[i])
+		assert(err == nil, "%d: Take: %s", i, err)
+		proof, ok := srv.CheckProof(cs[i].xM)
+		assert(ok && cs[i].CheckProof(proof), "%d: handshake failed", i)
+
+		_, err = m.Take(cs[i].i, nonces[i])
+		assert(errors.Is(err, ErrNoHandshake), "%d: taken twice", i)
+	}
+	assert(m.Len() == 0 && m.Pending(cs[0].i) == 0, "handshakes left behind")
+
+	// a nonce is only valid with its identity
+	n, err := m.Add(srv)
+	assert(err == nil, "Add: %s", err)
+	_, err = m.Take([]byte("other"), n)
+	assert(errors.Is(err, ErrNoHandshake), "nonce accepted for another identity")
+
+	// handshakes expire
+	m = NewHandshakeManager(time.Millisecond, 1, 0)
+	n, err = m.Add(srv)
+	assert(err == nil, "Add: %s", err)
+	time.Sleep(5 * time.Millisecond)
+	assert(m.Len() == 0, "handshake didn't expire")
+	_, err = m.Take(srv.i, n)
+	assert(errors.Is(err, ErrNoHandshake), "expired handshake taken")
+	_, err = m.Add(srv)
+	assert(err == nil, "cap not freed by expiry: %v", err)
+
+	// zero values take the defaults
+	m = NewHandshakeManager(0, 0, 0)
+	for i := 0; i < DefaultMaxPerIdentity; i++ {
+		_, err = m.Add(srv)
+		assert(err == nil, "%d: Add: %s", i, err)
+	}
+	_, err = m.Add(srv)
+	assert(errors.Is(err, ErrTooManyHandshakes), "exp ErrTooManyHandshakes, saw %v", err)
+	assert(m.ttl == DefaultHandshakeTTL, "ttl %s", m.ttl)
+	assert(m.total == DefaultMaxHandshakes, "total %d", m.total)
+
+	// the manager holds at most maxTotal handshakes of all identities
+	m = NewHandshakeManager(time.Minute, 1, 2)
+	for i, user := range []string{"a", "b", "c"} {
+		v, err := s.Verifier([]byte(user), []byte("pass"), nil)
+		assert(err == nil, "Verifier: %s", err)
+		c, err := s.NewClient([]byte(user), []byte("pass"))
+		assert(err == nil, "NewClient: %s", err)
+		srv, err := s.NewServer(v, c.xA)
+		assert(err == nil, "NewServer: %s", err)
+
+		_, err = m.Add(srv)
+		if i < 2 {
+			assert(err == nil, "%s: Add: %s", user, err)
+		} else {
+			assert(errors.Is(err, ErrHandshakesFull), "%s: total not enforced: %v", user, err)
+		}
+	}
+	assert(m.Len() == 2, "exp 2 pending, saw %d", m.Len())
+}
@@ -0,0 +1,184 @@
+// messages.go - protocol messages exchanged by SRP clients and servers
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// ClientCredentials is the first message of a handshake: the hashed identity
+// and public key <I, A> of the client. Its string form is returned by
+// Client.Credentials().
+//
+// The fields are tagged for encoding/json, which encodes byte slices as
+// base64; callers can thus place the message in a JSON body as is.
+type ClientCredentials struct {
+	IdentityHash []byte `json:"I"`
+	A            []byte `json:"A"`
+}
+
+// ServerCredentials is the server's reply to ClientCredentials: the user's
+// salt and the server public key <s, B> along with the password hardening
+// parameters of the verifier. Its string form is returned by
+// Server.Credentials(). Like ClientCredentials, it is tagged for
+// encoding/json.
+type ServerCredentials struct {
+	Salt  []byte `json:"s"`
+	B     []byte `json:"B"`
+	KDF   *KDF   `json:"kdf,omitempty"` // nil if the verifier doesn't use a KDF
+	Group string `json:"grp,omitempty"` // id of the group if its size alone is ambiguous
+
+	// Signature of the challenge if the server signs them (see
+	// WithChallengeSigningKey())
+	Signature []byte `json:"sig,omitempty"`
+
+	// Puzzle the client must solve if the server requires one (see
+	// WithClientPuzzle())
+	Puzzle *Puzzle `json:"pow,omitempty"`
+
+	// X names the derivation of the private key x if it isn't the default
+	// one (see ImportVerifier() and WithHMACPrivateKey())
+	X string `json:"x,omitempty"`
+}
+
+// String returns the string form "I:A" of the client credentials (as sent
+// by Client.Credentials())
+func (cc ClientCredentials) String() string {
+	return cc.encode()
+}
+
+// String returns the string form of the server credentials (as sent by
+// Server.Credentials())
+func (sc ServerCredentials) String() string {
+	return sc.encode()
+}
+
+// ParseClientCredentials parses the string form of ClientCredentials
+func ParseClientCredentials(creds string) (ClientCredentials, error) {
+	var cc ClientCredentials
+
+	v := strings.Split(creds, ":")
+	if len(v) != 2 {
+		return cc, ErrBadClientKey
+	}
+
+	I, err := hex.DecodeString(v[0])
+	if err != nil || len(I) == 0 {
+		return cc, fmt.Errorf("srp: invalid client identity")
+	}
+
+	A, err := decodeHexInt(v[1])
+	if err != nil || len(A) == 0 {
+		return cc, fmt.Errorf("srp: invalid client public key A")
+	}
+
+	cc.IdentityHash = I
+	cc.A = A
+	return cc, nil
+}
+
+// ParseServerCredentials parses the string form of ServerCredentials
+func ParseServerCredentials(creds string) (ServerCredentials, error) {
+	return parseServerCredentials(creds)
+}
+
+// encode the client credentials as "I:A"
+func (cc *ClientCredentials) encode() string {
+	return hex.EncodeToString(cc.IdentityHash) + ":" + hex.EncodeToString(cc.A)
+}
+
+// encode the server credentials as
+// "s:B[:kdf=params][:grp=id][:x=derivation][:pow=puzzle][:sig=hex]"
+func (sc *ServerCredentials) encode() string {
+	s := hex.EncodeToString(sc.Salt) + ":" + hex.EncodeToString(sc.B)
+	if sc.KDF != nil {
+		s += ":kdf=" + sc.KDF.String()
+	}
+	if sc.Group != "" {
+		s += ":grp=" + sc.Group
+	}
+	if sc.X != "" {
+		s += ":x=" + sc.X
+	}
+	if sc.Puzzle != nil {
+		s += ":pow=" + sc.Puzzle.String()
+	}
+	if sc.Signature != nil {
+		s += ":sig=" + hex.EncodeToString(sc.Signature)
+	}
+	return s
+}
+
+// parse the string form of ServerCredentials
+// NB: We don't leak any information in error messages.
+func parseServerCredentials(srv string) (ServerCredentials, error) {
+	var sc ServerCredentials
+
+	v := strings.Split(srv, ":")
+	if len(v) < 2 {
+		return sc, ErrBadServerKey
+	}
+
+	salt, err := hex.DecodeString(v[0])
+	if err != nil {
+		return sc, ErrBadServerKey
+	}
+
+	B, err := decodeHexInt(v[1])
+	if err != nil {
+		return sc, ErrBadServerKey
+	}
+
+	ext, err := parseExt(v[2:])
+	if err != nil {
+		return sc, ErrBadServerKey
+	}
+
+	if ss, ok := ext.take("kdf"); ok {
+		if sc.KDF, err = parseKDF(ss); err != nil {
+			return sc, fmt.Errorf("srp: invalid server kdf")
+		}
+	}
+
+	sc.Group, _ = ext.take("grp")
+
+	sc.X, _ = ext.take("x")
+	if checkXDerivation(sc.X) != nil {
+		return sc, ErrBadServerKey
+	}
+
+	if ss, ok := ext.take("pow"); ok {
+		if sc.Puzzle, err = parsePuzzle(ss); err != nil {
+			return sc, fmt.Errorf("srp: invalid server puzzle")
+		}
+	}
+
+	if ss, ok := ext.take("sig"); ok {
+		if sc.Signature, err = hex.DecodeString(ss); err != nil {
+			return sc, fmt.Errorf("srp: invalid server signature")
+		}
+	}
+
+	if err := ext.done(); err != nil {
+		return sc, ErrBadServerKey
+	}
+
+	sc.Salt = salt
+	sc.B = B
+	return sc, nil
+}
+
+// decode a hex number that may have an odd number of digits; leading zero
+// bytes are kept so that fixed-width encodings can be checked.
+func decodeHexInt(s string) ([]byte, error) {
+	if len(s)%2 == 1 {
+		s = "0" + s
+	}
+	return hex.DecodeString(s)
+}
@@ -0,0 +1,84 @@
+// self test for protocol messages
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestCredentialsJSON(t *testing.T) {
+	skipFIPS(t)
+
+	assert := newAsserter(t)
+
+	user := []byte("user")
+	pass := []byte("pass")
+
+	s, err := New(2048, WithKDF(testKDFs[0]))
+	assert(err == nil, "New: %s", err)
+
+	v, err := s.Verifier(user, pass, nil)
+	assert(err == nil, "Verifier: %s", err)
+
+	c, err := s.NewClient(user, pass)
+	assert(err == nil, "NewClient: %s", err)
+
+	cc := c.Hello()
+	assert(cc.String() == c.Credentials(), "client creds string mismatch")
+
+	b, err := json.Marshal(cc)
+	assert(err == nil, "json: %s", err)
+
+	var cc2 ClientCredentials
+	err = json.Unmarshal(b, &cc2)
+	assert(err == nil, "json: %s", err)
+	assert(bytes.Equal(cc.IdentityHash, cc2.IdentityHash) && bytes.Equal(cc.A, cc2.A), "client creds mismatch")
+
+	cc3, err := ParseClientCredentials(c.Credentials())
+	assert(err == nil, "ParseClientCredentials: %s", err)
+	assert(cc3.String() == cc.String(), "parsed client creds mismatch")
+
+	srv, err := s.NewServer(v, big.NewInt(0).SetBytes(cc2.A))
+	assert(err == nil, "NewServer: %s", err)
+
+	sc := srv.Challenge()
+	assert(sc.String() == srv.Credentials(), "server creds string mismatch")
+
+	b, err = json.Marshal(sc)
+	assert(err == nil, "json: %s", err)
+	assert(strings.Contains(string(b), `"kdf":"`+testKDFs[0].String()+`"`), "kdf not a string: %s", b)
+
+	var sc2 ServerCredentials
+	err = json.Unmarshal(b, &sc2)
+	assert(err == nil, "json: %s", err)
+	assert(sc2.String() == sc.String(), "server creds mismatch")
+
+	sc3, err := ParseServerCredentials(srv.Credentials())
+	assert(err == nil, "ParseServerCredentials: %s", err)
+	assert(sc3.String() == sc.String(), "parsed server creds mismatch")
+
+	m, err := c.Respond(sc2)
+	assert(err == nil, "Respond: %s", err)
+
+	proof, ok := srv.CheckProof(m)
+	assert(ok, "server: bad client proof")
+	assert(c.CheckProof(proof), "client: bad server proof")
+
+	// invalid KDF parameters are rejected when decoding
+	bad := strings.Replace(string(b), testKDFs[0].String(), "argon2id,t=0,m=1,p=1", 1)
+	err = json.Unmarshal([]byte(bad), &sc2)
+	assert(err != nil, "accepted invalid kdf")
+
+	for _, s := range []string{"", "00", "zz:01", ":01", "01:"} {
+		_, err = ParseClientCredentials(s)
+		assert(err != nil, "accepted client creds %q", s)
+	}
+}
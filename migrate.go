@@ -0,0 +1,107 @@
+// migrate.go - re-encoding stored verifiers
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"fmt"
+)
+
+// VerifierFormat names an encoding of stored verifiers
+type VerifierFormat int
+
+// Encodings of stored verifiers
+const (
+	VerifierText           VerifierFormat = iota // Encode(); the verifier string
+	VerifierCBOR                                 // EncodeCBOR()
+	VerifierCompact                              // EncodeCompact(false)
+	VerifierCompactDeflate                       // EncodeCompact(true)
+)
+
+// String returns the name of the format
+func (f VerifierFormat) String() string {
+	switch f {
+	case VerifierText:
+		return "text"
+	case VerifierCBOR:
+		return "cbor"
+	case VerifierCompact:
+		return "compact"
+	case VerifierCompactDeflate:
+		return "compact-deflate"
+	}
+	return fmt.Sprintf("format-%d", int(f))
+}
+
+// ParseVerifierFormat returns the format named 's' (see
+// VerifierFormat.String())
+func ParseVerifierFormat(s string) (VerifierFormat, error) {
+	for f := VerifierText; f <= VerifierCompactDeflate; f++ {
+		if f.String() == s {
+			return f, nil
+		}
+	}
+	return 0, fmt.Errorf("srp: unknown verifier format %q", s)
+}
+
+// DecodeVerifier decodes the verifier 'b' stored in format 'f' into an SRP
+// environment and Verifier. The options 'opts' are applied to the returned
+// SRP instance.
+func DecodeVerifier(b []byte, f VerifierFormat, opts ...Option) (*SRP, *Verifier, error) {
+	switch f {
+	case VerifierText:
+		return MakeSRPVerifier(string(b), opts...)
+	case VerifierCBOR:
+		return DecodeVerifierCBOR(b, opts...)
+	case VerifierCompact, VerifierCompactDeflate:
+		return DecodeVerifierCompact(b, opts...)
+	}
+	return nil, nil, fmt.Errorf("srp: unknown verifier format %s", f)
+}
+
+// EncodeAs returns the verifier encoded in format 'f'
+func (v *Verifier) EncodeAs(f VerifierFormat) ([]byte, error) {
+	switch f {
+	case VerifierText:
+		_, vh := v.Encode()
+		return []byte(vh), nil
+	case VerifierCBOR:
+		return v.EncodeCBOR(), nil
+	case VerifierCompact:
+		return v.EncodeCompact(false), nil
+	case VerifierCompactDeflate:
+		return v.EncodeCompact(true), nil
+	}
+	return nil, fmt.Errorf("srp: unknown verifier format %s", f)
+}
+
+// MigrateVerifier re-encodes the verifier 'b' stored in format 'from' into
+// format 'to' and checks that the result decodes to the same verifier.
+// Verifiers with a blinded identity need WithIdentityKey() in 'opts'.
+//
+// Only the encoding changes: a new salt size, hash function or group
+// changes the verifier itself and needs the password. Servers migrate
+// those at the next login of each user (see Verifier.NeedsUpgrade()).
+func MigrateVerifier(b []byte, from, to VerifierFormat, opts ...Option) ([]byte, *Verifier, error) {
+	_, v, err := DecodeVerifier(b, from, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	out, err := v.EncodeAs(to)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	_, w, err := DecodeVerifier(out, to, opts...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("srp: migrated verifier doesn't decode: %s", err)
+	}
+	if !v.Equal(w) {
+		return nil, nil, fmt.Errorf("srp: migrated verifier differs from the original")
+	}
+	return out, v, nil
+}
@@ -0,0 +1,59 @@
+// self test for verifier migration
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"testing"
+)
+
+func TestMigrateVerifier(t *testing.T) {
+	assert := newAsserter(t)
+
+	formats := []VerifierFormat{VerifierText, VerifierCBOR, VerifierCompact, VerifierCompactDeflate}
+	key := []byte("0123456789abcdef")
+
+	envs := [][]Option{
+		nil,
+		{WithIdentityKey(key)},
+	}
+	if !FIPSBuild {
+		envs = append(envs, []Option{WithKDF(KDF{Alg: KDFArgon2id, Time: 1, Memory: 64, Threads: 1})})
+	}
+
+	for _, opts := range envs {
+		s, err := New(2048, opts...)
+		assert(err == nil, "New: %s", err)
+
+		v, err := s.Verifier([]byte("user"), []byte("pass"), nil)
+		assert(err == nil, "Verifier: %s", err)
+
+		for _, from := range formats {
+			b, err := v.EncodeAs(from)
+			assert(err == nil, "EncodeAs %s: %s", from, err)
+
+			for _, to := range formats {
+				out, w, err := MigrateVerifier(b, from, to, opts...)
+				assert(err == nil, "%s -> %s: %s", from, to, err)
+				assert(w.Equal(v), "%s -> %s: verifier changed", from, to)
+
+				_, x, err := DecodeVerifier(out, to, opts...)
+				assert(err == nil, "%s -> %s: decode: %s", from, to, err)
+				assert(x.Equal(v), "%s -> %s: decoded verifier changed", from, to)
+			}
+		}
+	}
+
+	_, _, err := MigrateVerifier([]byte("garbage"), VerifierText, VerifierCBOR)
+	assert(err != nil, "migrated garbage")
+
+	for _, f := range formats {
+		g, err := ParseVerifierFormat(f.String())
+		assert(err == nil && g == f, "ParseVerifierFormat %s: %v %s", f, g, err)
+	}
+	_, err = ParseVerifierFormat("xml")
+	assert(err != nil, "parsed unknown format")
+}
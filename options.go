@@ -0,0 +1,161 @@
+// options.go - configuration options for SRP environments
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"fmt"
+	"io"
+)
+
+// minimum size of a provisioning seed in bytes
+const minSeedLen = 32
+
+// minimum size of a salt set by WithSaltSize() in bytes
+const minSaltLen = 16
+
+// Option configures an SRP environment. Options are given to New(),
+// NewWithHash() or NewDefault() and are applied in order.
+type Option func(s *SRP) error
+
+// AllowWeakGroups permits prime fields smaller than MinimumBits.
+//
+// Deprecated: use WithInsecureGroups().
+func AllowWeakGroups() Option {
+	return WithInsecureGroups()
+}
+
+// WithInsecureGroups permits New() and NewWithHash() to use prime fields
+// smaller than MinimumBits; without it, such fields are rejected. It
+// exists for existing deployments that have yet to migrate their
+// verifiers (see Verifier.NeedsUpgrade()).
+func WithInsecureGroups() Option {
+	return func(s *SRP) error {
+		s.weak = true
+		return nil
+	}
+}
+
+// WithTranscriptContext binds the application data 'ctx' (e.g., a channel
+// nonce, an API version or a client id) into the proofs exchanged by the
+// client and server. Both sides must use the same context or authentication
+// fails; this prevents a handshake from being replayed in another protocol
+// or context. The context isn't secret and isn't sent on the wire.
+func WithTranscriptContext(ctx []byte) Option {
+	return func(s *SRP) error {
+		s.tctx = append([]byte{}, ctx...)
+		return nil
+	}
+}
+
+// WithFixedWidthEncoding makes clients and servers send the public keys A
+// and B left-padded to exactly the size of the prime field instead of
+// without leading zeros, and reject a peer's key of any other width (see
+// SRP.ParsePublicKey() and SRP.ServerBegin()). Some peer implementations
+// need this. Salts are opaque, so they aren't padded: new salts are as
+// wide as the prime field (WithSaltSize() doesn't apply), and verifiers
+// with salts of any other width are refused by Verifier(), servers and
+// clients alike.
+func WithFixedWidthEncoding() Option {
+	return func(s *SRP) error {
+		s.fixed = true
+		return nil
+	}
+}
+
+// WithPaddedCredentials makes clients and servers send the public keys A
+// and B left-padded to the size of the prime field, in Credentials() and
+// Challenge() alike, but unlike WithFixedWidthEncoding() still accepts
+// peers that strip leading zeros. It suits peers (e.g., embedded C
+// implementations) that expect fixed-width values and fail on the rare
+// keys with a leading zero byte.
+func WithPaddedCredentials() Option {
+	return func(s *SRP) error {
+		s.padOut = true
+		return nil
+	}
+}
+
+// WithDeterministicSalts derives the salt of each new verifier from the
+// secret provisioning 'seed' and the identity instead of generating it at
+// random:
+//
+//	s = HKDF-SHA256(seed, "srp salt" || I)
+//
+// A device factory can then regenerate the exact verifier of a device from
+// a master seed. This is only for such provisioning: the salts of all
+// verifiers made with a seed are predictable by anyone who holds it, and
+// changing a user's password keeps their salt. Never use it for verifiers
+// that users create themselves.
+func WithDeterministicSalts(seed []byte) Option {
+	return func(s *SRP) error {
+		if len(seed) < minSeedLen {
+			return fmt.Errorf("srp: provisioning seed must be at least %d bytes", minSeedLen)
+		}
+		s.seed = append([]byte{}, seed...)
+		return nil
+	}
+}
+
+// WithRand makes the environment read salts, secret ephemerals and nonces
+// from 'r' instead of crypto/rand. 'r' must be a cryptographically secure
+// source; a failing source causes a panic. crypto/rand already uses the
+// platform's source everywhere, including crypto.getRandomValues() of the
+// Web Crypto API when built with GOOS=js GOARCH=wasm; this option is for
+// hosts that must supply their own, e.g., a hardware RNG.
+func WithRand(r io.Reader) Option {
+	return func(s *SRP) error {
+		if r == nil {
+			return fmt.Errorf("srp: nil random source")
+		}
+		s.rand = r
+		return nil
+	}
+}
+
+// WithSaltSize sets the size of new salts to 'n' bytes instead of the size
+// of the prime field; it shrinks the stored verifiers of large groups (see
+// EstimateVerifierSize()). 'n' must be at least 16.
+func WithSaltSize(n int) Option {
+	return func(s *SRP) error {
+		if n < minSaltLen {
+			return fmt.Errorf("srp: salt must be at least %d bytes", minSaltLen)
+		}
+		return withSaltLen(n)(s)
+	}
+}
+
+// withSaltLen sets the size of newly generated salts to 'n' bytes.
+func withSaltLen(n int) Option {
+	return func(s *SRP) error {
+		if n <= 0 {
+			return fmt.Errorf("srp: invalid salt length %d", n)
+		}
+		s.saltLen = n
+		return nil
+	}
+}
+
+// withEphemeralBits sets the size of the secret ephemerals a, b to 'n' bits.
+func withEphemeralBits(n int) Option {
+	return func(s *SRP) error {
+		if n <= 0 {
+			return fmt.Errorf("srp: invalid ephemeral size %d", n)
+		}
+		s.ephBits = n
+		return nil
+	}
+}
+
+// apply the options in 'opts' to this environment
+func (s *SRP) apply(opts []Option) error {
+	for _, o := range opts {
+		if err := o(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
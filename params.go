@@ -0,0 +1,99 @@
+// params.go - publishing the parameters of an environment
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"encoding/hex"
+	"fmt"
+)
+
+// Clients must use the hash function, group and proof scheme of their
+// server. Rather than shipping them in each client's configuration, a
+// server can publish them (package srphttp serves them at
+// /.well-known/srp-params) and clients create their environment from the
+// published form:
+//
+//	{"group":"rfc5054-3072","hash":"BLAKE2b-256","scheme":"rfc5054-padded",
+//	 "salt_size":32,"fingerprint":"9f2c..."}
+//
+// The published parameters are not authenticated beyond the channel they
+// came over; clients should pin the fingerprint they expect (see
+// PinServerParams()). Options that only matter to the server, such as
+// replay caches or limits, are not published; options that change the
+// computation, such as WithTranscriptContext(), must be given to New()
+// again by the client.
+
+// PublishedParams are the parameters of an environment that clients need
+// to talk to its servers
+type PublishedParams struct {
+	Group     string `json:"group"`                // built-in group ID
+	Hash      string `json:"hash"`                 // hash name, e.g., "SHA-256"
+	ProofHash string `json:"proof_hash,omitempty"` // hash of K and the proofs if not Hash
+	Scheme    string `json:"scheme"`               // proof scheme name
+	SaltSize  int    `json:"salt_size,omitempty"`  // bytes in new salts; 0 => as wide as N
+
+	// Fingerprint is the hex Fingerprint() of the environment; New()
+	// checks it.
+	Fingerprint string `json:"fingerprint"`
+}
+
+// PublishedParams returns the parameters of this environment in their
+// published form (see above); environments with a custom group can't be
+// published.
+func (s *SRP) PublishedParams() (*PublishedParams, error) {
+	if s.pf.id == "" {
+		return nil, fmt.Errorf("srp: parameters with a custom group can't be published")
+	}
+
+	p := &PublishedParams{
+		Group:       s.pf.id,
+		Hash:        hashName(s.h),
+		Scheme:      s.scheme().Name(),
+		SaltSize:    s.saltLen,
+		Fingerprint: hex.EncodeToString(s.Fingerprint()),
+	}
+	if s.ph != 0 && s.ph != s.h {
+		p.ProofHash = hashName(s.ph)
+	}
+	return p, nil
+}
+
+// New creates an environment with the published parameters and the
+// options 'opts'; options given later override the published ones. The
+// group must be strong enough for 'opts' (see WithInsecureGroups()) and the
+// resulting environment must have the published fingerprint.
+func (p *PublishedParams) New(opts ...Option) (*SRP, error) {
+	h, err := hashByName(p.Hash)
+	if err != nil {
+		return nil, err
+	}
+	ps := proofSchemeByName(p.Scheme)
+	if ps == nil {
+		return nil, fmt.Errorf("srp: unknown proof scheme %q", p.Scheme)
+	}
+
+	o := []Option{WithProofScheme(ps)}
+	if p.ProofHash != "" {
+		ph, err := hashByName(p.ProofHash)
+		if err != nil {
+			return nil, err
+		}
+		o = append(o, WithProofHash(ph))
+	}
+	if p.SaltSize != 0 {
+		o = append(o, WithSaltSize(p.SaltSize))
+	}
+
+	s, err := NewWithGroupID(h, p.Group, append(o, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+	if fp := hex.EncodeToString(s.Fingerprint()); fp != p.Fingerprint {
+		return nil, fmt.Errorf("%w: published %q, computed %s", ErrParamsMismatch, p.Fingerprint, fp)
+	}
+	return s, nil
+}
@@ -0,0 +1,70 @@
+// self test for published parameters
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"crypto"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestPublishedParams(t *testing.T) {
+	assert := newAsserter(t)
+
+	ph := crypto.SHA3_256
+	if FIPSBuild {
+		ph = crypto.SHA256
+	}
+	s, err := NewDefault(WithProofScheme(ProofRFC5054Padded), WithProofHash(ph))
+	assert(err == nil, "New: %s", err)
+
+	p, err := s.PublishedParams()
+	assert(err == nil, "publish: %s", err)
+	assert(p.Group == s.GroupID() && p.SaltSize == DefaultSaltLen, "wrong params %+v", p)
+
+	b, err := json.Marshal(p)
+	assert(err == nil, "marshal: %s", err)
+	var q PublishedParams
+	assert(json.Unmarshal(b, &q) == nil, "unmarshal")
+
+	c, err := q.New()
+	assert(err == nil, "New from params: %s", err)
+	assert(ctEqual(c.Fingerprint(), s.Fingerprint()), "fingerprints differ")
+	assert(c.saltSize() == s.saltSize(), "salt size differs")
+
+	// a handshake with the published parameters
+	v, err := s.Verifier([]byte("alice"), []byte("secret"), nil)
+	assert(err == nil, "verifier: %s", err)
+	cl, err := c.NewClient([]byte("alice"), []byte("secret"))
+	assert(err == nil, "client: %s", err)
+	srv, err := s.NewServer(v, cl.xA)
+	assert(err == nil, "server: %s", err)
+	assert(authenticate(cl, srv), "handshake failed")
+
+	// a fingerprint that doesn't match the rest
+	q.Scheme = ProofLegacy.Name()
+	_, err = q.New()
+	assert(errors.Is(err, ErrParamsMismatch), "altered params accepted: %v", err)
+
+	// weak groups need the client's consent; a "fips" build has none
+	if !FIPSBuild {
+		w, err := New(1024, WithInsecureGroups())
+		assert(err == nil, "New: %s", err)
+		p, err = w.PublishedParams()
+		assert(err == nil, "publish: %s", err)
+		_, err = p.New()
+		assert(err != nil, "weak group accepted")
+		_, err = p.New(WithInsecureGroups())
+		assert(err == nil, "weak group with consent: %s", err)
+	}
+
+	// custom groups have no ID
+	g := &SRP{h: crypto.SHA256, pf: &primeField{N: s.pf.N, g: s.pf.g, n: s.pf.n}}
+	_, err = g.PublishedParams()
+	assert(err != nil, "custom group published")
+}
@@ -0,0 +1,109 @@
+// password.go - password policies enforced when creating verifiers
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"fmt"
+	"math"
+	"unicode"
+	"unicode/utf8"
+)
+
+// PasswordPolicy returns an error if the password 'p' is unacceptable. The
+// error is returned by SRP.Verifier() as is, so it should be fit for the
+// user (e.g., "password is too short").
+type PasswordPolicy func(p []byte) error
+
+// WithPasswordPolicy makes Verifier() and VerifierWithSalt() reject
+// passwords for which 'pp' returns an error. It lets a registration
+// endpoint enforce its rules where the verifier is created; the server
+// never sees the password during a login.
+func WithPasswordPolicy(pp PasswordPolicy) Option {
+	return func(s *SRP) error {
+		if pp == nil {
+			return fmt.Errorf("srp: nil password policy")
+		}
+		s.pp = pp
+		return nil
+	}
+}
+
+// MinimumEntropy returns a PasswordPolicy that rejects passwords whose
+// EstimateEntropy() is less than 'bits'.
+func MinimumEntropy(bits float64) PasswordPolicy {
+	return func(p []byte) error {
+		if EstimateEntropy(p) < bits {
+			return fmt.Errorf("srp: password is too weak")
+		}
+		return nil
+	}
+}
+
+// EstimateEntropy returns a rough estimate of the entropy of the password
+// 'p' in bits: the number of characters times log2 of the size of the
+// character classes it uses (lower case, upper case, digits, ASCII symbols
+// and others). Repeats of the previous character count as one bit. This
+// overestimates passwords made from dictionary words; it is a floor for
+// policies, not a strength meter.
+func EstimateEntropy(p []byte) float64 {
+	var lower, upper, digit, symbol, other bool
+	var n, rep int
+	var prev rune = -1
+
+	for len(p) > 0 {
+		r, sz := utf8.DecodeRune(p)
+		p = p[sz:]
+
+		switch {
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= '0' && r <= '9':
+			digit = true
+		case r < utf8.RuneSelf && unicode.IsPrint(r):
+			symbol = true
+		default:
+			other = true
+		}
+
+		if r == prev {
+			rep++
+		} else {
+			n++
+		}
+		prev = r
+	}
+
+	pool := 0
+	for _, c := range []struct {
+		used bool
+		size int
+	}{
+		{lower, 26},
+		{upper, 26},
+		{digit, 10},
+		{symbol, 33},
+		{other, 100},
+	} {
+		if c.used {
+			pool += c.size
+		}
+	}
+	if pool == 0 {
+		return 0
+	}
+	return float64(n)*math.Log2(float64(pool)) + float64(rep)
+}
+
+// return an error if the password policy of the environment rejects 'p'
+func (s *SRP) checkPassword(p []byte) error {
+	if s.pp == nil {
+		return nil
+	}
+	return s.pp(p)
+}
@@ -0,0 +1,72 @@
+// self test for password policies
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestPasswordPolicy(t *testing.T) {
+	assert := newAsserter(t)
+
+	user := []byte("user")
+
+	_, err := New(2048, WithPasswordPolicy(nil))
+	assert(err != nil, "accepted nil policy")
+
+	short := func(p []byte) error {
+		if len(p) < 8 {
+			return fmt.Errorf("password is too short")
+		}
+		return nil
+	}
+
+	s, err := New(2048, WithPasswordPolicy(short))
+	assert(err == nil, "New: %s", err)
+
+	_, err = s.Verifier(user, []byte("pass"), nil)
+	assert(err != nil && err.Error() == "password is too short", "exp policy error, saw %v", err)
+	_, err = s.VerifierWithSalt(user, []byte("pass"), []byte("salt"))
+	assert(err != nil, "VerifierWithSalt bypassed the policy")
+
+	_, err = s.Verifier(user, []byte("password"), nil)
+	assert(err == nil, "Verifier: %s", err)
+
+	// logins aren't affected
+	c, err := s.NewClient(user, []byte("pass"))
+	assert(err == nil && c != nil, "NewClient: %s", err)
+
+	s, err = New(2048, WithPasswordPolicy(MinimumEntropy(50)))
+	assert(err == nil, "New: %s", err)
+	_, err = s.Verifier(user, []byte("aaaaaaaaaaaaaaaa"), nil)
+	assert(err != nil, "accepted repeated characters")
+	_, err = s.Verifier(user, []byte("Tr0ub4dor&3x"), nil)
+	assert(err == nil, "rejected strong password: %v", err)
+}
+
+func TestEstimateEntropy(t *testing.T) {
+	assert := newAsserter(t)
+
+	tests := []struct {
+		p    string
+		bits float64
+	}{
+		{"", 0},
+		{"abc", 3 * math.Log2(26)},
+		{"aaa", math.Log2(26) + 2},
+		{"aB3", 3 * math.Log2(62)},
+		{"a b", 3 * math.Log2(59)},
+		{"aé", 2 * math.Log2(126)},
+	}
+
+	for _, x := range tests {
+		e := EstimateEntropy([]byte(x.p))
+		assert(math.Abs(e-x.bits) < 1e-9, "%q: exp %f, saw %f", x.p, x.bits, e)
+	}
+}
@@ -0,0 +1,80 @@
+// pin.go - fingerprints of SRP parameters and pinning them
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// Both sides of a handshake must agree on the hash function, the group and
+// the proof scheme, none of which is authenticated on the wire. A client
+// whose parameters come from configuration (or, worse, from the server) can
+// pin the fingerprint of the parameters it expects; an environment with
+// other parameters then refuses to start handshakes instead of failing
+// them, or succeeding, with a group an attacker substituted.
+//
+// The fingerprint is SHA-256 over a version label and the parameters:
+//
+//	FP = SHA-256("srp params v1", hash name, 0, pad(N), pad(g), 0, scheme)
+//
+// where the hash name is as in HashUnavailableError (e.g., "SHA-256") and
+// the scheme is the name of the proof scheme (e.g., "rfc5054-padded"). An
+// environment whose proofs use another hash (see WithProofHash()) appends
+// 0 and the name of that hash.
+
+var paramsLabel = []byte("srp params v1")
+
+// ErrParamsMismatch is matched (with errors.Is()) by the errors of
+// environments whose parameters don't have the fingerprint pinned with
+// PinServerParams().
+var ErrParamsMismatch = fmt.Errorf("srp: parameters don't match the pinned fingerprint")
+
+// Fingerprint returns a stable SHA-256 fingerprint of the hash functions,
+// the group (N, g) and the proof scheme of this environment; it doesn't
+// change across releases of this package.
+func (s *SRP) Fingerprint() []byte {
+	h := sha256.New()
+	h.Write(paramsLabel)
+	h.Write([]byte(hashName(s.h)))
+	h.Write([]byte{0})
+	h.Write(pad(s.pf.N, s.pf.n))
+	h.Write(pad(s.pf.g, s.pf.n))
+	h.Write([]byte{0})
+	h.Write([]byte(s.scheme().Name()))
+	if ph := s.proofHash(); ph != s.h {
+		h.Write([]byte{0})
+		h.Write([]byte(hashName(ph)))
+	}
+	return h.Sum(nil)
+}
+
+// PinServerParams makes clients (and servers) in this environment refuse
+// to start a handshake unless the environment's Fingerprint() is 'fp'; the
+// error matches ErrParamsMismatch. It protects against configuration drift
+// and against parameters substituted by whoever supplies them.
+func PinServerParams(fp []byte) Option {
+	return func(s *SRP) error {
+		if len(fp) != sha256.Size {
+			return fmt.Errorf("srp: pinned fingerprint must be %d bytes", sha256.Size)
+		}
+		s.pin = append([]byte{}, fp...)
+		return nil
+	}
+}
+
+// return an error if the parameters don't match the pinned fingerprint
+func (s *SRP) checkPin() error {
+	if s.pin == nil {
+		return nil
+	}
+	if fp := s.Fingerprint(); !ctEqual(fp, s.pin) {
+		return fmt.Errorf("%w (have %s)", ErrParamsMismatch, hex.EncodeToString(fp))
+	}
+	return nil
+}
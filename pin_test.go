@@ -0,0 +1,81 @@
+// self test for parameter fingerprints
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"crypto"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func TestFingerprint(t *testing.T) {
+	assert := newAsserter(t)
+
+	s, err := NewWithHash(crypto.SHA256, 2048)
+	assert(err == nil, "New: %s", err)
+	fp := s.Fingerprint()
+
+	// the fingerprint must never change
+	const exp = "b3075211fb3ba1c54f69d25a4b77faec0cf68ff900ec0990cc1f849111f7dc03"
+	assert(hex.EncodeToString(fp) == exp, "fingerprint changed: %x", fp)
+
+	// options unrelated to the parameters don't matter
+	s2, err := NewWithHash(crypto.SHA256, 2048, WithFixedWidthEncoding())
+	assert(err == nil, "New: %s", err)
+	assert(ctEqual(fp, s2.Fingerprint()), "fingerprint depends on encoding")
+
+	others := []func() (*SRP, error){
+		func() (*SRP, error) { return NewWithHash(crypto.SHA256, 3072) },
+		func() (*SRP, error) { return NewWithHash(crypto.SHA256, 2048, WithProofScheme(ProofRFC5054)) },
+		func() (*SRP, error) { return NewWithHash(crypto.SHA256, 2048, WithIdentityFreeProofs()) },
+	}
+	if !FIPSBuild {
+		others = append(others,
+			func() (*SRP, error) { return NewWithHash(crypto.SHA3_256, 2048) },
+			func() (*SRP, error) { return NewWithHash(crypto.SHA256, 2048, WithProofHash(crypto.SHA3_256)) },
+		)
+	}
+	for i, f := range others {
+		o, err := f()
+		assert(err == nil, "%d: New: %s", i, err)
+		assert(!ctEqual(fp, o.Fingerprint()), "%d: same fingerprint", i)
+	}
+}
+
+func TestPinServerParams(t *testing.T) {
+	assert := newAsserter(t)
+
+	s, err := NewWithHash(crypto.SHA256, 2048)
+	assert(err == nil, "New: %s", err)
+	fp := s.Fingerprint()
+
+	_, err = New(2048, PinServerParams(fp[:16]))
+	assert(err != nil, "accepted short fingerprint")
+
+	// matching parameters
+	p, err := NewWithHash(crypto.SHA256, 2048, PinServerParams(fp))
+	assert(err == nil, "New: %s", err)
+	v, err := p.Verifier([]byte("user"), []byte("pass"), nil)
+	assert(err == nil, "Verifier: %s", err)
+	c, err := p.NewClient([]byte("user"), []byte("pass"))
+	assert(err == nil, "NewClient: %s", err)
+	srv, err := p.NewServer(v, c.xA)
+	assert(err == nil, "NewServer: %s", err)
+	assert(authenticate(c, srv), "handshake failed")
+
+	// drifted parameters
+	p, err = NewWithHash(crypto.SHA256, 2048, WithProofScheme(ProofRFC5054), PinServerParams(fp))
+	assert(err == nil, "New: %s", err)
+	_, err = p.NewClient([]byte("user"), []byte("pass"))
+	assert(errors.Is(err, ErrParamsMismatch), "NewClient: exp ErrParamsMismatch, saw %v", err)
+
+	p, err = NewWithHash(crypto.SHA256, 3072, PinServerParams(fp))
+	assert(err == nil, "New: %s", err)
+	_, err = p.NewClientPool().Get([]byte("user"), []byte("pass"))
+	assert(errors.Is(err, ErrParamsMismatch), "ClientPool.Get: exp ErrParamsMismatch, saw %v", err)
+}
@@ -0,0 +1,99 @@
+// policy.go - recommended SRP parameters and a policy checker
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"crypto"
+	"fmt"
+)
+
+const (
+	// DefaultBits is the prime-field size used by NewDefault().
+	DefaultBits = 3072
+
+	// MinimumBits is the smallest prime-field size accepted without an
+	// explicit WithInsecureGroups() opt-in.
+	MinimumBits = 2048
+
+	// DefaultSaltLen is the size of a new salt (in bytes) used by NewDefault().
+	DefaultSaltLen = 32
+
+	// DefaultEphemeralBits is the size of the secret ephemerals a, b used by
+	// NewDefault().
+	DefaultEphemeralBits = 256
+
+	// minHashSize is the smallest acceptable digest size in bytes
+	minHashSize = 32
+)
+
+// NewDefault creates a new SRP environment with the currently recommended
+// parameters: a 3072-bit prime field, Blake2b-256 (SHA-256 in FIPS
+// mode), 32 byte salts and 256-bit secret ephemerals. Callers that don't have to interoperate with
+// an existing deployment should use this instead of picking parameters by hand.
+func NewDefault(opts ...Option) (*SRP, error) {
+	o := []Option{
+		withSaltLen(DefaultSaltLen),
+		withEphemeralBits(DefaultEphemeralBits),
+	}
+	return newSRP(0, DefaultBits, append(o, opts...))
+}
+
+// MinimumAcceptable returns an error if a 'bits' sized prime field and the
+// hash function 'h' fall short of the minimum recommended parameters.
+// Prime fields smaller than MinimumBits are rejected unless WithInsecureGroups()
+// is one of 'opts'. Hash functions with a digest smaller than 256 bits
+// are always rejected. In FIPS mode (see WithFIPSMode()) parameters that
+// aren't approved are rejected as well.
+func MinimumAcceptable(bits int, h crypto.Hash, opts ...Option) error {
+	var s SRP
+
+	if err := s.apply(opts); err != nil {
+		return err
+	}
+
+	if err := checkHash(h); err != nil {
+		return err
+	}
+
+	if sz := newHash(h).Size(); sz < minHashSize {
+		return fmt.Errorf("srp: hash algorithm %d is too weak (%d bit digest)", h, sz*8)
+	}
+
+	if bits <= 0 {
+		return fmt.Errorf("srp: invalid prime-field size %d", bits)
+	}
+
+	if bits < MinimumBits && !s.weak {
+		return fmt.Errorf("srp: %d bit prime-field is too weak; need at least %d bits", bits, MinimumBits)
+	}
+	if s.FIPS() {
+		return fipsApproved(bits, h)
+	}
+	return nil
+}
+
+// NeedsUpgrade returns true if the verifier was created with parameters that
+// fall short of MinimumAcceptable(). A server should ask such a user to
+// provide a new verifier (see UpgradeVerifier()) right after a successful
+// authentication.
+func (v *Verifier) NeedsUpgrade() bool {
+	return MinimumAcceptable(v.pf.n*8, v.h) != nil
+}
+
+// UpgradeVerifier creates a replacement password verifier for user I and
+// passphrase p using the parameters of NewDefault(). This is meant to be run
+// by the client once the server has signalled (on an authenticated
+// session) that its existing verifier needs upgrading. The new verifier
+// may hash the identity differently; the server must store it against
+// the identity returned by its Encode() method.
+func UpgradeVerifier(I, p []byte, opts ...Option) (*Verifier, error) {
+	s, err := NewDefault(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return s.Verifier(I, p, nil)
+}
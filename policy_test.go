@@ -0,0 +1,101 @@
+// self test for srp parameter policy
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"crypto"
+	"testing"
+
+	_ "crypto/sha1"
+	_ "crypto/sha256"
+)
+
+func TestNewDefault(t *testing.T) {
+	assert := newAsserter(t)
+
+	s, err := NewDefault()
+	assert(err == nil, "NewDefault: %s", err)
+	assert(s.FieldSize() == DefaultBits, "exp %d bit field, saw %d", DefaultBits, s.FieldSize())
+	exp := crypto.BLAKE2b_256
+	if FIPSBuild {
+		exp = crypto.SHA256
+	}
+	assert(s.h == exp, "exp %s, saw %d", hashName(exp), s.h)
+
+	v, err := s.Verifier([]byte("user"), []byte("pass"), nil)
+	assert(err == nil, "Verifier: %s", err)
+	assert(len(v.s) == DefaultSaltLen, "exp %d byte salt, saw %d", DefaultSaltLen, len(v.s))
+
+	c, err := s.NewClient([]byte("user"), []byte("pass"))
+	assert(err == nil, "NewClient: %s", err)
+	assert(c.a.BitLen() <= DefaultEphemeralBits, "ephemeral too large: %d bits", c.a.BitLen())
+
+	db := newUserDBFrom(t, s, []byte("user"), []byte("pass"))
+	db.verify(t, []byte("user"), []byte("pass"), true)
+	db.verify(t, []byte("user"), []byte("wrong"), false)
+}
+
+func TestMinimumAcceptable(t *testing.T) {
+	assert := newAsserter(t)
+
+	tests := []struct {
+		bits int
+		h    crypto.Hash
+		opts []Option
+		ok   bool
+	}{
+		{3072, crypto.BLAKE2b_256, nil, true},
+		{2048, crypto.SHA256, nil, true},
+		{1024, crypto.SHA256, nil, false},
+		{1024, crypto.SHA256, []Option{WithInsecureGroups()}, true},
+		{1024, crypto.SHA256, []Option{AllowWeakGroups()}, true}, // the deprecated alias
+		{1536, crypto.BLAKE2b_256, nil, false},
+		{4096, crypto.SHA1, nil, false},
+		{4096, crypto.SHA1, []Option{WithInsecureGroups()}, false},
+		{0, crypto.SHA256, nil, false},
+	}
+
+	for i, x := range tests {
+		// a "fips" build also rejects parameters that aren't approved
+		if FIPSBuild && fipsApproved(x.bits, x.h) != nil {
+			x.ok = false
+		}
+		err := MinimumAcceptable(x.bits, x.h, x.opts...)
+		assert((err == nil) == x.ok, "%d: %d bits, hash %d: exp ok=%v, saw %v", i, x.bits, x.h, x.ok, err)
+	}
+}
+
+func TestInsecureGroups(t *testing.T) {
+	skipFIPS(t)
+
+	assert := newAsserter(t)
+
+	for _, bits := range []int{1024, 1536} {
+		_, err := New(bits)
+		assert(err != nil, "%d: expected error for weak group", bits)
+
+		s, err := New(bits, WithInsecureGroups())
+		assert(err == nil, "%d: WithInsecureGroups: %s", bits, err)
+
+		v, err := s.Verifier([]byte("user"), []byte("pass"), nil)
+		assert(err == nil, "%d: Verifier: %s", bits, err)
+		assert(v.NeedsUpgrade(), "%d: weak verifier doesn't need upgrade", bits)
+
+		_, vh := v.Encode()
+		_, v, err = MakeSRPVerifier(vh)
+		assert(err == nil, "%d: decoding weak verifier: %s", bits, err)
+		assert(v.NeedsUpgrade(), "%d: decoded weak verifier doesn't need upgrade", bits)
+	}
+
+	_, err := New(2048)
+	assert(err == nil, "2048: %s", err)
+
+	v, err := UpgradeVerifier([]byte("user"), []byte("pass"))
+	assert(err == nil, "UpgradeVerifier: %s", err)
+	assert(!v.NeedsUpgrade(), "upgraded verifier needs upgrade")
+	assert(v.pf.n*8 == DefaultBits, "upgraded verifier has %d bits", v.pf.n*8)
+}
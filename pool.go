@@ -0,0 +1,158 @@
+// pool.go - reuse of clients and servers on busy hosts
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"fmt"
+	"math/big"
+	"sync"
+)
+
+// ServerPool hands out Servers of one environment and reuses them (and
+// the buffer of the verifier) after they are released, which saves
+// allocations on servers that run many logins a second. The modular
+// arithmetic still dominates the cost of a login (see BenchmarkPooledLogin);
+// the pool mainly relieves the garbage collector. A Server must not
+// be used after Put(); Put() wipes its secrets first, so callers must copy
+// RawKey() if they keep it. It is safe for concurrent use.
+type ServerPool struct {
+	s *SRP
+	p sync.Pool
+}
+
+// ClientPool is the counterpart of ServerPool for clients, e.g., in load
+// generators and gateways that log in on behalf of many users.
+type ClientPool struct {
+	s *SRP
+	p sync.Pool
+}
+
+// NewServerPool returns a pool of Servers in this environment
+func (s *SRP) NewServerPool() *ServerPool {
+	return &ServerPool{s: s}
+}
+
+// NewClientPool returns a pool of Clients in this environment
+func (s *SRP) NewClientPool() *ClientPool {
+	return &ClientPool{s: s}
+}
+
+// Get returns a Server for verifier 'v' and the client public key 'A'; it
+// is the pooled equivalent of SRP.NewServer().
+func (p *ServerPool) Get(v *Verifier, A *big.Int) (*Server, error) {
+	if v.idk != nil {
+		return nil, errBlinded
+	}
+	return p.get(v, v.i, A)
+}
+
+// GetFor is the pooled equivalent of SRP.NewServerFor()
+func (p *ServerPool) GetFor(ih []byte, v *Verifier, A *big.Int) (*Server, error) {
+	if !v.MatchesIdentity(ih) {
+		return nil, fmt.Errorf("srp: verifier doesn't match identity")
+	}
+	return p.get(v, ih, A)
+}
+
+func (p *ServerPool) get(v *Verifier, ih []byte, A *big.Int) (*Server, error) {
+	sx, _ := p.p.Get().(*Server)
+	if sx == nil {
+		sx = &Server{vbuf: new(big.Int)}
+	}
+	vbuf := sx.vbuf.SetBytes(v.v)
+
+	_, err := p.s.initServer(sx, v, ih, vbuf, nil, A)
+	sx.vbuf = vbuf
+	if err != nil {
+		p.Put(sx)
+		return nil, err
+	}
+	return sx, nil
+}
+
+// Put wipes 'sx' and returns it to the pool
+func (p *ServerPool) Put(sx *Server) {
+	if sx == nil {
+		return
+	}
+	sx.Wipe()
+	if sx.vbuf == nil {
+		sx.vbuf = new(big.Int)
+	}
+	p.p.Put(sx)
+}
+
+// Get returns a Client for identity 'I' and password 'pw'; it is the
+// pooled equivalent of SRP.NewClient().
+func (p *ClientPool) Get(I, pw []byte) (*Client, error) {
+	if err := p.s.checkPolicy(); err != nil {
+		return nil, err
+	}
+
+	c, _ := p.p.Get().(*Client)
+	if c == nil {
+		c = new(Client)
+	}
+	return p.s.initClient(c, I, pw), nil
+}
+
+// Put wipes 'c' and returns it to the pool
+func (p *ClientPool) Put(c *Client) {
+	if c == nil {
+		return
+	}
+	c.Wipe()
+	p.p.Put(c)
+}
+
+// Wipe zeroes the secrets of the client (the hashed password, the secret
+// ephemeral, the session key and the cached private key) and leaves it
+// unusable. Slices returned by RawKey() are zeroed too.
+func (c *Client) Wipe() {
+	wipe(c.p)
+	wipe(c.ip)
+	wipe(c.xK)
+	wipe(c.xM)
+	wipe(c.bind)
+	wipeInt(c.a)
+	wipeInt(c.xc.x)
+	*c = Client{}
+}
+
+// Wipe zeroes the secrets of the server (the session key and the expected
+// client proof) and leaves it unusable. Slices returned by RawKey() are
+// zeroed too. The verifier may be shared (e.g., by a DecodedVerifier) and
+// is only zeroed if the server owns it.
+func (s *Server) Wipe() {
+	wipe(s.xK)
+	wipe(s.xM)
+	wipe(s.bind)
+	for _, k := range s.ak {
+		wipe(k.K)
+	}
+	wipeInt(s.vbuf)
+	*s = Server{vbuf: s.vbuf}
+}
+
+// zero the bytes of 'b'
+func wipe(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+// zero the words of 'x' and set it to 0
+func wipeInt(x *big.Int) {
+	if x == nil {
+		return
+	}
+	w := x.Bits()
+	for i := range w {
+		w[i] = 0
+	}
+	x.SetInt64(0)
+}
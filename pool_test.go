@@ -0,0 +1,125 @@
+// self test for pooled clients and servers
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestPools(t *testing.T) {
+	assert := newAsserter(t)
+
+	s, err := New(2048)
+	assert(err == nil, "New: %s", err)
+
+	v, err := s.Verifier([]byte("user"), []byte("pass"), nil)
+	assert(err == nil, "Verifier: %s", err)
+
+	cp := s.NewClientPool()
+	sp := s.NewServerPool()
+
+	for i := 0; i < 4; i++ {
+		c, err := cp.Get([]byte("user"), []byte("pass"))
+		assert(err == nil, "Get client: %s", err)
+
+		hello := c.Hello()
+		A, err := s.ParsePublicKey(hello.A)
+		assert(err == nil, "A: %s", err)
+
+		srv, err := sp.GetFor(hello.IdentityHash, v, A)
+		assert(err == nil, "Get server: %s", err)
+
+		m, err := c.Respond(srv.Challenge())
+		assert(err == nil, "Respond: %s", err)
+
+		proof, ok := srv.CheckProof(m)
+		assert(ok, "%d: client proof rejected", i)
+		assert(c.CheckProof(proof), "%d: server proof rejected", i)
+
+		K := srv.RawKey()
+		cK := c.RawKey()
+		assert(ctEqual(K, cK), "%d: key mismatch", i)
+
+		sp.Put(srv)
+		cp.Put(c)
+
+		for _, b := range [][]byte{K, cK} {
+			for _, x := range b {
+				assert(x == 0, "%d: key not wiped", i)
+			}
+		}
+	}
+
+	// the verifier is never modified by a pooled server
+	w, err := s.Verifier([]byte("user"), []byte("pass"), v.s)
+	assert(err == nil, "Verifier: %s", err)
+	assert(ctEqual(v.v, w.v), "verifier modified")
+
+	// a failed Get leaves nothing behind
+	_, err = sp.Get(v, big.NewInt(0))
+	assert(err != nil, "zero A accepted")
+}
+
+func benchmarkLogins(b *testing.B, pooled bool) {
+	s, err := NewDefault()
+	if err != nil {
+		b.Fatalf("NewDefault: %s", err)
+	}
+	v, err := s.Verifier([]byte("user"), []byte("pass"), nil)
+	if err != nil {
+		b.Fatalf("Verifier: %s", err)
+	}
+
+	cp := s.NewClientPool()
+	sp := s.NewServerPool()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var c *Client
+		if pooled {
+			c, err = cp.Get([]byte("user"), []byte("pass"))
+		} else {
+			c, err = s.NewClient([]byte("user"), []byte("pass"))
+		}
+		if err != nil {
+			b.Fatalf("client: %s", err)
+		}
+
+		var srv *Server
+		if pooled {
+			srv, err = sp.Get(v, c.xA)
+		} else {
+			srv, err = s.NewServer(v, c.xA)
+		}
+		if err != nil {
+			b.Fatalf("server: %s", err)
+		}
+
+		m, err := c.Respond(srv.Challenge())
+		if err != nil {
+			b.Fatalf("Respond: %s", err)
+		}
+		if _, ok := srv.CheckProof(m); !ok {
+			b.Fatalf("proof rejected")
+		}
+
+		if pooled {
+			sp.Put(srv)
+			cp.Put(c)
+		}
+	}
+}
+
+func BenchmarkLogin(b *testing.B) {
+	benchmarkLogins(b, false)
+}
+
+func BenchmarkPooledLogin(b *testing.B) {
+	benchmarkLogins(b, true)
+}
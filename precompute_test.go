@@ -0,0 +1,112 @@
+// self test for precomputed verifier powers
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"crypto"
+	"fmt"
+	"math/big"
+	"testing"
+)
+
+func TestFixedBase(t *testing.T) {
+	assert := newAsserter(t)
+
+	s, err := New(2048)
+	assert(err == nil, "New: %s", err)
+	N := s.pf.N
+	v := randBigInt(s.random(), 2048)
+
+	for _, w := range []int{1, 3, 4, 5, 8} {
+		fb := newFixedBase(MathBig, v, N, 256, w)
+		for _, e := range []*big.Int{
+			big.NewInt(0),
+			big.NewInt(1),
+			big.NewInt(0).Lsh(big.NewInt(1), 255),
+			big.NewInt(0).Sub(big.NewInt(0).Lsh(big.NewInt(1), 256), big.NewInt(1)),
+			randBigInt(s.random(), 256),
+			randBigInt(s.random(), 512), // beyond the table
+		} {
+			exp := big.NewInt(0).Exp(v, e, N)
+			assert(fb.exp(MathBig, e).Cmp(exp) == 0, "w %d: wrong v^%x", w, e)
+		}
+	}
+}
+
+func TestPrecompute(t *testing.T) {
+	assert := newAsserter(t)
+
+	s, err := NewDefault()
+	assert(err == nil, "New: %s", err)
+	v, err := s.Verifier([]byte("user"), []byte("pass"), nil)
+	assert(err == nil, "Verifier: %s", err)
+
+	d := NewDecodedVerifier(s, v)
+	assert(d.PrecomputedSize() == 0, "table before Precompute()")
+	assert(d.Precompute(9) != nil, "window 9 accepted")
+	assert(d.Precompute(-1) != nil, "window -1 accepted")
+
+	for _, w := range []int{0, 1, 6} {
+		assert(d.Precompute(w) == nil, "Precompute(%d)", w)
+		assert(d.PrecomputedSize() > 0, "no table")
+
+		for _, pw := range []string{"pass", "wrong"} {
+			c, err := s.NewClient([]byte("user"), []byte(pw))
+			assert(err == nil, "NewClient: %s", err)
+			srv, err := d.NewServer(c.xA)
+			assert(err == nil, "NewServer: %s", err)
+			assert(authenticate(c, srv) == (pw == "pass"), "window %d, password %s: wrong result", w, pw)
+		}
+	}
+}
+
+// measures the numbers in the comment of precompute.go
+func BenchmarkPrecomputed(b *testing.B) {
+	for _, g := range []struct{ bits, eph int }{{2048, DefaultEphemeralBits}, {4096, DefaultEphemeralBits}, {2048, 2048}} {
+		bits := g.bits
+		s, err := NewWithHash(crypto.SHA256, bits, withEphemeralBits(g.eph))
+		if err != nil {
+			b.Fatalf("New: %s", err)
+		}
+		v, err := s.Verifier([]byte("user"), []byte("pass"), nil)
+		if err != nil {
+			b.Fatalf("Verifier: %s", err)
+		}
+		c, err := s.NewClient([]byte("user"), []byte("pass"))
+		if err != nil {
+			b.Fatalf("NewClient: %s", err)
+		}
+		u := randBigInt(s.random(), 256)
+
+		for _, w := range []int{0, 4, 6} {
+			d := NewDecodedVerifier(s, v)
+			name := fmt.Sprintf("%d/eph%d/none", bits, g.eph)
+			if w > 0 {
+				d.Precompute(w)
+				name = fmt.Sprintf("%d/eph%d/w%d", bits, g.eph, w)
+			}
+
+			b.Run(name+"/vu", func(b *testing.B) {
+				b.ReportMetric(float64(d.PrecomputedSize()), "table-bytes")
+				for i := 0; i < b.N; i++ {
+					if vt := d.table(); vt != nil {
+						vt.exp(MathBig, u)
+					} else {
+						MathBig.Exp(d.v, u, s.pf.N)
+					}
+				}
+			})
+			b.Run(name+"/NewServer", func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					if _, err := d.NewServer(c.xA); err != nil {
+						b.Fatalf("NewServer: %s", err)
+					}
+				}
+			})
+		}
+	}
+}
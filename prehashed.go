@@ -0,0 +1,76 @@
+// prehashed.go - clients and verifiers from precomputed hashes
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"fmt"
+)
+
+// The identity and the password enter SRP only through their hashes
+// H(I) and H(p) under the hash function of the environment (see
+// Verifier()). Callers that compute these elsewhere, e.g., in a secure
+// enclave or another service that never releases the password, pass them
+// to NewClientPrehashed() and VerifierPrehashed(), which don't hash them
+// again: given
+//
+//	ih = H(NormalizeIdentity(I)), ph = H(p)
+//
+// they produce the same clients and verifiers as NewClient(I, p) and
+// Verifier(I, p, salt). What needs I or p itself isn't available: password
+// policies, deterministic salts (WithDeterministicSalts()) and verifiers
+// imported from other libraries (see ImportVerifier()).
+
+// NewClientPrehashed is NewClient() for the hashed identity 'ih' and the
+// hashed password 'ph' (see above).
+func (s *SRP) NewClientPrehashed(ih, ph []byte) (*Client, error) {
+	if err := s.checkPrehashed(ih, ph); err != nil {
+		return nil, err
+	}
+	if err := s.checkPolicy(); err != nil {
+		return nil, err
+	}
+
+	return s.initHashedClient(new(Client), dup(ih), dup(ph), nil), nil
+}
+
+// VerifierPrehashed is Verifier() for the hashed identity 'ih' and the
+// hashed password 'ph' (see above); 'sel' is the salt or nil for a random
+// one.
+func (s *SRP) VerifierPrehashed(ih, ph, sel []byte) (*Verifier, error) {
+	if err := s.checkPrehashed(ih, ph); err != nil {
+		return nil, err
+	}
+	if s.pp != nil {
+		return nil, fmt.Errorf("srp: password policy can't check a hashed password")
+	}
+	if len(sel) == 0 && s.seed != nil {
+		return nil, fmt.Errorf("srp: deterministic salts need the identity, not its hash")
+	}
+
+	return s.prehashedVerifier(dup(ih), dup(ph), dup(sel))
+}
+
+// return an error unless 'ih' and 'ph' are as large as the hash of this
+// environment
+func (s *SRP) checkPrehashed(ih, ph []byte) error {
+	n := newHash(s.h).Size()
+	if len(ih) != n {
+		return fmt.Errorf("srp: hashed identity must be %d bytes, saw %d", n, len(ih))
+	}
+	if len(ph) != n {
+		return fmt.Errorf("srp: hashed password must be %d bytes, saw %d", n, len(ph))
+	}
+	return nil
+}
+
+// return a copy of 'b'; nil stays nil
+func dup(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	return append([]byte{}, b...)
+}
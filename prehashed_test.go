@@ -0,0 +1,65 @@
+// self test for clients and verifiers from precomputed hashes
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"bytes"
+	"crypto"
+	"testing"
+)
+
+func TestPrehashed(t *testing.T) {
+	assert := newAsserter(t)
+
+	s, err := NewWithHash(crypto.SHA256, 2048, WithIdentityCaseFolding())
+	assert(err == nil, "NewWithHash: %s", err)
+
+	I, p := []byte("User"), []byte("pass")
+	ih := hashWith(crypto.SHA256, s.NormalizeIdentity(I))
+	ph := hashWith(crypto.SHA256, p)
+
+	// the same verifier as from the password
+	v1, err := s.Verifier(I, p, []byte("salt"))
+	assert(err == nil, "Verifier: %s", err)
+	v2, err := s.VerifierPrehashed(ih, ph, []byte("salt"))
+	assert(err == nil, "VerifierPrehashed: %s", err)
+	assert(bytes.Equal(v1.v, v2.v) && bytes.Equal(v1.i, v2.i), "verifiers differ")
+
+	// clients from hashes and passwords log in with either verifier
+	v, err := s.VerifierPrehashed(ih, ph, nil)
+	assert(err == nil, "VerifierPrehashed: %s", err)
+	for _, v := range []*Verifier{v1, v} {
+		c, err := s.NewClientPrehashed(ih, ph)
+		assert(err == nil, "NewClientPrehashed: %s", err)
+		srv, err := s.NewServer(v, c.xA)
+		assert(err == nil, "NewServer: %s", err)
+		assert(authenticate(c, srv), "prehashed login failed")
+
+		c, err = s.NewClient(I, p)
+		assert(err == nil, "NewClient: %s", err)
+		srv, err = s.NewServer(v, c.xA)
+		assert(err == nil, "NewServer: %s", err)
+		assert(authenticate(c, srv), "login failed")
+	}
+
+	// the hashes are copied
+	c, err := s.NewClientPrehashed(ih, ph)
+	assert(err == nil, "NewClientPrehashed: %s", err)
+	ph[0] ^= 1
+	srv, _ := s.NewServer(v, c.xA)
+	assert(authenticate(c, srv), "client kept the caller's buffer")
+
+	_, err = s.NewClientPrehashed(ih[:16], ph)
+	assert(err != nil, "short identity hash accepted")
+	_, err = s.VerifierPrehashed(ih, ph[:16], nil)
+	assert(err != nil, "short password hash accepted")
+
+	ds, err := NewWithHash(crypto.SHA256, 2048, WithDeterministicSalts(make([]byte, 32)))
+	assert(err == nil, "NewWithHash: %s", err)
+	_, err = ds.VerifierPrehashed(ih, ph, nil)
+	assert(err != nil, "deterministic salt without identity")
+}
@@ -0,0 +1,148 @@
+// primitives.go - the intermediate values of SRP-6a
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"crypto"
+	"math/big"
+)
+
+// The functions below compute the individual steps of a handshake in this
+// environment exactly as Client and Server do. They are meant for
+// reproducing a handshake step by step, e.g., when debugging interop with
+// another SRP implementation; applications should use Client and Server.
+
+// ComputeX returns the private key x = H(H(I), H(p), s) for identity 'I',
+// password 'p' and salt 's'. If the environment has a KDF, the hashed
+// password is hardened first: x = H(H(I), KDF(H(p), s), s). With
+// WithHMACPrivateKey(), x = HMAC(s, H(I) || H(p)).
+func (s *SRP) ComputeX(I, p, salt []byte) *big.Int {
+	return s.privateKey(s.hashbyte(s.identity(I)), s.hashbyte(p), salt, s.kdf, s.xd)
+}
+
+// ComputeVerifier returns the verifier v = g^x % N
+func (s *SRP) ComputeVerifier(x *big.Int) *big.Int {
+	return s.arith().Exp(s.pf.g, x, s.pf.N)
+}
+
+// ComputeK returns the multiplier k = H(N, pad(g))
+func (s *SRP) ComputeK() *big.Int {
+	return big.NewInt(0).Set(s.multiplier())
+}
+
+// Multiplier returns the multiplier k = H(N, pad(g)) of this environment;
+// it is computed once and shared by all its handshakes.
+func (s *SRP) Multiplier() *big.Int {
+	return s.ComputeK()
+}
+
+// return the cached k; the caller must not modify it
+func (s *SRP) multiplier() *big.Int {
+	s.precompute()
+	return s.k
+}
+
+// return the cached H(N) xor H(g); the caller must not modify it
+func (s *SRP) hashNG() []byte {
+	s.precompute()
+	return s.hng
+}
+
+// compute the values that are constant for all handshakes of this
+// environment
+func (s *SRP) precompute() {
+	s.once.Do(func() {
+		pf := s.pf
+		s.k = s.hashint(pf.N.Bytes(), pad(pf.g, pf.n))
+		s.hng = xorNG(s.h, pf)
+	})
+}
+
+// return H(N) xor H(g) of the prime field 'pf' with the hash 'h'
+func xorNG(h crypto.Hash, pf *primeField) []byte {
+	hn := hashWith(h, pf.N.Bytes())
+	hg := hashWith(h, pf.g.Bytes())
+	for i := range hn {
+		hn[i] ^= hg[i]
+	}
+	return hn
+}
+
+// ComputeU returns the scrambling parameter u = H(pad(A), pad(B))
+func (s *SRP) ComputeU(A, B *big.Int) *big.Int {
+	pf := s.pf
+	return s.hashint(pad(A, pf.n), pad(B, pf.n))
+}
+
+// ComputeB returns the server public key B = (kv + g^b) % N for the secret
+// ephemeral 'b' and verifier 'v'.
+func (s *SRP) ComputeB(b, v *big.Int) *big.Int {
+	pf := s.pf
+	ar := s.arith()
+	t0 := ar.Mul(s.multiplier(), v, pf.N)
+	return ar.Add(t0, ar.Exp(pf.g, b, pf.N), pf.N)
+}
+
+// ComputeClientS returns the shared secret computed by the client:
+// S = (B - kg^x) ^ (a + ux) % N
+func (s *SRP) ComputeClientS(a, x, u, B *big.Int) *big.Int {
+	S, _ := s.clientS(a, x, u, B)
+	return S
+}
+
+// ComputeServerS returns the shared secret computed by the server:
+// S = (Av^u) ^ b % N
+func (s *SRP) ComputeServerS(b, v, u, A *big.Int) *big.Int {
+	S, _ := s.serverS(b, v, u, A)
+	return S
+}
+
+// return the client's shared secret and an error if it or its base
+// is degenerate
+func (s *SRP) clientS(a, x, u, B *big.Int) (*big.Int, error) {
+	pf := s.pf
+	ar := s.arith()
+	t0 := ar.Mul(ar.Exp(pf.g, x, pf.N), s.multiplier(), pf.N)
+
+	t1 := ar.Sub(B, t0, pf.N)
+	t2 := big.NewInt(0).Add(a, big.NewInt(0).Mul(u, x))
+	return checkS(ar.Exp(t1, t2, pf.N), t1, pf.N)
+}
+
+// return the server's shared secret and an error if it or its base
+// is degenerate
+func (s *SRP) serverS(b, v, u, A *big.Int) (*big.Int, error) {
+	return s.serverSFrom(b, s.arith().Exp(v, u, s.pf.N), A)
+}
+
+// return S = (A * vu) ^ b % N for vu = v^u and an error if it is
+// degenerate
+func (s *SRP) serverSFrom(b, vu, A *big.Int) (*big.Int, error) {
+	pf := s.pf
+	ar := s.arith()
+	t0 := ar.Mul(A, vu, pf.N)
+	return checkS(ar.Exp(t0, b, pf.N), t0, pf.N)
+}
+
+// return 'S' and an error if 'S' or the 'base' it was raised from
+// is degenerate
+func checkS(S, base, N *big.Int) (*big.Int, error) {
+	if degenerate(base, N) {
+		return S, abort(AbortDegenerateBase)
+	}
+	if S.Sign() == 0 || S.Cmp(one) == 0 {
+		return S, abort(AbortDegenerateS)
+	}
+	return S, nil
+}
+
+// ComputeSessionKey returns the session key K = H(S); proof schemes that
+// pad (e.g., ProofRFC5054Padded) use K = H(pad(S)). K is resized with
+// WithSessionKeyBits().
+func (s *SRP) ComputeSessionKey(S *big.Int) []byte {
+	return s.sessionKey(S)
+}
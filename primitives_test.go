@@ -0,0 +1,88 @@
+// self test for the SRP primitives
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+)
+
+func TestPrimitives(t *testing.T) {
+	assert := newAsserter(t)
+
+	I := []byte("user")
+	p := []byte("pass")
+	salt := []byte("0123456789abcdef")
+
+	s, err := New(2048)
+	assert(err == nil, "New: %s", err)
+
+	vf, err := s.Verifier(I, p, salt)
+	assert(err == nil, "Verifier: %s", err)
+
+	x := s.ComputeX(I, p, salt)
+	v := s.ComputeVerifier(x)
+	assert(v.Cmp(big.NewInt(0).SetBytes(vf.v)) == 0, "verifier mismatch")
+
+	a := randBigInt(rand.Reader, 256)
+	b := randBigInt(rand.Reader, 256)
+	A := s.ComputeVerifier(a) // g^a
+	B := s.ComputeB(b, v)
+	u := s.ComputeU(A, B)
+
+	cS := s.ComputeClientS(a, x, u, B)
+	sS := s.ComputeServerS(b, v, u, A)
+	assert(cS.Cmp(sS) == 0, "S mismatch:\nclient %x\nserver %x", cS, sS)
+
+	// the primitives must agree with a real handshake
+	c, err := s.NewClient(I, p)
+	assert(err == nil, "NewClient: %s", err)
+
+	srv, err := s.NewServer(vf, c.xA)
+	assert(err == nil, "NewServer: %s", err)
+
+	_, err = c.Respond(srv.Challenge())
+	assert(err == nil, "Respond: %s", err)
+
+	B = big.NewInt(0).SetBytes(srv.Challenge().B)
+	S := s.ComputeClientS(c.a, x, s.ComputeU(c.xA, B), B)
+	assert(ctEqual(s.ComputeSessionKey(S), c.RawKey()), "client key mismatch")
+	assert(ctEqual(s.ComputeSessionKey(S), srv.RawKey()), "server key mismatch")
+}
+
+func TestMultiplier(t *testing.T) {
+	assert := newAsserter(t)
+
+	s, err := New(2048)
+	assert(err == nil, "New: %s", err)
+
+	pf := s.pf
+	k := s.hashint(pf.N.Bytes(), pad(pf.g, pf.n))
+	assert(s.Multiplier().Cmp(k) == 0, "wrong multiplier")
+
+	// callers get copies
+	s.Multiplier().SetInt64(1)
+	s.ComputeK().SetInt64(1)
+	assert(s.Multiplier().Cmp(k) == 0, "multiplier was modified")
+
+	// the environment is shared by concurrent handshakes
+	user := []byte("user")
+	pass := []byte("pass")
+	db := newUserDBFrom(t, s, user, pass)
+
+	done := make(chan bool)
+	for i := 0; i < 4; i++ {
+		go func() {
+			db.verify(t, user, pass, true)
+			done <- true
+		}()
+	}
+	for i := 0; i < 4; i++ {
+		<-done
+	}
+}
@@ -0,0 +1,301 @@
+// proof.go - constructions of the mutual authenticators M and M'
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"crypto"
+	"fmt"
+	"math/big"
+)
+
+// Transcript holds the public values of a handshake and the session key
+// K; it is the input to a ProofScheme.
+type Transcript struct {
+	N, G    *big.Int // copies of the group; see primeField
+	I       []byte   // hashed identity
+	Salt    []byte
+	A, B    *big.Int
+	K       []byte
+	Context []byte // see WithTranscriptContext() and WithSessionKeyBits()
+
+	s *SRP
+	h crypto.Hash // of K and the proofs; see WithProofHash()
+}
+
+// H hashes the concatenation of 'a' with the hash function of the proofs
+func (t *Transcript) H(a ...[]byte) []byte {
+	return hashWith(t.h, a...)
+}
+
+// return H(N) xor H(g) with the hash function of the proofs
+func (t *Transcript) hashNG() []byte {
+	if t.h == t.s.h {
+		return t.s.hashNG()
+	}
+	return xorNG(t.h, t.s.pf)
+}
+
+// Pad returns 'x' left-padded with zeros to the width of N
+func (t *Transcript) Pad(x *big.Int) []byte {
+	return pad(x, t.s.pf.n)
+}
+
+// return 'x' as hashed by a scheme that pads (or not) its numbers
+func (t *Transcript) num(x *big.Int, padded bool) []byte {
+	if padded {
+		return t.Pad(x)
+	}
+	return x.Bytes()
+}
+
+// ProofScheme computes the proof M that a client sends to the server and
+// the proof the server returns in reply. Both sides must use the same
+// scheme; a server can accept several while clients migrate (see
+// WithAcceptedProofSchemes()).
+type ProofScheme interface {
+	// Name identifies the scheme, e.g., in logs and metrics
+	Name() string
+
+	// ClientProof returns the client's proof M
+	ClientProof(t *Transcript) []byte
+
+	// ServerProof returns the server's proof for the client proof 'M'
+	ServerProof(t *Transcript, M []byte) []byte
+}
+
+// Proof schemes provided by this package
+var (
+	// ProofLegacy is the construction used by this package since its
+	// first release and is the default:
+	//
+	//	M  = H(K, A, B, I, s, N, g)
+	//	M' = H(K, M)
+	ProofLegacy ProofScheme = legacyProof{}
+
+	// ProofRFC5054 is the construction of RFC 2945 and RFC 5054:
+	//
+	//	M  = H(H(N) xor H(g), H(I), s, A, B, K)
+	//	M' = H(A, M, K)
+	ProofRFC5054 ProofScheme = rfcProof{}
+
+	// ProofLegacyPadded is ProofLegacy with A and B hashed left-padded to
+	// the width of N (as in k and u). The session key is derived from the
+	// padded S as well: K = H(pad(S)).
+	ProofLegacyPadded ProofScheme = legacyProof{padded: true}
+
+	// ProofRFC5054Padded is ProofRFC5054 with A, B and S padded like
+	// ProofLegacyPadded. Most other SRP-6a implementations need this.
+	ProofRFC5054Padded ProofScheme = rfcProof{padded: true}
+)
+
+// paddedKey is implemented by schemes that derive the session key from the
+// padded S.
+type paddedKey interface {
+	padsKey() bool
+}
+
+// WithProofScheme makes clients and servers in this environment compute
+// proofs with 'p' instead of ProofLegacy.
+func WithProofScheme(p ProofScheme) Option {
+	return func(s *SRP) error {
+		if p == nil {
+			return fmt.Errorf("srp: nil proof scheme")
+		}
+		s.ps = p
+		return nil
+	}
+}
+
+// WithAcceptedProofSchemes makes servers in this environment also accept
+// client proofs computed with any of 'alt' during a migration window; the
+// server replies using the scheme that matched (see Server.ProofScheme()).
+// The scheme set by WithProofScheme() is always tried first. Cutting over
+// is done by dropping this option. Alternates must derive the session key
+// the same way as the main scheme (i.e., both padded or both not); others
+// are ignored.
+func WithAcceptedProofSchemes(alt ...ProofScheme) Option {
+	return func(s *SRP) error {
+		for _, p := range alt {
+			if p == nil {
+				return fmt.Errorf("srp: nil proof scheme")
+			}
+		}
+		s.alt = append([]ProofScheme{}, alt...)
+		return nil
+	}
+}
+
+// WithoutIdentity returns a variant of 'p' that leaves the identity I out
+// of the proofs; the variant is named after 'p' with the suffix "-noid".
+// See WithIdentityFreeProofs() for the trade-offs.
+func WithoutIdentity(p ProofScheme) ProofScheme {
+	if _, ok := p.(noidProof); ok {
+		return p
+	}
+	return noidProof{p}
+}
+
+// WithIdentityFreeProofs leaves the identity I out of the proofs computed
+// and accepted in this environment (including those of
+// WithAcceptedProofSchemes() and the key confirmation messages). It is a
+// privacy mode for deployments that carry the identity out-of-band and
+// must not let it influence the comparison of proofs, e.g., when the
+// identity seen by the server is a per-session alias.
+//
+// The identity is still bound to the session through x and the verifier
+// v: a client that uses another identity derives another x, and thus
+// another session key, and fails. What is lost is the explicit binding of
+// the identity in M; two users who share a salt and password hash (which
+// the per-user salts prevent) would be indistinguishable in the proofs.
+// Both sides must use the mode.
+func WithIdentityFreeProofs() Option {
+	return func(s *SRP) error {
+		s.noid = true
+		return nil
+	}
+}
+
+// noidProof implements WithoutIdentity()
+type noidProof struct {
+	p ProofScheme
+}
+
+func (n noidProof) Name() string {
+	return n.p.Name() + "-noid"
+}
+
+func (n noidProof) ClientProof(t *Transcript) []byte {
+	return n.p.ClientProof(t.withoutIdentity())
+}
+
+func (n noidProof) ServerProof(t *Transcript, M []byte) []byte {
+	return n.p.ServerProof(t.withoutIdentity(), M)
+}
+
+func (n noidProof) padsKey() bool {
+	return padsKey(n.p)
+}
+
+// return a copy of this transcript without the identity
+func (t *Transcript) withoutIdentity() *Transcript {
+	u := *t
+	u.I = nil
+	return &u
+}
+
+// legacyProof implements ProofLegacy and ProofLegacyPadded
+type legacyProof struct {
+	padded bool
+}
+
+func (p legacyProof) Name() string {
+	if p.padded {
+		return "legacy-padded"
+	}
+	return "legacy"
+}
+
+func (p legacyProof) ClientProof(t *Transcript) []byte {
+	A := t.num(t.A, p.padded)
+	B := t.num(t.B, p.padded)
+	v := [][]byte{t.K, A, B, t.I, t.Salt, t.N.Bytes(), t.G.Bytes()}
+	if len(t.Context) > 0 {
+		v = append(v, t.H(t.Context))
+	}
+	return t.H(v...)
+}
+
+func (legacyProof) ServerProof(t *Transcript, M []byte) []byte {
+	return t.H(t.K, M)
+}
+
+func (p legacyProof) padsKey() bool {
+	return p.padded
+}
+
+// rfcProof implements ProofRFC5054 and ProofRFC5054Padded
+type rfcProof struct {
+	padded bool
+}
+
+func (p rfcProof) Name() string {
+	if p.padded {
+		return "rfc5054-padded"
+	}
+	return "rfc5054"
+}
+
+func (p rfcProof) ClientProof(t *Transcript) []byte {
+	A := t.num(t.A, p.padded)
+	B := t.num(t.B, p.padded)
+	v := [][]byte{t.hashNG(), t.H(t.I), t.Salt, A, B, t.K}
+	if len(t.Context) > 0 {
+		v = append(v, t.H(t.Context))
+	}
+	return t.H(v...)
+}
+
+func (p rfcProof) ServerProof(t *Transcript, M []byte) []byte {
+	return t.H(t.num(t.A, p.padded), M, t.K)
+}
+
+func (p rfcProof) padsKey() bool {
+	return p.padded
+}
+
+// return the proof scheme of this environment
+func (s *SRP) scheme() ProofScheme {
+	p := s.ps
+	if p == nil {
+		p = ProofLegacy
+	}
+	return s.variant(p)
+}
+
+// return the variant of 'p' used in this environment
+func (s *SRP) variant(p ProofScheme) ProofScheme {
+	if s.noid {
+		return WithoutIdentity(p)
+	}
+	return p
+}
+
+// return true if the scheme 'p' derives the session key from the padded S
+func padsKey(p ProofScheme) bool {
+	pk, ok := p.(paddedKey)
+	return ok && pk.padsKey()
+}
+
+// derive the session key K from the shared secret 'S'
+func (s *SRP) sessionKey(S *big.Int) []byte {
+	return s.sessionKeyWith(S, s.proofHash())
+}
+
+// derive the session key K from the shared secret 'S' with the hash 'h'
+func (s *SRP) sessionKeyWith(S *big.Int, h crypto.Hash) []byte {
+	n := 0
+	if padsKey(s.scheme()) {
+		n = s.pf.n
+	}
+	return s.sizeKey(hashWith(h, s.arith().Bytes(S, n)))
+}
+
+// return the transcript of a handshake in this environment
+func (s *SRP) transcript(K []byte, A, B *big.Int, I, salt []byte) *Transcript {
+	return &Transcript{
+		N:       big.NewInt(0).Set(s.pf.N),
+		G:       big.NewInt(0).Set(s.pf.g),
+		I:       I,
+		Salt:    salt,
+		A:       A,
+		B:       B,
+		K:       K,
+		Context: s.context(),
+		s:       s,
+		h:       s.proofHash(),
+	}
+}
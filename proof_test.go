@@ -0,0 +1,181 @@
+// self test for proof schemes
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestProofSchemes(t *testing.T) {
+	assert := newAsserter(t)
+
+	user := []byte("user")
+	pass := []byte("pass")
+
+	legacy := []Option{}
+	rfc := []Option{WithProofScheme(ProofRFC5054)}
+	lpad := []Option{WithProofScheme(ProofLegacyPadded)}
+	rpad := []Option{WithProofScheme(ProofRFC5054Padded)}
+	migrating := []Option{WithProofScheme(ProofRFC5054), WithAcceptedProofSchemes(ProofLegacy)}
+
+	tests := []struct {
+		client, server []Option
+		ok             bool
+		used           ProofScheme
+	}{
+		{legacy, legacy, true, ProofLegacy},
+		{rfc, rfc, true, ProofRFC5054},
+		{legacy, rfc, false, nil},
+		{rfc, legacy, false, nil},
+		{lpad, lpad, true, ProofLegacyPadded},
+		{rpad, rpad, true, ProofRFC5054Padded},
+
+		// during the migration window both kinds of clients succeed
+		{legacy, migrating, true, ProofLegacy},
+		{rfc, migrating, true, ProofRFC5054},
+	}
+
+	for i, x := range tests {
+		cs, err := New(2048, x.client...)
+		assert(err == nil, "New: %s", err)
+
+		v, err := cs.Verifier(user, pass, nil)
+		assert(err == nil, "Verifier: %s", err)
+		_, vh := v.Encode()
+
+		c, err := cs.NewClient(user, pass)
+		assert(err == nil, "NewClient: %s", err)
+
+		_, A, err := ServerBegin(c.Credentials())
+		assert(err == nil, "ServerBegin: %s", err)
+
+		ss, sv, err := MakeSRPVerifier(vh, x.server...)
+		assert(err == nil, "MakeSRPVerifier: %s", err)
+
+		srv, err := ss.NewServer(sv, A)
+		assert(err == nil, "NewServer: %s", err)
+
+		m, err := c.Generate(srv.Credentials())
+		assert(err == nil, "Generate: %s", err)
+
+		// the scheme must survive a marshaled server
+		srv, err = UnmarshalServer(srv.Marshal(), x.server...)
+		assert(err == nil, "UnmarshalServer: %s", err)
+
+		proof, ok := srv.ClientOk(m)
+		assert(ok == x.ok, "%d: exp %v, saw %v", i, x.ok, ok)
+		assert(srv.ProofScheme() == x.used, "%d: wrong scheme %v", i, srv.ProofScheme())
+		if ok {
+			assert(c.ServerOk(proof), "%d: bad server proof", i)
+		}
+	}
+}
+
+func TestPaddedProofs(t *testing.T) {
+	assert := newAsserter(t)
+
+	s, err := New(2048)
+	assert(err == nil, "New: %s", err)
+
+	// a short A must be hashed differently when padded
+	tr := s.transcript([]byte("K"), big.NewInt(2), big.NewInt(3), []byte("I"), []byte("s"))
+	pairs := [][2]ProofScheme{
+		{ProofLegacy, ProofLegacyPadded},
+		{ProofRFC5054, ProofRFC5054Padded},
+	}
+	for _, p := range pairs {
+		m0 := p[0].ClientProof(tr)
+		m1 := p[1].ClientProof(tr)
+		assert(!ctEqual(m0, m1), "%s: same proof as %s", p[1].Name(), p[0].Name())
+	}
+
+	m := ProofRFC5054.ClientProof(tr)
+	assert(!ctEqual(ProofRFC5054.ServerProof(tr, m), ProofRFC5054Padded.ServerProof(tr, m)), "server proof isn't padded")
+
+	assert(padsKey(ProofRFC5054Padded) && !padsKey(ProofRFC5054), "padsKey mismatch")
+}
+
+func TestIdentityFreeProofs(t *testing.T) {
+	assert := newAsserter(t)
+
+	user := []byte("user")
+	pass := []byte("pass")
+
+	noid := []Option{WithIdentityFreeProofs()}
+	migrating := []Option{WithAcceptedProofSchemes(WithoutIdentity(ProofLegacy))}
+
+	tests := []struct {
+		client, server []Option
+		ok             bool
+		used           string
+	}{
+		{noid, noid, true, "legacy-noid"},
+		{nil, noid, false, ""},
+		{noid, nil, false, ""},
+		{noid, migrating, true, "legacy-noid"},
+		{append([]Option{WithProofScheme(ProofRFC5054Padded)}, noid...),
+			append([]Option{WithProofScheme(ProofRFC5054Padded)}, noid...), true, "rfc5054-padded-noid"},
+	}
+
+	for i, x := range tests {
+		cs, err := New(2048, x.client...)
+		assert(err == nil, "New: %s", err)
+
+		v, err := cs.Verifier(user, pass, nil)
+		assert(err == nil, "Verifier: %s", err)
+		_, vh := v.Encode()
+
+		c, err := cs.NewClient(user, pass)
+		assert(err == nil, "NewClient: %s", err)
+
+		_, A, err := ServerBegin(c.Credentials())
+		assert(err == nil, "ServerBegin: %s", err)
+
+		ss, sv, err := MakeSRPVerifier(vh, x.server...)
+		assert(err == nil, "MakeSRPVerifier: %s", err)
+
+		srv, err := ss.NewServer(sv, A)
+		assert(err == nil, "NewServer: %s", err)
+
+		m, err := c.Generate(srv.Credentials())
+		assert(err == nil, "Generate: %s", err)
+
+		proof, ok := srv.ClientOk(m)
+		assert(ok == x.ok, "%d: exp %v, saw %v", i, x.ok, ok)
+		if ok {
+			assert(srv.ProofScheme().Name() == x.used, "%d: wrong scheme %s", i, srv.ProofScheme().Name())
+			assert(c.ServerOk(proof), "%d: bad server proof", i)
+		}
+	}
+
+	// the proof doesn't depend on I but a wrong identity still fails
+	s, err := New(2048)
+	assert(err == nil, "New: %s", err)
+	tr := s.transcript([]byte("K"), big.NewInt(2), big.NewInt(3), []byte("I"), []byte("s"))
+	tr2 := s.transcript([]byte("K"), big.NewInt(2), big.NewInt(3), []byte("J"), []byte("s"))
+	p := WithoutIdentity(ProofLegacy)
+	assert(ctEqual(p.ClientProof(tr), p.ClientProof(tr2)), "identity in proof")
+	assert(WithoutIdentity(p) == p, "variant wrapped twice")
+	assert(padsKey(WithoutIdentity(ProofRFC5054Padded)), "variant lost padding")
+
+	v, err := s.Verifier(user, pass, nil)
+	assert(err == nil, "Verifier: %s", err)
+	_, vh := v.Encode()
+
+	cs, _ := New(2048, WithIdentityFreeProofs())
+	c, err := cs.NewClient([]byte("other"), pass)
+	assert(err == nil, "NewClient: %s", err)
+	_, A, _ := ServerBegin(c.Credentials())
+	ss, sv, _ := MakeSRPVerifier(vh, WithIdentityFreeProofs())
+	srv, err := ss.NewServer(sv, A)
+	assert(err == nil, "NewServer: %s", err)
+	m, err := c.Generate(srv.Credentials())
+	assert(err == nil, "Generate: %s", err)
+	_, ok := srv.ClientOk(m)
+	assert(!ok, "wrong identity accepted")
+}
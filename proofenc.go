@@ -0,0 +1,119 @@
+// proofenc.go - encodings of proofs in the string API
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+)
+
+// ProofEncoding is the encoding of the proofs exchanged as strings by
+// Client.Generate(), Server.ClientOk() and Client.ServerOk()
+type ProofEncoding int
+
+// Proof encodings
+const (
+	// ProofHex is lower case hex; the default
+	ProofHex ProofEncoding = iota
+
+	// ProofBase64URL is unpadded base64 with the URL alphabet (RFC 4648,
+	// section 5); it fits HTTP headers and JWT claims in 2/3 of the
+	// space of hex.
+	ProofBase64URL
+
+	// ProofRaw is the bytes of the proof as they are, for binary
+	// transports that use the string API.
+	ProofRaw
+)
+
+// String names the encoding
+func (e ProofEncoding) String() string {
+	switch e {
+	case ProofHex:
+		return "hex"
+	case ProofBase64URL:
+		return "base64url"
+	case ProofRaw:
+		return "raw"
+	}
+	return fmt.Sprintf("encoding %d", int(e))
+}
+
+// WithProofEncoding makes the string API of clients and servers in this
+// environment encode proofs with 'e' instead of hex. Both sides must use
+// the same encoding.
+func WithProofEncoding(e ProofEncoding) Option {
+	return func(s *SRP) error {
+		if e < ProofHex || e > ProofRaw {
+			return fmt.Errorf("srp: unknown proof encoding %d", int(e))
+		}
+		s.penc = e
+		return nil
+	}
+}
+
+// ProofSize returns the size in bytes of the proof the server returns and
+// of the client's proof without a puzzle solution: the size of the hash
+// (see WithProofHash()).
+func (s *SRP) ProofSize() int {
+	return newHash(s.proofHash()).Size()
+}
+
+// ServerProofLen returns the length of the server's proof in the string
+// API
+func (s *SRP) ServerProofLen() int {
+	return s.penc.encodedLen(s.ProofSize())
+}
+
+// ClientProofLen returns the length of the client's proof in the string
+// API, including the solution of a puzzle the server requires (see
+// WithClientPuzzle())
+func (s *SRP) ClientProofLen() int {
+	return s.penc.encodedLen(s.ProofSize() + s.solutionLen())
+}
+
+// EncodeProof returns the proof 'p' as encoded by the string API
+func (s *SRP) EncodeProof(p []byte) string {
+	switch s.penc {
+	case ProofBase64URL:
+		return base64.RawURLEncoding.EncodeToString(p)
+	case ProofRaw:
+		return string(p)
+	}
+	return hex.EncodeToString(p)
+}
+
+// DecodeProof decodes a proof 'm' of the string API
+func (s *SRP) DecodeProof(m string) ([]byte, error) {
+	var p []byte
+	var err error
+
+	switch s.penc {
+	case ProofBase64URL:
+		p, err = base64.RawURLEncoding.DecodeString(m)
+	case ProofRaw:
+		p = []byte(m)
+	default:
+		p, err = hex.DecodeString(m)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("srp: invalid %s proof", s.penc)
+	}
+	return p, nil
+}
+
+// return the length of 'n' bytes in this encoding
+func (e ProofEncoding) encodedLen(n int) int {
+	switch e {
+	case ProofBase64URL:
+		return base64.RawURLEncoding.EncodedLen(n)
+	case ProofRaw:
+		return n
+	}
+	return hex.EncodedLen(n)
+}
@@ -0,0 +1,63 @@
+// self test for proof encodings
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestProofEncodings(t *testing.T) {
+	assert := newAsserter(t)
+
+	for _, e := range []ProofEncoding{ProofHex, ProofBase64URL, ProofRaw} {
+		s, err := New(2048, WithProofEncoding(e))
+		assert(err == nil, "New: %s", err)
+
+		v, err := s.Verifier([]byte("user"), []byte("pass"), nil)
+		assert(err == nil, "Verifier: %s", err)
+		_, vh := v.Encode()
+
+		c, err := s.NewClient([]byte("user"), []byte("pass"))
+		assert(err == nil, "NewClient: %s", err)
+
+		_, A, err := ServerBegin(c.Credentials())
+		assert(err == nil, "ServerBegin: %s", err)
+
+		ss, sv, err := MakeSRPVerifier(vh, WithProofEncoding(e))
+		assert(err == nil, "MakeSRPVerifier: %s", err)
+		srv, err := ss.NewServer(sv, A)
+		assert(err == nil, "NewServer: %s", err)
+
+		m, err := c.Generate(srv.Credentials())
+		assert(err == nil, "%s: Generate: %s", e, err)
+		assert(len(m) == s.ClientProofLen(), "%s: client proof is %d long, exp %d", e, len(m), s.ClientProofLen())
+
+		proof, err := srv.VerifyClientProof(m)
+		assert(err == nil, "%s: VerifyClientProof: %s", e, err)
+		assert(len(proof) == s.ServerProofLen(), "%s: server proof is %d long", e, len(proof))
+		assert(c.ServerOk(proof), "%s: server proof rejected", e)
+
+		// a proof in another encoding is malformed
+		if e != ProofRaw {
+			srv, _ = ss.NewServer(sv, A)
+			_, err = srv.VerifyClientProof(m + "=")
+			assert(errors.Is(err, ErrMalformedProof), "%s: exp ErrMalformedProof, saw %v", e, err)
+		}
+	}
+
+	s, _ := New(2048)
+	assert(s.ProofSize() == 32 && s.ServerProofLen() == 64, "wrong default sizes")
+	assert(s.EncodeProof([]byte{0xfb, 0xff}) == "fbff", "wrong hex")
+
+	s, _ = New(2048, WithProofEncoding(ProofBase64URL))
+	assert(s.ServerProofLen() == 43, "base64url of 32 bytes is %d long", s.ServerProofLen())
+	assert(s.EncodeProof([]byte{0xfb, 0xff}) == "-_8", "not the URL alphabet")
+
+	_, err := New(2048, WithProofEncoding(ProofEncoding(7)))
+	assert(err != nil, "unknown encoding accepted")
+}
@@ -0,0 +1,150 @@
+// proofhash.go - hash functions of the session key and proofs
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"crypto"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// The hash function of an environment computes x (and thus the verifier),
+// the multiplier k and the scrambler u. A server commits to B = kv + g^b
+// before it sees the client's proof, so these must be the same on both
+// sides. The session key K = H(S) and the proofs only depend on the shared
+// secret S and the public values, so they can use another hash function
+// (see WithProofHash()), and a server can check a client proof under each
+// of several (see WithAcceptedProofHashes()) without a new verifier. This
+// lets a legacy fleet move its session keys and proofs from, e.g., SHA-1
+// to SHA-256 one client at a time:
+//
+//	legacy clients: NewWithHash(crypto.SHA1, bits)
+//	new clients:    NewWithHash(crypto.SHA1, bits, WithProofHash(crypto.SHA256))
+//	servers:        MakeSRPVerifier(vh, WithAcceptedProofHashes(crypto.SHA256))
+//
+// Moving x itself to another hash needs new verifiers made from the
+// password (see UpgradeVerifier()).
+
+// altKey is the session key under an alternate proof hash
+type altKey struct {
+	h crypto.Hash
+	K []byte
+}
+
+// WithProofHash makes clients and servers in this environment derive the
+// session key and compute the proofs with 'h' instead of the hash function
+// of the environment, which still computes x, k and u.
+func WithProofHash(h crypto.Hash) Option {
+	return func(s *SRP) error {
+		if err := checkHash(h); err != nil {
+			return err
+		}
+		s.ph = h
+		return nil
+	}
+}
+
+// WithAcceptedProofHashes makes servers in this environment also accept
+// client proofs (and derive session keys) computed with any of the hashes
+// 'hs' during a migration; the server replies with, and keeps the session
+// key of, the hash that matched (see Server.ProofHash()). The hash set by
+// WithProofHash() is always tried first. Key confirmation (see
+// Server.CheckConfirm()) only uses the main hash.
+func WithAcceptedProofHashes(hs ...crypto.Hash) Option {
+	return func(s *SRP) error {
+		for _, h := range hs {
+			if err := checkHash(h); err != nil {
+				return err
+			}
+		}
+		s.aph = append([]crypto.Hash{}, hs...)
+		return nil
+	}
+}
+
+// ProofHash returns the hash function that verified the client's proof,
+// or 0 if CheckProof() hasn't succeeded. During a migration (see
+// WithAcceptedProofHashes()) servers can record it to tell when all
+// clients have moved to the new hash.
+func (s *Server) ProofHash() crypto.Hash {
+	return s.uh
+}
+
+// return the hash function of the session key and proofs
+func (s *SRP) proofHash() crypto.Hash {
+	if s.ph != 0 {
+		return s.ph
+	}
+	return s.h
+}
+
+// return the session keys of the shared secret 'S' under the alternate
+// proof hashes
+func (s *SRP) altKeys(S *big.Int) []altKey {
+	var ak []altKey
+	for _, h := range s.aph {
+		if h != s.proofHash() {
+			ak = append(ak, altKey{h, s.sessionKeyWith(S, h)})
+		}
+	}
+	return ak
+}
+
+// return true if 'n' is the size of a proof this server may accept
+func (s *Server) proofSizeOK(n int) bool {
+	if n == len(s.xM) {
+		return true
+	}
+	for _, k := range s.ak {
+		if n == newHash(k.h).Size() {
+			return true
+		}
+	}
+	return false
+}
+
+// return the transcript 't' under the alternate proof hash of 'k'
+func (t *Transcript) withKey(k altKey) *Transcript {
+	u := *t
+	u.h = k.h
+	u.K = k.K
+	return &u
+}
+
+// return the "ph=" encoding of the alternate session keys
+func encodeAltKeys(ak []altKey) string {
+	v := make([]string, 0, len(ak))
+	for _, k := range ak {
+		v = append(v, strconv.FormatUint(uint64(k.h), 10)+"."+hex.EncodeToString(k.K))
+	}
+	return strings.Join(v, ",")
+}
+
+// parse the alternate session keys encoded by encodeAltKeys()
+func decodeAltKeys(s string) ([]altKey, error) {
+	var ak []altKey
+	for _, kv := range strings.Split(s, ",") {
+		i := strings.IndexByte(kv, '.')
+		if i <= 0 {
+			return nil, fmt.Errorf("malformed proof hash key %q", kv)
+		}
+
+		h, err := strconv.ParseUint(kv[:i], 10, 32)
+		if err != nil || !hashAvailable(crypto.Hash(h)) {
+			return nil, fmt.Errorf("invalid proof hash %q", kv[:i])
+		}
+		K, err := hex.DecodeString(kv[i+1:])
+		if err != nil || len(K) == 0 {
+			return nil, fmt.Errorf("malformed proof hash key %q", kv)
+		}
+		ak = append(ak, altKey{crypto.Hash(h), K})
+	}
+	return ak, nil
+}
@@ -0,0 +1,83 @@
+// self test for proof hashes
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"crypto"
+	"testing"
+)
+
+func TestProofHashes(t *testing.T) {
+	skipFIPS(t)
+
+	assert := newAsserter(t)
+
+	user := []byte("user")
+	pass := []byte("pass")
+
+	legacy := []Option{}
+	sha3 := []Option{WithProofHash(crypto.SHA3_256)}
+	blake := []Option{WithProofHash(crypto.BLAKE2b_512)}
+	migrating := []Option{WithAcceptedProofHashes(crypto.SHA3_256, crypto.BLAKE2b_512)}
+	migrated := []Option{WithProofHash(crypto.SHA3_256), WithAcceptedProofHashes(crypto.SHA1)}
+
+	tests := []struct {
+		client, server []Option
+		ok             bool
+		used           crypto.Hash
+	}{
+		{legacy, legacy, true, crypto.SHA1},
+		{sha3, sha3, true, crypto.SHA3_256},
+		{sha3, legacy, false, 0},
+		{legacy, sha3, false, 0},
+
+		// during the migration window all clients succeed
+		{legacy, migrating, true, crypto.SHA1},
+		{sha3, migrating, true, crypto.SHA3_256},
+		{blake, migrating, true, crypto.BLAKE2b_512},
+		{legacy, migrated, true, crypto.SHA1},
+		{sha3, migrated, true, crypto.SHA3_256},
+		{blake, migrated, false, 0},
+	}
+
+	for i, x := range tests {
+		cs, err := NewWithHash(crypto.SHA1, 2048, x.client...)
+		assert(err == nil, "%d: New: %s", i, err)
+
+		v, err := cs.Verifier(user, pass, nil)
+		assert(err == nil, "Verifier: %s", err)
+		_, vh := v.Encode()
+
+		c, err := cs.NewClient(user, pass)
+		assert(err == nil, "NewClient: %s", err)
+
+		_, A, err := ServerBegin(c.Credentials())
+		assert(err == nil, "ServerBegin: %s", err)
+
+		ss, sv, err := MakeSRPVerifier(vh, x.server...)
+		assert(err == nil, "%d: MakeSRPVerifier: %s", i, err)
+
+		srv, err := ss.NewServer(sv, A)
+		assert(err == nil, "NewServer: %s", err)
+
+		m, err := c.Generate(srv.Credentials())
+		assert(err == nil, "Generate: %s", err)
+
+		// the alternate keys must survive a marshaled server
+		srv, err = UnmarshalServer(srv.Marshal(), x.server...)
+		assert(err == nil, "%d: UnmarshalServer: %s", i, err)
+
+		proof, ok := srv.ClientOk(m)
+		assert(ok == x.ok, "%d: exp %v, saw %v", i, x.ok, ok)
+		assert(srv.ProofHash() == x.used, "%d: wrong hash %v", i, srv.ProofHash())
+		if ok {
+			assert(c.ServerOk(proof), "%d: bad server proof", i)
+			assert(ctEqual(c.RawKey(), srv.RawKey()), "%d: keys differ", i)
+			assert(len(c.RawKey()) == newHash(x.used).Size(), "%d: wrong key size", i)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+// puzzle.go - memory-hard client puzzles against online password guessing
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"math/bits"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// A server can make each login attempt cost the client a memory-hard
+// proof of work; it raises the cost of online password guessing by botnets
+// while a single honest login only waits a little longer. The server sends
+// the difficulty along with <s, B> and the client must find a 64-bit nonce
+// n such that
+//
+//	Argon2id(n, seed, t=1, m=Memory, p=1)
+//
+// starts with Bits zero bits, where seed = SHA-256("srp puzzle" || pad(A)
+// || pad(B)) binds the puzzle to the handshake. The client appends n (8 bytes, big
+// endian) to its proof M (or to its key confirmation message) and the
+// server checks it before the proof. Finding n takes 2^Bits evaluations
+// on average; checking it takes one.
+
+// size of a puzzle solution in bytes
+const puzzleSolutionLen = 8
+
+// Upper bounds on the puzzle a client solves unless it sets its own with
+// WithMaxPuzzle(); they bound the work a malicious server can make a client
+// do to about 2^8 evaluations of 10ms each (on a 2.1 GHz Xeon).
+const (
+	DefaultMaxPuzzleBits   = 8
+	DefaultMaxPuzzleMemory = 16 * 1024 // KiB
+)
+
+// clients give up after this many times 2^Bits evaluations; an honest
+// server's puzzle fails with a probability of about e^-16
+const puzzleTries = 16
+
+var puzzleLabel = []byte("srp puzzle")
+
+// ErrPuzzleUnsolved is returned by Server.VerifyClientProof() when the
+// client's proof doesn't carry a solution of the server's puzzle (see
+// WithClientPuzzle()).
+var ErrPuzzleUnsolved = fmt.Errorf("srp: client puzzle unsolved")
+
+// Puzzle is the difficulty of a client puzzle
+type Puzzle struct {
+	Bits   int    `json:"bits"` // leading zero bits required of the hash
+	Memory uint32 `json:"mem"`  // memory of each evaluation in KiB
+}
+
+// WithClientPuzzle makes servers in this environment require clients to
+// solve a puzzle of difficulty 'p' with each proof (see Puzzle). Clients
+// refuse puzzles harder than they accept (see WithMaxPuzzle()).
+func WithClientPuzzle(p Puzzle) Option {
+	return func(s *SRP) error {
+		if err := p.validate(); err != nil {
+			return err
+		}
+		s.pz = &p
+		return nil
+	}
+}
+
+// WithMaxPuzzle makes clients in this environment refuse server puzzles
+// that need more than 'p.Bits' zero bits or 'p.Memory' KiB per evaluation.
+// Without it, clients solve puzzles of at most DefaultMaxPuzzleBits and
+// DefaultMaxPuzzleMemory.
+func WithMaxPuzzle(p Puzzle) Option {
+	return func(s *SRP) error {
+		if err := p.validate(); err != nil {
+			return err
+		}
+		s.pzMax = &p
+		return nil
+	}
+}
+
+// String returns the portable encoding "bits,memory" of the puzzle
+func (p *Puzzle) String() string {
+	return fmt.Sprintf("%d,%d", p.Bits, p.Memory)
+}
+
+// UnmarshalJSON decodes a puzzle (e.g., in ServerCredentials) and rejects
+// an invalid difficulty like the other decoders of server messages do
+func (p *Puzzle) UnmarshalJSON(b []byte) error {
+	type puzzle Puzzle // without this method
+	var x puzzle
+	if err := json.Unmarshal(b, &x); err != nil {
+		return err
+	}
+	if err := (*Puzzle)(&x).validate(); err != nil {
+		return err
+	}
+	*p = Puzzle(x)
+	return nil
+}
+
+// validate the difficulty
+func (p *Puzzle) validate() error {
+	if p.Bits <= 0 || p.Bits > 32 {
+		return fmt.Errorf("srp: invalid puzzle difficulty %d bits", p.Bits)
+	}
+	if p.Memory < 8 {
+		return fmt.Errorf("srp: invalid puzzle memory %d KiB", p.Memory)
+	}
+	return nil
+}
+
+// parse the output of Puzzle.String()
+func parsePuzzle(s string) (*Puzzle, error) {
+	v := strings.Split(s, ",")
+	if len(v) != 2 {
+		return nil, fmt.Errorf("srp: malformed puzzle %q", s)
+	}
+
+	b, err := strconv.Atoi(v[0])
+	if err != nil {
+		return nil, fmt.Errorf("srp: malformed puzzle %q", s)
+	}
+	m, err := strconv.ParseUint(v[1], 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("srp: malformed puzzle %q", s)
+	}
+
+	p := &Puzzle{Bits: b, Memory: uint32(m)}
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// return the size of the puzzle solution that clients append to proofs
+func (s *SRP) solutionLen() int {
+	if s.pz != nil {
+		return puzzleSolutionLen
+	}
+	return 0
+}
+
+// solve the puzzle 'p' of the handshake with public keys 'A' and 'B'
+func (s *SRP) solvePuzzle(p *Puzzle, A, B *big.Int) ([]byte, error) {
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+
+	max := &Puzzle{Bits: DefaultMaxPuzzleBits, Memory: DefaultMaxPuzzleMemory}
+	if s.pzMax != nil {
+		max = s.pzMax
+	}
+	if p.Bits > max.Bits || p.Memory > max.Memory {
+		return nil, fmt.Errorf("srp: server puzzle is harder than allowed (%s)", p)
+	}
+
+	seed := s.puzzleSeed(A, B)
+	n := make([]byte, puzzleSolutionLen)
+	for i := uint64(0); i < puzzleTries<<uint(p.Bits); i++ {
+		binary.BigEndian.PutUint64(n, i)
+		if p.solvedBy(seed, n) {
+			return n, nil
+		}
+	}
+	return nil, fmt.Errorf("srp: no solution of the server puzzle (%s)", p)
+}
+
+// split the client's message 'm' into the message and the solution of the
+// puzzle of this environment and check the solution
+func (s *Server) checkPuzzle(m []byte) ([]byte, error) {
+	p := s.s.pz
+	if p == nil {
+		return m, nil
+	}
+
+	// old marshaled servers lack A
+	if s.xA == nil || len(m) < puzzleSolutionLen {
+		return nil, ErrPuzzleUnsolved
+	}
+
+	k := len(m) - puzzleSolutionLen
+	if !p.solvedBy(s.s.puzzleSeed(s.xA, s.xB), m[k:]) {
+		return nil, ErrPuzzleUnsolved
+	}
+	return m[:k], nil
+}
+
+// return true if the nonce 'n' solves the puzzle with 'seed'
+func (p *Puzzle) solvedBy(seed, n []byte) bool {
+	h := argon2.IDKey(n, seed, 1, p.Memory, 1, 32)
+
+	z := 0
+	for _, b := range h {
+		if b != 0 {
+			z += bits.LeadingZeros8(b)
+			break
+		}
+		z += 8
+	}
+	return z >= p.Bits
+}
+
+// return the seed of the puzzle of the handshake with public keys 'A', 'B'
+func (s *SRP) puzzleSeed(A, B *big.Int) []byte {
+	h := sha256.New()
+	h.Write(puzzleLabel)
+	h.Write(pad(A, s.pf.n))
+	h.Write(pad(B, s.pf.n))
+	return h.Sum(nil)
+}
@@ -0,0 +1,136 @@
+// self test for client puzzles
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestClientPuzzle(t *testing.T) {
+	assert := newAsserter(t)
+
+	user := []byte("user")
+	pass := []byte("pass")
+	pz := Puzzle{Bits: 6, Memory: 64}
+
+	cs, err := New(2048)
+	assert(err == nil, "New: %s", err)
+
+	v, err := cs.Verifier(user, pass, nil)
+	assert(err == nil, "Verifier: %s", err)
+	_, vh := v.Encode()
+
+	ss, sv, err := MakeSRPVerifier(vh, WithClientPuzzle(pz))
+	assert(err == nil, "MakeSRPVerifier: %s", err)
+
+	begin := func(c *SRP) (*Client, *Server) {
+		cl, err := c.NewClient(user, pass)
+		assert(err == nil, "NewClient: %s", err)
+
+		srv, err := ss.NewServer(sv, big.NewInt(0).SetBytes(cl.Hello().A))
+		assert(err == nil, "NewServer: %s", err)
+		return cl, srv
+	}
+
+	// string form
+	c, srv := begin(cs)
+	m, err := c.Generate(srv.Credentials())
+	assert(err == nil, "Generate: %s", err)
+	proof, err := srv.VerifyClientProof(m)
+	assert(err == nil, "VerifyClientProof: %s", err)
+	assert(c.ServerOk(proof), "ServerOk failed")
+
+	// binary form, through CBOR
+	c, srv = begin(cs)
+	sc := srv.Challenge()
+	sc, err = DecodeServerCredentialsCBOR(sc.EncodeCBOR())
+	assert(err == nil, "DecodeServerCredentialsCBOR: %s", err)
+	assert(sc.Puzzle != nil && *sc.Puzzle == pz, "puzzle lost: %v", sc.Puzzle)
+	mb, err := c.Respond(sc)
+	assert(err == nil, "Respond: %s", err)
+	pb, ok := srv.CheckProof(mb)
+	assert(ok, "CheckProof failed")
+	assert(c.CheckProof(pb), "client CheckProof failed")
+
+	// key confirmation
+	c, srv = begin(cs)
+	_, err = c.Respond(srv.Challenge())
+	assert(err == nil, "Respond: %s", err)
+	cm, err := c.Confirm()
+	assert(err == nil, "Confirm: %s", err)
+	reply, ok := srv.CheckConfirm(cm)
+	assert(ok, "CheckConfirm failed")
+	assert(c.CheckConfirm(reply), "client CheckConfirm failed")
+
+	// a proof without a solution
+	c, srv = begin(cs)
+	sc = srv.Challenge()
+	sc.Puzzle = nil
+	mb, err = c.Respond(sc)
+	assert(err == nil, "Respond: %s", err)
+	_, err = srv.VerifyClientProof(hex.EncodeToString(mb))
+	assert(errors.Is(err, ErrMalformedProof), "unsolved proof: %v", err)
+
+	// a wrong solution
+	c, srv = begin(cs)
+	mb, err = c.Respond(srv.Challenge())
+	assert(err == nil, "Respond: %s", err)
+	mb[len(mb)-1]++
+	for srv.s.pz.solvedBy(srv.s.puzzleSeed(srv.xA, srv.xB), mb[len(mb)-puzzleSolutionLen:]) {
+		mb[len(mb)-1]++
+	}
+	_, err = srv.VerifyClientProof(hex.EncodeToString(mb))
+	assert(errors.Is(err, ErrPuzzleUnsolved), "wrong solution: %v", err)
+
+	// clients refuse puzzles that are too hard
+	weak, err := New(2048, WithMaxPuzzle(Puzzle{Bits: 4, Memory: 64}))
+	assert(err == nil, "New: %s", err)
+	c, srv = begin(weak)
+	_, err = c.Generate(srv.Credentials())
+	assert(err != nil, "solved a puzzle that is too hard")
+
+	// or harder than the defaults
+	_, err = cs.solvePuzzle(&Puzzle{Bits: DefaultMaxPuzzleBits + 1, Memory: 64}, srv.xA, srv.xB)
+	assert(err != nil, "solved a puzzle with too many bits")
+	_, err = cs.solvePuzzle(&Puzzle{Bits: 1, Memory: DefaultMaxPuzzleMemory + 1}, srv.xA, srv.xB)
+	assert(err != nil, "solved a puzzle with too much memory")
+
+	_, err = New(2048, WithClientPuzzle(Puzzle{Bits: 0, Memory: 64}))
+	assert(err != nil, "accepted an empty puzzle")
+}
+
+func TestPuzzleJSON(t *testing.T) {
+	assert := newAsserter(t)
+
+	var sc ServerCredentials
+	err := json.Unmarshal([]byte(`{"s":"AA==","B":"AQ==","pow":{"bits":6,"mem":64}}`), &sc)
+	assert(err == nil, "Unmarshal: %s", err)
+	assert(sc.Puzzle != nil && *sc.Puzzle == Puzzle{Bits: 6, Memory: 64}, "wrong puzzle %v", sc.Puzzle)
+
+	for _, pz := range []string{`{"bits":0,"mem":64}`, `{"bits":40,"mem":64}`, `{"bits":6,"mem":4}`, `{}`} {
+		err := json.Unmarshal([]byte(`{"s":"AA==","B":"AQ==","pow":`+pz+`}`), &sc)
+		assert(err != nil, "decoded puzzle %s", pz)
+	}
+}
+
+func TestParsePuzzle(t *testing.T) {
+	assert := newAsserter(t)
+
+	p, err := parsePuzzle("12,1024")
+	assert(err == nil, "parsePuzzle: %s", err)
+	assert(p.Bits == 12 && p.Memory == 1024, "wrong puzzle %v", p)
+	assert(p.String() == "12,1024", "wrong string %s", p)
+
+	for _, s := range []string{"", "12", "12,", "x,1024", "12,1024,1", "0,1024", "40,1024", "12,4"} {
+		_, err := parsePuzzle(s)
+		assert(err != nil, "parsed %q", s)
+	}
+}
@@ -0,0 +1,52 @@
+// pwreader.go - passwords read incrementally from an io.Reader
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"fmt"
+	"io"
+)
+
+// A password only enters SRP through its hashes H(p) and H(I:p), so a
+// large secret, e.g., a keyfile used as a passphrase, can be hashed as it
+// is read instead of being held in memory. The functions here produce
+// exactly the same clients and verifiers as NewClient() and Verifier()
+// given the whole secret.
+
+// NewClientFromReader is NewClient() with the password read from 'r'
+// until EOF.
+func (s *SRP) NewClientFromReader(I []byte, r io.Reader) (*Client, error) {
+	if err := s.checkPolicy(); err != nil {
+		return nil, err
+	}
+
+	I = s.identity(I)
+	ph := newHash(s.h)
+	iph := newHash(s.h)
+	iph.Write(I)
+	iph.Write([]byte(":"))
+
+	if _, err := io.Copy(io.MultiWriter(ph, iph), r); err != nil {
+		return nil, fmt.Errorf("srp: can't read password: %w", err)
+	}
+	return s.initHashedClient(new(Client), s.hashbyte(I), ph.Sum(nil), iph.Sum(nil)), nil
+}
+
+// VerifierFromReader is Verifier() with the password read from 'r' until
+// EOF. A password policy (see WithPasswordPolicy()) needs the whole
+// password, so environments that have one return an error instead.
+func (s *SRP) VerifierFromReader(I []byte, r io.Reader, sel []byte) (*Verifier, error) {
+	if s.pp != nil {
+		return nil, fmt.Errorf("srp: password policy can't check a streamed password")
+	}
+
+	ph := newHash(s.h)
+	if _, err := io.Copy(ph, r); err != nil {
+		return nil, fmt.Errorf("srp: can't read password: %w", err)
+	}
+	return s.hashedVerifier(s.identity(I), ph.Sum(nil), sel)
+}
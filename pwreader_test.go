@@ -0,0 +1,69 @@
+// self test for passwords read from an io.Reader
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+// a reader that fails after returning its data
+type failReader struct {
+	r   io.Reader
+	err error
+}
+
+func (f *failReader) Read(b []byte) (int, error) {
+	n, err := f.r.Read(b)
+	if err == io.EOF {
+		err = f.err
+	}
+	return n, err
+}
+
+func TestPasswordReader(t *testing.T) {
+	assert := newAsserter(t)
+
+	user := []byte("user")
+	key := bytes.Repeat([]byte("0123456789abcdef"), 64<<10)
+
+	s, err := New(2048)
+	assert(err == nil, "New: %s", err)
+
+	// the same verifier and client as with the whole secret
+	salt := []byte("0123456789abcdef")
+	v0, err := s.Verifier(user, key, salt)
+	assert(err == nil, "Verifier: %s", err)
+	v1, err := s.VerifierFromReader(user, bytes.NewReader(key), salt)
+	assert(err == nil, "VerifierFromReader: %s", err)
+	assert(bytes.Equal(v0.v, v1.v), "verifiers differ")
+
+	c0, err := s.NewClient(user, key)
+	assert(err == nil, "NewClient: %s", err)
+	c1, err := s.NewClientFromReader(user, bytes.NewReader(key))
+	assert(err == nil, "NewClientFromReader: %s", err)
+	assert(bytes.Equal(c0.p, c1.p) && bytes.Equal(c0.ip, c1.ip), "clients differ")
+
+	srv, err := s.NewServer(v1, c1.xA)
+	assert(err == nil, "NewServer: %s", err)
+	assert(authenticate(c1, srv), "handshake failed")
+
+	// read errors are returned
+	boom := errors.New("boom")
+	_, err = s.NewClientFromReader(user, &failReader{bytes.NewReader(key), boom})
+	assert(errors.Is(err, boom), "NewClientFromReader: exp %s, saw %v", boom, err)
+	_, err = s.VerifierFromReader(user, &failReader{bytes.NewReader(key), boom}, nil)
+	assert(errors.Is(err, boom), "VerifierFromReader: exp %s, saw %v", boom, err)
+
+	// a policy can't be checked on a stream
+	s, err = New(2048, WithPasswordPolicy(MinimumEntropy(40)))
+	assert(err == nil, "New: %s", err)
+	_, err = s.VerifierFromReader(user, bytes.NewReader(key), nil)
+	assert(err != nil, "policy skipped")
+}
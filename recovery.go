@@ -0,0 +1,132 @@
+// recovery.go - recovery codes verified with SRP
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Recovery codes let a user who lost their password prove who they are
+// with the same PAKE instead of a reset link sent by email. Each code is a
+// password of its own: the server keeps a secondary verifier per code
+// (apart from the user's password verifier) and deletes it once used.
+//
+// A code is 80 random bits in Crockford's base32, in groups of four
+// digits, followed by Crockford's check symbol (the value mod 37):
+//
+//	7K3Q-HV0B-XN2M-PD4R-W
+//
+// Parsing is forgiving in the ways Crockford's alphabet intends: case,
+// hyphens and spaces don't matter, and I and L read as 1 and O as 0. The
+// verifier is made from the canonical form (the 16 digits, upper case), so
+// every way of typing a code is accepted.
+
+// Parameters of recovery codes
+const (
+	RecoveryCodeBits   = 80
+	RecoveryCodeDigits = RecoveryCodeBits / 5
+)
+
+// Crockford's base32 digits and the additional check symbols
+const (
+	crockfordDigits = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+	crockfordCheck  = crockfordDigits + "*~$=U"
+)
+
+// NewRecoveryCodes generates 'n' recovery codes for identity 'I' and
+// returns them (for showing to the user once) with their verifiers (for
+// storing apart from the password verifier).
+func (s *SRP) NewRecoveryCodes(I []byte, n int) ([]string, []*Verifier, error) {
+	if n <= 0 {
+		return nil, nil, fmt.Errorf("srp: invalid number of recovery codes %d", n)
+	}
+
+	codes := make([]string, n)
+	vs := make([]*Verifier, n)
+	for i := range codes {
+		x := big.NewInt(0).SetBytes(s.randbytes(RecoveryCodeBits / 8))
+		codes[i] = formatRecoveryCode(x)
+
+		v, err := s.Verifier(I, []byte(canonicalRecoveryCode(x)), nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		vs[i] = v
+	}
+	return codes, vs, nil
+}
+
+// ParseRecoveryCode validates the recovery code 'code' as typed by a user
+// and returns the password to give to NewClient() along with the identity.
+func ParseRecoveryCode(code string) ([]byte, error) {
+	var digits []byte
+	for _, r := range strings.ToUpper(code) {
+		switch r {
+		case '-', ' ':
+			continue
+		case 'I', 'L':
+			r = '1'
+		case 'O':
+			r = '0'
+		}
+		if r > 0x7f {
+			return nil, fmt.Errorf("srp: invalid recovery code")
+		}
+		digits = append(digits, byte(r))
+	}
+
+	if len(digits) != RecoveryCodeDigits+1 {
+		return nil, fmt.Errorf("srp: recovery code must have %d digits and a check symbol", RecoveryCodeDigits)
+	}
+
+	x := big.NewInt(0)
+	for _, d := range digits[:RecoveryCodeDigits] {
+		i := strings.IndexByte(crockfordDigits, d)
+		if i < 0 {
+			return nil, fmt.Errorf("srp: invalid recovery code")
+		}
+		x.Lsh(x, 5).Or(x, big.NewInt(int64(i)))
+	}
+
+	if digits[RecoveryCodeDigits] != checkSymbol(x) {
+		return nil, fmt.Errorf("srp: mistyped recovery code")
+	}
+	return []byte(canonicalRecoveryCode(x)), nil
+}
+
+// return the 16 digits of the code with value 'x'
+func canonicalRecoveryCode(x *big.Int) string {
+	b := make([]byte, RecoveryCodeDigits)
+	y := big.NewInt(0).Set(x)
+	m := big.NewInt(31)
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i] = crockfordDigits[big.NewInt(0).And(y, m).Int64()]
+		y.Rsh(y, 5)
+	}
+	return string(b)
+}
+
+// return the code with value 'x' as shown to users
+func formatRecoveryCode(x *big.Int) string {
+	d := canonicalRecoveryCode(x)
+
+	var b strings.Builder
+	for i := 0; i < len(d); i += 4 {
+		b.WriteString(d[i : i+4])
+		b.WriteByte('-')
+	}
+	b.WriteByte(checkSymbol(x))
+	return b.String()
+}
+
+// return Crockford's check symbol of 'x'
+func checkSymbol(x *big.Int) byte {
+	m := big.NewInt(0).Mod(x, big.NewInt(int64(len(crockfordCheck))))
+	return crockfordCheck[m.Int64()]
+}
@@ -0,0 +1,63 @@
+// self test for recovery codes
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestRecoveryCodes(t *testing.T) {
+	assert := newAsserter(t)
+
+	s, err := New(2048)
+	assert(err == nil, "New: %s", err)
+
+	codes, vs, err := s.NewRecoveryCodes([]byte("user"), 3)
+	assert(err == nil, "NewRecoveryCodes: %s", err)
+	assert(len(codes) == 3 && len(vs) == 3, "wrong number of codes")
+
+	for i, code := range codes {
+		assert(len(code) == 21 && strings.Count(code, "-") == 4, "%d: malformed code %q", i, code)
+
+		// the code works however it is typed
+		typed := strings.ToLower(strings.Replace(code, "-", " ", -1))
+		typed = strings.Replace(strings.Replace(typed, "0", "o", -1), "1", "l", -1)
+		pw, err := ParseRecoveryCode(typed)
+		assert(err == nil, "%d: ParseRecoveryCode(%q): %s", i, typed, err)
+
+		c, err := s.NewClient([]byte("user"), pw)
+		assert(err == nil, "NewClient: %s", err)
+		srv, err := s.NewServer(vs[i], c.xA)
+		assert(err == nil, "NewServer: %s", err)
+		assert(authenticate(c, srv), "%d: recovery failed", i)
+
+		// a code only opens its own verifier
+		srv, _ = s.NewServer(vs[(i+1)%len(vs)], c.xA)
+		assert(!authenticate(c, srv), "%d: code opened another verifier", i)
+	}
+
+	// Crockford's check symbol catches typos
+	bad := []byte(codes[0])
+	if bad[0] == '7' {
+		bad[0] = '8'
+	} else {
+		bad[0] = '7'
+	}
+	_, err = ParseRecoveryCode(string(bad))
+	assert(err != nil, "typo in %q accepted", bad)
+
+	for _, c := range []string{"", "ABCD", codes[0] + "X", "UUUU-UUUU-UUUU-UUUU-0", "ÄBCD-EFGH-JKMN-PQRS-T"} {
+		_, err = ParseRecoveryCode(c)
+		assert(err != nil, "%q accepted", c)
+	}
+
+	// known answer: 37 = 1*32 + 5, with check symbol 37 mod 37 = 0
+	x := big.NewInt(37)
+	assert(formatRecoveryCode(x) == "0000-0000-0000-0015-0", "saw %s", formatRecoveryCode(x))
+}
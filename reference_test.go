@@ -0,0 +1,183 @@
+// self test against a reference implementation
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+	"testing"
+)
+
+// refSRP is a small, independent implementation of the handshake of this
+// package with SHA-256, written straight from the formulas (RFC 5054 and
+// the doc comments of this package) with math/big only. The differential
+// tests below run Client and Server against it with the same secret
+// ephemerals; any change to padding, ordering or hashing in a refactor
+// shows up as a mismatch. Keep it naive: it must not share code with the
+// package.
+type refSRP struct {
+	N, g   *big.Int
+	padded bool // ProofRFC5054Padded instead of ProofLegacy
+}
+
+// RFC 5054, appendix A: the 2048-bit group
+const refN2048Hex = "AC6BDB41324A9A9BF166DE5E1389582FAF72B6651987EE07FC3192943DB56050" +
+	"A37329CBB4A099ED8193E0757767A13DD52312AB4B03310DCD7F48A9DA04FD50" +
+	"E8083969EDB767B0CF6095179A163AB3661A05FBD5FAAAE82918A9962F0B93B8" +
+	"55F97993EC975EEAA80D740ADBF4FF747359D041D5C33EA71D281E446B14773B" +
+	"CA97B43A23FB801676BD207A436C6481F1D2B9078717461A5B9D32E688F87748" +
+	"544523B524B0D57D5EA77A2775D2ECFA032CFBDBF52FB3786160279004E57AE6" +
+	"AF874E7303CE53299CCC041C7BC308D82A5698F3A8D0C38271AE35F8E9DBFBB6" +
+	"94B5C803D89F7AE435DE236D525F54759B65E372FCD68EF20FA7111F9E4AFF73"
+
+var refN2048, _ = new(big.Int).SetString(refN2048Hex, 16)
+
+// the values of one handshake
+type refHandshake struct {
+	v, A, B  *big.Int
+	K, M, M2 []byte
+}
+
+func refH(a ...[]byte) []byte {
+	h := sha256.New()
+	for _, b := range a {
+		h.Write(b)
+	}
+	return h.Sum(nil)
+}
+
+func (r *refSRP) pad(x *big.Int) []byte {
+	n := (r.N.BitLen() + 7) / 8
+	b := x.Bytes()
+	return append(make([]byte, n-len(b)), b...)
+}
+
+func (r *refSRP) num(x *big.Int) []byte {
+	if r.padded {
+		return r.pad(x)
+	}
+	return x.Bytes()
+}
+
+// run a handshake of identity 'I' and password 'p' with salt 's' and the
+// secret ephemerals 'a' and 'b'
+func (r *refSRP) run(I, p, s []byte, a, b *big.Int) *refHandshake {
+	N, g := r.N, r.g
+	hI := refH(I)
+
+	// x = H(H(I), H(p), s); v = g^x
+	x := new(big.Int).SetBytes(refH(hI, refH(p), s))
+	v := new(big.Int).Exp(g, x, N)
+
+	// k = H(N, PAD(g)); A = g^a; B = kv + g^b
+	k := new(big.Int).SetBytes(refH(N.Bytes(), r.pad(g)))
+	A := new(big.Int).Exp(g, a, N)
+	B := new(big.Int).Mul(k, v)
+	B.Add(B, new(big.Int).Exp(g, b, N))
+	B.Mod(B, N)
+
+	// u = H(PAD(A), PAD(B))
+	u := new(big.Int).SetBytes(refH(r.pad(A), r.pad(B)))
+
+	// client: S = (B - kg^x)^(a + ux)
+	t := new(big.Int).Sub(B, new(big.Int).Mul(k, new(big.Int).Exp(g, x, N)))
+	t.Mod(t, N)
+	e := new(big.Int).Add(a, new(big.Int).Mul(u, x))
+	Sc := new(big.Int).Exp(t, e, N)
+
+	// server: S = (Av^u)^b
+	Ss := new(big.Int).Mul(A, new(big.Int).Exp(v, u, N))
+	Ss.Exp(Ss.Mod(Ss, N), b, N)
+	if Sc.Cmp(Ss) != 0 {
+		panic("reference: client and server disagree on S")
+	}
+
+	h := &refHandshake{v: v, A: A, B: B}
+	if r.padded {
+		// K = H(PAD(S))
+		// M = H(H(N) xor H(g), H(I), s, PAD(A), PAD(B), K)
+		// M2 = H(PAD(A), M, K)
+		h.K = refH(r.pad(Sc))
+		hn, hg := refH(N.Bytes()), refH(g.Bytes())
+		for i := range hn {
+			hn[i] ^= hg[i]
+		}
+		h.M = refH(hn, refH(hI), s, r.pad(A), r.pad(B), h.K)
+		h.M2 = refH(r.pad(A), h.M, h.K)
+	} else {
+		// K = H(S); M = H(K, A, B, I, s, N, g); M2 = H(K, M)
+		h.K = refH(Sc.Bytes())
+		h.M = refH(h.K, A.Bytes(), B.Bytes(), hI, s, N.Bytes(), g.Bytes())
+		h.M2 = refH(h.K, h.M)
+	}
+	return h
+}
+
+// return the 256 byte ephemeral of 'seed': it is in [2^2046, 2^2047), so
+// the package accepts it on its first draw
+func refEphemeral(seed string) []byte {
+	var b []byte
+	for i := 0; len(b) < 256; i++ {
+		b = append(b, refH([]byte(fmt.Sprintf("%s %d", seed, i)))...)
+	}
+	b[0] = b[0]&0x3f | 0x40
+	return b[:256]
+}
+
+func TestReferenceHandshake(t *testing.T) {
+	assert := newAsserter(t)
+
+	for _, padded := range []bool{false, true} {
+		ref := &refSRP{N: refN2048, g: big.NewInt(2), padded: padded}
+		scheme := ProofLegacy
+		if padded {
+			scheme = ProofRFC5054Padded
+		}
+
+		for i := 0; i < 8; i++ {
+			I := []byte(fmt.Sprintf("user%d", i))
+			p := []byte(fmt.Sprintf("password %d", i))
+			salt := refH([]byte(fmt.Sprintf("salt %d", i)))
+			ab := refEphemeral(fmt.Sprintf("a %d", i))
+			bb := refEphemeral(fmt.Sprintf("b %d", i))
+
+			exp := ref.run(I, p, salt, new(big.Int).SetBytes(ab), new(big.Int).SetBytes(bb))
+
+			cs, err := NewWithHash(crypto.SHA256, 2048, WithProofScheme(scheme), WithRand(bytes.NewReader(ab)))
+			assert(err == nil, "New: %s", err)
+			ss, err := NewWithHash(crypto.SHA256, 2048, WithProofScheme(scheme), WithRand(bytes.NewReader(bb)))
+			assert(err == nil, "New: %s", err)
+			assert(cs.pf.N.Cmp(refN2048) == 0, "wrong group")
+
+			v, err := ss.Verifier(I, p, salt)
+			assert(err == nil, "Verifier: %s", err)
+			assert(new(big.Int).SetBytes(v.v).Cmp(exp.v) == 0, "%s %d: v differs", scheme.Name(), i)
+
+			c, err := cs.NewClient(I, p)
+			assert(err == nil, "NewClient: %s", err)
+			assert(c.xA.Cmp(exp.A) == 0, "%s %d: A differs", scheme.Name(), i)
+
+			srv, err := ss.NewServer(v, c.xA)
+			assert(err == nil, "NewServer: %s", err)
+			assert(srv.xB.Cmp(exp.B) == 0, "%s %d: B differs", scheme.Name(), i)
+
+			m, err := c.Respond(srv.Challenge())
+			assert(err == nil, "Respond: %s", err)
+			assert(bytes.Equal(c.RawKey(), exp.K), "%s %d: client K differs", scheme.Name(), i)
+			assert(bytes.Equal(srv.RawKey(), exp.K), "%s %d: server K differs", scheme.Name(), i)
+			assert(bytes.Equal(m, exp.M), "%s %d: M differs", scheme.Name(), i)
+
+			proof, ok := srv.CheckProof(m)
+			assert(ok, "%s %d: CheckProof failed", scheme.Name(), i)
+			assert(bytes.Equal(proof, exp.M2), "%s %d: M2 differs", scheme.Name(), i)
+			assert(c.CheckProof(proof), "%s %d: client CheckProof failed", scheme.Name(), i)
+		}
+	}
+}
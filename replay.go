@@ -0,0 +1,140 @@
+// replay.go - protection against replayed handshakes
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ReplayCache records values seen in recent handshakes so that a server can
+// reject exact replays. Implementations must be safe for concurrent use;
+// servers in a cluster should share one cache (e.g., backed by Redis with
+// "SET key NX EX ttl").
+type ReplayCache interface {
+	// Seen records 'key' and returns true if it was already recorded and
+	// has not yet expired.
+	Seen(key []byte) (bool, error)
+}
+
+// WithReplayCache makes servers in this environment record every (identity,
+// A) pair in NewServer() and every client proof in ClientOk() in 'rc', and
+// reject values that were seen before.
+func WithReplayCache(rc ReplayCache) Option {
+	return func(s *SRP) error {
+		if rc == nil {
+			return fmt.Errorf("srp: nil replay cache")
+		}
+		s.rc = rc
+		return nil
+	}
+}
+
+// Defaults of NewMemoryReplayCache()
+const (
+	DefaultReplayTTL  = 5 * time.Minute
+	DefaultReplayKeys = 100000
+)
+
+// ErrReplayCacheFull is returned by MemoryReplayCache.Seen() when all the
+// keys it can hold are still live. Forgetting one of them would let it be
+// replayed, so the handshake is refused instead.
+var ErrReplayCacheFull = fmt.Errorf("srp: replay cache full")
+
+// MemoryReplayCache is an in-process ReplayCache that remembers keys for a
+// fixed time-to-live. When it is full of keys that haven't expired, new
+// keys are refused with ErrReplayCacheFull.
+type MemoryReplayCache struct {
+	mu sync.Mutex
+
+	ttl  time.Duration
+	max  int
+	keys map[string]time.Time
+
+	// keys in insertion order; with a fixed ttl this is also expiry order
+	fifo []string
+}
+
+// NewMemoryReplayCache creates a ReplayCache that remembers up to 'max' keys
+// for 'ttl'; values <= 0 take DefaultReplayKeys and DefaultReplayTTL. The
+// ttl should be longer than the time a server waits for the client's
+// proof, and 'max' larger than the handshakes expected in a ttl.
+func NewMemoryReplayCache(ttl time.Duration, max int) *MemoryReplayCache {
+	if ttl <= 0 {
+		ttl = DefaultReplayTTL
+	}
+	if max <= 0 {
+		max = DefaultReplayKeys
+	}
+	return &MemoryReplayCache{
+		ttl:  ttl,
+		max:  max,
+		keys: make(map[string]time.Time),
+	}
+}
+
+// Seen records 'key' and returns true if it was seen within the ttl
+func (m *MemoryReplayCache) Seen(key []byte) (bool, error) {
+	now := time.Now()
+	k := string(key)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.expire(now)
+	if exp, ok := m.keys[k]; ok && now.Before(exp) {
+		return true, nil
+	}
+
+	if len(m.fifo) >= m.max {
+		return false, ErrReplayCacheFull
+	}
+
+	m.keys[k] = now.Add(m.ttl)
+	m.fifo = append(m.fifo, k)
+	return false, nil
+}
+
+// Len returns the number of keys currently remembered
+func (m *MemoryReplayCache) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.expire(time.Now())
+	return len(m.fifo)
+}
+
+// forget keys that expired before 'now'
+func (m *MemoryReplayCache) expire(now time.Time) {
+	for len(m.fifo) > 0 {
+		k := m.fifo[0]
+		if now.Before(m.keys[k]) {
+			break
+		}
+		delete(m.keys, k)
+		m.fifo = m.fifo[1:]
+	}
+}
+
+// record 'v' in the replay cache of the environment and return an error if
+// it was seen before; 'tag' separates the different kinds of values.
+func (s *SRP) replayCheck(tag string, v ...[]byte) error {
+	if s.rc == nil {
+		return nil
+	}
+
+	key := s.hashbyte(append([][]byte{[]byte(tag)}, v...)...)
+	seen, err := s.rc.Seen(key)
+	if err != nil {
+		return fmt.Errorf("srp: replay cache: %w", err)
+	}
+	if seen {
+		return fmt.Errorf("%w (%s)", ErrReplayed, tag)
+	}
+	return nil
+}
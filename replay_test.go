@@ -0,0 +1,96 @@
+// self test for replay protection
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestReplayCache(t *testing.T) {
+	assert := newAsserter(t)
+
+	user := []byte("user")
+	pass := []byte("pass")
+
+	s, err := New(2048)
+	assert(err == nil, "New: %s", err)
+
+	v, err := s.Verifier(user, pass, nil)
+	assert(err == nil, "Verifier: %s", err)
+	_, vh := v.Encode()
+
+	rc := NewMemoryReplayCache(time.Minute, 100)
+
+	c, err := s.NewClient(user, pass)
+	assert(err == nil, "NewClient: %s", err)
+
+	creds := c.Credentials()
+	_, A, err := ServerBegin(creds)
+	assert(err == nil, "ServerBegin: %s", err)
+
+	ss, sv, err := MakeSRPVerifier(vh, WithReplayCache(rc))
+	assert(err == nil, "MakeSRPVerifier: %s", err)
+
+	srv, err := ss.NewServer(sv, A)
+	assert(err == nil, "NewServer: %s", err)
+
+	// the same <I, A> must be rejected
+	_, err = ss.NewServer(sv, A)
+	assert(errors.Is(err, ErrReplayed), "accepted replayed A: %v", err)
+
+	m, err := c.Generate(srv.Credentials())
+	assert(err == nil, "Generate: %s", err)
+
+	// the proof is recorded even after the server was marshaled
+	srv, err = UnmarshalServer(srv.Marshal(), WithReplayCache(rc))
+	assert(err == nil, "UnmarshalServer: %s", err)
+
+	proof, ok := srv.ClientOk(m)
+	assert(ok, "ClientOk failed")
+	assert(c.ServerOk(proof), "ServerOk failed")
+
+	_, ok = srv.ClientOk(m)
+	assert(!ok, "accepted replayed proof")
+
+	_, err = srv.VerifyClientProof(m)
+	assert(errors.Is(err, ErrReplayed), "exp replayed proof, saw %v", err)
+}
+
+func TestMemoryReplayCache(t *testing.T) {
+	assert := newAsserter(t)
+
+	rc := NewMemoryReplayCache(50*time.Millisecond, 2)
+
+	seen, _ := rc.Seen([]byte("a"))
+	assert(!seen, "new key seen")
+	seen, _ = rc.Seen([]byte("a"))
+	assert(seen, "key not remembered")
+
+	rc.Seen([]byte("b"))
+	assert(rc.Len() == 2, "exp 2 keys, saw %d", rc.Len())
+
+	// a full cache refuses new keys rather than forget live ones
+	_, err := rc.Seen([]byte("c"))
+	assert(errors.Is(err, ErrReplayCacheFull), "exp ErrReplayCacheFull, saw %v", err)
+	seen, _ = rc.Seen([]byte("a"))
+	assert(seen, "live key forgotten")
+
+	time.Sleep(60 * time.Millisecond)
+	assert(rc.Len() == 0, "keys didn't expire; saw %d", rc.Len())
+	seen, err = rc.Seen([]byte("c"))
+	assert(!seen && err == nil, "expired cache: %v %v", seen, err)
+
+	// zero sizes take the defaults
+	rc = NewMemoryReplayCache(0, 0)
+	assert(rc.ttl == DefaultReplayTTL && rc.max == DefaultReplayKeys, "no defaults: %s %d", rc.ttl, rc.max)
+	seen, _ = rc.Seen([]byte("a"))
+	assert(!seen, "new key seen")
+	seen, _ = rc.Seen([]byte("a"))
+	assert(seen, "replay not detected")
+}
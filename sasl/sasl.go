@@ -0,0 +1,193 @@
+// sasl.go - SRP as a SASL mechanism
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+// Package sasl implements SRP as a SASL mechanism for protocols such as
+// SMTP, IMAP or XMPP. The Client and Server interfaces have the same
+// methods as those of common Go SASL packages (e.g.,
+// github.com/emersion/go-sasl), so the mechanisms plug into their clients
+// and servers without an adapter.
+//
+// The exchange has the message flow of the (expired) SRP SASL draft,
+// draft-burdis-cat-srp-sasl, but not its message encoding or proofs, so it
+// doesn't interoperate with implementations of the draft; the mechanism
+// has the private name X-SRP-CBOR rather than the draft's "SRP":
+//
+//	C: Hello     <I, A>      initial response
+//	S: Challenge <s, B, kdf>
+//	C: Proof     M
+//	S: Proof     M'          the server is done
+//	C: (empty)               the client checked M'
+//
+// Each message is the CBOR encoding of the corresponding message of the srp
+// package. The draft's security layers and option negotiation aren't
+// supported; both sides must agree on the SRP parameters beforehand (they
+// are recorded in the verifier).
+package sasl
+
+import (
+	"fmt"
+
+	"github.com/tomsons/go-srp"
+)
+
+// Mechanism is the SASL name of the mechanism; the "X-" prefix marks it
+// as private (see above)
+const Mechanism = "X-SRP-CBOR"
+
+// Client is a SASL client mechanism
+type Client interface {
+	// Start begins the exchange; it returns the mechanism name and the
+	// initial response.
+	Start() (mech string, ir []byte, err error)
+
+	// Next returns the response to the server challenge 'challenge'
+	Next(challenge []byte) (response []byte, err error)
+}
+
+// Server is a SASL server mechanism
+type Server interface {
+	// Next processes the client response 'response' and returns the next
+	// challenge; 'done' is true when the client is authenticated.
+	Next(response []byte) (challenge []byte, done bool, err error)
+}
+
+// VerifierLookup returns the SRP environment and verifier of the user with
+// hashed identity 'ih'.
+type VerifierLookup func(ih []byte) (*srp.SRP, *srp.Verifier, error)
+
+// client states
+const (
+	clientStart = iota
+	clientChallenge
+	clientProof
+	clientDone
+)
+
+type client struct {
+	c     *srp.Client
+	state int
+}
+
+// NewClient returns a SASL client that authenticates with the SRP client 'c'
+func NewClient(c *srp.Client) Client {
+	return &client{c: c}
+}
+
+func (c *client) Start() (string, []byte, error) {
+	if c.state != clientStart {
+		return "", nil, fmt.Errorf("sasl: exchange already started")
+	}
+
+	cc := c.c.Hello()
+	c.state = clientChallenge
+	return Mechanism, cc.EncodeCBOR(), nil
+}
+
+func (c *client) Next(challenge []byte) ([]byte, error) {
+	switch c.state {
+	case clientChallenge:
+		sc, err := srp.DecodeServerCredentialsCBOR(challenge)
+		if err != nil {
+			return nil, err
+		}
+
+		m, err := c.c.Respond(sc)
+		if err != nil {
+			return nil, err
+		}
+
+		c.state = clientProof
+		return srp.EncodeProofCBOR(m), nil
+
+	case clientProof:
+		proof, err := srp.DecodeProofCBOR(challenge)
+		if err != nil {
+			return nil, err
+		}
+
+		c.state = clientDone
+		if !c.c.CheckProof(proof) {
+			return nil, fmt.Errorf("sasl: server authentication failed")
+		}
+		return []byte{}, nil
+	}
+	return nil, fmt.Errorf("sasl: unexpected challenge")
+}
+
+// server states
+const (
+	serverHello = iota
+	serverProof
+	serverDone
+	serverFailed
+)
+
+// SRPServer is a Server that authenticates clients with SRP
+type SRPServer struct {
+	lookup VerifierLookup
+	srv    *srp.Server
+	state  int
+}
+
+// NewServer returns a SASL server that finds verifiers with 'lookup'. The
+// authenticated srp.Server (e.g., for its session key) is returned by
+// Session() once the exchange is done.
+func NewServer(lookup VerifierLookup) *SRPServer {
+	return &SRPServer{lookup: lookup}
+}
+
+// Session returns the SRP server of an authenticated client or nil
+func (s *SRPServer) Session() *srp.Server {
+	if s.state != serverDone {
+		return nil
+	}
+	return s.srv
+}
+
+// Next implements Server
+func (s *SRPServer) Next(response []byte) ([]byte, bool, error) {
+	switch s.state {
+	case serverHello:
+		cc, err := srp.DecodeClientCredentialsCBOR(response)
+		if err != nil {
+			return nil, false, err
+		}
+
+		env, v, err := s.lookup(cc.IdentityHash)
+		if err != nil {
+			return nil, false, err
+		}
+
+		A, err := env.ParsePublicKey(cc.A)
+		if err != nil {
+			return nil, false, err
+		}
+
+		if s.srv, err = env.NewServerFor(cc.IdentityHash, v, A); err != nil {
+			return nil, false, err
+		}
+
+		sc := s.srv.Challenge()
+		s.state = serverProof
+		return sc.EncodeCBOR(), false, nil
+
+	case serverProof:
+		m, err := srp.DecodeProofCBOR(response)
+		if err != nil {
+			return nil, false, err
+		}
+
+		proof, ok := s.srv.CheckProof(m)
+		if !ok {
+			s.state = serverFailed
+			return nil, false, fmt.Errorf("sasl: client authentication failed")
+		}
+
+		s.state = serverDone
+		return srp.EncodeProofCBOR(proof), true, nil
+	}
+	return nil, false, fmt.Errorf("sasl: unexpected response")
+}
@@ -0,0 +1,84 @@
+// self test for the SASL mechanism
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package sasl
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/tomsons/go-srp"
+)
+
+func TestSASL(t *testing.T) {
+	user := []byte("user")
+	pass := []byte("pass")
+
+	s, err := srp.New(2048)
+	if err != nil {
+		t.Fatalf("New: %s", err)
+	}
+
+	v, err := s.Verifier(user, pass, nil)
+	if err != nil {
+		t.Fatalf("Verifier: %s", err)
+	}
+
+	lookup := func(ih []byte) (*srp.SRP, *srp.Verifier, error) {
+		if !v.MatchesIdentity(ih) {
+			return nil, nil, fmt.Errorf("unknown user")
+		}
+		return s, v, nil
+	}
+
+	for _, pw := range []string{"pass", "wrong"} {
+		c, err := s.NewClient(user, []byte(pw))
+		if err != nil {
+			t.Fatalf("NewClient: %s", err)
+		}
+
+		sc := NewClient(c)
+		ss := NewServer(lookup)
+
+		mech, resp, err := sc.Start()
+		if err != nil || mech != Mechanism {
+			t.Fatalf("Start: %s %s", mech, err)
+		}
+
+		var done bool
+		for !done {
+			var ch []byte
+			ch, done, err = ss.Next(resp)
+			if err != nil {
+				break
+			}
+			if resp, err = sc.Next(ch); err != nil {
+				break
+			}
+		}
+
+		if pw != string(pass) {
+			if err == nil {
+				t.Fatalf("accepted bad password")
+			}
+			if ss.Session() != nil {
+				t.Fatalf("session for bad password")
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("exchange: %s", err)
+		}
+		if len(resp) != 0 {
+			t.Fatalf("unexpected final response %x", resp)
+		}
+		if ss.Session() == nil || !bytes.Equal(ss.Session().RawKey(), c.RawKey()) {
+			t.Fatalf("key mismatch")
+		}
+	}
+}
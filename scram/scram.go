@@ -0,0 +1,188 @@
+// scram.go - SCRAM-SHA-256 credentials provisioned along with SRP verifiers
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+// Package scram derives SCRAM-SHA-256 (RFC 5802, RFC 7677) stored keys and
+// SRP verifiers from a single registration, so that operators running both
+// kinds of authentication (e.g., PostgreSQL and an SRP service) can
+// provision a user once. Like SRP verifiers, the SCRAM credentials are
+// computed by the client; the password never reaches the server.
+//
+// Passwords are used as given: they aren't normalized with SASLprep. This
+// matches PostgreSQL for passwords that SASLprep would leave unchanged
+// (e.g., ASCII passwords).
+package scram
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/tomsons/go-srp"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// Defaults for new credentials
+const (
+	DefaultIterations = 4096
+	DefaultSaltLen    = 16
+)
+
+// name of the mechanism in the PostgreSQL encoding
+const mechanism = "SCRAM-SHA-256"
+
+// Credentials are the SCRAM-SHA-256 values a server stores for a user
+type Credentials struct {
+	Salt       []byte
+	Iterations int
+	StoredKey  []byte
+	ServerKey  []byte
+}
+
+// NewCredentials derives the SCRAM-SHA-256 credentials for password 'p'
+// with 'salt' and 'iter' PBKDF2 iterations:
+//
+//	SaltedPassword = PBKDF2-HMAC-SHA256(p, salt, iter)
+//	StoredKey      = SHA256(HMAC(SaltedPassword, "Client Key"))
+//	ServerKey      = HMAC(SaltedPassword, "Server Key")
+func NewCredentials(p, salt []byte, iter int) (*Credentials, error) {
+	if iter <= 0 {
+		return nil, fmt.Errorf("scram: invalid iteration count %d", iter)
+	}
+	if len(salt) == 0 {
+		return nil, fmt.Errorf("scram: empty salt")
+	}
+
+	sp := pbkdf2.Key(p, salt, iter, sha256.Size, sha256.New)
+	ck := mac(sp, []byte("Client Key"))
+	sk := sha256.Sum256(ck)
+
+	return &Credentials{
+		Salt:       append([]byte{}, salt...),
+		Iterations: iter,
+		StoredKey:  sk[:],
+		ServerKey:  mac(sp, []byte("Server Key")),
+	}, nil
+}
+
+// String returns the credentials in the format PostgreSQL stores in
+// pg_authid.rolpassword:
+//
+//	SCRAM-SHA-256$<iter>:<salt>$<StoredKey>:<ServerKey>
+func (c *Credentials) String() string {
+	b64 := base64.StdEncoding.EncodeToString
+	return fmt.Sprintf("%s$%d:%s$%s:%s", mechanism, c.Iterations,
+		b64(c.Salt), b64(c.StoredKey), b64(c.ServerKey))
+}
+
+// Parse decodes credentials in the format returned by String()
+func Parse(s string) (*Credentials, error) {
+	v := strings.Split(s, "$")
+	if len(v) != 3 || v[0] != mechanism {
+		return nil, fmt.Errorf("scram: malformed credentials")
+	}
+
+	is := strings.Split(v[1], ":")
+	ks := strings.Split(v[2], ":")
+	if len(is) != 2 || len(ks) != 2 {
+		return nil, fmt.Errorf("scram: malformed credentials")
+	}
+
+	iter, err := strconv.Atoi(is[0])
+	if err != nil || iter <= 0 {
+		return nil, fmt.Errorf("scram: invalid iteration count %s", is[0])
+	}
+
+	var b [3][]byte
+	for i, z := range []string{is[1], ks[0], ks[1]} {
+		if b[i], err = base64.StdEncoding.DecodeString(z); err != nil {
+			return nil, fmt.Errorf("scram: malformed credentials: %s", err)
+		}
+	}
+	if len(b[1]) != sha256.Size || len(b[2]) != sha256.Size {
+		return nil, fmt.Errorf("scram: malformed credentials")
+	}
+
+	return &Credentials{
+		Salt:       b[0],
+		Iterations: iter,
+		StoredKey:  b[1],
+		ServerKey:  b[2],
+	}, nil
+}
+
+// VerifyClientProof returns true if 'proof' is a valid SCRAM ClientProof
+// for the 'authMessage' of an exchange.
+func (c *Credentials) VerifyClientProof(authMessage string, proof []byte) bool {
+	if len(proof) != sha256.Size {
+		return false
+	}
+
+	sig := mac(c.StoredKey, []byte(authMessage))
+	ck := make([]byte, len(proof))
+	for i := range proof {
+		ck[i] = proof[i] ^ sig[i]
+	}
+
+	sk := sha256.Sum256(ck)
+	return hmac.Equal(sk[:], c.StoredKey)
+}
+
+// ServerSignature returns the SCRAM ServerSignature for 'authMessage'
+func (c *Credentials) ServerSignature(authMessage string) []byte {
+	return mac(c.ServerKey, []byte(authMessage))
+}
+
+// Registration holds everything a client sends to provision a user for
+// both SRP and SCRAM.
+type Registration struct {
+	IdentityHash string // lookup key of the SRP verifier
+	Verifier     string // encoded SRP verifier
+	SCRAM        *Credentials
+}
+
+// Register creates the SRP verifier (in the environment 's') and the
+// SCRAM-SHA-256 credentials for identity 'I' and password 'p'. The SCRAM
+// salt is random and 'iter' is its PBKDF2 iteration count; zero means
+// DefaultIterations.
+func Register(s *srp.SRP, I, p []byte, iter int) (*Registration, error) {
+	if iter == 0 {
+		iter = DefaultIterations
+	}
+
+	v, err := s.Verifier(I, p, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, DefaultSaltLen)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, fmt.Errorf("scram: %s", err)
+	}
+
+	sc, err := NewCredentials(p, salt, iter)
+	if err != nil {
+		return nil, err
+	}
+
+	ih, vs := v.Encode()
+	return &Registration{
+		IdentityHash: ih,
+		Verifier:     vs,
+		SCRAM:        sc,
+	}, nil
+}
+
+// return HMAC-SHA256(key, msg)
+func mac(key, msg []byte) []byte {
+	m := hmac.New(sha256.New, key)
+	m.Write(msg)
+	return m.Sum(nil)
+}
@@ -0,0 +1,82 @@
+// self test for SCRAM credentials
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package scram
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+
+	"github.com/tomsons/go-srp"
+)
+
+// The example exchange of RFC 7677, section 3
+func TestRFC7677(t *testing.T) {
+	salt, _ := base64.StdEncoding.DecodeString("W22ZaJ0SNY7soEsUEjb6gQ==")
+	proof, _ := base64.StdEncoding.DecodeString("dHzbZapWIk4jUhN+Ute9ytag9zjfMHgsqmmiz7AndVQ=")
+	sig, _ := base64.StdEncoding.DecodeString("6rriTRBi23WpRR/wtup+mMhUZUn/dB5nLTJRsjl95G4=")
+
+	am := "n=user,r=rOprNGfwEbeRWgbNEkqO," +
+		"r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,s=W22ZaJ0SNY7soEsUEjb6gQ==,i=4096," +
+		"c=biws,r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0"
+
+	c, err := NewCredentials([]byte("pencil"), salt, 4096)
+	if err != nil {
+		t.Fatalf("NewCredentials: %s", err)
+	}
+
+	if !c.VerifyClientProof(am, proof) {
+		t.Fatalf("client proof rejected")
+	}
+	if c.VerifyClientProof(am+"x", proof) {
+		t.Fatalf("client proof accepted for another exchange")
+	}
+	if !bytes.Equal(c.ServerSignature(am), sig) {
+		t.Fatalf("server signature mismatch")
+	}
+
+	p, err := Parse(c.String())
+	if err != nil {
+		t.Fatalf("Parse: %s", err)
+	}
+	if p.String() != c.String() {
+		t.Fatalf("round trip mismatch:\n%s\n%s", c, p)
+	}
+
+	for _, bad := range []string{"", "SCRAM-SHA-1$4096:c2FsdA==$a:b", "SCRAM-SHA-256$x:c2FsdA==$a:b", "SCRAM-SHA-256$4096:c2FsdA==$YQ==:Yg=="} {
+		if _, err := Parse(bad); err == nil {
+			t.Fatalf("parsed malformed credentials %q", bad)
+		}
+	}
+}
+
+func TestRegister(t *testing.T) {
+	s, err := srp.New(2048)
+	if err != nil {
+		t.Fatalf("New: %s", err)
+	}
+
+	r, err := Register(s, []byte("user"), []byte("pass"), 0)
+	if err != nil {
+		t.Fatalf("Register: %s", err)
+	}
+	if r.SCRAM.Iterations != DefaultIterations || len(r.SCRAM.Salt) != DefaultSaltLen {
+		t.Fatalf("unexpected SCRAM parameters %s", r.SCRAM)
+	}
+
+	c, err := NewCredentials([]byte("pass"), r.SCRAM.Salt, r.SCRAM.Iterations)
+	if err != nil {
+		t.Fatalf("NewCredentials: %s", err)
+	}
+	if c.String() != r.SCRAM.String() {
+		t.Fatalf("SCRAM credentials mismatch")
+	}
+
+	if _, _, err := srp.MakeSRPVerifier(r.Verifier); err != nil {
+		t.Fatalf("MakeSRPVerifier: %s", err)
+	}
+}
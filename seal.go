@@ -0,0 +1,114 @@
+// seal.go - encrypting application secrets under the session key
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"fmt"
+	"hash"
+	"io"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+)
+
+// After a successful handshake, either side can send secrets (e.g., the key
+// of the user's vault) to the other, sealed with XChaCha20-Poly1305 under a
+// key derived from K for the direction of the message:
+//
+//	Kcs = HKDF(K, "srp seal client")   client -> server
+//	Ksc = HKDF(K, "srp seal server")   server -> client
+//
+// Each message is a random 24 byte nonce followed by the ciphertext; random
+// nonces of this size are safe for any practical number of messages. The
+// optional associated data is authenticated but not sent.
+
+// Labels for the direction-specific sealing keys
+var (
+	sealClientLabel = []byte("srp seal client")
+	sealServerLabel = []byte("srp seal server")
+)
+
+// Seal encrypts 'plaintext' for the client; 'ad' is authenticated along
+// with it and may be nil. The client must have been authenticated.
+func (s *Server) Seal(plaintext, ad []byte) ([]byte, error) {
+	s.s.misuse(!s.authed, "Server.Seal() before the client's proof was verified")
+	if !s.authed {
+		return nil, fmt.Errorf("srp: client isn't authenticated")
+	}
+	return s.s.seal(s.xK, sealServerLabel, plaintext, ad)
+}
+
+// Open decrypts a message sealed by Client.Seal()
+func (s *Server) Open(ciphertext, ad []byte) ([]byte, error) {
+	s.s.misuse(!s.authed, "Server.Open() before the client's proof was verified")
+	if !s.authed {
+		return nil, fmt.Errorf("srp: client isn't authenticated")
+	}
+	return s.s.open(s.xK, sealClientLabel, ciphertext, ad)
+}
+
+// Seal encrypts 'plaintext' for the server; 'ad' is authenticated along
+// with it and may be nil. The server must have been authenticated.
+func (c *Client) Seal(plaintext, ad []byte) ([]byte, error) {
+	c.s.misuse(!c.authed, "Client.Seal() before the server's proof was verified")
+	if !c.authed {
+		return nil, fmt.Errorf("srp: server isn't authenticated")
+	}
+	return c.s.seal(c.xK, sealClientLabel, plaintext, ad)
+}
+
+// Open decrypts a message sealed by Server.Seal()
+func (c *Client) Open(ciphertext, ad []byte) ([]byte, error) {
+	c.s.misuse(!c.authed, "Client.Open() before the server's proof was verified")
+	if !c.authed {
+		return nil, fmt.Errorf("srp: server isn't authenticated")
+	}
+	return c.s.open(c.xK, sealServerLabel, ciphertext, ad)
+}
+
+// seal 'pt' under the key for direction 'label'
+func (s *SRP) seal(K, label, pt, ad []byte) ([]byte, error) {
+	aead, err := chacha20poly1305.NewX(s.expandKey(K, label, chacha20poly1305.KeySize))
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := s.randbytes(aead.NonceSize())
+	return aead.Seal(nonce, nonce, pt, ad), nil
+}
+
+// open 'ct' sealed under the key for direction 'label'
+func (s *SRP) open(K, label, ct, ad []byte) ([]byte, error) {
+	aead, err := chacha20poly1305.NewX(s.expandKey(K, label, chacha20poly1305.KeySize))
+	if err != nil {
+		return nil, err
+	}
+
+	n := aead.NonceSize()
+	if len(ct) < n {
+		return nil, fmt.Errorf("srp: sealed message too short")
+	}
+
+	pt, err := aead.Open(nil, ct[:n], ct[n:], ad)
+	if err != nil {
+		return nil, fmt.Errorf("srp: can't open sealed message")
+	}
+	return pt, nil
+}
+
+// derive an 'n' byte key for purpose 'info' from the session key 'K'
+func (s *SRP) expandKey(K, info []byte, n int) []byte {
+	hf := func() hash.Hash {
+		return newHash(s.h)
+	}
+
+	key := make([]byte, n)
+	if _, err := io.ReadFull(hkdf.New(hf, K, nil, info), key); err != nil {
+		panic(fmt.Sprintf("srp: hkdf: %s", err))
+	}
+	return key
+}
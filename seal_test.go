@@ -0,0 +1,76 @@
+// self test for sealing secrets under the session key
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+)
+
+func TestSeal(t *testing.T) {
+	assert := newAsserter(t)
+
+	user := []byte("user")
+	pass := []byte("pass")
+	secret := []byte("vault key")
+	ad := []byte("user")
+
+	s, err := New(2048)
+	assert(err == nil, "New: %s", err)
+
+	v, err := s.Verifier(user, pass, nil)
+	assert(err == nil, "Verifier: %s", err)
+
+	c, err := s.NewClient(user, pass)
+	assert(err == nil, "NewClient: %s", err)
+
+	srv, err := s.NewServer(v, big.NewInt(0).SetBytes(c.Hello().A))
+	assert(err == nil, "NewServer: %s", err)
+
+	m, err := c.Respond(srv.Challenge())
+	assert(err == nil, "Respond: %s", err)
+
+	_, err = srv.Seal(secret, ad)
+	assert(err != nil, "sealed before the client was authenticated")
+
+	proof, ok := srv.CheckProof(m)
+	assert(ok, "CheckProof failed")
+
+	ct, err := srv.Seal(secret, ad)
+	assert(err == nil, "Seal: %s", err)
+
+	_, err = c.Open(ct, ad)
+	assert(err != nil, "opened before the server was authenticated")
+
+	assert(c.CheckProof(proof), "CheckProof failed")
+
+	pt, err := c.Open(ct, ad)
+	assert(err == nil, "Open: %s", err)
+	assert(bytes.Equal(pt, secret), "plaintext mismatch")
+
+	ct2, err := srv.Seal(secret, ad)
+	assert(err == nil, "Seal: %s", err)
+	assert(!bytes.Equal(ct, ct2), "nonce reused")
+
+	_, err = c.Open(ct, []byte("other"))
+	assert(err != nil, "opened with wrong associated data")
+
+	// messages can't be reflected to their sender
+	_, err = srv.Open(ct, ad)
+	assert(err != nil, "server opened its own message")
+
+	ct, err = c.Seal(secret, nil)
+	assert(err == nil, "Seal: %s", err)
+
+	pt, err = srv.Open(ct, nil)
+	assert(err == nil, "Open: %s", err)
+	assert(bytes.Equal(pt, secret), "plaintext mismatch")
+
+	_, err = srv.Open(ct[:10], nil)
+	assert(err != nil, "opened truncated message")
+}
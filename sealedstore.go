@@ -0,0 +1,200 @@
+// sealedstore.go - verifier stores encrypted at rest
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// A verifier is as good as a password hash to an attacker: it allows an
+// offline dictionary attack. SealedVerifierStore encrypts verifiers before
+// they reach the underlying store, so that a copy of the database (e.g., a
+// backup) is useless without the keys, which are kept elsewhere (e.g.,
+// data keys decrypted by a KMS when the server starts). A sealed record is
+//
+//	"sv1:" key id ":" base64url(nonce || XChaCha20-Poly1305(verifier))
+//
+// with the storage key and the key id as associated data, so that records
+// can't be swapped between users. Keys are rotated by making a new key
+// current in the KeyProvider: new and updated verifiers are sealed under
+// it, records under older keys stay readable while their keys are provided
+// and Reseal() moves them to the current key.
+
+// size of the keys of a SealedVerifierStore
+const SealedStoreKeySize = chacha20poly1305.KeySize
+
+const sealedRecordPrefix = "sv1:"
+
+// ErrUnsealVerifier is matched (with errors.Is()) by the errors of sealed
+// records that can't be decrypted: their key is unknown or they were
+// modified.
+var ErrUnsealVerifier = fmt.Errorf("srp: can't unseal verifier")
+
+// KeyProvider supplies the keys of a SealedVerifierStore; an
+// implementation typically fetches data keys from a KMS. Keys are
+// SealedStoreKeySize bytes; key ids must not contain ':'.
+type KeyProvider interface {
+	// CurrentKey returns the id and key under which records are sealed
+	CurrentKey() (id string, key []byte, err error)
+
+	// Key returns the key with id 'id'
+	Key(id string) ([]byte, error)
+}
+
+// KeyRing is a KeyProvider of keys held in memory
+type KeyRing struct {
+	Current string            // id of the key that seals new records
+	Keys    map[string][]byte // all keys by id, including retired ones
+}
+
+// CurrentKey implements KeyProvider
+func (kr *KeyRing) CurrentKey() (string, []byte, error) {
+	key, err := kr.Key(kr.Current)
+	return kr.Current, key, err
+}
+
+// Key implements KeyProvider
+func (kr *KeyRing) Key(id string) ([]byte, error) {
+	key, ok := kr.Keys[id]
+	if !ok {
+		return nil, fmt.Errorf("srp: unknown verifier key %q", id)
+	}
+	return key, nil
+}
+
+// SealedVerifierStore is a VerifierStore that encrypts the verifiers it
+// stores in another VerifierStore.
+type SealedVerifierStore struct {
+	st VerifierStore
+	kp KeyProvider
+
+	// AllowPlaintext makes Get() return records that aren't sealed as is,
+	// so that an existing store can be sealed gradually (see Reseal()).
+	AllowPlaintext bool
+}
+
+// NewSealedVerifierStore returns a store that seals verifiers with keys of
+// 'kp' and keeps them in 'st'.
+func NewSealedVerifierStore(st VerifierStore, kp KeyProvider) *SealedVerifierStore {
+	return &SealedVerifierStore{st: st, kp: kp}
+}
+
+// Get implements VerifierStore
+func (ss *SealedVerifierStore) Get(key string) (string, error) {
+	rec, err := ss.st.Get(key)
+	if err != nil {
+		return "", err
+	}
+	vh, _, err := ss.unseal(key, rec)
+	return vh, err
+}
+
+// Put implements VerifierStore
+func (ss *SealedVerifierStore) Put(key, vh string) error {
+	rec, err := ss.seal(key, vh)
+	if err != nil {
+		return err
+	}
+	return ss.st.Put(key, rec)
+}
+
+// Delete implements VerifierStore
+func (ss *SealedVerifierStore) Delete(key string) error {
+	return ss.st.Delete(key)
+}
+
+// Reseal seals the record stored under 'key' with the current key if it
+// was sealed with another key (or, with AllowPlaintext, isn't sealed). A
+// key rotation ends by calling it for every key of the store; the old key
+// can be retired after that.
+func (ss *SealedVerifierStore) Reseal(key string) error {
+	rec, err := ss.st.Get(key)
+	if err != nil {
+		return err
+	}
+	vh, id, err := ss.unseal(key, rec)
+	if err != nil {
+		return err
+	}
+
+	cur, _, err := ss.kp.CurrentKey()
+	if err != nil {
+		return err
+	}
+	if id == cur {
+		return nil
+	}
+	return ss.Put(key, vh)
+}
+
+// return the sealed record of the verifier 'vh' stored under 'key'
+func (ss *SealedVerifierStore) seal(key, vh string) (string, error) {
+	id, k, err := ss.kp.CurrentKey()
+	if err != nil {
+		return "", err
+	}
+	if strings.IndexByte(id, ':') >= 0 {
+		return "", fmt.Errorf("srp: invalid verifier key id %q", id)
+	}
+
+	aead, err := chacha20poly1305.NewX(k)
+	if err != nil {
+		return "", fmt.Errorf("srp: verifier key %q: %s", id, err)
+	}
+
+	nonce := randbytes(aead.NonceSize())
+	ct := aead.Seal(nonce, nonce, []byte(vh), sealedRecordAD(key, id))
+	return sealedRecordPrefix + id + ":" + base64.RawURLEncoding.EncodeToString(ct), nil
+}
+
+// return the verifier in the record 'rec' stored under 'key' and the id of
+// the key that sealed it ("" if it isn't sealed)
+func (ss *SealedVerifierStore) unseal(key, rec string) (string, string, error) {
+	if !strings.HasPrefix(rec, sealedRecordPrefix) {
+		if ss.AllowPlaintext {
+			return rec, "", nil
+		}
+		return "", "", fmt.Errorf("%w (not sealed)", ErrUnsealVerifier)
+	}
+
+	rest := rec[len(sealedRecordPrefix):]
+	i := strings.IndexByte(rest, ':')
+	if i < 0 {
+		return "", "", fmt.Errorf("%w (malformed record)", ErrUnsealVerifier)
+	}
+	id := rest[:i]
+
+	k, err := ss.kp.Key(id)
+	if err != nil {
+		return "", "", fmt.Errorf("%w (%s)", ErrUnsealVerifier, err)
+	}
+	aead, err := chacha20poly1305.NewX(k)
+	if err != nil {
+		return "", "", fmt.Errorf("%w (key %q: %s)", ErrUnsealVerifier, id, err)
+	}
+
+	ct, err := base64.RawURLEncoding.DecodeString(rest[i+1:])
+	if err != nil || len(ct) < aead.NonceSize() {
+		return "", "", fmt.Errorf("%w (malformed record)", ErrUnsealVerifier)
+	}
+	n := aead.NonceSize()
+	vh, err := aead.Open(nil, ct[:n], ct[n:], sealedRecordAD(key, id))
+	if err != nil {
+		return "", "", fmt.Errorf("%w (key %q)", ErrUnsealVerifier, id)
+	}
+	return string(vh), id, nil
+}
+
+// return the associated data of a record stored under 'key' and sealed
+// with the key 'id'
+func sealedRecordAD(key, id string) []byte {
+	return []byte(sealedRecordPrefix + id + ":" + key)
+}
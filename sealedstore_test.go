@@ -0,0 +1,85 @@
+// self test for sealed verifier stores
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSealedVerifierStore(t *testing.T) {
+	assert := newAsserter(t)
+
+	kr := &KeyRing{
+		Current: "k1",
+		Keys:    map[string][]byte{"k1": randbytes(SealedStoreKeySize)},
+	}
+	ms := &mapStore{m: map[string]string{}}
+	st := NewSealedVerifierStore(ms, kr)
+
+	s, err := New(2048)
+	assert(err == nil, "New: %s", err)
+	v, err := s.Verifier([]byte("user"), []byte("pass"), nil)
+	assert(err == nil, "Verifier: %s", err)
+	assert(StoreVerifier(st, v) == nil, "StoreVerifier failed")
+
+	key := v.StorageKey()
+	_, vh := v.Encode()
+	rec := ms.m[key]
+	assert(strings.HasPrefix(rec, "sv1:k1:"), "record not sealed: %s", rec)
+	assert(!strings.Contains(rec, vh[len(vh)-32:]), "verifier in the clear")
+
+	// lookups see the plain verifier
+	lookup, err := StoreLookup(st)
+	assert(err == nil, "StoreLookup: %s", err)
+	c, err := s.NewClient([]byte("user"), []byte("pass"))
+	assert(err == nil, "NewClient: %s", err)
+	vs, v2, err := lookup(c.Hello().IdentityHash)
+	assert(err == nil, "lookup: %s", err)
+	srv, err := vs.NewServerFor(c.Hello().IdentityHash, v2, c.xA)
+	assert(err == nil, "NewServerFor: %s", err)
+	assert(authenticate(c, srv), "login failed")
+
+	// records can't be moved to another user or modified
+	ms.m["other"] = rec
+	_, err = st.Get("other")
+	assert(errors.Is(err, ErrUnsealVerifier), "swapped record: %v", err)
+	ms.m["other"] = rec[:len(rec)-2] + "AA"
+	_, err = st.Get("other")
+	assert(errors.Is(err, ErrUnsealVerifier), "modified record: %v", err)
+
+	// plaintext records only with AllowPlaintext
+	ms.m["plain"] = vh
+	_, err = st.Get("plain")
+	assert(errors.Is(err, ErrUnsealVerifier), "plaintext accepted: %v", err)
+	st.AllowPlaintext = true
+	got, err := st.Get("plain")
+	assert(err == nil && got == vh, "plaintext: %v", err)
+	assert(st.Reseal("plain") == nil, "Reseal plaintext failed")
+	assert(strings.HasPrefix(ms.m["plain"], "sv1:k1:"), "plaintext not resealed")
+	st.AllowPlaintext = false
+
+	// rotation: old records stay readable and move to the new key
+	kr.Keys["k2"] = randbytes(SealedStoreKeySize)
+	kr.Current = "k2"
+	got, err = st.Get(key)
+	assert(err == nil && got == vh, "old key: %v", err)
+	assert(st.Reseal(key) == nil, "Reseal failed")
+	assert(strings.HasPrefix(ms.m[key], "sv1:k2:"), "not resealed: %s", ms.m[key])
+	rec = ms.m[key]
+	assert(st.Reseal(key) == nil && ms.m[key] == rec, "resealed under the current key")
+
+	delete(kr.Keys, "k1")
+	got, err = st.Get(key)
+	assert(err == nil && got == vh, "after retiring k1: %v", err)
+
+	// a bad key id is refused
+	kr.Keys["a:b"] = randbytes(SealedStoreKeySize)
+	kr.Current = "a:b"
+	assert(st.Put(key, vh) != nil, "accepted key id with ':'")
+}
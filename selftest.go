@@ -0,0 +1,182 @@
+// selftest.go - loopback self test of the SRP implementation
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"crypto"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SelfTestResult is the outcome of one loopback handshake run by SelfTest()
+type SelfTestResult struct {
+	Bits     int           // prime-field size
+	Hash     crypto.Hash   // hash function (or a registered hash id)
+	Err      error         // nil if the handshake behaved correctly
+	Duration time.Duration // time taken
+}
+
+// SelfTestReport holds the results of SelfTest()
+type SelfTestReport struct {
+	Results []SelfTestResult
+}
+
+// Ok returns true if every self test passed
+func (r *SelfTestReport) Ok() bool {
+	return r.Err() == nil
+}
+
+// Err returns an error describing all failed self tests; it returns nil if
+// there were none.
+func (r *SelfTestReport) Err() error {
+	var s []string
+	for i := range r.Results {
+		x := &r.Results[i]
+		if x.Err != nil {
+			s = append(s, fmt.Sprintf("%d bits, hash %d: %s", x.Bits, x.Hash, x.Err))
+		}
+	}
+
+	if len(s) > 0 {
+		return fmt.Errorf("srp: self test failed: %s", strings.Join(s, "; "))
+	}
+	return nil
+}
+
+// SelfTest runs a loopback handshake for every built-in prime field (using
+// the default hash) and for every available hash function (using a
+// MinimumBits sized field). Each handshake must succeed with the right
+// password and fail with a wrong one. This is akin to the power-on self test
+// required in some regulated environments; such callers can run it from an
+// init() function and refuse to start if the report isn't Ok(). A "fips"
+// build only tests the fields and hash functions approved in FIPS mode.
+func SelfTest() *SelfTestReport {
+	r := &SelfTestReport{}
+
+	bits := make([]int, 0, len(pflist))
+	for b := range pflist {
+		if !FIPSBuild || b >= fipsMinBits {
+			bits = append(bits, b)
+		}
+	}
+	sort.Ints(bits)
+
+	var env SRP
+	dh := env.defaultHash()
+	for _, b := range bits {
+		r.Results = append(r.Results, selfTest(b, dh))
+	}
+
+	for _, h := range availableHashes() {
+		if h != dh && (!FIPSBuild || fipsHashes[h]) {
+			r.Results = append(r.Results, selfTest(MinimumBits, h))
+		}
+	}
+	return r
+}
+
+// run one loopback handshake
+func selfTest(bits int, h crypto.Hash) SelfTestResult {
+	t0 := time.Now()
+	err := loopback(bits, h)
+	return SelfTestResult{
+		Bits:     bits,
+		Hash:     h,
+		Err:      err,
+		Duration: time.Since(t0),
+	}
+}
+
+// authenticate with the right and a wrong password via the string API
+func loopback(bits int, h crypto.Hash) error {
+	user := []byte("srp-self-test")
+	pass := []byte("correct horse battery staple")
+
+	s, err := NewWithHash(h, bits, WithInsecureGroups())
+	if err != nil {
+		return err
+	}
+
+	v, err := s.Verifier(user, pass, nil)
+	if err != nil {
+		return err
+	}
+
+	_, vh := v.Encode()
+	for i, pw := range [][]byte{pass, []byte("wrong password")} {
+		c, err := s.NewClient(user, pw)
+		if err != nil {
+			return err
+		}
+
+		_, A, err := ServerBegin(c.Credentials())
+		if err != nil {
+			return err
+		}
+
+		ss, sv, err := MakeSRPVerifier(vh)
+		if err != nil {
+			return err
+		}
+
+		srv, err := ss.NewServer(sv, A)
+		if err != nil {
+			return err
+		}
+
+		m, err := c.Generate(srv.Credentials())
+		if err != nil {
+			return err
+		}
+
+		proof, ok := srv.ClientOk(m)
+		good := i == 0
+		switch {
+		case good && !ok:
+			return fmt.Errorf("server rejected the right password")
+		case !good && ok:
+			return fmt.Errorf("server accepted a wrong password")
+		case !good:
+			continue
+		}
+
+		if !c.ServerOk(proof) {
+			return fmt.Errorf("client rejected the server proof")
+		}
+		if !ctEqual(c.RawKey(), srv.RawKey()) {
+			return fmt.Errorf("key mismatch")
+		}
+	}
+	return nil
+}
+
+// return the available hash functions: those from "crypto" followed by the
+// registered ones.
+func availableHashes() []crypto.Hash {
+	var hs []crypto.Hash
+
+	for h := crypto.Hash(1); h < CustomHashMin; h++ {
+		if h.Available() {
+			hs = append(hs, h)
+		}
+	}
+
+	hashes.RLock()
+	ids := make([]int, 0, len(hashes.m))
+	for id := range hashes.m {
+		ids = append(ids, int(id))
+	}
+	hashes.RUnlock()
+
+	sort.Ints(ids)
+	for _, id := range ids {
+		hs = append(hs, crypto.Hash(id))
+	}
+	return hs
+}
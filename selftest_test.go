@@ -0,0 +1,42 @@
+// self test for SelfTest()
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSelfTest(t *testing.T) {
+	assert := newAsserter(t)
+
+	r := SelfTest()
+	assert(r.Ok(), "self test: %s", r.Err())
+
+	var env SRP
+	var groups, hashes int
+	for _, x := range r.Results {
+		if x.Hash == env.defaultHash() {
+			groups++
+		} else {
+			hashes++
+		}
+		t.Logf("%d bits, hash %d: %s\n", x.Bits, x.Hash, x.Duration)
+	}
+	exp := 0
+	for b := range pflist {
+		if !FIPSBuild || b >= fipsMinBits {
+			exp++
+		}
+	}
+	assert(groups == exp, "exp %d groups, saw %d", exp, groups)
+	// SHA-384 and SHA-512 aren't linked into a "fips" build of the tests
+	assert(hashes > 0 || FIPSBuild, "no hashes tested")
+
+	r.Results[0].Err = errors.New("injected failure")
+	assert(!r.Ok(), "report ok with a failure")
+}
@@ -0,0 +1,54 @@
+// session.go - interfaces of the two sides of a handshake
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+// ClientSession is the client side of a handshake as used by
+// applications: *Client implements it. Code written against it can be
+// tested with a mock instead of running the handshake, and another
+// password-authenticated key exchange (e.g., OPAQUE or SPAKE2+) can be
+// adapted to it by carrying its messages in the fields of
+// ClientCredentials and ServerCredentials.
+type ClientSession interface {
+	// Hello returns the first message, to be sent to the server
+	Hello() ClientCredentials
+
+	// Respond processes the server's challenge and returns the client's
+	// proof, to be sent to the server
+	Respond(sc ServerCredentials) ([]byte, error)
+
+	// CheckProof returns true if the server's proof authenticates it
+	CheckProof(proof []byte) bool
+
+	// RawKey returns the session key once the handshake is done
+	RawKey() []byte
+
+	// Wipe erases the secrets of the session
+	Wipe()
+}
+
+// ServerSession is the server side of a handshake as used by
+// applications, after the user's verifier was found: *Server implements
+// it. See ClientSession.
+type ServerSession interface {
+	// Challenge returns the server's reply to the client's hello
+	Challenge() ServerCredentials
+
+	// CheckProof verifies the client's proof and returns the server's
+	// proof, to be sent to the client
+	CheckProof(m []byte) (proof []byte, ok bool)
+
+	// RawKey returns the session key once the handshake is done
+	RawKey() []byte
+
+	// Wipe erases the secrets of the session
+	Wipe()
+}
+
+var (
+	_ ClientSession = (*Client)(nil)
+	_ ServerSession = (*Server)(nil)
+)
@@ -0,0 +1,73 @@
+// self test for the session interfaces
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"bytes"
+	"testing"
+)
+
+// a handshake between any two sessions, as an application would run it
+func exchange(c ClientSession, s ServerSession) ([]byte, bool) {
+	c.Hello()
+	m, err := c.Respond(s.Challenge())
+	if err != nil {
+		return nil, false
+	}
+	proof, ok := s.CheckProof(m)
+	if !ok || !c.CheckProof(proof) {
+		return nil, false
+	}
+	return c.RawKey(), bytes.Equal(c.RawKey(), s.RawKey())
+}
+
+// mockSession accepts the client proof "ok" and shares a fixed key
+type mockSession struct {
+	key   []byte
+	wiped bool
+}
+
+func (m *mockSession) Hello() ClientCredentials { return ClientCredentials{} }
+func (m *mockSession) Respond(ServerCredentials) ([]byte, error) {
+	return []byte("ok"), nil
+}
+func (m *mockSession) CheckProof(p []byte) bool     { return string(p) == "ok" }
+func (m *mockSession) Challenge() ServerCredentials { return ServerCredentials{} }
+func (m *mockSession) RawKey() []byte               { return m.key }
+func (m *mockSession) Wipe()                        { m.wiped = true }
+
+type mockServer struct{ mockSession }
+
+func (m *mockServer) CheckProof(p []byte) ([]byte, bool) {
+	return []byte("ok"), string(p) == "ok"
+}
+
+func TestSessionInterfaces(t *testing.T) {
+	assert := newAsserter(t)
+
+	s, err := New(2048)
+	assert(err == nil, "New: %s", err)
+	v, err := s.Verifier([]byte("user"), []byte("pass"), nil)
+	assert(err == nil, "Verifier: %s", err)
+
+	c, err := s.NewClient([]byte("user"), []byte("pass"))
+	assert(err == nil, "NewClient: %s", err)
+	srv, err := s.NewServer(v, c.xA)
+	assert(err == nil, "NewServer: %s", err)
+
+	var cs ClientSession = c
+	var ss ServerSession = srv
+	key, ok := exchange(cs, ss)
+	assert(ok && len(key) > 0, "handshake failed")
+	cs.Wipe()
+	ss.Wipe()
+
+	// a mock stands in for either side
+	key = []byte("key")
+	_, ok = exchange(&mockSession{key: key}, &mockServer{mockSession{key: key}})
+	assert(ok, "mock handshake failed")
+}
@@ -0,0 +1,104 @@
+// sign.go - signed server challenges for tamper detection before key agreement
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"crypto/ed25519"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+)
+
+// SRP itself detects a tampered <s, B> only when the server's proof fails,
+// i.e., after the client has done its expensive exponentiation. A server
+// can instead sign each challenge with a per-deployment Ed25519 key whose
+// public half is built into its clients; a client then rejects a tampered
+// challenge before doing any work. A MAC would not do: its key would be in
+// every client and anyone could compute it. The signature covers the
+// client's hashed identity and A so that it can't be replayed to another
+// handshake:
+//
+//	sig = Ed25519(priv, "srp challenge" || I || A || s || B || kdf || grp || pow || x)
+//
+// where every field is prefixed by its length as a 16-bit big-endian
+// number, A and B have no leading zeros and kdf and pow are the text forms
+// of the KDF and Puzzle ("" if none). This doesn't replace the proofs; it only lets
+// constrained clients fail fast.
+
+var challengeLabel = []byte("srp challenge")
+
+// ErrBadChallengeSignature is returned by clients with
+// WithChallengeVerifyKey() when the server's challenge isn't signed or its
+// signature is invalid.
+var ErrBadChallengeSignature = fmt.Errorf("srp: invalid server challenge signature")
+
+// WithChallengeSigningKey makes servers in this environment sign their
+// challenges <s, B> with 'priv' (see ServerCredentials.Signature).
+func WithChallengeSigningKey(priv ed25519.PrivateKey) Option {
+	return func(s *SRP) error {
+		if len(priv) != ed25519.PrivateKeySize {
+			return fmt.Errorf("srp: invalid challenge signing key")
+		}
+		s.csk = append(ed25519.PrivateKey{}, priv...)
+		return nil
+	}
+}
+
+// WithChallengeVerifyKey makes clients in this environment reject server
+// challenges that aren't signed with the private half of 'pub', before
+// they compute anything with them.
+func WithChallengeVerifyKey(pub ed25519.PublicKey) Option {
+	return func(s *SRP) error {
+		if len(pub) != ed25519.PublicKeySize {
+			return fmt.Errorf("srp: invalid challenge verify key")
+		}
+		s.cvk = append(ed25519.PublicKey{}, pub...)
+		return nil
+	}
+}
+
+// sign the challenge 'sc' for the client with hashed identity 'I' and
+// public key 'A'
+func (s *SRP) signChallenge(sc *ServerCredentials, I []byte, A *big.Int) {
+	if s.csk != nil {
+		sc.Signature = ed25519.Sign(s.csk, challengeMessage(sc, I, A))
+	}
+}
+
+// verify the signature of the challenge 'sc' if this environment requires
+// one
+func (s *SRP) verifyChallenge(sc *ServerCredentials, I []byte, A *big.Int) error {
+	if s.cvk == nil {
+		return nil
+	}
+	if len(sc.Signature) != ed25519.SignatureSize ||
+		!ed25519.Verify(s.cvk, challengeMessage(sc, I, A), sc.Signature) {
+		return ErrBadChallengeSignature
+	}
+	return nil
+}
+
+// return the message that is signed for the challenge 'sc'
+func challengeMessage(sc *ServerCredentials, I []byte, A *big.Int) []byte {
+	var kdf, pow string
+	if sc.KDF != nil {
+		kdf = sc.KDF.String()
+	}
+	if sc.Puzzle != nil {
+		pow = sc.Puzzle.String()
+	}
+
+	B := big.NewInt(0).SetBytes(sc.B)
+	m := append([]byte{}, challengeLabel...)
+	for _, f := range [][]byte{I, A.Bytes(), sc.Salt, B.Bytes(), []byte(kdf), []byte(sc.Group), []byte(pow), []byte(sc.X)} {
+		var n [2]byte
+		binary.BigEndian.PutUint16(n[:], uint16(len(f)))
+		m = append(m, n[:]...)
+		m = append(m, f...)
+	}
+	return m
+}
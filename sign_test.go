@@ -0,0 +1,100 @@
+// self test for signed server challenges
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"testing"
+)
+
+func TestSignedChallenge(t *testing.T) {
+	assert := newAsserter(t)
+
+	user := []byte("user")
+	pass := []byte("pass")
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	assert(err == nil, "GenerateKey: %s", err)
+	pub2, _, err := ed25519.GenerateKey(rand.Reader)
+	assert(err == nil, "GenerateKey: %s", err)
+
+	cs, err := New(2048, WithChallengeVerifyKey(pub))
+	assert(err == nil, "New: %s", err)
+
+	v, err := cs.Verifier(user, pass, nil)
+	assert(err == nil, "Verifier: %s", err)
+	_, vh := v.Encode()
+
+	// start a handshake and return the client and the signed challenge
+	begin := func(opts ...Option) (*Client, *Server) {
+		c, err := cs.NewClient(user, pass)
+		assert(err == nil, "NewClient: %s", err)
+
+		_, A, err := ServerBegin(c.Credentials())
+		assert(err == nil, "ServerBegin: %s", err)
+
+		ss, sv, err := MakeSRPVerifier(vh, opts...)
+		assert(err == nil, "MakeSRPVerifier: %s", err)
+
+		srv, err := ss.NewServer(sv, A)
+		assert(err == nil, "NewServer: %s", err)
+		return c, srv
+	}
+
+	c, srv := begin(WithChallengeSigningKey(priv))
+	m, err := c.Generate(srv.Credentials())
+	assert(err == nil, "Generate: %s", err)
+	proof, ok := srv.ClientOk(m)
+	assert(ok, "ClientOk failed")
+	assert(c.ServerOk(proof), "ServerOk failed")
+
+	// the signature survives CBOR
+	c, srv = begin(WithChallengeSigningKey(priv))
+	sc := srv.Challenge()
+	sc, err = DecodeServerCredentialsCBOR(sc.EncodeCBOR())
+	assert(err == nil, "DecodeServerCredentialsCBOR: %s", err)
+	_, err = c.Respond(sc)
+	assert(err == nil, "Respond: %s", err)
+
+	// unsigned
+	c, srv = begin()
+	_, err = c.Generate(srv.Credentials())
+	assert(errors.Is(err, ErrBadChallengeSignature), "unsigned challenge: %v", err)
+
+	// tampered salt
+	c, srv = begin(WithChallengeSigningKey(priv))
+	sc = srv.Challenge()
+	sc.Salt = append([]byte{}, sc.Salt...)
+	sc.Salt[0] ^= 1
+	_, err = c.Respond(sc)
+	assert(errors.Is(err, ErrBadChallengeSignature), "tampered challenge: %v", err)
+
+	// a challenge signed for another client
+	c, _ = begin(WithChallengeSigningKey(priv))
+	_, srv = begin(WithChallengeSigningKey(priv))
+	_, err = c.Respond(srv.Challenge())
+	assert(errors.Is(err, ErrBadChallengeSignature), "replayed challenge: %v", err)
+
+	// wrong key
+	c2, err := New(2048, WithChallengeVerifyKey(pub2))
+	assert(err == nil, "New: %s", err)
+	cl, err := c2.NewClient(user, pass)
+	assert(err == nil, "NewClient: %s", err)
+	_, A, err := ServerBegin(cl.Credentials())
+	assert(err == nil, "ServerBegin: %s", err)
+	ss, sv, err := MakeSRPVerifier(vh, WithChallengeSigningKey(priv))
+	assert(err == nil, "MakeSRPVerifier: %s", err)
+	srv, err = ss.NewServer(sv, A)
+	assert(err == nil, "NewServer: %s", err)
+	_, err = cl.Generate(srv.Credentials())
+	assert(errors.Is(err, ErrBadChallengeSignature), "wrong key: %v", err)
+
+	_, err = New(2048, WithChallengeSigningKey(priv[:10]))
+	assert(err != nil, "short signing key accepted")
+}
@@ -21,6 +21,8 @@
 //     M = H(K, A, B, I, s, N, g)
 //     M' = H(M, K)
 //
+// The construction is selectable per environment (see WithProofScheme());
+// ProofRFC5054 implements the one from the paper.
 //
 // In this implementation:
 //
@@ -123,28 +125,102 @@ package srp
 import (
 	"bytes"
 	"crypto"
+	"crypto/ed25519"
 	CR "crypto/rand"
+	"crypto/sha256"
 	"crypto/subtle"
 	"encoding/hex"
 	"fmt"
 	"io"
 	"math/big"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	// stdlib has an enum for Blake2b_256; this lib registers itself against it.
 	_ "golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/hkdf"
 )
 
 // SRP represents an environment for the client and server to share certain properties;
 // notably the hash function and prime-field size.  The default hash function is
-// Blake2b-256. Any valid hash function as documented in "crypto" can be used.
-// There are two ways for creating an SRP environment:
+// Blake2b-256. Any valid hash function as documented in "crypto" can be used; other
+// hash functions can be used via WithHasher().
+// There are three ways for creating an SRP environment:
 //   New()
 //   NewWithHash()
+//   NewDefault()
 type SRP struct {
-	h  crypto.Hash
+	h  crypto.Hash // ids >= CustomHashMin are registered hashes
 	pf *primeField
+
+	weak    bool   // permit prime fields smaller than MinimumBits
+	saltLen int    // salt size in bytes; 0 => same as the prime field
+	ephBits int    // size of a, b in bits; 0 => same as the prime field
+	kdf     *KDF   // password hardening for new verifiers
+	xd      string // derivation of x for new verifiers; see WithHMACPrivateKey()
+
+	rc  ReplayCache // servers reject replayed A and M
+	gp  GroupPolicy // consulted at the start of each handshake
+	lim Limits      // size limits for wire messages
+
+	tctx []byte        // application context bound into the proofs
+	ps   ProofScheme   // nil => ProofLegacy
+	alt  []ProofScheme // also accepted by servers during a migration
+	noid bool          // leave I out of the proofs
+
+	keyBits int // size of K; 0 => size of the hash. See WithSessionKeyBits()
+
+	pin []byte // expected Fingerprint(); see PinServerParams()
+
+	ph  crypto.Hash   // of K and the proofs; 0 => h. See WithProofHash()
+	aph []crypto.Hash // also accepted by servers during a migration
+
+	idk []byte // key for blinding identities in verifiers
+
+	skLen int // size of storage keys in bytes; see WithStorageKeyLen()
+
+	csk ed25519.PrivateKey // servers sign challenges with it
+	cvk ed25519.PublicKey  // clients require challenges signed for it
+
+	pz    *Puzzle // servers require clients to solve it
+	pzMax *Puzzle // the hardest puzzle clients solve; nil => default
+
+	audit io.Writer // see WithAuditLog()
+
+	foldID bool // lowercase identities before hashing
+	trimID bool // trim white space around identities before hashing
+
+	fixed  bool // public keys on the wire are exactly as wide as N
+	padOut bool // send public keys as wide as N; see WithPaddedCredentials()
+
+	penc ProofEncoding // of proofs in the string API
+
+	seed []byte // derive salts from this seed; see WithDeterministicSalts()
+
+	rand io.Reader // nil => crypto/rand; see WithRand()
+
+	pp PasswordPolicy // checked by Verifier()
+
+	be       Backend // nil => MathBig; see WithBackend()
+	blindExp bool    // see WithExponentBlinding()
+	fips     bool    // see WithFIPSMode()
+	strict   bool    // see StrictMode()
+
+	lat time.Duration // see WithLatencyTarget()
+
+	fh FailureHook // see WithFailureHook()
+	sn *Notifier   // see WithSecurityNotifier()
+
+	escrow *EscrowConfig // see WithKeyEscrow()
+
+	cw []*primeField // groups of WithConstantWork() by size
+
+	once sync.Once // computes the values below on first use
+	k    *big.Int  // the multiplier H(N, pad(g))
+	hng  []byte    // H(N) xor H(g)
 }
 
 // FieldSize returns this instance's prime-field size in bits
@@ -153,24 +229,58 @@ func (s *SRP) FieldSize() int {
 }
 
 // New creates a new SRP environment using a 'bits' sized prime-field for
-// use by SRP clients and Servers.The default hash function is Blake-2b-256.
-func New(bits int) (*SRP, error) {
-	return NewWithHash(crypto.BLAKE2b_256, bits)
+// use by SRP clients and Servers.The default hash function is Blake-2b-256,
+// or SHA-256 in FIPS mode.
+func New(bits int, opts ...Option) (*SRP, error) {
+	return newSRP(0, bits, opts)
 }
 
 // NewWithHash creates a new SRP environment using the hash function 'h' and
-// 'bits' sized prime-field size.
-func NewWithHash(h crypto.Hash, bits int) (*SRP, error) {
+// 'bits' sized prime-field size. It returns a *HashUnavailableError if the
+// package that implements 'h' isn't imported by the program.
+func NewWithHash(h crypto.Hash, bits int, opts ...Option) (*SRP, error) {
+	if h == 0 {
+		return nil, checkHash(h)
+	}
+	return newSRP(h, bits, opts)
+}
 
+// do the work of NewWithHash(); 'h' is 0 for the default hash
+func newSRP(h crypto.Hash, bits int, opts []Option) (*SRP, error) {
 	pf, err := findPrimeField(bits)
 	if err != nil {
 		return nil, err
 	}
 
+	return newEnv(h, pf, opts)
+}
+
+// return the environment of the hash function 'h' (0 for the default one)
+// and the group 'pf' with the options 'opts'; all constructors end here so
+// that they check the hash, FIPS mode and the size of the group alike
+func newEnv(h crypto.Hash, pf *primeField, opts []Option) (*SRP, error) {
 	s := &SRP{
 		h:  h,
 		pf: pf,
 	}
+
+	if err := s.apply(opts); err != nil {
+		return nil, err
+	}
+	if s.h == 0 {
+		s.h = s.defaultHash()
+	}
+
+	if err := checkHash(s.h); err != nil {
+		return nil, err
+	}
+	if err := s.checkFIPS(); err != nil {
+		return nil, err
+	}
+
+	if bits := s.pf.N.BitLen(); bits < MinimumBits && !s.weak {
+		return nil, fmt.Errorf("srp: %d bit prime-field is insecure; see WithInsecureGroups()", bits)
+	}
 	return s, nil
 }
 
@@ -179,13 +289,26 @@ func NewWithHash(h crypto.Hash, bits int) (*SRP, error) {
 // to lookup durable storage and find the corresponding encoded Verifier. This verifier
 // is given to MakeSRPVerifier() to create an instance of SRP and Verifier.
 func ServerBegin(creds string) (string, *big.Int, error) {
+	return ServerBeginWithLimits(creds, Limits{})
+}
+
+// parse the client credentials; 'l' has no zero fields
+func serverBegin(creds string, l Limits) (string, *big.Int, error) {
 	v := strings.Split(creds, ":")
 	if len(v) != 2 {
-		return "", nil, fmt.Errorf("srp: invalid client public key")
+		return "", nil, ErrBadClientKey
 	}
 
 	//fmt.Printf("v0: %s\nv1: %s\n", v[0], v[1])
 
+	if len(v[0]) > 2*l.Identity {
+		return "", nil, fmt.Errorf("srp: client identity too large")
+	}
+
+	if len(v[1]) > 2*l.PublicKey {
+		return "", nil, fmt.Errorf("srp: invalid client public key A")
+	}
+
 	A, ok := big.NewInt(0).SetString(v[1], 16)
 	if !ok {
 		return "", nil, fmt.Errorf("srp: invalid client public key A")
@@ -201,30 +324,75 @@ type Verifier struct {
 	v  []byte      // password verifier
 	h  crypto.Hash // hash algo used for building v
 	pf *primeField // the prime field (g, N)
+
+	kdf *KDF   // password hardening; nil if none
+	idk []byte // key that blinded 'i'; nil if it isn't blinded
+	xd  string // derivation of x; see ImportVerifier() and WithHMACPrivateKey()
+
+	env *SRP // the environment that made or decoded it; see NewSession()
+}
+
+// VerifierWithSalt is like Verifier() but always uses the caller supplied
+// 'salt' instead of generating one. It is meant for migrating users from a
+// legacy database whose salts must be kept so that values derived by
+// clients stay the same.
+func (s *SRP) VerifierWithSalt(I, p, salt []byte) (*Verifier, error) {
+	if len(salt) == 0 {
+		return nil, fmt.Errorf("srp: empty salt")
+	}
+	if l := s.Limits(); len(salt) > l.Salt {
+		return nil, fmt.Errorf("srp: salt too large (%d bytes, max %d)", len(salt), l.Salt)
+	}
+	return s.Verifier(I, p, append([]byte{}, salt...))
 }
 
 // Verifier generates a password verifier for user I and passphrase p
 // in the environment 's'. It returns an instance of Verifier that holds the
 // parameters needed for a future authentication.
 func (s *SRP) Verifier(I, p, sel []byte) (*Verifier, error) {
-	ih := s.hashbyte(I)
-	ph := s.hashbyte(p)
+	if err := s.checkPassword(p); err != nil {
+		return nil, err
+	}
+	return s.hashedVerifier(s.identity(I), s.hashbyte(p), sel)
+}
+
+// return a new verifier for the identity 'I' (as returned by identity())
+// and the password hash 'ph' = H(p); 'sel' is the salt, if any
+func (s *SRP) hashedVerifier(I, ph, sel []byte) (*Verifier, error) {
+	if len(sel) == 0 && s.seed != nil {
+		sel = s.deterministicSalt(I)
+	}
+	return s.prehashedVerifier(s.hashbyte(I), ph, sel)
+}
+
+// return a new verifier for the hashed identity 'ih' = H(I) and the
+// password hash 'ph' = H(p); 'sel' is the salt, if any
+func (s *SRP) prehashedVerifier(ih, ph, sel []byte) (*Verifier, error) {
 	pf := s.pf
-	var salt []byte
-	if len(sel) == 0 {
-		salt = randbytes(pf.n)
-	} else {
-		salt = sel
+	salt := sel
+	if len(salt) == 0 {
+		salt = s.randbytes(s.saltSize())
+	}
+	if err := s.checkSaltWidth(salt); err != nil {
+		return nil, err
 	}
-	x := s.hashint(ih, ph, salt)
-	r := big.NewInt(0).Exp(pf.g, x, pf.N)
+	x := s.privateKey(ih, ph, salt, s.kdf, s.xd)
+	r := s.arith().Exp(pf.g, x, pf.N)
 
 	v := &Verifier{
-		i:  ih,
-		s:  salt,
-		v:  r.Bytes(),
-		h:  s.h,
-		pf: pf,
+		i:   ih,
+		s:   salt,
+		v:   r.Bytes(),
+		h:   s.h,
+		pf:  pf,
+		kdf: s.kdf,
+		xd:  s.xd,
+		env: s,
+	}
+
+	if s.idk != nil {
+		v.i = blindIdentity(s.idk, ih)
+		v.idk = s.idk
 	}
 
 	return v, nil
@@ -235,11 +403,14 @@ func (s *SRP) Verifier(I, p, sel []byte) (*Verifier, error) {
 // returned by Verifier.Encode().  A caller of this function uses the identity
 // provided by the SRP Client to lookup some DB to find the corresponding encoded
 // verifier string; this encoded data contains enough information to create a
-// valid SRP instance and Verifier instance.
-func MakeSRPVerifier(b string) (*SRP, *Verifier, error) {
+// valid SRP instance and Verifier instance. The options 'opts' are applied to
+// the returned SRP instance. The instance always uses the group of the
+// verifier; servers that create their environments otherwise get an error
+// matching ErrGroupMismatch when a verifier doesn't fit.
+func MakeSRPVerifier(b string, opts ...Option) (*SRP, *Verifier, error) {
 	v := strings.Split(b, ":")
-	if len(v) != 7 {
-		return nil, nil, fmt.Errorf("verifier: malformed fields exp 5, saw %d", len(v))
+	if len(v) < 7 {
+		return nil, nil, fmt.Errorf("verifier: malformed fields exp 7, saw %d", len(v))
 	}
 
 	ss := v[0]
@@ -266,11 +437,6 @@ func MakeSRPVerifier(b string) (*SRP, *Verifier, error) {
 		return nil, nil, fmt.Errorf("verifier: malformed hash type %s", ss)
 	}
 
-	hf := crypto.Hash(h)
-	if !hf.Available() {
-		return nil, nil, fmt.Errorf("verifier: hash algorithm %d unavailable", h)
-	}
-
 	ss = v[4]
 	i, err := hex.DecodeString(ss)
 	if err != nil {
@@ -289,21 +455,90 @@ func MakeSRPVerifier(b string) (*SRP, *Verifier, error) {
 		return nil, nil, fmt.Errorf("verifier: invalid verifier: %s", ss)
 	}
 
+	ext, err := parseExt(v[7:])
+	if err != nil {
+		return nil, nil, fmt.Errorf("verifier: %s", err)
+	}
+
+	var kdf *KDF
+	if ss, ok := ext.take("kdf"); ok {
+		if kdf, err = parseKDF(ss); err != nil {
+			return nil, nil, fmt.Errorf("verifier: %s", err)
+		}
+	}
+
+	var blind bool
+	if ss, ok := ext.take("idk"); ok {
+		if ss != identityBlinding {
+			return nil, nil, fmt.Errorf("verifier: unknown identity blinding %q", ss)
+		}
+		blind = true
+	}
+
+	grp, _ := ext.take("grp")
+
+	xd, _ := ext.take("x")
+	if err := checkXDerivation(xd); err != nil {
+		return nil, nil, fmt.Errorf("verifier: %s", err)
+	}
+
+	if err := ext.done(); err != nil {
+		return nil, nil, fmt.Errorf("verifier: %s", err)
+	}
+
+	pf, err := resolveGroup(grp, &primeField{
+		n: sz,
+		N: p,
+		g: g,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return makeSRPVerifier(pf, crypto.Hash(h), i, s, vx, kdf, xd, blind, opts)
+}
+
+// build the SRP environment and Verifier from the decoded fields of a
+// verifier; 'blind' is true if the identity 'i' is blinded.
+func makeSRPVerifier(pf *primeField, h crypto.Hash, i, s, v []byte, kdf *KDF, xd string, blind bool, opts []Option) (*SRP, *Verifier, error) {
+	if err := checkHash(h); err != nil {
+		return nil, nil, fmt.Errorf("verifier: %w", err)
+	}
+
 	sr := &SRP{
-		h: hf,
-		pf: &primeField{
-			n: sz,
-			N: p,
-			g: g,
-		},
+		h:   h,
+		pf:  pf,
+		kdf: kdf,
+	}
+	if xd == DeriveHMAC {
+		sr.xd = xd
+	}
+
+	if err := sr.apply(opts); err != nil {
+		return nil, nil, err
+	}
+	if err := sr.checkFIPS(); err != nil {
+		return nil, nil, err
+	}
+	if err := sr.checkConstantWork(); err != nil {
+		return nil, nil, err
 	}
 
 	vf := &Verifier{
-		i:  i,
-		s:  s,
-		v:  vx,
-		h:  hf,
-		pf: sr.pf,
+		i:   i,
+		s:   s,
+		v:   v,
+		h:   h,
+		pf:  pf,
+		kdf: kdf,
+		xd:  xd,
+		env: sr,
+	}
+
+	if blind {
+		if sr.idk == nil {
+			return nil, nil, fmt.Errorf("verifier: blinded identity needs WithIdentityKey()")
+		}
+		vf.idk = sr.idk
 	}
 
 	return sr, vf, nil
@@ -330,109 +565,337 @@ func (v *Verifier) Encode() (string, string) {
 	b.WriteByte(':')
 	b.WriteString(hex.EncodeToString(v.v))
 
+	if v.kdf != nil {
+		b.WriteString(":kdf=")
+		b.WriteString(v.kdf.String())
+	}
+
+	if v.idk != nil {
+		b.WriteString(":idk=")
+		b.WriteString(identityBlinding)
+	}
+
+	if v.pf.id != "" {
+		b.WriteString(":grp=")
+		b.WriteString(v.pf.id)
+	}
+
+	if v.xd != "" {
+		b.WriteString(":x=")
+		b.WriteString(v.xd)
+	}
+
 	return ih, b.String()
 }
 
+// MatchesIdentity returns true if 'ih' is the hashed identity this verifier
+// was created for. The comparison takes constant time; a server should use
+// it to confirm that the verifier found by a lookup belongs to the identity
+// sent by the client.
+func (v *Verifier) MatchesIdentity(ih []byte) bool {
+	if v.idk != nil {
+		ih = blindIdentity(v.idk, ih)
+	}
+	return ctEqual(v.i, ih)
+}
+
+// Salt returns a copy of the verifier's salt
+func (v *Verifier) Salt() []byte {
+	return append([]byte{}, v.s...)
+}
+
+// IdentityHash returns a copy of the hashed identity the verifier is stored
+// under (the first value returned by Encode()); with WithIdentityKey() it is
+// the blinded hash.
+func (v *Verifier) IdentityHash() []byte {
+	return append([]byte{}, v.i...)
+}
+
+// Params returns the hash function and the prime-field size in bits of the
+// verifier.
+func (v *Verifier) Params() (crypto.Hash, int) {
+	return v.h, v.pf.n * 8
+}
+
 // Client represents an SRP client instance
 type Client struct {
 	s  *SRP
 	i  []byte
 	p  []byte
+	ip []byte // H(I | ":" | p) for imported verifiers
 	a  *big.Int
 	xA *big.Int
-	k  *big.Int
 
-	xK []byte
-	xM []byte
+	xK  []byte
+	xM  []byte
+	xT  *Transcript
+	sol []byte // solution of the server's puzzle; nil if none
+
+	authed bool // the server proved it knows the verifier
+
+	bind []byte // binds the proofs to a prior session; see StepUp()
+
+	// cached private key x; see Reuse()
+	xc struct {
+		salt []byte
+		kdf  string
+		x    *big.Int
+	}
 }
 
 // NewClient constructs an SRP client instance.
 func (s *SRP) NewClient(I, p []byte) (*Client, error) {
+	if err := s.checkPolicy(); err != nil {
+		return nil, err
+	}
+
+	return s.initClient(new(Client), I, p), nil
+}
+
+// initialize 'c' as a new client in this environment and return it
+func (s *SRP) initClient(c *Client, I, p []byte) *Client {
+	I = s.identity(I)
+	return s.initHashedClient(c, s.hashbyte(I), s.hashbyte(p), s.hashbyte(I, []byte(":"), p))
+}
+
+// initialize 'c' as a new client for the hashed identity 'ih' = H(I) with
+// the password hashes 'ph' = H(p) and 'iph' = H(I:p); 'iph' is nil if
+// unknown
+func (s *SRP) initHashedClient(c *Client, ih, ph, iph []byte) *Client {
 	pf := s.pf
-	c := &Client{
-		s: s,
-		i: s.hashbyte(I),
-		p: s.hashbyte(p),
-		a: randBigInt(pf.n * 8),
-		k: s.hashint(pf.N.Bytes(), pad(pf.g, pf.n)),
+	*c = Client{
+		s:  s,
+		i:  ih,
+		p:  ph,
+		ip: iph,
+		a:  s.ephemeral(),
 	}
 
-	c.xA = big.NewInt(0).Exp(pf.g, c.a, pf.N)
-	//fmt.Printf("Client %d:\n\tA=%x\n\tk=%x", bits, c.xA, c.k)
-	return c, nil
+	c.xA = s.arith().Exp(pf.g, c.a, pf.N)
+	//fmt.Printf("Client %d:\n\tA=%x\n", bits, c.xA)
+	return c
+}
+
+// Reset prepares the client for a new handshake: it generates a new secret
+// ephemeral a (and public key A) and forgets the keys of the previous
+// attempt. The hashed identity and password are kept so that a login UI can
+// retry after a transient failure without asking the user again.
+func (c *Client) Reset() {
+	pf := c.s.pf
+	c.a = c.s.ephemeral()
+	c.xA = c.s.arith().Exp(pf.g, c.a, pf.N)
+	c.xK = nil
+	c.xM = nil
+	c.xT = nil
+	c.sol = nil
+	c.authed = false
+}
+
+// Reuse makes the client cache the private key x it derives for 'salt' and
+// keep it across Reset(). A retried handshake that receives the same salt
+// (and KDF parameters) then skips the password hardening. Since x is
+// equivalent to the password, callers should only enable this for the
+// duration of a login attempt.
+func (c *Client) Reuse(salt []byte) {
+	c.xc.salt = append([]byte{}, salt...)
+	c.xc.kdf = ""
+	c.xc.x = nil
+}
+
+// return the private key x for 'salt' and 'kdf', using the cache if possible
+func (c *Client) privateKey(salt []byte, kdf *KDF, xd string) (*big.Int, error) {
+	if xd != "" && xd != DeriveHMAC {
+		if kdf != nil {
+			return nil, fmt.Errorf("srp: imported verifiers can't have a kdf")
+		}
+		return c.importedX(salt, xd)
+	}
+
+	var ks string
+	if kdf != nil {
+		ks = kdf.String()
+	}
+	if xd != "" {
+		ks += ":x=" + xd
+	}
+
+	xc := &c.xc
+	if xc.salt == nil || !bytes.Equal(xc.salt, salt) {
+		return c.s.privateKey(c.i, c.p, salt, kdf, xd), nil
+	}
+
+	if xc.x == nil || xc.kdf != ks {
+		xc.x = c.s.privateKey(c.i, c.p, salt, kdf, xd)
+		xc.kdf = ks
+	}
+	return xc.x, nil
 }
 
 // Credentials returns client public credentials to send to server
 // Send <I, A> to server
 func (c *Client) Credentials() string {
-	var b bytes.Buffer
+	cc := c.Hello()
+	return cc.encode()
+}
 
-	b.WriteString(hex.EncodeToString(c.i))
-	b.WriteByte(':')
-	b.WriteString(hex.EncodeToString(c.xA.Bytes()))
-	return b.String()
+// Hello returns the client public credentials <I, A> to send to the server.
+// It is the binary counterpart of Credentials().
+func (c *Client) Hello() ClientCredentials {
+	return ClientCredentials{
+		IdentityHash: c.i,
+		A:            c.s.encodeInt(c.xA),
+	}
 }
 
 // Generate validates the server public credentials and generate session key
 // Return the mutual authenticator.
 // NB: We don't send leak any information in error messages.
 func (c *Client) Generate(srv string) (string, error) {
-	v := strings.Split(srv, ":")
-	if len(v) != 2 {
-		return "", fmt.Errorf("srp: invalid server public key")
+	l := c.s.Limits()
+	if err := l.checkServer(srv); err != nil {
+		return "", err
 	}
 
-	salt, err := hex.DecodeString(v[0])
+	sc, err := parseServerCredentials(srv)
 	if err != nil {
-		return "", fmt.Errorf("srp: invalid server public key")
+		return "", err
 	}
 
-	B, ok1 := big.NewInt(0).SetString(v[1], 16)
-	if !ok1 {
-		return "", fmt.Errorf("srp: invalid server public key")
+	m, err := c.Respond(sc)
+	if err != nil {
+		return "", err
+	}
+	return c.s.EncodeProof(m), nil
+}
+
+// Respond validates the server public credentials, generates the session key
+// and returns the mutual authenticator M. It is the binary counterpart of
+// Generate().
+func (c *Client) Respond(sc ServerCredentials) ([]byte, error) {
+	m, err := c.respond(sc)
+	if err != nil && c.s.sn != nil {
+		c.s.sn.observe(c.s, "client", c.i, err)
+	}
+	return m, err
+}
+
+// do the work of Respond()
+func (c *Client) respond(sc ServerCredentials) ([]byte, error) {
+	if sc.Group != "" && !c.s.pf.is(groupByID(sc.Group)) {
+		other := GroupInfo{ID: sc.Group}
+		if pf := groupByID(sc.Group); pf != nil {
+			other = pf.info()
+		}
+		return nil, &GroupMismatchError{Source: "server", Env: c.s.Group(), Other: other}
+	}
+	if sc.Group == "" && c.s.pf.alt {
+		return nil, &GroupMismatchError{Source: "server", Env: c.s.Group(), Other: GroupInfo{Bits: c.s.FieldSize()}}
+	}
+
+	// Don't let the server downgrade the password hardening we expect
+	if min := c.s.kdf; min != nil && (sc.KDF == nil || !sc.KDF.atLeast(min)) {
+		return nil, fmt.Errorf("%w: server kdf is weaker than required", ErrDowngrade)
+	}
+	if c.s.xd != "" && sc.X != c.s.xd {
+		return nil, fmt.Errorf("%w: server derivation of x differs from required %s", ErrDowngrade, c.s.xd)
+	}
+
+	l := c.s.Limits()
+	if len(sc.Salt) > l.Salt || len(sc.B) > l.PublicKey {
+		return nil, ErrBadServerKey
+	}
+
+	if err := c.s.verifyChallenge(&sc, c.i, c.xA); err != nil {
+		return nil, err
 	}
 
 	pf := c.s.pf
-	zero := big.NewInt(0)
-	z := big.NewInt(0).Mod(B, pf.N)
-	if zero.Cmp(z) == 0 {
-		return "", fmt.Errorf("srp: invalid server public key")
+	salt := sc.Salt
+	if err := c.s.checkSaltWidth(salt); err != nil {
+		return nil, err
+	}
+	B, err := c.s.ParsePublicKey(sc.B)
+	if err != nil {
+		return nil, ErrBadServerKey
 	}
 
-	u := c.s.hashint(pad(c.xA, pf.n), pad(B, pf.n))
-	if u.Cmp(zero) == 0 {
-		return "", fmt.Errorf("srp: invalid server public key")
+	z := c.s.arith().Mod(B, pf.N)
+	if z.Sign() == 0 {
+		return nil, abort(AbortZeroB)
 	}
 
-	// S := ((B - kg^x) ^ (a + ux)) % N
+	u := c.s.ComputeU(c.xA, B)
+	if u.Sign() == 0 {
+		return nil, abort(AbortZeroU)
+	}
 
-	x := c.s.hashint(c.i, c.p, salt)
-	t0 := big.NewInt(0).Exp(pf.g, x, pf.N)
-	t0 = t0.Mul(t0, c.k)
+	var sol []byte
+	if sc.Puzzle != nil {
+		if sol, err = c.s.solvePuzzle(sc.Puzzle, c.xA, B); err != nil {
+			return nil, err
+		}
+	}
 
-	t1 := big.NewInt(0).Sub(B, t0)
-	t2 := big.NewInt(0).Add(c.a, big.NewInt(0).Mul(u, x))
-	S := big.NewInt(0).Exp(t1, t2, pf.N)
+	// S := ((B - kg^x) ^ (a + ux)) % N
 
-	c.xK = c.s.hashbyte(S.Bytes())
-	c.xM = c.s.hashbyte(c.xK, c.xA.Bytes(), B.Bytes(), c.i, salt, pf.N.Bytes(), pf.g.Bytes())
+	x, err := c.privateKey(salt, sc.KDF, sc.X)
+	if err != nil {
+		return nil, err
+	}
+	S, err := c.s.clientS(c.a, x, u, B)
+	if err != nil {
+		return nil, err
+	}
+
+	c.xK = c.s.ComputeSessionKey(S)
+	c.authed = false
+	c.xT = c.s.transcript(c.xK, c.xA, B, c.i, salt).bound(c.bind)
+	c.xM = c.s.scheme().ClientProof(c.xT)
+	c.sol = sol
+	c.s.logAudit(c.xT)
 
 	//fmt.Printf("Client %d:\n\tx=%x\n\tS=%x\n\tK=%x\n\tM=%x\n", c.n *8, x, S, c.xK, c.xM)
 
-	return hex.EncodeToString(c.xM), nil
+	if sol != nil {
+		return append(append([]byte{}, c.xM...), sol...), nil
+	}
+	return c.xM, nil
 }
 
 // ServerOk takes a 'proof' offered by the server and verifies that it is valid.
 // i.e., we should compute the same hash() on M that the server did.
 func (c *Client) ServerOk(proof string) bool {
-	h := c.s.hashbyte(c.xK, c.xM)
-	myh := hex.EncodeToString(h)
+	l := c.s.Limits()
+	if l.checkProof(proof, c.s.penc) != nil {
+		return false
+	}
 
-	return subtle.ConstantTimeCompare([]byte(myh), []byte(proof)) == 1
+	z, err := c.s.DecodeProof(proof)
+	if err != nil {
+		return false
+	}
+
+	return c.CheckProof(z)
+}
+
+// CheckProof verifies the 'proof' offered by the server; it is the binary
+// counterpart of ServerOk().
+func (c *Client) CheckProof(proof []byte) bool {
+	if c.xM == nil {
+		return false
+	}
+
+	h := c.s.scheme().ServerProof(c.xT, c.xM)
+	c.authed = ctEqual(h, proof)
+	if c.authed && c.s.escrowKey("client", c.i, c.s.PadBytes(c.xT.B), c.xK) != nil {
+		c.authed = false
+	}
+	return c.authed
 }
 
 // RawKey returns the raw key computed as part of the protocol
 func (c *Client) RawKey() []byte {
+	c.s.misuse(!c.authed, "Client.RawKey() before the server's proof was verified")
 	return c.xK
 }
 
@@ -450,15 +913,28 @@ type Server struct {
 	salt []byte
 	v    *big.Int
 	xB   *big.Int
+	xA   *big.Int
 	xK   []byte
 	xM   []byte
+	kdf  *KDF
+	xd   string // derivation of x of the verifier
+
+	used   ProofScheme // the scheme that verified the client's proof
+	authed bool        // the client proved it knows the password
+	issued bool        // Challenge() was called; see StrictMode()
+
+	ak []altKey    // K under the hashes of WithAcceptedProofHashes()
+	uh crypto.Hash // the hash that verified the client's proof
+
+	vbuf *big.Int // the buffer of v of a pooled server; see ServerPool
+	bind []byte   // binds the proofs to a prior session; see StepUp()
 }
 
 // Marshal returns a string encoding of the Server. This encoded string can be stored by the
 // server for use later in the SRP process in the case that the client and server can not
 // maintain a session and thus a live copy of the Server struct.
 func (s *Server) Marshal() string {
-	return strings.Join([]string{
+	v := []string{
 		strconv.Itoa(s.s.FieldSize()),
 		strconv.FormatUint(uint64(s.s.h), 10),
 		hex.EncodeToString(s.i),
@@ -467,14 +943,34 @@ func (s *Server) Marshal() string {
 		s.xB.Text(10),
 		hex.EncodeToString(s.xK),
 		hex.EncodeToString(s.xM),
-	}, ":")
+	}
+	if s.kdf != nil {
+		v = append(v, "kdf="+s.kdf.String())
+	}
+	if s.xA != nil {
+		v = append(v, "a="+s.xA.Text(16))
+	}
+	if id := s.s.pf.id; id != "" {
+		v = append(v, "grp="+id)
+	}
+	if s.xd != "" {
+		v = append(v, "x="+s.xd)
+	}
+	if s.bind != nil {
+		v = append(v, "su="+hex.EncodeToString(s.bind))
+	}
+	if s.ak != nil {
+		v = append(v, "ph="+encodeAltKeys(s.ak))
+	}
+	return strings.Join(v, ":")
 }
 
 // UnmarshalServer parses the encoded string generated by Marshal and returns a populated
 // Server struct with the data if possible, otherwise it returns an error.
-func UnmarshalServer(s string) (*Server, error) {
+// The options 'opts' are applied to the server's SRP environment.
+func UnmarshalServer(s string, opts ...Option) (*Server, error) {
 	p := strings.Split(s, ":")
-	if len(p) != 8 {
+	if len(p) < 8 {
 		return nil, fmt.Errorf("unmarshal: malformed fields exp 8, saw %d", len(p))
 	}
 
@@ -482,10 +978,7 @@ func UnmarshalServer(s string) (*Server, error) {
 	if err != nil || sz <= 0 {
 		return nil, fmt.Errorf("unmarshal: malformed field size %s", p[0])
 	}
-	pf, ok := pflist[sz]
-	if !ok {
-		return nil, fmt.Errorf("unmarshal: invalid prime-field size: %d", sz)
-	}
+	pf := pflist[sz]
 
 	h, err := strconv.Atoi(p[1])
 	if err != nil || h <= 0 {
@@ -493,8 +986,8 @@ func UnmarshalServer(s string) (*Server, error) {
 	}
 
 	hf := crypto.Hash(h)
-	if !hf.Available() {
-		return nil, fmt.Errorf("unmarshal: hash algorithm %d unavailable", h)
+	if err := checkHash(hf); err != nil {
+		return nil, fmt.Errorf("unmarshal: %w", err)
 	}
 
 	i, err := hex.DecodeString(p[2])
@@ -507,13 +1000,13 @@ func UnmarshalServer(s string) (*Server, error) {
 		return nil, fmt.Errorf("unmarshal: invalid salt: %s", p[3])
 	}
 
-	v := atobi(p[4], 10)
-	if r := recover(); r != nil {
+	v, ok := big.NewInt(0).SetString(p[4], 10)
+	if !ok {
 		return nil, fmt.Errorf("unmarshal: invalid verifier: %s", p[4])
 	}
 
-	B := atobi(p[5], 10)
-	if r := recover(); r != nil {
+	B, ok := big.NewInt(0).SetString(p[5], 10)
+	if !ok {
 		return nil, fmt.Errorf("unmarshal: invalid ephemeral key B: %s", p[5])
 	}
 
@@ -527,36 +1020,158 @@ func UnmarshalServer(s string) (*Server, error) {
 		return nil, fmt.Errorf("unmarshal: invalid M: %s", p[7])
 	}
 
+	ext, err := parseExt(p[8:])
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal: %s", err)
+	}
+
+	var kdf *KDF
+	if ss, ok := ext.take("kdf"); ok {
+		if kdf, err = parseKDF(ss); err != nil {
+			return nil, fmt.Errorf("unmarshal: %s", err)
+		}
+	}
+
+	// A is absent in servers marshaled by older versions; they can only
+	// verify proofs whose scheme doesn't need it.
+	var A *big.Int
+	if ss, ok := ext.take("a"); ok {
+		if A, ok = big.NewInt(0).SetString(ss, 16); !ok {
+			return nil, fmt.Errorf("unmarshal: invalid client public key: %s", ss)
+		}
+	}
+
+	// the group id is absent in servers marshaled by older versions
+	if id, ok := ext.take("grp"); ok {
+		if pf = groupByID(id); pf == nil {
+			return nil, fmt.Errorf("unmarshal: unknown group %s", id)
+		}
+	}
+
+	xd, _ := ext.take("x")
+	if err := checkXDerivation(xd); err != nil {
+		return nil, fmt.Errorf("unmarshal: %s", err)
+	}
+
+	var bind []byte
+	if ss, ok := ext.take("su"); ok {
+		if bind, err = hex.DecodeString(ss); err != nil || len(bind) == 0 {
+			return nil, fmt.Errorf("unmarshal: invalid step-up binding: %s", ss)
+		}
+	}
+
+	var ak []altKey
+	if ss, ok := ext.take("ph"); ok {
+		if ak, err = decodeAltKeys(ss); err != nil {
+			return nil, fmt.Errorf("unmarshal: %s", err)
+		}
+	}
+
+	if err := ext.done(); err != nil {
+		return nil, fmt.Errorf("unmarshal: %s", err)
+	}
+
+	if pf == nil || pf.n*8 != sz {
+		return nil, fmt.Errorf("unmarshal: invalid prime-field size: %d", sz)
+	}
+
+	sr := &SRP{
+		h:  hf,
+		pf: pf,
+	}
+
+	if err := sr.apply(opts); err != nil {
+		return nil, err
+	}
+	if err := sr.checkFIPS(); err != nil {
+		return nil, err
+	}
+
 	return &Server{
-		s: &SRP{
-			h:  hf,
-			pf: pf,
-		},
+		s:    sr,
 		i:    i,
 		salt: salt,
 		v:    v,
 		xB:   B,
+		xA:   A,
 		xK:   K,
 		xM:   M,
+		kdf:  kdf,
+		xd:   xd,
+		bind: bind,
+		ak:   ak,
 	}, nil
 }
 
 // NewServer constructs a Server instance for computing a shared secret.
-func (s *SRP) NewServer(v *Verifier, A *big.Int, PB *big.Int) (*Server, error) {
+// Verifiers with a blinded identity need NewServerFor().
+func (s *SRP) NewServer(v *Verifier, A *big.Int) (*Server, error) {
+	if v.idk != nil {
+		return nil, errBlinded
+	}
+	return s.newServer(v, v.i, big.NewInt(0).SetBytes(v.v), A)
+}
+
+// NewSession constructs a Server for the client whose public key is 'A' in
+// the environment that made or decoded this verifier (e.g., the one
+// returned along with it by MakeSRPVerifier()); it is shorthand for
+// NewServer() on that environment. Verifiers with a blinded identity need
+// SRP.NewServerFor().
+func (v *Verifier) NewSession(A *big.Int) (*Server, error) {
+	if v.env == nil {
+		return nil, fmt.Errorf("srp: verifier has no environment; use SRP.NewServer()")
+	}
+	return v.env.NewServer(v, A)
+}
+
+// construct a Server for verifier 'v' whose numeric value is 'vx'; 'ih'
+// is the hashed identity sent by the client.
+func (s *SRP) newServer(v *Verifier, ih []byte, vx *big.Int, A *big.Int) (*Server, error) {
+	return s.initServer(new(Server), v, ih, vx, nil, A)
+}
+
+// initialize 'sx' as a Server for verifier 'v' (see newServer()) and
+// return it; 'vt' is the precomputed table of 'vx' or nil
+func (s *SRP) initServer(sx *Server, v *Verifier, ih []byte, vx *big.Int, vt *fixedBase, A *big.Int) (*Server, error) {
+	sx, err := s.startServer(sx, v, ih, vx, vt, A)
+	return sx, s.failed(ih, err)
+}
+
+// do the work of initServer()
+func (s *SRP) startServer(sx *Server, v *Verifier, ih []byte, vx *big.Int, vt *fixedBase, A *big.Int) (*Server, error) {
+	if err := s.checkPolicy(); err != nil {
+		return nil, err
+	}
 
 	pf := s.pf
+	if v.pf != nil && !pf.is(v.pf) {
+		return nil, &GroupMismatchError{Source: "verifier", Env: s.Group(), Other: v.pf.info()}
+	}
+
+	if l := s.Limits(); (A.BitLen()+7)/8 > l.PublicKey {
+		return nil, ErrBadClientKey
+	}
 
-	zero := big.NewInt(0)
-	z := big.NewInt(0).Mod(A, pf.N)
-	if zero.Cmp(z) == 0 {
-		return nil, fmt.Errorf("srp: invalid client public key")
+	if err := s.checkSaltWidth(v.s); err != nil {
+		return nil, err
 	}
 
-	sx := &Server{
+	z := s.arith().Mod(A, pf.N)
+	if z.Sign() == 0 {
+		return nil, abort(AbortZeroA)
+	}
+
+	if err := s.replayCheck("A", ih, A.Bytes()); err != nil {
+		return nil, err
+	}
+
+	*sx = Server{
 		s:    s,
 		salt: v.s,
-		i:    v.i,
-		v:    big.NewInt(0).SetBytes(v.v),
+		i:    ih,
+		v:    vx,
+		kdf:  v.kdf,
+		xd:   v.xd,
 	}
 
 	// g, N := field(bits)
@@ -566,57 +1181,241 @@ func (s *SRP) NewServer(v *Verifier, A *big.Int, PB *big.Int) (*Server, error) {
 	// u := H(A, B)
 	// S := (Av^u) ^ b
 	// K := H(S)
-	var B *big.Int
-	if PB != nil {
-		B = PB
-	} else {
-		b := randBigInt(pf.n * 8)
-		k := s.hashint(pf.N.Bytes(), pad(pf.g, pf.n))
-		t0 := big.NewInt(0).Mul(k, sx.v)
-		t0.Add(t0, big.NewInt(0).Exp(pf.g, b, pf.N))
-		B = t0.Mod(t0, pf.N)
+	b := s.ephemeral()
+	B := s.ComputeB(b, sx.v)
+
+	u := s.ComputeU(A, B)
+	if u.Sign() == 0 {
+		return nil, abort(AbortZeroU)
 	}
 
-	u := s.hashint(pad(A, pf.n), pad(B, pf.n))
-	if u.Cmp(zero) == 0 {
-		return nil, fmt.Errorf("srp: invalid client public key u")
+	// v^u from the verifier's precomputed table, if it has one
+	var vu *big.Int
+	if vt != nil {
+		vu = vt.exp(s.arith(), u)
+	} else {
+		vu = s.arith().Exp(sx.v, u, pf.N)
 	}
 
-	t0 = big.NewInt(0).Mul(A, big.NewInt(0).Exp(sx.v, u, pf.N))
-	S := big.NewInt(0).Exp(t0, b, pf.N)
+	S, err := s.serverSFrom(b, vu, A)
+	if err != nil {
+		return nil, err
+	}
 
 	sx.xB = B
-	sx.xK = s.hashbyte(S.Bytes())
-	sx.xM = s.hashbyte(sx.xK, A.Bytes(), B.Bytes(), v.i, v.s, pf.N.Bytes(), pf.g.Bytes())
+	sx.xA = A
+	sx.xK = s.ComputeSessionKey(S)
+	sx.xM = s.scheme().ClientProof(s.transcript(sx.xK, A, B, ih, v.s))
+	sx.ak = s.altKeys(S)
 
 	//fmt.Printf("Server %d:\n\tv=%x\n\tk=%x\n\tA=%x\n\tS=%x\n\tK=%x\n\tM=%x\n", bits, v, k, A.Bytes(), S, s.xK, s.xM)
 
+	s.logAudit(s.transcript(nil, A, B, ih, v.s))
+	s.balanceWork()
 	return sx, nil
 }
 
+// DummyHandshake does the same computations as NewServer() (and thus takes
+// about the same time) on random values of this environment's group. A
+// server can call it when it rejects a locked or rate-limited account so
+// that the rejection takes as long as a real handshake; nothing is
+// computed on the values sent by the client. With WithConstantWork() it
+// does the work of a handshake in each of the listed groups.
+func (s *SRP) DummyHandshake() {
+	s.dummyHandshake()
+	s.balanceWork()
+}
+
+// do the work of NewServer() on random values
+func (s *SRP) dummyHandshake() {
+	pf := s.pf
+
+	v := s.arith().Mod(randBigInt(s.random(), pf.n*8), pf.N)
+	A := s.arith().Mod(randBigInt(s.random(), pf.n*8), pf.N)
+	ih := s.randbytes(newHash(s.h).Size())
+	salt := s.randbytes(s.saltSize())
+
+	b := s.ephemeral()
+	B := s.ComputeB(b, v)
+	u := s.ComputeU(A, B)
+	S := s.ComputeServerS(b, v, u, A)
+
+	K := s.ComputeSessionKey(S)
+	s.scheme().ClientProof(s.transcript(K, A, B, ih, salt))
+}
+
 // Credentials returns the server credentials (s,B) in a network portable
 // format.
 func (s *Server) Credentials() string {
+	sc := s.Challenge()
+	return sc.encode()
+}
 
-	s0 := hex.EncodeToString(s.salt)
-	s1 := hex.EncodeToString(s.xB.Bytes())
-	return s0 + ":" + s1
+// Challenge returns the server credentials <s, B> to send to the client. It
+// is the binary counterpart of Credentials().
+func (s *Server) Challenge() ServerCredentials {
+	s.s.misuse(s.issued, "Server.Credentials() called twice")
+	s.issued = true
+
+	sc := ServerCredentials{
+		Salt: s.salt,
+		B:    s.s.encodeInt(s.xB),
+		KDF:  s.kdf,
+		X:    s.xd,
+	}
+	if s.s.pf.alt {
+		sc.Group = s.s.pf.id
+	}
+	sc.Puzzle = s.s.pz
+	s.s.signChallenge(&sc, s.i, s.xA)
+	return sc
 }
 
 // ClientOk verifies that the client has generated the same password as the
 // server and return proof that the server too has done the same.
 func (s *Server) ClientOk(m string) (proof string, ok bool) {
-	mym := hex.EncodeToString(s.xM)
-	if subtle.ConstantTimeCompare([]byte(mym), []byte(m)) != 1 {
-		return "", false
+	proof, err := s.VerifyClientProof(m)
+	return proof, err == nil
+}
+
+// VerifyClientProof is like ClientOk() but returns why the proof 'm' was
+// rejected: ErrMalformedProof (not a proof of the right size in the
+// encoding of the environment; see WithProofEncoding()),
+// ErrProofMismatch, ErrReplayed, ErrPuzzleUnsolved or an error of
+// the replay cache. The reason is for the server's logs only; the client
+// must see the same response in every case. A malformed proof takes as
+// long to reject as a wrong one.
+func (s *Server) VerifyClientProof(m string) (proof string, err error) {
+	l := s.s.Limits()
+	l.Proof += s.s.solutionLen()
+	z, err := s.s.DecodeProof(m)
+	if l.checkProof(m, s.s.penc) != nil || err != nil || !s.proofSizeOK(len(z)-s.s.solutionLen()) {
+		// compare a proof of the right size to take the same time
+		s.matchHash(s.transcript(), make([]byte, len(s.xM)))
+		return "", s.s.failed(s.i, ErrMalformedProof)
 	}
 
-	h := s.s.hashbyte(s.xK, s.xM)
-	return hex.EncodeToString(h), true
+	h, err := s.verifyProof(z)
+	if err != nil {
+		return "", err
+	}
+	return s.s.EncodeProof(h), nil
+}
+
+// CheckProof verifies the client's mutual authenticator 'm' and returns the
+// server's proof. It is the binary counterpart of ClientOk().
+func (s *Server) CheckProof(m []byte) (proof []byte, ok bool) {
+	proof, err := s.verifyProof(m)
+	return proof, err == nil
+}
+
+// verify the client's proof 'm' and return the server's proof
+func (s *Server) verifyProof(m []byte) ([]byte, error) {
+	proof, err := s.checkClientProof(m)
+	return proof, s.s.failed(s.i, err)
+}
+
+// do the work of verifyProof()
+func (s *Server) checkClientProof(m []byte) ([]byte, error) {
+	if err := s.s.replayCheck("M", m); err != nil {
+		return nil, err
+	}
+
+	m, err := s.checkPuzzle(m)
+	if err != nil {
+		return nil, err
+	}
+
+	p, t := s.matchHash(s.transcript(), m)
+	if p == nil {
+		return nil, ErrProofMismatch
+	}
+
+	proof, ok := s.reply(p, t, m)
+	if !ok {
+		return nil, ErrProofMismatch
+	}
+	if err := s.s.escrowKey("server", s.i, s.s.PadBytes(s.xB), s.xK); err != nil {
+		s.authed = false
+		return nil, err
+	}
+	return proof, nil
+}
+
+// return the scheme that computes the client proof 'm' for transcript 't'
+// under the main or an alternate proof hash, and the transcript under that
+// hash; the scheme is nil if none does
+func (s *Server) matchHash(t *Transcript, m []byte) (ProofScheme, *Transcript) {
+	if p := s.match(t, m); p != nil {
+		return p, t
+	}
+	for _, k := range s.ak {
+		u := t.withKey(k)
+		if p := s.match(u, m); p != nil {
+			return p, u
+		}
+	}
+	return nil, t
+}
+
+// return the scheme that computes the client proof 'm' for transcript 't'
+// or nil if none does
+func (s *Server) match(t *Transcript, m []byte) ProofScheme {
+	main := t.h == s.s.proofHash()
+	if main && ctEqual(s.xM, m) {
+		return s.s.scheme()
+	}
+
+	// Alternate schemes need A, which old marshaled servers lack
+	if s.xA == nil {
+		return nil
+	}
+	if !main && ctEqual(s.s.scheme().ClientProof(t), m) {
+		return s.s.scheme()
+	}
+	for _, p := range s.s.alt {
+		p = s.s.variant(p)
+		if padsKey(p) != padsKey(s.s.scheme()) {
+			continue
+		}
+		if ctEqual(p.ClientProof(t), m) {
+			return p
+		}
+	}
+	return nil
+}
+
+// ProofScheme returns the scheme that verified the client's proof, or nil
+// if CheckProof() hasn't succeeded. During a migration (see
+// WithAcceptedProofSchemes()) servers can record it to tell when all
+// clients have moved to the new scheme.
+func (s *Server) ProofScheme() ProofScheme {
+	return s.used
+}
+
+// record 'p' as the scheme that verified the client's proof 'm' and
+// return the server's proof
+func (s *Server) reply(p ProofScheme, t *Transcript, m []byte) ([]byte, bool) {
+	if t.A == nil {
+		base := p
+		if n, ok := p.(noidProof); ok {
+			base = n.p
+		}
+		if _, ok := base.(legacyProof); !ok {
+			return nil, false
+		}
+	}
+
+	s.used = p
+	s.uh = t.h
+	s.xK = t.K
+	s.authed = true
+	return p.ServerProof(t, m), true
 }
 
 // RawKey returns the raw key negotiated as part of the SRP
 func (s *Server) RawKey() []byte {
+	s.s.misuse(!s.authed, "Server.RawKey() before the client's proof was verified")
 	return s.xK
 }
 
@@ -627,9 +1426,108 @@ func (s *Server) String() string {
 		pf.g, pf.N, s.i, s.salt, s.xB, s.xK)
 }
 
+// return the size of a new salt in bytes
+func (s *SRP) saltSize() int {
+	if s.saltLen > 0 && !s.fixed {
+		return s.saltLen
+	}
+	return s.pf.n
+}
+
+// with WithFixedWidthEncoding(), return an error unless 'salt' is as wide
+// as the prime field
+func (s *SRP) checkSaltWidth(salt []byte) error {
+	if s.fixed && len(salt) != s.pf.n {
+		return fmt.Errorf("srp: salt must be %d bytes, saw %d", s.pf.n, len(salt))
+	}
+	return nil
+}
+
+// ParsePublicKey converts the public key 'b' received from a peer (e.g.,
+// ClientCredentials.A) to a number. In an environment with
+// WithFixedWidthEncoding(), 'b' must be exactly as wide as the prime field;
+// the error matches ErrBadClientKey (clients report ErrBadServerKey).
+func (s *SRP) ParsePublicKey(b []byte) (*big.Int, error) {
+	if s.fixed && len(b) != s.pf.n {
+		return nil, fmt.Errorf("%w (must be %d bytes, saw %d)", ErrBadClientKey, s.pf.n, len(b))
+	}
+	return big.NewInt(0).SetBytes(b), nil
+}
+
+// ServerBegin is like the function ServerBegin() but applies the limits of
+// this environment and, with WithFixedWidthEncoding(), requires A to be
+// exactly as wide as the prime field (the function can't check it, as A
+// loses its width as a number). Servers that know their environment before
+// looking up the identity should use it.
+func (s *SRP) ServerBegin(creds string) (string, *big.Int, error) {
+	ih, A, err := ServerBeginWithLimits(creds, s.Limits())
+	if err != nil || !s.fixed {
+		return ih, A, err
+	}
+
+	cc, err := ParseClientCredentials(creds)
+	if err != nil {
+		return "", nil, err
+	}
+	if _, err := s.ParsePublicKey(cc.A); err != nil {
+		return "", nil, err
+	}
+	return ih, A, nil
+}
+
+// PadBytes returns 'x' left-padded with zeros to the size of the prime
+// field, as public keys are sent with WithPaddedCredentials()
+func (s *SRP) PadBytes(x *big.Int) []byte {
+	return pad(x, s.pf.n)
+}
+
+// return the wire encoding of the public key 'x'
+func (s *SRP) encodeInt(x *big.Int) []byte {
+	if s.fixed || s.padOut {
+		return s.PadBytes(x)
+	}
+	return x.Bytes()
+}
+
+// derive the salt of identity 'I' from the provisioning seed
+func (s *SRP) deterministicSalt(I []byte) []byte {
+	info := append([]byte("srp salt"), I...)
+	salt := make([]byte, s.saltSize())
+	if _, err := io.ReadFull(hkdf.New(sha256.New, s.seed, nil, info), salt); err != nil {
+		panic(fmt.Sprintf("srp: hkdf: %s", err))
+	}
+	return salt
+}
+
+// return the size of the secret ephemerals a, b in bits
+func (s *SRP) ephemeralBits() int {
+	if s.ephBits > 0 {
+		return s.ephBits
+	}
+	return s.pf.n * 8
+}
+
+// compute the private key x from the hashed identity & password with the
+// derivation 'xd' ("" or DeriveHMAC); the password is hardened first if 'k'
+// is not nil.
+func (s *SRP) privateKey(ih, ph, salt []byte, k *KDF, xd string) *big.Int {
+	if k != nil {
+		ph = k.key(ph, salt, len(ph))
+	}
+	if xd == DeriveHMAC {
+		return s.hmacX(ih, ph, salt)
+	}
+	return s.hashint(ih, ph, salt)
+}
+
 // hash byte stream and return as bytes
 func (s *SRP) hashbyte(a ...[]byte) []byte {
-	h := s.h.New()
+	return hashWith(s.h, a...)
+}
+
+// hash byte stream with the hash function 'hf' and return as bytes
+func hashWith(hf crypto.Hash, a ...[]byte) []byte {
+	h := newHash(hf)
 	for _, z := range a {
 		h.Write(z)
 	}
@@ -661,6 +1559,47 @@ func atobi(s string, base int) *big.Int {
 	return i
 }
 
+// optional "key=value" fields that trail an encoded message
+type extFields map[string]string
+
+// parse the trailing "key=value" fields 'v'
+func parseExt(v []string) (extFields, error) {
+	e := make(extFields, len(v))
+	for _, kv := range v {
+		i := strings.IndexByte(kv, '=')
+		if i <= 0 {
+			return nil, fmt.Errorf("malformed field %q", kv)
+		}
+
+		k := kv[:i]
+		if _, ok := e[k]; ok {
+			return nil, fmt.Errorf("duplicate field %q", k)
+		}
+		e[k] = kv[i+1:]
+	}
+	return e, nil
+}
+
+// remove and return the field 'k'
+func (e extFields) take(k string) (string, bool) {
+	v, ok := e[k]
+	delete(e, k)
+	return v, ok
+}
+
+// return an error if there are any fields we don't understand
+func (e extFields) done() error {
+	for k := range e {
+		return fmt.Errorf("unknown field %q", k)
+	}
+	return nil
+}
+
+// constant time comparison of a and b; the lengths are not secret.
+func ctEqual(a, b []byte) bool {
+	return subtle.ConstantTimeCompare(a, b) == 1
+}
+
 // pad x to n bytes if needed
 func pad(x *big.Int, n int) []byte {
 	b := x.Bytes()
@@ -680,23 +1619,76 @@ func pad(x *big.Int, n int) []byte {
 // Return n bytes of random  bytes. Uses cryptographically strong
 // random generator
 func randbytes(n int) []byte {
+	return readRand(CR.Reader, n)
+}
+
+// Return n bytes read from the random source 'r'
+func readRand(r io.Reader, n int) []byte {
 	b := make([]byte, n)
-	_, err := io.ReadFull(CR.Reader, b)
+	_, err := io.ReadFull(r, b)
 	if err != nil {
 		panic("Random source is broken!")
 	}
 	return b
 }
 
-// Generate and return a bigInt 'bits' bits in length
-func randBigInt(bits int) *big.Int {
+// Generate and return a bigInt 'bits' bits in length from the random
+// source 'r'
+func randBigInt(r io.Reader, bits int) *big.Int {
 	n := bits / 8
 	if (bits % 8) != 0 {
 		n += 1
 	}
-	b := randbytes(n)
-	r := big.NewInt(0).SetBytes(b)
-	return r
+	b := readRand(r, n)
+	return big.NewInt(0).SetBytes(b)
+}
+
+// maximum number of draws for a secret ephemeral before the random source
+// is considered broken; each draw succeeds with probability >= 1/4
+const maxEphemeralDraws = 128
+
+// return a new secret ephemeral a or b, uniformly distributed in
+// [2^(bits-1), 2^bits) where bits = ephemeralBits(). When the ephemeral is
+// as large as N, the range is [2^(bits-2), N) instead. Candidates outside
+// the range are rejected rather than reduced so that the distribution stays
+// uniform; tiny values (e.g., 0 or 1) are never used.
+func (s *SRP) ephemeral() *big.Int {
+	N := s.pf.N
+	bits := s.ephemeralBits()
+
+	lo := uint(bits - 1)
+	if nb := N.BitLen(); bits >= nb {
+		bits = nb
+		lo = uint(bits - 2)
+	}
+	min := big.NewInt(0).Lsh(one, lo)
+
+	// mask of the excess bits of the candidate bytes
+	mask := big.NewInt(0).Lsh(one, uint(bits))
+	mask.Sub(mask, one)
+
+	ar := s.arith()
+	for i := 0; i < maxEphemeralDraws; i++ {
+		x := randBigInt(s.random(), bits)
+		x.And(x, mask)
+		if ar.Cmp(x, min) >= 0 && ar.Cmp(x, N) < 0 {
+			return x
+		}
+	}
+	panic("Random source is broken!")
+}
+
+// return the random source of this environment
+func (s *SRP) random() io.Reader {
+	if s.rand != nil {
+		return s.rand
+	}
+	return CR.Reader
+}
+
+// Return n random bytes from the random source of this environment
+func (s *SRP) randbytes(n int) []byte {
+	return readRand(s.random(), n)
 }
 
 // Make a new prime field (safe prime & generator) that is 'nbits' long
@@ -777,17 +1769,48 @@ func init() {
 		b := atoi(v[0])
 
 		pf := &primeField{
-			g: atobi(v[1], 10),
-			N: atobi(v[2], 0),
-			n: b / 8,
+			g:  atobi(v[1], 10),
+			N:  atobi(v[2], 0),
+			n:  b / 8,
+			id: fmt.Sprintf("rfc5054-%d", b),
 		}
 		if big.NewInt(0).Cmp(pf.N) == 0 {
 			panic(fmt.Sprintf("srp init: N (%s) is zero", v[2]))
 		}
 		pflist[b] = pf
+		groups = append(groups, pf)
+	}
+
+	for _, s := range strings.Split(altGroupsStr, "\n") {
+		v := strings.Split(s, ":")
+		b := atoi(v[1])
+
+		pf := &primeField{
+			g:   atobi(v[2], 10),
+			N:   atobi(v[3], 0),
+			n:   b / 8,
+			id:  v[0],
+			alt: true,
+		}
+		if pf.N.BitLen() != b {
+			panic(fmt.Sprintf("srp init: group %s isn't %d bits", v[0], b))
+		}
+		groups = append(groups, pf)
 	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].n < groups[j].n
+	})
 }
 
+// Groups that share their size with a group above and are only used when
+// selected by id (see NewWithGroupID()): id:bits:g:N. These are the MODP
+// groups of RFC 2409 and RFC 3526; 2 generates their subgroup of prime
+// order (N-1)/2 rather than the full group.
+const altGroupsStr = `rfc2409-1024:1024:2:0xFFFFFFFFFFFFFFFFC90FDAA22168C234C4C6628B80DC1CD129024E088A67CC74020BBEA63B139B22514A08798E3404DDEF9519B3CD3A431B302B0A6DF25F14374FE1356D6D51C245E485B576625E7EC6F44C42E9A637ED6B0BFF5CB6F406B7EDEE386BFB5A899FA5AE9F24117C4B1FE649286651ECE65381FFFFFFFFFFFFFFFF
+rfc3526-1536:1536:2:0xFFFFFFFFFFFFFFFFC90FDAA22168C234C4C6628B80DC1CD129024E088A67CC74020BBEA63B139B22514A08798E3404DDEF9519B3CD3A431B302B0A6DF25F14374FE1356D6D51C245E485B576625E7EC6F44C42E9A637ED6B0BFF5CB6F406B7EDEE386BFB5A899FA5AE9F24117C4B1FE649286651ECE45B3DC2007CB8A163BF0598DA48361C55D39A69163FA8FD24CF5F83655D23DCA3AD961C62F356208552BB9ED529077096966D670C354E4ABC9804F1746C08CA237327FFFFFFFFFFFFFFFF
+rfc3526-2048:2048:2:0xFFFFFFFFFFFFFFFFC90FDAA22168C234C4C6628B80DC1CD129024E088A67CC74020BBEA63B139B22514A08798E3404DDEF9519B3CD3A431B302B0A6DF25F14374FE1356D6D51C245E485B576625E7EC6F44C42E9A637ED6B0BFF5CB6F406B7EDEE386BFB5A899FA5AE9F24117C4B1FE649286651ECE45B3DC2007CB8A163BF0598DA48361C55D39A69163FA8FD24CF5F83655D23DCA3AD961C62F356208552BB9ED529077096966D670C354E4ABC9804F1746C08CA18217C32905E462E36CE3BE39E772C180E86039B2783A2EC07A28FB5C55DF06F4C52C9DE2BCBF6955817183995497CEA956AE515D2261898FA051015728E5A8AACAA68FFFFFFFFFFFFFFFF`
+
 // Map of bits to <g, N> tuple
 const pflistStr = `1024:2:0xEEAF0AB9ADB38DD69C33F80AFA8FC5E86072618775FF3C0B9EA2314C9C256576D674DF7496EA81D3383B4813D692C6E0E0D5D8E250B98BE48E495C1D6089DAD15DC7D7B46154D6B6CE8EF4AD69B15D4982559B297BCF1885C529F566660E57EC68EDBC3C05726CC02FD4CBF4976EAA9AFD5138FE8376435B9FC61D2FC0EB06E3
 1536:2:0x9DEF3CAFB939277AB1F12A8617A47BBBDBA51DF499AC4C80BEEEA9614B19CC4D5F4F5F556E27CBDE51C6A94BE4607A291558903BA0D0F84380B655BB9A22E8DCDF028A7CEC67F0D08134B1C8B97989149B609E0BE3BAB63D47548381DBC5B1FC764E3F4B53DD9DA1158BFD3E2B9C8CF56EDF019539349627DB2FD53D24B7C48665772E437D6C7F8CE442734AF7CCB7AE837C264AE3A9BEB87F8A2FE9B8B5292E5A021FFF5E91479E8CE7A28C2442C6F315180F93499A234DCF76E3FED135F9BB
@@ -808,14 +1831,25 @@ var simplePrimes = []int64{
 	463, 467, 479, 487, 491, 499, 503, 509, 521, 523, 541,
 }
 
+// A primeField is immutable once made: the built-in groups are shared by
+// all environments of the program, and every environment of a custom
+// group or decoded verifier shares its own. N and g must never be modified
+// or handed to callers; GroupInfo, Transcript and group policies get
+// copies.
 type primeField struct {
-	g *big.Int
-	N *big.Int
-	n int // size of N in bytes
+	g   *big.Int
+	N   *big.Int
+	n   int    // size of N in bytes
+	id  string // standard name of a built-in group; "" for custom groups
+	alt bool   // a built-in group that isn't the default for its size
 }
 
-// prime field list - mapped by bit size; initialized via init() above.
+// prime field list - mapped by bit size; initialized via init() above and
+// read-only afterwards.
 var pflist map[int]*primeField
+
+// all built-in groups in increasing size; read-only after init()
+var groups []*primeField
 var one *big.Int
 
 // vim: noexpandtab:sw=8:ts=8:tw=92:
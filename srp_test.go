@@ -7,11 +7,16 @@
 package srp
 
 import (
+	"bytes"
+	"crypto"
 	"fmt"
+	"math/big"
 	"runtime"
+	"strings"
 	"testing"
 
 	"crypto/subtle"
+	"encoding/hex"
 )
 
 func newAsserter(t *testing.T) func(cond bool, msg string, args ...interface{}) {
@@ -31,6 +36,21 @@ func newAsserter(t *testing.T) func(cond bool, msg string, args ...interface{})
 	}
 }
 
+// skip a test of parameters that a "fips" build rejects
+func skipFIPS(t *testing.T) {
+	if FIPSBuild {
+		t.Skip("not approved in FIPS mode")
+	}
+}
+
+// return the size of the smallest group a test may use
+func weakTestBits() int {
+	if FIPSBuild {
+		return fipsMinBits
+	}
+	return 1024
+}
+
 type userdb struct {
 	s *SRP
 	u map[string]string
@@ -38,7 +58,7 @@ type userdb struct {
 
 func newUserDB(user, pass []byte, p int) (*userdb, error) {
 
-	s, err := New(p)
+	s, err := New(p, WithInsecureGroups())
 	if err != nil {
 		return nil, err
 	}
@@ -60,6 +80,21 @@ func newUserDB(user, pass []byte, p int) (*userdb, error) {
 	return db, nil
 }
 
+// make a user db with a single user in the environment 's'
+func newUserDBFrom(t *testing.T, s *SRP, user, pass []byte) *userdb {
+	assert := newAsserter(t)
+
+	v, err := s.Verifier(user, pass, nil)
+	assert(err == nil, "Verifier: %s", err)
+
+	ih, vh := v.Encode()
+	db := &userdb{
+		s: s,
+		u: map[string]string{ih: vh},
+	}
+	return db
+}
+
 // simulated user lookup
 func (db *userdb) lookup(ih string) (bool, string) {
 	u, ok := db.u[ih]
@@ -146,6 +181,9 @@ func TestSRP(t *testing.T) {
 	bits := []int{1024, 2048, 3072, 4096, 6144, 8192}
 
 	for _, p := range bits {
+		if FIPSBuild && p < fipsMinBits {
+			continue
+		}
 		t.Logf("Prime bits %d ..\n", p)
 		db, err := newUserDB(user, goodpass, p)
 		assert(err == nil, "expected err to be nil; saw %s", err)
@@ -154,3 +192,624 @@ func TestSRP(t *testing.T) {
 		db.verify(t, user, badpass, false)
 	}
 }
+
+func TestUnmarshalServerMalformed(t *testing.T) {
+	assert := newAsserter(t)
+
+	s, err := New(2048)
+	assert(err == nil, "New: %s", err)
+
+	v, err := s.Verifier([]byte("user00"), []byte("pass"), nil)
+	assert(err == nil, "Verifier: %s", err)
+
+	c, err := s.NewClient([]byte("user00"), []byte("pass"))
+	assert(err == nil, "NewClient: %s", err)
+
+	_, A, err := ServerBegin(c.Credentials())
+	assert(err == nil, "ServerBegin: %s", err)
+
+	srv, err := s.NewServer(v, A)
+	assert(err == nil, "NewServer: %s", err)
+
+	m := srv.Marshal()
+	_, err = UnmarshalServer(m)
+	assert(err == nil, "UnmarshalServer: %s", err)
+
+	// corrupt v, B and A in turn; none of them may panic
+	p := strings.Split(m, ":")
+	for _, i := range []int{4, 5} {
+		q := append([]string{}, p...)
+		q[i] = "zz"
+		_, err = UnmarshalServer(strings.Join(q, ":"))
+		assert(err != nil, "accepted malformed field %d", i)
+	}
+	for i, f := range p {
+		if strings.HasPrefix(f, "a=") {
+			q := append([]string{}, p...)
+			q[i] = "a=zz"
+			_, err = UnmarshalServer(strings.Join(q, ":"))
+			assert(err != nil, "accepted malformed client public key")
+		}
+	}
+}
+
+func TestMatchesIdentity(t *testing.T) {
+	assert := newAsserter(t)
+
+	s, err := New(2048)
+	assert(err == nil, "New: %s", err)
+
+	v, err := s.Verifier([]byte("user00"), []byte("pass"), nil)
+	assert(err == nil, "Verifier: %s", err)
+
+	c, err := s.NewClient([]byte("user00"), []byte("pass"))
+	assert(err == nil, "NewClient: %s", err)
+
+	ih, _, err := ServerBegin(c.Credentials())
+	assert(err == nil, "ServerBegin: %s", err)
+
+	ib, err := hex.DecodeString(ih)
+	assert(err == nil, "decode identity: %s", err)
+	assert(v.MatchesIdentity(ib), "identity mismatch")
+
+	c, err = s.NewClient([]byte("user01"), []byte("pass"))
+	assert(err == nil, "NewClient: %s", err)
+
+	ih, _, err = ServerBegin(c.Credentials())
+	assert(err == nil, "ServerBegin: %s", err)
+
+	ib, err = hex.DecodeString(ih)
+	assert(err == nil, "decode identity: %s", err)
+	assert(!v.MatchesIdentity(ib), "matched wrong identity")
+	assert(!v.MatchesIdentity(nil), "matched empty identity")
+}
+
+func TestVerifierAccessors(t *testing.T) {
+	assert := newAsserter(t)
+
+	// a hash other than the default one; a "fips" build has no other
+	hash := crypto.SHA3_256
+	if FIPSBuild {
+		hash = crypto.SHA256
+	}
+	s, err := NewWithHash(hash, 3072)
+	assert(err == nil, "New: %s", err)
+
+	salt := []byte("0123456789abcdef")
+	v, err := s.VerifierWithSalt([]byte("user00"), []byte("pass"), salt)
+	assert(err == nil, "Verifier: %s", err)
+
+	assert(bytes.Equal(v.Salt(), salt), "salt %x, expected %x", v.Salt(), salt)
+	v.Salt()[0] ^= 1
+	assert(bytes.Equal(v.Salt(), salt), "salt not copied")
+
+	ih, _ := v.Encode()
+	assert(hex.EncodeToString(v.IdentityHash()) == ih, "identity hash %x, expected %s", v.IdentityHash(), ih)
+	assert(v.MatchesIdentity(v.IdentityHash()), "identity hash doesn't match")
+
+	h, bits := v.Params()
+	assert(h == hash && bits == 3072, "params %d %d", h, bits)
+}
+
+func TestBinaryAPI(t *testing.T) {
+	assert := newAsserter(t)
+
+	user := []byte("user00")
+	pass := []byte("secretpassword")
+
+	s, err := New(2048)
+	assert(err == nil, "New: %s", err)
+
+	v, err := s.Verifier(user, pass, nil)
+	assert(err == nil, "Verifier: %s", err)
+
+	c, err := s.NewClient(user, pass)
+	assert(err == nil, "NewClient: %s", err)
+
+	cc := c.Hello()
+	assert(v.MatchesIdentity(cc.IdentityHash), "identity mismatch")
+
+	srv, err := s.NewServer(v, big.NewInt(0).SetBytes(cc.A))
+	assert(err == nil, "NewServer: %s", err)
+
+	m, err := c.Respond(srv.Challenge())
+	assert(err == nil, "Respond: %s", err)
+
+	proof, ok := srv.CheckProof(m)
+	assert(ok, "server: bad client proof")
+	assert(c.CheckProof(proof), "client: bad server proof")
+	assert(subtle.ConstantTimeCompare(c.RawKey(), srv.RawKey()) == 1, "key mismatch")
+
+	// the string API must be a thin veneer over the binary one
+	assert(c.Credentials() == hex.EncodeToString(cc.IdentityHash)+":"+hex.EncodeToString(cc.A),
+		"string credentials mismatch")
+
+	m[0] ^= 1
+	_, ok = srv.CheckProof(m)
+	assert(!ok, "server: accepted bad proof")
+	assert(!c.CheckProof(m), "client: accepted bad proof")
+}
+
+func TestTranscriptContext(t *testing.T) {
+	assert := newAsserter(t)
+
+	user := []byte("user")
+	pass := []byte("pass")
+
+	tests := []struct {
+		cctx, sctx string
+		ok         bool
+	}{
+		{"", "", true},
+		{"api-v2", "api-v2", true},
+		{"api-v2", "api-v1", false},
+		{"api-v2", "", false},
+		{"", "api-v2", false},
+	}
+
+	for i, x := range tests {
+		cs, err := New(2048, WithTranscriptContext([]byte(x.cctx)))
+		assert(err == nil, "New: %s", err)
+
+		v, err := cs.Verifier(user, pass, nil)
+		assert(err == nil, "Verifier: %s", err)
+		_, vh := v.Encode()
+
+		c, err := cs.NewClient(user, pass)
+		assert(err == nil, "NewClient: %s", err)
+
+		_, A, err := ServerBegin(c.Credentials())
+		assert(err == nil, "ServerBegin: %s", err)
+
+		ss, sv, err := MakeSRPVerifier(vh, WithTranscriptContext([]byte(x.sctx)))
+		assert(err == nil, "MakeSRPVerifier: %s", err)
+
+		srv, err := ss.NewServer(sv, A)
+		assert(err == nil, "NewServer: %s", err)
+
+		m, err := c.Generate(srv.Credentials())
+		assert(err == nil, "Generate: %s", err)
+
+		proof, ok := srv.ClientOk(m)
+		assert(ok == x.ok, "%d: exp %v, saw %v", i, x.ok, ok)
+		if ok {
+			assert(c.ServerOk(proof), "%d: bad server proof", i)
+		}
+	}
+}
+
+func TestFixedWidthEncoding(t *testing.T) {
+	assert := newAsserter(t)
+
+	user := []byte("user")
+	pass := []byte("pass")
+
+	s, err := New(2048, WithFixedWidthEncoding())
+	assert(err == nil, "New: %s", err)
+	n := s.FieldSize() / 8
+
+	v, err := s.Verifier(user, pass, nil)
+	assert(err == nil, "Verifier: %s", err)
+
+	c, err := s.NewClient(user, pass)
+	assert(err == nil, "NewClient: %s", err)
+
+	cc := c.Hello()
+	assert(len(cc.A) == n, "A: exp %d bytes, saw %d", n, len(cc.A))
+
+	A, err := s.ParsePublicKey(cc.A)
+	assert(err == nil, "ParsePublicKey: %s", err)
+
+	_, err = s.ParsePublicKey(cc.A[1:])
+	assert(err != nil, "accepted short public key")
+
+	srv, err := s.NewServer(v, A)
+	assert(err == nil, "NewServer: %s", err)
+
+	sc := srv.Challenge()
+	assert(len(sc.B) == n, "B: exp %d bytes, saw %d", n, len(sc.B))
+
+	// the width survives the string encoding
+	m, err := c.Generate(srv.Credentials())
+	assert(err == nil, "Generate: %s", err)
+
+	_, ok := srv.ClientOk(m)
+	assert(ok, "ClientOk failed")
+
+	c.Reset()
+	sc.B = sc.B[1:]
+	_, err = c.Respond(sc)
+	assert(err != nil, "accepted short B")
+
+	// servers check A in the string API too
+	c.Reset()
+	creds := c.Credentials()
+	_, _, err = s.ServerBegin(creds)
+	assert(err == nil, "ServerBegin: %s", err)
+
+	i := strings.IndexByte(creds, ':')
+	_, _, err = s.ServerBegin(creds[:i+1] + creds[i+3:])
+	assert(err != nil, "server accepted short A")
+
+	// salts are as wide as the prime field on both sides
+	ws, err := New(2048, WithFixedWidthEncoding(), WithSaltSize(16))
+	assert(err == nil, "New: %s", err)
+
+	v, err = ws.Verifier(user, pass, nil)
+	assert(err == nil, "Verifier: %s", err)
+	assert(len(v.Salt()) == n, "salt: exp %d bytes, saw %d", n, len(v.Salt()))
+
+	_, err = ws.VerifierWithSalt(user, pass, make([]byte, 16))
+	assert(err != nil, "accepted short salt")
+
+	ps, err := New(2048, WithSaltSize(16))
+	assert(err == nil, "New: %s", err)
+
+	pv, err := ps.Verifier(user, pass, nil)
+	assert(err == nil, "Verifier: %s", err)
+
+	_, err = ws.NewServer(pv, A)
+	assert(err != nil, "server sent short salt")
+
+	psrv, err := ps.NewServer(pv, A)
+	assert(err == nil, "NewServer: %s", err)
+
+	c.Reset()
+	_, err = c.Respond(psrv.Challenge())
+	assert(err != nil, "client accepted short salt")
+}
+
+func TestPaddedCredentials(t *testing.T) {
+	assert := newAsserter(t)
+
+	user := []byte("user")
+	pass := []byte("pass")
+
+	s, err := New(2048, WithPaddedCredentials())
+	assert(err == nil, "New: %s", err)
+	n := s.FieldSize() / 8
+
+	b := s.PadBytes(big.NewInt(0x0102))
+	assert(len(b) == n, "PadBytes: exp %d bytes, saw %d", n, len(b))
+	assert(b[n-2] == 1 && b[n-1] == 2 && b[0] == 0, "PadBytes: wrong value %x", b[n-4:])
+
+	v, err := s.Verifier(user, pass, nil)
+	assert(err == nil, "Verifier: %s", err)
+
+	c, err := s.NewClient(user, pass)
+	assert(err == nil, "NewClient: %s", err)
+
+	cc := c.Hello()
+	assert(len(cc.A) == n, "A: exp %d bytes, saw %d", n, len(cc.A))
+
+	_, A, err := ServerBegin(c.Credentials())
+	assert(err == nil, "ServerBegin: %s", err)
+	assert(A.Cmp(c.xA) == 0, "A mangled by padding")
+
+	// unlike WithFixedWidthEncoding(), short keys are still accepted
+	_, err = s.ParsePublicKey(cc.A[1:])
+	assert(err == nil, "rejected short public key: %s", err)
+
+	srv, err := s.NewServer(v, A)
+	assert(err == nil, "NewServer: %s", err)
+
+	sc := srv.Challenge()
+	assert(len(sc.B) == n, "B: exp %d bytes, saw %d", n, len(sc.B))
+
+	m, err := c.Generate(srv.Credentials())
+	assert(err == nil, "Generate: %s", err)
+
+	proof, ok := srv.ClientOk(m)
+	assert(ok, "ClientOk failed")
+	assert(c.ServerOk(proof), "ServerOk failed")
+}
+
+func TestVerifierWithSalt(t *testing.T) {
+	assert := newAsserter(t)
+
+	user := []byte("user")
+	pass := []byte("pass")
+	salt := []byte("legacy-salt")
+
+	s, err := New(2048)
+	assert(err == nil, "New: %s", err)
+
+	_, err = s.VerifierWithSalt(user, pass, nil)
+	assert(err != nil, "accepted empty salt")
+
+	_, err = s.VerifierWithSalt(user, pass, make([]byte, s.Limits().Salt+1))
+	assert(err != nil, "accepted oversized salt")
+
+	v0, err := s.VerifierWithSalt(user, pass, salt)
+	assert(err == nil, "VerifierWithSalt: %s", err)
+
+	v1, err := s.VerifierWithSalt(user, pass, salt)
+	assert(err == nil, "VerifierWithSalt: %s", err)
+
+	_, e0 := v0.Encode()
+	_, e1 := v1.Encode()
+	assert(e0 == e1, "verifiers differ for the same salt")
+
+	salt[0] ^= 1
+	ih, e2 := v0.Encode()
+	assert(e0 == e2, "verifier aliases the caller's salt")
+
+	db := &userdb{
+		s: s,
+		u: map[string]string{ih: e0},
+	}
+	db.verify(t, user, pass, true)
+}
+
+func TestDeterministicSalts(t *testing.T) {
+	assert := newAsserter(t)
+
+	seed := []byte("0123456789abcdef0123456789abcdef")
+	pass := []byte("pass")
+
+	_, err := New(2048, WithDeterministicSalts(seed[:16]))
+	assert(err != nil, "accepted short seed")
+
+	s, err := New(2048, WithDeterministicSalts(seed))
+	assert(err == nil, "New: %s", err)
+
+	v0, err := s.Verifier([]byte("dev0"), pass, nil)
+	assert(err == nil, "Verifier: %s", err)
+	v1, err := s.Verifier([]byte("dev0"), pass, nil)
+	assert(err == nil, "Verifier: %s", err)
+	v2, err := s.Verifier([]byte("dev1"), pass, nil)
+	assert(err == nil, "Verifier: %s", err)
+
+	_, e0 := v0.Encode()
+	_, e1 := v1.Encode()
+	assert(e0 == e1, "verifiers for the same identity differ")
+	assert(len(v0.s) == s.FieldSize()/8, "wrong salt size %d", len(v0.s))
+	assert(!bytes.Equal(v0.s, v2.s), "same salt for different identities")
+
+	// the default path stays random
+	r, err := New(2048)
+	assert(err == nil, "New: %s", err)
+	r0, err := r.Verifier([]byte("dev0"), pass, nil)
+	assert(err == nil, "Verifier: %s", err)
+	r1, err := r.Verifier([]byte("dev0"), pass, nil)
+	assert(err == nil, "Verifier: %s", err)
+	assert(!bytes.Equal(r0.s, r1.s), "random salts repeat")
+
+	newUserDBFrom(t, s, []byte("dev0"), pass).verify(t, []byte("dev0"), pass, true)
+}
+
+// fills reads with a repeating counter; for tests only
+type counterReader struct {
+	n byte
+}
+
+func (r *counterReader) Read(b []byte) (int, error) {
+	for i := range b {
+		r.n++
+		b[i] = r.n
+	}
+	return len(b), nil
+}
+
+func TestWithRand(t *testing.T) {
+	assert := newAsserter(t)
+
+	user := []byte("user")
+	pass := []byte("pass")
+
+	_, err := New(2048, WithRand(nil))
+	assert(err != nil, "accepted nil random source")
+
+	// every read is as long as N, so every draw of an ephemeral starts
+	// with 0x80 and is within the range of ephemerals (see ephemeral())
+	var vs []*Verifier
+	var as []string
+	for i := 0; i < 2; i++ {
+		s, err := New(2048, WithRand(&counterReader{n: 0x7f}))
+		assert(err == nil, "New: %s", err)
+
+		v, err := s.Verifier(user, pass, nil)
+		assert(err == nil, "Verifier: %s", err)
+		vs = append(vs, v)
+
+		c, err := s.NewClient(user, pass)
+		assert(err == nil, "NewClient: %s", err)
+		as = append(as, c.Credentials())
+	}
+
+	assert(bytes.Equal(vs[0].s, vs[1].s), "salt not read from the random source")
+	assert(as[0] == as[1], "ephemeral not read from the random source")
+
+	s, err := New(2048, WithRand(&counterReader{n: 0x7f}))
+	assert(err == nil, "New: %s", err)
+	newUserDBFrom(t, s, user, pass).verify(t, user, pass, true)
+}
+
+func TestDummyHandshake(t *testing.T) {
+	assert := newAsserter(t)
+
+	d, err := NewDefault()
+	assert(err == nil, "NewDefault: %s", err)
+	w, err := New(weakTestBits(), WithInsecureGroups())
+	assert(err == nil, "New: %s", err)
+
+	for _, s := range []*SRP{d, w} {
+		s.DummyHandshake()
+	}
+}
+
+func BenchmarkDummyHandshake(b *testing.B) {
+	s, err := NewDefault()
+	if err != nil {
+		b.Fatalf("NewDefault: %s", err)
+	}
+
+	for i := 0; i < b.N; i++ {
+		s.DummyHandshake()
+	}
+}
+
+func BenchmarkNewServer(b *testing.B) {
+	s, err := NewDefault()
+	if err != nil {
+		b.Fatalf("NewDefault: %s", err)
+	}
+
+	v, err := s.Verifier([]byte("user"), []byte("pass"), nil)
+	if err != nil {
+		b.Fatalf("Verifier: %s", err)
+	}
+
+	c, err := s.NewClient([]byte("user"), []byte("pass"))
+	if err != nil {
+		b.Fatalf("NewClient: %s", err)
+	}
+	_, A, err := ServerBegin(c.Credentials())
+	if err != nil {
+		b.Fatalf("ServerBegin: %s", err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := s.NewServer(v, A); err != nil {
+			b.Fatalf("NewServer: %s", err)
+		}
+	}
+}
+
+func TestVerifyClientProof(t *testing.T) {
+	assert := newAsserter(t)
+
+	user := []byte("user")
+	pass := []byte("pass")
+
+	s, err := New(2048)
+	assert(err == nil, "New: %s", err)
+
+	v, err := s.Verifier(user, pass, nil)
+	assert(err == nil, "Verifier: %s", err)
+
+	for _, pw := range []string{"pass", "wrong"} {
+		c, err := s.NewClient(user, []byte(pw))
+		assert(err == nil, "NewClient: %s", err)
+
+		_, A, err := ServerBegin(c.Credentials())
+		assert(err == nil, "ServerBegin: %s", err)
+
+		srv, err := s.NewServer(v, A)
+		assert(err == nil, "NewServer: %s", err)
+
+		m, err := c.Generate(srv.Credentials())
+		assert(err == nil, "Generate: %s", err)
+
+		bad := []string{"", "zz", m[:len(m)-1], m[2:], strings.Repeat("00", 1024)}
+		for _, x := range bad {
+			_, err = srv.VerifyClientProof(x)
+			assert(err == ErrMalformedProof, "%q: exp malformed, saw %v", x, err)
+		}
+
+		proof, err := srv.VerifyClientProof(m)
+		if pw == "wrong" {
+			assert(err == ErrProofMismatch, "exp mismatch, saw %v", err)
+			assert(proof == "", "proof for a mismatch")
+			continue
+		}
+		assert(err == nil, "VerifyClientProof: %s", err)
+		assert(c.ServerOk(proof), "bad server proof")
+	}
+}
+
+// returns only zeros; for tests only
+type zeroReader struct{}
+
+func (zeroReader) Read(b []byte) (int, error) {
+	for i := range b {
+		b[i] = 0
+	}
+	return len(b), nil
+}
+
+func TestEphemeralRange(t *testing.T) {
+	assert := newAsserter(t)
+
+	d, err := NewDefault()
+	assert(err == nil, "NewDefault: %s", err)
+	s, err := New(2048)
+	assert(err == nil, "New: %s", err)
+	w, err := New(weakTestBits(), WithInsecureGroups(), withEphemeralBits(255))
+	assert(err == nil, "New: %s", err)
+
+	tests := []struct {
+		s      *SRP
+		lo, hi int // bit length
+	}{
+		{d, DefaultEphemeralBits, DefaultEphemeralBits},
+		{s, 2047, 2048},
+		{w, 255, 255},
+	}
+
+	const n = 500
+	for _, x := range tests {
+		set := 0
+		for i := 0; i < n; i++ {
+			e := x.s.ephemeral()
+			bl := e.BitLen()
+			assert(bl >= x.lo && bl <= x.hi, "%d bits out of range [%d, %d]", bl, x.lo, x.hi)
+			assert(e.Cmp(x.s.pf.N) < 0, "ephemeral not less than N")
+			set += int(e.Bit(x.lo - 2))
+		}
+
+		// the bits below the top are uniform
+		assert(set > n*35/100 && set < n*65/100, "%d bits: biased; %d of %d set", x.hi, set, n)
+	}
+
+	// a source that only yields tiny values is rejected
+	for _, e := range []*SRP{d, s} {
+		func() {
+			defer func() {
+				assert(recover() != nil, "accepted tiny ephemerals")
+			}()
+			e.rand = zeroReader{}
+			e.ephemeral()
+		}()
+	}
+}
+
+func TestNewSession(t *testing.T) {
+	assert := newAsserter(t)
+
+	user := []byte("user")
+	pass := []byte("pass")
+
+	s, err := New(2048, WithProofScheme(ProofRFC5054))
+	assert(err == nil, "New: %s", err)
+
+	v, err := s.Verifier(user, pass, nil)
+	assert(err == nil, "Verifier: %s", err)
+	_, vh := v.Encode()
+
+	// the decoded verifier carries the options given to MakeSRPVerifier()
+	_, sv, err := MakeSRPVerifier(vh, WithProofScheme(ProofRFC5054))
+	assert(err == nil, "MakeSRPVerifier: %s", err)
+
+	for _, x := range []*Verifier{v, sv} {
+		c, err := s.NewClient(user, pass)
+		assert(err == nil, "NewClient: %s", err)
+
+		_, A, err := ServerBegin(c.Credentials())
+		assert(err == nil, "ServerBegin: %s", err)
+
+		srv, err := x.NewSession(A)
+		assert(err == nil, "NewSession: %s", err)
+
+		m, err := c.Generate(srv.Credentials())
+		assert(err == nil, "Generate: %s", err)
+
+		proof, ok := srv.ClientOk(m)
+		assert(ok, "ClientOk failed")
+		assert(c.ServerOk(proof), "ServerOk failed")
+	}
+
+	_, err = (&Verifier{}).NewSession(big.NewInt(2))
+	assert(err != nil, "NewSession without an environment")
+}
@@ -0,0 +1,251 @@
+// srpcompat.go - the API of github.com/opencoff/go-srp
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+// Package srpcompat provides the exported API of github.com/opencoff/go-srp,
+// from which package srp is forked, on top of package srp. Programs written
+// against the upstream package switch by changing the import:
+//
+//	import srp "github.com/tomsons/go-srp/srpcompat"
+//
+// Environments created without options behave like the upstream package:
+// BLAKE2b-256 by default, groups from 1024 bits, and the upstream string
+// forms of verifiers ("n:N:g:h:I:s:v"), credentials ("I:A" and "s:B"),
+// proofs (hex) and marshaled servers, so that upstream and converted peers
+// and stores interoperate. The constructors take options of package srp
+// as an extension; environments with options use the encodings of package
+// srp, which upstream peers may not understand. Unwrap() returns the
+// underlying types for the rest of package srp. A "fips" build of package
+// srp rejects BLAKE2b-256; such programs can only use NewWithHash() with
+// an approved hash and can't interoperate with upstream peers.
+package srpcompat
+
+import (
+	"crypto"
+	"math/big"
+	"strings"
+
+	"github.com/tomsons/go-srp"
+)
+
+// SRP is an environment of clients and servers (see srp.SRP)
+type SRP struct {
+	s     *srp.SRP
+	plain bool // made without options; use the upstream encodings
+}
+
+// New creates an environment with a 'bits' sized prime field and
+// BLAKE2b-256
+func New(bits int, opts ...srp.Option) (*SRP, error) {
+	return NewWithHash(crypto.BLAKE2b_256, bits, opts...)
+}
+
+// NewWithHash creates an environment with a 'bits' sized prime field and
+// the hash function 'h'
+func NewWithHash(h crypto.Hash, bits int, opts ...srp.Option) (*SRP, error) {
+	s, err := srp.NewWithHash(h, bits, compatOpts(opts)...)
+	if err != nil {
+		return nil, err
+	}
+	return &SRP{s: s, plain: len(opts) == 0}, nil
+}
+
+// FieldSize returns the size of the prime field in bits
+func (s *SRP) FieldSize() int {
+	return s.s.FieldSize()
+}
+
+// Verifier returns the password verifier of user 'I' with password 'p'
+// and a random salt, or the salt passed as the optional last argument.
+func (s *SRP) Verifier(I, p []byte, salt ...[]byte) (*Verifier, error) {
+	var sel []byte
+	if len(salt) > 0 {
+		sel = salt[0]
+	}
+
+	v, err := s.s.Verifier(I, p, sel)
+	if err != nil {
+		return nil, err
+	}
+	return &Verifier{v: v, plain: s.plain}, nil
+}
+
+// NewClient creates a client for user 'I' with password 'p'
+func (s *SRP) NewClient(I, p []byte) (*Client, error) {
+	c, err := s.s.NewClient(I, p)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{c: c}, nil
+}
+
+// NewServer creates a server for the verifier 'v' and the client public
+// key 'A' returned by ServerBegin()
+func (s *SRP) NewServer(v *Verifier, A *big.Int) (*Server, error) {
+	srv, err := s.s.NewServer(v.v, A)
+	if err != nil {
+		return nil, err
+	}
+	return &Server{s: srv, plain: s.plain}, nil
+}
+
+// Unwrap returns the environment of package srp
+func (s *SRP) Unwrap() *srp.SRP {
+	return s.s
+}
+
+// ServerBegin parses the client credentials "I:A" and returns the hashed
+// identity (in hex) and A.
+func ServerBegin(creds string) (string, *big.Int, error) {
+	return srp.ServerBegin(creds)
+}
+
+// MakeSRPVerifier decodes a verifier encoded by Verifier.Encode() (of this
+// package, the upstream package or package srp) into its environment and
+// Verifier.
+func MakeSRPVerifier(b string, opts ...srp.Option) (*SRP, *Verifier, error) {
+	s, v, err := srp.MakeSRPVerifier(b, compatOpts(opts)...)
+	if err != nil {
+		return nil, nil, err
+	}
+	plain := len(opts) == 0
+	return &SRP{s: s, plain: plain}, &Verifier{v: v, plain: plain}, nil
+}
+
+// NewPrimeField generates a new safe prime field of size 'nbits'
+func NewPrimeField(nbits int) (p, g *big.Int, err error) {
+	return srp.NewPrimeField(nbits)
+}
+
+// Verifier is a password verifier (see srp.Verifier)
+type Verifier struct {
+	v     *srp.Verifier
+	plain bool
+}
+
+// Encode returns the hashed identity and the encoded verifier; both are
+// strings to store against each other.
+func (v *Verifier) Encode() (string, string) {
+	ih, vh := v.v.Encode()
+	if v.plain {
+		vh = upstreamForm(vh, 7)
+	}
+	return ih, vh
+}
+
+// Unwrap returns the verifier of package srp
+func (v *Verifier) Unwrap() *srp.Verifier {
+	return v.v
+}
+
+// Client is the client side of a handshake (see srp.Client)
+type Client struct {
+	c *srp.Client
+}
+
+// Credentials returns the client credentials "I:A" to send to the server
+func (c *Client) Credentials() string {
+	return c.c.Credentials()
+}
+
+// Generate processes the server credentials and returns the client's proof
+func (c *Client) Generate(srv string) (string, error) {
+	return c.c.Generate(srv)
+}
+
+// ServerOk returns true if 'proof' is the server's valid proof
+func (c *Client) ServerOk(proof string) bool {
+	return c.c.ServerOk(proof)
+}
+
+// RawKey returns the session key
+func (c *Client) RawKey() []byte {
+	return c.c.RawKey()
+}
+
+// String returns the client's parameters for debugging
+func (c *Client) String() string {
+	return c.c.String()
+}
+
+// Unwrap returns the client of package srp
+func (c *Client) Unwrap() *srp.Client {
+	return c.c
+}
+
+// Server is the server side of a handshake (see srp.Server)
+type Server struct {
+	s     *srp.Server
+	plain bool
+}
+
+// UnmarshalServer decodes a server encoded by Server.Marshal()
+func UnmarshalServer(s string, opts ...srp.Option) (*Server, error) {
+	srv, err := srp.UnmarshalServer(s, compatOpts(opts)...)
+	if err != nil {
+		return nil, err
+	}
+	return &Server{s: srv, plain: len(opts) == 0}, nil
+}
+
+// Credentials returns the server credentials "s:B" to send to the client
+func (s *Server) Credentials() string {
+	return s.s.Credentials()
+}
+
+// ClientOk checks the client's proof 'm' and returns the server's proof
+func (s *Server) ClientOk(m string) (proof string, ok bool) {
+	return s.s.ClientOk(m)
+}
+
+// Marshal encodes the state of the server; UnmarshalServer() restores it
+func (s *Server) Marshal() string {
+	m := s.s.Marshal()
+	if s.plain {
+		m = upstreamForm(m, 8)
+	}
+	return m
+}
+
+// RawKey returns the session key
+func (s *Server) RawKey() []byte {
+	return s.s.RawKey()
+}
+
+// String returns the server's parameters for debugging
+func (s *Server) String() string {
+	return s.s.String()
+}
+
+// Unwrap returns the server of package srp
+func (s *Server) Unwrap() *srp.Server {
+	return s.s
+}
+
+// return the options of an environment of this package: those of the
+// upstream package and 'opts'
+func compatOpts(opts []srp.Option) []srp.Option {
+	return append([]srp.Option{srp.WithInsecureGroups()}, opts...)
+}
+
+// return the encoding 'b' of package srp in the upstream form of 'n'
+// colon separated fields if the extensions after them only repeat what
+// upstream peers infer: the name of an RFC 5054 group and the client's
+// public key. Other encodings are returned as they are.
+func upstreamForm(b string, n int) string {
+	f := strings.Split(b, ":")
+	if len(f) <= n {
+		return b
+	}
+	for _, e := range f[n:] {
+		switch {
+		case strings.HasPrefix(e, "grp=rfc5054-"):
+		case strings.HasPrefix(e, "a="):
+		default:
+			return b
+		}
+	}
+	return strings.Join(f[:n], ":")
+}
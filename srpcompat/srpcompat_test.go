@@ -0,0 +1,225 @@
+// self test for the upstream API
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srpcompat
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/tomsons/go-srp"
+	"golang.org/x/crypto/blake2b"
+)
+
+// hash 'a' with BLAKE2b-256 like the upstream package
+func h(a ...[]byte) []byte {
+	d, _ := blake2b.New256(nil)
+	for _, b := range a {
+		d.Write(b)
+	}
+	return d.Sum(nil)
+}
+
+func hint(a ...[]byte) *big.Int {
+	return big.NewInt(0).SetBytes(h(a...))
+}
+
+// pad 'x' to 'n' bytes
+func pad(x *big.Int, n int) []byte {
+	b := x.Bytes()
+	return append(make([]byte, n-len(b)), b...)
+}
+
+// the client of the upstream package, computed as it does
+type upstreamClient struct {
+	N, g  *big.Int
+	n     int
+	ih, p []byte
+	a, A  *big.Int
+	K, M  []byte
+}
+
+func newUpstreamClient(bits int, I, p string) *upstreamClient {
+	var N, g *big.Int
+	for _, gi := range srp.SupportedGroups() {
+		if gi.ID == "rfc5054-"+big.NewInt(int64(bits)).String() {
+			N, g = gi.N, gi.G
+		}
+	}
+
+	b := make([]byte, bits/8)
+	rand.Read(b)
+	c := &upstreamClient{N: N, g: g, n: bits / 8, ih: h([]byte(I)), p: h([]byte(p))}
+	c.a = big.NewInt(0).SetBytes(b)
+	c.A = big.NewInt(0).Exp(g, c.a, N)
+	return c
+}
+
+func (c *upstreamClient) Credentials() string {
+	return hex.EncodeToString(c.ih) + ":" + hex.EncodeToString(c.A.Bytes())
+}
+
+func (c *upstreamClient) Generate(srv string) string {
+	v := strings.Split(srv, ":")
+	salt, _ := hex.DecodeString(v[0])
+	B, _ := big.NewInt(0).SetString(v[1], 16)
+
+	k := hint(c.N.Bytes(), pad(c.g, c.n))
+	u := hint(pad(c.A, c.n), pad(B, c.n))
+	x := hint(c.ih, c.p, salt)
+
+	t0 := big.NewInt(0).Mul(big.NewInt(0).Exp(c.g, x, c.N), k)
+	t1 := big.NewInt(0).Sub(B, t0)
+	t2 := big.NewInt(0).Add(c.a, big.NewInt(0).Mul(u, x))
+	S := big.NewInt(0).Exp(t1, t2, c.N)
+
+	c.K = h(S.Bytes())
+	c.M = h(c.K, c.A.Bytes(), B.Bytes(), c.ih, salt, c.N.Bytes(), c.g.Bytes())
+	return hex.EncodeToString(c.M)
+}
+
+func (c *upstreamClient) ServerOk(proof string) bool {
+	return proof == hex.EncodeToString(h(c.K, c.M))
+}
+
+func TestUpstreamClient(t *testing.T) {
+	skipFIPS(t)
+	for _, bits := range []int{1024, 2048} {
+		s, err := New(bits)
+		if err != nil {
+			t.Fatalf("New(%d): %s", bits, err)
+		}
+		v, err := s.Verifier([]byte("user"), []byte("pass"))
+		if err != nil {
+			t.Fatalf("Verifier: %s", err)
+		}
+
+		ih, vh := v.Encode()
+		if n := len(strings.Split(vh, ":")); n != 7 {
+			t.Fatalf("verifier has %d fields, expected 7: %s", n, vh)
+		}
+
+		for _, pass := range []string{"pass", "wrong"} {
+			c := newUpstreamClient(bits, "user", pass)
+
+			I, A, err := ServerBegin(c.Credentials())
+			if err != nil || I != ih {
+				t.Fatalf("ServerBegin: %s %s", I, err)
+			}
+
+			ss, sv, err := MakeSRPVerifier(vh)
+			if err != nil {
+				t.Fatalf("MakeSRPVerifier: %s", err)
+			}
+			srv, err := ss.NewServer(sv, A)
+			if err != nil {
+				t.Fatalf("NewServer: %s", err)
+			}
+
+			creds := srv.Credentials()
+			if n := len(strings.Split(creds, ":")); n != 2 {
+				t.Fatalf("server credentials have %d fields: %s", n, creds)
+			}
+
+			m := srv.Marshal()
+			if n := len(strings.Split(m, ":")); n != 8 {
+				t.Fatalf("marshaled server has %d fields: %s", n, m)
+			}
+			srv, err = UnmarshalServer(m)
+			if err != nil {
+				t.Fatalf("UnmarshalServer: %s", err)
+			}
+
+			proof, ok := srv.ClientOk(c.Generate(creds))
+			if ok != (pass == "pass") {
+				t.Fatalf("%d bits, %s: server accepted %v", bits, pass, ok)
+			}
+			if ok && (!c.ServerOk(proof) || !bytes.Equal(c.K, srv.RawKey())) {
+				t.Fatalf("%d bits: client rejects the server", bits)
+			}
+		}
+	}
+}
+
+func TestHandshake(t *testing.T) {
+	skipFIPS(t)
+	s, err := New(2048)
+	if err != nil {
+		t.Fatalf("New: %s", err)
+	}
+	salt := []byte("0123456789abcdef")
+	v, err := s.Verifier([]byte("user"), []byte("pass"), salt)
+	if err != nil {
+		t.Fatalf("Verifier: %s", err)
+	}
+	if !bytes.Equal(v.Unwrap().Salt(), salt) {
+		t.Fatalf("salt not used")
+	}
+
+	c, err := s.NewClient([]byte("user"), []byte("pass"))
+	if err != nil {
+		t.Fatalf("NewClient: %s", err)
+	}
+	_, A, err := ServerBegin(c.Credentials())
+	if err != nil {
+		t.Fatalf("ServerBegin: %s", err)
+	}
+	srv, err := s.NewServer(v, A)
+	if err != nil {
+		t.Fatalf("NewServer: %s", err)
+	}
+	m, err := c.Generate(srv.Credentials())
+	if err != nil {
+		t.Fatalf("Generate: %s", err)
+	}
+	proof, ok := srv.ClientOk(m)
+	if !ok || !c.ServerOk(proof) {
+		t.Fatalf("handshake failed")
+	}
+	if !bytes.Equal(c.RawKey(), srv.RawKey()) {
+		t.Fatalf("keys differ")
+	}
+}
+
+func TestOptions(t *testing.T) {
+	skipFIPS(t)
+	// options of package srp use its encodings
+	kdf := srp.KDF{Alg: srp.KDFArgon2id, Time: 1, Memory: 64, Threads: 1}
+	s, err := New(2048, srp.WithKDF(kdf))
+	if err != nil {
+		t.Fatalf("New: %s", err)
+	}
+	v, err := s.Verifier([]byte("user"), []byte("pass"))
+	if err != nil {
+		t.Fatalf("Verifier: %s", err)
+	}
+	_, vh := v.Encode()
+	if !strings.Contains(vh, ":kdf=") {
+		t.Fatalf("kdf dropped from the verifier: %s", vh)
+	}
+
+	_, w, err := MakeSRPVerifier(vh, srp.WithKDF(kdf))
+	if err != nil {
+		t.Fatalf("MakeSRPVerifier: %s", err)
+	}
+	if !w.Unwrap().Equal(v.Unwrap()) {
+		t.Fatalf("verifier changed")
+	}
+	if s.Unwrap().FieldSize() != s.FieldSize() {
+		t.Fatalf("field sizes differ")
+	}
+}
+
+// skip a test in a "fips" build, which rejects the upstream BLAKE2b-256
+func skipFIPS(t *testing.T) {
+	if srp.FIPSBuild {
+		t.Skip("not approved in FIPS mode")
+	}
+}
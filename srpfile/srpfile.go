@@ -0,0 +1,269 @@
+// srpfile.go - flat-file store of SRP verifiers
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+// Package srpfile keeps SRP verifiers in a flat file, in the spirit of an
+// htpasswd file: one verifier per line as
+//
+//	storage key <TAB> encoded verifier
+//
+// (the format of srp-migrate), blank lines and lines starting with '#'
+// are ignored. It suits small deployments and ops-managed accounts; see
+// cmd/srpverify for a tool that maintains such files.
+//
+// A Store implements srp.VerifierStore. Readers load the file once and
+// again whenever it changes. Writers take an exclusive lock, a file next
+// to the store named after it with the suffix ".lock", and replace the
+// store atomically by writing a temporary file in the same directory and
+// renaming it over the store; readers never see a partial file. A lock
+// left behind by a crashed writer must be removed by hand.
+package srpfile
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tomsons/go-srp"
+)
+
+// LockTimeout bounds the time a writer waits for the lock of a store
+var LockTimeout = 10 * time.Second
+
+// ErrLocked is returned when the lock of a store can't be taken in time
+var ErrLocked = errors.New("srpfile: store is locked")
+
+// Store is a flat file of verifiers
+type Store struct {
+	path string
+
+	sync.Mutex
+	m     map[string]string
+	mtime time.Time
+	size  int64
+}
+
+// Open returns the store in the file 'path'. The file needn't exist yet;
+// it is created by the first Put().
+func Open(path string) *Store {
+	return &Store{path: path}
+}
+
+// Get implements srp.VerifierStore
+func (st *Store) Get(key string) (string, error) {
+	st.Lock()
+	defer st.Unlock()
+
+	if err := st.refresh(); err != nil {
+		return "", err
+	}
+	vh, ok := st.m[key]
+	if !ok {
+		return "", srp.ErrNoVerifier
+	}
+	return vh, nil
+}
+
+// Put implements srp.VerifierStore
+func (st *Store) Put(key, vh string) error {
+	return st.Update(func(m map[string]string) error {
+		m[key] = vh
+		return nil
+	})
+}
+
+// Delete implements srp.VerifierStore
+func (st *Store) Delete(key string) error {
+	return st.Update(func(m map[string]string) error {
+		if _, ok := m[key]; !ok {
+			return srp.ErrNoVerifier
+		}
+		delete(m, key)
+		return nil
+	})
+}
+
+// Keys returns the storage keys of the store in sorted order
+func (st *Store) Keys() ([]string, error) {
+	st.Lock()
+	defer st.Unlock()
+
+	if err := st.refresh(); err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(st.m))
+	for k := range st.m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Update runs 'fn' on the current contents of the store, with the store
+// locked against other writers, and replaces the store with the modified
+// contents unless 'fn' returns an error.
+func (st *Store) Update(fn func(m map[string]string) error) error {
+	st.Lock()
+	defer st.Unlock()
+
+	unlock, err := lockFile(st.path + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	m, _, err := load(st.path)
+	if err != nil {
+		return err
+	}
+	if err := fn(m); err != nil {
+		return err
+	}
+	if err := write(st.path, m); err != nil {
+		return err
+	}
+
+	// reload on the next read; the rename changed the file
+	st.m = nil
+	return nil
+}
+
+// reload the store if the file changed
+func (st *Store) refresh() error {
+	fi, err := os.Stat(st.path)
+	switch {
+	case os.IsNotExist(err):
+		st.m = map[string]string{}
+		st.mtime, st.size = time.Time{}, 0
+		return nil
+	case err != nil:
+		return err
+	}
+
+	if st.m != nil && fi.ModTime().Equal(st.mtime) && fi.Size() == st.size {
+		return nil
+	}
+
+	m, fi, err := load(st.path)
+	if err != nil {
+		return err
+	}
+	st.m = m
+	if fi != nil {
+		st.mtime, st.size = fi.ModTime(), fi.Size()
+	}
+	return nil
+}
+
+// read the store in 'path'; a missing file is an empty store
+func load(path string) (map[string]string, os.FileInfo, error) {
+	m := map[string]string{}
+
+	fd, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return m, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	defer fd.Close()
+
+	fi, err := fd.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sc := bufio.NewScanner(fd)
+	sc.Buffer(nil, 1<<20)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+
+		i := strings.IndexByte(line, '\t')
+		if i <= 0 {
+			return nil, nil, fmt.Errorf("srpfile: %s:%d: malformed line", path, n)
+		}
+		k, vh := line[:i], strings.TrimSpace(line[i+1:])
+		if _, ok := m[k]; ok {
+			return nil, nil, fmt.Errorf("srpfile: %s:%d: duplicate key %s", path, n, k)
+		}
+		m[k] = vh
+	}
+	if err := sc.Err(); err != nil {
+		return nil, nil, fmt.Errorf("srpfile: %s: %s", path, err)
+	}
+	return m, fi, nil
+}
+
+// atomically replace the store in 'path' with 'm'
+func write(path string, m map[string]string) error {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b bytes.Buffer
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s\t%s\n", k, m[k])
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(b.Bytes()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// take the lock file 'path' and return the function that releases it
+func lockFile(path string) (func(), error) {
+	deadline := time.Now().Add(LockTimeout)
+	for wait := time.Millisecond; ; wait *= 2 {
+		fd, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err == nil {
+			fmt.Fprintf(fd, "%d\n", os.Getpid())
+			fd.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+
+		if time.Now().Add(wait).After(deadline) {
+			return nil, fmt.Errorf("%w (%s)", ErrLocked, path)
+		}
+		if wait > 100*time.Millisecond {
+			wait = 100 * time.Millisecond
+		}
+		time.Sleep(wait)
+	}
+}
@@ -0,0 +1,174 @@
+// self test for flat-file verifier stores
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srpfile
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tomsons/go-srp"
+)
+
+func tempStore(t *testing.T) (*Store, string, func()) {
+	dir, err := ioutil.TempDir("", "srpfile")
+	if err != nil {
+		t.Fatalf("TempDir: %s", err)
+	}
+	path := filepath.Join(dir, "users.srp")
+	return Open(path), path, func() { os.RemoveAll(dir) }
+}
+
+func TestStore(t *testing.T) {
+	st, path, done := tempStore(t)
+	defer done()
+
+	if _, err := st.Get("k1"); err != srp.ErrNoVerifier {
+		t.Fatalf("empty store: %v", err)
+	}
+	if err := st.Put("k1", "v1"); err != nil {
+		t.Fatalf("Put: %s", err)
+	}
+	if err := st.Put("k2", "v2"); err != nil {
+		t.Fatalf("Put: %s", err)
+	}
+	if vh, err := st.Get("k1"); err != nil || vh != "v1" {
+		t.Fatalf("Get: %q %v", vh, err)
+	}
+
+	// another writer's changes are seen
+	if err := Open(path).Put("k1", "v1'"); err != nil {
+		t.Fatalf("Put: %s", err)
+	}
+	if vh, _ := st.Get("k1"); vh != "v1'" {
+		t.Fatalf("stale read: %q", vh)
+	}
+
+	if err := st.Delete("k2"); err != nil {
+		t.Fatalf("Delete: %s", err)
+	}
+	if err := st.Delete("k2"); err != srp.ErrNoVerifier {
+		t.Fatalf("Delete twice: %v", err)
+	}
+	if keys, _ := st.Keys(); len(keys) != 1 || keys[0] != "k1" {
+		t.Fatalf("Keys: %v", keys)
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat: %s", err)
+	}
+	if fi.Mode().Perm() != 0600 {
+		t.Fatalf("store mode %v", fi.Mode())
+	}
+	if m, _ := filepath.Glob(path + "*"); len(m) != 1 {
+		t.Fatalf("stray files: %v", m)
+	}
+}
+
+func TestStoreFormat(t *testing.T) {
+	st, path, done := tempStore(t)
+	defer done()
+
+	data := "# users\n\nk1\tv1\nk2\tv2\n"
+	if err := ioutil.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+	if vh, err := st.Get("k2"); err != nil || vh != "v2" {
+		t.Fatalf("Get: %q %v", vh, err)
+	}
+
+	for _, bad := range []string{"k1 v1\n", "k1\tv1\nk1\tv2\n"} {
+		if err := ioutil.WriteFile(path, []byte(bad), 0600); err != nil {
+			t.Fatalf("WriteFile: %s", err)
+		}
+		if _, err := Open(path).Get("k1"); err == nil {
+			t.Fatalf("accepted %q", bad)
+		}
+	}
+}
+
+func TestStoreLocking(t *testing.T) {
+	st, path, done := tempStore(t)
+	defer done()
+
+	// concurrent writers don't lose updates
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			k := strings.Repeat("k", i+1)
+			if err := Open(path).Put(k, "v"); err != nil {
+				t.Errorf("Put: %s", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if keys, _ := st.Keys(); len(keys) != 8 {
+		t.Fatalf("lost updates: %v", keys)
+	}
+
+	// a held lock times out
+	if err := ioutil.WriteFile(path+".lock", nil, 0600); err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+	defer func(d time.Duration) { LockTimeout = d }(LockTimeout)
+	LockTimeout = 50 * time.Millisecond
+
+	if err := st.Put("k", "v"); !errors.Is(err, ErrLocked) {
+		t.Fatalf("locked store: %v", err)
+	}
+}
+
+func TestStoreLookup(t *testing.T) {
+	st, _, done := tempStore(t)
+	defer done()
+
+	s, err := srp.New(2048)
+	if err != nil {
+		t.Fatalf("New: %s", err)
+	}
+	v, err := s.Verifier([]byte("user"), []byte("pass"), nil)
+	if err != nil {
+		t.Fatalf("Verifier: %s", err)
+	}
+	if err := srp.StoreVerifier(st, v); err != nil {
+		t.Fatalf("StoreVerifier: %s", err)
+	}
+
+	lookup, err := srp.StoreLookup(st)
+	if err != nil {
+		t.Fatalf("StoreLookup: %s", err)
+	}
+	c, err := s.NewClient([]byte("user"), []byte("pass"))
+	if err != nil {
+		t.Fatalf("NewClient: %s", err)
+	}
+	hello := c.Hello()
+	vs, v, err := lookup(hello.IdentityHash)
+	if err != nil {
+		t.Fatalf("lookup: %s", err)
+	}
+	A, _ := vs.ParsePublicKey(hello.A)
+	srv, err := vs.NewServerFor(hello.IdentityHash, v, A)
+	if err != nil {
+		t.Fatalf("NewServerFor: %s", err)
+	}
+	m, err := c.Respond(srv.Challenge())
+	if err != nil {
+		t.Fatalf("Respond: %s", err)
+	}
+	if proof, ok := srv.CheckProof(m); !ok || !c.CheckProof(proof) {
+		t.Fatalf("login failed")
+	}
+}
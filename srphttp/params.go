@@ -0,0 +1,257 @@
+// params.go - publishing and discovering SRP parameters
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srphttp
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tomsons/go-srp"
+)
+
+// A server publishes the parameters of its environment (see
+// srp.PublishedParams) at WellKnownParams of its origin, and a client
+// creates its environment from them instead of configuring the group, the
+// hash and the proof scheme by hand:
+//
+//	GET /.well-known/srp-params -> {"group": ..., "hash": ..., ...}
+//
+// The parameters are only fetched over HTTPS. A ParamsCache keeps them for
+// a while and checks them against the fingerprints the client pinned; a
+// server that publishes other parameters (after a compromise or by
+// mistake) can then only make the client refuse to log in.
+
+// WellKnownParams is the path of the published parameters
+const WellKnownParams = "/.well-known/srp-params"
+
+// DefaultParamsTTL is the time a ParamsCache keeps fetched parameters
+const DefaultParamsTTL = time.Hour
+
+// ParamsHandler returns a handler that serves the parameters of 's' at
+// WellKnownParams; mount it at the root of the origin.
+func ParamsHandler(s *srp.SRP) (http.Handler, error) {
+	p, err := s.PublishedParams()
+	if err != nil {
+		return nil, err
+	}
+	body, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", fmt.Sprintf("max-age=%d", int(DefaultParamsTTL/time.Second)))
+		w.Write(body)
+	}), nil
+}
+
+// FetchParams fetches the parameters published by the HTTPS origin
+// 'origin' (e.g., "https://example.com") with 'hc'; nil uses
+// http.DefaultClient. Redirects to anything but HTTPS are refused.
+func FetchParams(ctx context.Context, hc *http.Client, origin string) (*srp.PublishedParams, error) {
+	u, err := paramsURL(origin)
+	if err != nil {
+		return nil, err
+	}
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	hc = httpsOnly(hc)
+
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := hc.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("srphttp: %s: %s", u, resp.Status)
+	}
+	if ct, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); ct != "application/json" {
+		return nil, fmt.Errorf("srphttp: %s: expected application/json, got %q", u, ct)
+	}
+
+	var b bytes.Buffer
+	if _, err := io.Copy(&b, io.LimitReader(resp.Body, maxBody+1)); err != nil {
+		return nil, err
+	}
+	if b.Len() > maxBody {
+		return nil, fmt.Errorf("srphttp: %s: response too large", u)
+	}
+
+	var p srp.PublishedParams
+	if err := json.Unmarshal(b.Bytes(), &p); err != nil {
+		return nil, fmt.Errorf("srphttp: %s: %w", u, err)
+	}
+	return &p, nil
+}
+
+// most redirects followed by a client without a CheckRedirect policy
+const maxRedirects = 10
+
+// return a copy of 'hc' that refuses redirects to anything but HTTPS and
+// otherwise applies the redirect policy of 'hc'
+func httpsOnly(hc *http.Client) *http.Client {
+	c := *hc
+	next := hc.CheckRedirect
+	c.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if req.URL.Scheme != "https" {
+			return fmt.Errorf("srphttp: redirect to %s isn't https", req.URL)
+		}
+		if next != nil {
+			return next(req, via)
+		}
+		if len(via) >= maxRedirects {
+			return fmt.Errorf("srphttp: stopped after %d redirects", maxRedirects)
+		}
+		return nil
+	}
+	return &c
+}
+
+// return the URL of the parameters of 'origin'
+func paramsURL(origin string) (string, error) {
+	u, err := url.Parse(origin)
+	if err != nil {
+		return "", fmt.Errorf("srphttp: origin %q: %w", origin, err)
+	}
+	if u.Scheme != "https" || u.Host == "" {
+		return "", fmt.Errorf("srphttp: origin %q isn't an https origin", origin)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + WellKnownParams
+	u.RawQuery = ""
+	u.Fragment = ""
+	return u.String(), nil
+}
+
+// ParamsConfig configures a ParamsCache; zero fields take the defaults
+type ParamsConfig struct {
+	// Client fetches the parameters; http.DefaultClient if nil
+	Client *http.Client
+
+	// TTL is the time fetched parameters are kept; DefaultParamsTTL if
+	// zero
+	TTL time.Duration
+
+	// Pins are the accepted srp.Fingerprint()s; the parameters of an
+	// origin must match one of them. Empty accepts any parameters, which
+	// is only as safe as the origin's TLS certificate.
+	Pins [][]byte
+
+	// Options are added to the published parameters when creating the
+	// environment (e.g., srp.WithTranscriptContext())
+	Options []srp.Option
+}
+
+// ParamsCache creates environments from the parameters published by
+// origins and keeps them for the configured TTL. It is safe for
+// concurrent use.
+type ParamsCache struct {
+	conf ParamsConfig
+
+	mu sync.Mutex
+	m  map[string]*cachedParams
+
+	now func() time.Time // for tests
+}
+
+type cachedParams struct {
+	s       *srp.SRP
+	expires time.Time
+}
+
+// NewParamsCache returns a ParamsCache with the configuration 'cfg'; 'cfg'
+// may be nil.
+func NewParamsCache(cfg *ParamsConfig) *ParamsCache {
+	var c ParamsConfig
+	if cfg != nil {
+		c = *cfg
+	}
+	if c.TTL <= 0 {
+		c.TTL = DefaultParamsTTL
+	}
+	return &ParamsCache{
+		conf: c,
+		m:    make(map[string]*cachedParams),
+		now:  time.Now,
+	}
+}
+
+// SRP returns the environment of the parameters published by 'origin',
+// fetching them if they aren't cached or have expired. Parameters that
+// don't match a pin fail with an error matching srp.ErrParamsMismatch and
+// aren't cached.
+func (pc *ParamsCache) SRP(ctx context.Context, origin string) (*srp.SRP, error) {
+	now := pc.now()
+
+	pc.mu.Lock()
+	if e, ok := pc.m[origin]; ok && now.Before(e.expires) {
+		pc.mu.Unlock()
+		return e.s, nil
+	}
+	pc.mu.Unlock()
+
+	p, err := FetchParams(ctx, pc.conf.Client, origin)
+	if err != nil {
+		return nil, err
+	}
+	s, err := p.New(pc.conf.Options...)
+	if err != nil {
+		return nil, fmt.Errorf("srphttp: %s: %w", origin, err)
+	}
+	if err := pc.checkPins(s); err != nil {
+		return nil, fmt.Errorf("srphttp: %s: %w", origin, err)
+	}
+
+	pc.mu.Lock()
+	pc.m[origin] = &cachedParams{s: s, expires: now.Add(pc.conf.TTL)}
+	pc.mu.Unlock()
+	return s, nil
+}
+
+// Forget drops the cached parameters of 'origin'; e.g., after a handshake
+// failed because the server changed them.
+func (pc *ParamsCache) Forget(origin string) {
+	pc.mu.Lock()
+	delete(pc.m, origin)
+	pc.mu.Unlock()
+}
+
+// return an error if 's' doesn't match one of the pins
+func (pc *ParamsCache) checkPins(s *srp.SRP) error {
+	if len(pc.conf.Pins) == 0 {
+		return nil
+	}
+	fp := s.Fingerprint()
+	for _, pin := range pc.conf.Pins {
+		if bytes.Equal(fp, pin) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w (have %s)", srp.ErrParamsMismatch, hex.EncodeToString(fp))
+}
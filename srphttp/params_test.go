@@ -0,0 +1,97 @@
+// self test for publishing and discovering parameters
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srphttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tomsons/go-srp"
+)
+
+func TestParamsCache(t *testing.T) {
+	s, err := srp.NewDefault()
+	if err != nil {
+		t.Fatalf("New: %s", err)
+	}
+	ph, err := ParamsHandler(s)
+	if err != nil {
+		t.Fatalf("ParamsHandler: %s", err)
+	}
+
+	var fetches int32
+	mux := http.NewServeMux()
+	mux.Handle(WellKnownParams, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		ph.ServeHTTP(w, r)
+	}))
+	srv := httptest.NewTLSServer(mux)
+	defer srv.Close()
+
+	ctx := context.Background()
+	pc := NewParamsCache(&ParamsConfig{Client: srv.Client(), TTL: time.Minute, Pins: [][]byte{s.Fingerprint()}})
+	now := time.Now()
+	pc.now = func() time.Time { return now }
+
+	c, err := pc.SRP(ctx, srv.URL)
+	if err != nil {
+		t.Fatalf("SRP: %s", err)
+	}
+	if c.GroupID() != s.GroupID() || string(c.Fingerprint()) != string(s.Fingerprint()) {
+		t.Fatalf("wrong environment")
+	}
+
+	// cached until the TTL passes
+	if _, err := pc.SRP(ctx, srv.URL); err != nil || atomic.LoadInt32(&fetches) != 1 {
+		t.Fatalf("not cached: %v, %d fetches", err, fetches)
+	}
+	now = now.Add(2 * time.Minute)
+	if _, err := pc.SRP(ctx, srv.URL); err != nil || atomic.LoadInt32(&fetches) != 2 {
+		t.Fatalf("not refetched: %v, %d fetches", err, fetches)
+	}
+	pc.Forget(srv.URL)
+	if _, err := pc.SRP(ctx, srv.URL); err != nil || atomic.LoadInt32(&fetches) != 3 {
+		t.Fatalf("not forgotten: %v, %d fetches", err, fetches)
+	}
+
+	// other pins
+	o, _ := srp.New(2048)
+	pc = NewParamsCache(&ParamsConfig{Client: srv.Client(), Pins: [][]byte{o.Fingerprint()}})
+	if _, err := pc.SRP(ctx, srv.URL); !errors.Is(err, srp.ErrParamsMismatch) {
+		t.Fatalf("unpinned parameters accepted: %v", err)
+	}
+
+	// only https
+	plain := httptest.NewServer(mux)
+	defer plain.Close()
+	if _, err := FetchParams(ctx, nil, plain.URL); err == nil {
+		t.Fatalf("fetched over plain http")
+	}
+	if _, err := FetchParams(ctx, srv.Client(), srv.URL+"/nothing"); err == nil {
+		t.Fatalf("fetched missing parameters")
+	}
+
+	// nor through a redirect to plain http
+	redir := httptest.NewTLSServer(http.RedirectHandler(plain.URL+WellKnownParams, http.StatusFound))
+	defer redir.Close()
+	if _, err := FetchParams(ctx, redir.Client(), redir.URL); err == nil || !strings.Contains(err.Error(), "isn't https") {
+		t.Fatalf("followed a redirect to plain http: %v", err)
+	}
+
+	// redirects to https are still followed
+	moved := httptest.NewTLSServer(http.RedirectHandler(srv.URL+WellKnownParams, http.StatusFound))
+	defer moved.Close()
+	if _, err := FetchParams(ctx, moved.Client(), moved.URL); err != nil {
+		t.Fatalf("redirect to https: %s", err)
+	}
+}
@@ -0,0 +1,84 @@
+// srpecho.go - SRP logins and sessions for Echo
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+// Package srpecho mounts a srphttp.Handler in an Echo router and carries
+// the session of each request in its echo.Context:
+//
+//	h := srphttp.NewHandler(lookup, nil)
+//	srpecho.Mount(e.Group("/auth"), h)
+//
+//	api := e.Group("/api", srpecho.Require(h))
+//	api.GET("/me", func(c echo.Context) error {
+//		s, _ := srpecho.Session(c)
+//		return c.JSON(http.StatusOK, s.Identity)
+//	})
+//
+// The endpoints, cookies and CSRF rules are those of package srphttp.
+package srpecho
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/labstack/echo/v4"
+	"github.com/tomsons/go-srp/srphttp"
+)
+
+// SessionKey is the key of the session in an echo.Context
+const SessionKey = "srp.session"
+
+// Router is implemented by *echo.Echo and *echo.Group
+type Router interface {
+	GET(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route
+	POST(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route
+}
+
+// Mount serves the endpoints of 'h' (/begin, /verify, /logout and
+// /session) in 'r'
+func Mount(r Router, h *srphttp.Handler) {
+	r.POST("/begin", echo.WrapHandler(endpoint(h, "/begin")))
+	r.POST("/verify", echo.WrapHandler(endpoint(h, "/verify")))
+	r.POST("/logout", echo.WrapHandler(endpoint(h, "/logout")))
+	r.GET("/session", echo.WrapHandler(endpoint(h, "/session")))
+}
+
+// Require is the middleware of h.Require(): it passes only requests of a
+// live session, with the session in the echo.Context (see Session()) and
+// in the context of the request (see srphttp.SessionFrom()).
+func Require(h *srphttp.Handler) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			var err error
+			h.Require(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				s, _ := srphttp.SessionFrom(r.Context())
+				c.SetRequest(r)
+				c.Set(SessionKey, s)
+				err = next(c)
+			})).ServeHTTP(c.Response(), c.Request())
+			return err
+		}
+	}
+}
+
+// Session returns the session of a request passed by Require()
+func Session(c echo.Context) (*srphttp.Session, bool) {
+	s, ok := c.Get(SessionKey).(*srphttp.Session)
+	return s, ok && s != nil
+}
+
+// return a handler that serves the endpoint 'path' of 'h' whatever the
+// path of the route is
+func endpoint(h http.Handler, path string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r2 := new(http.Request)
+		*r2 = *r
+		r2.URL = new(url.URL)
+		*r2.URL = *r.URL
+		r2.URL.Path = path
+		r2.URL.RawPath = ""
+		h.ServeHTTP(w, r2)
+	})
+}
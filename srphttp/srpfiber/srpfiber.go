@@ -0,0 +1,84 @@
+// srpfiber.go - SRP logins and sessions for Fiber
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+// Package srpfiber mounts a srphttp.Handler in a Fiber router and carries
+// the session of each request in its fiber.Ctx:
+//
+//	h := srphttp.NewHandler(lookup, nil)
+//	srpfiber.Mount(app.Group("/auth"), h)
+//
+//	api := app.Group("/api", srpfiber.Require(h))
+//	api.Get("/me", func(c *fiber.Ctx) error {
+//		s, _ := srpfiber.Session(c)
+//		return c.JSON(s.Identity)
+//	})
+//
+// Fiber isn't built on net/http: the login endpoints run through Fiber's
+// adaptor, and Require() checks sessions natively with h.Authorize(). The
+// endpoints, cookies and CSRF rules are those of package srphttp.
+package srpfiber
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/tomsons/go-srp/srphttp"
+)
+
+// SessionKey is the key of the session in the locals of a fiber.Ctx
+const SessionKey = "srp.session"
+
+// Mount serves the endpoints of 'h' (/begin, /verify, /logout and
+// /session) in 'r'
+func Mount(r fiber.Router, h *srphttp.Handler) {
+	r.Post("/begin", adaptor.HTTPHandler(endpoint(h, "/begin")))
+	r.Post("/verify", adaptor.HTTPHandler(endpoint(h, "/verify")))
+	r.Post("/logout", adaptor.HTTPHandler(endpoint(h, "/logout")))
+	r.Get("/session", adaptor.HTTPHandler(endpoint(h, "/session")))
+}
+
+// Require is the middleware of h.Require(): it passes only requests of a
+// live session, with the session in the locals of the fiber.Ctx (see
+// Session()). Other requests are answered as h.Require() answers them.
+func Require(h *srphttp.Handler) fiber.Handler {
+	refuse := adaptor.HTTPHandler(h.Require(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the session went away between the checks
+		http.Error(w, "not authenticated", http.StatusUnauthorized)
+	})))
+
+	return func(c *fiber.Ctx) error {
+		s, code := h.Authorize(c.Cookies(srphttp.SessionCookie), c.Method(), c.Get(srphttp.CSRFHeader))
+		if code != http.StatusOK {
+			// let srphttp reply, so that stale cookies are cleared
+			return refuse(c)
+		}
+
+		c.Locals(SessionKey, s)
+		return c.Next()
+	}
+}
+
+// Session returns the session of a request passed by Require()
+func Session(c *fiber.Ctx) (*srphttp.Session, bool) {
+	s, ok := c.Locals(SessionKey).(*srphttp.Session)
+	return s, ok && s != nil
+}
+
+// return a handler that serves the endpoint 'path' of 'h' whatever the
+// path of the route is
+func endpoint(h http.Handler, path string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r2 := new(http.Request)
+		*r2 = *r
+		r2.URL = new(url.URL)
+		*r2.URL = *r.URL
+		r2.URL.Path = path
+		r2.URL.RawPath = ""
+		h.ServeHTTP(w, r2)
+	})
+}
@@ -0,0 +1,132 @@
+// self test for Fiber logins and sessions
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srpfiber
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/tomsons/go-srp"
+	"github.com/tomsons/go-srp/srphttp"
+)
+
+func TestFiber(t *testing.T) {
+	s, err := srp.New(2048)
+	if err != nil {
+		t.Fatalf("New: %s", err)
+	}
+	v, err := s.Verifier([]byte("user"), []byte("pass"), nil)
+	if err != nil {
+		t.Fatalf("Verifier: %s", err)
+	}
+	lookup := func(ih []byte) (*srp.SRP, *srp.Verifier, error) {
+		if !v.MatchesIdentity(ih) {
+			return nil, nil, fmt.Errorf("unknown user")
+		}
+		return s, v, nil
+	}
+	h := srphttp.NewHandler(lookup, &srphttp.Config{Insecure: true, Decoys: s})
+
+	app := fiber.New()
+	Mount(app.Group("/auth"), h)
+	app.All("/api", Require(h), func(c *fiber.Ctx) error {
+		s, ok := Session(c)
+		if !ok || !v.MatchesIdentity(s.Identity) {
+			t.Errorf("no session in the fiber.Ctx")
+		}
+		return c.SendString("ok")
+	})
+
+	// Fiber has no server to give a client; carry the cookies by hand
+	u, _ := url.Parse("http://example.com")
+	jar, _ := cookiejar.New(nil)
+
+	do := func(method, path string, body interface{}, hdr map[string]string) (int, []byte) {
+		var rd bytes.Buffer
+		if body != nil {
+			json.NewEncoder(&rd).Encode(body)
+		}
+		req := httptest.NewRequest(method, u.String()+path, &rd)
+		req.Header.Set("Content-Type", "application/json")
+		for k, v := range hdr {
+			req.Header.Set(k, v)
+		}
+		for _, c := range jar.Cookies(u) {
+			req.AddCookie(c)
+		}
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("%s %s: %s", method, path, err)
+		}
+		jar.SetCookies(u, resp.Cookies())
+		defer resp.Body.Close()
+
+		var out bytes.Buffer
+		out.ReadFrom(resp.Body)
+		return resp.StatusCode, out.Bytes()
+	}
+
+	if code, _ := do("GET", "/api", nil, nil); code != http.StatusUnauthorized {
+		t.Fatalf("unauthenticated: exp 401, saw %d", code)
+	}
+
+	cs, _ := srp.New(2048)
+	c, err := cs.NewClient([]byte("user"), []byte("pass"))
+	if err != nil {
+		t.Fatalf("NewClient: %s", err)
+	}
+	code, b := do("POST", "/auth/begin", c.Hello(), nil)
+	if code != http.StatusOK {
+		t.Fatalf("begin: exp 200, saw %d", code)
+	}
+	var sc srp.ServerCredentials
+	if err := json.Unmarshal(b, &sc); err != nil {
+		t.Fatalf("challenge: %s", err)
+	}
+	m, err := c.Respond(sc)
+	if err != nil {
+		t.Fatalf("Respond: %s", err)
+	}
+	var proof struct{ M []byte }
+	proof.M = m
+	code, b = do("POST", "/auth/verify", proof, nil)
+	if code != http.StatusOK {
+		t.Fatalf("verify: exp 200, saw %d", code)
+	}
+	var sp struct{ M []byte }
+	if err := json.Unmarshal(b, &sp); err != nil || !c.CheckProof(sp.M) {
+		t.Fatalf("bad server proof")
+	}
+
+	if code, b := do("GET", "/api", nil, nil); code != http.StatusOK || string(b) != "ok" {
+		t.Fatalf("authenticated GET: %d %q", code, b)
+	}
+	if code, _ := do("GET", "/auth/session", nil, nil); code != http.StatusOK {
+		t.Fatalf("session: exp 200, saw %d", code)
+	}
+	if code, _ := do("POST", "/api", nil, nil); code != http.StatusForbidden {
+		t.Fatalf("POST without CSRF: exp 403, saw %d", code)
+	}
+	csrf := map[string]string{srphttp.CSRFHeader: srphttp.CSRFToken(c.RawKey())}
+	if code, _ := do("POST", "/api", nil, csrf); code != http.StatusOK {
+		t.Fatalf("POST with CSRF: exp 200, saw %d", code)
+	}
+
+	if code, _ := do("POST", "/auth/logout", nil, csrf); code != http.StatusNoContent {
+		t.Fatalf("logout: exp 204, saw %d", code)
+	}
+	if code, _ := do("GET", "/api", nil, nil); code != http.StatusUnauthorized {
+		t.Fatalf("after logout: exp 401, saw %d", code)
+	}
+}
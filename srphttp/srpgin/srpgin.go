@@ -0,0 +1,82 @@
+// srpgin.go - SRP logins and sessions for Gin
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+// Package srpgin mounts a srphttp.Handler in a Gin router and carries the
+// session of each request in its gin.Context:
+//
+//	h := srphttp.NewHandler(lookup, nil)
+//	srpgin.Mount(r.Group("/auth"), h)
+//
+//	api := r.Group("/api", srpgin.Require(h))
+//	api.GET("/me", func(c *gin.Context) {
+//		s, _ := srpgin.Session(c)
+//		c.JSON(http.StatusOK, s.Identity)
+//	})
+//
+// The endpoints, cookies and CSRF rules are those of package srphttp.
+package srpgin
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tomsons/go-srp/srphttp"
+)
+
+// SessionKey is the key of the session in a gin.Context
+const SessionKey = "srp.session"
+
+// Mount serves the endpoints of 'h' (/begin, /verify, /logout and
+// /session) in 'r'
+func Mount(r gin.IRoutes, h *srphttp.Handler) {
+	r.POST("/begin", gin.WrapH(endpoint(h, "/begin")))
+	r.POST("/verify", gin.WrapH(endpoint(h, "/verify")))
+	r.POST("/logout", gin.WrapH(endpoint(h, "/logout")))
+	r.GET("/session", gin.WrapH(endpoint(h, "/session")))
+}
+
+// Require is the middleware of h.Require(): it passes only requests of a
+// live session, with the session in the gin.Context (see Session()) and in
+// the context of the request (see srphttp.SessionFrom()). Other requests
+// are answered by h.Require() and aborted.
+func Require(h *srphttp.Handler) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		passed := false
+		h.Require(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			s, _ := srphttp.SessionFrom(r.Context())
+			c.Request = r
+			c.Set(SessionKey, s)
+			passed = true
+			c.Next()
+		})).ServeHTTP(c.Writer, c.Request)
+
+		if !passed {
+			c.Abort()
+		}
+	}
+}
+
+// Session returns the session of a request passed by Require()
+func Session(c *gin.Context) (*srphttp.Session, bool) {
+	v, _ := c.Get(SessionKey)
+	s, ok := v.(*srphttp.Session)
+	return s, ok && s != nil
+}
+
+// return a handler that serves the endpoint 'path' of 'h' whatever the
+// path of the route is
+func endpoint(h http.Handler, path string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r2 := new(http.Request)
+		*r2 = *r
+		r2.URL = new(url.URL)
+		*r2.URL = *r.URL
+		r2.URL.Path = path
+		r2.URL.RawPath = ""
+		h.ServeHTTP(w, r2)
+	})
+}
@@ -0,0 +1,599 @@
+// srphttp.go - SRP login and sessions for net/http servers
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+// Package srphttp runs SRP logins over HTTP and manages the sessions they
+// create. A Handler serves the login endpoints and Require() protects the
+// application's handlers:
+//
+//	POST /begin    ClientCredentials -> ServerCredentials (or 429)
+//	POST /verify   {"M": proof}      -> {"M": server proof}
+//	POST /logout
+//	GET  /session  -> {"I": hashed identity, "expires": time}
+//
+// Bodies are JSON; the credentials use the JSON form of package srp and
+// proofs are base64 encoded like the other byte strings.
+//
+// Session lifecycle: /begin stores the pending handshake and sets a
+// short-lived pre-auth cookie; /verify completes it, replaces the pre-auth
+// cookie with a session cookie and forgets the handshake. The session keeps
+// a key derived from the SRP session key K. /logout (or the expiry of the
+// session) destroys the session and its key, so that nothing derived from
+// K is accepted afterwards.
+//
+// Limits: the server math of /begin is expensive and unauthenticated, so
+// the handshakes of an identity (known or not) and of a client address
+// that may be in progress at once are bounded (Config.MaxPerIdentity and
+// Config.MaxPerIP). A handshake counts from /begin until /verify or its
+// expiry; /begin beyond a limit fails with 429 Too Many Requests before
+// any math is done.
+//
+// CSRF: cookies are HttpOnly and SameSite=Strict, the login endpoints only
+// accept application/json bodies (which browsers can't send cross-origin
+// without a CORS preflight) and every request that changes state in a
+// session must carry the header X-CSRF-Token with CSRFToken(K). The client
+// knows K, so the token never has to be sent to it or stored in the page.
+//
+// Unknown users: /begin answers an identity without a verifier with the
+// challenge of a decoy verifier (see srp.DecoyVerifier()) in
+// Config.Decoys, and /verify fails as it does for a wrong password, so the
+// endpoints don't tell which identities exist.
+//
+// The Handler is a plain http.Handler (and Require() plain middleware), so
+// it can be mounted in any router built on net/http. Handlers behind
+// Require() find the session with SessionFrom(). The modules srpecho,
+// srpgin and srpfiber below this package mount it in Echo, Gin and Fiber
+// (which isn't built on net/http) and carry the session in the context of
+// each framework; other frameworks can check sessions with Authorize().
+package srphttp
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/tomsons/go-srp"
+)
+
+// Default configuration
+const (
+	DefaultHandshakeTTL = 30 * time.Second
+	DefaultSessionTTL   = 12 * time.Hour
+	DefaultMaxPending   = 4096
+	DefaultMaxPerID     = 4
+	DefaultMaxPerIP     = 16
+
+	PreAuthCookie = "srp_handshake"
+	SessionCookie = "srp_session"
+	CSRFHeader    = "X-CSRF-Token"
+)
+
+// largest request body accepted by the login endpoints
+const maxBody = 16 * 1024
+
+var csrfLabel = []byte("srp csrf")
+
+// Config tunes a Handler; zero fields take the defaults
+type Config struct {
+	// HandshakeTTL bounds the time between /begin and /verify
+	HandshakeTTL time.Duration
+
+	// SessionTTL is the lifetime of a session
+	SessionTTL time.Duration
+
+	// MaxPending bounds the number of pending handshakes; /begin fails
+	// while it is reached.
+	MaxPending int
+
+	// MaxPerIdentity and MaxPerIP bound the handshakes in progress for
+	// a hashed identity and from a client address; /begin fails with 429
+	// while one is reached. Negative values disable the limit.
+	MaxPerIdentity int
+	MaxPerIP       int
+
+	// ClientIP returns the address of the client of a request for
+	// MaxPerIP; the host of r.RemoteAddr if nil. Servers behind a proxy
+	// must take it from a header the proxy sets.
+	ClientIP func(r *http.Request) string
+
+	// Decoys is the environment of the challenges sent to unknown
+	// identities; srp.NewDefault() if nil. It should have the group, hash
+	// and KDF of the users' verifiers, so that decoys look like them.
+	Decoys *srp.SRP
+
+	// DecoyKey derives the salts of the decoys (see srp.DecoyVerifier());
+	// random if nil. Servers with several instances should share it, and
+	// keep it across restarts, so that an unknown identity always gets
+	// the same salt.
+	DecoyKey []byte
+
+	// Path of the cookies; "/" if empty
+	Path string
+
+	// Insecure omits the Secure attribute of the cookies; only for
+	// testing over plain HTTP.
+	Insecure bool
+}
+
+// Session is an authenticated login
+type Session struct {
+	Identity []byte    // the hashed identity of the user
+	Expires  time.Time // when the session ends
+
+	id   string
+	csrf []byte
+}
+
+// Handler serves the login endpoints and tracks sessions. It is safe for
+// concurrent use.
+type Handler struct {
+	lookup srp.VerifierLookup
+	conf   Config
+	mux    *http.ServeMux
+
+	mu       sync.Mutex
+	pending  map[string]*pending
+	sessions map[string]*Session
+	pruned   time.Time
+
+	perID map[string]int // handshakes in progress by hashed identity
+	perIP map[string]int // and by client address
+}
+
+// a handshake between /begin and /verify
+type pending struct {
+	srv     *srp.Server
+	ih      []byte
+	ip      string
+	expires time.Time
+}
+
+// NewHandler returns a Handler that finds verifiers with 'lookup'; 'cfg'
+// may be nil.
+func NewHandler(lookup srp.VerifierLookup, cfg *Config) *Handler {
+	h := &Handler{
+		lookup:   lookup,
+		conf:     cfg.withDefaults(),
+		mux:      http.NewServeMux(),
+		pending:  make(map[string]*pending),
+		sessions: make(map[string]*Session),
+		perID:    make(map[string]int),
+		perIP:    make(map[string]int),
+	}
+
+	h.mux.HandleFunc("/begin", h.begin)
+	h.mux.HandleFunc("/verify", h.verify)
+	h.mux.Handle("/logout", h.Require(http.HandlerFunc(h.logout)))
+	h.mux.Handle("/session", h.Require(http.HandlerFunc(h.session)))
+	return h
+}
+
+// return a copy of 'cfg' with the defaults filled in
+func (cfg *Config) withDefaults() Config {
+	var c Config
+	if cfg != nil {
+		c = *cfg
+	}
+	if c.HandshakeTTL <= 0 {
+		c.HandshakeTTL = DefaultHandshakeTTL
+	}
+	if c.SessionTTL <= 0 {
+		c.SessionTTL = DefaultSessionTTL
+	}
+	if c.MaxPending <= 0 {
+		c.MaxPending = DefaultMaxPending
+	}
+	if c.MaxPerIdentity == 0 {
+		c.MaxPerIdentity = DefaultMaxPerID
+	}
+	if c.MaxPerIP == 0 {
+		c.MaxPerIP = DefaultMaxPerIP
+	}
+	if c.ClientIP == nil {
+		c.ClientIP = remoteHost
+	}
+	if c.Decoys == nil {
+		c.Decoys, _ = srp.NewDefault()
+	}
+	if c.DecoyKey == nil {
+		c.DecoyKey = make([]byte, 32)
+		if _, err := rand.Read(c.DecoyKey); err != nil {
+			panic(fmt.Sprintf("srphttp: random: %s", err))
+		}
+	}
+	if c.Path == "" {
+		c.Path = "/"
+	}
+	return c
+}
+
+// ServeHTTP implements http.Handler
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// CSRFToken returns the value of the X-CSRF-Token header for the session
+// with SRP session key 'K': hex(HMAC-SHA256(K, "srp csrf")).
+func CSRFToken(K []byte) string {
+	return hex.EncodeToString(csrfKey(K))
+}
+
+type sessionKey struct{}
+
+// SessionFrom returns the session of a request passed by Require()
+func SessionFrom(ctx context.Context) (*Session, bool) {
+	s, ok := ctx.Value(sessionKey{}).(*Session)
+	return s, ok
+}
+
+// Require passes only requests of a live session to 'next'; requests with
+// methods other than GET, HEAD and OPTIONS must carry the X-CSRF-Token of
+// the session.
+func (h *Handler) Require(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := r.Cookie(SessionCookie)
+		if err != nil {
+			http.Error(w, "not authenticated", http.StatusUnauthorized)
+			return
+		}
+
+		s, code := h.Authorize(c.Value, r.Method, r.Header.Get(CSRFHeader))
+		switch code {
+		case http.StatusOK:
+		case http.StatusForbidden:
+			http.Error(w, "invalid CSRF token", code)
+			return
+		default:
+			h.clearCookie(w, SessionCookie)
+			http.Error(w, "not authenticated", code)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), sessionKey{}, s)))
+	})
+}
+
+// Authorize returns the live session with the session cookie value 'id'
+// and http.StatusOK if a request with 'method' and the X-CSRF-Token header
+// 'token' may use it, as Require() does; otherwise it returns nil and the
+// status of the refusal: 401 without a live session and 403 with a wrong
+// token.
+func (h *Handler) Authorize(id, method, token string) (*Session, int) {
+	// the token is checked under h.mu, since revoke() wipes the key
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s, ok := h.sessions[id]
+	if ok && time.Now().After(s.Expires) {
+		h.revoke(s)
+		ok = false
+	}
+	if !ok {
+		return nil, http.StatusUnauthorized
+	}
+
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+	default:
+		t, err := hex.DecodeString(token)
+		if err != nil || !hmac.Equal(t, s.csrf) {
+			return nil, http.StatusForbidden
+		}
+	}
+	return s, http.StatusOK
+}
+
+// Len returns the number of pending handshakes and live sessions
+func (h *Handler) Len() (pending, sessions int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.pending), len(h.sessions)
+}
+
+// POST /begin
+func (h *Handler) begin(w http.ResponseWriter, r *http.Request) {
+	var cc srp.ClientCredentials
+	if !readJSON(w, r, &cc) {
+		return
+	}
+
+	// count the handshake before doing any work for it
+	p := &pending{ih: cc.IdentityHash, ip: h.conf.ClientIP(r)}
+	if !h.acquire(p) {
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, "too many logins in progress", http.StatusTooManyRequests)
+		return
+	}
+
+	ok := false
+	defer func() {
+		if !ok {
+			h.mu.Lock()
+			h.release(p)
+			h.mu.Unlock()
+		}
+	}()
+
+	// unknown identities get a decoy challenge and fail at /verify
+	s, v, err := h.lookup(cc.IdentityHash)
+	if err != nil {
+		s, v, err = h.decoy(cc.IdentityHash)
+	}
+	if err != nil {
+		http.Error(w, "authentication failed", http.StatusUnauthorized)
+		return
+	}
+	A, err := s.ParsePublicKey(cc.A)
+	if err != nil {
+		http.Error(w, "invalid credentials", http.StatusBadRequest)
+		return
+	}
+
+	srv, err := s.NewServerFor(cc.IdentityHash, v, A)
+	if err != nil {
+		http.Error(w, "authentication failed", http.StatusUnauthorized)
+		return
+	}
+
+	id := newID()
+	now := time.Now()
+
+	h.mu.Lock()
+	h.prune(now)
+	if len(h.pending) >= h.conf.MaxPending {
+		h.mu.Unlock()
+		http.Error(w, "too many logins", http.StatusServiceUnavailable)
+		return
+	}
+	p.srv = srv
+	p.expires = now.Add(h.conf.HandshakeTTL)
+	h.pending[id] = p
+	ok = true
+	h.mu.Unlock()
+
+	h.setCookie(w, PreAuthCookie, id, h.conf.HandshakeTTL)
+	writeJSON(w, srv.Challenge())
+}
+
+// return the environment and decoy verifier of the unknown identity 'ih'
+func (h *Handler) decoy(ih []byte) (*srp.SRP, *srp.Verifier, error) {
+	s := h.conf.Decoys
+	if s == nil {
+		return nil, nil, fmt.Errorf("srphttp: no environment for decoys")
+	}
+	v, err := s.DecoyVerifier(h.conf.DecoyKey, ih)
+	return s, v, err
+}
+
+// proof of /verify
+type proofMsg struct {
+	M []byte `json:"M"`
+}
+
+// POST /verify
+func (h *Handler) verify(w http.ResponseWriter, r *http.Request) {
+	var m proofMsg
+	if !readJSON(w, r, &m) {
+		return
+	}
+
+	c, err := r.Cookie(PreAuthCookie)
+	if err != nil {
+		http.Error(w, "no login in progress", http.StatusUnauthorized)
+		return
+	}
+
+	// a handshake gets one attempt
+	h.mu.Lock()
+	p, ok := h.pending[c.Value]
+	if ok {
+		delete(h.pending, c.Value)
+		h.release(p)
+	}
+	h.mu.Unlock()
+
+	h.clearCookie(w, PreAuthCookie)
+	if !ok || time.Now().After(p.expires) {
+		http.Error(w, "no login in progress", http.StatusUnauthorized)
+		return
+	}
+
+	proof, ok := p.srv.CheckProof(m.M)
+	if !ok {
+		http.Error(w, "authentication failed", http.StatusUnauthorized)
+		return
+	}
+
+	s := &Session{
+		Identity: p.ih,
+		Expires:  time.Now().Add(h.conf.SessionTTL),
+		id:       newID(),
+		csrf:     csrfKey(p.srv.RawKey()),
+	}
+
+	h.mu.Lock()
+	h.sessions[s.id] = s
+	h.mu.Unlock()
+
+	h.setCookie(w, SessionCookie, s.id, h.conf.SessionTTL)
+	writeJSON(w, proofMsg{M: proof})
+}
+
+// POST /logout
+func (h *Handler) logout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s, _ := SessionFrom(r.Context())
+	h.mu.Lock()
+	h.revoke(s)
+	h.mu.Unlock()
+
+	h.clearCookie(w, SessionCookie)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /session
+func (h *Handler) session(w http.ResponseWriter, r *http.Request) {
+	s, _ := SessionFrom(r.Context())
+	writeJSON(w, struct {
+		I       []byte    `json:"I"`
+		Expires time.Time `json:"expires"`
+	}{s.Identity, s.Expires})
+}
+
+// forget the session 's' and its key; the caller holds h.mu
+func (h *Handler) revoke(s *Session) {
+	delete(h.sessions, s.id)
+	for i := range s.csrf {
+		s.csrf[i] = 0
+	}
+}
+
+// forget expired handshakes and sessions at most once a second; the
+// caller holds h.mu
+func (h *Handler) prune(now time.Time) {
+	if now.Sub(h.pruned) < time.Second {
+		return
+	}
+	h.expire(now)
+	for _, s := range h.sessions {
+		if now.After(s.Expires) {
+			h.revoke(s)
+		}
+	}
+	h.pruned = now
+}
+
+// forget expired handshakes; the caller holds h.mu
+func (h *Handler) expire(now time.Time) {
+	for k, p := range h.pending {
+		if now.After(p.expires) {
+			delete(h.pending, k)
+			h.release(p)
+		}
+	}
+}
+
+// count the handshake 'p' against the limits of its identity and address;
+// return false if one is reached
+func (h *Handler) acquire(p *pending) bool {
+	id := string(p.ih)
+	full := func() bool {
+		return h.conf.MaxPerIdentity > 0 && h.perID[id] >= h.conf.MaxPerIdentity ||
+			h.conf.MaxPerIP > 0 && h.perIP[p.ip] >= h.conf.MaxPerIP
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if full() {
+		// expired handshakes may still be counted
+		h.expire(time.Now())
+		if full() {
+			return false
+		}
+	}
+	h.perID[id]++
+	h.perIP[p.ip]++
+	return true
+}
+
+// stop counting the handshake 'p'; the caller holds h.mu
+func (h *Handler) release(p *pending) {
+	id := string(p.ih)
+	if h.perID[id]--; h.perID[id] <= 0 {
+		delete(h.perID, id)
+	}
+	if h.perIP[p.ip]--; h.perIP[p.ip] <= 0 {
+		delete(h.perIP, p.ip)
+	}
+}
+
+// return the host of the remote address of 'r'
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func (h *Handler) setCookie(w http.ResponseWriter, name, value string, ttl time.Duration) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     h.conf.Path,
+		MaxAge:   int(ttl / time.Second),
+		Secure:   !h.conf.Insecure,
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+func (h *Handler) clearCookie(w http.ResponseWriter, name string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Path:     h.conf.Path,
+		MaxAge:   -1,
+		Secure:   !h.conf.Insecure,
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// decode the JSON body of a POST request into 'v'; on failure, reply with
+// an error and return false
+func readJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return false
+	}
+
+	// forms can be posted cross-origin without a preflight; JSON can't
+	if ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); ct != "application/json" {
+		http.Error(w, "expected application/json", http.StatusUnsupportedMediaType)
+		return false
+	}
+
+	d := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBody))
+	if err := d.Decode(v); err != nil {
+		http.Error(w, "malformed request", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(v)
+}
+
+// return HMAC-SHA256(K, "srp csrf")
+func csrfKey(K []byte) []byte {
+	m := hmac.New(sha256.New, K)
+	m.Write(csrfLabel)
+	return m.Sum(nil)
+}
+
+// return a new random id for a cookie
+func newID() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("srphttp: random: %s", err))
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,354 @@
+// self test for HTTP logins and sessions
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srphttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tomsons/go-srp"
+)
+
+type fixture struct {
+	t   *testing.T
+	h   *Handler
+	srv *httptest.Server
+	hc  *http.Client
+}
+
+func newFixture(t *testing.T, user, pass string) *fixture {
+	s, err := srp.New(2048)
+	if err != nil {
+		t.Fatalf("New: %s", err)
+	}
+	v, err := s.Verifier([]byte(user), []byte(pass), nil)
+	if err != nil {
+		t.Fatalf("Verifier: %s", err)
+	}
+
+	lookup := func(ih []byte) (*srp.SRP, *srp.Verifier, error) {
+		if !v.MatchesIdentity(ih) {
+			return nil, nil, fmt.Errorf("unknown user")
+		}
+		return s, v, nil
+	}
+
+	h := NewHandler(lookup, &Config{Insecure: true, Decoys: s})
+	mux := http.NewServeMux()
+	mux.Handle("/auth/", http.StripPrefix("/auth", h))
+	mux.Handle("/api", h.Require(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := SessionFrom(r.Context()); !ok {
+			t.Errorf("no session in context")
+		}
+		fmt.Fprint(w, "ok")
+	})))
+
+	srv := httptest.NewServer(mux)
+	jar, _ := cookiejar.New(nil)
+	return &fixture{t: t, h: h, srv: srv, hc: &http.Client{Jar: jar}}
+}
+
+// send 'body' as JSON and return the response status and body
+func (f *fixture) do(method, path string, body interface{}, hdr map[string]string) (int, []byte) {
+	var rd bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&rd).Encode(body)
+	}
+	req, err := http.NewRequest(method, f.srv.URL+path, &rd)
+	if err != nil {
+		f.t.Fatalf("request: %s", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range hdr {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := f.hc.Do(req)
+	if err != nil {
+		f.t.Fatalf("%s %s: %s", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	var out bytes.Buffer
+	out.ReadFrom(resp.Body)
+	return resp.StatusCode, out.Bytes()
+}
+
+// log in as 'user'; return the client if the server accepted it
+func (f *fixture) login(user, pass string) (*srp.Client, int) {
+	s, _ := srp.New(2048)
+	c, err := s.NewClient([]byte(user), []byte(pass))
+	if err != nil {
+		f.t.Fatalf("NewClient: %s", err)
+	}
+
+	code, b := f.do("POST", "/auth/begin", c.Hello(), nil)
+	if code != http.StatusOK {
+		return nil, code
+	}
+
+	var sc srp.ServerCredentials
+	if err := json.Unmarshal(b, &sc); err != nil {
+		f.t.Fatalf("challenge: %s", err)
+	}
+	m, err := c.Respond(sc)
+	if err != nil {
+		f.t.Fatalf("Respond: %s", err)
+	}
+
+	code, b = f.do("POST", "/auth/verify", proofMsg{M: m}, nil)
+	if code != http.StatusOK {
+		return nil, code
+	}
+
+	var p proofMsg
+	if err := json.Unmarshal(b, &p); err != nil {
+		f.t.Fatalf("proof: %s", err)
+	}
+	if !c.CheckProof(p.M) {
+		f.t.Fatalf("bad server proof")
+	}
+	return c, code
+}
+
+func TestSessionLifecycle(t *testing.T) {
+	f := newFixture(t, "user", "pass")
+	defer f.srv.Close()
+
+	if code, _ := f.do("GET", "/api", nil, nil); code != http.StatusUnauthorized {
+		t.Fatalf("unauthenticated: exp 401, saw %d", code)
+	}
+
+	c, code := f.login("user", "pass")
+	if c == nil {
+		t.Fatalf("login failed: %d", code)
+	}
+	if p, s := f.h.Len(); p != 0 || s != 1 {
+		t.Fatalf("exp 0 pending and 1 session, saw %d, %d", p, s)
+	}
+
+	if code, b := f.do("GET", "/api", nil, nil); code != http.StatusOK || string(b) != "ok" {
+		t.Fatalf("authenticated GET: %d %q", code, b)
+	}
+
+	// state changes need the CSRF token
+	if code, _ := f.do("POST", "/api", nil, nil); code != http.StatusForbidden {
+		t.Fatalf("POST without CSRF: exp 403, saw %d", code)
+	}
+	csrf := map[string]string{CSRFHeader: CSRFToken(c.RawKey())}
+	if code, _ := f.do("POST", "/api", nil, csrf); code != http.StatusOK {
+		t.Fatalf("POST with CSRF: exp 200, saw %d", code)
+	}
+
+	if code, _ := f.do("GET", "/auth/session", nil, nil); code != http.StatusOK {
+		t.Fatalf("session: exp 200, saw %d", code)
+	}
+
+	if code, _ := f.do("POST", "/auth/logout", nil, nil); code != http.StatusForbidden {
+		t.Fatalf("logout without CSRF: exp 403, saw %d", code)
+	}
+	if code, _ := f.do("POST", "/auth/logout", nil, csrf); code != http.StatusNoContent {
+		t.Fatalf("logout: exp 204, saw %d", code)
+	}
+	if _, s := f.h.Len(); s != 0 {
+		t.Fatalf("session survived logout")
+	}
+	if code, _ := f.do("GET", "/api", nil, nil); code != http.StatusUnauthorized {
+		t.Fatalf("after logout: exp 401, saw %d", code)
+	}
+}
+
+func TestLogoutRace(t *testing.T) {
+	f := newFixture(t, "user", "pass")
+	defer f.srv.Close()
+
+	// protected POSTs with the right and an all-zero token race the
+	// logout; the race detector flags unlocked reads of the key
+	for round := 0; round < 20; round++ {
+		c, code := f.login("user", "pass")
+		if c == nil {
+			t.Fatalf("login failed: %d", code)
+		}
+		u, _ := url.Parse(f.srv.URL)
+		var id string
+		for _, ck := range f.hc.Jar.Cookies(u) {
+			if ck.Name == SessionCookie {
+				id = ck.Value
+			}
+		}
+
+		post := func(path, token string) int {
+			r := httptest.NewRequest("POST", path, nil)
+			r.AddCookie(&http.Cookie{Name: SessionCookie, Value: id})
+			r.Header.Set(CSRFHeader, token)
+			w := httptest.NewRecorder()
+			f.srv.Config.Handler.ServeHTTP(w, r)
+			return w.Code
+		}
+		token := CSRFToken(c.RawKey())
+		zero := strings.Repeat("00", len(c.RawKey()))
+
+		var wg sync.WaitGroup
+		var forged, ok int32
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for post("/api", token) == http.StatusOK {
+					atomic.AddInt32(&ok, 1)
+					if post("/api", zero) == http.StatusOK {
+						atomic.AddInt32(&forged, 1)
+					}
+				}
+			}()
+		}
+		for atomic.LoadInt32(&ok) < 16 {
+			time.Sleep(time.Millisecond)
+		}
+		if code := post("/auth/logout", token); code != http.StatusNoContent {
+			t.Fatalf("logout: exp 204, saw %d", code)
+		}
+		wg.Wait()
+
+		if forged != 0 {
+			t.Fatalf("all-zero CSRF token accepted %d times", forged)
+		}
+		if code := post("/api", token); code != http.StatusUnauthorized {
+			t.Fatalf("after logout: exp 401, saw %d", code)
+		}
+	}
+}
+
+func TestLoginFailures(t *testing.T) {
+	f := newFixture(t, "user", "pass")
+	defer f.srv.Close()
+
+	if _, code := f.login("user", "wrong"); code != http.StatusUnauthorized {
+		t.Fatalf("wrong password: exp 401, saw %d", code)
+	}
+	if _, code := f.login("nobody", "pass"); code != http.StatusUnauthorized {
+		t.Fatalf("unknown user: exp 401, saw %d", code)
+	}
+	if p, s := f.h.Len(); p != 0 || s != 0 {
+		t.Fatalf("exp no state, saw %d pending and %d sessions", p, s)
+	}
+
+	// an unknown identity gets a challenge, the same one each time
+	salt := func(user string) []byte {
+		s, _ := srp.New(2048)
+		c, err := s.NewClient([]byte(user), []byte("pass"))
+		if err != nil {
+			t.Fatalf("NewClient: %s", err)
+		}
+		code, b := f.do("POST", "/auth/begin", c.Hello(), nil)
+		if code != http.StatusOK {
+			t.Fatalf("%s: exp 200, saw %d", user, code)
+		}
+		var sc srp.ServerCredentials
+		if err := json.Unmarshal(b, &sc); err != nil {
+			t.Fatalf("challenge: %s", err)
+		}
+		return sc.Salt
+	}
+	if !bytes.Equal(salt("nobody"), salt("nobody")) {
+		t.Fatalf("unknown user: salt changed")
+	}
+	if bytes.Equal(salt("nobody"), salt("someone")) {
+		t.Fatalf("unknown users share a salt")
+	}
+
+	// the proof can't be sent without a pending handshake
+	if code, _ := f.do("POST", "/auth/verify", proofMsg{M: []byte("x")}, nil); code != http.StatusUnauthorized {
+		t.Fatalf("verify without begin: exp 401, saw %d", code)
+	}
+
+	// forms are refused
+	req, _ := http.NewRequest("POST", f.srv.URL+"/auth/begin", bytes.NewBufferString("I=x"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := f.hc.Do(req)
+	if err != nil {
+		t.Fatalf("form: %s", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnsupportedMediaType {
+		t.Fatalf("form: exp 415, saw %d", resp.StatusCode)
+	}
+}
+
+func TestHandshakeLimits(t *testing.T) {
+	f := newFixture(t, "user", "pass")
+	defer f.srv.Close()
+
+	f.h.conf.MaxPerIdentity = 2
+	f.h.conf.MaxPerIP = 3
+	f.h.conf.ClientIP = func(r *http.Request) string { return r.Header.Get("X-Client") }
+
+	s, _ := srp.New(2048)
+	begin := func(user, ip string) int {
+		c, err := s.NewClient([]byte(user), []byte("pass"))
+		if err != nil {
+			t.Fatalf("NewClient: %s", err)
+		}
+		code, _ := f.do("POST", "/auth/begin", c.Hello(), map[string]string{"X-Client": ip})
+		return code
+	}
+
+	for i, exp := range []int{200, 200, 429} {
+		if code := begin("user", "a"); code != exp {
+			t.Fatalf("begin %d: exp %d, saw %d", i, exp, code)
+		}
+	}
+
+	// unknown identities count like known ones
+	if code := begin("nobody", "a"); code != http.StatusOK {
+		t.Fatalf("unknown user: exp 200, saw %d", code)
+	}
+	if code := begin("nobody", "a"); code != http.StatusTooManyRequests {
+		t.Fatalf("address limit: exp 429, saw %d", code)
+	}
+
+	// other addresses have their own limit, identities have one across
+	// all addresses
+	if code := begin("nobody", "b"); code != http.StatusOK {
+		t.Fatalf("other address: exp 200, saw %d", code)
+	}
+	if code := begin("user", "b"); code != http.StatusTooManyRequests {
+		t.Fatalf("identity limit from another address: exp 429, saw %d", code)
+	}
+
+	// expired handshakes stop counting
+	f.h.mu.Lock()
+	for _, p := range f.h.pending {
+		p.expires = time.Now().Add(-time.Second)
+	}
+	f.h.mu.Unlock()
+	if code := begin("user", "a"); code != http.StatusOK {
+		t.Fatalf("after expiry: exp 200, saw %d", code)
+	}
+
+	// a completed login releases its handshake
+	f.h.conf.ClientIP = func(r *http.Request) string { return "c" }
+	if _, code := f.login("user", "pass"); code != http.StatusOK {
+		t.Fatalf("login: %d", code)
+	}
+	f.h.mu.Lock()
+	n := f.h.perIP["c"]
+	f.h.mu.Unlock()
+	if n != 0 {
+		t.Fatalf("login still counted: %d", n)
+	}
+}
@@ -0,0 +1,58 @@
+// webhook.go - delivering security events to a webhook
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srphttp
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// SignatureHeader carries the hex HMAC-SHA256 of a webhook body under the
+// webhook's secret
+const SignatureHeader = "X-SRP-Signature"
+
+// Webhook returns a delivery function for srp.NotifierConfig that POSTs
+// the JSON payload of each security event to 'url' with 'hc' (nil uses
+// http.DefaultClient). If 'secret' isn't nil, the body is signed in
+// SignatureHeader so that the receiver can tell forged events apart. Any
+// response but 2xx is an error, so the notifier retries it.
+func Webhook(url string, secret []byte, hc *http.Client) func(payload []byte) error {
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+
+	return func(payload []byte) error {
+		req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if secret != nil {
+			m := hmac.New(sha256.New, secret)
+			m.Write(payload)
+			req.Header.Set(SignatureHeader, hex.EncodeToString(m.Sum(nil)))
+		}
+
+		resp, err := hc.Do(req)
+		if err != nil {
+			return err
+		}
+		io.Copy(ioutil.Discard, io.LimitReader(resp.Body, maxBody))
+		resp.Body.Close()
+
+		if resp.StatusCode/100 != 2 {
+			return fmt.Errorf("srphttp: webhook: %s", resp.Status)
+		}
+		return nil
+	}
+}
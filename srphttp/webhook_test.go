@@ -0,0 +1,61 @@
+// self test for security event webhooks
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srphttp
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWebhook(t *testing.T) {
+	secret := []byte("secret")
+
+	var got []byte
+	fail := true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		m := hmac.New(sha256.New, secret)
+		m.Write(b)
+		if r.Header.Get(SignatureHeader) != hex.EncodeToString(m.Sum(nil)) {
+			http.Error(w, "bad signature", http.StatusForbidden)
+			return
+		}
+		if r.Header.Get("Content-Type") != "application/json" {
+			http.Error(w, "bad type", http.StatusUnsupportedMediaType)
+			return
+		}
+		got = b
+	}))
+	defer srv.Close()
+
+	deliver := Webhook(srv.URL, secret, nil)
+	payload := []byte(`{"kind":"downgrade"}`)
+	if err := deliver(payload); err == nil {
+		t.Fatalf("failed delivery succeeded")
+	}
+
+	fail = false
+	if err := deliver(payload); err != nil {
+		t.Fatalf("deliver: %s", err)
+	}
+	if string(got) != string(payload) {
+		t.Fatalf("received %q", got)
+	}
+
+	if err := Webhook(srv.URL, []byte("other"), nil)(payload); err == nil {
+		t.Fatalf("wrong signature accepted")
+	}
+}
@@ -0,0 +1,211 @@
+// srpmobile.go - SRP client API for gomobile bindings
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+// Package srpmobile is a flattened API of the SRP client for iOS and
+// Android apps. It is meant to be built with gomobile:
+//
+//	gomobile bind -target=ios github.com/tomsons/go-srp/srpmobile
+//	gomobile bind -target=android github.com/tomsons/go-srp/srpmobile
+//
+// Exported signatures only use types that gomobile can bind: numbers,
+// strings, byte slices and pointers to the types of this package. Instead
+// of returning errors, each method records a status code and message that
+// the caller reads with Status() and StatusMessage().
+//
+// The binary methods exchange the CBOR encoded messages of the srp package
+// (see srp.ClientCredentials.EncodeCBOR()); the string methods exchange
+// the same text messages as srp.Client.Credentials() and friends.
+package srpmobile
+
+import (
+	"github.com/tomsons/go-srp"
+)
+
+// Status codes
+const (
+	OK              = 0 // the last call succeeded
+	InvalidArgument = 1 // the caller passed an invalid parameter
+	BadMessage      = 2 // the server sent an invalid or unacceptable message
+	AuthFailed      = 3 // the server failed to prove it knows the verifier
+	BadState        = 4 // the call was made out of order
+)
+
+// Client is an SRP client for one login
+type Client struct {
+	c      *srp.Client
+	authed bool
+
+	status int
+	msg    string
+}
+
+// NewClient returns a client for 'user' and 'password' using the
+// built-in group of size 'bits' (e.g., 2048 or 3072). Check Status() before
+// using the client.
+func NewClient(bits int, user, password []byte) *Client {
+	c := &Client{}
+
+	s, err := srp.New(bits)
+	if err != nil {
+		c.fail(InvalidArgument, err.Error())
+		return c
+	}
+
+	sc, err := s.NewClient(user, password)
+	if err != nil {
+		c.fail(InvalidArgument, err.Error())
+		return c
+	}
+
+	c.c = sc
+	c.ok()
+	return c
+}
+
+// Status returns the status code of the last call
+func (c *Client) Status() int {
+	return c.status
+}
+
+// StatusMessage returns a description of the status of the last call
+func (c *Client) StatusMessage() string {
+	return c.msg
+}
+
+// Hello returns the CBOR encoded client credentials <I, A> to send to the
+// server or nil on error.
+func (c *Client) Hello() []byte {
+	if !c.valid() {
+		return nil
+	}
+
+	cc := c.c.Hello()
+	c.ok()
+	return cc.EncodeCBOR()
+}
+
+// Respond processes the CBOR encoded server 'challenge' <s, B> and returns
+// the CBOR encoded proof M to send to the server or nil on error.
+func (c *Client) Respond(challenge []byte) []byte {
+	if !c.valid() {
+		return nil
+	}
+
+	sc, err := srp.DecodeServerCredentialsCBOR(challenge)
+	if err != nil {
+		c.fail(BadMessage, err.Error())
+		return nil
+	}
+
+	m, err := c.c.Respond(sc)
+	if err != nil {
+		c.fail(BadMessage, err.Error())
+		return nil
+	}
+
+	c.authed = false
+	c.ok()
+	return srp.EncodeProofCBOR(m)
+}
+
+// CheckProof verifies the CBOR encoded server 'proof' M' and returns true
+// if the server is authenticated.
+func (c *Client) CheckProof(proof []byte) bool {
+	if !c.valid() {
+		return false
+	}
+
+	p, err := srp.DecodeProofCBOR(proof)
+	if err != nil {
+		c.fail(BadMessage, err.Error())
+		return false
+	}
+	return c.check(c.c.CheckProof(p))
+}
+
+// Credentials returns the client credentials <I, A> to send to the server
+// or "" on error; it is the text counterpart of Hello().
+func (c *Client) Credentials() string {
+	if !c.valid() {
+		return ""
+	}
+
+	c.ok()
+	return c.c.Credentials()
+}
+
+// Generate processes the server credentials 'creds' and returns the proof
+// M to send to the server or "" on error; it is the text counterpart of
+// Respond().
+func (c *Client) Generate(creds string) string {
+	if !c.valid() {
+		return ""
+	}
+
+	m, err := c.c.Generate(creds)
+	if err != nil {
+		c.fail(BadMessage, err.Error())
+		return ""
+	}
+
+	c.authed = false
+	c.ok()
+	return m
+}
+
+// ServerOk verifies the server 'proof' M' and returns true if the server is
+// authenticated; it is the text counterpart of CheckProof().
+func (c *Client) ServerOk(proof string) bool {
+	if !c.valid() {
+		return false
+	}
+	return c.check(c.c.ServerOk(proof))
+}
+
+// Key returns the session key K once the server is authenticated or nil
+func (c *Client) Key() []byte {
+	if !c.valid() {
+		return nil
+	}
+	if !c.authed {
+		c.fail(BadState, "srpmobile: server isn't authenticated")
+		return nil
+	}
+
+	c.ok()
+	return append([]byte{}, c.c.RawKey()...)
+}
+
+// record the result of verifying the server's proof
+func (c *Client) check(ok bool) bool {
+	c.authed = ok
+	if !ok {
+		c.fail(AuthFailed, "srpmobile: server authentication failed")
+		return false
+	}
+
+	c.ok()
+	return true
+}
+
+// return true if the client was created successfully
+func (c *Client) valid() bool {
+	if c.c == nil {
+		c.fail(BadState, "srpmobile: client isn't initialized")
+		return false
+	}
+	return true
+}
+
+func (c *Client) ok() {
+	c.status = OK
+	c.msg = ""
+}
+
+func (c *Client) fail(status int, msg string) {
+	c.status = status
+	c.msg = msg
+}
@@ -0,0 +1,148 @@
+// self test for the gomobile API
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srpmobile
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/tomsons/go-srp"
+)
+
+func TestBinary(t *testing.T) {
+	user := []byte("user")
+	pass := []byte("pass")
+
+	s, err := srp.New(2048)
+	if err != nil {
+		t.Fatalf("New: %s", err)
+	}
+
+	v, err := s.Verifier(user, pass, nil)
+	if err != nil {
+		t.Fatalf("Verifier: %s", err)
+	}
+
+	for _, pw := range []string{"pass", "wrong"} {
+		c := NewClient(2048, user, []byte(pw))
+		if c.Status() != OK {
+			t.Fatalf("NewClient: %s", c.StatusMessage())
+		}
+
+		if c.Key() != nil || c.Status() != BadState {
+			t.Fatalf("%s: key before authentication", pw)
+		}
+
+		cc, err := srp.DecodeClientCredentialsCBOR(c.Hello())
+		if err != nil {
+			t.Fatalf("Hello: %s", err)
+		}
+
+		A, err := s.ParsePublicKey(cc.A)
+		if err != nil {
+			t.Fatalf("ParsePublicKey: %s", err)
+		}
+
+		srv, err := s.NewServerFor(cc.IdentityHash, v, A)
+		if err != nil {
+			t.Fatalf("NewServerFor: %s", err)
+		}
+
+		sc := srv.Challenge()
+		m := c.Respond(sc.EncodeCBOR())
+		if m == nil {
+			t.Fatalf("%s: Respond: %s", pw, c.StatusMessage())
+		}
+
+		M, err := srp.DecodeProofCBOR(m)
+		if err != nil {
+			t.Fatalf("DecodeProofCBOR: %s", err)
+		}
+
+		proof, ok := srv.CheckProof(M)
+		if pw == "wrong" {
+			if ok {
+				t.Fatalf("server accepted wrong password")
+			}
+
+			if c.CheckProof(srp.EncodeProofCBOR(M)) || c.Status() != AuthFailed {
+				t.Fatalf("client accepted bogus proof; status %d", c.Status())
+			}
+			continue
+		}
+		if !ok {
+			t.Fatalf("server rejected client")
+		}
+
+		if !c.CheckProof(srp.EncodeProofCBOR(proof)) {
+			t.Fatalf("CheckProof: %s", c.StatusMessage())
+		}
+		if k := c.Key(); !bytes.Equal(k, srv.RawKey()) {
+			t.Fatalf("key mismatch")
+		}
+	}
+}
+
+func TestText(t *testing.T) {
+	user := []byte("user")
+	pass := []byte("pass")
+
+	s, err := srp.New(2048)
+	if err != nil {
+		t.Fatalf("New: %s", err)
+	}
+
+	v, err := s.Verifier(user, pass, nil)
+	if err != nil {
+		t.Fatalf("Verifier: %s", err)
+	}
+
+	c := NewClient(2048, user, pass)
+	_, A, err := srp.ServerBegin(c.Credentials())
+	if err != nil {
+		t.Fatalf("ServerBegin: %s", err)
+	}
+
+	srv, err := s.NewServer(v, A)
+	if err != nil {
+		t.Fatalf("NewServer: %s", err)
+	}
+
+	if c.Generate("bogus") != "" || c.Status() != BadMessage {
+		t.Fatalf("accepted bogus server credentials")
+	}
+
+	m := c.Generate(srv.Credentials())
+	if m == "" {
+		t.Fatalf("Generate: %s", c.StatusMessage())
+	}
+
+	proof, ok := srv.ClientOk(m)
+	if !ok {
+		t.Fatalf("server rejected client")
+	}
+	if !c.ServerOk(proof) || c.Status() != OK {
+		t.Fatalf("ServerOk: %s", c.StatusMessage())
+	}
+	if c.Key() == nil {
+		t.Fatalf("no key")
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	c := NewClient(1000, []byte("user"), []byte("pass"))
+	if c.Status() != InvalidArgument || c.StatusMessage() == "" {
+		t.Fatalf("accepted invalid group size")
+	}
+
+	if c.Hello() != nil || c.Status() != BadState {
+		t.Fatalf("uninitialized client sent hello")
+	}
+	if c.Credentials() != "" || c.ServerOk("00") || c.Key() != nil {
+		t.Fatalf("uninitialized client is usable")
+	}
+}
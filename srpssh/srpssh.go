@@ -0,0 +1,278 @@
+// srpssh.go - SRP in SSH keyboard-interactive authentication
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+// Package srpssh runs SRP inside the keyboard-interactive authentication
+// of golang.org/x/crypto/ssh, so that users log in to an SSH server with
+// a password that neither travels to the server nor is stored on it. The
+// server asks its questions with KeyboardInteractiveCallback() and the
+// client answers them with AuthMethod():
+//
+//	S: instruction "SRP", prompt "srp-hello:"
+//	C: Hello     <I, A>
+//	S: prompt "srp-challenge:" Challenge <s, B, kdf>
+//	C: Proof     M
+//	S: prompt "srp-proof:" Proof M'
+//	C: (empty)   the client checked M'
+//	S: success
+//
+// Each message is the base64 (standard encoding) of the CBOR encoding of
+// the corresponding message of the srp package; the server's messages
+// follow the prompt text. The SRP identity is the SSH user name. Prompts
+// are never echoed; clients refuse any prompt but the above, so that a
+// server can't trick the AuthMethod into revealing anything else.
+//
+// Unknown users get the challenge of a decoy verifier (see
+// srp.DecoyVerifier()) and fail at their proof, as they do with a wrong
+// password, so the server doesn't tell which users exist.
+//
+// SSH lets the server end the authentication with a success at any time,
+// so the client can't refuse a login whose server skipped its proof M'.
+// Callers that rely on the server knowing the verifier use a Client and
+// check ServerAuthenticated() once the connection is up.
+package srpssh
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/tomsons/go-srp"
+)
+
+// Instruction is the instruction of the keyboard-interactive requests
+const Instruction = "SRP"
+
+// IdentityExtension is the key of the hex hashed identity in the
+// ssh.Permissions of an authenticated user
+const IdentityExtension = "srp-identity"
+
+// prompts of the three legs
+const (
+	helloPrompt     = "srp-hello:"
+	challengePrompt = "srp-challenge:"
+	proofPrompt     = "srp-proof:"
+)
+
+var b64 = base64.StdEncoding
+
+// VerifierLookup returns the SRP environment and verifier of the SSH user
+// 'user'
+type VerifierLookup func(user string) (*srp.SRP, *srp.Verifier, error)
+
+// Config configures KeyboardInteractiveCallback(); zero fields take the
+// defaults
+type Config struct {
+	// Decoys is the environment of the challenges sent to unknown users;
+	// srp.NewDefault() if nil. It should have the group, hash and KDF of
+	// the users' verifiers, so that decoys look like them.
+	Decoys *srp.SRP
+
+	// DecoyKey derives the salts of the decoys (see srp.DecoyVerifier());
+	// random if nil. Servers with several instances should share it, and
+	// keep it across restarts, so that an unknown user always gets the
+	// same salt.
+	DecoyKey []byte
+}
+
+// return a copy of 'cfg' with the defaults filled in; 'cfg' may be nil
+func (cfg *Config) withDefaults() Config {
+	var c Config
+	if cfg != nil {
+		c = *cfg
+	}
+	if c.Decoys == nil {
+		c.Decoys, _ = srp.NewDefault()
+	}
+	if c.DecoyKey == nil {
+		c.DecoyKey = make([]byte, 32)
+		if _, err := rand.Read(c.DecoyKey); err != nil {
+			panic(fmt.Sprintf("srpssh: random: %s", err))
+		}
+	}
+	return c
+}
+
+// KeyboardInteractiveCallback returns a callback for
+// ssh.ServerConfig.KeyboardInteractiveCallback that authenticates users
+// with SRP; 'lookup' finds their verifiers and 'cfg', which may be nil,
+// configures the decoys of unknown users. The permissions of an
+// authenticated user carry the hashed identity under IdentityExtension.
+func KeyboardInteractiveCallback(lookup VerifierLookup, cfg *Config) func(ssh.ConnMetadata, ssh.KeyboardInteractiveChallenge) (*ssh.Permissions, error) {
+	c := cfg.withDefaults()
+	return func(conn ssh.ConnMetadata, client ssh.KeyboardInteractiveChallenge) (*ssh.Permissions, error) {
+		user := conn.User()
+		msg, err := ask(client, user, helloPrompt)
+		if err != nil {
+			return nil, err
+		}
+		cc, err := srp.DecodeClientCredentialsCBOR(msg)
+		if err != nil {
+			return nil, err
+		}
+
+		// unknown users (also those the lookup returns no environment or
+		// verifier for), and identities that aren't the SSH user's, get a
+		// decoy challenge and fail at the proof
+		env, v, err := lookup(user)
+		if err != nil || env == nil || v == nil || !v.MatchesIdentity(cc.IdentityHash) {
+			if env = c.Decoys; env == nil {
+				return nil, fmt.Errorf("srpssh: no environment for decoys")
+			}
+			v, err = env.DecoyVerifier(c.DecoyKey, cc.IdentityHash)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		A, err := env.ParsePublicKey(cc.A)
+		if err != nil {
+			return nil, err
+		}
+		srv, err := env.NewServerFor(cc.IdentityHash, v, A)
+		if err != nil {
+			return nil, err
+		}
+
+		sc := srv.Challenge()
+		msg, err = ask(client, user, challengePrompt+b64.EncodeToString(sc.EncodeCBOR()))
+		if err != nil {
+			return nil, err
+		}
+		m, err := srp.DecodeProofCBOR(msg)
+		if err != nil {
+			return nil, err
+		}
+
+		proof, ok := srv.CheckProof(m)
+		if !ok {
+			return nil, fmt.Errorf("srpssh: client authentication failed")
+		}
+
+		// the client must check our proof before it is logged in
+		if _, err := ask(client, user, proofPrompt+b64.EncodeToString(srp.EncodeProofCBOR(proof))); err != nil {
+			return nil, err
+		}
+
+		return &ssh.Permissions{
+			Extensions: map[string]string{IdentityExtension: hex.EncodeToString(cc.IdentityHash)},
+		}, nil
+	}
+}
+
+// send the single prompt 'q' to the client and return its decoded answer
+func ask(client ssh.KeyboardInteractiveChallenge, user, q string) ([]byte, error) {
+	ans, err := client(user, Instruction, []string{q}, []bool{false})
+	if err != nil {
+		return nil, err
+	}
+	if len(ans) != 1 {
+		return nil, fmt.Errorf("srpssh: expected one answer, got %d", len(ans))
+	}
+	b, err := b64.DecodeString(ans[0])
+	if err != nil {
+		return nil, fmt.Errorf("srpssh: malformed answer: %w", err)
+	}
+	return b, nil
+}
+
+// AuthMethod returns an ssh.AuthMethod that answers the questions of
+// KeyboardInteractiveCallback() with the SRP client 'c', whose identity
+// must be the SSH user name. It checks the server's proof if the server
+// sends one, but can't tell the caller whether it did; see Client.
+func AuthMethod(c *srp.Client) ssh.AuthMethod {
+	return NewClient(c).AuthMethod()
+}
+
+// Client answers the questions of KeyboardInteractiveCallback() with an
+// SRP client and records whether the server proved that it knows the
+// verifier
+type Client struct {
+	c       *srp.Client
+	started bool
+	authed  bool
+}
+
+// NewClient returns a Client that answers with the SRP client 'c', whose
+// identity must be the SSH user name
+func NewClient(c *srp.Client) *Client {
+	return &Client{c: c}
+}
+
+// AuthMethod returns the ssh.AuthMethod of the client
+func (ci *Client) AuthMethod() ssh.AuthMethod {
+	return ssh.KeyboardInteractive(ci.challenge)
+}
+
+// ServerAuthenticated returns true if the server sent a valid proof M'.
+// Call it once ssh.Dial() (or ssh.NewClientConn()) has returned and close
+// the connection if it is false; the SRP client's RawKey() is then the
+// session key.
+func (ci *Client) ServerAuthenticated() bool {
+	return ci.authed
+}
+
+// answer the questions of a keyboard-interactive request
+func (ci *Client) challenge(user, instruction string, questions []string, echos []bool) ([]string, error) {
+	// servers may send requests without prompts
+	if len(questions) == 0 {
+		return nil, nil
+	}
+	if instruction != Instruction || len(questions) != 1 {
+		return nil, fmt.Errorf("srpssh: unexpected keyboard-interactive request %q", instruction)
+	}
+
+	q := questions[0]
+	switch {
+	case q == helloPrompt:
+		if ci.started {
+			ci.c.Reset()
+		}
+		ci.started = true
+		ci.authed = false
+		cc := ci.c.Hello()
+		return answer(cc.EncodeCBOR()), nil
+
+	case strings.HasPrefix(q, challengePrompt) && ci.started:
+		msg, err := b64.DecodeString(q[len(challengePrompt):])
+		if err != nil {
+			return nil, fmt.Errorf("srpssh: malformed challenge: %w", err)
+		}
+		sc, err := srp.DecodeServerCredentialsCBOR(msg)
+		if err != nil {
+			return nil, err
+		}
+		m, err := ci.c.Respond(sc)
+		if err != nil {
+			return nil, err
+		}
+		return answer(srp.EncodeProofCBOR(m)), nil
+
+	case strings.HasPrefix(q, proofPrompt) && ci.started:
+		msg, err := b64.DecodeString(q[len(proofPrompt):])
+		if err != nil {
+			return nil, fmt.Errorf("srpssh: malformed proof: %w", err)
+		}
+		proof, err := srp.DecodeProofCBOR(msg)
+		if err != nil {
+			return nil, err
+		}
+		if !ci.c.CheckProof(proof) {
+			return nil, fmt.Errorf("srpssh: server authentication failed")
+		}
+		ci.authed = true
+		return answer(nil), nil
+	}
+	return nil, fmt.Errorf("srpssh: unexpected prompt %q", q)
+}
+
+// return the answer carrying 'b'
+func answer(b []byte) []string {
+	return []string{b64.EncodeToString(b)}
+}
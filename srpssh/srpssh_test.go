@@ -0,0 +1,215 @@
+// self test for SRP in SSH keyboard-interactive authentication
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srpssh
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/tomsons/go-srp"
+)
+
+// the keyboard-interactive callback of a server that knows the verifier
+// 'v' of "user"
+func callback(s *srp.SRP, v *srp.Verifier) func(ssh.ConnMetadata, ssh.KeyboardInteractiveChallenge) (*ssh.Permissions, error) {
+	return KeyboardInteractiveCallback(func(u string) (*srp.SRP, *srp.Verifier, error) {
+		if u != "user" {
+			return nil, nil, fmt.Errorf("unknown user %q", u)
+		}
+		return s, v, nil
+	}, &Config{Decoys: s})
+}
+
+// log in as 'user' with the password 'pass' to a server that answers with
+// 'cb'; return the errors of both sides
+func login(t *testing.T, cb func(ssh.ConnMetadata, ssh.KeyboardInteractiveChallenge) (*ssh.Permissions, error), s *srp.SRP, user, pass string) (*ssh.Permissions, *Client, error, error) {
+	_, hk, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("host key: %s", err)
+	}
+	signer, err := ssh.NewSignerFromKey(hk)
+	if err != nil {
+		t.Fatalf("signer: %s", err)
+	}
+
+	scfg := &ssh.ServerConfig{KeyboardInteractiveCallback: cb}
+	scfg.AddHostKey(signer)
+
+	// net.Pipe() deadlocks when both sides send their version
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %s", err)
+	}
+	defer l.Close()
+
+	type result struct {
+		perms *ssh.Permissions
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		sc, err := l.Accept()
+		if err != nil {
+			done <- result{nil, err}
+			return
+		}
+		conn, _, _, err := ssh.NewServerConn(sc, scfg)
+		sc.Close()
+		if err != nil {
+			done <- result{nil, err}
+			return
+		}
+		done <- result{conn.Permissions, nil}
+	}()
+
+	c, err := s.NewClient([]byte(user), []byte(pass))
+	if err != nil {
+		t.Fatalf("NewClient: %s", err)
+	}
+	ci := NewClient(c)
+	ccfg := &ssh.ClientConfig{
+		User:            user,
+		Auth:            []ssh.AuthMethod{ci.AuthMethod()},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	}
+	cc, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %s", err)
+	}
+	conn, _, _, cerr := ssh.NewClientConn(cc, l.Addr().String(), ccfg)
+	if cerr == nil {
+		conn.Close()
+	}
+	cc.Close()
+	r := <-done
+	return r.perms, ci, cerr, r.err
+}
+
+func TestKeyboardInteractive(t *testing.T) {
+	s, err := srp.New(2048)
+	if err != nil {
+		t.Fatalf("New: %s", err)
+	}
+	v, err := s.Verifier([]byte("user"), []byte("pass"), nil)
+	if err != nil {
+		t.Fatalf("Verifier: %s", err)
+	}
+
+	cb := callback(s, v)
+	perms, ci, cerr, serr := login(t, cb, s, "user", "pass")
+	if cerr != nil || serr != nil {
+		t.Fatalf("login failed: client %v, server %v", cerr, serr)
+	}
+	if !ci.ServerAuthenticated() || len(ci.c.RawKey()) == 0 {
+		t.Fatalf("server not authenticated")
+	}
+	ih, _ := hex.DecodeString(perms.Extensions[IdentityExtension])
+	if !v.MatchesIdentity(ih) {
+		t.Fatalf("wrong identity in permissions")
+	}
+
+	// unknown users fail at the proof, like wrong passwords
+	var challenges int
+	counted := func(conn ssh.ConnMetadata, client ssh.KeyboardInteractiveChallenge) (*ssh.Permissions, error) {
+		return cb(conn, func(user, instr string, qs []string, echos []bool) ([]string, error) {
+			if len(qs) == 1 && strings.HasPrefix(qs[0], challengePrompt) {
+				challenges++
+			}
+			return client(user, instr, qs, echos)
+		})
+	}
+	for _, x := range []struct{ user, pass string }{{"user", "wrong"}, {"other", "pass"}} {
+		challenges = 0
+		if _, _, cerr, serr := login(t, counted, s, x.user, x.pass); cerr == nil || serr == nil {
+			t.Fatalf("%s/%s accepted", x.user, x.pass)
+		}
+		if challenges == 0 {
+			t.Fatalf("%s/%s: no challenge", x.user, x.pass)
+		}
+	}
+}
+
+func TestNilLookup(t *testing.T) {
+	s, err := srp.New(2048)
+	if err != nil {
+		t.Fatalf("New: %s", err)
+	}
+	v, err := s.Verifier([]byte("user"), []byte("pass"), nil)
+	if err != nil {
+		t.Fatalf("Verifier: %s", err)
+	}
+
+	// lookups that return nothing without an error get decoys
+	lookups := map[string]VerifierLookup{
+		"no verifier": func(string) (*srp.SRP, *srp.Verifier, error) {
+			return s, nil, nil
+		},
+		"no environment": func(string) (*srp.SRP, *srp.Verifier, error) {
+			return nil, v, nil
+		},
+		"nothing": func(string) (*srp.SRP, *srp.Verifier, error) {
+			return nil, nil, nil
+		},
+	}
+	for name, lookup := range lookups {
+		cb := KeyboardInteractiveCallback(lookup, &Config{Decoys: s})
+		if _, _, cerr, serr := login(t, cb, s, "user", "pass"); cerr == nil || serr == nil {
+			t.Fatalf("%s: login accepted", name)
+		}
+	}
+}
+
+func TestSkippedServerProof(t *testing.T) {
+	s, err := srp.New(2048)
+	if err != nil {
+		t.Fatalf("New: %s", err)
+	}
+
+	// a server that accepts any login without a proof of its own
+	cb := func(conn ssh.ConnMetadata, client ssh.KeyboardInteractiveChallenge) (*ssh.Permissions, error) {
+		if _, err := ask(client, conn.User(), helloPrompt); err != nil {
+			return nil, err
+		}
+		return &ssh.Permissions{}, nil
+	}
+	_, ci, cerr, serr := login(t, cb, s, "user", "pass")
+	if cerr != nil || serr != nil {
+		t.Fatalf("login failed: client %v, server %v", cerr, serr)
+	}
+	if ci.ServerAuthenticated() {
+		t.Fatalf("server authenticated without a proof")
+	}
+}
+
+func TestForeignPrompts(t *testing.T) {
+	s, err := srp.New(2048)
+	if err != nil {
+		t.Fatalf("New: %s", err)
+	}
+	c, err := s.NewClient([]byte("user"), []byte("pass"))
+	if err != nil {
+		t.Fatalf("NewClient: %s", err)
+	}
+
+	ci := NewClient(c)
+	if _, err := ci.challenge("user", "", []string{"Password: "}, []bool{false}); err == nil {
+		t.Fatalf("answered a password prompt")
+	}
+	if _, err := ci.challenge("user", Instruction, []string{proofPrompt + "AAAA"}, []bool{false}); err == nil {
+		t.Fatalf("answered a proof before the hello")
+	}
+	if ans, err := ci.challenge("user", Instruction, nil, nil); err != nil || len(ans) != 0 {
+		t.Fatalf("empty request: %v %v", ans, err)
+	}
+}
@@ -0,0 +1,352 @@
+// srptest.go - malicious peers for testing SRP integrations
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+// Package srptest simulates malicious SRP peers. It sends the values an
+// attacker would (A or B that are 0 or multiples of N, oversized or
+// truncated values, proofs that are empty, truncated or wrong, challenges
+// for another group) to a client or server under test and reports every
+// attack that wasn't rejected.
+//
+// The peers speak the string forms of the messages (Client.Credentials(),
+// Server.Credentials() and hex proofs), so applications can point them at
+// their own transport wrappers through the Client and Server interfaces;
+// WrapClient() and WrapServer() adapt the types of package srp.
+//
+// A peer can't force u = H(A, B) to zero without breaking the hash; the
+// checks for it are covered by the tests of package srp.
+package srptest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/tomsons/go-srp"
+)
+
+// Client is the client side of an integration under test
+type Client interface {
+	// Hello returns the client credentials "I:A"
+	Hello() (string, error)
+
+	// Respond handles the server credentials and returns the client's
+	// proof; it must fail if they are unacceptable.
+	Respond(srv string) (string, error)
+
+	// Finish verifies the server's proof; it must fail if it is wrong.
+	Finish(proof string) error
+}
+
+// Server is the server side of an integration under test
+type Server interface {
+	// Begin handles the client credentials and returns the server
+	// credentials; it must fail if they are unacceptable.
+	Begin(creds string) (string, error)
+
+	// Finish verifies the client's proof and returns the server's proof;
+	// it must fail if the proof is wrong.
+	Finish(proof string) (string, error)
+}
+
+// WrapClient adapts a client of package srp to Client
+func WrapClient(c *srp.Client) Client {
+	return &client{c}
+}
+
+// WrapServer adapts the verifier 'v' in environment 's' to Server; each
+// call of Begin() starts a new handshake.
+func WrapServer(s *srp.SRP, v *srp.Verifier) Server {
+	return &server{env: s, v: v}
+}
+
+// Attack names an attack that a client or server under test didn't reject
+type Attack struct {
+	Name string
+	Err  error // why the attack is considered successful
+}
+
+func (a Attack) Error() string {
+	return fmt.Sprintf("srptest: %s: %s", a.Name, a.Err)
+}
+
+// AttackServer runs a malicious client against servers returned by
+// 'newServer', one for each attack. The server must hold the verifier of
+// identity 'I' and password 'p' in environment 's'; an honest handshake is
+// run first to check this. It returns the attacks that succeeded.
+func AttackServer(s *srp.SRP, I, p []byte, newServer func() Server) []Attack {
+	var failed []Attack
+	fail := func(name string, f string, v ...interface{}) {
+		failed = append(failed, Attack{name, fmt.Errorf(f, v...)})
+	}
+
+	honest := func() (*srp.Client, Server, string, bool) {
+		c, err := s.NewClient(I, p)
+		if err != nil {
+			fail("honest", "NewClient: %s", err)
+			return nil, nil, "", false
+		}
+		srv := newServer()
+		sc, err := srv.Begin(c.Credentials())
+		if err != nil {
+			fail("honest", "Begin: %s", err)
+			return nil, nil, "", false
+		}
+		m, err := c.Generate(sc)
+		if err != nil {
+			fail("honest", "Generate: %s", err)
+			return nil, nil, "", false
+		}
+		return c, srv, m, true
+	}
+
+	c, srv, m, ok := honest()
+	if !ok {
+		return failed
+	}
+	proof, err := srv.Finish(m)
+	if err != nil {
+		return append(failed, Attack{"honest", fmt.Errorf("Finish: %s", err)})
+	}
+	if !c.ServerOk(proof) {
+		return append(failed, Attack{"honest", fmt.Errorf("wrong server proof")})
+	}
+
+	// malicious public keys
+	ih := strings.SplitN(c.Credentials(), ":", 2)[0]
+	for _, x := range badPublicKeys(s) {
+		if _, err := newServer().Begin(ih + ":" + x.val); err == nil {
+			fail(x.name, "server accepted A")
+		}
+	}
+
+	// malicious proofs after an honest start
+	for _, x := range badProofs(m) {
+		_, srv, _, ok := honest()
+		if !ok {
+			return failed
+		}
+		if _, err := srv.Finish(x.val); err == nil {
+			fail(x.name, "server accepted the proof")
+		}
+	}
+
+	// the right proof of another handshake
+	_, _, m1, ok := honest()
+	if !ok {
+		return failed
+	}
+	_, srv2, _, ok := honest()
+	if !ok {
+		return failed
+	}
+	if _, err := srv2.Finish(m1); err == nil {
+		fail("proof-of-other-handshake", "server accepted the proof")
+	}
+
+	// a wrong password
+	wrong := append(append([]byte{}, p...), 'x')
+	c, err = s.NewClient(I, wrong)
+	if err == nil {
+		srv := newServer()
+		if sc, err := srv.Begin(c.Credentials()); err == nil {
+			if m, err := c.Generate(sc); err == nil {
+				if _, err := srv.Finish(m); err == nil {
+					fail("wrong-password", "server accepted the proof")
+				}
+			}
+		}
+	}
+	return failed
+}
+
+// AttackClient runs a malicious server against clients returned by
+// 'newClient', one for each attack. The clients must know the password of
+// the verifier 'v' in environment 's'; an honest handshake is run first to
+// check this. It returns the attacks that succeeded.
+func AttackClient(s *srp.SRP, v *srp.Verifier, newClient func() Client) []Attack {
+	var failed []Attack
+	fail := func(name string, f string, x ...interface{}) {
+		failed = append(failed, Attack{name, fmt.Errorf(f, x...)})
+	}
+
+	// returns a client and the server that answered its hello
+	honest := func() (Client, *srp.Server, bool) {
+		c := newClient()
+		creds, err := c.Hello()
+		if err != nil {
+			fail("honest", "Hello: %s", err)
+			return nil, nil, false
+		}
+		_, A, err := srp.ServerBegin(creds)
+		if err != nil {
+			fail("honest", "ServerBegin: %s", err)
+			return nil, nil, false
+		}
+		srv, err := s.NewServer(v, A)
+		if err != nil {
+			fail("honest", "NewServer: %s", err)
+			return nil, nil, false
+		}
+		return c, srv, true
+	}
+
+	c, srv, ok := honest()
+	if !ok {
+		return failed
+	}
+	m, err := c.Respond(srv.Credentials())
+	if err != nil {
+		return append(failed, Attack{"honest", fmt.Errorf("Respond: %s", err)})
+	}
+	proof, ok := srv.ClientOk(m)
+	if !ok {
+		return append(failed, Attack{"honest", fmt.Errorf("server rejected the proof")})
+	}
+	if err := c.Finish(proof); err != nil {
+		return append(failed, Attack{"honest", fmt.Errorf("Finish: %s", err)})
+	}
+
+	// malicious challenges
+	salt := hex.EncodeToString(random(16))
+	for _, x := range badPublicKeys(s) {
+		c := newClient()
+		if _, err := c.Hello(); err != nil {
+			fail("honest", "Hello: %s", err)
+			return failed
+		}
+		if _, err := c.Respond(salt + ":" + x.val); err == nil {
+			fail(x.name, "client accepted B")
+		}
+	}
+
+	// a challenge for another group of the same size
+	gid := s.GroupID()
+	for _, g := range srp.SupportedGroups() {
+		if g.Bits != s.FieldSize() || g.ID == gid {
+			continue
+		}
+		c, srv, ok := honest()
+		if !ok {
+			return failed
+		}
+		sc := srv.Challenge()
+		sc.Group = g.ID
+		if _, err := c.Respond(sc.String()); err == nil {
+			fail("group-"+g.ID, "client accepted a challenge for another group")
+		}
+	}
+
+	// malicious server proofs
+	for _, x := range badProofs(proof) {
+		c, srv, ok := honest()
+		if !ok {
+			return failed
+		}
+		if _, err := c.Respond(srv.Credentials()); err != nil {
+			fail("honest", "Respond: %s", err)
+			return failed
+		}
+		if err := c.Finish(x.val); err == nil {
+			fail(x.name, "client accepted the server proof")
+		}
+	}
+	return failed
+}
+
+// a named malicious value
+type value struct {
+	name string
+	val  string
+}
+
+// return malicious public keys A or B for the environment 's'
+func badPublicKeys(s *srp.SRP) []value {
+	N := s.Group().N
+	mul := func(k int64) string {
+		return big.NewInt(0).Mul(N, big.NewInt(k)).Text(16)
+	}
+
+	return []value{
+		{"zero", "0"},
+		{"N", N.Text(16)},
+		{"2N", mul(2)},
+		{"oversized", hex.EncodeToString(random(2 * s.FieldSize() / 8))},
+		{"not-hex", "xyz"},
+		{"empty", ""},
+	}
+}
+
+// return malicious variants of the honest proof 'm'
+func badProofs(m string) []value {
+	b, _ := hex.DecodeString(m)
+	flipped := append([]byte{}, b...)
+	if len(flipped) > 0 {
+		flipped[0] ^= 1
+	}
+
+	return []value{
+		{"empty-proof", ""},
+		{"truncated-proof", m[:len(m)/2]},
+		{"extended-proof", m + "00"},
+		{"zero-proof", hex.EncodeToString(make([]byte, len(b)))},
+		{"flipped-proof", hex.EncodeToString(flipped)},
+		{"not-hex-proof", "zz" + m[2:]},
+	}
+}
+
+// return 'n' random bytes
+func random(n int) []byte {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return b
+}
+
+type client struct {
+	c *srp.Client
+}
+
+func (c *client) Hello() (string, error) {
+	return c.c.Credentials(), nil
+}
+
+func (c *client) Respond(srv string) (string, error) {
+	return c.c.Generate(srv)
+}
+
+func (c *client) Finish(proof string) error {
+	if !c.c.ServerOk(proof) {
+		return fmt.Errorf("srptest: wrong server proof")
+	}
+	return nil
+}
+
+type server struct {
+	env *srp.SRP
+	v   *srp.Verifier
+	s   *srp.Server
+}
+
+func (s *server) Begin(creds string) (string, error) {
+	_, A, err := srp.ServerBegin(creds)
+	if err != nil {
+		return "", err
+	}
+	if s.s, err = s.env.NewServer(s.v, A); err != nil {
+		return "", err
+	}
+	return s.s.Credentials(), nil
+}
+
+func (s *server) Finish(proof string) (string, error) {
+	if s.s == nil {
+		return "", fmt.Errorf("srptest: no handshake")
+	}
+	return s.s.VerifyClientProof(proof)
+}
@@ -0,0 +1,209 @@
+// self test for malicious peers and properties of handshakes
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srptest
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math/big"
+	"testing"
+	"testing/quick"
+
+	"github.com/tomsons/go-srp"
+)
+
+var (
+	user = []byte("user")
+	pass = []byte("pass")
+)
+
+func setup(t *testing.T) (*srp.SRP, *srp.Verifier) {
+	s, err := srp.New(2048)
+	if err != nil {
+		t.Fatalf("New: %s", err)
+	}
+	v, err := s.Verifier(user, pass, nil)
+	if err != nil {
+		t.Fatalf("Verifier: %s", err)
+	}
+	return s, v
+}
+
+func TestAttackServer(t *testing.T) {
+	s, v := setup(t)
+
+	for _, a := range AttackServer(s, user, pass, func() Server { return WrapServer(s, v) }) {
+		t.Errorf("%s", a)
+	}
+}
+
+func TestAttackClient(t *testing.T) {
+	s, v := setup(t)
+
+	newClient := func() Client {
+		c, err := s.NewClient(user, pass)
+		if err != nil {
+			t.Fatalf("NewClient: %s", err)
+		}
+		return WrapClient(c)
+	}
+	for _, a := range AttackClient(s, v, newClient) {
+		t.Errorf("%s", a)
+	}
+}
+
+// a server that accepts any proof
+type gullibleServer struct {
+	Server
+}
+
+func (s gullibleServer) Finish(proof string) (string, error) {
+	s.Server.Finish(proof)
+	return "00", nil
+}
+
+// a client that accepts any server proof
+type gullibleClient struct {
+	Client
+}
+
+func (c gullibleClient) Finish(proof string) error {
+	return nil
+}
+
+func TestAttacksDetected(t *testing.T) {
+	s, v := setup(t)
+
+	// the honest handshake fails against a server that returns a wrong proof
+	failed := AttackServer(s, user, pass, func() Server { return gullibleServer{WrapServer(s, v)} })
+	if len(failed) != 1 || failed[0].Name != "honest" {
+		t.Fatalf("exp an honest failure, saw %v", failed)
+	}
+
+	newClient := func() Client {
+		c, _ := s.NewClient(user, pass)
+		return gullibleClient{WrapClient(c)}
+	}
+	failed = AttackClient(s, v, newClient)
+	if len(failed) != len(badProofs("00")) {
+		t.Fatalf("exp %d attacks to succeed, saw %v", len(badProofs("00")), failed)
+	}
+	for _, a := range failed {
+		if a.Error() == "" {
+			t.Fatalf("empty error for %s", a.Name)
+		}
+	}
+}
+
+// handshakes succeed if and only if the client knows the password
+func TestQuickHandshake(t *testing.T) {
+	s, err := srp.New(2048)
+	if err != nil {
+		t.Fatalf("New: %s", err)
+	}
+
+	prop := func(I, p, q []byte) bool {
+		v, err := s.Verifier(I, p, nil)
+		if err != nil {
+			return false
+		}
+		c, err := s.NewClient(I, q)
+		if err != nil {
+			return false
+		}
+
+		srv := WrapServer(s, v)
+		sc, err := srv.Begin(c.Credentials())
+		if err != nil {
+			return false
+		}
+		m, err := c.Generate(sc)
+		if err != nil {
+			return false
+		}
+		proof, err := srv.Finish(m)
+		if !bytes.Equal(p, q) {
+			return err != nil
+		}
+		return err == nil && c.ServerOk(proof)
+	}
+
+	if err := quick.Check(prop, &quick.Config{MaxCount: 20}); err != nil {
+		t.Fatal(err)
+	}
+
+	// equal passwords are rare among random ones
+	same := func(I, p []byte) bool {
+		return prop(I, p, p)
+	}
+	if err := quick.Check(same, &quick.Config{MaxCount: 10}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// both sides abort on public keys that are multiples of N
+func TestQuickMultiplesOfN(t *testing.T) {
+	s, v := setup(t)
+	N := s.Group().N
+
+	prop := func(k uint16) bool {
+		x := big.NewInt(0).Mul(N, big.NewInt(int64(k)))
+		if x.BitLen() > s.FieldSize() {
+			// too large for the wire; rejected by the size limits
+			x.Mod(x, N)
+		}
+
+		if _, err := s.NewServer(v, x); err == nil {
+			return false
+		}
+
+		c, err := s.NewClient(user, pass)
+		if err != nil {
+			return false
+		}
+		_, err = c.Generate(hex.EncodeToString([]byte("salt")) + ":" + x.Text(16))
+		return err != nil
+	}
+
+	if err := quick.Check(prop, &quick.Config{MaxCount: 20}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// any change to a proof is rejected
+func TestQuickProofMutation(t *testing.T) {
+	s, v := setup(t)
+
+	prop := func(i uint8, d uint8) bool {
+		if d == 0 {
+			d = 1
+		}
+
+		c, err := s.NewClient(user, pass)
+		if err != nil {
+			return false
+		}
+		srv := WrapServer(s, v)
+		sc, err := srv.Begin(c.Credentials())
+		if err != nil {
+			return false
+		}
+		m, err := c.Generate(sc)
+		if err != nil {
+			return false
+		}
+
+		b, _ := hex.DecodeString(m)
+		b[int(i)%len(b)] ^= d
+		_, err = srv.Finish(hex.EncodeToString(b))
+		return err != nil
+	}
+
+	if err := quick.Check(prop, &quick.Config{MaxCount: 20}); err != nil {
+		t.Fatal(err)
+	}
+}
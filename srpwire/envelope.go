@@ -0,0 +1,146 @@
+// envelope.go - versioned envelope of handshake messages
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srpwire
+
+import (
+	"fmt"
+)
+
+// An envelope prefixes a message with its version and type:
+//
+//	version (1 byte) | type (1 byte) | payload
+//
+// Versions are at most 0x1f. Legacy messages start with a printable
+// character (string forms and JSON) or a CBOR head of 0x40 and above, so a
+// parser can tell an envelope from a bare message by its first byte; bare
+// messages are reported as version 0.
+
+// EnvelopeVersion is the version of envelopes made by Wrap()
+const EnvelopeVersion = 1
+
+// largest version byte; larger first bytes are bare messages
+const maxEnvelopeVersion = 0x1f
+
+// MessageType identifies the message in an envelope
+type MessageType byte
+
+// Message types of a handshake
+const (
+	MessageHello     MessageType = 1 // ClientCredentials
+	MessageChallenge MessageType = 2 // ServerCredentials
+	MessageProof     MessageType = 3 // client or server proof
+)
+
+// String names the message type
+func (t MessageType) String() string {
+	switch t {
+	case MessageHello:
+		return "hello"
+	case MessageChallenge:
+		return "challenge"
+	case MessageProof:
+		return "proof"
+	}
+	return fmt.Sprintf("type %d", byte(t))
+}
+
+// Wrap returns 'payload' in an envelope of the current version for a
+// message of type 't'
+func Wrap(t MessageType, payload []byte) []byte {
+	b := make([]byte, 0, 2+len(payload))
+	b = append(b, EnvelopeVersion, byte(t))
+	return append(b, payload...)
+}
+
+// Unwrap returns the version, type and payload of the message 'b'. A bare
+// legacy message is returned as is with version 0 and type 0; the caller
+// knows its type from its place in the handshake. Envelopes of versions
+// newer than EnvelopeVersion are rejected.
+func Unwrap(b []byte) (version int, t MessageType, payload []byte, err error) {
+	if len(b) == 0 {
+		return 0, 0, nil, fmt.Errorf("srpwire: empty message")
+	}
+	if b[0] > maxEnvelopeVersion {
+		return 0, 0, b, nil
+	}
+
+	if b[0] == 0 || b[0] > EnvelopeVersion {
+		return 0, 0, nil, fmt.Errorf("srpwire: unsupported envelope version %d", b[0])
+	}
+	if len(b) < 2 {
+		return 0, 0, nil, fmt.Errorf("srpwire: truncated envelope")
+	}
+	return int(b[0]), MessageType(b[1]), b[2:], nil
+}
+
+// unwrap a message that must be of type 't' if it is in an envelope
+func unwrap(t MessageType, b []byte) ([]byte, error) {
+	v, mt, p, err := Unwrap(b)
+	if err != nil {
+		return nil, err
+	}
+	if v > 0 && mt != t {
+		return nil, fmt.Errorf("srpwire: expected %s, saw %s", t, mt)
+	}
+	return p, nil
+}
+
+// Enveloped returns a codec that sends the messages of 'c' in envelopes
+// and accepts them with or without one. Its name is that of 'c' with the
+// suffix "+env1", so that peers negotiate it (see Select()) before
+// receiving envelopes they can't parse.
+func Enveloped(c Codec) Codec {
+	if e, ok := c.(envCodec); ok {
+		return e
+	}
+	return envCodec{c}
+}
+
+// envCodec implements Enveloped()
+type envCodec struct {
+	c Codec
+}
+
+func (e envCodec) Name() string {
+	return fmt.Sprintf("%s+env%d", e.c.Name(), EnvelopeVersion)
+}
+
+func (e envCodec) EncodeHello(cc ClientCredentials) []byte {
+	return Wrap(MessageHello, e.c.EncodeHello(cc))
+}
+
+func (e envCodec) DecodeHello(b []byte) (ClientCredentials, error) {
+	p, err := unwrap(MessageHello, b)
+	if err != nil {
+		return ClientCredentials{}, err
+	}
+	return e.c.DecodeHello(p)
+}
+
+func (e envCodec) EncodeChallenge(sc ServerCredentials) []byte {
+	return Wrap(MessageChallenge, e.c.EncodeChallenge(sc))
+}
+
+func (e envCodec) DecodeChallenge(b []byte) (ServerCredentials, error) {
+	p, err := unwrap(MessageChallenge, b)
+	if err != nil {
+		return ServerCredentials{}, err
+	}
+	return e.c.DecodeChallenge(p)
+}
+
+func (e envCodec) EncodeProof(proof []byte) []byte {
+	return Wrap(MessageProof, e.c.EncodeProof(proof))
+}
+
+func (e envCodec) DecodeProof(b []byte) ([]byte, error) {
+	p, err := unwrap(MessageProof, b)
+	if err != nil {
+		return nil, err
+	}
+	return e.c.DecodeProof(p)
+}
@@ -0,0 +1,259 @@
+// srpwire.go - wire encodings of SRP messages and verifiers
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+// Package srpwire holds the encodings of SRP messages and stored
+// verifiers, apart from the math of package srp: the legacy string forms,
+// JSON and CBOR, and the negotiation of an encoding between peers.
+//
+// A handshake is three messages, each encoded by a Codec:
+//
+//	client -> server   hello      ClientCredentials
+//	server -> client   challenge  ServerCredentials
+//	client -> server   proof      M (and the server's proof in reply)
+//
+// Peers that support several codecs agree on one before the handshake:
+// the client sends Offer() and the server answers with the name returned
+// by Select(). Names carry a version ("srp-cbor/1") so that an encoding
+// can change without breaking deployed peers. Enveloped() codecs also
+// prefix each message with a version and type (see Wrap()); they accept
+// bare messages as version 0.
+//
+// The types of the messages are aliases of those of package srp, so
+// values pass between the packages without conversion. This package does
+// not hold the encodings: the string, hex, JSON and CBOR forms are defined
+// by package srp (Client.Credentials(), Server.Marshal(),
+// Verifier.EncodeCBOR() and so on) and the codecs here call them.
+package srpwire
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/tomsons/go-srp"
+)
+
+// Message types shared with package srp
+type (
+	ClientCredentials = srp.ClientCredentials
+	ServerCredentials = srp.ServerCredentials
+	VerifierFormat    = srp.VerifierFormat
+)
+
+// Formats of stored verifiers (see EncodeVerifier())
+const (
+	VerifierText           = srp.VerifierText
+	VerifierCBOR           = srp.VerifierCBOR
+	VerifierCompact        = srp.VerifierCompact
+	VerifierCompactDeflate = srp.VerifierCompactDeflate
+)
+
+// Codec encodes the messages of a handshake
+type Codec interface {
+	// Name identifies the codec and its version in negotiation
+	Name() string
+
+	EncodeHello(cc ClientCredentials) []byte
+	DecodeHello(b []byte) (ClientCredentials, error)
+
+	EncodeChallenge(sc ServerCredentials) []byte
+	DecodeChallenge(b []byte) (ServerCredentials, error)
+
+	EncodeProof(proof []byte) []byte
+	DecodeProof(b []byte) ([]byte, error)
+}
+
+// Codecs provided by this package
+var (
+	// Text is the legacy string form: "I:A" for the hello, "s:B[:ext]..."
+	// for the challenge and hex proofs. Peers that don't negotiate use it.
+	Text Codec = textCodec{}
+
+	// JSON encodes the messages with encoding/json; byte strings are
+	// base64. It suits HTTP APIs.
+	JSON Codec = jsonCodec{}
+
+	// CBOR is the compact binary encoding of RFC 8949 for constrained
+	// transports.
+	CBOR Codec = cborCodec{}
+)
+
+// all codecs in order of preference
+var codecs = []Codec{Enveloped(CBOR), Enveloped(JSON), Enveloped(Text), CBOR, JSON, Text}
+
+// Codecs returns the codecs of this package in order of preference
+func Codecs() []Codec {
+	return append([]Codec{}, codecs...)
+}
+
+// Lookup returns the codec named 'name'
+func Lookup(name string) (Codec, error) {
+	for _, c := range codecs {
+		if c.Name() == name {
+			return c, nil
+		}
+	}
+	return nil, fmt.Errorf("srpwire: unknown codec %q", name)
+}
+
+// Offer returns the list of codecs a client sends to negotiate an encoding;
+// 'cs' are in the order of the client's preference. The list is the names
+// separated by commas.
+func Offer(cs ...Codec) string {
+	v := make([]string, len(cs))
+	for i, c := range cs {
+		v[i] = c.Name()
+	}
+	return strings.Join(v, ",")
+}
+
+// Select returns the first codec of the client's 'offer' that is among
+// 'supported' (all codecs of this package if empty); the server replies
+// with its name. Unknown names in the offer are skipped, so that clients
+// can offer newer versions first.
+func Select(offer string, supported ...Codec) (Codec, error) {
+	if len(supported) == 0 {
+		supported = codecs
+	}
+	for _, name := range strings.Split(offer, ",") {
+		name = strings.TrimSpace(name)
+		for _, c := range supported {
+			if c.Name() == name {
+				return c, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("srpwire: no common codec in %q", offer)
+}
+
+// EncodeVerifier returns the verifier 'v' in format 'f'
+func EncodeVerifier(v *srp.Verifier, f VerifierFormat) ([]byte, error) {
+	return v.EncodeAs(f)
+}
+
+// DecodeVerifier decodes a verifier stored in format 'f' and returns it
+// with its SRP environment; 'opts' are applied to the environment.
+func DecodeVerifier(b []byte, f VerifierFormat, opts ...srp.Option) (*srp.SRP, *srp.Verifier, error) {
+	return srp.DecodeVerifier(b, f, opts...)
+}
+
+// textCodec implements Text
+type textCodec struct{}
+
+func (textCodec) Name() string { return "srp-text/1" }
+
+func (textCodec) EncodeHello(cc ClientCredentials) []byte {
+	return []byte(cc.String())
+}
+
+func (textCodec) DecodeHello(b []byte) (ClientCredentials, error) {
+	return srp.ParseClientCredentials(string(b))
+}
+
+func (textCodec) EncodeChallenge(sc ServerCredentials) []byte {
+	return []byte(sc.String())
+}
+
+func (textCodec) DecodeChallenge(b []byte) (ServerCredentials, error) {
+	return srp.ParseServerCredentials(string(b))
+}
+
+func (textCodec) EncodeProof(proof []byte) []byte {
+	return []byte(hex.EncodeToString(proof))
+}
+
+func (textCodec) DecodeProof(b []byte) ([]byte, error) {
+	p, err := hex.DecodeString(string(b))
+	if err != nil || len(p) == 0 {
+		return nil, fmt.Errorf("srpwire: invalid proof")
+	}
+	return p, nil
+}
+
+// jsonCodec implements JSON
+type jsonCodec struct{}
+
+// the JSON proof message
+type jsonProof struct {
+	M []byte `json:"M"`
+}
+
+func (jsonCodec) Name() string { return "srp-json/1" }
+
+func (jsonCodec) EncodeHello(cc ClientCredentials) []byte {
+	return mustJSON(cc)
+}
+
+func (jsonCodec) DecodeHello(b []byte) (ClientCredentials, error) {
+	var cc ClientCredentials
+	if err := json.Unmarshal(b, &cc); err != nil || len(cc.IdentityHash) == 0 || len(cc.A) == 0 {
+		return cc, fmt.Errorf("srpwire: invalid client credentials")
+	}
+	return cc, nil
+}
+
+func (jsonCodec) EncodeChallenge(sc ServerCredentials) []byte {
+	return mustJSON(sc)
+}
+
+func (jsonCodec) DecodeChallenge(b []byte) (ServerCredentials, error) {
+	var sc ServerCredentials
+	if err := json.Unmarshal(b, &sc); err != nil || len(sc.Salt) == 0 || len(sc.B) == 0 {
+		return sc, fmt.Errorf("srpwire: invalid server credentials")
+	}
+	return sc, nil
+}
+
+func (jsonCodec) EncodeProof(proof []byte) []byte {
+	return mustJSON(jsonProof{proof})
+}
+
+func (jsonCodec) DecodeProof(b []byte) ([]byte, error) {
+	var p jsonProof
+	if err := json.Unmarshal(b, &p); err != nil || len(p.M) == 0 {
+		return nil, fmt.Errorf("srpwire: invalid proof")
+	}
+	return p.M, nil
+}
+
+// cborCodec implements CBOR
+type cborCodec struct{}
+
+func (cborCodec) Name() string { return "srp-cbor/1" }
+
+func (cborCodec) EncodeHello(cc ClientCredentials) []byte {
+	return cc.EncodeCBOR()
+}
+
+func (cborCodec) DecodeHello(b []byte) (ClientCredentials, error) {
+	return srp.DecodeClientCredentialsCBOR(b)
+}
+
+func (cborCodec) EncodeChallenge(sc ServerCredentials) []byte {
+	return sc.EncodeCBOR()
+}
+
+func (cborCodec) DecodeChallenge(b []byte) (ServerCredentials, error) {
+	return srp.DecodeServerCredentialsCBOR(b)
+}
+
+func (cborCodec) EncodeProof(proof []byte) []byte {
+	return srp.EncodeProofCBOR(proof)
+}
+
+func (cborCodec) DecodeProof(b []byte) ([]byte, error) {
+	return srp.DecodeProofCBOR(b)
+}
+
+// marshal 'v', which can't fail for the message types
+func mustJSON(v interface{}) []byte {
+	b, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Sprintf("srpwire: %s", err))
+	}
+	return b
+}
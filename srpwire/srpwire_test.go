@@ -0,0 +1,164 @@
+// self test for wire encodings
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srpwire
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/tomsons/go-srp"
+)
+
+func TestCodecs(t *testing.T) {
+	s, err := srp.New(2048)
+	if err != nil {
+		t.Fatalf("New: %s", err)
+	}
+	v, err := s.Verifier([]byte("user"), []byte("pass"), nil)
+	if err != nil {
+		t.Fatalf("Verifier: %s", err)
+	}
+
+	for _, cd := range Codecs() {
+		c, err := s.NewClient([]byte("user"), []byte("pass"))
+		if err != nil {
+			t.Fatalf("NewClient: %s", err)
+		}
+
+		cc, err := cd.DecodeHello(cd.EncodeHello(c.Hello()))
+		if err != nil {
+			t.Fatalf("%s: hello: %s", cd.Name(), err)
+		}
+
+		A, err := s.ParsePublicKey(cc.A)
+		if err != nil {
+			t.Fatalf("%s: A: %s", cd.Name(), err)
+		}
+		srv, err := s.NewServerFor(cc.IdentityHash, v, A)
+		if err != nil {
+			t.Fatalf("%s: NewServerFor: %s", cd.Name(), err)
+		}
+
+		sc, err := cd.DecodeChallenge(cd.EncodeChallenge(srv.Challenge()))
+		if err != nil {
+			t.Fatalf("%s: challenge: %s", cd.Name(), err)
+		}
+		m, err := c.Respond(sc)
+		if err != nil {
+			t.Fatalf("%s: Respond: %s", cd.Name(), err)
+		}
+
+		m, err = cd.DecodeProof(cd.EncodeProof(m))
+		if err != nil {
+			t.Fatalf("%s: proof: %s", cd.Name(), err)
+		}
+		proof, ok := srv.CheckProof(m)
+		if !ok {
+			t.Fatalf("%s: client proof rejected", cd.Name())
+		}
+
+		proof, err = cd.DecodeProof(cd.EncodeProof(proof))
+		if err != nil || !c.CheckProof(proof) {
+			t.Fatalf("%s: server proof rejected: %v", cd.Name(), err)
+		}
+
+		if _, err := cd.DecodeHello([]byte("junk")); err == nil {
+			t.Fatalf("%s: junk hello accepted", cd.Name())
+		}
+	}
+}
+
+func TestNegotiation(t *testing.T) {
+	c, err := Select(Offer(CBOR, Text))
+	if err != nil || c != CBOR {
+		t.Fatalf("exp CBOR, saw %v, %v", c, err)
+	}
+
+	// newer versions the server doesn't know are skipped
+	c, err = Select("srp-cbor/9, srp-json/1", Text, JSON)
+	if err != nil || c != JSON {
+		t.Fatalf("exp JSON, saw %v, %v", c, err)
+	}
+
+	if _, err := Select("srp-cbor/1", Text); err == nil {
+		t.Fatalf("no common codec accepted")
+	}
+
+	for _, c := range Codecs() {
+		if l, err := Lookup(c.Name()); err != nil || l != c {
+			t.Fatalf("Lookup %s: %v, %v", c.Name(), l, err)
+		}
+	}
+}
+
+func TestVerifierFormats(t *testing.T) {
+	s, _ := srp.New(2048)
+	v, err := s.Verifier([]byte("user"), []byte("pass"), nil)
+	if err != nil {
+		t.Fatalf("Verifier: %s", err)
+	}
+
+	for _, f := range []VerifierFormat{VerifierText, VerifierCBOR, VerifierCompact, VerifierCompactDeflate} {
+		b, err := EncodeVerifier(v, f)
+		if err != nil {
+			t.Fatalf("%s: encode: %s", f, err)
+		}
+		_, w, err := DecodeVerifier(b, f)
+		if err != nil {
+			t.Fatalf("%s: decode: %s", f, err)
+		}
+		b2, _ := EncodeVerifier(w, f)
+		if !bytes.Equal(b, b2) {
+			t.Fatalf("%s: round trip mismatch", f)
+		}
+	}
+}
+
+func TestEnvelope(t *testing.T) {
+	b := Wrap(MessageProof, []byte("abc"))
+	v, mt, p, err := Unwrap(b)
+	if err != nil || v != EnvelopeVersion || mt != MessageProof || string(p) != "abc" {
+		t.Fatalf("Unwrap: %d %s %q %v", v, mt, p, err)
+	}
+
+	// bare messages of every codec are version 0
+	cc := ClientCredentials{IdentityHash: []byte{1, 2}, A: []byte{3, 4}}
+	for _, c := range []Codec{Text, JSON, CBOR} {
+		m := c.EncodeHello(cc)
+		v, _, p, err := Unwrap(m)
+		if err != nil || v != 0 || !bytes.Equal(p, m) {
+			t.Fatalf("%s: bare hello: %d %v", c.Name(), v, err)
+		}
+
+		m = c.EncodeProof([]byte{5})
+		if v, _, _, err := Unwrap(m); err != nil || v != 0 {
+			t.Fatalf("%s: bare proof: %d %v", c.Name(), v, err)
+		}
+
+		// enveloped codecs read bare messages and check the type
+		e := Enveloped(c)
+		if _, err := e.DecodeHello(c.EncodeHello(cc)); err != nil {
+			t.Fatalf("%s: bare hello rejected: %s", e.Name(), err)
+		}
+		if _, err := e.DecodeHello(e.EncodeProof([]byte{5})); err == nil {
+			t.Fatalf("%s: proof accepted as hello", e.Name())
+		}
+		if Enveloped(e) != e {
+			t.Fatalf("%s: enveloped twice", e.Name())
+		}
+	}
+
+	for _, b := range [][]byte{nil, {0, 1}, {EnvelopeVersion + 1, 1}, {EnvelopeVersion}} {
+		if _, _, _, err := Unwrap(b); err == nil {
+			t.Fatalf("%x accepted", b)
+		}
+	}
+
+	if c, err := Select("srp-json/1+env1,srp-json/1", JSON); err != nil || c != JSON {
+		t.Fatalf("exp bare JSON, saw %v %v", c, err)
+	}
+}
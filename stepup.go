@@ -0,0 +1,88 @@
+// stepup.go - re-authentication within an established session
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// Step-up authentication ("sudo mode") asks a logged in user to prove
+// again that they know the password before a sensitive operation. The
+// fresh handshake uses new ephemerals and the stored verifier and is bound
+// to the session it steps up: both sides add
+//
+//	L = HKDF(K, "srp step-up")
+//
+// of the prior session key K to the transcript context (see
+// WithTranscriptContext()), so its proofs are only valid within that
+// session. A proof captured in one session can't step up another, and a
+// peer that doesn't hold K can't complete the handshake even with the
+// password. The messages are those of an ordinary handshake.
+
+var stepUpLabel = []byte("srp step-up")
+
+// StepUp returns a client for re-authenticating within this session: it
+// has the same identity and password, a new ephemeral, and proofs bound to
+// this session's key. The session must be authenticated (see
+// CheckProof()); the new client is used like one from NewClient().
+func (c *Client) StepUp() (*Client, error) {
+	if !c.authed || c.xK == nil {
+		return nil, fmt.Errorf("srp: step-up needs an authenticated session")
+	}
+
+	n := &Client{
+		s:    c.s,
+		i:    c.i,
+		p:    c.p,
+		ip:   c.ip,
+		a:    c.s.ephemeral(),
+		bind: c.s.expandKey(c.xK, stepUpLabel, len(c.xK)),
+	}
+	n.xA = c.s.arith().Exp(c.s.pf.g, n.a, c.s.pf.N)
+	return n, nil
+}
+
+// StepUp returns a server for the client's re-authentication within this
+// session (see Client.StepUp()); 'A' is the public key the client sent
+// for it. The verifier of this session is reused, so no lookup is needed.
+// The session must be authenticated.
+func (s *Server) StepUp(A *big.Int) (*Server, error) {
+	if !s.authed || s.xK == nil {
+		return nil, fmt.Errorf("srp: step-up needs an authenticated session")
+	}
+
+	v := &Verifier{s: s.salt, kdf: s.kdf, xd: s.xd}
+	n, err := s.s.newServer(v, s.i, s.v, A)
+	if err != nil {
+		return nil, err
+	}
+
+	n.bind = s.s.expandKey(s.xK, stepUpLabel, len(s.xK))
+	n.xM = s.s.scheme().ClientProof(n.transcript())
+	return n, nil
+}
+
+// IsStepUp returns true if the server authenticates a step-up within
+// another session
+func (s *Server) IsStepUp() bool {
+	return s.bind != nil
+}
+
+// return the transcript of the server's handshake
+func (s *Server) transcript() *Transcript {
+	return s.s.transcript(s.xK, s.xA, s.xB, s.i, s.salt).bound(s.bind)
+}
+
+// return the transcript with the step-up binding 'bind' added to its
+// context, if any
+func (t *Transcript) bound(bind []byte) *Transcript {
+	if bind != nil {
+		t.Context = append(append(append([]byte{}, t.Context...), stepUpLabel...), bind...)
+	}
+	return t
+}
@@ -0,0 +1,81 @@
+// self test for step-up authentication
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"testing"
+)
+
+// run a handshake between 'c' and 'srv' and return true if both sides
+// authenticated
+func authenticate(c *Client, srv *Server) bool {
+	m, err := c.Respond(srv.Challenge())
+	if err != nil {
+		return false
+	}
+	proof, ok := srv.CheckProof(m)
+	return ok && c.CheckProof(proof)
+}
+
+func TestStepUp(t *testing.T) {
+	assert := newAsserter(t)
+
+	s, err := New(2048)
+	assert(err == nil, "New: %s", err)
+
+	v, err := s.Verifier([]byte("user"), []byte("pass"), nil)
+	assert(err == nil, "Verifier: %s", err)
+
+	login := func() (*Client, *Server) {
+		c, err := s.NewClient([]byte("user"), []byte("pass"))
+		assert(err == nil, "NewClient: %s", err)
+		srv, err := s.NewServer(v, c.xA)
+		assert(err == nil, "NewServer: %s", err)
+		assert(authenticate(c, srv), "login failed")
+		return c, srv
+	}
+
+	c, srv := login()
+
+	// an unauthenticated session can't step up
+	c0, _ := s.NewClient([]byte("user"), []byte("pass"))
+	_, err = c0.StepUp()
+	assert(err != nil, "unauthenticated client stepped up")
+
+	c1, err := c.StepUp()
+	assert(err == nil, "Client.StepUp: %s", err)
+	assert(c1.xA.Cmp(c.xA) != 0, "ephemeral reused")
+
+	srv1, err := srv.StepUp(c1.xA)
+	assert(err == nil, "Server.StepUp: %s", err)
+	assert(srv1.IsStepUp() && !srv.IsStepUp(), "IsStepUp mismatch")
+
+	// the step-up must survive a marshaled server
+	srv1, err = UnmarshalServer(srv1.Marshal())
+	assert(err == nil, "UnmarshalServer: %s", err)
+	assert(authenticate(c1, srv1), "step-up failed")
+	assert(!ctEqual(c1.RawKey(), c.RawKey()), "session key reused")
+
+	// a step-up of another session fails, even with the password
+	c2, _ := login()
+	c3, err := c2.StepUp()
+	assert(err == nil, "StepUp: %s", err)
+	srv3, err := srv.StepUp(c3.xA)
+	assert(err == nil, "StepUp: %s", err)
+	assert(!authenticate(c3, srv3), "step-up of another session accepted")
+
+	// an ordinary login can't pose as a step-up
+	c4, _ := s.NewClient([]byte("user"), []byte("pass"))
+	srv4, err := srv.StepUp(c4.xA)
+	assert(err == nil, "StepUp: %s", err)
+	assert(!authenticate(c4, srv4), "login accepted as step-up")
+
+	// the audit transcripts of both sides still match
+	ca, err := c1.Audit()
+	assert(err == nil, "Audit: %s", err)
+	assert(ca == srv1.Audit(), "audit mismatch:\n%s\n%s", ca, srv1.Audit())
+}
@@ -0,0 +1,80 @@
+// storagekey.go - shortened identity keys for verifier databases
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"encoding/base32"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// The hashed identity that keys a verifier is as large as the hash (128
+// hex digits for SHA-512), which is too long for the indexes of some
+// databases. WithStorageKeyLen(n) makes the storage key of a verifier the
+// first n bytes of its identity in lower case base32 without padding,
+// e.g., 26 characters for n = 16.
+//
+// A shortened key may collide, and stores (see VerifierStore and package
+// srpfile) keep one verifier per key, so a collision would replace
+// another user's verifier. Keys are therefore at least 16 bytes, where
+// the chance is negligible (about 2^-64 among 2^32 users). A server looks
+// verifiers up by StorageKey() and starts the handshake with
+// NewServerFor(), which checks the full hashed identity sent by the
+// client against the one recorded in the verifier.
+
+// minimum size of a shortened storage key in bytes
+const minStorageKeyLen = 16
+
+var storageKeyEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// WithStorageKeyLen makes StorageKey() return the first 'n' bytes of the
+// identity of a verifier in base32 instead of the whole identity in hex.
+// 'n' must be at least 16 (see above).
+func WithStorageKeyLen(n int) Option {
+	return func(s *SRP) error {
+		if n < minStorageKeyLen {
+			return fmt.Errorf("srp: storage key must be at least %d bytes", minStorageKeyLen)
+		}
+		s.skLen = n
+		return nil
+	}
+}
+
+// StorageKey returns the key under which a server stores and looks up
+// this verifier. It is the identity returned by Encode() unless the
+// environment of the verifier shortens it (see WithStorageKeyLen()).
+func (v *Verifier) StorageKey() string {
+	n := 0
+	if v.env != nil {
+		n = v.env.skLen
+	}
+	return storageKey(v.i, n)
+}
+
+// StorageKey returns the key of the verifier of the client with hashed
+// identity 'ih' (as returned by ServerBegin()) in this environment: it is
+// blinded (see WithIdentityKey()) and shortened (see WithStorageKeyLen())
+// as the verifiers of the environment are.
+func (s *SRP) StorageKey(ih []byte) string {
+	if s.idk != nil {
+		ih = blindIdentity(s.idk, ih)
+	}
+	return storageKey(ih, s.skLen)
+}
+
+// return the storage key of the stored identity 'i' shortened to 'n'
+// bytes; 0 means the whole identity in hex
+func storageKey(i []byte, n int) string {
+	if n == 0 {
+		return hex.EncodeToString(i)
+	}
+	if n > len(i) {
+		n = len(i)
+	}
+	return strings.ToLower(storageKeyEncoding.EncodeToString(i[:n]))
+}
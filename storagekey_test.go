@@ -0,0 +1,69 @@
+// self test for shortened storage keys
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestStorageKey(t *testing.T) {
+	assert := newAsserter(t)
+
+	_, err := New(2048, WithStorageKeyLen(15))
+	assert(err != nil, "accepted short storage key")
+
+	// the default is the identity of Encode()
+	s, err := New(2048)
+	assert(err == nil, "New: %s", err)
+	v, err := s.Verifier([]byte("user"), []byte("pass"), nil)
+	assert(err == nil, "Verifier: %s", err)
+	ih, _ := v.Encode()
+	assert(v.StorageKey() == ih, "exp %s, saw %s", ih, v.StorageKey())
+
+	key := []byte("0123456789abcdef0123456789abcdef")
+	for _, opts := range [][]Option{
+		{WithStorageKeyLen(16)},
+		{WithStorageKeyLen(16), WithIdentityKey(key)},
+	} {
+		s, err := New(2048, opts...)
+		assert(err == nil, "New: %s", err)
+		v, err := s.Verifier([]byte("user"), []byte("pass"), nil)
+		assert(err == nil, "Verifier: %s", err)
+
+		sk := v.StorageKey()
+		assert(len(sk) == 26, "storage key %q isn't 26 characters", sk)
+
+		// the server finds the verifier from the client's hello
+		c, err := s.NewClient([]byte("user"), []byte("pass"))
+		assert(err == nil, "NewClient: %s", err)
+		cih, A, err := ServerBegin(c.Credentials())
+		assert(err == nil, "ServerBegin: %s", err)
+		ihb, _ := hex.DecodeString(cih)
+		assert(s.StorageKey(ihb) == sk, "exp %s, saw %s", sk, s.StorageKey(ihb))
+
+		// the handshake still checks the full identity
+		_, vh := v.Encode()
+		ss, sv, err := MakeSRPVerifier(vh, opts...)
+		assert(err == nil, "MakeSRPVerifier: %s", err)
+		assert(sv.StorageKey() == sk, "decoded verifier has key %s", sv.StorageKey())
+
+		// an identity that collides in the storage key (which blinding
+		// prevents)
+		other := append([]byte{}, ihb...)
+		other[len(other)-1] ^= 1
+		if len(opts) == 1 {
+			assert(ss.StorageKey(other) == sk, "key depends on the tail of the identity")
+		}
+		_, err = ss.NewServerFor(other, sv, A)
+		assert(err != nil, "colliding identity accepted")
+
+		srv, err := ss.NewServerFor(ihb, sv, A)
+		assert(err == nil, "NewServerFor: %s", err)
+		assert(authenticate(c, srv), "handshake failed")
+	}
+}
@@ -0,0 +1,61 @@
+// store.go - persistent stores of encoded verifiers
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"fmt"
+)
+
+// ErrNoVerifier is returned by a VerifierStore that has no verifier for a
+// key
+var ErrNoVerifier = fmt.Errorf("srp: no such verifier")
+
+// VerifierStore persists encoded verifiers (the second value returned by
+// Verifier.Encode()) under their storage keys (see Verifier.StorageKey()).
+// Implementations must be safe for concurrent use. Package srpfile has a
+// flat-file store.
+type VerifierStore interface {
+	// Get returns the encoded verifier stored under 'key' or ErrNoVerifier
+	Get(key string) (string, error)
+
+	// Put stores the encoded verifier 'vh' under 'key', replacing any
+	// previous one
+	Put(key, vh string) error
+
+	// Delete removes the verifier stored under 'key'; it returns
+	// ErrNoVerifier if there is none
+	Delete(key string) error
+}
+
+// StoreVerifier stores the verifier 'v' in 'st' under its storage key.
+func StoreVerifier(st VerifierStore, v *Verifier) error {
+	_, vh := v.Encode()
+	return st.Put(v.StorageKey(), vh)
+}
+
+// StoreLookup returns a VerifierLookup that finds verifiers in 'st'. The
+// options 'opts' are those given to MakeSRPVerifier(); they must include
+// the options that change storage keys (WithIdentityKey() and
+// WithStorageKeyLen()) if the verifiers were made with them. Users imported
+// by LegacyMigration and not yet migrated get ErrLegacyLogin.
+func StoreLookup(st VerifierStore, opts ...Option) (VerifierLookup, error) {
+	var keys SRP
+	if err := keys.apply(opts); err != nil {
+		return nil, err
+	}
+
+	return func(ih []byte) (*SRP, *Verifier, error) {
+		vh, err := st.Get(keys.StorageKey(ih))
+		if err != nil {
+			return nil, nil, err
+		}
+		if isLegacyRecord(vh) {
+			return nil, nil, ErrLegacyLogin
+		}
+		return MakeSRPVerifier(vh, opts...)
+	}, nil
+}
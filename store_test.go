@@ -0,0 +1,86 @@
+// self test for verifier stores
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"sync"
+	"testing"
+)
+
+// in-memory VerifierStore
+type mapStore struct {
+	sync.Mutex
+	m map[string]string
+}
+
+func (ms *mapStore) Get(key string) (string, error) {
+	ms.Lock()
+	defer ms.Unlock()
+	vh, ok := ms.m[key]
+	if !ok {
+		return "", ErrNoVerifier
+	}
+	return vh, nil
+}
+
+func (ms *mapStore) Put(key, vh string) error {
+	ms.Lock()
+	defer ms.Unlock()
+	ms.m[key] = vh
+	return nil
+}
+
+func (ms *mapStore) Delete(key string) error {
+	ms.Lock()
+	defer ms.Unlock()
+	if _, ok := ms.m[key]; !ok {
+		return ErrNoVerifier
+	}
+	delete(ms.m, key)
+	return nil
+}
+
+func TestStoreLookup(t *testing.T) {
+	assert := newAsserter(t)
+
+	for _, opts := range [][]Option{
+		nil,
+		{WithStorageKeyLen(16)},
+		{WithIdentityKey([]byte("0123456789abcdef0123456789abcdef")), WithStorageKeyLen(16)},
+	} {
+		s, err := New(2048, opts...)
+		assert(err == nil, "New: %s", err)
+
+		st := &mapStore{m: map[string]string{}}
+		for _, u := range []string{"alice", "bob"} {
+			v, err := s.Verifier([]byte(u), []byte(u+"-pass"), nil)
+			assert(err == nil, "Verifier: %s", err)
+			assert(StoreVerifier(st, v) == nil, "StoreVerifier failed")
+		}
+		assert(len(st.m) == 2, "expected 2 verifiers, have %d", len(st.m))
+
+		lookup, err := StoreLookup(st, opts...)
+		assert(err == nil, "StoreLookup: %s", err)
+
+		c, err := s.NewClient([]byte("bob"), []byte("bob-pass"))
+		assert(err == nil, "NewClient: %s", err)
+		ih := c.Hello().IdentityHash
+
+		vs, v, err := lookup(ih)
+		assert(err == nil, "lookup: %s", err)
+		srv, err := vs.NewServerFor(ih, v, c.xA)
+		assert(err == nil, "NewServerFor: %s", err)
+		assert(authenticate(c, srv), "login failed")
+
+		c, _ = s.NewClient([]byte("carol"), []byte("carol-pass"))
+		_, _, err = lookup(c.Hello().IdentityHash)
+		assert(err == ErrNoVerifier, "unknown user: %v", err)
+	}
+
+	_, err := StoreLookup(&mapStore{}, WithStorageKeyLen(2))
+	assert(err != nil, "bad option accepted")
+}
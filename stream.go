@@ -0,0 +1,114 @@
+// stream.go - handshake messages over an io.Reader/io.Writer
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// The helpers below exchange the handshake messages over a stream such as
+// a net.Conn. Each message is a frame: a 2 byte big-endian length followed
+// by the CBOR encoding of the message (see cbor.go). A handshake is:
+//
+//	client: c.WriteHello(conn)
+//	server: cc, _ := ReadHello(conn)
+//	        ... lookup the verifier for cc.IdentityHash, create srv ...
+//	        srv.WriteChallenge(conn)
+//	client: sc, _ := ReadChallenge(conn)
+//	        m, _ := c.Respond(sc)
+//	        WriteProof(conn, m)
+//	server: m, _ := ReadProof(conn)
+//	        proof, ok := srv.CheckProof(m)
+//	        WriteProof(conn, proof)
+//	client: proof, _ := ReadProof(conn)
+//	        ok := c.CheckProof(proof)
+//
+// Handshake() (see handshake.go) runs either side in a single call.
+
+// largest frame we accept; it holds the largest server credentials
+const maxFrameLen = 8192
+
+// WriteHello writes the client credentials (see Hello()) to 'w'
+func (c *Client) WriteHello(w io.Writer) error {
+	cc := c.Hello()
+	return writeFrame(w, cc.EncodeCBOR())
+}
+
+// ReadHello reads the client credentials written by Client.WriteHello()
+func ReadHello(r io.Reader) (ClientCredentials, error) {
+	b, err := readFrame(r)
+	if err != nil {
+		return ClientCredentials{}, err
+	}
+	return DecodeClientCredentialsCBOR(b)
+}
+
+// WriteChallenge writes the server credentials (see Challenge()) to 'w'
+func (s *Server) WriteChallenge(w io.Writer) error {
+	sc := s.Challenge()
+	return writeFrame(w, sc.EncodeCBOR())
+}
+
+// ReadChallenge reads the server credentials written by
+// Server.WriteChallenge()
+func ReadChallenge(r io.Reader) (ServerCredentials, error) {
+	b, err := readFrame(r)
+	if err != nil {
+		return ServerCredentials{}, err
+	}
+	return DecodeServerCredentialsCBOR(b)
+}
+
+// WriteProof writes a client or server proof to 'w'
+func WriteProof(w io.Writer, proof []byte) error {
+	return writeFrame(w, EncodeProofCBOR(proof))
+}
+
+// ReadProof reads a proof written by WriteProof()
+func ReadProof(r io.Reader) ([]byte, error) {
+	b, err := readFrame(r)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeProofCBOR(b)
+}
+
+// write 'b' as a single frame
+func writeFrame(w io.Writer, b []byte) error {
+	if len(b) > maxFrameLen {
+		return fmt.Errorf("srp: message too large (%d bytes)", len(b))
+	}
+
+	f := make([]byte, 2+len(b))
+	binary.BigEndian.PutUint16(f, uint16(len(b)))
+	copy(f[2:], b)
+	if _, err := w.Write(f); err != nil {
+		return fmt.Errorf("srp: write: %s", err)
+	}
+	return nil
+}
+
+// read a single frame
+func readFrame(r io.Reader) ([]byte, error) {
+	var hdr [2]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, fmt.Errorf("srp: read: %s", err)
+	}
+
+	n := int(binary.BigEndian.Uint16(hdr[:]))
+	if n > maxFrameLen {
+		return nil, fmt.Errorf("srp: message too large (%d bytes)", n)
+	}
+
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, fmt.Errorf("srp: read: %s", err)
+	}
+	return b, nil
+}
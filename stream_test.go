@@ -0,0 +1,97 @@
+// self test for the stream helpers
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"bytes"
+	"math/big"
+	"net"
+	"testing"
+)
+
+func TestStream(t *testing.T) {
+	assert := newAsserter(t)
+
+	user := []byte("user")
+	pass := []byte("pass")
+
+	s, err := New(2048)
+	assert(err == nil, "New: %s", err)
+
+	v, err := s.Verifier(user, pass, nil)
+	assert(err == nil, "Verifier: %s", err)
+
+	cc, sc := net.Pipe()
+	defer cc.Close()
+
+	// server
+	done := make(chan error, 1)
+	go func() {
+		defer sc.Close()
+
+		hello, err := ReadHello(sc)
+		if err != nil {
+			done <- err
+			return
+		}
+
+		srv, err := s.NewServer(v, big.NewInt(0).SetBytes(hello.A))
+		if err != nil {
+			done <- err
+			return
+		}
+
+		if err = srv.WriteChallenge(sc); err != nil {
+			done <- err
+			return
+		}
+
+		m, err := ReadProof(sc)
+		if err != nil {
+			done <- err
+			return
+		}
+
+		proof, _ := srv.CheckProof(m)
+		done <- WriteProof(sc, proof)
+	}()
+
+	c, err := s.NewClient(user, pass)
+	assert(err == nil, "NewClient: %s", err)
+
+	assert(c.WriteHello(cc) == nil, "WriteHello failed")
+
+	ch, err := ReadChallenge(cc)
+	assert(err == nil, "ReadChallenge: %s", err)
+
+	m, err := c.Respond(ch)
+	assert(err == nil, "Respond: %s", err)
+	assert(WriteProof(cc, m) == nil, "WriteProof failed")
+
+	proof, err := ReadProof(cc)
+	assert(err == nil, "ReadProof: %s", err)
+	assert(c.CheckProof(proof), "bad server proof")
+
+	err = <-done
+	assert(err == nil, "server: %s", err)
+}
+
+func TestStreamMalformed(t *testing.T) {
+	assert := newAsserter(t)
+
+	bad := [][]byte{
+		{},
+		{0x00},
+		{0x00, 0x05, 0x01},
+		{0xff, 0xff},
+	}
+
+	for i, b := range bad {
+		_, err := ReadHello(bytes.NewReader(b))
+		assert(err != nil, "%d: accepted malformed frame", i)
+	}
+}
@@ -0,0 +1,39 @@
+// strict.go - turning protocol misuse into panics
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+// The session key of a handshake must not be used before the peer has
+// proved that it knows the same key: a client that encrypts with RawKey()
+// before checking the server's proof talks to whoever answered. Such
+// bugs go unnoticed because the handshake works with honest peers. In
+// StrictMode() clients and servers panic on these misuses:
+//
+//   - RawKey(), SessionKeys(), Seal() and Open() before the peer's proof
+//     was verified (CheckProof(), ClientOk() or ServerOk() succeeded);
+//   - a second Credentials() (or Challenge()) of a server, which would
+//     send the same B to two handshakes.
+//
+// The methods that return errors return them as before when strict mode is
+// off. Strict mode is meant for development and tests; a server restored
+// with UnmarshalServer() doesn't know whether its challenge was sent.
+
+// StrictMode makes clients and servers in this environment panic when the
+// application misuses a handshake (see above).
+func StrictMode() Option {
+	return func(s *SRP) error {
+		s.strict = true
+		return nil
+	}
+}
+
+// panic if this environment is in strict mode and 'bad' is true; 'what'
+// describes the misuse
+func (s *SRP) misuse(bad bool, what string) {
+	if bad && s.strict {
+		panic("srp: strict mode: " + what)
+	}
+}
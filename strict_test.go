@@ -0,0 +1,82 @@
+// self test for StrictMode()
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"testing"
+)
+
+// return true if 'fn' panics
+func panics(fn func()) (p bool) {
+	defer func() {
+		p = recover() != nil
+	}()
+	fn()
+	return false
+}
+
+func TestStrictMode(t *testing.T) {
+	assert := newAsserter(t)
+
+	s, err := New(2048, StrictMode())
+	assert(err == nil, "New: %s", err)
+
+	v, err := s.Verifier([]byte("user"), []byte("pass"), nil)
+	assert(err == nil, "Verifier: %s", err)
+
+	c, err := s.NewClient([]byte("user"), []byte("pass"))
+	assert(err == nil, "NewClient: %s", err)
+	srv, err := s.NewServer(v, c.xA)
+	assert(err == nil, "NewServer: %s", err)
+
+	sc := srv.Challenge()
+	assert(panics(func() { srv.Credentials() }), "second challenge allowed")
+
+	m, err := c.Respond(sc)
+	assert(err == nil, "Respond: %s", err)
+
+	// neither side has verified its peer
+	misuses := map[string]func(){
+		"Client.RawKey":      func() { c.RawKey() },
+		"Client.SessionKeys": func() { c.SessionKeys() },
+		"Client.Seal":        func() { c.Seal([]byte("x"), nil) },
+		"Client.Open":        func() { c.Open([]byte("x"), nil) },
+		"Server.RawKey":      func() { srv.RawKey() },
+		"Server.SessionKeys": func() { srv.SessionKeys() },
+		"Server.Seal":        func() { srv.Seal([]byte("x"), nil) },
+		"Server.Open":        func() { srv.Open([]byte("x"), nil) },
+	}
+	for name, fn := range misuses {
+		assert(panics(fn), "%s before the proofs didn't panic", name)
+	}
+
+	proof, ok := srv.CheckProof(m)
+	assert(ok, "server rejected the client")
+	assert(!panics(func() { srv.RawKey() }), "server key after the client's proof panics")
+	assert(panics(func() { c.RawKey() }), "client key before the server's proof")
+
+	assert(c.CheckProof(proof), "client rejected the server")
+	assert(!panics(func() { c.RawKey() }), "client key after the server's proof panics")
+
+	ct, err := c.Seal([]byte("hello"), nil)
+	assert(err == nil, "Seal: %s", err)
+	pt, err := srv.Open(ct, nil)
+	assert(err == nil && string(pt) == "hello", "Open: %s", err)
+
+	// without strict mode the same misuses don't panic
+	ns, err := New(2048)
+	assert(err == nil, "New: %s", err)
+	c, err = ns.NewClient([]byte("user"), []byte("pass"))
+	assert(err == nil, "NewClient: %s", err)
+	srv, err = ns.NewServer(v, c.xA)
+	assert(err == nil, "NewServer: %s", err)
+	srv.Challenge()
+	assert(!panics(func() { srv.Credentials() }), "second challenge panics")
+	assert(!panics(func() { c.RawKey() }), "RawKey panics")
+	_, err = c.SessionKeys()
+	assert(err != nil, "session keys before the proofs")
+}
@@ -0,0 +1,267 @@
+// tarpit.go - slowing down password guessing per identity
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Each handshake lets a client test one password guess, so a server must
+// bound the rate of handshakes per user. A Tarpit is consulted before a
+// handshake starts (before NewServer()) and told how it ended:
+//
+//   - a token bucket allows a burst of attempts and then a steady rate;
+//   - after a number of consecutive failures each further failure doubles
+//     the time until the next attempt, up to a maximum; a success resets
+//     it.
+//
+// Attempts are keyed by the hashed identity and a source tag chosen by the
+// server: the client's address (or network) bounds the guesses of each
+// source, while an empty tag bounds all guesses at an identity. Unknown
+// identities must be counted like known ones, or the tarpit tells them
+// apart. ServerHandshake() uses the tarpit in HandshakeConfig.
+
+// Tarpit tracks login attempts. Implementations must be safe for
+// concurrent use; servers in a cluster should share one (e.g., backed by
+// Redis) so that attackers can't spread their guesses over the servers.
+type Tarpit interface {
+	// Allow returns 0 if an attempt by the identity 'ih' from 'source' may
+	// proceed (and counts it) or how long the client must wait
+	Allow(ih []byte, source string) (time.Duration, error)
+
+	// Failure records a failed attempt
+	Failure(ih []byte, source string) error
+
+	// Success records a successful attempt
+	Success(ih []byte, source string) error
+}
+
+// ErrTarpit is matched (with errors.Is()) by the errors of attempts
+// refused by a Tarpit; errors.As() with a *TarpitError yields the wait.
+var ErrTarpit = fmt.Errorf("srp: too many login attempts")
+
+// TarpitError is the error of an attempt refused by a Tarpit
+type TarpitError struct {
+	Wait time.Duration // until the next attempt is allowed
+}
+
+// Error implements error
+func (e *TarpitError) Error() string {
+	return fmt.Sprintf("%s; retry in %s", ErrTarpit, e.Wait)
+}
+
+// Is returns true if 'target' is ErrTarpit
+func (e *TarpitError) Is(target error) bool {
+	return target == ErrTarpit
+}
+
+// CheckTarpit returns a *TarpitError if 'tp' doesn't allow an attempt by
+// the identity 'ih' from 'source' now.
+func CheckTarpit(tp Tarpit, ih []byte, source string) error {
+	wait, err := tp.Allow(ih, source)
+	if err != nil {
+		return err
+	}
+	if wait > 0 {
+		return &TarpitError{Wait: wait}
+	}
+	return nil
+}
+
+// TarpitConfig configures a MemoryTarpit; zero fields take the defaults
+type TarpitConfig struct {
+	Burst int           // attempts allowed at once; default 10
+	Rate  time.Duration // time to regain one attempt; default 6s
+	Free  int           // consecutive failures before the backoff; default 3
+	Base  time.Duration // the first backoff; default 1s
+	Max   time.Duration // the largest backoff; default 15m
+	Keys  int           // identities and sources tracked; default 100000
+}
+
+// MemoryTarpit is an in-process Tarpit. When it tracks as many keys as
+// configured, keys whose bucket is full and whose backoff has passed are
+// forgotten; if none can be, the key whose backoff ends first makes room
+// for the new one. New keys are thus never refused: an attacker who fills
+// the tarpit with keys of their own loses them first, and can at worst
+// cut short the backoff closest to its end.
+type MemoryTarpit struct {
+	mu  sync.Mutex
+	cfg TarpitConfig
+	m   map[string]*tarpitEntry
+
+	now func() time.Time // for tests
+}
+
+type tarpitEntry struct {
+	tokens float64   // in the bucket at 'at'
+	at     time.Time // of the last refill
+	fails  int       // consecutive failures
+	until  time.Time // end of the backoff
+}
+
+// NewMemoryTarpit creates a Tarpit with the configuration 'cfg'
+func NewMemoryTarpit(cfg TarpitConfig) *MemoryTarpit {
+	if cfg.Burst <= 0 {
+		cfg.Burst = 10
+	}
+	if cfg.Rate <= 0 {
+		cfg.Rate = 6 * time.Second
+	}
+	if cfg.Free <= 0 {
+		cfg.Free = 3
+	}
+	if cfg.Base <= 0 {
+		cfg.Base = time.Second
+	}
+	if cfg.Max <= 0 {
+		cfg.Max = 15 * time.Minute
+	}
+	if cfg.Keys <= 0 {
+		cfg.Keys = 100000
+	}
+	return &MemoryTarpit{
+		cfg: cfg,
+		m:   make(map[string]*tarpitEntry),
+		now: time.Now,
+	}
+}
+
+// Allow implements Tarpit
+func (tp *MemoryTarpit) Allow(ih []byte, source string) (time.Duration, error) {
+	now := tp.now()
+
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+
+	e := tp.entry(tarpitKey(ih, source), now)
+	if now.Before(e.until) {
+		return e.until.Sub(now), nil
+	}
+
+	tp.refill(e, now)
+	if e.tokens < 1 {
+		return time.Duration((1 - e.tokens) * float64(tp.cfg.Rate)), nil
+	}
+	e.tokens--
+	return 0, nil
+}
+
+// Failure implements Tarpit
+func (tp *MemoryTarpit) Failure(ih []byte, source string) error {
+	now := tp.now()
+
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+
+	e := tp.entry(tarpitKey(ih, source), now)
+	e.fails++
+	if n := e.fails - tp.cfg.Free; n > 0 {
+		d := tp.cfg.Max
+		if n <= 32 {
+			if b := tp.cfg.Base << uint(n-1); b > 0 && b < d {
+				d = b
+			}
+		}
+		e.until = now.Add(d)
+	}
+	return nil
+}
+
+// Success implements Tarpit
+func (tp *MemoryTarpit) Success(ih []byte, source string) error {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+
+	if e, ok := tp.m[tarpitKey(ih, source)]; ok {
+		e.fails = 0
+		e.until = time.Time{}
+	}
+	return nil
+}
+
+// Len returns the number of keys tracked
+func (tp *MemoryTarpit) Len() int {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	return len(tp.m)
+}
+
+// return the entry of 'key', creating it if needed and making room for it
+// if the tarpit is full. The caller holds tp.mu.
+func (tp *MemoryTarpit) entry(key string, now time.Time) *tarpitEntry {
+	if e, ok := tp.m[key]; ok {
+		return e
+	}
+
+	if len(tp.m) >= tp.cfg.Keys {
+		tp.expire(now)
+		if len(tp.m) >= tp.cfg.Keys {
+			tp.evict()
+		}
+	}
+
+	e := &tarpitEntry{tokens: float64(tp.cfg.Burst), at: now}
+	tp.m[key] = e
+	return e
+}
+
+// forget the entries that are back to their initial state; the caller
+// holds tp.mu
+func (tp *MemoryTarpit) expire(now time.Time) {
+	for k, e := range tp.m {
+		if now.Before(e.until) {
+			continue
+		}
+		tp.refill(e, now)
+		if e.tokens >= float64(tp.cfg.Burst) {
+			delete(tp.m, k)
+		}
+	}
+}
+
+// forget the entry whose backoff ends first; of those without a backoff,
+// the one with the fewest failures and then the fullest bucket. The caller
+// holds tp.mu.
+func (tp *MemoryTarpit) evict() {
+	var victim string
+	var v *tarpitEntry
+	for k, e := range tp.m {
+		if v == nil || evictsBefore(e, v) {
+			victim, v = k, e
+		}
+	}
+	delete(tp.m, victim)
+}
+
+// return true if 'a' is evicted before 'b'
+func evictsBefore(a, b *tarpitEntry) bool {
+	switch {
+	case !a.until.Equal(b.until):
+		return a.until.Before(b.until)
+	case a.fails != b.fails:
+		return a.fails < b.fails
+	}
+	return a.tokens > b.tokens
+}
+
+// refill the bucket of 'e' for the time since its last refill
+func (tp *MemoryTarpit) refill(e *tarpitEntry, now time.Time) {
+	if d := now.Sub(e.at); d > 0 {
+		e.tokens += float64(d) / float64(tp.cfg.Rate)
+		if max := float64(tp.cfg.Burst); e.tokens > max {
+			e.tokens = max
+		}
+	}
+	e.at = now
+}
+
+// return the key of the identity 'ih' and 'source'
+func tarpitKey(ih []byte, source string) string {
+	return source + "\x00" + string(ih)
+}
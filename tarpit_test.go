@@ -0,0 +1,144 @@
+// self test for the login tarpit
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestMemoryTarpit(t *testing.T) {
+	assert := newAsserter(t)
+
+	now := time.Unix(1000, 0)
+	tp := NewMemoryTarpit(TarpitConfig{
+		Burst: 3,
+		Rate:  10 * time.Second,
+		Free:  2,
+		Base:  time.Second,
+		Max:   4 * time.Second,
+		Keys:  2,
+	})
+	tp.now = func() time.Time { return now }
+
+	ih := []byte("identity")
+	allow := func(src string) time.Duration {
+		d, err := tp.Allow(ih, src)
+		assert(err == nil, "Allow: %s", err)
+		return d
+	}
+
+	// the bucket
+	for i := 0; i < 3; i++ {
+		assert(allow("a") == 0, "attempt %d refused", i)
+	}
+	assert(allow("a") == 10*time.Second, "empty bucket allowed")
+	assert(allow("b") == 0, "sources share a bucket")
+	now = now.Add(5 * time.Second)
+	assert(allow("a") == 5*time.Second, "partial refill")
+	now = now.Add(5 * time.Second)
+	assert(allow("a") == 0, "refilled attempt refused")
+
+	// the backoff doubles up to the maximum and resets on success
+	now = now.Add(time.Minute)
+	for i, exp := range []time.Duration{0, 0, time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second} {
+		assert(tp.Failure(ih, "a") == nil, "Failure failed")
+		assert(allow("a") == exp, "failure %d: exp %s wait", i+1, exp)
+		now = now.Add(exp)
+		if exp > 0 {
+			assert(allow("a") == 0, "failure %d: backoff didn't end", i+1)
+		}
+		now = now.Add(time.Minute)
+	}
+	assert(tp.Success(ih, "a") == nil, "Success failed")
+	tp.Failure(ih, "a")
+	assert(allow("a") == 0, "backoff after success")
+
+	// a full tarpit forgets idle keys
+	assert(tp.Len() == 2, "exp 2 keys, saw %d", tp.Len())
+	now = now.Add(time.Hour)
+	assert(allow("c") == 0, "full tarpit refused a new key")
+	assert(tp.Len() == 1, "idle keys not forgotten: %d keys", tp.Len())
+
+	// ... and makes room for new keys by evicting the key with the least
+	// backoff, so junk keys don't lock out users or reset backoffs
+	for i := 0; i < 4; i++ {
+		tp.Failure(ih, "c")
+	}
+	wait := allow("c")
+	assert(wait == 2*time.Second, "exp a 2s backoff, saw %s", wait)
+	for i := 0; i < 10; i++ {
+		assert(allow(fmt.Sprintf("junk%d", i)) == 0, "junk key %d refused", i)
+	}
+	assert(tp.Len() == 2, "exp 2 keys, saw %d", tp.Len())
+	assert(allow("c") == wait, "backoff evicted by junk keys")
+
+	// failures of keys the tarpit doesn't track are recorded
+	assert(tp.Failure(ih, "d") == nil, "Failure failed")
+	assert(tp.Failure(ih, "d") == nil, "Failure failed")
+	assert(tp.Failure(ih, "d") == nil, "Failure failed")
+	assert(allow("d") == time.Second, "failures of a new key dropped")
+	assert(allow("c") == wait, "backoff evicted by a shorter one")
+}
+
+func TestTarpitHandshake(t *testing.T) {
+	assert := newAsserter(t)
+
+	s, err := New(2048)
+	assert(err == nil, "New: %s", err)
+	v, err := s.Verifier([]byte("user"), []byte("pass"), nil)
+	assert(err == nil, "Verifier: %s", err)
+	lookup := func(ih []byte) (*SRP, *Verifier, error) {
+		return s, v, nil
+	}
+
+	var reasons []FailureReason
+	tp := NewMemoryTarpit(TarpitConfig{Free: 1})
+	cfg := &HandshakeConfig{
+		Tarpit:    tp,
+		OnFailure: func(r FailureReason, err error) { reasons = append(reasons, r) },
+	}
+
+	login := func(pw string) error {
+		cc, sc := net.Pipe()
+		done := make(chan error, 1)
+		go func() {
+			_, err := ServerHandshake(context.Background(), sc, lookup, cfg)
+			sc.Close()
+			done <- err
+		}()
+		c, err := s.NewClient([]byte("user"), []byte(pw))
+		assert(err == nil, "NewClient: %s", err)
+		c.Handshake(context.Background(), cc, nil)
+		cc.Close()
+		return <-done
+	}
+
+	assert(login("pass") == nil, "login failed")
+	assert(login("wrong") != nil, "bad password accepted")
+	assert(login("wrong") != nil, "bad password accepted")
+	err = login("wrong")
+	assert(errors.Is(err, ErrTarpit), "exp ErrTarpit, saw %v", err)
+
+	var te *TarpitError
+	assert(errors.As(err, &te) && te.Wait > 0, "no wait in %v", err)
+	exp := []FailureReason{FailureBadProof, FailureBadProof, FailureRateLimited}
+	assert(fmt.Sprint(reasons) == fmt.Sprint(exp), "exp %v, saw %v", exp, reasons)
+
+	// the failures also count against the identity from any source
+	c, err := s.NewClient([]byte("user"), []byte("pass"))
+	assert(err == nil, "NewClient: %s", err)
+	ih := c.Hello().IdentityHash
+	wait, err := tp.Allow(ih, "elsewhere")
+	assert(err == nil && wait == 0, "new source refused: %v %s", err, wait)
+	wait, err = tp.Allow(ih, "")
+	assert(err == nil && wait > 0, "identity-wide key not backed off")
+}
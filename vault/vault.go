@@ -0,0 +1,223 @@
+// vault.go - master keys wrapped under a password-derived key
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+// Package vault registers users with SRP and wraps a random master key
+// for their data under a key derived from the same password, as password
+// managers and end-to-end encrypted services do. The server stores the SRP
+// verifier and the wrapped key; it can authenticate the user but learns
+// neither the password nor the master key.
+//
+// Authentication and encryption use separate secrets. SRP derives its
+// private key x from the password with the salt of the verifier. The key
+// encryption key (KEK) is derived with its own random salt and a distinct
+// label:
+//
+//	x   = H(I, KDF(H(p), s), s)                        (package srp)
+//	KEK = HKDF(KDF(p, s'), "go-srp vault kek")
+//	W   = XChaCha20-Poly1305(KEK, master key)
+//
+// Neither the verifier nor anything the server sees during a login (A, M,
+// the session key K) is related to the KEK. The master key is random and
+// survives password changes: Rewrap() makes a new verifier and wraps the
+// same key under the new password.
+//
+// A login is:
+//
+//	client: c, _ := env.NewClient(I, p); send c.Credentials()
+//	server: look up the Record, authenticate as usual
+//	server: ct, _ := srv.Seal([]byte(rec.Key.String()), nil); send ct
+//	client: b, _ := c.Open(ct, nil)
+//	        wk, _ := ParseWrappedKey(string(b))
+//	        mk, _ := wk.Unwrap(p)
+//
+// Anyone who steals the stored records can mount an offline guessing
+// attack on the verifier or on the wrapped key; both should be hardened
+// with a memory-hard KDF (see srp.WithKDF()).
+package vault
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/tomsons/go-srp"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+)
+
+// MasterKeyLen is the size of master keys in bytes
+const MasterKeyLen = 32
+
+// size of the salt of the KEK in bytes
+const saltLen = 16
+
+// version of the wrapped key encoding
+const wrapVersion = "v1"
+
+var (
+	kekLabel = []byte("go-srp vault kek")
+	wrapAD   = []byte("go-srp vault " + wrapVersion)
+)
+
+// ErrUnwrap is returned when a wrapped key can't be opened, i.e., the
+// password is wrong or the wrapped key was modified.
+var ErrUnwrap = errors.New("vault: can't unwrap master key")
+
+// DefaultKDF hardens the KEK with Argon2id at the cost recommended by
+// RFC 9106 for memory-constrained environments.
+var DefaultKDF = srp.KDF{
+	Alg:     srp.KDFArgon2id,
+	Time:    3,
+	Memory:  64 * 1024,
+	Threads: 4,
+}
+
+// Record is what the server stores for a user
+type Record struct {
+	Identity string     // hashed identity (see srp.Verifier.Encode())
+	Verifier string     // encoded SRP verifier
+	Key      WrappedKey // master key wrapped under the password
+}
+
+// WrappedKey is a master key encrypted under a key derived from the
+// password. It is sent to the client after a login.
+type WrappedKey struct {
+	KDF  srp.KDF
+	Salt []byte
+	Data []byte // nonce | ciphertext | tag
+}
+
+// Register creates the record of a new user with identity 'I' and password
+// 'p' in the SRP environment 's' and returns it with the user's new master
+// key. The KEK is hardened with 'k'.
+func Register(s *srp.SRP, I, p []byte, k srp.KDF) (*Record, []byte, error) {
+	mk := make([]byte, MasterKeyLen)
+	if _, err := io.ReadFull(rand.Reader, mk); err != nil {
+		return nil, nil, fmt.Errorf("vault: can't read random bytes: %w", err)
+	}
+
+	r, err := Rewrap(s, I, p, mk, k)
+	if err != nil {
+		return nil, nil, err
+	}
+	return r, mk, nil
+}
+
+// Rewrap creates a new record for the user with identity 'I' that keeps
+// the master key 'mk' under the new password 'p', e.g., after the user
+// changed it (or recovered their account). The previous record must be
+// replaced.
+func Rewrap(s *srp.SRP, I, p, mk []byte, k srp.KDF) (*Record, error) {
+	if len(mk) != MasterKeyLen {
+		return nil, fmt.Errorf("vault: master key must be %d bytes", MasterKeyLen)
+	}
+
+	v, err := s.Verifier(I, p, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	wk, err := wrap(p, mk, k)
+	if err != nil {
+		return nil, err
+	}
+
+	ih, vh := v.Encode()
+	return &Record{Identity: ih, Verifier: vh, Key: *wk}, nil
+}
+
+// Unwrap returns the master key wrapped under the password 'p'
+func (w *WrappedKey) Unwrap(p []byte) ([]byte, error) {
+	aead, err := kek(p, w.Salt, w.KDF)
+	if err != nil {
+		return nil, err
+	}
+
+	ns := aead.NonceSize()
+	if len(w.Data) < ns+aead.Overhead() {
+		return nil, ErrUnwrap
+	}
+
+	mk, err := aead.Open(nil, w.Data[:ns], w.Data[ns:], wrapAD)
+	if err != nil {
+		return nil, ErrUnwrap
+	}
+	return mk, nil
+}
+
+// String returns the portable encoding of the wrapped key:
+//
+//	v1:<kdf>:<hex salt>:<hex data>
+func (w *WrappedKey) String() string {
+	return strings.Join([]string{wrapVersion, w.KDF.String(), hex.EncodeToString(w.Salt), hex.EncodeToString(w.Data)}, ":")
+}
+
+// ParseWrappedKey decodes a wrapped key encoded by WrappedKey.String(). The
+// KDF parameters are validated as in srp.WithKDF(), which bounds the work a
+// malicious server can make a client do.
+func ParseWrappedKey(s string) (*WrappedKey, error) {
+	v := strings.Split(s, ":")
+	if len(v) != 4 || v[0] != wrapVersion {
+		return nil, fmt.Errorf("vault: malformed wrapped key")
+	}
+
+	var w WrappedKey
+	if err := w.KDF.UnmarshalText([]byte(v[1])); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if w.Salt, err = hex.DecodeString(v[2]); err != nil || len(w.Salt) == 0 {
+		return nil, fmt.Errorf("vault: malformed salt")
+	}
+	if w.Data, err = hex.DecodeString(v[3]); err != nil {
+		return nil, fmt.Errorf("vault: malformed wrapped key data")
+	}
+	return &w, nil
+}
+
+// wrap 'mk' under the KEK derived from 'p' and a new salt
+func wrap(p, mk []byte, k srp.KDF) (*WrappedKey, error) {
+	salt := make([]byte, saltLen)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, fmt.Errorf("vault: can't read random bytes: %w", err)
+	}
+
+	aead, err := kek(p, salt, k)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(mk)+aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("vault: can't read random bytes: %w", err)
+	}
+
+	return &WrappedKey{
+		KDF:  k,
+		Salt: salt,
+		Data: aead.Seal(nonce, nonce, mk, wrapAD),
+	}, nil
+}
+
+// return the AEAD keyed with the KEK of password 'p'
+func kek(p, salt []byte, k srp.KDF) (cipher.AEAD, error) {
+	hp, err := k.Derive(p, salt, chacha20poly1305.KeySize)
+	if err != nil {
+		return nil, err
+	}
+
+	key := make([]byte, chacha20poly1305.KeySize)
+	if _, err := io.ReadFull(hkdf.Expand(sha256.New, hp, kekLabel), key); err != nil {
+		return nil, fmt.Errorf("vault: hkdf: %w", err)
+	}
+	return chacha20poly1305.NewX(key)
+}
@@ -0,0 +1,148 @@
+// self test for wrapped master keys
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package vault
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/tomsons/go-srp"
+)
+
+// cheap parameters for tests
+var testKDF = srp.KDF{
+	Alg:     srp.KDFArgon2id,
+	Time:    1,
+	Memory:  64,
+	Threads: 1,
+}
+
+// run a login of 'I' with password 'p' against 'rec' and return the
+// master key the client unwrapped
+func login(t *testing.T, rec *Record, I, p []byte) ([]byte, error) {
+	ss, sv, err := srp.MakeSRPVerifier(rec.Verifier)
+	if err != nil {
+		t.Fatalf("MakeSRPVerifier: %s", err)
+	}
+
+	c, err := ss.NewClient(I, p)
+	if err != nil {
+		t.Fatalf("NewClient: %s", err)
+	}
+	_, A, err := srp.ServerBegin(c.Credentials())
+	if err != nil {
+		t.Fatalf("ServerBegin: %s", err)
+	}
+	srv, err := ss.NewServer(sv, A)
+	if err != nil {
+		t.Fatalf("NewServer: %s", err)
+	}
+	m, err := c.Generate(srv.Credentials())
+	if err != nil {
+		t.Fatalf("Generate: %s", err)
+	}
+	proof, ok := srv.ClientOk(m)
+	if !ok || !c.ServerOk(proof) {
+		return nil, ErrUnwrap
+	}
+
+	ct, err := srv.Seal([]byte(rec.Key.String()), nil)
+	if err != nil {
+		t.Fatalf("Seal: %s", err)
+	}
+	b, err := c.Open(ct, nil)
+	if err != nil {
+		t.Fatalf("Open: %s", err)
+	}
+	wk, err := ParseWrappedKey(string(b))
+	if err != nil {
+		t.Fatalf("ParseWrappedKey: %s", err)
+	}
+	return wk.Unwrap(p)
+}
+
+func TestVault(t *testing.T) {
+	user := []byte("user")
+	pass := []byte("pass")
+
+	s, err := srp.New(2048)
+	if err != nil {
+		t.Fatalf("New: %s", err)
+	}
+
+	rec, mk, err := Register(s, user, pass, testKDF)
+	if err != nil {
+		t.Fatalf("Register: %s", err)
+	}
+	if len(mk) != MasterKeyLen {
+		t.Fatalf("master key is %d bytes", len(mk))
+	}
+
+	k, err := login(t, rec, user, pass)
+	if err != nil || !bytes.Equal(k, mk) {
+		t.Fatalf("login: wrong master key (%v)", err)
+	}
+
+	// the wrapped key alone doesn't open with another password
+	if _, err := rec.Key.Unwrap([]byte("guess")); err != ErrUnwrap {
+		t.Fatalf("Unwrap: exp ErrUnwrap, saw %v", err)
+	}
+
+	// a new password keeps the master key
+	rec2, err := Rewrap(s, user, []byte("new pass"), mk, testKDF)
+	if err != nil {
+		t.Fatalf("Rewrap: %s", err)
+	}
+	if bytes.Equal(rec2.Key.Salt, rec.Key.Salt) {
+		t.Fatalf("salt reused")
+	}
+	if _, err := login(t, rec2, user, pass); err == nil {
+		t.Fatalf("old password accepted")
+	}
+	k, err = login(t, rec2, user, []byte("new pass"))
+	if err != nil || !bytes.Equal(k, mk) {
+		t.Fatalf("login after Rewrap: wrong master key (%v)", err)
+	}
+
+	// tampering is detected
+	wk := rec.Key
+	wk.Data = append([]byte{}, wk.Data...)
+	wk.Data[len(wk.Data)-1] ^= 1
+	if _, err := wk.Unwrap(pass); err != ErrUnwrap {
+		t.Fatalf("Unwrap of modified key: exp ErrUnwrap, saw %v", err)
+	}
+}
+
+func TestParseWrappedKey(t *testing.T) {
+	s, _ := srp.New(2048)
+	rec, mk, err := Register(s, []byte("user"), []byte("pass"), testKDF)
+	if err != nil {
+		t.Fatalf("Register: %s", err)
+	}
+
+	wk, err := ParseWrappedKey(rec.Key.String())
+	if err != nil {
+		t.Fatalf("ParseWrappedKey: %s", err)
+	}
+	k, err := wk.Unwrap([]byte("pass"))
+	if err != nil || !bytes.Equal(k, mk) {
+		t.Fatalf("round trip: wrong master key (%v)", err)
+	}
+
+	bad := []string{
+		"",
+		"v2:" + rec.Key.String()[3:],
+		"v1:argon2id,t=1000,m=64,p=1:00:00",
+		"v1:argon2id,t=1,m=64,p=1::00",
+		"v1:argon2id,t=1,m=64,p=1:00:zz",
+	}
+	for i, b := range bad {
+		if _, err := ParseWrappedKey(b); err == nil {
+			t.Fatalf("%d: accepted %q", i, b)
+		}
+	}
+}
@@ -0,0 +1,324 @@
+// vectors.go - reproducible test vectors for other implementations
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Implementations of SRP in other languages that must interoperate with
+// this package need known answers for every step of a handshake, and in
+// particular for its proof schemes (ProofLegacy is specific to this
+// package). GenerateVectors() computes a complete handshake for each
+// combination of group, hash function and proof scheme; all random values
+// (the salt and the ephemerals a and b) are drawn from a deterministic
+// generator keyed by a seed, so a seed always yields the same vectors:
+//
+//	block_i = SHA-256(SHA-256(seed, 0, group, 0, hash, 0, scheme) || uint64be(i))
+//
+// The vectors serialize to JSON with numbers and byte strings in hex.
+// TestVector.Check() replays a vector: it recomputes every value and runs
+// the client side of a handshake with the recorded a and B.
+
+// VectorIdentity and VectorPassword are the credentials of the vectors
+const (
+	VectorIdentity = "alice"
+	VectorPassword = "password123"
+)
+
+// TestVector is one handshake computed by GenerateVectors(); all numbers
+// are big-endian hex without padding, byte strings are hex.
+type TestVector struct {
+	Group  string `json:"group"`  // the built-in group's ID
+	Hash   string `json:"hash"`   // hash name, e.g., "SHA-256"
+	Scheme string `json:"scheme"` // proof scheme name, e.g., "legacy"
+
+	I    string `json:"I"`    // identity
+	P    string `json:"P"`    // password
+	Salt string `json:"salt"` // salt s
+
+	PrivA string `json:"a"` // the client's secret ephemeral
+	PrivB string `json:"b"` // the server's secret ephemeral
+
+	X    string `json:"x"` // x = H(H(I), H(P), s)
+	V    string `json:"v"` // v = g^x
+	Mult string `json:"k"` // k = H(N, pad(g))
+	A    string `json:"A"` // A = g^a
+	B    string `json:"B"` // B = kv + g^b
+	U    string `json:"u"` // u = H(pad(A), pad(B))
+	S    string `json:"S"` // the shared secret
+
+	Key string `json:"K"`  // the session key
+	M1  string `json:"M1"` // the client's proof
+	M2  string `json:"M2"` // the server's proof
+}
+
+// VectorConfig selects the vectors of GenerateVectors(); nil fields take
+// every built-in group, every available hash and every proof scheme of
+// this package.
+type VectorConfig struct {
+	Seed    []byte
+	Groups  []string
+	Hashes  []crypto.Hash
+	Schemes []ProofScheme
+}
+
+// GenerateVectors returns a vector for each combination of group, hash and
+// proof scheme of 'cfg' (see above).
+func GenerateVectors(cfg VectorConfig) ([]TestVector, error) {
+	if len(cfg.Seed) == 0 {
+		return nil, fmt.Errorf("srp: test vectors need a seed")
+	}
+
+	groupIDs := cfg.Groups
+	if groupIDs == nil {
+		for _, pf := range groups {
+			groupIDs = append(groupIDs, pf.id)
+		}
+	}
+	hs := cfg.Hashes
+	if hs == nil {
+		hs = AvailableHashes()
+	}
+	schemes := cfg.Schemes
+	if schemes == nil {
+		schemes = []ProofScheme{ProofLegacy, ProofLegacyPadded, ProofRFC5054, ProofRFC5054Padded}
+	}
+
+	var tv []TestVector
+	for _, id := range groupIDs {
+		for _, h := range hs {
+			for _, p := range schemes {
+				v, err := generateVector(cfg.Seed, id, h, p)
+				if err != nil {
+					return nil, err
+				}
+				tv = append(tv, *v)
+			}
+		}
+	}
+	return tv, nil
+}
+
+// compute the vector of group 'id', hash 'h' and scheme 'p' with the
+// generator keyed by 'seed'
+func generateVector(seed []byte, id string, h crypto.Hash, p ProofScheme) (*TestVector, error) {
+	key := sha256.New()
+	for _, b := range [][]byte{seed, []byte(id), []byte(hashName(h)), []byte(p.Name())} {
+		key.Write(b)
+		key.Write([]byte{0})
+	}
+
+	r := &vectorRand{key: key.Sum(nil)}
+	s, err := NewWithGroupID(h, id, WithInsecureGroups(), WithProofScheme(p), WithRand(r))
+	if err != nil {
+		return nil, fmt.Errorf("srp: vector %s/%s/%s: %w", id, hashName(h), p.Name(), err)
+	}
+
+	salt := s.randbytes(s.saltSize())
+	a := s.ephemeral()
+	b := s.ephemeral()
+	return s.vector([]byte(VectorIdentity), []byte(VectorPassword), salt, a, b)
+}
+
+// return the vector of a handshake in this environment with the given
+// inputs
+func (s *SRP) vector(I, p, salt []byte, a, b *big.Int) (*TestVector, error) {
+	pf := s.pf
+	x := s.ComputeX(I, p, salt)
+	v := s.ComputeVerifier(x)
+	A := s.arith().Exp(pf.g, a, pf.N)
+	B := s.ComputeB(b, v)
+	u := s.ComputeU(A, B)
+
+	S, err := s.serverS(b, v, u, A)
+	if err != nil {
+		return nil, err
+	}
+	if cs, _ := s.clientS(a, x, u, B); cs.Cmp(S) != 0 {
+		return nil, fmt.Errorf("srp: client and server secrets differ")
+	}
+
+	K := s.ComputeSessionKey(S)
+	t := s.transcript(K, A, B, s.hashbyte(s.identity(I)), salt)
+	M1 := s.scheme().ClientProof(t)
+	M2 := s.scheme().ServerProof(t, M1)
+
+	return &TestVector{
+		Group:  s.pf.id,
+		Hash:   hashName(s.h),
+		Scheme: s.scheme().Name(),
+		I:      string(I),
+		P:      string(p),
+		Salt:   hex.EncodeToString(salt),
+		X:      x.Text(16),
+		V:      v.Text(16),
+		Mult:   s.multiplier().Text(16),
+		A:      A.Text(16),
+		B:      B.Text(16),
+		U:      u.Text(16),
+		S:      S.Text(16),
+		Key:    hex.EncodeToString(K),
+		M1:     hex.EncodeToString(M1),
+		M2:     hex.EncodeToString(M2),
+		PrivA:  a.Text(16),
+		PrivB:  b.Text(16),
+	}, nil
+}
+
+// Check recomputes the vector from its inputs (I, P, salt, a and b),
+// compares every value and runs a client handshake with a and B; it
+// returns an error describing the first difference.
+func (tv *TestVector) Check() error {
+	h, err := hashByName(tv.Hash)
+	if err != nil {
+		return err
+	}
+	p := proofSchemeByName(tv.Scheme)
+	if p == nil {
+		return fmt.Errorf("srp: unknown proof scheme %q", tv.Scheme)
+	}
+	s, err := NewWithGroupID(h, tv.Group, WithInsecureGroups(), WithProofScheme(p))
+	if err != nil {
+		return err
+	}
+
+	salt, err := hex.DecodeString(tv.Salt)
+	if err != nil {
+		return fmt.Errorf("srp: vector salt: %w", err)
+	}
+	a, ok := big.NewInt(0).SetString(tv.PrivA, 16)
+	if !ok {
+		return fmt.Errorf("srp: vector a: invalid number")
+	}
+	b, ok := big.NewInt(0).SetString(tv.PrivB, 16)
+	if !ok {
+		return fmt.Errorf("srp: vector b: invalid number")
+	}
+
+	want, err := s.vector([]byte(tv.I), []byte(tv.P), salt, a, b)
+	if err != nil {
+		return err
+	}
+	for _, f := range []struct {
+		name      string
+		have, got string
+		num       bool
+	}{
+		{"x", tv.X, want.X, true},
+		{"v", tv.V, want.V, true},
+		{"k", tv.Mult, want.Mult, true},
+		{"A", tv.A, want.A, true},
+		{"B", tv.B, want.B, true},
+		{"u", tv.U, want.U, true},
+		{"S", tv.S, want.S, true},
+		{"K", tv.Key, want.Key, false},
+		{"M1", tv.M1, want.M1, false},
+		{"M2", tv.M2, want.M2, false},
+	} {
+		if !equalHex(f.have, f.got, f.num) {
+			return fmt.Errorf("srp: vector %s: %s is %s, expected %s", tv.name(), f.name, f.have, f.got)
+		}
+	}
+
+	// the same handshake through Client
+	c, err := s.NewClient([]byte(tv.I), []byte(tv.P))
+	if err != nil {
+		return err
+	}
+	c.a = a
+	c.xA = s.arith().Exp(s.pf.g, a, s.pf.N)
+
+	B, _ := big.NewInt(0).SetString(want.B, 16)
+	M1, err := c.Respond(ServerCredentials{Salt: salt, B: s.encodeInt(B)})
+	if err != nil {
+		return fmt.Errorf("srp: vector %s: client: %w", tv.name(), err)
+	}
+	M2, _ := hex.DecodeString(want.M2)
+	if hex.EncodeToString(M1) != want.M1 || !c.CheckProof(M2) {
+		return fmt.Errorf("srp: vector %s: client proofs differ", tv.name())
+	}
+	return nil
+}
+
+// return the name of the vector in errors
+func (tv *TestVector) name() string {
+	return tv.Group + "/" + tv.Hash + "/" + tv.Scheme
+}
+
+// return true if the hex strings 'x' and 'y' are the same, ignoring case
+// and, if they are numbers ('num'), leading zeros
+func equalHex(x, y string, num bool) bool {
+	if !num {
+		return len(x) > 0 && strings.EqualFold(x, y)
+	}
+	xi, ok := big.NewInt(0).SetString(x, 16)
+	yi, ok2 := big.NewInt(0).SetString(y, 16)
+	return ok && ok2 && xi.Cmp(yi) == 0
+}
+
+// return the hash function named 'name' by hashName()
+func hashByName(name string) (crypto.Hash, error) {
+	for h, p := range hashPackages {
+		if p[0] == name {
+			return h, checkHash(h)
+		}
+	}
+	if n, err := strconv.ParseUint(name, 10, 32); err == nil {
+		h := crypto.Hash(n)
+		return h, checkHash(h)
+	}
+	return 0, fmt.Errorf("srp: unknown hash %q", name)
+}
+
+// return the proof scheme of this package named 'name' or nil
+func proofSchemeByName(name string) ProofScheme {
+	for _, p := range []ProofScheme{ProofLegacy, ProofLegacyPadded, ProofRFC5054, ProofRFC5054Padded} {
+		if p.Name() == name {
+			return p
+		}
+		if np := WithoutIdentity(p); np.Name() == name {
+			return np
+		}
+	}
+	return nil
+}
+
+// vectorRand is the deterministic generator of the vectors
+type vectorRand struct {
+	key []byte
+	ctr uint64
+	buf []byte
+}
+
+// Read implements io.Reader
+func (r *vectorRand) Read(b []byte) (int, error) {
+	n := len(b)
+	for len(b) > 0 {
+		if len(r.buf) == 0 {
+			var c [8]byte
+			binary.BigEndian.PutUint64(c[:], r.ctr)
+			r.ctr++
+
+			h := sha256.New()
+			h.Write(r.key)
+			h.Write(c[:])
+			r.buf = h.Sum(nil)
+		}
+		k := copy(b, r.buf)
+		r.buf = r.buf[k:]
+		b = b[k:]
+	}
+	return n, nil
+}
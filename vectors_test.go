@@ -0,0 +1,64 @@
+// self test for GenerateVectors()
+//
+// Copyright 2013-2017 Sudhi Herle <sudhi.herle-at-gmail-dot-com>
+// License: MIT
+//
+
+package srp
+
+import (
+	"crypto"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestVectors(t *testing.T) {
+	skipFIPS(t)
+
+	assert := newAsserter(t)
+
+	cfg := VectorConfig{
+		Seed:   []byte("test vectors"),
+		Groups: []string{"rfc5054-2048", "rfc5054-3072"},
+		Hashes: []crypto.Hash{crypto.SHA1, crypto.SHA256},
+	}
+	tv, err := GenerateVectors(cfg)
+	assert(err == nil, "generate: %s", err)
+	assert(len(tv) == 2*2*4, "exp 16 vectors, saw %d", len(tv))
+
+	for i := range tv {
+		assert(tv[i].Check() == nil, "vector %s: %s", tv[i].name(), tv[i].Check())
+	}
+
+	// the seed determines the vectors; each is independent of the others
+	tv2, err := GenerateVectors(VectorConfig{Seed: cfg.Seed, Groups: cfg.Groups[1:], Hashes: cfg.Hashes[1:], Schemes: []ProofScheme{ProofRFC5054}})
+	assert(err == nil, "generate: %s", err)
+	assert(len(tv2) == 1, "exp 1 vector, saw %d", len(tv2))
+	assert(reflect.DeepEqual(tv2[0], tv[len(tv)-2]), "vectors differ:\n%+v\n%+v", tv2[0], tv[len(tv)-2])
+
+	tv3, err := GenerateVectors(VectorConfig{Seed: []byte("other"), Groups: cfg.Groups[1:], Hashes: cfg.Hashes[1:], Schemes: []ProofScheme{ProofRFC5054}})
+	assert(err == nil, "generate: %s", err)
+	assert(tv3[0].M1 != tv2[0].M1, "seed doesn't change the vectors")
+
+	// JSON round trip
+	b, err := json.Marshal(tv[:2])
+	assert(err == nil, "marshal: %s", err)
+	var rt []TestVector
+	assert(json.Unmarshal(b, &rt) == nil, "unmarshal")
+	assert(reflect.DeepEqual(rt, tv[:2]), "json round trip differs")
+
+	// a changed value is detected
+	bad := tv[0]
+	bad.M2 = tv[1].M2
+	assert(bad.Check() != nil, "changed M2 passes")
+	bad = tv[0]
+	bad.PrivA = tv[1].PrivA
+	assert(bad.Check() != nil, "changed a passes")
+	bad = tv[0]
+	bad.Scheme = "nope"
+	assert(bad.Check() != nil, "unknown scheme passes")
+
+	_, err = GenerateVectors(VectorConfig{})
+	assert(err != nil, "generated without a seed")
+}